@@ -0,0 +1,225 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package command
+
+import (
+	"fmt"
+	"time"
+
+	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/spf13/cobra"
+)
+
+// NewDebugCommand returns the cobra command for "debug".
+func NewDebugCommand() *cobra.Command {
+	dc := &cobra.Command{
+		Use:   "debug <subcommand>",
+		Short: "Debug related commands",
+	}
+
+	dc.AddCommand(newDebugSnapshotCommand())
+	dc.AddCommand(newDebugModulesCommand())
+	dc.AddCommand(newDebugDrainCommand())
+	dc.AddCommand(newDebugUndrainCommand())
+	dc.AddCommand(newDebugRequestAckCommand())
+	dc.AddCommand(newDebugDrainInstanceCommand())
+	dc.AddCommand(newDebugUndrainInstanceCommand())
+
+	return dc
+}
+
+var (
+	drainResource   string
+	drainDisconnect bool
+
+	requestAckTimeout time.Duration
+)
+
+func newDebugSnapshotCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "snapshot",
+		Short: "Dumps a JSON snapshot of the server internal state",
+		Run:   debugSnapshotCommandFunc,
+	}
+}
+
+// debugSnapshotCommandFunc executes the "debug snapshot" command.
+func debugSnapshotCommandFunc(cmd *cobra.Command, _ []string) {
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.GetStateSnapshot(ctx, &adminpb.GetStateSnapshotRequest{})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.StateSnapshot(resp)
+}
+
+func newDebugModulesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "modules",
+		Short: "Lists every configured module along with the namespaces it matches and the features and identities it advertises",
+		Run:   debugModulesCommandFunc,
+	}
+}
+
+// debugModulesCommandFunc executes the "debug modules" command.
+func debugModulesCommandFunc(cmd *cobra.Command, _ []string) {
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.GetStateSnapshot(ctx, &adminpb.GetStateSnapshotRequest{})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.Modules(resp.Modules)
+}
+
+func newDebugDrainCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "drain <user name> [options]",
+		Short: "Stops delivering stanzas to user sessions hosted by the instance",
+		Run:   debugDrainCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&drainResource, "resource", "", "Drain a single session instead of the whole account")
+	cmd.Flags().BoolVar(&drainDisconnect, "disconnect", false, "If true, drained sessions are also disconnected")
+
+	return &cmd
+}
+
+func newDebugUndrainCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "undrain <user name> [options]",
+		Short: "Resumes delivering stanzas to previously drained user sessions",
+		Run:   debugUndrainCommandFunc,
+	}
+
+	cmd.Flags().StringVar(&drainResource, "resource", "", "Undrain a single session instead of the whole account")
+
+	return &cmd
+}
+
+func newDebugRequestAckCommand() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "request-ack <full jid> [options]",
+		Short: "Sends a stream management ack request to a session and waits for its acknowledgement",
+		Run:   debugRequestAckCommandFunc,
+	}
+
+	cmd.Flags().DurationVar(&requestAckTimeout, "ack-timeout", 0, "Maximum time to wait for the acknowledgement (server default if zero)")
+
+	return &cmd
+}
+
+func newDebugDrainInstanceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "drain-instance",
+		Short: "Puts the serving instance in draining mode, so that new connections are rejected",
+		Run:   debugDrainInstanceCommandFunc,
+	}
+}
+
+func newDebugUndrainInstanceCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "undrain-instance",
+		Short: "Takes the serving instance out of draining mode",
+		Run:   debugUndrainInstanceCommandFunc,
+	}
+}
+
+// debugDrainCommandFunc executes the "debug drain" command.
+func debugDrainCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("debug drain command requires user name as its argument"))
+	}
+	username := args[0]
+
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.DrainSessions(ctx, &adminpb.DrainSessionsRequest{
+		Username:   username,
+		Resource:   drainResource,
+		Disconnect: drainDisconnect,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.DrainSessions(username, resp)
+}
+
+// debugUndrainCommandFunc executes the "debug undrain" command.
+func debugUndrainCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("debug undrain command requires user name as its argument"))
+	}
+	username := args[0]
+
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.UndrainSessions(ctx, &adminpb.UndrainSessionsRequest{
+		Username: username,
+		Resource: drainResource,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.UndrainSessions(username, resp)
+}
+
+// debugRequestAckCommandFunc executes the "debug request-ack" command.
+func debugRequestAckCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		ExitWithError(ExitBadArgs, fmt.Errorf("debug request-ack command requires full jid as its argument"))
+	}
+	jd := args[0]
+
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.RequestStreamAck(ctx, &adminpb.RequestStreamAckRequest{
+		Jid:       jd,
+		TimeoutMs: uint32(requestAckTimeout.Milliseconds()),
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.RequestStreamAck(jd, resp)
+}
+
+// debugDrainInstanceCommandFunc executes the "debug drain-instance" command.
+func debugDrainInstanceCommandFunc(cmd *cobra.Command, _ []string) {
+	setInstanceDraining(cmd, true)
+}
+
+// debugUndrainInstanceCommandFunc executes the "debug undrain-instance" command.
+func debugUndrainInstanceCommandFunc(cmd *cobra.Command, _ []string) {
+	setInstanceDraining(cmd, false)
+}
+
+func setInstanceDraining(cmd *cobra.Command, draining bool) {
+	cc, ctx, cancel := mustDebugClientFromCmd(cmd)
+	defer cancel()
+
+	resp, err := cc.SetInstanceDraining(ctx, &adminpb.SetInstanceDrainingRequest{
+		Draining: draining,
+	})
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	display.SetInstanceDraining(draining, resp)
+}
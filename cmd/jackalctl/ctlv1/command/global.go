@@ -57,6 +57,12 @@ func mustUsersClientFromCmd(cmd *cobra.Command) (adminpb.UsersClient, context.Co
 	return adminpb.NewUsersClient(conn), ctx, cancel
 }
 
+func mustDebugClientFromCmd(cmd *cobra.Command) (adminpb.DebugClient, context.Context, context.CancelFunc) {
+	conn := connFromCmd(cmd)
+	ctx, cancel := commandCtx(cmd)
+	return adminpb.NewDebugClient(conn), ctx, cancel
+}
+
 func initDisplayFromCmd(cmd *cobra.Command) {
 	display = &simplePrinter{}
 }
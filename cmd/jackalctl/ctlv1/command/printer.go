@@ -16,14 +16,22 @@ package command
 
 import (
 	"fmt"
+	"strings"
 
 	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 type printer interface {
 	CreateUser(name string, _ *adminpb.CreateUserResponse)
 	ChangeUserPassword(*adminpb.ChangeUserPasswordResponse)
 	DeleteUser(string, *adminpb.DeleteUserResponse)
+	StateSnapshot(*adminpb.GetStateSnapshotResponse)
+	Modules([]*adminpb.Module)
+	DrainSessions(string, *adminpb.DrainSessionsResponse)
+	UndrainSessions(string, *adminpb.UndrainSessionsResponse)
+	RequestStreamAck(string, *adminpb.RequestStreamAckResponse)
+	SetInstanceDraining(bool, *adminpb.SetInstanceDrainingResponse)
 }
 
 type simplePrinter struct{}
@@ -39,3 +47,57 @@ func (p *simplePrinter) ChangeUserPassword(*adminpb.ChangeUserPasswordResponse)
 func (p *simplePrinter) DeleteUser(user string, _ *adminpb.DeleteUserResponse) {
 	fmt.Printf("User %s deleted\n", user)
 }
+
+func (p *simplePrinter) StateSnapshot(resp *adminpb.GetStateSnapshotResponse) {
+	b, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(resp)
+	if err != nil {
+		ExitWithError(ExitError, err)
+	}
+	fmt.Println(string(b))
+}
+
+func (p *simplePrinter) Modules(mods []*adminpb.Module) {
+	for _, mod := range mods {
+		status := "started"
+		if !mod.Started {
+			status = "stopped"
+		}
+		fmt.Printf("%s (%s)\n", mod.Name, status)
+		fmt.Printf("  namespaces:       %s\n", strings.Join(mod.Namespaces, ", "))
+		fmt.Printf("  server features:  %s\n", strings.Join(mod.ServerFeatures, ", "))
+		fmt.Printf("  account features: %s\n", strings.Join(mod.AccountFeatures, ", "))
+
+		var identities []string
+		for _, identity := range mod.Identities {
+			identities = append(identities, strings.TrimSpace(fmt.Sprintf("%s/%s %s", identity.Category, identity.Type, identity.Name)))
+		}
+		fmt.Printf("  identities:       %s\n", strings.Join(identities, ", "))
+	}
+}
+
+func (p *simplePrinter) DrainSessions(user string, resp *adminpb.DrainSessionsResponse) {
+	fmt.Printf("User %s sessions drained\n", user)
+	for _, jd := range resp.Disconnected {
+		fmt.Printf("Disconnected %s\n", jd)
+	}
+}
+
+func (p *simplePrinter) UndrainSessions(user string, _ *adminpb.UndrainSessionsResponse) {
+	fmt.Printf("User %s sessions undrained\n", user)
+}
+
+func (p *simplePrinter) RequestStreamAck(jd string, resp *adminpb.RequestStreamAckResponse) {
+	if !resp.Acknowledged {
+		fmt.Printf("Session %s did not acknowledge the request\n", jd)
+		return
+	}
+	fmt.Printf("Session %s acknowledged the request in %dms\n", jd, resp.LatencyMs)
+}
+
+func (p *simplePrinter) SetInstanceDraining(draining bool, _ *adminpb.SetInstanceDrainingResponse) {
+	if !draining {
+		fmt.Println("Instance draining disabled")
+		return
+	}
+	fmt.Println("Instance draining enabled")
+}
@@ -51,6 +51,7 @@ func init() {
 
 	rootCmd.AddCommand(
 		command.NewUserCommand(),
+		command.NewDebugCommand(),
 		command.NewVersionCommand(),
 	)
 }
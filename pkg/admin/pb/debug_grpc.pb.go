@@ -0,0 +1,297 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// DebugClient is the client API for Debug service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DebugClient interface {
+	// GetStateSnapshot returns a read-only snapshot of the internal server state.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INTERNAL(13): When an internal problem happens.
+	GetStateSnapshot(ctx context.Context, in *GetStateSnapshotRequest, opts ...grpc.CallOption) (*GetStateSnapshotResponse, error)
+	// DrainSessions stops delivering stanzas to the sessions of an account hosted by the serving instance.
+	// Messages that can no longer be delivered are stored offline.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When username is empty.
+	// - INTERNAL(13): When an internal problem happens.
+	DrainSessions(ctx context.Context, in *DrainSessionsRequest, opts ...grpc.CallOption) (*DrainSessionsResponse, error)
+	// UndrainSessions resumes delivering stanzas to previously drained sessions.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When username is empty.
+	UndrainSessions(ctx context.Context, in *UndrainSessionsRequest, opts ...grpc.CallOption) (*UndrainSessionsResponse, error)
+	// RequestStreamAck sends an out-of-band stream management ack request (XEP-0198) to a session hosted
+	// by the serving instance, and waits for the client to acknowledge it.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When jid is not a valid full JID.
+	// - NOT_FOUND(5): When session has no stream management queue in the serving instance.
+	RequestStreamAck(ctx context.Context, in *RequestStreamAckRequest, opts ...grpc.CallOption) (*RequestStreamAckResponse, error)
+	// SetInstanceDraining enables or disables draining mode of the serving instance. While draining, the instance
+	// is marked as such in the cluster memberlist and new incoming connections are rejected, while already established
+	// sessions are kept.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INTERNAL(13): When an internal problem happens.
+	SetInstanceDraining(ctx context.Context, in *SetInstanceDrainingRequest, opts ...grpc.CallOption) (*SetInstanceDrainingResponse, error)
+}
+
+type debugClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDebugClient(cc grpc.ClientConnInterface) DebugClient {
+	return &debugClient{cc}
+}
+
+func (c *debugClient) GetStateSnapshot(ctx context.Context, in *GetStateSnapshotRequest, opts ...grpc.CallOption) (*GetStateSnapshotResponse, error) {
+	out := new(GetStateSnapshotResponse)
+	err := c.cc.Invoke(ctx, "/admin.v1.Debug/GetStateSnapshot", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugClient) DrainSessions(ctx context.Context, in *DrainSessionsRequest, opts ...grpc.CallOption) (*DrainSessionsResponse, error) {
+	out := new(DrainSessionsResponse)
+	err := c.cc.Invoke(ctx, "/admin.v1.Debug/DrainSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugClient) UndrainSessions(ctx context.Context, in *UndrainSessionsRequest, opts ...grpc.CallOption) (*UndrainSessionsResponse, error) {
+	out := new(UndrainSessionsResponse)
+	err := c.cc.Invoke(ctx, "/admin.v1.Debug/UndrainSessions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugClient) RequestStreamAck(ctx context.Context, in *RequestStreamAckRequest, opts ...grpc.CallOption) (*RequestStreamAckResponse, error) {
+	out := new(RequestStreamAckResponse)
+	err := c.cc.Invoke(ctx, "/admin.v1.Debug/RequestStreamAck", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugClient) SetInstanceDraining(ctx context.Context, in *SetInstanceDrainingRequest, opts ...grpc.CallOption) (*SetInstanceDrainingResponse, error) {
+	out := new(SetInstanceDrainingResponse)
+	err := c.cc.Invoke(ctx, "/admin.v1.Debug/SetInstanceDraining", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DebugServer is the server API for Debug service.
+// All implementations must embed UnimplementedDebugServer
+// for forward compatibility
+type DebugServer interface {
+	// GetStateSnapshot returns a read-only snapshot of the internal server state.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INTERNAL(13): When an internal problem happens.
+	GetStateSnapshot(context.Context, *GetStateSnapshotRequest) (*GetStateSnapshotResponse, error)
+	// DrainSessions stops delivering stanzas to the sessions of an account hosted by the serving instance.
+	// Messages that can no longer be delivered are stored offline.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When username is empty.
+	// - INTERNAL(13): When an internal problem happens.
+	DrainSessions(context.Context, *DrainSessionsRequest) (*DrainSessionsResponse, error)
+	// UndrainSessions resumes delivering stanzas to previously drained sessions.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When username is empty.
+	UndrainSessions(context.Context, *UndrainSessionsRequest) (*UndrainSessionsResponse, error)
+	// RequestStreamAck sends an out-of-band stream management ack request (XEP-0198) to a session hosted
+	// by the serving instance, and waits for the client to acknowledge it.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INVALID_ARGUMENT(3): When jid is not a valid full JID.
+	// - NOT_FOUND(5): When session has no stream management queue in the serving instance.
+	RequestStreamAck(context.Context, *RequestStreamAckRequest) (*RequestStreamAckResponse, error)
+	// SetInstanceDraining enables or disables draining mode of the serving instance. While draining, the instance
+	// is marked as such in the cluster memberlist and new incoming connections are rejected, while already established
+	// sessions are kept.
+	//
+	// Return status codes (https://github.com/grpc/grpc/blob/master/doc/statuscodes.md):
+	// - INTERNAL(13): When an internal problem happens.
+	SetInstanceDraining(context.Context, *SetInstanceDrainingRequest) (*SetInstanceDrainingResponse, error)
+	mustEmbedUnimplementedDebugServer()
+}
+
+// UnimplementedDebugServer must be embedded to have forward compatible implementations.
+type UnimplementedDebugServer struct {
+}
+
+func (UnimplementedDebugServer) GetStateSnapshot(context.Context, *GetStateSnapshotRequest) (*GetStateSnapshotResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStateSnapshot not implemented")
+}
+func (UnimplementedDebugServer) DrainSessions(context.Context, *DrainSessionsRequest) (*DrainSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainSessions not implemented")
+}
+func (UnimplementedDebugServer) UndrainSessions(context.Context, *UndrainSessionsRequest) (*UndrainSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UndrainSessions not implemented")
+}
+func (UnimplementedDebugServer) RequestStreamAck(context.Context, *RequestStreamAckRequest) (*RequestStreamAckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestStreamAck not implemented")
+}
+func (UnimplementedDebugServer) SetInstanceDraining(context.Context, *SetInstanceDrainingRequest) (*SetInstanceDrainingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetInstanceDraining not implemented")
+}
+func (UnimplementedDebugServer) mustEmbedUnimplementedDebugServer() {}
+
+// UnsafeDebugServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DebugServer will
+// result in compilation errors.
+type UnsafeDebugServer interface {
+	mustEmbedUnimplementedDebugServer()
+}
+
+func RegisterDebugServer(s grpc.ServiceRegistrar, srv DebugServer) {
+	s.RegisterService(&Debug_ServiceDesc, srv)
+}
+
+func _Debug_GetStateSnapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateSnapshotRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServer).GetStateSnapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.v1.Debug/GetStateSnapshot",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServer).GetStateSnapshot(ctx, req.(*GetStateSnapshotRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Debug_DrainSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServer).DrainSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.v1.Debug/DrainSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServer).DrainSessions(ctx, req.(*DrainSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Debug_UndrainSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UndrainSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServer).UndrainSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.v1.Debug/UndrainSessions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServer).UndrainSessions(ctx, req.(*UndrainSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Debug_RequestStreamAck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestStreamAckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServer).RequestStreamAck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.v1.Debug/RequestStreamAck",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServer).RequestStreamAck(ctx, req.(*RequestStreamAckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Debug_SetInstanceDraining_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetInstanceDrainingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugServer).SetInstanceDraining(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/admin.v1.Debug/SetInstanceDraining",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugServer).SetInstanceDraining(ctx, req.(*SetInstanceDrainingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Debug_ServiceDesc is the grpc.ServiceDesc for Debug service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Debug_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "admin.v1.Debug",
+	HandlerType: (*DebugServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStateSnapshot",
+			Handler:    _Debug_GetStateSnapshot_Handler,
+		},
+		{
+			MethodName: "DrainSessions",
+			Handler:    _Debug_DrainSessions_Handler,
+		},
+		{
+			MethodName: "UndrainSessions",
+			Handler:    _Debug_UndrainSessions_Handler,
+		},
+		{
+			MethodName: "RequestStreamAck",
+			Handler:    _Debug_RequestStreamAck_Handler,
+		},
+		{
+			MethodName: "SetInstanceDraining",
+			Handler:    _Debug_SetInstanceDraining_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/admin/v1/debug.proto",
+}
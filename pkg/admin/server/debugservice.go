@@ -0,0 +1,277 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminserver
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/ortuman/jackal/pkg/c2s"
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/ortuman/jackal/pkg/cluster/memberlist"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	"github.com/ortuman/jackal/pkg/module"
+	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const defaultStreamAckTimeout = time.Second * 10
+
+type debugService struct {
+	adminpb.UnimplementedDebugServer
+	resMng      resourcemanager.Manager
+	localRouter localRouter
+	memberList  memberlist.MemberList
+	stmQueueMap *streamqueue.QueueMap
+	mods        *module.Modules
+	drainer     instanceDrainer
+	logger      kitlog.Logger
+}
+
+func newDebugService(
+	resMng resourcemanager.Manager,
+	localRouter localRouter,
+	memberList memberlist.MemberList,
+	stmQueueMap *streamqueue.QueueMap,
+	mods *module.Modules,
+	drainer instanceDrainer,
+	logger kitlog.Logger,
+) adminpb.DebugServer {
+	return &debugService{
+		resMng:      resMng,
+		localRouter: localRouter,
+		memberList:  memberList,
+		stmQueueMap: stmQueueMap,
+		mods:        mods,
+		drainer:     drainer,
+		logger:      logger,
+	}
+}
+
+func (s *debugService) GetStateSnapshot(ctx context.Context, _ *adminpb.GetStateSnapshotRequest) (*adminpb.GetStateSnapshotResponse, error) {
+	sessions, err := s.sessionsSnapshot(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	modules, err := s.modulesSnapshot(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &adminpb.GetStateSnapshotResponse{
+		InstanceId:   instance.ID(),
+		Sessions:     sessions,
+		Members:      s.membersSnapshot(),
+		StreamQueues: s.streamQueuesSnapshot(),
+		Modules:      modules,
+	}
+	if s.drainer != nil {
+		resp.Draining = s.drainer.IsDraining()
+	}
+	level.Info(s.logger).Log("msg", "state snapshot taken",
+		"sessions", len(resp.Sessions),
+		"members", len(resp.Members),
+		"stream_queues", len(resp.StreamQueues),
+		"modules", len(resp.Modules),
+	)
+	return resp, nil
+}
+
+func (s *debugService) DrainSessions(ctx context.Context, req *adminpb.DrainSessionsRequest) (*adminpb.DrainSessionsResponse, error) {
+	username, resource := req.GetUsername(), req.GetResource()
+	if len(username) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "username must not be empty")
+	}
+	s.localRouter.Drain(username, resource)
+
+	level.Info(s.logger).Log("msg", "sessions drained", "username", username, "resource", resource)
+
+	resp := &adminpb.DrainSessionsResponse{}
+	if !req.GetDisconnect() {
+		return resp, nil
+	}
+	rss, err := s.resMng.GetResources(ctx, username)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	for _, res := range rss {
+		if res.InstanceID() != instance.ID() {
+			continue // hosted by another instance
+		}
+		if len(resource) > 0 && res.JID().Resource() != resource {
+			continue
+		}
+		if err := s.localRouter.Disconnect(username, res.JID().Resource(), streamerror.E(streamerror.PolicyViolation)); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Disconnected = append(resp.Disconnected, res.JID().String())
+	}
+	sort.Strings(resp.Disconnected)
+
+	level.Info(s.logger).Log("msg", "drained sessions disconnected", "username", username, "count", len(resp.Disconnected))
+	return resp, nil
+}
+
+func (s *debugService) UndrainSessions(_ context.Context, req *adminpb.UndrainSessionsRequest) (*adminpb.UndrainSessionsResponse, error) {
+	username, resource := req.GetUsername(), req.GetResource()
+	if len(username) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "username must not be empty")
+	}
+	s.localRouter.Undrain(username, resource)
+
+	level.Info(s.logger).Log("msg", "sessions undrained", "username", username, "resource", resource)
+	return &adminpb.UndrainSessionsResponse{}, nil
+}
+
+func (s *debugService) RequestStreamAck(ctx context.Context, req *adminpb.RequestStreamAckRequest) (*adminpb.RequestStreamAckResponse, error) {
+	jd, err := jid.NewWithString(req.GetJid(), false)
+	if err != nil || !jd.IsFull() {
+		return nil, status.Error(codes.InvalidArgument, "jid must be a valid full JID")
+	}
+	var sq *streamqueue.Queue
+	if s.stmQueueMap != nil {
+		sq = s.stmQueueMap.Get(jd.String())
+	}
+	if sq == nil {
+		return nil, status.Error(codes.NotFound, "stream management queue not found")
+	}
+	timeout := time.Duration(req.GetTimeoutMs()) * time.Millisecond
+	if timeout == 0 {
+		timeout = defaultStreamAckTimeout
+	}
+	ackCh := sq.ForceRequestAck()
+
+	level.Info(s.logger).Log("msg", "stream ack requested", "jid", jd.String())
+
+	tm := time.NewTimer(timeout)
+	defer tm.Stop()
+
+	select {
+	case latency := <-ackCh:
+		level.Info(s.logger).Log("msg", "stream ack received", "jid", jd.String(), "latency", latency)
+		return &adminpb.RequestStreamAckResponse{
+			Acknowledged: true,
+			LatencyMs:    latency.Milliseconds(),
+		}, nil
+
+	case <-tm.C:
+		level.Warn(s.logger).Log("msg", "stream ack timeout", "jid", jd.String(), "timeout", timeout)
+		return &adminpb.RequestStreamAckResponse{}, nil
+
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (s *debugService) SetInstanceDraining(ctx context.Context, req *adminpb.SetInstanceDrainingRequest) (*adminpb.SetInstanceDrainingResponse, error) {
+	if err := s.drainer.SetDraining(ctx, req.GetDraining()); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	level.Info(s.logger).Log("msg", "instance draining mode updated", "draining", req.GetDraining())
+	return &adminpb.SetInstanceDrainingResponse{}, nil
+}
+
+func (s *debugService) sessionsSnapshot(ctx context.Context) ([]*adminpb.Session, error) {
+	rss, err := s.resMng.GetAllResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retVal := make([]*adminpb.Session, 0, len(rss))
+	for _, res := range rss {
+		retVal = append(retVal, &adminpb.Session{
+			Jid:            res.JID().String(),
+			InstanceId:     res.InstanceID(),
+			Available:      res.IsAvailable(),
+			ClientSoftware: res.Info().String(c2s.ClientSoftwareInfoKey),
+			RemoteIp:       res.Info().String(c2s.RemoteIPInfoKey),
+		})
+	}
+	sort.Slice(retVal, func(i, j int) bool { return retVal[i].Jid < retVal[j].Jid })
+	return retVal, nil
+}
+
+func (s *debugService) membersSnapshot() []*adminpb.Member {
+	members := s.memberList.GetMembers()
+
+	retVal := make([]*adminpb.Member, 0, len(members))
+	for _, m := range members {
+		var apiVer string
+		if m.APIVer != nil {
+			apiVer = m.APIVer.String()
+		}
+		retVal = append(retVal, &adminpb.Member{
+			InstanceId: m.InstanceID,
+			Host:       m.Host,
+			Port:       int32(m.Port),
+			ApiVersion: apiVer,
+			Draining:   m.Draining,
+		})
+	}
+	sort.Slice(retVal, func(i, j int) bool { return retVal[i].InstanceId < retVal[j].InstanceId })
+	return retVal
+}
+
+func (s *debugService) streamQueuesSnapshot() []*adminpb.StreamQueue {
+	if s.stmQueueMap == nil {
+		return nil
+	}
+	var retVal []*adminpb.StreamQueue
+	s.stmQueueMap.Range(func(k string, q *streamqueue.Queue) bool {
+		retVal = append(retVal, &adminpb.StreamQueue{
+			Key:       k,
+			Length:    int32(q.Len()),
+			InboundH:  q.InboundH(),
+			OutboundH: q.OutboundH(),
+		})
+		return true
+	})
+	sort.Slice(retVal, func(i, j int) bool { return retVal[i].Key < retVal[j].Key })
+	return retVal
+}
+
+func (s *debugService) modulesSnapshot(ctx context.Context) ([]*adminpb.Module, error) {
+	if s.mods == nil {
+		return nil, nil
+	}
+	infos, err := s.mods.Inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var retVal []*adminpb.Module
+	for _, inf := range infos {
+		mod := &adminpb.Module{
+			Name:            inf.Name,
+			Started:         inf.Started,
+			Namespaces:      inf.Namespaces,
+			ServerFeatures:  inf.ServerFeatures,
+			AccountFeatures: inf.AccountFeatures,
+		}
+		for _, identity := range inf.Identities {
+			mod.Identities = append(mod.Identities, &adminpb.Identity{
+				Category: identity.Category,
+				Type:     identity.Type,
+				Name:     identity.Name,
+			})
+		}
+		retVal = append(retVal, mod)
+	}
+	return retVal, nil
+}
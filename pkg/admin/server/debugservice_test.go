@@ -0,0 +1,261 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
+	"github.com/ortuman/jackal/pkg/c2s"
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	clustermodel "github.com/ortuman/jackal/pkg/model/cluster"
+	"github.com/ortuman/jackal/pkg/module"
+	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
+	"github.com/ortuman/jackal/pkg/module/xep0202"
+	"github.com/ortuman/jackal/pkg/version"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDebugService_GetStateSnapshot(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	inf := c2smodel.NewInfoMap()
+	inf.SetString(c2s.ClientSoftwareInfoKey, "gajim")
+	inf.SetString(c2s.RemoteIPInfoKey, "203.0.113.7")
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetAllResourcesFunc = func(ctx context.Context) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			c2smodel.NewResourceDesc("i1", jd, nil, inf),
+		}, nil
+	}
+	memberListMock := &memberListMock{}
+	memberListMock.GetMembersFunc = func() map[string]clustermodel.Member {
+		return map[string]clustermodel.Member{
+			"i2": {InstanceID: "i2", Host: "192.168.0.2", Port: 14369, APIVer: version.NewVersion(1, 0, 0), Draining: true},
+		}
+	}
+	stmQueueMap := streamqueue.NewQueueMap()
+	sq := streamqueue.New(nil, nil, nil, 4, 2, time.Hour, 0, time.Hour)
+	sq.CancelTimers()
+	stmQueueMap.Set("ortuman@jackal.im/yard", sq)
+
+	mods := module.NewModules([]module.Module{xep0202.New(xep0202.Config{}, nil, kitlog.NewNopLogger())}, nil, nil, nil, hook.NewHooks(), kitlog.NewNopLogger())
+	_ = mods.Start(context.Background())
+
+	drainerMock := &instanceDrainerMock{}
+	drainerMock.IsDrainingFunc = func() bool { return true }
+
+	svc := newDebugService(resMngMock, &localRouterMock{}, memberListMock, stmQueueMap, mods, drainerMock, kitlog.NewNopLogger())
+
+	// when
+	resp, err := svc.GetStateSnapshot(context.Background(), &adminpb.GetStateSnapshotRequest{})
+
+	// then
+	require.NoError(t, err)
+
+	require.Len(t, resp.Sessions, 1)
+	require.Equal(t, "ortuman@jackal.im/yard", resp.Sessions[0].Jid)
+	require.Equal(t, "i1", resp.Sessions[0].InstanceId)
+	require.Equal(t, "gajim", resp.Sessions[0].ClientSoftware)
+	require.Equal(t, "203.0.113.7", resp.Sessions[0].RemoteIp)
+
+	require.Len(t, resp.Members, 1)
+	require.Equal(t, "i2", resp.Members[0].InstanceId)
+	require.Equal(t, "192.168.0.2", resp.Members[0].Host)
+	require.Equal(t, "v1.0.0", resp.Members[0].ApiVersion)
+	require.True(t, resp.Members[0].Draining)
+	require.True(t, resp.Draining)
+
+	require.Len(t, resp.StreamQueues, 1)
+	require.Equal(t, "ortuman@jackal.im/yard", resp.StreamQueues[0].Key)
+	require.Equal(t, uint32(4), resp.StreamQueues[0].InboundH)
+	require.Equal(t, uint32(2), resp.StreamQueues[0].OutboundH)
+
+	require.Len(t, resp.Modules, 1)
+	require.Equal(t, xep0202.ModuleName, resp.Modules[0].Name)
+	require.True(t, resp.Modules[0].Started)
+	require.Equal(t, []string{"urn:xmpp:time"}, resp.Modules[0].Namespaces)
+	require.Equal(t, []string{"urn:xmpp:time"}, resp.Modules[0].ServerFeatures)
+}
+
+func TestDebugService_DrainSessions(t *testing.T) {
+	// given
+	jd0, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+	jd1, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			c2smodel.NewResourceDesc(instance.ID(), jd0, nil, c2smodel.NewInfoMap()),
+			c2smodel.NewResourceDesc("i2", jd1, nil, c2smodel.NewInfoMap()),
+		}, nil
+	}
+	localRouterMock := &localRouterMock{}
+	localRouterMock.DrainFunc = func(username, resource string) {}
+	localRouterMock.DisconnectFunc = func(username, resource string, streamErr *streamerror.Error) error {
+		return nil
+	}
+
+	svc := newDebugService(resMngMock, localRouterMock, &memberListMock{}, nil, nil, nil, kitlog.NewNopLogger())
+
+	// when
+	resp, err := svc.DrainSessions(context.Background(), &adminpb.DrainSessionsRequest{
+		Username:   "ortuman",
+		Disconnect: true,
+	})
+
+	// then
+	require.NoError(t, err)
+
+	require.Len(t, localRouterMock.DrainCalls(), 1)
+	require.Equal(t, "ortuman", localRouterMock.DrainCalls()[0].Username)
+	require.Equal(t, "", localRouterMock.DrainCalls()[0].Resource)
+
+	require.Len(t, localRouterMock.DisconnectCalls(), 1)
+	require.Equal(t, "yard", localRouterMock.DisconnectCalls()[0].Resource)
+
+	require.Equal(t, []string{"ortuman@jackal.im/yard"}, resp.Disconnected)
+}
+
+func TestDebugService_DrainSessionsInvalidArgument(t *testing.T) {
+	// given
+	localRouterMock := &localRouterMock{}
+
+	svc := newDebugService(&resourceManagerMock{}, localRouterMock, &memberListMock{}, nil, nil, nil, kitlog.NewNopLogger())
+
+	// when
+	_, err := svc.DrainSessions(context.Background(), &adminpb.DrainSessionsRequest{})
+
+	// then
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Len(t, localRouterMock.DrainCalls(), 0)
+}
+
+func TestDebugService_RequestStreamAck(t *testing.T) {
+	// given
+	stmQueueMap := streamqueue.NewQueueMap()
+
+	stmMock := &c2sStreamMock{}
+	sq := streamqueue.New(stmMock, nil, nil, 0, 0, time.Hour, 0, time.Hour)
+	defer sq.CancelTimers()
+
+	var sentElements []stravaganza.Element
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sentElements = append(sentElements, elem)
+		go func() {
+			time.Sleep(time.Millisecond * 20)
+			sq.Acknowledge(0) // client answers the request
+		}()
+		return nil
+	}
+	stmQueueMap.Set("ortuman@jackal.im/yard", sq)
+
+	svc := newDebugService(&resourceManagerMock{}, &localRouterMock{}, &memberListMock{}, stmQueueMap, nil, nil, kitlog.NewNopLogger())
+
+	// when
+	resp, err := svc.RequestStreamAck(context.Background(), &adminpb.RequestStreamAckRequest{
+		Jid:       "ortuman@jackal.im/yard",
+		TimeoutMs: 1000,
+	})
+
+	// then
+	require.NoError(t, err)
+
+	require.Len(t, sentElements, 1)
+	require.Equal(t, "r", sentElements[0].Name())
+	require.Equal(t, "urn:xmpp:sm:3", sentElements[0].Attribute(stravaganza.Namespace))
+
+	require.True(t, resp.Acknowledged)
+	require.GreaterOrEqual(t, resp.LatencyMs, int64(20))
+}
+
+func TestDebugService_RequestStreamAckTimeout(t *testing.T) {
+	// given
+	stmQueueMap := streamqueue.NewQueueMap()
+
+	stmMock := &c2sStreamMock{}
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error { return nil }
+
+	sq := streamqueue.New(stmMock, nil, nil, 0, 0, time.Hour, 0, time.Hour)
+	defer sq.CancelTimers()
+
+	stmQueueMap.Set("ortuman@jackal.im/yard", sq)
+
+	svc := newDebugService(&resourceManagerMock{}, &localRouterMock{}, &memberListMock{}, stmQueueMap, nil, nil, kitlog.NewNopLogger())
+
+	// when
+	resp, err := svc.RequestStreamAck(context.Background(), &adminpb.RequestStreamAckRequest{
+		Jid:       "ortuman@jackal.im/yard",
+		TimeoutMs: 50,
+	})
+
+	// then
+	require.NoError(t, err)
+	require.False(t, resp.Acknowledged)
+	require.Len(t, stmMock.SendElementCalls(), 1)
+}
+
+func TestDebugService_RequestStreamAckNotFound(t *testing.T) {
+	// given
+	svc := newDebugService(&resourceManagerMock{}, &localRouterMock{}, &memberListMock{}, streamqueue.NewQueueMap(), nil, nil, kitlog.NewNopLogger())
+
+	// when
+	_, err1 := svc.RequestStreamAck(context.Background(), &adminpb.RequestStreamAckRequest{Jid: "ortuman@jackal.im"})
+	_, err2 := svc.RequestStreamAck(context.Background(), &adminpb.RequestStreamAckRequest{Jid: "ortuman@jackal.im/yard"})
+
+	// then
+	require.Equal(t, codes.InvalidArgument, status.Code(err1))
+	require.Equal(t, codes.NotFound, status.Code(err2))
+}
+
+func TestDebugService_SetInstanceDraining(t *testing.T) {
+	// given
+	var draining []bool
+	drainerMock := &instanceDrainerMock{}
+	drainerMock.SetDrainingFunc = func(ctx context.Context, d bool) error {
+		draining = append(draining, d)
+		return nil
+	}
+	svc := newDebugService(&resourceManagerMock{}, &localRouterMock{}, &memberListMock{}, nil, nil, drainerMock, kitlog.NewNopLogger())
+
+	// when
+	_, err0 := svc.SetInstanceDraining(context.Background(), &adminpb.SetInstanceDrainingRequest{Draining: true})
+	_, err1 := svc.SetInstanceDraining(context.Background(), &adminpb.SetInstanceDrainingRequest{Draining: false})
+
+	drainerMock.SetDrainingFunc = func(ctx context.Context, d bool) error {
+		return errors.New("kv unavailable")
+	}
+	_, err2 := svc.SetInstanceDraining(context.Background(), &adminpb.SetInstanceDrainingRequest{Draining: true})
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+	require.Equal(t, []bool{true, false}, draining)
+
+	require.Equal(t, codes.Internal, status.Code(err2))
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adminserver
+
+import (
+	"context"
+
+	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/ortuman/jackal/pkg/cluster/memberlist"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	"github.com/ortuman/jackal/pkg/router/stream"
+)
+
+//go:generate moq -out resourcemanager.mock_test.go . resourceManager
+type resourceManager interface {
+	resourcemanager.Manager
+}
+
+//go:generate moq -out memberlist.mock_test.go . memberList
+type memberList interface {
+	memberlist.MemberList
+}
+
+//go:generate moq -out localrouter.mock_test.go . localRouter
+type localRouter interface {
+	Drain(username, resource string)
+	Undrain(username, resource string)
+	Disconnect(username, resource string, streamErr *streamerror.Error) error
+}
+
+//go:generate moq -out instancedrainer.mock_test.go . instanceDrainer
+type instanceDrainer interface {
+	SetDraining(ctx context.Context, draining bool) error
+	IsDraining() bool
+}
+
+//go:generate moq -out c2sstream.mock_test.go . c2sStream
+type c2sStream interface {
+	stream.C2S
+}
@@ -27,7 +27,12 @@ import (
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
 	adminpb "github.com/ortuman/jackal/pkg/admin/pb"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
+	"github.com/ortuman/jackal/pkg/c2s"
+	"github.com/ortuman/jackal/pkg/cluster/memberlist"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module"
+	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 	"github.com/ortuman/jackal/pkg/storage/repository"
 	"google.golang.org/grpc"
 )
@@ -41,10 +46,16 @@ type Server struct {
 	ln       net.Listener
 	active   int32
 
-	rep     repository.Repository
-	peppers *pepper.Keys
-	hk      *hook.Hooks
-	logger  kitlog.Logger
+	rep         repository.Repository
+	peppers     *pepper.Keys
+	resMng      resourcemanager.Manager
+	localRouter *c2s.LocalRouter
+	memberList  memberlist.MemberList
+	stmQueueMap *streamqueue.QueueMap
+	mods        *module.Modules
+	drainer     instanceDrainer
+	hk          *hook.Hooks
+	logger      kitlog.Logger
 }
 
 // Config contains Server configuration parameters.
@@ -59,6 +70,12 @@ func New(
 	cfg Config,
 	rep repository.Repository,
 	peppers *pepper.Keys,
+	resMng resourcemanager.Manager,
+	localRouter *c2s.LocalRouter,
+	memberList memberlist.MemberList,
+	stmQueueMap *streamqueue.QueueMap,
+	mods *module.Modules,
+	drainer instanceDrainer,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Server {
@@ -66,12 +83,18 @@ func New(
 		return nil
 	}
 	return &Server{
-		bindAddr: cfg.BindAddr,
-		port:     cfg.Port,
-		rep:      rep,
-		peppers:  peppers,
-		hk:       hk,
-		logger:   logger,
+		bindAddr:    cfg.BindAddr,
+		port:        cfg.Port,
+		rep:         rep,
+		peppers:     peppers,
+		resMng:      resMng,
+		localRouter: localRouter,
+		memberList:  memberList,
+		stmQueueMap: stmQueueMap,
+		mods:        mods,
+		drainer:     drainer,
+		hk:          hk,
+		logger:      logger,
 	}
 }
 
@@ -94,6 +117,7 @@ func (s *Server) Start(_ context.Context) error {
 			grpc.UnaryInterceptor(grpc_prometheus.UnaryServerInterceptor),
 		)
 		adminpb.RegisterUsersServer(grpcServer, newUsersService(s.rep, s.peppers, s.hk, s.logger))
+		adminpb.RegisterDebugServer(grpcServer, newDebugService(s.resMng, s.localRouter, s.memberList, s.stmQueueMap, s.mods, s.drainer, s.logger))
 		if err := grpcServer.Serve(s.ln); err != nil {
 			if atomic.LoadInt32(&s.active) == 1 {
 				level.Error(s.logger).Log("msg", "admin server error", "err", err)
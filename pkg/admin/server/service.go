@@ -101,6 +101,9 @@ func (s *usersService) DeleteUser(ctx context.Context, req *userspb.DeleteUserRe
 	if err := s.rep.DeleteUser(ctx, username); err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
+	if err := s.rep.DeleteAccountFlags(ctx, username); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	// run user deleted hook
 	_, err := s.hk.Run(ctx, hook.UserDeleted, &hook.ExecutionContext{
 		Info: &hook.UserInfo{
@@ -60,6 +60,9 @@ const (
 
 	// TemporaryAuthFailure represents a 'temporary-auth-failure' authentication error.
 	TemporaryAuthFailure
+
+	// InvalidMechanism represents a 'invalid-mechanism' authentication error.
+	InvalidMechanism
 )
 
 // String returns SASLErrorReason string representation.
@@ -73,6 +76,8 @@ func (r SASLErrorReason) String() string {
 		return "not-authorized"
 	case TemporaryAuthFailure:
 		return "temporary-auth-failure"
+	case InvalidMechanism:
+		return "invalid-mechanism"
 	default:
 		return ""
 	}
@@ -60,6 +60,12 @@ const (
 	challengedScramState
 )
 
+// cbMechanisms contains supported channel binding types.
+var cbMechanisms = map[string]transport.ChannelBindingMechanism{
+	"tls-unique":   transport.TLSUnique,
+	"tls-exporter": transport.TLSExporter,
+}
+
 type scramParameter struct {
 	key string
 	val string
@@ -259,7 +265,10 @@ func (s *Scram) handleChallenged(elem stravaganza.Element) (stravaganza.Element,
 	if saslErr != nil {
 		return nil, saslErr
 	}
-	c := s.getCBindInputString()
+	c, saslErr := s.getCBindInputString()
+	if saslErr != nil {
+		return nil, saslErr
+	}
 	initialMessage := s.params.String()
 	clientFinalMessageBare := fmt.Sprintf("c=%s,r=%s", c, s.srvNonce)
 
@@ -315,11 +324,6 @@ func (s *Scram) parseParameters(str string) *SASLError {
 
 	// https://tools.ietf.org/html/rfc5801#section-5
 	switch gs2BindFlag {
-	case "p":
-		// Channel binding is supported and required.
-		if !s.usesCb {
-			return newSASLError(NotAuthorized, nil)
-		}
 	case "n", "y":
 		// Channel binding is not supported, or is supported but is not required.
 		break
@@ -327,10 +331,15 @@ func (s *Scram) parseParameters(str string) *SASLError {
 		if !strings.HasPrefix(gs2BindFlag, "p=") {
 			return newSASLError(MalformedRequest, nil)
 		}
+		// Channel binding is supported and required.
 		if !s.usesCb {
 			return newSASLError(NotAuthorized, nil)
 		}
-		p.cbMechanism = gs2BindFlag[2:]
+		cbMechanism := gs2BindFlag[2:]
+		if _, ok := cbMechanisms[cbMechanism]; !ok {
+			return newSASLError(NotAuthorized, nil) // unsupported channel binding type
+		}
+		p.cbMechanism = cbMechanism
 	}
 	authzID := sp[1]
 	p.gs2Header = gs2BindFlag + "," + authzID + ","
@@ -350,16 +359,18 @@ func (s *Scram) parseParameters(str string) *SASLError {
 	return nil
 }
 
-func (s *Scram) getCBindInputString() string {
+func (s *Scram) getCBindInputString() (string, *SASLError) {
 	buf := new(bytes.Buffer)
 	buf.Write([]byte(s.params.gs2Header))
-	if s.usesCb {
-		switch s.params.cbMechanism {
-		case "tls-unique":
-			buf.Write(s.tr.ChannelBindingBytes(transport.TLSUnique))
+	if s.usesCb && len(s.params.cbMechanism) > 0 {
+		cbBytes := s.tr.ChannelBindingBytes(cbMechanisms[s.params.cbMechanism])
+		if len(cbBytes) == 0 {
+			// binding data not available for the negotiated TLS version
+			return "", newSASLError(NotAuthorized, nil)
 		}
+		buf.Write(cbBytes)
 	}
-	return base64.StdEncoding.EncodeToString(buf.Bytes())
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
 }
 
 func (s *Scram) getScramPassword() ([]byte, error) {
@@ -61,6 +61,7 @@ type scramAuthTestCase struct {
 	name              string
 	scramType         ScramType
 	usesCb            bool
+	cbMechanism       transport.ChannelBindingMechanism
 	cbBytes           []byte
 	gs2BindFlag       string
 	authID            string
@@ -147,6 +148,19 @@ func testScramTypeCases(t *testing.T, tp ScramType) {
 			r:           "7e51aff7-6875-4dce-820a-6d4970635006",
 			password:    "1234",
 		},
+		{
+			// Success (PLUS, TLS 1.3)
+			name:        "SuccessPLUSTLSExporter",
+			scramType:   tp,
+			usesCb:      true,
+			cbMechanism: transport.TLSExporter,
+			cbBytes:     randomBytes(32),
+			gs2BindFlag: "p=tls-exporter",
+			authID:      "a=jackal.im",
+			n:           "ortuman",
+			r:           "0d4ff5e6-0a3f-4e2c-9c25-1a6b7a3c8f42",
+			password:    "1234",
+		},
 		{
 			// Invalid user
 			name:              "InvalidUser",
@@ -184,6 +198,33 @@ func testScramTypeCases(t *testing.T, tp ScramType) {
 			expectsError:      true,
 			expectedErrReason: NotAuthorized,
 		},
+		{
+			// Unsupported channel binding type
+			name:              "UnsupportedCbMechanism",
+			scramType:         tp,
+			usesCb:            true,
+			cbBytes:           randomBytes(23),
+			gs2BindFlag:       "p=tls-server-end-point",
+			authID:            "a=jackal.im",
+			n:                 "ortuman",
+			r:                 "bb769406-eaa4-4f38-a279-2b90e596f6dd",
+			password:          "1234",
+			expectsError:      true,
+			expectedErrReason: NotAuthorized,
+		},
+		{
+			// Channel binding data not available (eg. 'tls-unique' over TLS 1.3)
+			name:              "UnavailableCbBytes",
+			scramType:         tp,
+			usesCb:            true,
+			gs2BindFlag:       "p=tls-unique",
+			authID:            "a=jackal.im",
+			n:                 "ortuman",
+			r:                 "bb769406-eaa4-4f38-a279-2b90e596f6dd",
+			password:          "1234",
+			expectsError:      true,
+			expectedErrReason: NotAuthorized,
+		},
 		{
 			// No matching gs2BindFlag (malformed)
 			name:              "NoMatchingG2SBindFlagMalformed",
@@ -242,7 +283,10 @@ func processScramTestCase(t *testing.T, tc *scramAuthTestCase) *SASLError {
 	trMock := &transportMock{}
 	repMock := &usersRepository{}
 
-	trMock.ChannelBindingBytesFunc = func(_ transport.ChannelBindingMechanism) []byte {
+	trMock.ChannelBindingBytesFunc = func(mechanism transport.ChannelBindingMechanism) []byte {
+		if mechanism != tc.cbMechanism {
+			return nil
+		}
 		return tc.cbBytes
 	}
 	testUsr := testUser()
@@ -0,0 +1,456 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"bytes"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	xmppparser "github.com/ortuman/jackal/pkg/parser"
+	"github.com/ortuman/jackal/pkg/transport"
+)
+
+const boshVersion = "1.11"
+
+type boshCfg struct {
+	wait           time.Duration
+	hold           int
+	requests       int
+	inactivity     time.Duration
+	maxStanzaSize  int
+	allowedOrigins []string
+}
+
+type boshSession struct {
+	sid    string
+	tr     *transport.BOSHTransport
+	domain string
+	wait   time.Duration
+	hold   int
+
+	mu     sync.Mutex
+	rid    int64 // last request whose payload has been fed into the stream
+	ridCh  chan struct{}
+	queued map[int64]struct{}
+	held   []chan struct{}
+	resps  map[int64][]byte
+}
+
+func newBOSHSession(sid string, tr *transport.BOSHTransport, domain string, rid int64) *boshSession {
+	return &boshSession{
+		sid:    sid,
+		tr:     tr,
+		domain: domain,
+		rid:    rid,
+		ridCh:  make(chan struct{}),
+		queued: make(map[int64]struct{}),
+		resps:  make(map[int64][]byte),
+	}
+}
+
+// enqueue reserves rid processing turn. Returns the cached response of an already responded request,
+// or false in case rid is neither pending nor within the requests window.
+func (bs *boshSession) enqueue(rid int64, window int) (cachedResp []byte, ok bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if resp, found := bs.resps[rid]; found {
+		return resp, true
+	}
+	if _, found := bs.queued[rid]; found || rid <= bs.rid || rid > bs.rid+int64(window) {
+		return nil, false
+	}
+	bs.queued[rid] = struct{}{}
+	return nil, true
+}
+
+// awaitTurn blocks until the payload of every request preceding rid has been fed into the stream,
+// so that requests received out of order are processed in sequence.
+// Returns false in case the session terminates or wait time elapses before.
+func (bs *boshSession) awaitTurn(rid int64, wait time.Duration) bool {
+	tm := time.NewTimer(wait)
+	defer tm.Stop()
+
+	for {
+		bs.mu.Lock()
+		if bs.rid == rid-1 {
+			bs.mu.Unlock()
+			return true
+		}
+		ridCh := bs.ridCh
+		bs.mu.Unlock()
+
+		select {
+		case <-ridCh:
+		case <-bs.tr.Done():
+			return false
+		case <-tm.C:
+			return false
+		}
+	}
+}
+
+// advance marks rid payload as fed into the stream, handing over turn to the next request.
+func (bs *boshSession) advance(rid int64) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.rid = rid
+	delete(bs.queued, rid)
+	close(bs.ridCh)
+	bs.ridCh = make(chan struct{})
+}
+
+// cacheResponse keeps rid response around to be resent in case the client retransmits the request,
+// evicting those falling outside the requests window.
+func (bs *boshSession) cacheResponse(rid int64, resp []byte, window int) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.resps[rid] = resp
+	for cachedRID := range bs.resps {
+		if cachedRID <= rid-int64(window) {
+			delete(bs.resps, cachedRID)
+		}
+	}
+}
+
+// acquire reserves a held request slot, releasing the oldest held request if hold limit is exceeded.
+func (bs *boshSession) acquire() chan struct{} {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	releaseCh := make(chan struct{})
+	bs.held = append(bs.held, releaseCh)
+	if len(bs.held) > bs.hold {
+		close(bs.held[0])
+		bs.held = bs.held[1:]
+	}
+	return releaseCh
+}
+
+func (bs *boshSession) release(releaseCh chan struct{}) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	for i, ch := range bs.held {
+		if ch == releaseCh {
+			bs.held = append(bs.held[:i], bs.held[i+1:]...)
+			return
+		}
+	}
+}
+
+// boshHandler serves C2S streams over BOSH (XEP-0124, XEP-0206), by feeding the payload of incoming HTTP requests
+// into the session transport and responding held requests with its flushed output.
+type boshHandler struct {
+	cfg     boshCfg
+	startFn func(tr transport.Transport, remoteAddr net.Addr) <-chan struct{}
+	logger  kitlog.Logger
+
+	mu       sync.RWMutex
+	sessions map[string]*boshSession
+}
+
+func newBOSHHandler(
+	cfg boshCfg,
+	startFn func(tr transport.Transport, remoteAddr net.Addr) <-chan struct{},
+	logger kitlog.Logger,
+) *boshHandler {
+	if cfg.requests <= cfg.hold {
+		cfg.requests = cfg.hold + 1 // a client must always be able to send data while requests are held
+	}
+	return &boshHandler{
+		cfg:      cfg,
+		startFn:  startFn,
+		logger:   logger,
+		sessions: make(map[string]*boshSession),
+	}
+}
+
+func (h *boshHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowedOrigin := h.setAllowedOrigin(w, r)
+
+	switch r.Method {
+	case http.MethodPost:
+		break
+
+	case http.MethodOptions:
+		if allowedOrigin {
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		}
+		return
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	rd := http.MaxBytesReader(w, r.Body, int64(h.cfg.maxStanzaSize))
+
+	body, err := xmppparser.New(rd, xmppparser.DefaultMode, h.cfg.maxStanzaSize).Parse()
+	if err != nil || body == nil || body.Name() != "body" || body.Attribute(stravaganza.Namespace) != httpBindNamespace {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rid, err := strconv.ParseInt(body.Attribute("rid"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sid := body.Attribute("sid")
+	if len(sid) == 0 {
+		h.createSession(w, r, body, rid)
+		return
+	}
+	h.mu.RLock()
+	bs := h.sessions[sid]
+	h.mu.RUnlock()
+
+	if bs == nil {
+		writeBOSHTerminate(w, "item-not-found")
+		return
+	}
+	h.processRequest(w, bs, body, rid)
+}
+
+// setAllowedOrigin sets CORS allowed origin header in case request origin is one of the configured allowed origins.
+func (h *boshHandler) setAllowedOrigin(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		return false
+	}
+	for _, allowed := range h.cfg.allowedOrigins {
+		switch allowed {
+		case "*":
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			return true
+		case origin:
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			return true
+		}
+	}
+	return false
+}
+
+func (h *boshHandler) closeSessions() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, bs := range h.sessions {
+		_ = bs.tr.Close()
+	}
+}
+
+func (h *boshHandler) createSession(w http.ResponseWriter, r *http.Request, body stravaganza.Element, rid int64) {
+	domain := body.Attribute(stravaganza.To)
+	if len(domain) == 0 {
+		writeBOSHTerminate(w, "improper-addressing")
+		return
+	}
+	bs := newBOSHSession(uuid.New().String(), transport.NewBOSHTransport(h.cfg.inactivity, peerCertificates(r)), domain, rid)
+	bs.wait = h.cfg.wait
+	bs.hold = h.cfg.hold
+
+	if wait, err := strconv.Atoi(body.Attribute("wait")); err == nil && wait >= 0 && time.Duration(wait)*time.Second < bs.wait {
+		bs.wait = time.Duration(wait) * time.Second
+	}
+	if hold, err := strconv.Atoi(body.Attribute("hold")); err == nil && hold >= 0 && hold < bs.hold {
+		bs.hold = hold
+	}
+	var remoteAddr net.Addr
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		remoteAddr = addr
+	}
+	h.mu.Lock()
+	h.sessions[bs.sid] = bs
+	h.mu.Unlock()
+
+	go func() {
+		if doneCh := h.startFn(bs.tr, remoteAddr); doneCh != nil {
+			<-doneCh
+		}
+		_ = bs.tr.Close()
+
+		h.mu.Lock()
+		delete(h.sessions, bs.sid)
+		h.mu.Unlock()
+	}()
+
+	if err := h.feed(bs, streamOpenBody(bs.domain, body.Attribute("xmpp:version")), body.AllChildren()); err != nil {
+		writeBOSHTerminate(w, "internal-server-error")
+		return
+	}
+	h.respond(w, bs, rid, false, []stravaganza.Attribute{
+		{Label: "sid", Value: bs.sid},
+		{Label: "wait", Value: strconv.Itoa(int(bs.wait.Seconds()))},
+		{Label: "requests", Value: strconv.Itoa(h.cfg.requests)},
+		{Label: "hold", Value: strconv.Itoa(bs.hold)},
+		{Label: "inactivity", Value: strconv.Itoa(int(h.cfg.inactivity.Seconds()))},
+		{Label: "ver", Value: boshVersion},
+		{Label: stravaganza.From, Value: bs.domain},
+		{Label: "xmpp:version", Value: "1.0"},
+		{Label: "xmlns:xmpp", Value: xboshNamespace},
+		{Label: stravaganza.StreamNamespace, Value: streamNamespace},
+	})
+}
+
+func (h *boshHandler) processRequest(w http.ResponseWriter, bs *boshSession, body stravaganza.Element, rid int64) {
+	cachedResp, ok := bs.enqueue(rid, h.cfg.requests)
+	switch {
+	case !ok:
+		level.Warn(h.logger).Log("msg", "terminating BOSH session with out of window request", "sid", bs.sid, "rid", rid)
+
+		_ = bs.tr.Close()
+		writeBOSHTerminate(w, "item-not-found")
+		return
+
+	case cachedResp != nil:
+		writeBOSHResponse(w, cachedResp) // retransmitted request
+		return
+	}
+	if !bs.awaitTurn(rid, h.cfg.wait) {
+		level.Warn(h.logger).Log("msg", "terminating BOSH session with missing preceding request", "sid", bs.sid, "rid", rid)
+
+		_ = bs.tr.Close()
+		writeBOSHTerminate(w, "item-not-found")
+		return
+	}
+	var openElem stravaganza.Element
+	if body.Attribute("xmpp:restart") == "true" {
+		openElem = streamOpenBody(bs.domain, "1.0")
+	}
+	err := h.feed(bs, openElem, body.AllChildren())
+	bs.advance(rid)
+	if err != nil {
+		writeBOSHTerminate(w, "")
+		return
+	}
+	if body.Attribute(stravaganza.Type) == "terminate" {
+		closeElem := stravaganza.NewBuilder("body").
+			WithAttribute(stravaganza.Namespace, httpBindNamespace).
+			WithAttribute(stravaganza.Type, "terminate").
+			Build()
+		_ = h.feed(bs, closeElem, nil)
+
+		writeBOSHTerminate(w, "")
+		return
+	}
+	h.respond(w, bs, rid, true, nil)
+}
+
+func (h *boshHandler) feed(bs *boshSession, openElem stravaganza.Element, payload []stravaganza.Element) error {
+	buf := bytes.NewBuffer(nil)
+	if openElem != nil {
+		_ = openElem.ToXML(buf, true)
+	}
+	for _, elem := range payload {
+		_ = elem.ToXML(buf, true)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return bs.tr.Feed(buf.Bytes())
+}
+
+// respond holds the request until there's outgoing data to deliver, wait time elapses
+// or a newer request must be held instead.
+func (h *boshHandler) respond(w http.ResponseWriter, bs *boshSession, rid int64, releasable bool, attrs []stravaganza.Attribute) {
+	bs.tr.Hold()
+	defer bs.tr.Release()
+
+	var releaseCh chan struct{}
+	if releasable {
+		releaseCh = bs.acquire()
+		defer bs.release(releaseCh)
+	}
+	tm := time.NewTimer(bs.wait)
+	defer tm.Stop()
+
+	var terminated bool
+	select {
+	case <-bs.tr.Pending():
+	case <-bs.tr.Done():
+		terminated = true
+	case <-tm.C:
+	case <-releaseCh:
+	}
+	payload := bs.tr.TakeOutput()
+
+	if terminated {
+		var condition string
+		if bytes.Contains(payload, []byte("<stream:error")) {
+			condition = "remote-stream-error"
+		}
+		attrs = append(attrs,
+			stravaganza.Attribute{Label: stravaganza.Type, Value: "terminate"},
+			stravaganza.Attribute{Label: "condition", Value: condition},
+		)
+	}
+	resp := boshBody(attrs, payload)
+	bs.cacheResponse(rid, resp, h.cfg.requests)
+
+	writeBOSHResponse(w, resp)
+}
+
+func streamOpenBody(domain, version string) stravaganza.Element {
+	return stravaganza.NewBuilder("body").
+		WithAttribute(stravaganza.Namespace, httpBindNamespace).
+		WithAttribute(stravaganza.To, domain).
+		WithAttribute(stravaganza.Version, version).
+		Build()
+}
+
+func writeBOSHTerminate(w http.ResponseWriter, condition string) {
+	writeBOSHResponse(w, boshBody([]stravaganza.Attribute{
+		{Label: stravaganza.Type, Value: "terminate"},
+		{Label: "condition", Value: condition},
+	}, nil))
+}
+
+func writeBOSHResponse(w http.ResponseWriter, resp []byte) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	_, _ = w.Write(resp)
+}
+
+func boshBody(attrs []stravaganza.Attribute, payload []byte) []byte {
+	body := stravaganza.NewBuilder("body").
+		WithAttribute(stravaganza.Namespace, httpBindNamespace).
+		WithAttributes(attrs...).
+		Build()
+
+	buf := bytes.NewBuffer(nil)
+	_ = body.ToXML(buf, false)
+	buf.Write(payload)
+	buf.WriteString("</body>")
+	return buf.Bytes()
+}
+
+func peerCertificates(r *http.Request) []*x509.Certificate {
+	if r.TLS == nil {
+		return nil
+	}
+	return r.TLS.PeerCertificates
+}
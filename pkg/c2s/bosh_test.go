@@ -0,0 +1,201 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	xmppparser "github.com/ortuman/jackal/pkg/parser"
+	"github.com/ortuman/jackal/pkg/transport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBOSHHandler_Session(t *testing.T) {
+	// given
+	h := newBOSHHandler(boshCfg{
+		wait:          time.Second,
+		hold:          1,
+		requests:      2,
+		inactivity:    time.Minute,
+		maxStanzaSize: 4096,
+	}, echoBOSHStream, kitlog.NewNopLogger())
+
+	// when
+	createResp := postBOSH(h, `<body rid='100' to='jackal.im' wait='60' hold='1' xmpp:version='1.0' xmlns='http://jabber.org/protocol/httpbind' xmlns:xmpp='urn:xmpp:xbosh'/>`)
+	sid := createResp.Attribute("sid")
+
+	msgResp := postBOSH(h, `<body rid='101' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'><message to='noelia@jackal.im'/></body>`)
+	replayResp := postBOSH(h, `<body rid='101' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.NotEmpty(t, sid)
+	require.Equal(t, "1", createResp.Attribute("wait")) // capped by configured wait
+	require.Equal(t, "2", createResp.Attribute("requests"))
+	require.Equal(t, "jackal.im", createResp.Attribute(stravaganza.From))
+	require.NotNil(t, createResp.Child("stream:features"))
+
+	require.Empty(t, msgResp.Attribute(stravaganza.Type))
+	require.NotNil(t, msgResp.Child("message"))
+	require.Equal(t, "jabber:client", msgResp.Child("message").Attribute(stravaganza.Namespace))
+
+	require.Equal(t, msgResp.String(), replayResp.String()) // retransmission answered from cache
+}
+
+func TestBOSHHandler_Terminate(t *testing.T) {
+	// given
+	h := newBOSHHandler(boshCfg{
+		wait:          time.Second,
+		hold:          1,
+		requests:      2,
+		inactivity:    time.Minute,
+		maxStanzaSize: 4096,
+	}, echoBOSHStream, kitlog.NewNopLogger())
+
+	createResp := postBOSH(h, `<body rid='100' to='jackal.im' xmpp:version='1.0' xmlns='http://jabber.org/protocol/httpbind' xmlns:xmpp='urn:xmpp:xbosh'/>`)
+	sid := createResp.Attribute("sid")
+
+	// when
+	termResp := postBOSH(h, `<body rid='101' sid='`+sid+`' type='terminate' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	time.Sleep(time.Millisecond * 100) // wait for session to be unregistered
+
+	unknownResp := postBOSH(h, `<body rid='102' sid='`+sid+`' xmlns='http://jabber.org/protocol/httpbind'/>`)
+
+	// then
+	require.Equal(t, "terminate", termResp.Attribute(stravaganza.Type))
+
+	require.Equal(t, "terminate", unknownResp.Attribute(stravaganza.Type))
+	require.Equal(t, "item-not-found", unknownResp.Attribute("condition"))
+}
+
+func TestBOSHHandler_BadRequest(t *testing.T) {
+	// given
+	h := newBOSHHandler(boshCfg{maxStanzaSize: 4096}, echoBOSHStream, kitlog.NewNopLogger())
+
+	// when
+	badBodyRec := httptest.NewRecorder()
+	h.ServeHTTP(badBodyRec, httptest.NewRequest(http.MethodPost, "/http-bind", strings.NewReader(`<message/>`)))
+
+	getRec := httptest.NewRecorder()
+	h.ServeHTTP(getRec, httptest.NewRequest(http.MethodGet, "/http-bind", nil))
+
+	// then
+	require.Equal(t, http.StatusBadRequest, badBodyRec.Code)
+	require.Equal(t, http.StatusMethodNotAllowed, getRec.Code)
+}
+
+func TestBOSHSession_RequestsWindow(t *testing.T) {
+	// given
+	bs := newBOSHSession("s1", transport.NewBOSHTransport(0, nil), "jackal.im", 100)
+	bs.cacheResponse(100, []byte("<body/>"), 2)
+
+	// when
+	_, ok102 := bs.enqueue(102, 2)
+	_, ok103 := bs.enqueue(103, 2) // out of window
+	_, dupOK := bs.enqueue(102, 2) // already pending
+	cachedResp, cachedOK := bs.enqueue(100, 2)
+
+	turnCh := make(chan bool, 1)
+	go func() { turnCh <- bs.awaitTurn(102, time.Second) }()
+
+	var turnBeforeAdvance bool
+	select {
+	case <-turnCh:
+		turnBeforeAdvance = true
+	case <-time.After(time.Millisecond * 50):
+	}
+	_, ok101 := bs.enqueue(101, 2)
+	bs.advance(101)
+
+	// then
+	require.True(t, ok102)
+	require.False(t, ok103)
+	require.False(t, dupOK)
+	require.True(t, cachedOK)
+	require.Equal(t, []byte("<body/>"), cachedResp)
+
+	require.True(t, ok101)
+	require.False(t, turnBeforeAdvance) // request 102 waits for 101 to be processed
+	require.True(t, <-turnCh)
+}
+
+func TestBOSHHandler_AllowedOrigins(t *testing.T) {
+	// given
+	h := newBOSHHandler(boshCfg{
+		allowedOrigins: []string{"https://web.jackal.im"},
+		maxStanzaSize:  4096,
+	}, echoBOSHStream, kitlog.NewNopLogger())
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/http-bind", nil)
+		req.Header.Set("Origin", origin)
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// when
+	allowedRec := preflight("https://web.jackal.im")
+	deniedRec := preflight("https://evil.example")
+
+	// then
+	require.Equal(t, "https://web.jackal.im", allowedRec.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "POST, OPTIONS", allowedRec.Header().Get("Access-Control-Allow-Methods"))
+
+	require.Empty(t, deniedRec.Header().Get("Access-Control-Allow-Origin"))
+	require.Empty(t, deniedRec.Header().Get("Access-Control-Allow-Methods"))
+}
+
+// echoBOSHStream replies stream features to every stream open body and echoes back any other element.
+func echoBOSHStream(tr transport.Transport, _ net.Addr) <-chan struct{} {
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+
+	pr := xmppparser.New(tr, xmppparser.DefaultMode, 4096)
+	for {
+		elem, err := pr.Parse()
+		if err != nil {
+			return doneCh
+		}
+		if elem.Name() == "body" {
+			if elem.Attribute(stravaganza.Type) == "terminate" {
+				return doneCh
+			}
+			elem = stravaganza.NewBuilder("stream:features").Build()
+		} else {
+			elem = stravaganza.NewBuilderFromElement(elem).
+				WithAttribute(stravaganza.Namespace, "jabber:client").
+				Build()
+		}
+		_ = elem.ToXML(tr, true)
+		_ = tr.Flush()
+	}
+}
+
+func postBOSH(h http.Handler, body string) stravaganza.Element {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/http-bind", strings.NewReader(body)))
+
+	elem, _ := xmppparser.New(bytes.NewReader(rec.Body.Bytes()), xmppparser.DefaultMode, 0).Parse()
+	return elem
+}
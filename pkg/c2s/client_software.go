@@ -0,0 +1,88 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jackal-xmpp/stravaganza"
+)
+
+// ClientSoftwareInfoKey is the C2S stream info key under which the normalized client software label is stored.
+const ClientSoftwareInfoKey = "client:software"
+
+const (
+	capsNamespace = "http://jabber.org/protocol/caps"
+
+	otherClientSoftware = "other"
+)
+
+// knownClientSoftware maps well known client software tokens to its normalized label.
+// The set of resulting labels is deliberately bounded to keep metrics cardinality under control.
+var knownClientSoftware = map[string]string{
+	"beagle":        "beagle",
+	"blabber":       "blabber",
+	"chatsecure":    "chatsecure",
+	"cheogram":      "cheogram",
+	"conversations": "conversations",
+	"converse":      "converse",
+	"conversejs":    "converse",
+	"dino":          "dino",
+	"gajim":         "gajim",
+	"jitsi":         "jitsi",
+	"kaidan":        "kaidan",
+	"libpurple":     "pidgin",
+	"mcabber":       "mcabber",
+	"monal":         "monal",
+	"movim":         "movim",
+	"pidgin":        "pidgin",
+	"poezio":        "poezio",
+	"profanity":     "profanity",
+	"psi":           "psi",
+	"quicksy":       "quicksy",
+	"siskin":        "siskin",
+	"smack":         "smack",
+	"swift":         "swift",
+	"tkabber":       "tkabber",
+	"xabber":        "xabber",
+	"yaxim":         "yaxim",
+}
+
+// normalizeClientSoftware maps a client software hint (either an entity capabilities node or a bound resource)
+// into one of the known client software labels, or "other" in case it can't be recognized.
+func normalizeClientSoftware(hint string) string {
+	tokens := strings.FieldsFunc(strings.ToLower(hint), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, tk := range tokens {
+		if label, ok := knownClientSoftware[tk]; ok {
+			return label
+		}
+	}
+	return otherClientSoftware
+}
+
+func capsClientSoftware(presence *stravaganza.Presence) (string, bool) {
+	c := presence.ChildNamespace("c", capsNamespace)
+	if c == nil {
+		return "", false
+	}
+	node := c.Attribute("node")
+	if len(node) == 0 {
+		return "", false
+	}
+	return normalizeClientSoftware(node), true
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSoftware_Normalize(t *testing.T) {
+	var tcs = map[string]struct {
+		hint  string
+		label string
+	}{
+		"Conversations resource":   {hint: "Conversations.aBcD", label: "conversations"},
+		"Conversations caps node":  {hint: "http://conversations.im", label: "conversations"},
+		"Gajim caps node":          {hint: "https://gajim.org", label: "gajim"},
+		"Dino resource":            {hint: "dino.7f3a58fc", label: "dino"},
+		"Monal caps node":          {hint: "https://monal-im.org/", label: "monal"},
+		"Psi+ resource":            {hint: "Psi+", label: "psi"},
+		"libpurple caps node":      {hint: "http://pidgin.im/", label: "pidgin"},
+		"libpurple resource":       {hint: "libpurple", label: "pidgin"},
+		"Converse.js caps node":    {hint: "https://conversejs.org", label: "converse"},
+		"Converse.js resource":     {hint: "converse.js-1234", label: "converse"},
+		"Already normalized label": {hint: "siskin", label: "siskin"},
+		"Unknown client":           {hint: "yard", label: "other"},
+		"Server generated":         {hint: "2a1bdcb0-0c41-4e99-8d1c-c2a317a7d7b9", label: "other"},
+		"Empty hint":               {hint: "", label: "other"},
+	}
+	for tn, tc := range tcs {
+		t.Run(tn, func(t *testing.T) {
+			require.Equal(t, tc.label, normalizeClientSoftware(tc.hint))
+		})
+	}
+}
+
+func TestClientSoftware_Caps(t *testing.T) {
+	// given
+	pr0, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("c").
+				WithAttribute(stravaganza.Namespace, capsNamespace).
+				WithAttribute("node", "https://gajim.org").
+				Build(),
+		).
+		BuildPresence()
+
+	pr1, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		BuildPresence()
+
+	// when
+	label0, ok0 := capsClientSoftware(pr0)
+	_, ok1 := capsClientSoftware(pr1)
+
+	// then
+	require.True(t, ok0)
+	require.Equal(t, "gajim", label0)
+	require.False(t, ok1)
+}
@@ -14,7 +14,11 @@
 
 package c2s
 
-import "time"
+import (
+	"time"
+
+	"github.com/ortuman/jackal/pkg/cluster/distratelimit"
+)
 
 // ListenersConfig defines a set of C2S listener configurations.
 type ListenersConfig []ListenerConfig
@@ -30,9 +34,69 @@ type ListenerConfig struct {
 	// Transport specifies the type of transport used for incoming connections.
 	Transport string `fig:"transport" default:"socket"`
 
+	// WebSocket contains WebSocket transport configuration (RFC 7395).
+	// Only applies when transport is set to 'websocket'.
+	WebSocket struct {
+		// Path defines the HTTP path at which WebSocket connections are upgraded.
+		Path string `fig:"path" default:"/xmpp-websocket"`
+
+		// KeepAliveTimeout, if greater than zero, overrides the listener keep alive timeout for WebSocket
+		// connections, as intermediate HTTP proxies usually enforce their own idle timeouts.
+		KeepAliveTimeout time.Duration `fig:"keep_alive_timeout"`
+	} `fig:"websocket"`
+
+	// BOSH contains BOSH transport configuration (XEP-0206).
+	// Only applies when transport is set to 'bosh', in which case connections are served by the HTTP server.
+	BOSH struct {
+		// Path defines the HTTP path at which BOSH requests are served.
+		Path string `fig:"path" default:"/http-bind"`
+
+		// Wait defines the maximum amount of time a BOSH request may be held waiting for outgoing data.
+		Wait time.Duration `fig:"wait" default:"60s"`
+
+		// Hold defines the maximum number of BOSH requests that may be held at once.
+		Hold int `fig:"hold" default:"1"`
+
+		// Requests defines the maximum number of simultaneous requests a client may have in flight.
+		Requests int `fig:"requests" default:"2"`
+
+		// Inactivity defines the maximum amount of time a BOSH session may go without held requests
+		// before being considered disconnected.
+		Inactivity time.Duration `fig:"inactivity" default:"60s"`
+
+		// AllowedOrigins contains the origins allowed to issue cross-origin BOSH requests from web browsers.
+		// A "*" entry allows any origin. If empty, cross-origin requests are not allowed.
+		AllowedOrigins []string `fig:"allowed_origins"`
+	} `fig:"bosh"`
+
 	// DirectTLS, if true, tls.Listen will be used as network listener.
 	DirectTLS bool `fig:"direct_tls"`
 
+	// TLSHandshakeFailure contains socket TLS handshake failures handling configuration,
+	// for both direct TLS and STARTTLS secured connections.
+	TLSHandshakeFailure struct {
+		// LogLevel is the level handshake failures are logged with.
+		// Valid values are 'debug', 'info', 'warn' and 'off'.
+		LogLevel string `fig:"log_level" default:"warn"`
+
+		// Block contains temporary blocking of remote addresses with repeated handshake failures.
+		Block struct {
+			// Threshold is the number of handshake failures within Window after which a remote address
+			// is blocked. Zero value disables blocking.
+			Threshold int `fig:"threshold"`
+
+			// Window is the period of time handshake failures are accounted within.
+			Window time.Duration `fig:"window" default:"1m"`
+
+			// Duration is the period of time connections from a blocked address are rejected.
+			Duration time.Duration `fig:"duration" default:"10m"`
+		} `fig:"block"`
+	} `fig:"tls_handshake_failure"`
+
+	// ProxyProtocol, if true, a PROXY protocol v2 header will be required at the beginning of every accepted
+	// connection, and the client address it conveys will be used as connection remote address.
+	ProxyProtocol bool `fig:"proxy_protocol"`
+
 	// SASL contains authentication related configuration.
 	SASL struct {
 		// Mechanisms contains enabled SASL mechanisms.
@@ -43,19 +107,77 @@ type ListenerConfig struct {
 			Address  string `fig:"address"`
 			IsSecure bool   `fig:"is_secure"`
 		} `fig:"external"`
+
+		// UnknownMechanism contains unadvertised SASL mechanism attempts handling configuration.
+		UnknownMechanism struct {
+			// Track, if true, unknown mechanism attempts will be logged and reported as a security metric.
+			Track bool `fig:"track"`
+
+			// CountAsFailure, if true, unknown mechanism attempts will be accounted as failed authentications,
+			// leading to stream disconnection once the maximum number of authentication failures is reached.
+			CountAsFailure bool `fig:"count_as_failure"`
+		} `fig:"unknown_mechanism"`
+
+		// RateLimit contains cluster-wide authentication attempts rate limit configuration.
+		// Attempts are accounted per remote address and shared among all cluster instances
+		// through the cluster KV store. When not running in cluster mode limits are enforced per instance.
+		RateLimit distratelimit.Config `fig:"rate_limit"`
 	} `fig:"sasl"`
 
+	// Shaper, if set, is the name of the shaper applied to every connection accepted by the listener,
+	// regardless of the connecting JID. This binding is re-applied to new connections on configuration reload.
+	Shaper string `fig:"shaper"`
+
 	// CompressionLevel is the compression level that may be applied to the stream.
 	// Valid values are 'default', 'best', 'speed' and 'no_compression'.
 	CompressionLevel string `fig:"compression_level" default:"default"`
 
+	// ReadBufferSize is the size in bytes of the buffer incoming connection data is read through.
+	ReadBufferSize int `fig:"read_buffer_size" default:"4096"`
+
+	// MaxPooledWriteBufferSize is the maximum size in bytes of a write buffer that can be returned to the
+	// shared buffer pool once flushed. Larger buffers are discarded to avoid retaining memory.
+	MaxPooledWriteBufferSize int `fig:"max_pooled_write_buffer_size" default:"65536"`
+
+	// FlushCoalescingDelay, if greater than zero, is the maximum time outgoing data may be held before being
+	// flushed, so that stanzas sent in a burst are written to the socket together. Zero flushes immediately.
+	FlushCoalescingDelay time.Duration `fig:"flush_coalescing_delay"`
+
 	// ResourceConflict defines the which rule should be applied in a resource conflict is detected.
 	// Valid values are `override`, `disallow` and `terminate_old`.
 	ResourceConflict string `fig:"resource_conflict" default:"terminate_old"`
 
+	// ResourceBinding contains resource binding related configuration.
+	ResourceBinding struct {
+		// MaxLength defines the maximum length in bytes a client suggested resource may have.
+		MaxLength int `fig:"max_length" default:"1023"`
+
+		// DisallowedChars contains the set of characters a client suggested resource cannot contain.
+		DisallowedChars string `fig:"disallowed_chars"`
+
+		// ForceServerGenerated, if true, client suggested resources will be ignored
+		// and replaced by a server generated one.
+		ForceServerGenerated bool `fig:"force_server_generated"`
+	} `fig:"resource_binding"`
+
+	// BareJIDFallbackIQNamespaces contains the set of IQ namespaces that, when addressed to an unavailable
+	// full JID, will be handled on behalf of the account bare JID instead of replying with a service-unavailable error.
+	BareJIDFallbackIQNamespaces []string `fig:"bare_jid_fallback_iq_namespaces"`
+
+	// OverwriteSpoofedFrom tells whether a stanza 'from' address not matching the authenticated JID
+	// should be overwritten instead of closing the stream with an invalid-from error.
+	OverwriteSpoofedFrom bool `fig:"overwrite_spoofed_from"`
+
 	// MaxStanzaSize is the maximum size a listener incoming stanza may have.
 	MaxStanzaSize int `fig:"max_stanza_size" default:"524288"`
 
+	// MaxStanzaDepth is the maximum element nesting depth a listener incoming stanza may have.
+	MaxStanzaDepth int `fig:"max_stanza_depth" default:"64"`
+
+	// MaxAssemblyBufferSize is the maximum number of unparsed bytes a listener connection may buffer
+	// while assembling an incoming stanza. Zero means no limit other than max stanza size.
+	MaxAssemblyBufferSize int `fig:"max_assembly_buffer_size"`
+
 	// ConnectTimeout defines connection timeout.
 	ConnectTimeout time.Duration `fig:"conn_timeout" default:"3s"`
 
@@ -63,9 +185,33 @@ type ListenerConfig struct {
 	AuthenticateTimeout time.Duration `fig:"auth_timeout" default:"10s"`
 
 	// KeepAliveTimeout defines the maximum amount of time that an inactive connection
-	// would be considered alive.
+	// would be considered alive. WebSocket connections may override it, while BOSH sessions
+	// are governed by their inactivity setting.
 	KeepAliveTimeout time.Duration `fig:"keep_alive_timeout" default:"3m"`
 
 	// RequestTimeout defines C2S stream request timeout.
 	RequestTimeout time.Duration `fig:"req_timeout" default:"15s"`
+
+	// WhitespaceKeepAlive contains whitespace keepalive handling configuration.
+	WhitespaceKeepAlive struct {
+		// UpdateLastActivity, if true, accepted whitespace keepalives will be reported as stream activity.
+		UpdateLastActivity bool `fig:"update_last_activity"`
+
+		// MaxRate defines the maximum number of whitespace keepalives per second
+		// that will be accepted. Exceeding ones will be throttled.
+		MaxRate float64 `fig:"max_rate" default:"1"`
+
+		// Burst defines the maximum number of whitespace keepalives that can be accepted at once.
+		Burst int `fig:"burst" default:"5"`
+	} `fig:"whitespace_keep_alive"`
+
+	// InFlightIQ contains server-processed IQ accounting configuration.
+	InFlightIQ struct {
+		// Max defines the maximum number of server-processed IQs a session may have awaiting a response.
+		// Exceeding ones will be replied with a resource-constraint error. A zero value means no limit.
+		Max int `fig:"max"`
+
+		// Timeout defines the amount of time after which an unanswered IQ stops being accounted as in-flight.
+		Timeout time.Duration `fig:"timeout" default:"30s"`
+	} `fig:"in_flight_iq"`
 }
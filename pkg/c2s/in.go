@@ -19,6 +19,8 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -32,9 +34,11 @@ import (
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/auth"
+	"github.com/ortuman/jackal/pkg/cluster/distratelimit"
 	"github.com/ortuman/jackal/pkg/cluster/instance"
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/component"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
@@ -44,9 +48,12 @@ import (
 	"github.com/ortuman/jackal/pkg/router/stream"
 	xmppsession "github.com/ortuman/jackal/pkg/session"
 	"github.com/ortuman/jackal/pkg/shaper"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/tlsticket"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/ortuman/jackal/pkg/transport/compress"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"golang.org/x/time/rate"
 )
 
 type state uint32
@@ -66,6 +73,9 @@ const (
 	maxAuthAborted = 1
 )
 
+// RemoteIPInfoKey is the C2S stream info key under which the client remote IP address is stored.
+const RemoteIPInfoKey = "remote:ip"
+
 var (
 	disconnectTimeout = time.Second * 5
 )
@@ -82,10 +92,44 @@ type inCfg struct {
 	authenticateTimeout time.Duration
 	reqTimeout          time.Duration
 	maxStanzaSize       int
+	maxStanzaDepth      int
+	maxAsmBufSize       int
+	overwriteFrom       bool
 	compressionLevel    compress.Level
 	resConflict         resourceConflict
+	resBinding          resBindingCfg
+	iqBareFallback      []string
 	useTLS              bool
 	tlsConfig           *tls.Config
+	ticketKeys          *tlsticket.Manager
+	wsKeepAlive         wsKeepAliveCfg
+	iqInFlight          iqInFlightCfg
+	unknownMech         unknownMechCfg
+	draining            bool
+	authLimiter         *distratelimit.Limiter
+	tlsFailureFn        func(remoteIP string, err error)
+}
+
+type resBindingCfg struct {
+	maxLength            int
+	disallowedChars      string
+	forceServerGenerated bool
+}
+
+type wsKeepAliveCfg struct {
+	updateLastActivity bool
+	maxRate            rate.Limit
+	burst              int
+}
+
+type iqInFlightCfg struct {
+	max     int
+	timeout time.Duration
+}
+
+type unknownMechCfg struct {
+	track          bool
+	countAsFailure bool
 }
 
 type authState struct {
@@ -110,14 +154,26 @@ type inC2S struct {
 	comps        components
 	mods         modules
 	resMng       resourcemanager.Manager
+	rep          repository.AccountSettings
 	session      session
 	shapers      shaper.Shapers
+	tier         string
+	origin       geoip.Origin
+	remoteIP     net.IP
 	hk           *hook.Hooks
 	logger       kitlog.Logger
 	rq           *runqueue.RunQueue
 	discTm       *time.Timer
+	autoPrTm     *time.Timer
 	doneCh       chan struct{}
 	sendDisabled bool
+	wsLim        *rate.Limiter
+	stzLim       *shaper.StanzaLimiter
+	heldCh       chan struct{}
+	iqsInFlight  *inFlightIQs
+	budgetHost   string
+	swLabel      string
+	tlsPending   bool
 
 	mu    sync.RWMutex
 	state state
@@ -130,55 +186,81 @@ type inC2S struct {
 func newInC2S(
 	cfg inCfg,
 	tr transport.Transport,
+	remoteIP net.IP,
+	origin geoip.Origin,
 	authenticators []auth.Authenticator,
 	hosts *host.Hosts,
 	router router.Router,
 	comps *component.Components,
 	mods *module.Modules,
 	resMng resourcemanager.Manager,
+	rep repository.AccountSettings,
 	shapers shaper.Shapers,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) (*inC2S, error) {
-	// set default rate limiter
-	rLim := shapers.DefaultC2S().RateLimiter()
-	if err := tr.SetReadRateLimiter(rLim); err != nil {
+	// set default rate limiters
+	if err := shapers.DefaultC2S().ApplyRateLimiters(tr); err != nil {
 		return nil, err
 	}
 	// create session
 	id := nextStreamID()
 
 	sLogger := kitlog.With(logger, "id", id)
+	if remoteIP != nil {
+		sLogger = kitlog.With(sLogger, "remote_ip", remoteIP.String())
+	}
+
+	inf := c2smodel.NewInfoMap()
+	if remoteIP != nil {
+		inf.SetString(RemoteIPInfoKey, remoteIP.String())
+	}
+	if !origin.IsZero() {
+		// tag connection origin
+		sLogger = kitlog.With(sLogger, "geo_country", origin.Country, "geo_asn", origin.ASN)
+
+		shaper.SetOriginInfo(inf, origin)
+	}
 	session := xmppsession.New(
 		xmppsession.C2SSession,
 		id.String(),
 		tr,
 		hosts,
 		xmppsession.Config{
-			MaxStanzaSize: cfg.maxStanzaSize,
+			MaxStanzaSize:         cfg.maxStanzaSize,
+			MaxStanzaDepth:        cfg.maxStanzaDepth,
+			MaxAssemblyBufferSize: cfg.maxAsmBufSize,
+			OverwriteSpoofedFrom:  cfg.overwriteFrom,
 		},
 		sLogger,
 	)
 	// init stream
 	stm := &inC2S{
-		id:      id,
-		cfg:     cfg,
-		tr:      tr,
-		inf:     c2smodel.NewInfoMap(),
-		session: session,
-		authSt:  authState{authenticators: authenticators},
-		hosts:   hosts,
-		router:  router,
-		comps:   comps,
-		mods:    mods,
-		resMng:  resMng,
-		shapers: shapers,
-		rq:      runqueue.New(id.String()),
-		doneCh:  make(chan struct{}),
-		state:   inConnecting,
-		hk:      hk,
-		logger:  sLogger,
-	}
+		id:          id,
+		cfg:         cfg,
+		tr:          tr,
+		inf:         inf,
+		session:     session,
+		authSt:      authState{authenticators: authenticators},
+		hosts:       hosts,
+		router:      router,
+		comps:       comps,
+		mods:        mods,
+		resMng:      resMng,
+		rep:         rep,
+		shapers:     shapers,
+		origin:      origin,
+		remoteIP:    remoteIP,
+		rq:          runqueue.New(id.String()),
+		doneCh:      make(chan struct{}),
+		wsLim:       rate.NewLimiter(cfg.wsKeepAlive.maxRate, cfg.wsKeepAlive.burst),
+		state:       inConnecting,
+		iqsInFlight: newInFlightIQs(cfg.iqInFlight.max, cfg.iqInFlight.timeout),
+		hk:          hk,
+		logger:      sLogger,
+	}
+	session.SetWhitespaceKeepAliveHandler(stm.onWhitespaceKeepAlive)
+
 	if cfg.useTLS {
 		stm.flags.setSecured() // stream already secured
 	}
@@ -293,6 +375,8 @@ func (s *inC2S) Resume(ctx context.Context, jd *jid.JID, pr *stravaganza.Presenc
 	s.inf = c2smodel.NewInfoMapFromInfo(inf)
 	s.mu.Unlock()
 
+	s.setClientSoftware(normalizeClientSoftware(inf.String(ClientSoftwareInfoKey)))
+
 	s.session.SetFromJID(jd)
 
 	if err := s.bindC2S(ctx); err != nil {
@@ -369,6 +453,8 @@ func (s *inC2S) readLoop() {
 }
 
 func (s *inC2S) handleSessionResult(elem stravaganza.Element, sErr error) {
+	var heldCh chan struct{}
+
 	handledCh := make(chan struct{})
 	s.rq.Run(func() {
 		defer close(handledCh)
@@ -378,6 +464,8 @@ func (s *inC2S) handleSessionResult(elem stravaganza.Element, sErr error) {
 
 		switch {
 		case sErr == nil && elem != nil:
+			s.tlsPending = false // STARTTLS handshake succeeded
+
 			err := s.handleElement(ctx, elem)
 			if err != nil {
 				level.Warn(s.logger).Log("msg", "failed to process incoming C2S session element", "err", err)
@@ -387,8 +475,43 @@ func (s *inC2S) handleSessionResult(elem stravaganza.Element, sErr error) {
 		case sErr != nil:
 			s.handleSessionError(ctx, sErr)
 		}
+		heldCh, s.heldCh = s.heldCh, nil
 	})
 	<-handledCh
+
+	if heldCh != nil {
+		// do not read any further until stanza held back by the shaper gets processed
+		select {
+		case <-heldCh:
+		case <-s.doneCh:
+		}
+	}
+}
+
+func (s *inC2S) onWhitespaceKeepAlive() {
+	if !s.wsLim.Allow() {
+		reportWhitespaceKeepAlive(true)
+		return // whitespace flood: do not consider it as stream activity
+	}
+	s.rq.Run(func() {
+		// whitespace keepalives are charged to the stream shaper as the smallest possible stanza
+		if s.stzLim != nil && !s.stzLim.Allow(0) {
+			reportWhitespaceKeepAlive(true)
+			return
+		}
+		reportWhitespaceKeepAlive(false)
+
+		if !s.cfg.wsKeepAlive.updateLastActivity || s.getState() != inBinded {
+			return
+		}
+		ctx, cancel := s.requestContext()
+		defer cancel()
+
+		_, _ = s.runHook(ctx, hook.C2SStreamWhitespaceKeepAlive, &hook.C2SStreamInfo{
+			ID:  s.ID().String(),
+			JID: s.JID(),
+		})
+	})
 }
 
 func (s *inC2S) connTimeout() {
@@ -446,6 +569,19 @@ func (s *inC2S) handleConnecting(ctx context.Context, elem stravaganza.Element)
 	// open stream session
 	s.session.SetFromJID(s.JID())
 
+	// reserve a slot from host session budget
+	if len(s.budgetHost) == 0 {
+		if s.cfg.draining {
+			level.Info(s.logger).Log("msg", "rejected C2S stream: instance is draining")
+			return s.disconnect(ctx, streamerror.E(streamerror.SystemShutdown))
+		}
+		if !s.hosts.AcquireSession(s.Domain()) {
+			level.Info(s.logger).Log("msg", "host session budget exhausted", "domain", s.Domain())
+			return s.disconnect(ctx, streamerror.E(streamerror.ResourceConstraint))
+		}
+		s.budgetHost = s.Domain()
+	}
+
 	fb := stravaganza.NewBuilder("stream:features").
 		WithAttribute(stravaganza.StreamNamespace, streamNamespace).
 		WithAttribute(stravaganza.Version, "1.0")
@@ -504,7 +640,7 @@ func (s *inC2S) handleAuthenticating(ctx context.Context, elem stravaganza.Eleme
 		return err
 	}
 	if s.authSt.active.Authenticated() {
-		return s.finishAuthentication()
+		return s.finishAuthentication(ctx)
 	}
 	return nil
 }
@@ -531,6 +667,40 @@ func (s *inC2S) handleBinded(ctx context.Context, elem stravaganza.Element) erro
 }
 
 func (s *inC2S) processStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	delay, ok := s.shapeStanza()
+	if !ok {
+		level.Info(s.logger).Log("msg", "stanza rejected: shaper limit exceeded", "username", s.Username())
+		return s.sendStanzaError(ctx, stanzaerror.ResourceConstraint, stanza)
+	}
+	if delay > 0 {
+		s.holdStanza(stanza, delay)
+		return nil
+	}
+	return s.routeStanza(ctx, stanza)
+}
+
+// holdStanza defers stanza processing until delay elapses, without blocking the stream run queue.
+func (s *inC2S) holdStanza(stanza stravaganza.Stanza, delay time.Duration) {
+	heldCh := make(chan struct{})
+	time.AfterFunc(delay, func() {
+		s.rq.Run(func() {
+			defer close(heldCh)
+
+			if s.getState() != inBinded {
+				return
+			}
+			ctx, cancel := s.requestContext()
+			defer cancel()
+
+			if err := s.routeStanza(ctx, stanza); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to process shaped C2S stanza", "err", err)
+			}
+		})
+	})
+	s.heldCh = heldCh
+}
+
+func (s *inC2S) routeStanza(ctx context.Context, stanza stravaganza.Stanza) error {
 	toJID := stanza.ToJID()
 	if s.comps.IsComponentHost(toJID.Domain()) {
 		return s.comps.ProcessStanza(ctx, stanza)
@@ -570,7 +740,9 @@ func (s *inC2S) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
 		return nil // silently ignore
 	}
 	if s.mods.IsModuleIQ(iq) {
-		return s.mods.ProcessIQ(ctx, iq)
+		// addressed to the server or to a bare JID (including account's own one)...
+		// handle it on behalf of the account
+		return s.processModuleIQ(ctx, iq)
 	}
 	// run will route iq hook
 	hInf := &hook.C2SStreamInfo{
@@ -592,14 +764,21 @@ func (s *inC2S) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	}
 	targets, err := s.router.Route(ctx, outIQ)
 	switch err {
-	case router.ErrResourceNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, iq).Element())
+	case router.ErrResourceNotFound, router.ErrUserNotAvailable, router.ErrNotExistingAccount:
+		// addressed to an unavailable full JID (RFC 6121, 8.5.3.2.1)
+		if bareIQ := s.bareJIDFallbackIQ(outIQ); bareIQ != nil {
+			return s.processModuleIQ(ctx, bareIQ)
+		}
+		return s.sendStanzaError(ctx, stanzaerror.ServiceUnavailable, iq)
 
 	case router.ErrRemoteServerNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerNotFound, iq).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerNotFound, iq)
 
 	case router.ErrRemoteServerTimeout:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerTimeout, iq).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerTimeout, iq)
+
+	case router.ErrPolicyDenied:
+		return s.sendStanzaError(ctx, stanzaerror.NotAllowed, iq)
 
 	case nil:
 		_, err := s.runHook(ctx, hook.C2SStreamIQRouted, &hook.C2SStreamInfo{
@@ -614,6 +793,39 @@ func (s *inC2S) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	return nil
 }
 
+func (s *inC2S) processModuleIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	if s.iqsInFlight != nil && !s.iqsInFlight.acquire(iq.Attribute(stravaganza.ID)) {
+		level.Info(s.logger).Log("msg", "in-flight IQ limit reached", "username", s.Username())
+		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ResourceConstraint, iq).Element())
+	}
+	return s.mods.ProcessIQ(ctx, iq)
+}
+
+func (s *inC2S) bareJIDFallbackIQ(iq *stravaganza.IQ) *stravaganza.IQ {
+	if len(s.cfg.iqBareFallback) == 0 || !iq.ToJID().IsFullWithUser() || iq.ChildrenCount() == 0 {
+		return nil
+	}
+	ns := iq.AllChildren()[0].Attribute(stravaganza.Namespace)
+
+	var matched bool
+	for _, fallbackNS := range s.cfg.iqBareFallback {
+		if ns == fallbackNS {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil
+	}
+	bareIQ, err := stravaganza.NewBuilderFromElement(iq).
+		WithAttribute(stravaganza.To, iq.ToJID().ToBareJID().String()).
+		BuildIQ()
+	if err != nil || !s.mods.IsModuleIQ(bareIQ) {
+		return nil
+	}
+	return bareIQ
+}
+
 func (s *inC2S) processPresence(ctx context.Context, presence *stravaganza.Presence) error {
 	// run presence received hook
 	_, err := s.runHook(ctx, hook.C2SStreamPresenceReceived, &hook.C2SStreamInfo{
@@ -662,6 +874,10 @@ func (s *inC2S) processPresence(ctx context.Context, presence *stravaganza.Prese
 	matchesUserJID := s.JID().MatchesWithOptions(presence.ToJID(), jid.MatchesBare)
 	if matchesUserJID && (presence.IsAvailable() || presence.IsUnavailable()) {
 		s.setPresence(presence)
+
+		if label, ok := capsClientSoftware(presence); ok {
+			s.setClientSoftware(label)
+		}
 	}
 	// update cluster resource
 	return s.resMng.PutResource(ctx, s.getResource())
@@ -710,16 +926,19 @@ sendMsg:
 		goto sendMsg
 
 	case router.ErrNotExistingAccount:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, message).Element())
+		return s.bounceMessage(ctx, stanzaerror.ServiceUnavailable, message)
 
 	case router.ErrRemoteServerNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerNotFound, message).Element())
+		return s.bounceMessage(ctx, stanzaerror.RemoteServerNotFound, message)
 
 	case router.ErrRemoteServerTimeout:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerTimeout, message).Element())
+		return s.bounceMessage(ctx, stanzaerror.RemoteServerTimeout, message)
+
+	case router.ErrPolicyDenied:
+		return s.bounceMessage(ctx, stanzaerror.NotAllowed, message)
 
-	case router.ErrUserNotAvailable:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, message).Element())
+	case router.ErrUserNotAvailable, router.ErrStanzaBounced:
+		return s.bounceMessage(ctx, stanzaerror.ServiceUnavailable, message)
 
 	case nil:
 		_, err = s.runHook(ctx, hook.C2SStreamMessageRouted, &hook.C2SStreamInfo{
@@ -737,7 +956,15 @@ sendMsg:
 }
 
 func (s *inC2S) handleSessionError(ctx context.Context, err error) {
+	if s.tlsPending {
+		// no element could be read after STARTTLS proceed: TLS handshake failed
+		s.tlsPending = false
+		if s.cfg.tlsFailureFn != nil && s.remoteIP != nil {
+			s.cfg.tlsFailureFn(s.remoteIP.String(), err)
+		}
+	}
 	if errors.Is(err, xmppparser.ErrStreamClosedByPeer) {
+		_ = s.sendFinalElement(ctx, err)
 		_ = s.session.Close(ctx)
 	}
 	_ = s.close(ctx, err)
@@ -765,7 +992,7 @@ func (s *inC2S) unauthenticatedFeatures() []stravaganza.Element {
 		sb.WithAttribute(stravaganza.Namespace, saslNamespace)
 		for _, authenticator := range s.authSt.authenticators {
 			if authenticator.UsesChannelBinding() && !supportsCb {
-				continue // transport doesn't support channel binding (eg. TLS 1.3)
+				continue // transport doesn't support channel binding (eg. WebSocket)
 			}
 			sb.WithChild(
 				stravaganza.NewBuilder("mechanism").
@@ -787,15 +1014,16 @@ func (s *inC2S) authenticatedFeatures(ctx context.Context) ([]stravaganza.Elemen
 	compressionAvailable := isSocketTr && s.cfg.compressionLevel != compress.NoCompression
 
 	if !s.flags.isCompressed() && compressionAvailable {
-		compressionElem := stravaganza.NewBuilder("compression").
-			WithAttribute(stravaganza.Namespace, "http://jabber.org/features/compress").
-			WithChild(
+		cb := stravaganza.NewBuilder("compression").
+			WithAttribute(stravaganza.Namespace, "http://jabber.org/features/compress")
+		for _, m := range compress.Methods {
+			cb.WithChild(
 				stravaganza.NewBuilder("method").
-					WithText("zlib").
+					WithText(m.String()).
 					Build(),
-			).
-			Build()
-		features = append(features, compressionElem)
+			)
+		}
+		features = append(features, cb.Build())
 	}
 	// bind feature
 	bindElem := stravaganza.NewBuilder("bind").
@@ -835,9 +1063,14 @@ func (s *inC2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) e
 	); err != nil {
 		return err
 	}
-	s.tr.StartTLS(&tls.Config{
+	tlsCfg := &tls.Config{
 		Certificates: s.hosts.Certificates(),
-	}, false)
+	}
+	if s.cfg.ticketKeys != nil {
+		s.cfg.ticketKeys.Apply(tlsCfg)
+	}
+	s.tr.StartTLS(tlsCfg, false)
+	s.tlsPending = true
 
 	level.Info(s.logger).Log("msg", "secured C2S stream")
 
@@ -849,6 +1082,9 @@ func (s *inC2S) startAuthentication(ctx context.Context, elem stravaganza.Elemen
 	if elem.Attribute(stravaganza.Namespace) != saslNamespace {
 		return s.disconnect(ctx, streamerror.E(streamerror.InvalidNamespace))
 	}
+	if !s.allowAuthentication(ctx) {
+		return s.failAuthentication(ctx, &auth.SASLError{Reason: auth.TemporaryAuthFailure})
+	}
 	mechanism := elem.Attribute("mechanism")
 	for _, authenticator := range s.authSt.authenticators {
 		if authenticator.Mechanism() != mechanism {
@@ -862,15 +1098,48 @@ func (s *inC2S) startAuthentication(ctx context.Context, elem stravaganza.Elemen
 			return err
 		}
 		if s.authSt.active.Authenticated() {
-			return s.finishAuthentication()
+			return s.finishAuthentication(ctx)
 		}
 		s.setState(inAuthenticating)
 		return nil
 	}
 	// ...mechanism not found...
+	return s.rejectMechanism(ctx, mechanism)
+}
+
+func (s *inC2S) allowAuthentication(ctx context.Context) bool {
+	if s.cfg.authLimiter == nil || s.remoteIP == nil {
+		return true
+	}
+	allowed, err := s.cfg.authLimiter.Allow(ctx, s.remoteIP.String())
+	if errors.Is(err, distratelimit.ErrContention) {
+		// concurrent attempts racing on the same bucket... most likely a brute force burst
+		level.Warn(s.logger).Log("msg", "authentication rate limit contention")
+		return false
+	}
+	if err != nil {
+		// do not lock out clients because of cluster KV store failures
+		level.Warn(s.logger).Log("msg", "failed to check authentication rate limit", "err", err)
+		return true
+	}
+	if !allowed {
+		level.Warn(s.logger).Log("msg", "authentication rate limit exceeded")
+	}
+	return allowed
+}
+
+func (s *inC2S) rejectMechanism(ctx context.Context, mechanism string) error {
+	if s.cfg.unknownMech.track {
+		level.Warn(s.logger).Log("msg", "unknown SASL mechanism attempted", "mechanism", mechanism)
+		reportUnknownSASLMechanism()
+	}
+	saslErr := &auth.SASLError{Reason: auth.InvalidMechanism}
+	if s.cfg.unknownMech.countAsFailure {
+		return s.failAuthentication(ctx, saslErr)
+	}
 	failureElem := stravaganza.NewBuilder("failure").
 		WithAttribute(stravaganza.Namespace, saslNamespace).
-		WithChild(stravaganza.NewBuilder("invalid-mechanism").Build()).
+		WithChild(saslErr.Element()).
 		Build()
 	return s.sendElement(ctx, failureElem)
 }
@@ -883,18 +1152,21 @@ func (s *inC2S) continueAuthentication(ctx context.Context, elem stravaganza.Ele
 	return s.sendElement(ctx, elem)
 }
 
-func (s *inC2S) finishAuthentication() error {
+func (s *inC2S) finishAuthentication(ctx context.Context) error {
 	username := s.authSt.active.Username()
 
 	j, _ := jid.New(username, s.Domain(), "", true)
 	s.setJID(j)
 	s.flags.setAuthenticated()
 
-	// update rate limiter
+	// rebind session to its account tier shaper
+	if err := s.resolveTier(ctx); err != nil {
+		return err
+	}
 	if err := s.updateRateLimiter(); err != nil {
 		return err
 	}
-	level.Info(s.logger).Log("msg", "authenticated C2S stream", "username", username)
+	level.Info(s.logger).Log("msg", "authenticated C2S stream", "username", username, "tier", s.tier)
 
 	s.authSt.reset()
 	s.restartSession()
@@ -938,7 +1210,8 @@ func (s *inC2S) compress(ctx context.Context, elem stravaganza.Element) error {
 			Build()
 		return s.sendElement(ctx, failureElem)
 	}
-	if method.Text() != "zlib" {
+	cmpMethod, ok := compress.MethodFromString(method.Text())
+	if !ok {
 		failure := stravaganza.NewBuilder("failure").
 			WithAttribute(stravaganza.Namespace, compressNamespace).
 			WithChild(stravaganza.NewBuilder("unsupported-method").Build()).
@@ -952,10 +1225,10 @@ func (s *inC2S) compress(ctx context.Context, elem stravaganza.Element) error {
 		return err
 	}
 	// compress transport
-	s.tr.EnableCompression(s.cfg.compressionLevel)
+	s.tr.EnableCompression(cmpMethod, s.cfg.compressionLevel)
 	s.flags.setCompressed()
 
-	level.Info(s.logger).Log("msg", "compressed C2S stream", "username", s.Username())
+	level.Info(s.logger).Log("msg", "compressed C2S stream", "username", s.Username(), "method", cmpMethod)
 
 	s.restartSession()
 	return nil
@@ -972,7 +1245,7 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 		return err
 	}
 	// check is max session count has been reached
-	maxSessions := s.shapers.MatchingJID(s.JID()).MaxSessions
+	maxSessions := s.shapers.MatchingJIDOriginAndTier(s.JID(), s.origin, s.tier).MaxSessions
 	if len(rss) == maxSessions {
 		se := streamerror.E(streamerror.PolicyViolation)
 		se.ApplicationElement = stravaganza.NewBuilder("reached-max-session-count").
@@ -982,8 +1255,11 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 	}
 
 	var res string
-	if resElem := bind.Child("resource"); resElem != nil {
+	if resElem := bind.Child("resource"); resElem != nil && !s.cfg.resBinding.forceServerGenerated {
 		res = resElem.Text()
+		if !s.isValidResource(res) {
+			return s.sendElement(ctx, stanzaerror.E(stanzaerror.BadRequest, iq).Element())
+		}
 
 		// check if another stream with same resource value did already connect
 		for _, rs := range rss {
@@ -1032,6 +1308,9 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 		BuildPresence()
 	s.setPresence(pr)
 
+	// tag session using resource heuristic until client announces its capabilities
+	s.setClientSoftware(normalizeClientSoftware(res))
+
 	if err := s.bindC2S(ctx); err != nil {
 		return err
 	}
@@ -1058,13 +1337,67 @@ func (s *inC2S) bindResource(ctx context.Context, iq *stravaganza.IQ) error {
 			).
 			Build(),
 	)
-	return s.sendElement(ctx, resIQ)
+	if err := s.sendElement(ctx, resIQ); err != nil {
+		return err
+	}
+	s.scheduleAutoPresence()
+	return nil
+}
+
+func (s *inC2S) scheduleAutoPresence() {
+	window := s.hosts.AutoPresenceWindow(s.Domain())
+	if window <= 0 {
+		return
+	}
+	s.autoPrTm = time.AfterFunc(window, func() {
+		s.rq.Run(func() {
+			ctx, cancel := s.requestContext()
+			defer cancel()
+			if err := s.autoSendPresence(ctx); err != nil {
+				level.Warn(s.logger).Log("msg", "failed to autosend initial presence", "err", err)
+			}
+		})
+	})
+}
+
+func (s *inC2S) autoSendPresence(ctx context.Context) error {
+	// client did already announce its availability or stream is gone
+	if s.getState() != inBinded || !s.Presence().IsUnavailable() {
+		return nil
+	}
+	pr, err := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, s.JID().String()).
+		WithAttribute(stravaganza.To, s.JID().ToBareJID().String()).
+		BuildPresence()
+	if err != nil {
+		return err
+	}
+	level.Info(s.logger).Log("msg", "autosending initial presence", "jid", s.JID().String())
+
+	return s.processPresence(ctx, pr)
+}
+
+func (s *inC2S) isValidResource(res string) bool {
+	if maxLen := s.cfg.resBinding.maxLength; maxLen > 0 && len(res) > maxLen {
+		return false
+	}
+	if chars := s.cfg.resBinding.disallowedChars; len(chars) > 0 && strings.ContainsAny(res, chars) {
+		return false
+	}
+	return true
 }
 
 func (s *inC2S) disconnect(ctx context.Context, streamErr *streamerror.Error) error {
 	if s.getState() == inConnecting {
 		_ = s.session.OpenStream(ctx)
 	}
+	var discErr error
+	if streamErr != nil {
+		discErr = streamErr
+	}
+	if err := s.sendFinalElement(ctx, discErr); err != nil {
+		return err
+	}
 	if streamErr != nil {
 		if err := s.sendElement(ctx, streamErr.Element()); err != nil {
 			return err
@@ -1087,6 +1420,20 @@ func (s *inC2S) disconnect(ctx context.Context, streamErr *streamerror.Error) er
 	return s.close(ctx, streamErr)
 }
 
+func (s *inC2S) sendFinalElement(ctx context.Context, disconnectErr error) error {
+	if s.getState() != inBinded {
+		return nil
+	}
+	hInf := s.disconnectInfo(disconnectErr)
+	if _, err := s.runHook(ctx, hook.C2SStreamWillDisconnect, hInf); err != nil {
+		return err
+	}
+	if hInf.Element == nil {
+		return nil
+	}
+	return s.sendElement(ctx, hInf.Element)
+}
+
 func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	switch s.getState() {
 	case inDisconnected:
@@ -1101,12 +1448,16 @@ func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	if s.discTm != nil {
 		s.discTm.Stop()
 	}
+	hInf := s.disconnectInfo(disconnectErr)
+	if disconnectErr != nil {
+		var jidStr string
+		if hInf.JID != nil {
+			jidStr = hInf.JID.String()
+		}
+		level.Info(s.logger).Log("msg", "C2S stream disconnected", "jid", jidStr, "reason", hInf.DisconnectReason)
+	}
 	// run disconnected C2S hook
-	halted, err := s.runHook(ctx, hook.C2SStreamDisconnected, &hook.C2SStreamInfo{
-		ID:              s.ID().String(),
-		JID:             s.JID(),
-		DisconnectError: disconnectErr,
-	})
+	halted, err := s.runHook(ctx, hook.C2SStreamDisconnected, hInf)
 	if halted {
 		return nil
 	}
@@ -1116,6 +1467,16 @@ func (s *inC2S) close(ctx context.Context, disconnectErr error) error {
 	return s.terminate(ctx)
 }
 
+func (s *inC2S) disconnectInfo(disconnectErr error) *hook.C2SStreamInfo {
+	return &hook.C2SStreamInfo{
+		ID:               s.ID().String(),
+		JID:              s.JID(),
+		DisconnectError:  disconnectErr,
+		DisconnectReason: disconnectReason(disconnectErr),
+		RemoteIP:         s.remoteIP,
+	}
+}
+
 func (s *inC2S) terminate(ctx context.Context) error {
 	// unregister C2S stream
 	if err := s.router.C2S().Unregister(s); err != nil {
@@ -1127,6 +1488,16 @@ func (s *inC2S) terminate(ctx context.Context) error {
 	}
 	reportConnectionUnregistered()
 
+	if s.autoPrTm != nil {
+		s.autoPrTm.Stop()
+	}
+	if len(s.swLabel) > 0 {
+		reportClientSoftwareChanged(s.swLabel, "")
+	}
+	// release host session budget slot
+	if len(s.budgetHost) > 0 {
+		s.hosts.ReleaseSession(s.budgetHost)
+	}
 	// close underlying transport
 	_ = s.tr.Close()
 
@@ -1152,14 +1523,35 @@ func (s *inC2S) sendElement(ctx context.Context, elem stravaganza.Element) error
 	if s.sendDisabled {
 		return nil
 	}
+	// run will send element hook
+	hInf := &hook.C2SStreamInfo{
+		ID:      s.ID().String(),
+		JID:     s.JID(),
+		Element: elem,
+	}
+	halted, err := s.runHook(ctx, hook.C2SStreamWillSendElement, hInf)
+	if halted {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	elem = hInf.Element
+
 	_ = s.session.Send(ctx, elem)
 
+	// free in-flight slot once a server-processed IQ gets answered
+	if s.iqsInFlight != nil && elem.Name() == "iq" {
+		if typ := elem.Attribute(stravaganza.Type); typ == stravaganza.ResultType || typ == stravaganza.ErrorType {
+			s.iqsInFlight.release(elem.Attribute(stravaganza.ID))
+		}
+	}
 	reportOutgoingRequest(
 		elem.Name(),
 		elem.Attribute(stravaganza.Type),
 	)
 	// run element sent hook
-	_, err := s.runHook(ctx, hook.C2SStreamElementSent, &hook.C2SStreamInfo{
+	_, err = s.runHook(ctx, hook.C2SStreamElementSent, &hook.C2SStreamInfo{
 		ID:      s.ID().String(),
 		JID:     s.JID(),
 		Element: elem,
@@ -1167,6 +1559,34 @@ func (s *inC2S) sendElement(ctx context.Context, elem stravaganza.Element) error
 	return err
 }
 
+func (s *inC2S) sendStanzaError(ctx context.Context, reason stanzaerror.Reason, stanza stravaganza.Stanza) error {
+	if !xmpputil.IsBounceable(stanza) {
+		return nil // never reply an error with another error
+	}
+	return s.sendElement(ctx, stanzaerror.E(reason, stanza).Element())
+}
+
+func (s *inC2S) bounceMessage(ctx context.Context, reason stanzaerror.Reason, message *stravaganza.Message) error {
+	if !xmpputil.IsBounceable(message) {
+		return nil // never reply an error with another error
+	}
+	errMsg, err := stravaganza.NewBuilderFromElement(stanzaerror.E(reason, message).Element()).BuildMessage()
+	if err != nil {
+		return err
+	}
+	if err := s.sendElement(ctx, errMsg); err != nil {
+		return err
+	}
+	// run message bounced hook
+	_, err = s.runHook(ctx, hook.C2SStreamMessageBounced, &hook.C2SStreamInfo{
+		ID:       s.ID().String(),
+		JID:      s.JID(),
+		Presence: s.Presence(),
+		Element:  errMsg,
+	})
+	return err
+}
+
 func (s *inC2S) getResource() c2smodel.ResourceDesc {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -1178,10 +1598,58 @@ func (s *inC2S) getResource() c2smodel.ResourceDesc {
 	)
 }
 
+func (s *inC2S) setClientSoftware(label string) {
+	if s.swLabel == label {
+		return
+	}
+	reportClientSoftwareChanged(s.swLabel, label)
+	s.swLabel = label
+
+	s.mu.Lock()
+	s.inf.SetString(ClientSoftwareInfoKey, label)
+	s.mu.Unlock()
+}
+
+// resolveTier fetches the tier authenticated account belongs to.
+// Account flags are only fetched in case any shaper is restricted to a tier.
+func (s *inC2S) resolveTier(ctx context.Context) error {
+	if !s.shapers.Tiered() {
+		return nil
+	}
+	flags, err := s.rep.FetchAccountFlags(ctx, s.Username())
+	if err != nil {
+		return err
+	}
+	s.tier = shaper.AccountTier(flags)
+
+	s.mu.Lock()
+	shaper.SetTierInfo(s.inf, s.tier)
+	s.mu.Unlock()
+	return nil
+}
+
 func (s *inC2S) updateRateLimiter() error {
 	j := s.JID()
-	rLim := s.shapers.MatchingJID(j).RateLimiter()
-	return s.tr.SetReadRateLimiter(rLim)
+	shp := s.shapers.MatchingJIDOriginAndTier(j, s.origin, s.tier)
+	s.stzLim = shp.StanzaLimiter()
+	return shp.ApplyRateLimiters(s.tr)
+}
+
+// shapeStanza charges last received stanza cost to the stanza limiter, returning the time
+// its processing should be delayed. Returns false in case stanza should be rejected.
+func (s *inC2S) shapeStanza() (time.Duration, bool) {
+	if s.stzLim == nil {
+		return 0, true
+	}
+	delay, ok := s.stzLim.Reserve(s.session.LastElementSize())
+	if !ok {
+		reportStanzaShaped(true)
+		return 0, false
+	}
+	if delay > 0 {
+		reportStanzaShaped(false)
+	}
+	return delay, true
 }
 
 func (s *inC2S) setJID(jd *jid.JID) {
@@ -1224,3 +1692,14 @@ var currentID uint64
 func nextStreamID() stream.C2SID {
 	return stream.C2SID(atomic.AddUint64(&currentID, 1))
 }
+
+func disconnectReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	var sErr *streamerror.Error
+	if errors.As(err, &sErr) {
+		return sErr.Reason.String()
+	}
+	return err.Error()
+}
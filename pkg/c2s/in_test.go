@@ -19,6 +19,7 @@ import (
 	"context"
 	"crypto/tls"
 	"io"
+	"net"
 	"strings"
 	"sync"
 	"testing"
@@ -30,13 +31,19 @@ import (
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/auth"
+	"github.com/ortuman/jackal/pkg/cluster/distratelimit"
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
 	xmppparser "github.com/ortuman/jackal/pkg/parser"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 )
@@ -119,6 +126,7 @@ func TestInC2S_Disconnect(t *testing.T) {
 		rq:      runqueue.New("in_c2s:test"),
 		doneCh:  make(chan struct{}),
 		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
 	}
 	// when
 	s.Disconnect(streamerror.E(streamerror.SystemShutdown))
@@ -136,6 +144,386 @@ func TestInC2S_Disconnect(t *testing.T) {
 	require.Len(t, rmMock.DelResourceCalls(), 1)
 }
 
+func TestInC2S_DisconnectedHookInfo(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	trMock := &transportMock{}
+	trMock.CloseFunc = func() error { return nil }
+
+	sessMock := &sessionMock{}
+	sessMock.SendFunc = func(_ context.Context, _ stravaganza.Element) error { return nil }
+	sessMock.CloseFunc = func(_ context.Context) error { return nil }
+
+	rmMock := &resourceManagerMock{}
+	rmMock.DelResourceFunc = func(_ context.Context, _ string, _ string) error { return nil }
+
+	routerMock := &routerMock{}
+	c2sRouterMock := &c2sRouterMock{}
+
+	c2sRouterMock.UnregisterFunc = func(stm stream.C2S) error { return nil }
+	routerMock.C2SFunc = func() router.C2SRouter {
+		return c2sRouterMock
+	}
+	var hInf *hook.C2SStreamInfo
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.C2SStreamDisconnected, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		hInf = execCtx.Info.(*hook.C2SStreamInfo)
+		return nil
+	}, hook.DefaultPriority)
+
+	s := &inC2S{
+		state:    inBinded,
+		jd:       jd,
+		remoteIP: net.ParseIP("192.0.2.10"),
+		session:  sessMock,
+		tr:       trMock,
+		router:   routerMock,
+		resMng:   rmMock,
+		rq:       runqueue.New("in_c2s:test"),
+		doneCh:   make(chan struct{}),
+		hk:       hk,
+		logger:   kitlog.NewNopLogger(),
+	}
+	// when
+	err := s.disconnect(context.Background(), streamerror.E(streamerror.PolicyViolation))
+
+	// then
+	require.Nil(t, err)
+
+	require.NotNil(t, hInf)
+	require.Equal(t, "ortuman@jackal.im/yard", hInf.JID.String())
+	require.Equal(t, "192.0.2.10", hInf.RemoteIP.String())
+	require.Equal(t, "policy-violation", hInf.DisconnectReason)
+}
+
+func TestInC2S_WhitespaceKeepAlive(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var mtx sync.Mutex
+	var keepAlives int
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.C2SStreamWhitespaceKeepAlive, func(_ context.Context, _ *hook.ExecutionContext) error {
+		mtx.Lock()
+		keepAlives++
+		mtx.Unlock()
+		return nil
+	}, hook.DefaultPriority)
+
+	s := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			wsKeepAlive: wsKeepAliveCfg{
+				updateLastActivity: true,
+			},
+		},
+		state:  inBinded,
+		jd:     jd,
+		wsLim:  rate.NewLimiter(rate.Every(time.Hour), 3),
+		rq:     runqueue.New("in_c2s:test"),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	for i := 0; i < 10; i++ {
+		s.onWhitespaceKeepAlive()
+	}
+	time.Sleep(time.Millisecond * 250)
+
+	// then
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	require.Equal(t, 3, keepAlives) // flood throttled
+}
+
+func TestInC2S_WhitespaceKeepAliveShaped(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var mtx sync.Mutex
+	var keepAlives int
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.C2SStreamWhitespaceKeepAlive, func(_ context.Context, _ *hook.ExecutionContext) error {
+		mtx.Lock()
+		keepAlives++
+		mtx.Unlock()
+		return nil
+	}, hook.DefaultPriority)
+
+	s := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			wsKeepAlive: wsKeepAliveCfg{
+				updateLastActivity: true,
+			},
+		},
+		state:  inBinded,
+		jd:     jd,
+		wsLim:  rate.NewLimiter(rate.Inf, 0),
+		stzLim: shaper.NewStanzaLimiter(0.01, 2, 16),
+		rq:     runqueue.New("in_c2s:test"),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	for i := 0; i < 5; i++ {
+		s.onWhitespaceKeepAlive()
+	}
+	time.Sleep(time.Millisecond * 250)
+
+	delay, ok := s.stzLim.Reserve(0)
+
+	// then
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	require.Equal(t, 2, keepAlives) // shaper allowance drained by keepalives
+	require.True(t, ok)
+	require.True(t, delay > 0)
+}
+
+func TestInC2S_OriginTag(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.Socket }
+	trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+	trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
+
+	newStream := func(origin geoip.Origin) *inC2S {
+		stm, _ := newInC2S(
+			inCfg{},
+			trMock,
+			nil,
+			origin,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			shaper.Shapers{},
+			hook.NewHooks(),
+			kitlog.NewNopLogger(),
+		)
+		return stm
+	}
+
+	// when
+	stm0 := newStream(geoip.Origin{Country: "ES", ASN: 64496})
+	stm1 := newStream(geoip.Origin{})
+
+	// then
+	require.Equal(t, geoip.Origin{Country: "ES", ASN: 64496}, shaper.OriginFromInfo(stm0.Info()))
+
+	require.Len(t, stm1.Info().Map(), 0)
+}
+
+func TestInC2S_BareJIDFallbackIQ(t *testing.T) {
+	// given
+	modsMock := &modulesMock{}
+	modsMock.IsModuleIQFunc = func(iq *stravaganza.IQ) bool {
+		return iq.ChildNamespace("query", "jabber:iq:last") != nil && !iq.ToJID().IsFull()
+	}
+	stm := &inC2S{
+		cfg: inCfg{
+			iqBareFallback: []string{"jabber:iq:last"},
+		},
+		mods: modsMock,
+	}
+	buildIQ := func(namespace string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.From, "ortuman@localhost/yard").
+			WithAttribute(stravaganza.To, "noelia@localhost/hall").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithAttribute(stravaganza.ID, "iq_1").
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, namespace).
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// when
+	bareIQ := stm.bareJIDFallbackIQ(buildIQ("jabber:iq:last"))
+	nonFallbackIQ := stm.bareJIDFallbackIQ(buildIQ("jabber:iq:version"))
+
+	// then
+	require.NotNil(t, bareIQ)
+	require.Equal(t, "noelia@localhost", bareIQ.Attribute(stravaganza.To))
+	require.Equal(t, "iq_1", bareIQ.Attribute(stravaganza.ID))
+
+	require.Nil(t, nonFallbackIQ)
+}
+
+func TestInC2S_InFlightIQLimit(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var sentElems []stravaganza.Element
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, elem stravaganza.Element) error {
+		sentElems = append(sentElems, elem)
+		return nil
+	}
+	var processedIQs []string
+	modsMock := &modulesMock{}
+	modsMock.IsModuleIQFunc = func(iq *stravaganza.IQ) bool { return true }
+	modsMock.ProcessIQFunc = func(_ context.Context, iq *stravaganza.IQ) error {
+		processedIQs = append(processedIQs, iq.Attribute(stravaganza.ID))
+		return nil
+	}
+	s := &inC2S{
+		state:       inBinded,
+		jd:          jd,
+		session:     ssMock,
+		mods:        modsMock,
+		iqsInFlight: newInFlightIQs(2, time.Minute),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+	buildIQ := func(id string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithAttribute(stravaganza.ID, id).
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, "http://jabber.org/protocol/disco#info").
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// when
+	err0 := s.processIQ(context.Background(), buildIQ("iq_1"))
+	err1 := s.processIQ(context.Background(), buildIQ("iq_2"))
+	err2 := s.processIQ(context.Background(), buildIQ("iq_3")) // exceeds in-flight cap
+
+	// answer first IQ
+	err3 := s.sendElement(context.Background(), buildIQ("iq_1").ResultBuilder().Build())
+
+	err4 := s.processIQ(context.Background(), buildIQ("iq_4"))
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	require.NoError(t, err4)
+
+	require.Equal(t, []string{"iq_1", "iq_2", "iq_4"}, processedIQs)
+
+	require.Len(t, sentElems, 2)
+	require.Equal(t, "iq_3", sentElems[0].Attribute(stravaganza.ID))
+	require.Equal(t, stravaganza.ErrorType, sentElems[0].Attribute(stravaganza.Type))
+	require.NotNil(t, sentElems[0].Child("error").ChildNamespace("resource-constraint", "urn:ietf:params:xml:ns:xmpp-stanzas"))
+
+	require.Equal(t, 2, s.iqsInFlight.len())
+}
+
+func TestInC2S_ErrorStanzaNotBounced(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var sentElems []stravaganza.Element
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, elem stravaganza.Element) error {
+		sentElems = append(sentElems, elem)
+		return nil
+	}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(_ context.Context, _ stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, router.ErrNotExistingAccount
+	}
+	s := &inC2S{
+		state:   inBinded,
+		jd:      jd,
+		session: ssMock,
+		router:  routerMock,
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+	buildMessage := func(typ string) *stravaganza.Message {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+			WithAttribute(stravaganza.Type, typ).
+			WithAttribute(stravaganza.ID, "msg_1").
+			BuildMessage()
+		return msg
+	}
+
+	// when
+	err0 := s.processMessage(context.Background(), buildMessage(stravaganza.ChatType))
+	err1 := s.processMessage(context.Background(), buildMessage(stravaganza.ErrorType))
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+
+	require.Len(t, sentElems, 1) // error stanza not replied with another error
+	require.Equal(t, stravaganza.ErrorType, sentElems[0].Attribute(stravaganza.Type))
+	require.Equal(t, 1, len(sentElems[0].Children("error")))
+}
+
+func TestInC2S_MessageBouncedHook(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, _ stravaganza.Element) error { return nil }
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(_ context.Context, _ stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, router.ErrNotExistingAccount
+	}
+	var bouncedElem stravaganza.Element
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.C2SStreamMessageBounced, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		bouncedElem = execCtx.Info.(*hook.C2SStreamInfo).Element
+		return nil
+	}, hook.DefaultPriority)
+
+	s := &inC2S{
+		state:   inBinded,
+		jd:      jd,
+		session: ssMock,
+		router:  routerMock,
+		hk:      hk,
+		logger:  kitlog.NewNopLogger(),
+	}
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.Type, stravaganza.ChatType).
+		WithAttribute(stravaganza.ID, "msg_1").
+		BuildMessage()
+
+	// when
+	err := s.processMessage(context.Background(), msg)
+
+	// then
+	require.NoError(t, err)
+
+	errMsg, ok := bouncedElem.(*stravaganza.Message)
+	require.True(t, ok)
+	require.Equal(t, stravaganza.ErrorType, errMsg.Attribute(stravaganza.Type))
+	require.Equal(t, "ortuman@jackal.im/yard", errMsg.Attribute(stravaganza.To))
+}
+
 func TestInC2S_HandleSessionElement(t *testing.T) {
 	jd0, _ := jid.New("ortuman", "jackal.im", "yard", true)
 	jd1, _ := jid.New("ortuman", "jackal.im", "hall", true)
@@ -151,9 +539,11 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 		routeError    error
 		hubResources  []c2smodel.ResourceDesc
 		flags         uint8
+		resBinding    resBindingCfg
 
 		// expectations
 		expectedOutput        string
+		expectServerResource  bool
 		expectRouted          bool
 		expectResourceUpdated bool
 		expectedState         state
@@ -199,7 +589,7 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 					WithAttribute(stravaganza.Version, "1.0").
 					Build(), nil
 			},
-			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><compression xmlns='http://jabber.org/features/compress'><method>zlib</method></compression><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><required/></bind><session xmlns='urn:ietf:params:xml:ns:xmpp-session'/></stream:features>`,
+			expectedOutput: `<?xml version='1.0'?><stream:stream xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' id='c2s1' from='localhost' version='1.0'><stream:features xmlns:stream='http://etherx.jabber.org/streams' version='1.0'><compression xmlns='http://jabber.org/features/compress'><method>zlib</method><method>gzip</method></compression><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><required/></bind><session xmlns='urn:ietf:params:xml:ns:xmpp-session'/></stream:features>`,
 			expectedState:  inAuthenticated,
 		},
 		{
@@ -390,6 +780,60 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<iq from='ortuman@localhost' to='ortuman@localhost' type='error' id='bind_2'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><resource>yard</resource></bind><error code='409' type='cancel'><conflict xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
 			expectedState:  inAuthenticated,
 		},
+		{
+			name:       "Authenticated/BindOverlongResource",
+			state:      inAuthenticated,
+			flags:      fSecured | fCompressed | fAuthenticated,
+			resBinding: resBindingCfg{maxLength: 8},
+			sessionResFn: func() (stravaganza.Element, error) {
+				iq, _ := stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.From, "ortuman@localhost").
+					WithAttribute(stravaganza.To, "ortuman@localhost").
+					WithAttribute(stravaganza.Type, stravaganza.SetType).
+					WithAttribute(stravaganza.ID, "bind_2").
+					WithChild(
+						stravaganza.NewBuilder("bind").
+							WithAttribute(stravaganza.Namespace, bindNamespace).
+							WithChild(
+								stravaganza.NewBuilder("resource").WithText("a-very-long-resource").Build(),
+							).
+							Build(),
+					).
+					BuildIQ()
+				return iq, nil
+			},
+			expectedOutput: `<iq from='ortuman@localhost' to='ortuman@localhost' type='error' id='bind_2'><bind xmlns='urn:ietf:params:xml:ns:xmpp-bind'><resource>a-very-long-resource</resource></bind><error code='400' type='modify'><bad-request xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
+			expectedState:  inAuthenticated,
+		},
+		{
+			name:       "Authenticated/BindForceServerGenerated",
+			state:      inAuthenticated,
+			flags:      fSecured | fCompressed | fAuthenticated,
+			resBinding: resBindingCfg{forceServerGenerated: true},
+			sessionResFn: func() (stravaganza.Element, error) {
+				iq, _ := stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.From, "ortuman@localhost").
+					WithAttribute(stravaganza.To, "ortuman@localhost").
+					WithAttribute(stravaganza.Type, stravaganza.SetType).
+					WithAttribute(stravaganza.ID, "bind_2").
+					WithChild(
+						stravaganza.NewBuilder("bind").
+							WithAttribute(stravaganza.Namespace, bindNamespace).
+							WithChild(
+								stravaganza.NewBuilder("resource").WithText("yard").Build(),
+							).
+							Build(),
+					).
+					BuildIQ()
+				return iq, nil
+			},
+			hubResources: []c2smodel.ResourceDesc{
+				c2smodel.NewResourceDesc("inst-2", jd0, nil, c2smodel.NewInfoMap()),
+			},
+			expectServerResource:  true,
+			expectedState:         inBinded,
+			expectResourceUpdated: true,
+		},
 		{
 			name:  "Authenticated/BindMaxSessions",
 			state: inAuthenticated,
@@ -436,6 +880,23 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<compressed xmlns='http://jabber.org/protocol/compress'/>`,
 			expectedState:  inConnecting,
 		},
+		{
+			name:  "Authenticated/CompressGzipSuccess",
+			state: inAuthenticated,
+			flags: fSecured | fAuthenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return stravaganza.NewBuilder("compress").
+					WithAttribute(stravaganza.Namespace, compressNamespace).
+					WithChild(
+						stravaganza.NewBuilder("method").
+							WithText("gzip").
+							Build(),
+					).
+					Build(), nil
+			},
+			expectedOutput: `<compressed xmlns='http://jabber.org/protocol/compress'/>`,
+			expectedState:  inConnecting,
+		},
 		{
 			name:  "Authenticated/CompressMalformed",
 			state: inAuthenticated,
@@ -550,6 +1011,28 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<iq from='noelia@localhost/hall' to='ortuman@localhost/yard' type='error' id='iq_1'><ping xmlns='urn:xmpp:ping'/><error code='503' type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
 			expectedState:  inBinded,
 		},
+		{
+			name:  "Binded/RouteIQUserNotAvailable",
+			state: inBinded,
+			flags: fSecured | fCompressed | fAuthenticated | fSessionStarted,
+			sessionResFn: func() (stravaganza.Element, error) {
+				iq, _ := stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.From, "ortuman@localhost/yard").
+					WithAttribute(stravaganza.To, "noelia@localhost/hall").
+					WithAttribute(stravaganza.Type, stravaganza.SetType).
+					WithAttribute(stravaganza.ID, "iq_1").
+					WithChild(
+						stravaganza.NewBuilder("ping").
+							WithAttribute(stravaganza.Namespace, "urn:xmpp:ping").
+							Build(),
+					).
+					BuildIQ()
+				return iq, nil
+			},
+			routeError:     router.ErrUserNotAvailable,
+			expectedOutput: `<iq from='noelia@localhost/hall' to='ortuman@localhost/yard' type='error' id='iq_1'><ping xmlns='urn:xmpp:ping'/><error code='503' type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
+			expectedState:  inBinded,
+		},
 		{
 			name:  "Binded/RouteIQFailedRemoteConnect",
 			state: inBinded,
@@ -572,6 +1055,28 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			expectedOutput: `<iq from='noelia@localhost/hall' to='ortuman@localhost/yard' type='error' id='iq_1'><ping xmlns='urn:xmpp:ping'/><error code='404' type='cancel'><remote-server-not-found xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
 			expectedState:  inBinded,
 		},
+		{
+			name:  "Binded/RouteIQPolicyDenied",
+			state: inBinded,
+			flags: fSecured | fCompressed | fAuthenticated | fSessionStarted,
+			sessionResFn: func() (stravaganza.Element, error) {
+				iq, _ := stravaganza.NewIQBuilder().
+					WithAttribute(stravaganza.From, "ortuman@localhost/yard").
+					WithAttribute(stravaganza.To, "noelia@localhost/hall").
+					WithAttribute(stravaganza.Type, stravaganza.SetType).
+					WithAttribute(stravaganza.ID, "iq_1").
+					WithChild(
+						stravaganza.NewBuilder("ping").
+							WithAttribute(stravaganza.Namespace, "urn:xmpp:ping").
+							Build(),
+					).
+					BuildIQ()
+				return iq, nil
+			},
+			routeError:     router.ErrPolicyDenied,
+			expectedOutput: `<iq from='noelia@localhost/hall' to='ortuman@localhost/yard' type='error' id='iq_1'><ping xmlns='urn:xmpp:ping'/><error code='405' type='cancel'><not-allowed xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>`,
+			expectedState:  inBinded,
+		},
 		{
 			name:  "Binded/RoutePresenceSuccess",
 			state: inBinded,
@@ -643,13 +1148,17 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			trMock.TypeFunc = func() transport.Type { return transport.Socket }
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SupportsChannelBindingFunc = func() bool { return false }
-			trMock.EnableCompressionFunc = func(_ compress.Level) {}
+			trMock.EnableCompressionFunc = func(_ compress.Method, _ compress.Level) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error { return nil }
 
 			// hosts mock
 			hMock.IsLocalHostFunc = func(host string) bool { return host == "localhost" }
 			hMock.CertificatesFunc = func() []tls.Certificate { return nil }
+			hMock.AcquireSessionFunc = func(host string) bool { return true }
+			hMock.ReleaseSessionFunc = func(host string) {}
+			hMock.AutoPresenceWindowFunc = func(host string) time.Duration { return 0 }
 
 			// router mocks
 			c2sRouterMock.BindFunc = func(id stream.C2SID) error { return nil }
@@ -726,6 +1235,7 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 					maxStanzaSize:    8192,
 					compressionLevel: compress.DefaultCompression,
 					resConflict:      disallow,
+					resBinding:       tt.resBinding,
 				},
 				state:  tt.state,
 				flags:  flags{flg: tt.flags},
@@ -751,7 +1261,13 @@ func TestInC2S_HandleSessionElement(t *testing.T) {
 			stm.handleSessionResult(tt.sessionResFn())
 
 			// then
-			require.Equal(t, tt.expectedOutput, outBuf.String())
+			if tt.expectServerResource {
+				res := stm.JID().Resource()
+				require.NotEqual(t, "yard", res)
+				require.Contains(t, outBuf.String(), "<jid>ortuman@localhost/"+res+"</jid>")
+			} else {
+				require.Equal(t, tt.expectedOutput, outBuf.String())
+			}
 			require.Equal(t, tt.expectedState, stm.getState())
 			require.Equal(t, tt.expectRouted, routed)
 			require.Equal(t, tt.expectResourceUpdated, updatedRes)
@@ -764,6 +1280,7 @@ func TestInC2S_HandleSessionError(t *testing.T) {
 		name           string
 		state          state
 		sErr           error
+		finalElement   stravaganza.Element
 		expectedOutput string
 		expectClosed   bool
 	}{
@@ -774,6 +1291,14 @@ func TestInC2S_HandleSessionError(t *testing.T) {
 			expectedOutput: `</stream:stream>`,
 			expectClosed:   true,
 		},
+		{
+			name:           "ClosedByPeerErrorWithFinalElement",
+			state:          inBinded,
+			sErr:           xmppparser.ErrStreamClosedByPeer,
+			finalElement:   stravaganza.NewBuilder("a").WithAttribute("h", "3").Build(),
+			expectedOutput: `<a h='3'/></stream:stream>`,
+			expectClosed:   true,
+		},
 		{
 			name:           "EOFError",
 			state:          inBinded,
@@ -818,7 +1343,15 @@ func TestInC2S_HandleSessionError(t *testing.T) {
 				return nil
 			}
 
-			stm := &inC2S{
+			hk := hook.NewHooks()
+			if tt.finalElement != nil {
+				hk.AddHook(hook.C2SStreamWillDisconnect, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+					execCtx.Info.(*hook.C2SStreamInfo).Element = tt.finalElement
+					return nil
+				}, hook.DefaultPriority)
+			}
+
+			stm := &inC2S{
 				cfg: inCfg{
 					reqTimeout:    time.Minute,
 					maxStanzaSize: 8192,
@@ -830,7 +1363,7 @@ func TestInC2S_HandleSessionError(t *testing.T) {
 				session: ssMock,
 				router:  routerMock,
 				resMng:  resMngMock,
-				hk:      hook.NewHooks(),
+				hk:      hk,
 				logger:  kitlog.NewNopLogger(),
 			}
 			// when
@@ -842,3 +1375,731 @@ func TestInC2S_HandleSessionError(t *testing.T) {
 		})
 	}
 }
+
+func TestInC2S_StartTLSHandshakeFailure(t *testing.T) {
+	// given
+	ssMock := &sessionMock{}
+	trMock := &transportMock{}
+	routerMock := &routerMock{}
+	c2sRouterMock := &c2sRouterMock{}
+
+	ssMock.CloseFunc = func(_ context.Context) error { return nil }
+	trMock.CloseFunc = func() error { return nil }
+	routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+	c2sRouterMock.UnregisterFunc = func(stm stream.C2S) error { return nil }
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.DelResourceFunc = func(ctx context.Context, username string, resource string) error {
+		return nil
+	}
+
+	var failedIP string
+	var failedErr error
+	stm := &inC2S{
+		cfg: inCfg{
+			reqTimeout: time.Minute,
+			tlsFailureFn: func(remoteIP string, err error) {
+				failedIP = remoteIP
+				failedErr = err
+			},
+		},
+		state:      inConnecting,
+		remoteIP:   net.ParseIP("127.0.0.1"),
+		tlsPending: true,
+		rq:         runqueue.New("in_c2s:test"),
+		doneCh:     make(chan struct{}),
+		tr:         trMock,
+		session:    ssMock,
+		router:     routerMock,
+		resMng:     resMngMock,
+		hk:         hook.NewHooks(),
+		logger:     kitlog.NewNopLogger(),
+	}
+	hsErr := tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}
+
+	// when
+	stm.handleSessionResult(nil, hsErr)
+
+	// then
+	require.Equal(t, "127.0.0.1", failedIP)
+	require.Equal(t, hsErr, failedErr)
+	require.False(t, stm.tlsPending)
+}
+
+func TestInC2S_HostSessionBudget(t *testing.T) {
+	// given
+	hs := testHosts(t)
+
+	hs.RegisterHost("jackal.im", tls.Certificate{})
+	hs.RegisterHost("jackal.net", tls.Certificate{})
+	hs.SetMaxSessions("jackal.im", 1)
+
+	require.True(t, hs.AcquireSession("jackal.im")) // budget exhausted
+
+	newStream := func(sendBuf *bytes.Buffer) *inC2S {
+		trMock := &transportMock{}
+		trMock.TypeFunc = func() transport.Type { return transport.Socket }
+		trMock.CloseFunc = func() error { return nil }
+
+		sessMock := &sessionMock{}
+		sessMock.SetFromJIDFunc = func(ssJID *jid.JID) {}
+		sessMock.OpenStreamFunc = func(ctx context.Context) error { return nil }
+		sessMock.CloseFunc = func(ctx context.Context) error { return nil }
+		sessMock.SendFunc = func(ctx context.Context, element stravaganza.Element) error {
+			_ = element.ToXML(sendBuf, true)
+			return nil
+		}
+		rmMock := &resourceManagerMock{}
+		rmMock.DelResourceFunc = func(ctx context.Context, username string, resource string) error {
+			return nil
+		}
+		c2sRouterMock := &c2sRouterMock{}
+		c2sRouterMock.UnregisterFunc = func(stm stream.C2S) error { return nil }
+
+		routerMock := &routerMock{}
+		routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+		return &inC2S{
+			state:   inConnecting,
+			session: sessMock,
+			tr:      trMock,
+			hosts:   hs,
+			router:  routerMock,
+			resMng:  rmMock,
+			inf:     c2smodel.NewInfoMap(),
+			doneCh:  make(chan struct{}),
+			hk:      hook.NewHooks(),
+			logger:  kitlog.NewNopLogger(),
+		}
+	}
+	imBuf := bytes.NewBuffer(nil)
+	netBuf := bytes.NewBuffer(nil)
+
+	imStm := newStream(imBuf)
+	netStm := newStream(netBuf)
+
+	// when
+	streamOpen := func(domain string) stravaganza.Element {
+		return stravaganza.NewBuilder("stream:stream").
+			WithAttribute(stravaganza.To, domain).
+			Build()
+	}
+	_ = imStm.handleConnecting(context.Background(), streamOpen("jackal.im"))
+	_ = netStm.handleConnecting(context.Background(), streamOpen("jackal.net"))
+
+	// then
+	require.Equal(t, `<stream:error><resource-constraint xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`, imBuf.String())
+	require.Equal(t, inTerminated, imStm.getState())
+
+	require.Contains(t, netBuf.String(), "<stream:features")
+	require.Equal(t, inConnected, netStm.getState())
+
+	// terminated stream must not release a slot it never acquired
+	require.False(t, hs.AcquireSession("jackal.im"))
+	require.True(t, hs.AcquireSession("jackal.net"))
+}
+
+func TestInC2S_Draining(t *testing.T) {
+	// given
+	hs := testHosts(t)
+
+	hs.RegisterHost("jackal.im", tls.Certificate{})
+
+	sendBuf := bytes.NewBuffer(nil)
+
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.Socket }
+	trMock.CloseFunc = func() error { return nil }
+
+	sessMock := &sessionMock{}
+	sessMock.SetFromJIDFunc = func(ssJID *jid.JID) {}
+	sessMock.OpenStreamFunc = func(ctx context.Context) error { return nil }
+	sessMock.CloseFunc = func(ctx context.Context) error { return nil }
+	sessMock.SendFunc = func(ctx context.Context, element stravaganza.Element) error {
+		_ = element.ToXML(sendBuf, true)
+		return nil
+	}
+	rmMock := &resourceManagerMock{}
+	rmMock.DelResourceFunc = func(ctx context.Context, username string, resource string) error {
+		return nil
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.UnregisterFunc = func(stm stream.C2S) error { return nil }
+
+	routerMock := &routerMock{}
+	routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+	stm := &inC2S{
+		cfg:     inCfg{draining: true},
+		state:   inConnecting,
+		session: sessMock,
+		tr:      trMock,
+		hosts:   hs,
+		router:  routerMock,
+		resMng:  rmMock,
+		inf:     c2smodel.NewInfoMap(),
+		doneCh:  make(chan struct{}),
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = stm.handleConnecting(context.Background(), stravaganza.NewBuilder("stream:stream").
+		WithAttribute(stravaganza.To, "jackal.im").
+		Build(),
+	)
+
+	// then
+	require.Equal(t, `<stream:error><system-shutdown xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`, sendBuf.String())
+	require.Equal(t, inTerminated, stm.getState())
+}
+
+func TestInC2S_AutoPresence(t *testing.T) {
+	tests := []struct {
+		name             string
+		window           time.Duration
+		expectedPresence bool
+	}{
+		{name: "Enabled", window: time.Millisecond * 50, expectedPresence: true},
+		{name: "Disabled", window: 0, expectedPresence: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			hs := testHosts(t)
+
+			hs.RegisterHost("jackal.im", tls.Certificate{})
+			hs.SetAutoPresenceWindow("jackal.im", tt.window)
+
+			var mtx sync.Mutex
+			var presences []*stravaganza.Presence
+
+			hk := hook.NewHooks()
+			hk.AddHook(hook.C2SStreamPresenceReceived, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+				inf := execCtx.Info.(*hook.C2SStreamInfo)
+				mtx.Lock()
+				presences = append(presences, inf.Element.(*stravaganza.Presence))
+				mtx.Unlock()
+				return nil
+			}, hook.DefaultPriority)
+
+			trMock := &transportMock{}
+			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
+
+			sessMock := &sessionMock{}
+			sessMock.SetFromJIDFunc = func(ssJID *jid.JID) {}
+			sessMock.SendFunc = func(ctx context.Context, element stravaganza.Element) error { return nil }
+
+			rmMock := &resourceManagerMock{}
+			rmMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+				return nil, nil
+			}
+			rmMock.PutResourceFunc = func(ctx context.Context, res c2smodel.ResourceDesc) error { return nil }
+
+			c2sRouterMock := &c2sRouterMock{}
+			c2sRouterMock.BindFunc = func(id stream.C2SID) error { return nil }
+
+			routerMock := &routerMock{}
+			routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+			jd, _ := jid.NewWithString("ortuman@jackal.im", true)
+			s := &inC2S{
+				cfg:     inCfg{reqTimeout: time.Minute},
+				state:   inAuthenticated,
+				jd:      jd,
+				tr:      trMock,
+				session: sessMock,
+				hosts:   hs,
+				router:  routerMock,
+				resMng:  rmMock,
+				inf:     c2smodel.NewInfoMap(),
+				rq:      runqueue.New("in_c2s:test"),
+				hk:      hk,
+				logger:  kitlog.NewNopLogger(),
+			}
+			iq, _ := stravaganza.NewIQBuilder().
+				WithAttribute(stravaganza.From, "ortuman@jackal.im").
+				WithAttribute(stravaganza.To, "jackal.im").
+				WithAttribute(stravaganza.ID, "bind_1").
+				WithAttribute(stravaganza.Type, stravaganza.SetType).
+				WithChild(
+					stravaganza.NewBuilder("bind").
+						WithAttribute(stravaganza.Namespace, bindNamespace).
+						WithChild(stravaganza.NewBuilder("resource").WithText("bot").Build()).
+						Build(),
+				).
+				BuildIQ()
+
+			// when
+			err := s.bindResource(context.Background(), iq)
+			time.Sleep(time.Millisecond * 250)
+
+			// then
+			require.Nil(t, err)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			if !tt.expectedPresence {
+				require.Len(t, presences, 0)
+				require.True(t, s.Presence().IsUnavailable())
+				return
+			}
+			require.Len(t, presences, 1)
+			require.True(t, presences[0].IsAvailable())
+			require.Equal(t, "ortuman@jackal.im/bot", presences[0].FromJID().String())
+			require.Equal(t, "ortuman@jackal.im", presences[0].ToJID().String())
+			require.True(t, s.Presence().IsAvailable())
+		})
+	}
+}
+
+func TestInC2S_AutoPresenceAlreadySent(t *testing.T) {
+	// given
+	hs := testHosts(t)
+
+	hs.RegisterHost("jackal.im", tls.Certificate{})
+	hs.SetAutoPresenceWindow("jackal.im", time.Millisecond*50)
+
+	var mtx sync.Mutex
+	var presences int
+
+	hk := hook.NewHooks()
+	hk.AddHook(hook.C2SStreamPresenceReceived, func(_ context.Context, _ *hook.ExecutionContext) error {
+		mtx.Lock()
+		presences++
+		mtx.Unlock()
+		return nil
+	}, hook.DefaultPriority)
+
+	rmMock := &resourceManagerMock{}
+	rmMock.PutResourceFunc = func(ctx context.Context, res c2smodel.ResourceDesc) error { return nil }
+
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+	s := &inC2S{
+		cfg:    inCfg{reqTimeout: time.Minute},
+		state:  inBinded,
+		jd:     jd,
+		hosts:  hs,
+		resMng: rmMock,
+		inf:    c2smodel.NewInfoMap(),
+		rq:     runqueue.New("in_c2s:test"),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	pr, _ := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		BuildPresence()
+
+	// when
+	s.scheduleAutoPresence()
+	_ = s.processPresence(context.Background(), pr)
+	time.Sleep(time.Millisecond * 250)
+
+	// then
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	require.Equal(t, 1, presences) // only client initial presence
+}
+
+func TestInC2S_UnknownSASLMechanism(t *testing.T) {
+	tests := []struct {
+		name             string
+		cfg              unknownMechCfg
+		expectedReported float64
+		expectedState    state
+		expectedOutput   string
+	}{
+		{
+			name:             "Untracked",
+			cfg:              unknownMechCfg{},
+			expectedReported: 0,
+			expectedState:    inConnected,
+			expectedOutput:   strings.Repeat(`<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><invalid-mechanism/></failure>`, maxAuthFailed),
+		},
+		{
+			name:             "Tracked",
+			cfg:              unknownMechCfg{track: true},
+			expectedReported: maxAuthFailed,
+			expectedState:    inConnected,
+			expectedOutput:   strings.Repeat(`<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><invalid-mechanism/></failure>`, maxAuthFailed),
+		},
+		{
+			name:             "CountAsFailure",
+			cfg:              unknownMechCfg{track: true, countAsFailure: true},
+			expectedReported: maxAuthFailed,
+			expectedState:    inTerminated,
+			expectedOutput: strings.Repeat(`<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><invalid-mechanism/></failure>`, maxAuthFailed-1) +
+				`<stream:error><policy-violation xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			trMock := &transportMock{}
+			trMock.CloseFunc = func() error { return nil }
+
+			outBuf := bytes.NewBuffer(nil)
+			ssMock := &sessionMock{}
+			ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+				return element.ToXML(outBuf, true)
+			}
+			ssMock.CloseFunc = func(_ context.Context) error { return nil }
+
+			c2sRouterMock := &c2sRouterMock{}
+			c2sRouterMock.UnregisterFunc = func(stm stream.C2S) error { return nil }
+
+			routerMock := &routerMock{}
+			routerMock.C2SFunc = func() router.C2SRouter { return c2sRouterMock }
+
+			rmMock := &resourceManagerMock{}
+			rmMock.DelResourceFunc = func(ctx context.Context, username string, resource string) error { return nil }
+
+			authMock := &authenticatorMock{}
+			authMock.MechanismFunc = func() string { return "SCRAM-SHA-1" }
+
+			s := &inC2S{
+				cfg:     inCfg{unknownMech: tt.cfg},
+				state:   inConnected,
+				flags:   flags{flg: fSecured},
+				tr:      trMock,
+				session: ssMock,
+				router:  routerMock,
+				resMng:  rmMock,
+				authSt:  authState{authenticators: []auth.Authenticator{authMock}},
+				inf:     c2smodel.NewInfoMap(),
+				doneCh:  make(chan struct{}),
+				hk:      hook.NewHooks(),
+				logger:  kitlog.NewNopLogger(),
+			}
+			authElem := stravaganza.NewBuilder("auth").
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				WithAttribute("mechanism", "FOO-AUTH-MECHANISM").
+				Build()
+
+			prevReported := testutil.ToFloat64(c2sUnknownSASLMechanisms.With(prometheus.Labels{"instance": instance.ID()}))
+
+			// when
+			for i := 0; i < maxAuthFailed; i++ {
+				require.Nil(t, s.handleElement(context.Background(), authElem))
+			}
+
+			// then
+			reported := testutil.ToFloat64(c2sUnknownSASLMechanisms.With(prometheus.Labels{"instance": instance.ID()}))
+
+			require.Equal(t, tt.expectedOutput, outBuf.String())
+			require.Equal(t, tt.expectedState, s.getState())
+			require.Equal(t, tt.expectedReported, reported-prevReported)
+		})
+	}
+}
+
+func TestInC2S_AuthenticationRateLimit(t *testing.T) {
+	// given
+	var kvVal []byte
+	kvMock := &kvMock{}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		return kvVal, nil
+	}
+	kvMock.CompareAndSwapFunc = func(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+		kvVal = []byte(value)
+		return true, nil
+	}
+	authLim := distratelimit.New("c2s_auth", distratelimit.Config{Rate: 0.01, Burst: 1}, kvMock)
+
+	newStream := func(outBuf *bytes.Buffer) *inC2S {
+		ssMock := &sessionMock{}
+		ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+			return element.ToXML(outBuf, true)
+		}
+		authMock := &authenticatorMock{}
+		authMock.MechanismFunc = func() string { return "SCRAM-SHA-1" }
+		authMock.ProcessElementFunc = func(ctx context.Context, element stravaganza.Element) (stravaganza.Element, *auth.SASLError) {
+			return stravaganza.NewBuilder("challenge").
+				WithAttribute(stravaganza.Namespace, saslNamespace).
+				Build(), nil
+		}
+		authMock.AuthenticatedFunc = func() bool { return false }
+
+		return &inC2S{
+			cfg:      inCfg{authLimiter: authLim},
+			state:    inConnected,
+			flags:    flags{flg: fSecured},
+			session:  ssMock,
+			remoteIP: net.ParseIP("192.168.1.10"),
+			authSt:   authState{authenticators: []auth.Authenticator{authMock}},
+			inf:      c2smodel.NewInfoMap(),
+			hk:       hook.NewHooks(),
+			logger:   kitlog.NewNopLogger(),
+		}
+	}
+	authElem := stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithAttribute("mechanism", "SCRAM-SHA-1").
+		Build()
+
+	outBuf1 := bytes.NewBuffer(nil)
+	s1 := newStream(outBuf1)
+
+	outBuf2 := bytes.NewBuffer(nil)
+	s2 := newStream(outBuf2)
+
+	// when
+	require.Nil(t, s1.handleElement(context.Background(), authElem))
+	require.Nil(t, s2.handleElement(context.Background(), authElem))
+
+	// then
+	require.Equal(t, inAuthenticating, s1.getState())
+	require.Equal(t, `<challenge xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/>`, outBuf1.String())
+
+	require.Equal(t, inConnected, s2.getState())
+	require.Equal(t, `<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><temporary-auth-failure/></failure>`, outBuf2.String())
+	require.Equal(t, 1, s2.authSt.failedTimes)
+}
+
+func TestInC2S_AuthenticationRateLimitContention(t *testing.T) {
+	// given
+	kvMock := &kvMock{}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		return nil, nil
+	}
+	kvMock.CompareAndSwapFunc = func(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+		return false, nil // always losing the race against concurrent attempts
+	}
+	outBuf := bytes.NewBuffer(nil)
+
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, element stravaganza.Element) error {
+		return element.ToXML(outBuf, true)
+	}
+	authMock := &authenticatorMock{}
+	authMock.MechanismFunc = func() string { return "SCRAM-SHA-1" }
+
+	s := &inC2S{
+		cfg:      inCfg{authLimiter: distratelimit.New("c2s_auth", distratelimit.Config{Rate: 1, Burst: 5}, kvMock)},
+		state:    inConnected,
+		flags:    flags{flg: fSecured},
+		session:  ssMock,
+		remoteIP: net.ParseIP("192.168.1.10"),
+		authSt:   authState{authenticators: []auth.Authenticator{authMock}},
+		inf:      c2smodel.NewInfoMap(),
+		hk:       hook.NewHooks(),
+		logger:   kitlog.NewNopLogger(),
+	}
+
+	// when
+	err := s.handleElement(context.Background(), stravaganza.NewBuilder("auth").
+		WithAttribute(stravaganza.Namespace, saslNamespace).
+		WithAttribute("mechanism", "SCRAM-SHA-1").
+		Build(),
+	)
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, inConnected, s.getState())
+	require.Equal(t, `<failure xmlns='urn:ietf:params:xml:ns:xmpp-sasl'><temporary-auth-failure/></failure>`, outBuf.String())
+	require.Len(t, authMock.ProcessElementCalls(), 0)
+}
+
+func TestInC2S_StanzaShaping(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var sentElems []stravaganza.Element
+	ssMock := &sessionMock{}
+	ssMock.SendFunc = func(_ context.Context, elem stravaganza.Element) error {
+		sentElems = append(sentElems, elem)
+		return nil
+	}
+	ssMock.LastElementSizeFunc = func() int { return 256 }
+
+	routerMock := &routerMock{}
+
+	s := &inC2S{
+		state:   inBinded,
+		jd:      jd,
+		session: ssMock,
+		router:  routerMock,
+		stzLim:  shaper.NewStanzaLimiter(1, 3, 16),
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.Type, stravaganza.ChatType).
+		WithAttribute(stravaganza.ID, "msg_1").
+		WithChild(stravaganza.NewBuilder("body").WithText(strings.Repeat("a", 128)).Build()).
+		BuildMessage()
+
+	// drain limiter allowance (a single large stanza takes the whole burst)
+	_, ok1 := s.stzLim.Reserve(256)
+	_, ok2 := s.stzLim.Reserve(256)
+
+	// when
+	err := s.processStanza(context.Background(), msg)
+
+	// then
+	require.True(t, ok1)
+	require.True(t, ok2)
+	require.NoError(t, err)
+
+	require.Len(t, routerMock.RouteCalls(), 0)
+
+	require.Len(t, sentElems, 1)
+	require.Equal(t, stravaganza.ErrorType, sentElems[0].Attribute(stravaganza.Type))
+	require.NotNil(t, sentElems[0].Child("error").Child("resource-constraint"))
+}
+
+func TestInC2S_StanzaShapingDelayed(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	ssMock := &sessionMock{}
+	ssMock.LastElementSizeFunc = func() int { return 8 }
+
+	compsMock := &componentsMock{}
+	compsMock.IsComponentHostFunc = func(_ string) bool { return false }
+
+	var mtx sync.Mutex
+	var routed bool
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(_ context.Context, _ stravaganza.Stanza) ([]jid.JID, error) {
+		mtx.Lock()
+		routed = true
+		mtx.Unlock()
+		return nil, nil
+	}
+	s := &inC2S{
+		cfg:     inCfg{reqTimeout: time.Minute},
+		state:   inBinded,
+		jd:      jd,
+		session: ssMock,
+		router:  routerMock,
+		comps:   compsMock,
+		stzLim:  shaper.NewStanzaLimiter(10, 1, 16),
+		rq:      runqueue.New("in_c2s:test"),
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.Type, stravaganza.ChatType).
+		WithAttribute(stravaganza.ID, "msg_1").
+		BuildMessage()
+
+	_, _ = s.stzLim.Reserve(8) // drain limiter allowance
+
+	// when
+	handledCh := make(chan struct{})
+	go func() {
+		s.handleSessionResult(msg, nil)
+		close(handledCh)
+	}()
+	time.Sleep(time.Millisecond * 20)
+
+	// run queue keeps processing while stanza is held back
+	var routedWhileHeld bool
+	rqDoneCh := make(chan struct{})
+	s.rq.Run(func() {
+		mtx.Lock()
+		routedWhileHeld = routed
+		mtx.Unlock()
+		close(rqDoneCh)
+	})
+	<-rqDoneCh
+	<-handledCh
+
+	// then
+	require.False(t, routedWhileHeld)
+	require.True(t, routed)
+}
+
+func TestInC2S_AccountTierShaper(t *testing.T) {
+	// given
+	var premiumCfg, freeCfg shaper.Config
+	premiumCfg.Name = "premium"
+	premiumCfg.MaxSessions = 10
+	premiumCfg.Rate.Limit = 8192
+	premiumCfg.Matching.Tier = "premium"
+
+	freeCfg.Name = "free"
+	freeCfg.MaxSessions = 2
+	freeCfg.Rate.Limit = 1024
+
+	premiumShp, _ := shaper.New(premiumCfg)
+	freeShp, _ := shaper.New(freeCfg)
+	shapers := shaper.Shapers{premiumShp, freeShp}
+
+	repMock := &repositoryMock{}
+	repMock.FetchAccountFlagsFunc = func(ctx context.Context, username string) (map[string]bool, error) {
+		if username == "ortuman" {
+			return map[string]bool{"tier_premium": true}, nil
+		}
+		return nil, nil
+	}
+
+	authenticate := func(username string) (*inC2S, *rate.Limiter) {
+		var rLim *rate.Limiter
+
+		trMock := &transportMock{}
+		trMock.SetReadRateLimiterFunc = func(l *rate.Limiter) error {
+			rLim = l
+			return nil
+		}
+		trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
+
+		authMock := &authenticatorMock{}
+		authMock.UsernameFunc = func() string { return username }
+		authMock.ResetFunc = func() {}
+
+		ssMock := &sessionMock{}
+		ssMock.ResetFunc = func(_ transport.Transport) error { return nil }
+
+		hMock := &hostsMock{}
+		hMock.IsLocalHostFunc = func(h string) bool { return true }
+
+		stm := &inC2S{
+			state:   inAuthenticating,
+			tr:      trMock,
+			hosts:   hMock,
+			session: ssMock,
+			rep:     repMock,
+			shapers: shapers,
+			authSt: authState{
+				authenticators: []auth.Authenticator{authMock},
+				active:         authMock,
+			},
+			inf:    c2smodel.NewInfoMap(),
+			hk:     hook.NewHooks(),
+			logger: kitlog.NewNopLogger(),
+		}
+		stm.jd, _ = jid.NewWithString("jackal.im", true)
+
+		require.NoError(t, stm.finishAuthentication(context.Background()))
+		return stm, rLim
+	}
+
+	// when
+	premiumStm, premiumLim := authenticate("ortuman")
+	freeStm, freeLim := authenticate("noelia")
+
+	// then
+	require.Equal(t, "premium", premiumStm.tier)
+	require.Equal(t, "", freeStm.tier)
+	require.Equal(t, "premium", shaper.TierFromInfo(premiumStm.Info()))
+
+	require.Equal(t, rate.Limit(8192), premiumLim.Limit())
+	require.Equal(t, rate.Limit(1024), freeLim.Limit())
+
+	premiumMaxSessions := shapers.MatchingJIDOriginAndTier(premiumStm.JID(), premiumStm.origin, premiumStm.tier).MaxSessions
+	freeMaxSessions := shapers.MatchingJIDOriginAndTier(freeStm.JID(), freeStm.origin, freeStm.tier).MaxSessions
+	require.Greater(t, premiumMaxSessions, freeMaxSessions)
+
+	require.Len(t, repMock.FetchAccountFlagsCalls(), 2)
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import "time"
+
+type inFlightIQ struct {
+	id string
+	tm time.Time
+}
+
+// inFlightIQs keeps track of server-processed IQs awaiting a response.
+type inFlightIQs struct {
+	max     int
+	timeout time.Duration
+	nowFn   func() time.Time
+	iqs     []inFlightIQ
+}
+
+func newInFlightIQs(max int, timeout time.Duration) *inFlightIQs {
+	return &inFlightIQs{
+		max:     max,
+		timeout: timeout,
+		nowFn:   time.Now,
+	}
+}
+
+// acquire reserves an in-flight slot for an IQ identifier, returning false if no slot is available.
+func (f *inFlightIQs) acquire(id string) bool {
+	if f.max <= 0 {
+		return true
+	}
+	now := f.nowFn()
+
+	// discard IQs that were never answered
+	if f.timeout > 0 {
+		iqs := f.iqs[:0]
+		for _, iq := range f.iqs {
+			if now.Sub(iq.tm) < f.timeout {
+				iqs = append(iqs, iq)
+			}
+		}
+		f.iqs = iqs
+	}
+	if len(f.iqs) >= f.max {
+		return false
+	}
+	f.iqs = append(f.iqs, inFlightIQ{id: id, tm: now})
+	return true
+}
+
+// release frees the in-flight slot reserved for an IQ identifier.
+func (f *inFlightIQs) release(id string) {
+	for i, iq := range f.iqs {
+		if iq.id == id {
+			f.iqs = append(f.iqs[:i], f.iqs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *inFlightIQs) len() int {
+	return len(f.iqs)
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInFlightIQs_AcquireRelease(t *testing.T) {
+	// given
+	f := newInFlightIQs(2, time.Minute)
+
+	// when
+	ok0 := f.acquire("iq_1")
+	ok1 := f.acquire("iq_2")
+	ok2 := f.acquire("iq_3")
+
+	f.release("iq_1")
+	ok3 := f.acquire("iq_3")
+
+	// then
+	require.True(t, ok0)
+	require.True(t, ok1)
+	require.False(t, ok2)
+	require.True(t, ok3)
+	require.Equal(t, 2, f.len())
+}
+
+func TestInFlightIQs_Timeout(t *testing.T) {
+	// given
+	tm := time.Now()
+
+	f := newInFlightIQs(1, time.Minute)
+	f.nowFn = func() time.Time { return tm }
+
+	// when
+	ok0 := f.acquire("iq_1")
+	ok1 := f.acquire("iq_2")
+
+	tm = tm.Add(time.Minute) // iq_1 never answered
+	ok2 := f.acquire("iq_2")
+
+	// then
+	require.True(t, ok0)
+	require.False(t, ok1)
+	require.True(t, ok2)
+	require.Equal(t, 1, f.len())
+}
+
+func TestInFlightIQs_Unlimited(t *testing.T) {
+	// given
+	f := newInFlightIQs(0, time.Minute)
+
+	// when
+	for i := 0; i < 100; i++ {
+		require.True(t, f.acquire("iq"))
+	}
+
+	// then
+	require.Equal(t, 0, f.len())
+}
@@ -17,6 +17,7 @@ package c2s
 import (
 	"context"
 	"crypto/tls"
+	"time"
 
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
@@ -75,6 +76,9 @@ type globalC2SRouter interface {
 type hosts interface {
 	Certificates() []tls.Certificate
 	IsLocalHost(host string) bool
+	AcquireSession(host string) bool
+	ReleaseSession(host string)
+	AutoPresenceWindow(host string) time.Duration
 }
 
 //go:generate moq -out session.mock_test.go . session
@@ -83,11 +87,14 @@ type session interface {
 
 	Send(ctx context.Context, element stravaganza.Element) error
 	Receive() (stravaganza.Element, error)
+	LastElementSize() int
 
 	OpenStream(ctx context.Context) error
 	Close(ctx context.Context) error
 
 	Reset(tr transport.Transport) error
+
+	SetWhitespaceKeepAliveHandler(hnd func())
 }
 
 //go:generate moq -out localrouter.mock_test.go . localRouter
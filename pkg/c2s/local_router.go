@@ -23,6 +23,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/host"
+	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 )
 
@@ -39,10 +40,11 @@ var errStreamNotFound = func(id stream.C2SID) error {
 type LocalRouter struct {
 	hosts hosts
 
-	mu     sync.RWMutex
-	stms   map[stream.C2SID]stream.C2S
-	bndRes map[string]*resources
-	doneCh chan chan struct{}
+	mu      sync.RWMutex
+	stms    map[stream.C2SID]stream.C2S
+	bndRes  map[string]*resources
+	drained map[string]struct{}
+	doneCh  chan chan struct{}
 }
 
 // NewLocalRouter returns a new initialized local router.
@@ -56,17 +58,46 @@ func NewLocalRouter(hosts *host.Hosts) *LocalRouter {
 }
 
 // Route routes a stanza to a local router resource.
+// Stanzas addressed to a drained resource are never delivered, as if the resource was not found.
 func (r *LocalRouter) Route(stanza stravaganza.Stanza, username, resource string) error {
 	r.mu.RLock()
 	rs := r.bndRes[username]
+	drained := r.isDrained(username, resource)
 	r.mu.RUnlock()
 
-	if rs == nil {
-		return nil
+	if rs == nil || drained {
+		return router.ErrResourceNotFound
 	}
 	return rs.route(stanza, resource)
 }
 
+// Drain stops routing stanzas to a local account resource.
+// In case resource is empty all account resources will be drained, including the ones bound afterwards.
+func (r *LocalRouter) Drain(username, resource string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.drained == nil {
+		r.drained = make(map[string]struct{})
+	}
+	r.drained[drainKey(username, resource)] = struct{}{}
+}
+
+// Undrain resumes routing stanzas to a previously drained local account resource.
+func (r *LocalRouter) Undrain(username, resource string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.drained, drainKey(username, resource))
+}
+
+// IsDrained tells whether a local account resource is drained.
+func (r *LocalRouter) IsDrained(username, resource string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isDrained(username, resource)
+}
+
 // Disconnect performs disconnection over a local router resource.
 func (r *LocalRouter) Disconnect(username, resource string, streamErr *streamerror.Error) error {
 	r.mu.RLock()
@@ -190,6 +221,24 @@ func (r *LocalRouter) Stop(ctx context.Context) error {
 	return nil
 }
 
+func (r *LocalRouter) isDrained(username, resource string) bool {
+	if len(r.drained) == 0 {
+		return false
+	}
+	if _, ok := r.drained[drainKey(username, "")]; ok {
+		return true // whole account drained
+	}
+	_, ok := r.drained[drainKey(username, resource)]
+	return ok
+}
+
+func drainKey(username, resource string) string {
+	if len(resource) == 0 {
+		return username
+	}
+	return username + "/" + resource
+}
+
 func (r *LocalRouter) reportMetrics() {
 	tc := time.NewTicker(reportTotalConnectionsInterval)
 	defer tc.Stop()
@@ -21,6 +21,7 @@ import (
 
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/stretchr/testify/require"
 )
@@ -175,6 +176,71 @@ func TestLocalRouter_Route(t *testing.T) {
 	require.Equal(t, stanza.String(), sentElement.String())
 }
 
+func TestLocalRouter_RouteResourceNotFound(t *testing.T) {
+	// given
+	mockStm := &c2sStreamMock{}
+	mockStm.IDFunc = func() stream.C2SID { return 1234 }
+	mockStm.UsernameFunc = func() string { return "ortuman" }
+	mockStm.ResourceFunc = func() string { return "yard" }
+
+	r := &LocalRouter{
+		hosts:  &hostsMock{},
+		stms:   make(map[stream.C2SID]stream.C2S),
+		bndRes: make(map[string]*resources),
+	}
+
+	_ = r.Register(mockStm)
+	_, _ = r.Bind(1234)
+
+	// when
+	stanza := testMessageStanza()
+	err1 := r.Route(stanza, "ortuman", "balcony")
+	err2 := r.Route(stanza, "noelia", "yard")
+
+	// then
+	require.Equal(t, router.ErrResourceNotFound, err1)
+	require.Equal(t, router.ErrResourceNotFound, err2)
+	require.Len(t, mockStm.SendElementCalls(), 0)
+}
+
+func TestLocalRouter_RouteDrained(t *testing.T) {
+	// given
+	mockStm := &c2sStreamMock{}
+	mockStm.IDFunc = func() stream.C2SID { return 1234 }
+	mockStm.UsernameFunc = func() string { return "ortuman" }
+	mockStm.ResourceFunc = func() string { return "yard" }
+	mockStm.SendElementFunc = func(elem stravaganza.Element) <-chan error { return nil }
+
+	r := &LocalRouter{
+		hosts:  &hostsMock{},
+		stms:   make(map[stream.C2SID]stream.C2S),
+		bndRes: make(map[string]*resources),
+	}
+
+	_ = r.Register(mockStm)
+	_, _ = r.Bind(1234)
+
+	stanza := testMessageStanza()
+
+	// when
+	r.Drain("ortuman", "")
+	err1 := r.Route(stanza, "ortuman", "yard")
+
+	r.Undrain("ortuman", "")
+	r.Drain("ortuman", "yard")
+	err2 := r.Route(stanza, "ortuman", "yard")
+
+	r.Undrain("ortuman", "yard")
+	err3 := r.Route(stanza, "ortuman", "yard")
+
+	// then
+	require.Equal(t, router.ErrResourceNotFound, err1)
+	require.Equal(t, router.ErrResourceNotFound, err2)
+	require.Nil(t, err3)
+	require.False(t, r.IsDrained("ortuman", "yard"))
+	require.Len(t, mockStm.SendElementCalls(), 1)
+}
+
 func TestLocalRouter_Disconnect(t *testing.T) {
 	// given
 	mockStm := &c2sStreamMock{}
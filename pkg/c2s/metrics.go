@@ -15,6 +15,7 @@
 package c2s
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/ortuman/jackal/pkg/cluster/instance"
@@ -70,6 +71,15 @@ var (
 		},
 		[]string{"instance", "name", "type"},
 	)
+	c2sWhitespaceKeepAlives = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "whitespace_keepalives_total",
+			Help:      "The total number of received whitespace keepalives.",
+		},
+		[]string{"instance", "throttled"},
+	)
 	c2sIncomingTotalConnections = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "jackal",
@@ -79,6 +89,51 @@ var (
 		},
 		[]string{"instance"},
 	)
+	c2sClientSoftwareSessions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "client_software_sessions",
+			Help:      "Total bound C2S sessions by normalized client software.",
+		},
+		[]string{"instance", "client"},
+	)
+	c2sUnknownSASLMechanisms = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "sasl_unknown_mechanism_total",
+			Help:      "The total number of authentication attempts using an unadvertised SASL mechanism.",
+		},
+		[]string{"instance"},
+	)
+	c2sShapedStanzas = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "shaped_stanzas_total",
+			Help:      "The total number of incoming stanzas delayed or rejected by the stanza shaper.",
+		},
+		[]string{"instance", "rejected"},
+	)
+	c2sTLSHandshakeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "tls_handshake_failures_total",
+			Help:      "The total number of failed TLS handshakes by failure reason.",
+		},
+		[]string{"instance", "reason"},
+	)
+	c2sTLSBlockedConnections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "c2s",
+			Name:      "tls_blocked_connections_total",
+			Help:      "The total number of connections rejected from addresses blocked after repeated TLS handshake failures.",
+		},
+		[]string{"instance"},
+	)
 )
 
 func init() {
@@ -87,7 +142,13 @@ func init() {
 	prometheus.MustRegister(c2sOutgoingRequests)
 	prometheus.MustRegister(c2sIncomingRequests)
 	prometheus.MustRegister(c2sIncomingRequestDurationBucket)
+	prometheus.MustRegister(c2sWhitespaceKeepAlives)
 	prometheus.MustRegister(c2sIncomingTotalConnections)
+	prometheus.MustRegister(c2sClientSoftwareSessions)
+	prometheus.MustRegister(c2sUnknownSASLMechanisms)
+	prometheus.MustRegister(c2sShapedStanzas)
+	prometheus.MustRegister(c2sTLSHandshakeFailures)
+	prometheus.MustRegister(c2sTLSBlockedConnections)
 }
 
 func reportOutgoingRequest(name, typ string) {
@@ -109,6 +170,14 @@ func reportIncomingRequest(name, typ string, durationInSecs float64) {
 	c2sIncomingRequestDurationBucket.With(metricLabel).Observe(durationInSecs)
 }
 
+func reportWhitespaceKeepAlive(throttled bool) {
+	metricLabel := prometheus.Labels{
+		"instance":  instance.ID(),
+		"throttled": strconv.FormatBool(throttled),
+	}
+	c2sWhitespaceKeepAlives.With(metricLabel).Inc()
+}
+
 func reportConnectionRegistered() {
 	metricLabel := prometheus.Labels{
 		"instance": instance.ID(),
@@ -129,3 +198,48 @@ func reportTotalIncomingConnections(totalConns int) {
 	}
 	c2sIncomingTotalConnections.With(metricLabel).Set(float64(totalConns))
 }
+
+func reportUnknownSASLMechanism() {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	c2sUnknownSASLMechanisms.With(metricLabel).Inc()
+}
+
+func reportStanzaShaped(rejected bool) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"rejected": strconv.FormatBool(rejected),
+	}
+	c2sShapedStanzas.With(metricLabel).Inc()
+}
+
+func reportClientSoftwareChanged(prevLabel, label string) {
+	if len(prevLabel) > 0 {
+		c2sClientSoftwareSessions.With(prometheus.Labels{
+			"instance": instance.ID(),
+			"client":   prevLabel,
+		}).Dec()
+	}
+	if len(label) > 0 {
+		c2sClientSoftwareSessions.With(prometheus.Labels{
+			"instance": instance.ID(),
+			"client":   label,
+		}).Inc()
+	}
+}
+
+func reportTLSHandshakeFailure(reason string) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"reason":   reason,
+	}
+	c2sTLSHandshakeFailures.With(metricLabel).Inc()
+}
+
+func reportTLSBlockedConnection() {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	c2sTLSBlockedConnections.With(metricLabel).Inc()
+}
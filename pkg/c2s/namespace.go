@@ -22,4 +22,6 @@ const (
 	bindNamespace          = "urn:ietf:params:xml:ns:xmpp-bind"
 	sessionNamespace       = "urn:ietf:params:xml:ns:xmpp-session"
 	blockingErrorNamespace = "urn:xmpp:blocking:errors"
+	httpBindNamespace      = "http://jabber.org/protocol/httpbind"
+	xboshNamespace         = "urn:xmpp:xbosh"
 )
@@ -19,6 +19,7 @@ import (
 
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
+	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 )
 
@@ -81,11 +82,16 @@ func (r *resources) route(stanza stravaganza.Stanza, resource string) error {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	var routed bool
 	for _, s := range r.stms {
 		if s.Resource() != resource {
 			continue
 		}
 		s.SendElement(stanza)
+		routed = true
+	}
+	if !routed {
+		return router.ErrResourceNotFound
 	}
 	return nil
 }
@@ -135,6 +135,9 @@ func (r *c2sRouter) Stop(ctx context.Context) error {
 
 func (r *c2sRouter) route(ctx context.Context, stanza stravaganza.Stanza, resources []c2smodel.ResourceDesc) ([]jid.JID, error) {
 	if len(resources) == 0 {
+		if _, ok := stanza.(*stravaganza.Message); ok {
+			return nil, r.runUndeliveredHook(ctx, stanza)
+		}
 		return nil, router.ErrUserNotAvailable
 	}
 	var targets []jid.JID
@@ -161,32 +164,58 @@ func (r *c2sRouter) route(ctx context.Context, stanza stravaganza.Stanza, resour
 		})
 		p0 := resources[0].Priority() // highest priority
 
-		var routed bool
+		var undelivered bool
 		for _, res := range resources {
 			if res.Priority() < 0 || res.Priority() != p0 {
 				break
 			}
-			if err := r.routeTo(ctx, stanza, res); err != nil {
+			switch err := r.routeTo(ctx, stanza, res); err {
+			case nil:
+				targets = append(targets, *res.JID())
+			case router.ErrResourceNotFound:
+				undelivered = true // resource went away before delivery
+			default:
 				return nil, err
 			}
-			targets = append(targets, *res.JID())
-			routed = true
 		}
-		if !routed {
-			return nil, router.ErrUserNotAvailable
+		if len(targets) > 0 {
+			return targets, nil
 		}
-		return targets, nil
+		if undelivered {
+			return nil, r.runUndeliveredHook(ctx, stanza)
+		}
+		return nil, router.ErrUserNotAvailable
 	}
 	// broadcast to all resources
 	for _, res := range resources {
-		if err := r.routeTo(ctx, stanza, res); err != nil {
+		switch err := r.routeTo(ctx, stanza, res); err {
+		case nil:
+			targets = append(targets, *res.JID())
+		case router.ErrResourceNotFound:
+			continue
+		default:
 			return nil, err
 		}
-		targets = append(targets, *res.JID())
 	}
 	return targets, nil
 }
 
+func (r *c2sRouter) runUndeliveredHook(ctx context.Context, stanza stravaganza.Stanza) error {
+	halted, err := r.hk.Run(ctx, hook.C2SRouterMessageUndelivered, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stanza,
+		},
+		Sender: r,
+	})
+	if err != nil {
+		return err
+	}
+	if halted {
+		return nil // already handled
+	}
+	return router.ErrUserNotAvailable
+}
+
 func (r *c2sRouter) routeTo(ctx context.Context, stanza stravaganza.Stanza, toRes c2smodel.ResourceDesc) error {
 	var username, resource = toRes.JID().Node(), toRes.JID().Resource()
 
@@ -24,6 +24,7 @@ import (
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -81,6 +82,28 @@ func (s *routerSuite) TestRouter_NotAuthenticated() {
 	s.Require().Equal(router.ErrUserNotAvailable, err)
 }
 
+func (s *routerSuite) TestRouter_NotAuthenticatedMessageStoredOffline() {
+	// given
+	s.resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+	var undelivered stravaganza.Element
+	s.router.hk.AddHook(hook.C2SRouterMessageUndelivered, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		undelivered = execCtx.Info.(*hook.C2SStreamInfo).Element
+		return hook.ErrStopped // stored offline
+	}, hook.DefaultPriority)
+
+	// when
+	msg := testBareMessageStanza()
+	targets, err := s.router.Route(context.Background(), msg, router.RoutingOptions(0))
+
+	// then
+	s.Require().Nil(err)
+	s.Require().Len(targets, 0)
+	s.Require().NotNil(undelivered)
+	s.Require().Equal(msg.String(), undelivered.String())
+}
+
 func (s *routerSuite) TestRouter_ResourceNotFound() {
 	// given
 	jd, _ := jid.New("ortuman", "jackal.im", "yard", true)
@@ -157,6 +180,89 @@ func (s *routerSuite) TestRouter_ClusterRoute() {
 	s.Require().True(routed)
 }
 
+func (s *routerSuite) TestRouter_ClusterRouteUndelivered() {
+	// given
+	s.resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			testResource("abcd1234", 1, "ortuman", "balcony"),
+		}, nil
+	}
+	s.clusterRouterMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza, username string, resource string, instanceID string) error {
+		return router.ErrResourceNotFound
+	}
+	var undelivered stravaganza.Element
+	s.router.hk.AddHook(hook.C2SRouterMessageUndelivered, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		undelivered = execCtx.Info.(*hook.C2SStreamInfo).Element
+		return hook.ErrStopped // stored offline
+	}, hook.DefaultPriority)
+
+	// when
+	msg := testBareMessageStanza()
+	targets, err := s.router.Route(context.Background(), msg, router.RoutingOptions(0))
+
+	// then
+	s.Require().Nil(err)
+	s.Require().Len(targets, 0)
+	s.Require().NotNil(undelivered)
+	s.Require().Equal(msg.String(), undelivered.String())
+}
+
+func (s *routerSuite) TestRouter_ClusterRouteUndeliveredUnhandled() {
+	// given
+	s.resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			testResource("abcd1234", 1, "ortuman", "balcony"),
+		}, nil
+	}
+	s.clusterRouterMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza, username string, resource string, instanceID string) error {
+		return router.ErrResourceNotFound
+	}
+
+	// when
+	msg := testBareMessageStanza()
+	_, err := s.router.Route(context.Background(), msg, router.RoutingOptions(0))
+
+	// then
+	s.Require().Equal(router.ErrUserNotAvailable, err)
+}
+
+func (s *routerSuite) TestRouter_DrainedAccountMessageStoredOffline() {
+	// given
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.UsernameFunc = func() string { return "ortuman" }
+	stmMock.ResourceFunc = func() string { return "balcony" }
+
+	localRouter := NewLocalRouter(nil)
+	_ = localRouter.Register(stmMock)
+	_, _ = localRouter.Bind(1234)
+
+	localRouter.Drain("ortuman", "")
+	s.router.local = localRouter
+
+	s.resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			testResource(instance.ID(), 1, "ortuman", "balcony"),
+		}, nil
+	}
+	var undelivered stravaganza.Element
+	s.router.hk.AddHook(hook.C2SRouterMessageUndelivered, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		undelivered = execCtx.Info.(*hook.C2SStreamInfo).Element
+		return hook.ErrStopped // stored offline
+	}, hook.DefaultPriority)
+
+	// when
+	msg := testBareMessageStanza()
+	targets, err := s.router.Route(context.Background(), msg, router.RoutingOptions(0))
+
+	// then
+	s.Require().Nil(err)
+	s.Require().Len(targets, 0)
+	s.Require().NotNil(undelivered)
+	s.Require().Equal(msg.String(), undelivered.String())
+	s.Require().Len(stmMock.SendElementCalls(), 0)
+}
+
 func TestC2SRouterSuite(t *testing.T) {
 	suite.Run(t, new(routerSuite))
 }
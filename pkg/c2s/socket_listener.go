@@ -17,8 +17,11 @@ package c2s
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"net"
+	"net/http"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -26,16 +29,23 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/ortuman/jackal/pkg/auth"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
+	"github.com/ortuman/jackal/pkg/cluster/distratelimit"
+	"github.com/ortuman/jackal/pkg/cluster/kv"
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/component"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/tlsticket"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/ortuman/jackal/pkg/util/proxyproto"
+	"golang.org/x/net/websocket"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -45,8 +55,16 @@ const (
 	scramSHA256Mechanism  = "scram_sha_256"
 	scramSHA512Mechanism  = "scram_sha_512"
 	scramSHA3512Mechanism = "scram_sha3_512"
+
+	webSocketTransport   = "websocket"
+	boshTransport        = "bosh"
+	webSocketSubprotocol = "xmpp"
+
+	authRateLimiterName = "c2s_auth"
 )
 
+var errMissingXMPPSubprotocol = errors.New("c2s: missing 'xmpp' WebSocket subprotocol")
+
 var cmpLevelMap = map[string]compress.Level{
 	"default": compress.DefaultCompression,
 	"best":    compress.BestCompression,
@@ -63,6 +81,7 @@ var resConflictMap = map[string]resourceConflict{
 type SocketListener struct {
 	cfg     ListenerConfig
 	extAuth *auth.External
+	authLim *distratelimit.Limiter
 	hosts   *host.Hosts
 	router  router.Router
 	comps   *component.Components
@@ -70,15 +89,25 @@ type SocketListener struct {
 	resMng  resourcemanager.Manager
 	rep     repository.Repository
 	peppers *pepper.Keys
-	shapers shaper.Shapers
+	geoIP   geoip.Provider
+	tks     *tlsticket.Manager
 	hk      *hook.Hooks
 	logger  kitlog.Logger
 
+	shMu    sync.RWMutex
+	shapers shaper.Shapers
+	shName  string
+
 	tlsCfg        *tls.Config
+	hsBlocker     *handshakeBlocker
 	connHandlerFn func(conn net.Conn)
+	wsHandlerFn   func(ws *websocket.Conn)
+	boshHnd       *boshHandler
 
-	ln     net.Listener
-	active uint32
+	ln       net.Listener
+	httpSrv  *http.Server
+	active   uint32
+	draining uint32
 }
 
 // NewListeners creates and initializes a set of C2S listeners based of cfg configuration.
@@ -89,9 +118,12 @@ func NewListeners(
 	comps *component.Components,
 	mods *module.Modules,
 	resMng resourcemanager.Manager,
+	kv kv.KV,
 	rep repository.Repository,
 	peppers *pepper.Keys,
 	shapers shaper.Shapers,
+	geoIP geoip.Provider,
+	tks *tlsticket.Manager,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) []*SocketListener {
@@ -104,9 +136,12 @@ func NewListeners(
 			comps,
 			mods,
 			resMng,
+			kv,
 			rep,
 			peppers,
 			shapers,
+			geoIP,
+			tks,
 			hk,
 			logger,
 		)
@@ -122,9 +157,12 @@ func newSocketListener(
 	comps *component.Components,
 	mods *module.Modules,
 	resMng resourcemanager.Manager,
+	kv kv.KV,
 	rep repository.Repository,
 	peppers *pepper.Keys,
 	shapers shaper.Shapers,
+	geoIP geoip.Provider,
+	tks *tlsticket.Manager,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *SocketListener {
@@ -135,9 +173,14 @@ func newSocketListener(
 			cfg.SASL.External.IsSecure,
 		)
 	}
+	var authLim *distratelimit.Limiter
+	if cfg.SASL.RateLimit.Enabled() {
+		authLim = distratelimit.New(authRateLimiterName, cfg.SASL.RateLimit, kv)
+	}
 	ln := &SocketListener{
 		cfg:     cfg,
 		extAuth: extAuth,
+		authLim: authLim,
 		hosts:   hosts,
 		router:  router,
 		comps:   comps,
@@ -146,10 +189,27 @@ func newSocketListener(
 		rep:     rep,
 		peppers: peppers,
 		shapers: shapers,
+		shName:  cfg.Shaper,
+		geoIP:   geoIP,
+		tks:     tks,
 		hk:      hk,
 		logger:  logger,
 	}
+	if blockCfg := cfg.TLSHandshakeFailure.Block; blockCfg.Threshold > 0 {
+		ln.hsBlocker = newHandshakeBlocker(blockCfg.Threshold, blockCfg.Window, blockCfg.Duration)
+	}
 	ln.connHandlerFn = ln.handleConn
+	ln.wsHandlerFn = ln.handleWebSocket
+	if cfg.Transport == boshTransport {
+		ln.boshHnd = newBOSHHandler(boshCfg{
+			wait:           cfg.BOSH.Wait,
+			hold:           cfg.BOSH.Hold,
+			requests:       cfg.BOSH.Requests,
+			inactivity:     cfg.BOSH.Inactivity,
+			allowedOrigins: cfg.BOSH.AllowedOrigins,
+			maxStanzaSize:  cfg.MaxStanzaSize,
+		}, ln.startBOSHStream, logger)
+	}
 	return ln
 }
 
@@ -161,6 +221,13 @@ func (l *SocketListener) Start(ctx context.Context) error {
 			return err
 		}
 	}
+	if l.boshHnd != nil {
+		// BOSH requests are served by the shared HTTP server
+		atomic.StoreUint32(&l.active, 1)
+
+		level.Info(l.logger).Log("msg", "accepting C2S BOSH connections", "path", l.cfg.BOSH.Path)
+		return nil
+	}
 	var err error
 	var ln net.Listener
 
@@ -171,16 +238,35 @@ func (l *SocketListener) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if l.cfg.ProxyProtocol {
+		ln = proxyproto.NewListener(ln, l.cfg.ConnectTimeout)
+	}
 	if l.cfg.DirectTLS {
 		l.tlsCfg = &tls.Config{
 			Certificates: l.hosts.Certificates(),
 			MinVersion:   tls.VersionTLS12,
 		}
+		if l.tks != nil {
+			l.tks.Register(l.tlsCfg)
+		}
 		ln = tls.NewListener(ln, l.tlsCfg)
 	}
 	l.ln = ln
 	l.active = 1
 
+	if l.cfg.Transport == webSocketTransport {
+		l.serveWebSocket()
+
+		level.Info(l.logger).Log("msg", "accepting C2S WebSocket connections",
+			"bind_addr", l.getAddress(),
+			"path", l.cfg.WebSocket.Path,
+			"direct_tls", l.cfg.DirectTLS,
+		)
+		return nil
+	}
+	if l.hsBlocker != nil {
+		l.hsBlocker.start()
+	}
 	go func() {
 		for atomic.LoadUint32(&l.active) == 1 {
 			conn, err := l.ln.Accept()
@@ -201,8 +287,22 @@ func (l *SocketListener) Start(ctx context.Context) error {
 // Stop stops handling incoming C2S connections and closes underlying TCP listener.
 func (l *SocketListener) Stop(ctx context.Context) error {
 	atomic.StoreUint32(&l.active, 0)
-	if err := l.ln.Close(); err != nil {
-		return err
+	switch {
+	case l.boshHnd != nil:
+		l.boshHnd.closeSessions()
+
+	case l.httpSrv != nil:
+		if err := l.httpSrv.Shutdown(ctx); err != nil {
+			return err
+		}
+
+	default:
+		if l.hsBlocker != nil {
+			l.hsBlocker.stop()
+		}
+		if err := l.ln.Close(); err != nil {
+			return err
+		}
 	}
 	if l.extAuth != nil {
 		// close external authenticator conn
@@ -214,30 +314,216 @@ func (l *SocketListener) Stop(ctx context.Context) error {
 	return nil
 }
 
+// UpdateShaperBinding updates the shaper collection and the name of the shaper bound to the listener.
+// Changes only apply to connections accepted after the update, while already established ones
+// keep their current shaper.
+func (l *SocketListener) UpdateShaperBinding(shaperName string, shapers shaper.Shapers) {
+	l.shMu.Lock()
+	l.shapers = shapers
+	l.shName = shaperName
+	l.shMu.Unlock()
+
+	level.Info(l.logger).Log("msg", "updated C2S listener shaper binding",
+		"bind_addr", l.getAddress(),
+		"shaper", shaperName,
+	)
+}
+
+// SetDraining sets listener draining mode. While draining, new incoming streams are rejected
+// with a system-shutdown stream error, while already established ones are kept.
+func (l *SocketListener) SetDraining(draining bool) {
+	var val uint32
+	if draining {
+		val = 1
+	}
+	atomic.StoreUint32(&l.draining, val)
+}
+
+// HTTPHandler returns the handler serving listener connections over the shared HTTP server,
+// along with the path it should be registered at. A nil handler is returned if connections are served by the listener itself.
+func (l *SocketListener) HTTPHandler() (string, http.Handler) {
+	if l.boshHnd == nil {
+		return "", nil
+	}
+	return l.cfg.BOSH.Path, l.boshHnd
+}
+
+// BindAddress returns the listener bind address.
+func (l *SocketListener) BindAddress() string {
+	return l.getAddress()
+}
+
+func (l *SocketListener) serveWebSocket() {
+	wsSrv := websocket.Server{
+		Handshake: func(cfg *websocket.Config, _ *http.Request) error {
+			for _, protocol := range cfg.Protocol {
+				if protocol == webSocketSubprotocol {
+					cfg.Protocol = []string{webSocketSubprotocol}
+					return nil
+				}
+			}
+			return errMissingXMPPSubprotocol
+		},
+		Handler: func(ws *websocket.Conn) {
+			l.wsHandlerFn(ws)
+		},
+	}
+	mux := http.NewServeMux()
+	mux.Handle(l.cfg.WebSocket.Path, wsSrv)
+
+	l.httpSrv = &http.Server{Handler: mux}
+	go func() {
+		if err := l.httpSrv.Serve(l.ln); err != nil && err != http.ErrServerClosed {
+			level.Warn(l.logger).Log("msg", "failed to serve C2S WebSocket connections", "err", err)
+		}
+	}()
+}
+
 func (l *SocketListener) handleConn(conn net.Conn) {
-	tr := transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout)
+	if pc, ok := conn.(*proxyproto.Conn); ok {
+		// reject connection before any XML is parsed
+		if err := pc.ReadHeader(); err != nil {
+			level.Warn(l.logger).Log("msg", "failed to read C2S PROXY protocol header", "err", err)
+			_ = conn.Close()
+			return
+		}
+	}
+	remoteIP := geoip.AddrIP(conn.RemoteAddr()).String()
+	if l.hsBlocker != nil && l.hsBlocker.isBlocked(remoteIP) {
+		reportTLSBlockedConnection()
+		level.Debug(l.logger).Log("msg", "rejected C2S connection from blocked address", "remote_ip", remoteIP)
+		_ = conn.Close()
+		return
+	}
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		_ = l.startStream(l.newSocketTransport(conn), conn.RemoteAddr())
+		return
+	}
+	// direct TLS: handshake completes before any XML is read
+	tr, err := transport.NewTLSSocketTransport(
+		tlsConn,
+		l.cfg.ConnectTimeout,
+		l.keepAliveTimeout(),
+		l.cfg.ReadBufferSize,
+		l.cfg.MaxPooledWriteBufferSize,
+		l.cfg.FlushCoalescingDelay,
+	)
+	if err != nil {
+		l.handleTLSHandshakeFailure(remoteIP, err)
+		_ = conn.Close()
+		return
+	}
+	_ = l.startStream(tr, conn.RemoteAddr())
+}
+
+func (l *SocketListener) handleTLSHandshakeFailure(remoteIP string, err error) {
+	reason := transport.TLSHandshakeFailureReason(err)
+	reportTLSHandshakeFailure(reason)
+
+	var logger kitlog.Logger
+	switch l.cfg.TLSHandshakeFailure.LogLevel {
+	case tlsFailureOffLogLevel:
+		// failures are only reported as metrics
+	case tlsFailureDebugLogLevel:
+		logger = level.Debug(l.logger)
+	case tlsFailureInfoLogLevel:
+		logger = level.Info(l.logger)
+	default:
+		logger = level.Warn(l.logger)
+	}
+	if logger != nil {
+		logger.Log("msg", "failed to complete C2S TLS handshake", "reason", reason, "remote_ip", remoteIP, "err", err)
+	}
+	if l.hsBlocker == nil {
+		return
+	}
+	if l.hsBlocker.reportFailure(remoteIP) {
+		level.Warn(l.logger).Log("msg", "blocked C2S remote address after repeated TLS handshake failures",
+			"remote_ip", remoteIP,
+			"duration", l.cfg.TLSHandshakeFailure.Block.Duration,
+		)
+	}
+}
+
+func (l *SocketListener) handleWebSocket(ws *websocket.Conn) {
+	// WebSocket connection remote address refers to the handshake origin
+	remoteAddr, err := net.ResolveTCPAddr("tcp", ws.Request().RemoteAddr)
+	if err != nil {
+		level.Warn(l.logger).Log("msg", "failed to resolve WebSocket remote address", "err", err)
+		return
+	}
+	stm := l.startStream(l.newWebSocketTransport(ws), remoteAddr)
+	if stm == nil {
+		return
+	}
+	<-stm.Done() // connection is released as soon as the handler returns
+}
+
+func (l *SocketListener) newSocketTransport(conn net.Conn) transport.Transport {
+	return transport.NewSocketTransport(
+		conn,
+		l.cfg.ConnectTimeout,
+		l.keepAliveTimeout(),
+		l.cfg.ReadBufferSize,
+		l.cfg.MaxPooledWriteBufferSize,
+		l.cfg.FlushCoalescingDelay,
+	)
+}
+
+func (l *SocketListener) newWebSocketTransport(ws *websocket.Conn) transport.Transport {
+	return transport.NewWebSocketTransport(ws, l.cfg.ConnectTimeout, l.keepAliveTimeout())
+}
+
+// keepAliveTimeout returns the keep alive timeout that applies to the listener transport type.
+func (l *SocketListener) keepAliveTimeout() time.Duration {
+	if l.cfg.Transport == webSocketTransport && l.cfg.WebSocket.KeepAliveTimeout > 0 {
+		return l.cfg.WebSocket.KeepAliveTimeout
+	}
+	return l.cfg.KeepAliveTimeout
+}
+
+func (l *SocketListener) startBOSHStream(tr transport.Transport, remoteAddr net.Addr) <-chan struct{} {
+	stm := l.startStream(tr, remoteAddr)
+	if stm == nil {
+		return nil
+	}
+	return stm.Done()
+}
+
+func (l *SocketListener) startStream(tr transport.Transport, remoteAddr net.Addr) *inC2S {
 	stm, err := newInC2S(
 		l.getInConfig(),
 		tr,
+		geoip.AddrIP(remoteAddr),
+		geoip.Tag(context.Background(), l.geoIP, remoteAddr),
 		l.getAuthenticators(tr),
 		l.hosts,
 		l.router,
 		l.comps,
 		l.mods,
 		l.resMng,
-		l.shapers,
+		l.rep,
+		l.getShapers(),
 		l.hk,
 		l.logger,
 	)
 	if err != nil {
 		level.Warn(l.logger).Log("msg", "failed to initialize C2S stream", "err", err)
-		return
+		return nil
 	}
 	// start reading stream
 	if err := stm.start(); err != nil {
 		level.Warn(l.logger).Log("msg", "failed to start C2S stream", "err", err)
-		return
+		return nil
 	}
+	return stm
+}
+
+func (l *SocketListener) getShapers() shaper.Shapers {
+	l.shMu.RLock()
+	defer l.shMu.RUnlock()
+	return l.shapers.Bound(l.shName)
 }
 
 func (l *SocketListener) getAuthenticators(tr transport.Transport) []auth.Authenticator {
@@ -274,10 +560,36 @@ func (l *SocketListener) getInConfig() inCfg {
 		authenticateTimeout: l.cfg.AuthenticateTimeout,
 		reqTimeout:          l.cfg.RequestTimeout,
 		maxStanzaSize:       l.cfg.MaxStanzaSize,
+		maxStanzaDepth:      l.cfg.MaxStanzaDepth,
+		maxAsmBufSize:       l.cfg.MaxAssemblyBufferSize,
+		overwriteFrom:       l.cfg.OverwriteSpoofedFrom,
 		compressionLevel:    cmpLevelMap[l.cfg.CompressionLevel],
 		resConflict:         resConflictMap[l.cfg.ResourceConflict],
+		iqBareFallback:      l.cfg.BareJIDFallbackIQNamespaces,
 		useTLS:              l.cfg.DirectTLS,
 		tlsConfig:           l.tlsCfg,
+		ticketKeys:          l.tks,
+		resBinding: resBindingCfg{
+			maxLength:            l.cfg.ResourceBinding.MaxLength,
+			disallowedChars:      l.cfg.ResourceBinding.DisallowedChars,
+			forceServerGenerated: l.cfg.ResourceBinding.ForceServerGenerated,
+		},
+		wsKeepAlive: wsKeepAliveCfg{
+			updateLastActivity: l.cfg.WhitespaceKeepAlive.UpdateLastActivity,
+			maxRate:            rate.Limit(l.cfg.WhitespaceKeepAlive.MaxRate),
+			burst:              l.cfg.WhitespaceKeepAlive.Burst,
+		},
+		iqInFlight: iqInFlightCfg{
+			max:     l.cfg.InFlightIQ.Max,
+			timeout: l.cfg.InFlightIQ.Timeout,
+		},
+		unknownMech: unknownMechCfg{
+			track:          l.cfg.SASL.UnknownMechanism.Track,
+			countAsFailure: l.cfg.SASL.UnknownMechanism.CountAsFailure,
+		},
+		draining:     atomic.LoadUint32(&l.draining) == 1,
+		authLimiter:  l.authLim,
+		tlsFailureFn: l.handleTLSHandshakeFailure,
 	}
 }
 
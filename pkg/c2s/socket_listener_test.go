@@ -16,13 +16,25 @@ package c2s
 
 import (
 	"context"
+	"crypto/tls"
+	"io"
 	"net"
+	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/ortuman/jackal/pkg/shaper"
+	"github.com/ortuman/jackal/pkg/util/proxyproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+	"golang.org/x/time/rate"
 )
 
 func TestSocketListener_Listen(t *testing.T) {
@@ -54,3 +66,231 @@ func TestSocketListener_Listen(t *testing.T) {
 
 	require.Equal(t, uint32(0), atomic.LoadUint32(&s.active))
 }
+
+func TestSocketListener_ListenProxyProtocol(t *testing.T) {
+	// given
+	remoteAddrCh := make(chan net.Addr, 1)
+
+	s := &SocketListener{
+		cfg: ListenerConfig{BindAddr: "", Port: 51126, ProxyProtocol: true, ConnectTimeout: time.Second},
+		connHandlerFn: func(conn net.Conn) {
+			remoteAddrCh <- conn.RemoteAddr()
+		},
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	err := s.Start(context.Background())
+	require.Nil(t, err)
+
+	conn, err := net.Dial("tcp", ":51126")
+	require.Nil(t, err)
+
+	hdr := []byte{0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a, 0x21, 0x11, 0x00, 0x0c}
+	hdr = append(hdr, 203, 0, 113, 7, 127, 0, 0, 1, 0x30, 0x39, 0x14, 0x66)
+	_, err = conn.Write(hdr)
+	require.Nil(t, err)
+
+	var remoteAddr net.Addr
+	select {
+	case remoteAddr = <-remoteAddrCh:
+	case <-time.After(time.Second):
+	}
+	_ = conn.Close()
+	_ = s.Stop(context.Background())
+
+	// then
+	require.NotNil(t, remoteAddr)
+	require.Equal(t, "203.0.113.7:12345", remoteAddr.String())
+}
+
+func TestSocketListener_RejectMissingProxyHeader(t *testing.T) {
+	// given
+	s := &SocketListener{
+		cfg:    ListenerConfig{ProxyProtocol: true},
+		logger: kitlog.NewNopLogger(),
+	}
+	cli, srv := net.Pipe()
+
+	// when
+	go func() {
+		_, _ = cli.Write([]byte(`<?xml version="1.0"?><stream:stream to="jackal.im">`))
+	}()
+	s.handleConn(proxyproto.NewConn(srv, time.Second))
+
+	// then
+	_ = cli.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := cli.Read(make([]byte, 1))
+	require.Equal(t, io.EOF, err) // connection closed without replying
+}
+
+func TestSocketListener_TLSHandshakeFailure(t *testing.T) {
+	// given
+	var cfg ListenerConfig
+	cfg.ConnectTimeout = time.Second
+	cfg.TLSHandshakeFailure.LogLevel = "off"
+	cfg.TLSHandshakeFailure.Block.Threshold = 2
+	cfg.TLSHandshakeFailure.Block.Window = time.Minute
+	cfg.TLSHandshakeFailure.Block.Duration = time.Minute
+
+	s := newSocketListener(cfg, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, kitlog.NewNopLogger())
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	failuresMetric := c2sTLSHandshakeFailures.With(prometheus.Labels{"instance": instance.ID(), "reason": "not_tls"})
+	blockedMetric := c2sTLSBlockedConnections.With(prometheus.Labels{"instance": instance.ID()})
+
+	prevFailures := testutil.ToFloat64(failuresMetric)
+	prevBlocked := testutil.ToFloat64(blockedMetric)
+
+	connectPlainText := func() {
+		go func() {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(`<?xml version="1.0"?><stream:stream to="jackal.im">`))
+			_, _ = io.Copy(io.Discard, conn)
+			_ = conn.Close()
+		}()
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+
+		s.handleConn(tls.Server(conn, &tls.Config{}))
+	}
+
+	// when
+	connectPlainText()
+	connectPlainText() // remote address gets blocked
+	connectPlainText() // rejected before handshaking
+
+	// then
+	require.Equal(t, float64(2), testutil.ToFloat64(failuresMetric)-prevFailures)
+	require.Equal(t, float64(1), testutil.ToFloat64(blockedMetric)-prevBlocked)
+
+	require.True(t, s.hsBlocker.isBlocked("127.0.0.1"))
+}
+
+func TestSocketListener_ListenWebSocket(t *testing.T) {
+	// given
+	var handledWS uint32
+
+	s := &SocketListener{
+		cfg: ListenerConfig{BindAddr: "", Port: 51125, Transport: webSocketTransport},
+		wsHandlerFn: func(_ *websocket.Conn) {
+			atomic.StoreUint32(&handledWS, 1)
+		},
+		logger: kitlog.NewNopLogger(),
+	}
+	s.cfg.WebSocket.Path = "/xmpp-websocket"
+
+	// when
+	err := s.Start(context.Background())
+	require.Nil(t, err)
+
+	noProtoCfg, _ := websocket.NewConfig("ws://127.0.0.1:51125/xmpp-websocket", "http://127.0.0.1")
+	_, noProtoErr := websocket.DialConfig(noProtoCfg)
+
+	wsCfg, _ := websocket.NewConfig("ws://127.0.0.1:51125/xmpp-websocket", "http://127.0.0.1")
+	wsCfg.Protocol = []string{"xmpp"}
+	ws, err := websocket.DialConfig(wsCfg)
+	require.Nil(t, err)
+
+	time.Sleep(time.Millisecond * 250) // wait to accept
+
+	handled := atomic.LoadUint32(&handledWS) == 1
+	_ = ws.Close()
+	_ = s.Stop(context.Background())
+
+	// then
+	require.NotNil(t, noProtoErr) // 'xmpp' subprotocol is mandatory
+	require.True(t, handled)
+	require.Equal(t, []string{"xmpp"}, ws.Config().Protocol)
+
+	require.Equal(t, uint32(0), atomic.LoadUint32(&s.active))
+}
+
+func TestSocketListener_TransportKeepAliveTimeout(t *testing.T) {
+	// given
+	socketLn := &SocketListener{
+		cfg: ListenerConfig{Transport: "socket", KeepAliveTimeout: time.Hour},
+	}
+	socketLn.cfg.WebSocket.KeepAliveTimeout = time.Millisecond * 50 // not applicable to socket transport
+
+	wsLn := &SocketListener{
+		cfg: ListenerConfig{Transport: webSocketTransport, KeepAliveTimeout: time.Hour},
+	}
+	wsLn.cfg.WebSocket.KeepAliveTimeout = time.Millisecond * 50
+
+	// when
+	socketExpiredCh := make(chan struct{})
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	socketTr := socketLn.newSocketTransport(srv)
+	socketTr.SetKeepAliveDeadlineHandler(func() { close(socketExpiredCh) })
+	go func() { _, _ = socketTr.Read(make([]byte, 1)) }()
+
+	wsExpiredCh := make(chan struct{})
+	wsSrv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		wsTr := wsLn.newWebSocketTransport(ws)
+		wsTr.SetKeepAliveDeadlineHandler(func() { close(wsExpiredCh) })
+		_, _ = wsTr.Read(make([]byte, 1))
+	}))
+	defer wsSrv.Close()
+
+	ws, err := websocket.Dial(strings.Replace(wsSrv.URL, "http", "ws", 1), "", wsSrv.URL)
+	require.Nil(t, err)
+	defer func() { _ = ws.Close() }()
+
+	// then
+	select {
+	case <-wsExpiredCh:
+	case <-time.After(time.Second):
+		require.Fail(t, "WebSocket keep alive timeout not applied")
+	}
+	select {
+	case <-socketExpiredCh:
+		require.Fail(t, "socket keep alive timeout overridden")
+	default:
+	}
+}
+
+func TestSocketListener_UpdateShaperBinding(t *testing.T) {
+	// given
+	var cfg0, cfg1 shaper.Config
+	cfg0.Name = "normal"
+	cfg0.MaxSessions = 5
+	cfg0.Rate.Limit = 1000
+
+	cfg1.Name = "super"
+	cfg1.MaxSessions = 20
+	cfg1.Rate.Limit = 4000
+
+	shp0, _ := shaper.New(cfg0)
+	shp1, _ := shaper.New(cfg1)
+	shapers := shaper.Shapers{shp0, shp1}
+
+	s := &SocketListener{
+		cfg:     ListenerConfig{Port: 5222, Shaper: "normal"},
+		shapers: shapers,
+		shName:  "normal",
+		logger:  kitlog.NewNopLogger(),
+	}
+	j, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	prevShapers := s.getShapers()
+
+	// when
+	s.UpdateShaperBinding("super", shapers)
+
+	newShapers := s.getShapers()
+
+	// then
+	require.Equal(t, 20, newShapers.MatchingJID(j).MaxSessions)
+	require.Equal(t, rate.Limit(4000), newShapers.MatchingJID(j).RateLimiter().Limit())
+
+	require.Equal(t, 5, prevShapers.MatchingJID(j).MaxSessions) // existing connections keep their shaper
+}
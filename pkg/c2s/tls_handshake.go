@@ -0,0 +1,137 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	tlsFailureDebugLogLevel = "debug"
+	tlsFailureInfoLogLevel  = "info"
+	tlsFailureOffLogLevel   = "off"
+)
+
+// handshakeBlocker temporarily blocks remote addresses after repeated TLS handshake failures.
+type handshakeBlocker struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+	nowFn     func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*handshakeFailures
+	doneCh  chan chan struct{}
+}
+
+type handshakeFailures struct {
+	count        int
+	windowStart  time.Time
+	blockedUntil time.Time
+}
+
+func newHandshakeBlocker(threshold int, window, duration time.Duration) *handshakeBlocker {
+	return &handshakeBlocker{
+		threshold: threshold,
+		window:    window,
+		duration:  duration,
+		nowFn:     time.Now,
+		entries:   make(map[string]*handshakeFailures),
+	}
+}
+
+// isBlocked tells whether connections from ip should be rejected before handshaking.
+func (b *handshakeBlocker) isBlocked(ip string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[ip]
+	if !ok {
+		return false
+	}
+	return b.nowFn().Before(e.blockedUntil)
+}
+
+// reportFailure accounts a handshake failure from ip, returning true in case ip got blocked as a result.
+func (b *handshakeBlocker) reportFailure(ip string) bool {
+	now := b.nowFn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e, ok := b.entries[ip]
+	if !ok {
+		e = &handshakeFailures{windowStart: now}
+		b.entries[ip] = e
+	}
+	if now.Sub(e.windowStart) > b.window {
+		e.count = 0
+		e.windowStart = now
+	}
+	e.count++
+	if e.count < b.threshold {
+		return false
+	}
+	e.count = 0
+	e.windowStart = now
+	e.blockedUntil = now.Add(b.duration)
+	return true
+}
+
+// start periodically discards expired entries until stop is invoked.
+func (b *handshakeBlocker) start() {
+	b.doneCh = make(chan chan struct{})
+	go b.pruneLoop(b.doneCh)
+}
+
+func (b *handshakeBlocker) stop() {
+	if b.doneCh == nil {
+		return // not started
+	}
+	ch := make(chan struct{})
+	b.doneCh <- ch
+	<-ch
+	b.doneCh = nil
+}
+
+func (b *handshakeBlocker) pruneLoop(doneCh chan chan struct{}) {
+	tc := time.NewTicker(b.window)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			b.prune()
+
+		case ch := <-doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (b *handshakeBlocker) prune() {
+	now := b.nowFn()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ip, e := range b.entries {
+		if now.Sub(e.windowStart) > b.window && !now.Before(e.blockedUntil) {
+			delete(b.entries, ip)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package c2s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeBlocker(t *testing.T) {
+	// given
+	now := time.Now()
+
+	b := newHandshakeBlocker(3, time.Minute, time.Minute*10)
+	b.nowFn = func() time.Time { return now }
+
+	// when
+	blocked1 := b.reportFailure("10.0.0.1")
+	blocked2 := b.reportFailure("10.0.0.1")
+
+	now = now.Add(time.Minute * 2) // failures outside of the window are forgotten
+	blocked3 := b.reportFailure("10.0.0.1")
+	blocked4 := b.reportFailure("10.0.0.1")
+	blocked5 := b.reportFailure("10.0.0.1")
+
+	isBlocked := b.isBlocked("10.0.0.1")
+	isOtherBlocked := b.isBlocked("10.0.0.2")
+
+	now = now.Add(time.Minute * 11)
+	isStillBlocked := b.isBlocked("10.0.0.1")
+
+	// then
+	require.False(t, blocked1)
+	require.False(t, blocked2)
+	require.False(t, blocked3)
+	require.False(t, blocked4)
+	require.True(t, blocked5)
+
+	require.True(t, isBlocked)
+	require.False(t, isOtherBlocked)
+	require.False(t, isStillBlocked)
+}
+
+func TestHandshakeBlocker_Prune(t *testing.T) {
+	// given
+	now := time.Now()
+
+	b := newHandshakeBlocker(2, time.Minute, time.Minute*10)
+	b.nowFn = func() time.Time { return now }
+
+	_ = b.reportFailure("10.0.0.1")
+	_ = b.reportFailure("10.0.0.2")
+	_ = b.reportFailure("10.0.0.2") // blocked
+
+	// when
+	now = now.Add(time.Minute * 2)
+	b.prune()
+
+	// then
+	require.Len(t, b.entries, 1)
+	require.True(t, b.isBlocked("10.0.0.2"))
+
+	now = now.Add(time.Minute * 10)
+	b.prune()
+
+	require.Len(t, b.entries, 0)
+}
+
+func TestHandshakeBlocker_StopNotStarted(t *testing.T) {
+	// given
+	b := newHandshakeBlocker(2, time.Minute, time.Minute*10)
+
+	// when
+	b.stop()
+
+	// then
+	require.Nil(t, b.doneCh)
+}
@@ -15,11 +15,24 @@
 package c2s
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
 	"strconv"
+	"testing"
+	"time"
 
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/host"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/stretchr/testify/require"
 )
 
 func testMessageStanza() *stravaganza.Message {
@@ -35,6 +48,13 @@ func testMessageStanza() *stravaganza.Message {
 	return msg
 }
 
+func testBareMessageStanza() *stravaganza.Message {
+	msg, _ := stravaganza.NewBuilderFromElement(testMessageStanza()).
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		BuildMessage()
+	return msg
+}
+
 func testResource(instanceID string, priority int8, username, resource string) c2smodel.ResourceDesc {
 	pr, _ := stravaganza.NewPresenceBuilder().
 		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
@@ -49,3 +69,39 @@ func testResource(instanceID string, priority int8, username, resource string) c
 	jd, _ := jid.New(username, "jackal.im", resource, true)
 	return c2smodel.NewResourceDesc(instanceID, jd, pr, c2smodel.NewInfoMapFromMap(map[string]string{"k1": "v1", "k2": "v2"}))
 }
+
+// testHosts returns a hosts set whose default localhost certificate is generated into a
+// test temporary directory, so that no key material is written into the package tree.
+func testHosts(t *testing.T) *host.Hosts {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+
+	var cfg host.Config
+	cfg.Domain = "localhost"
+	cfg.TLS.CertFile = filepath.Join(dir, "cert.pem")
+	cfg.TLS.PrivateKeyFile = filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(cfg.TLS.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(cfg.TLS.PrivateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	hs, err := host.NewHosts(host.Configs{cfg})
+	require.NoError(t, err)
+	return hs
+}
@@ -16,7 +16,7 @@ package clusterconnmanager
 
 import (
 	"context"
-	"io"
+	"net"
 	"strconv"
 	"time"
 
@@ -25,6 +25,7 @@ import (
 	clusterpb "github.com/ortuman/jackal/pkg/cluster/pb"
 	"github.com/ortuman/jackal/pkg/version"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -51,16 +52,18 @@ var dialFn = dialContext
 type clusterConn struct {
 	target     string
 	ver        *version.SemanticVersion
-	cc         io.Closer
+	draining   bool
+	cc         grpcConn
 	lcRouter   LocalRouter
 	compRouter ComponentRouter
 	stmMgmt    StreamManagement
 }
 
-func newConn(addr string, port int, ver *version.SemanticVersion) *clusterConn {
+func newConn(addr string, port int, ver *version.SemanticVersion, draining bool) *clusterConn {
 	return &clusterConn{
-		target: addr + ":" + strconv.Itoa(port),
-		ver:    ver,
+		target:   net.JoinHostPort(addr, strconv.Itoa(port)),
+		ver:      ver,
+		draining: draining,
 	}
 }
 
@@ -84,6 +87,18 @@ func (c *clusterConn) dialContext(ctx context.Context) error {
 	return nil
 }
 
+func (c *clusterConn) warmUp(ctx context.Context) error {
+	for {
+		st := c.cc.GetState()
+		if st == connectivity.Ready {
+			return nil
+		}
+		if !c.cc.WaitForStateChange(ctx, st) {
+			return ctx.Err()
+		}
+	}
+}
+
 func (c *clusterConn) close() error {
 	return c.cc.Close()
 }
@@ -100,7 +115,7 @@ func toProtoStreamError(sErr *streamerror.Error) *clusterpb.StreamError {
 	return pse
 }
 
-func dialContext(ctx context.Context, target string) (lcRouter LocalRouter, compRouter ComponentRouter, stmMgmt StreamManagement, cc io.Closer, err error) {
+func dialContext(ctx context.Context, target string) (lcRouter LocalRouter, compRouter ComponentRouter, stmMgmt StreamManagement, cc grpcConn, err error) {
 	grpcConn, err := grpc.DialContext(ctx,
 		target,
 		grpc.WithKeepaliveParams(keepalive.ClientParameters{
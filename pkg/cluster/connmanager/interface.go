@@ -15,7 +15,10 @@
 package clusterconnmanager
 
 import (
+	"context"
 	"io"
+
+	"google.golang.org/grpc/connectivity"
 )
 
 //go:generate moq -out localrouter.mock_test.go . LocalRouter:localRouterMock
@@ -25,4 +28,6 @@ import (
 //go:generate moq -out grpcconn.mock_test.go . grpcConn
 type grpcConn interface {
 	io.Closer
+	GetState() connectivity.State
+	WaitForStateChange(ctx context.Context, sourceState connectivity.State) bool
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	clusterpb "github.com/ortuman/jackal/pkg/cluster/pb"
+	"github.com/ortuman/jackal/pkg/router"
 )
 
 // LocalRouter defines local router service.
@@ -33,12 +34,21 @@ type localRouter struct {
 }
 
 func (cc *localRouter) Route(ctx context.Context, stanza stravaganza.Stanza, username, resource string) error {
-	_, err := cc.cl.Route(ctx, &clusterpb.LocalRouteRequest{
+	resp, err := cc.cl.Route(ctx, &clusterpb.LocalRouteRequest{
 		Username: username,
 		Resource: resource,
 		Stanza:   stanza.Proto(),
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	switch resp.GetResult() {
+	case clusterpb.DeliveryResult_DELIVERY_RESULT_NO_SUCH_RESOURCE:
+		return router.ErrResourceNotFound
+	case clusterpb.DeliveryResult_DELIVERY_RESULT_BOUNCED:
+		return router.ErrStanzaBounced
+	}
+	return nil
 }
 
 func (cc *localRouter) Disconnect(ctx context.Context, username, resource string, streamErr *streamerror.Error) error {
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 
@@ -44,8 +45,19 @@ type Conn interface {
 	StreamManagement() StreamManagement
 }
 
+// Config contains cluster connection manager configuration.
+type Config struct {
+	// Warmup tells whether connections to cluster members should be eagerly established
+	// as soon as members are known, instead of on first use.
+	Warmup bool `fig:"warmup"`
+
+	// WarmupTimeout defines the maximum amount of time to wait for a member connection to be ready.
+	WarmupTimeout time.Duration `fig:"warmup_timeout" default:"5s"`
+}
+
 // Manager is the cluster connection manager.
 type Manager struct {
+	cfg    Config
 	mu     sync.RWMutex
 	conns  map[string]*clusterConn
 	hk     *hook.Hooks
@@ -53,8 +65,9 @@ type Manager struct {
 }
 
 // NewManager returns a new initialized cluster connection manager.
-func NewManager(hk *hook.Hooks, logger kitlog.Logger) *Manager {
+func NewManager(cfg Config, hk *hook.Hooks, logger kitlog.Logger) *Manager {
 	return &Manager{
+		cfg:    cfg,
 		hk:     hk,
 		conns:  make(map[string]*clusterConn),
 		logger: logger,
@@ -124,14 +137,48 @@ func (m *Manager) onMemberListUpdated(ctx context.Context, execCtx *hook.Executi
 	}
 	// dial connections to new registered members...
 	for _, member := range inf.Registered {
-		cl := newConn(member.Host, member.Port, member.APIVer)
+		if cl, ok := m.conns[member.InstanceID]; ok {
+			if cl.target == member.String() {
+				// member info update (e.g. draining state change)... keep current connection
+				wasDraining := cl.draining
+				cl.ver = member.APIVer
+				cl.draining = member.Draining
+				level.Info(m.logger).Log("msg", "updated cluster member", "remote_addr", member.String(), "draining", member.Draining)
+
+				if wasDraining && !member.Draining && m.cfg.Warmup {
+					go m.warmUp(cl)
+				}
+				continue
+			}
+			if err := cl.close(); err != nil {
+				level.Warn(m.logger).Log("msg", "failed to close cluster client conn", "err", err)
+			}
+			delete(m.conns, member.InstanceID)
+		}
+		cl := newConn(member.Host, member.Port, member.APIVer, member.Draining)
 		if err := cl.dialContext(ctx); err != nil {
 			level.Warn(m.logger).Log("msg", "failed to dial cluster conn", "err", err)
 			continue
 		}
-		level.Info(m.logger).Log("msg", "dialed cluster router connection", "remote_addr", fmt.Sprintf("%s:%d", member.Host, member.Port))
+		level.Info(m.logger).Log("msg", "dialed cluster router connection", "remote_addr", member.String())
 
 		m.conns[member.InstanceID] = cl
+
+		// draining members are not given new work, so there's no point in eagerly establishing their connections
+		if m.cfg.Warmup && !member.Draining {
+			go m.warmUp(cl)
+		}
 	}
 	return nil
 }
+
+func (m *Manager) warmUp(cl *clusterConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.WarmupTimeout)
+	defer cancel()
+
+	if err := cl.warmUp(ctx); err != nil {
+		level.Warn(m.logger).Log("msg", "failed to warm up cluster conn", "remote_addr", cl.target, "err", err)
+		return
+	}
+	level.Info(m.logger).Log("msg", "warmed up cluster router connection", "remote_addr", cl.target)
+}
@@ -17,14 +17,16 @@ package clusterconnmanager
 import (
 	"context"
 	"errors"
-	"io"
+	"sync"
 	"testing"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/ortuman/jackal/pkg/hook"
 	clustermodel "github.com/ortuman/jackal/pkg/model/cluster"
 	"github.com/ortuman/jackal/pkg/version"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
 )
 
 func TestConnections_UpdateMembers(t *testing.T) {
@@ -36,11 +38,11 @@ func TestConnections_UpdateMembers(t *testing.T) {
 	ccMock := &grpcConnMock{}
 	ccMock.CloseFunc = func() error { return nil }
 
-	dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, io.Closer, error) {
+	dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, grpcConn, error) {
 		return lcRouterMock, compRouterMock, stmMgmtMock, ccMock, nil
 	}
 	hk := hook.NewHooks()
-	connMng := NewManager(hk, kitlog.NewNopLogger())
+	connMng := NewManager(Config{}, hk, kitlog.NewNopLogger())
 
 	// when
 	_ = connMng.Start(context.Background())
@@ -77,6 +79,47 @@ func TestConnections_UpdateMembers(t *testing.T) {
 	require.Len(t, ccMock.CloseCalls(), 1)
 }
 
+func TestConnections_MemberUpdate(t *testing.T) {
+	// given
+	ccMock := &grpcConnMock{}
+	ccMock.CloseFunc = func() error { return nil }
+
+	var dialCount int
+	dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, grpcConn, error) {
+		dialCount++
+		return &localRouterMock{}, &componentRouterMock{}, &streamManagementMock{}, ccMock, nil
+	}
+	hk := hook.NewHooks()
+	connMng := NewManager(Config{}, hk, kitlog.NewNopLogger())
+
+	// when
+	_ = connMng.Start(context.Background())
+
+	registerMember := func(m clustermodel.Member) {
+		_, _ = hk.Run(context.Background(), hook.MemberListUpdated, &hook.ExecutionContext{
+			Info: &hook.MemberListInfo{Registered: []clustermodel.Member{m}},
+		})
+	}
+	registerMember(clustermodel.Member{InstanceID: "a1234", Host: "192.168.2.1", Port: 1234, APIVer: version.ClusterAPIVersion})
+	registerMember(clustermodel.Member{InstanceID: "a1234", Host: "192.168.2.1", Port: 1234, APIVer: version.ClusterAPIVersion, Draining: true})
+
+	conn, err := connMng.GetConnection("a1234")
+
+	// then
+	require.Nil(t, err)
+	require.NotNil(t, conn)
+
+	require.Equal(t, 1, dialCount)
+	require.Len(t, ccMock.CloseCalls(), 0)
+
+	// when (member address changed)
+	registerMember(clustermodel.Member{InstanceID: "a1234", Host: "192.168.2.2", Port: 1234, APIVer: version.ClusterAPIVersion})
+
+	// then
+	require.Equal(t, 2, dialCount)
+	require.Len(t, ccMock.CloseCalls(), 1)
+}
+
 func TestConnections_IncompatibleClusterAPI(t *testing.T) {
 	// given
 	localRouterMock := &localRouterMock{}
@@ -84,11 +127,11 @@ func TestConnections_IncompatibleClusterAPI(t *testing.T) {
 	stmMgmtMock := &streamManagementMock{}
 	ccMock := &grpcConnMock{}
 
-	dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, io.Closer, error) {
+	dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, grpcConn, error) {
 		return localRouterMock, compRouterMock, stmMgmtMock, ccMock, nil
 	}
 	hk := hook.NewHooks()
-	connMng := NewManager(hk, kitlog.NewNopLogger())
+	connMng := NewManager(Config{}, hk, kitlog.NewNopLogger())
 
 	// when
 	_ = connMng.Start(context.Background())
@@ -110,3 +153,76 @@ func TestConnections_IncompatibleClusterAPI(t *testing.T) {
 
 	require.True(t, errors.Is(err, ErrIncompatibleProtocol))
 }
+
+func TestConnections_Warmup(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		warmup   bool
+		draining bool
+	}{
+		{name: "enabled", warmup: true},
+		{name: "disabled", warmup: false},
+		{name: "draining member", warmup: true, draining: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			var mu sync.Mutex
+			st := connectivity.Idle
+
+			readyCh := make(chan struct{})
+
+			ccMock := &grpcConnMock{}
+			ccMock.CloseFunc = func() error { return nil }
+			ccMock.GetStateFunc = func() connectivity.State {
+				mu.Lock()
+				defer mu.Unlock()
+				return st
+			}
+			ccMock.WaitForStateChangeFunc = func(ctx context.Context, sourceState connectivity.State) bool {
+				mu.Lock()
+				defer mu.Unlock()
+				switch sourceState {
+				case connectivity.Idle:
+					st = connectivity.Connecting
+				case connectivity.Connecting:
+					st = connectivity.Ready
+					close(readyCh)
+				}
+				return true
+			}
+			dialFn = func(ctx context.Context, target string) (LocalRouter, ComponentRouter, StreamManagement, grpcConn, error) {
+				return &localRouterMock{}, &componentRouterMock{}, &streamManagementMock{}, ccMock, nil
+			}
+			hk := hook.NewHooks()
+			connMng := NewManager(Config{Warmup: tc.warmup, WarmupTimeout: time.Second}, hk, kitlog.NewNopLogger())
+
+			// when
+			_ = connMng.Start(context.Background())
+
+			_, _ = hk.Run(context.Background(), hook.MemberListUpdated, &hook.ExecutionContext{
+				Info: &hook.MemberListInfo{
+					Registered: []clustermodel.Member{
+						{InstanceID: "a1234", Host: "192.168.2.1", Port: 1234, APIVer: version.ClusterAPIVersion, Draining: tc.draining},
+					},
+				},
+			})
+
+			// then
+			if !tc.warmup || tc.draining {
+				require.Len(t, ccMock.GetStateCalls(), 0)
+				require.Len(t, ccMock.WaitForStateChangeCalls(), 0)
+				return
+			}
+			select {
+			case <-readyCh:
+			case <-time.After(time.Second):
+				require.Fail(t, "cluster connection not established")
+			}
+			require.Eventually(t, func() bool {
+				return len(ccMock.GetStateCalls()) == 3
+			}, time.Second, time.Millisecond*10)
+
+			require.Len(t, ccMock.WaitForStateChangeCalls(), 2)
+		})
+	}
+}
@@ -17,25 +17,12 @@ package clusterconnmanager
 import (
 	"context"
 
-	"github.com/jackal-xmpp/stravaganza"
 	clusterpb "github.com/ortuman/jackal/pkg/cluster/pb"
-	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
+	"github.com/ortuman/jackal/pkg/cluster/queueformat"
 )
 
 // StreamQueue represents a stream managed queue.
-type StreamQueue struct {
-	// Elements contains the queue elements.
-	Elements []streamqueue.Element
-
-	// Nonce is the nonce queue byte slice.
-	Nonce []byte
-
-	// InH is the queue incoming h value.
-	InH uint32
-
-	// OutH is the queue outgoing h value.
-	OutH uint32
-}
+type StreamQueue = queueformat.Queue
 
 // StreamManagement defines a stream management service.
 type StreamManagement interface {
@@ -49,6 +36,7 @@ type streamManagement struct {
 func (cc *streamManagement) TransferQueue(ctx context.Context, queueID string) (*StreamQueue, error) {
 	resp, err := cc.cl.TransferQueue(ctx, &clusterpb.TransferQueueRequest{
 		Identifier: queueID,
+		Format:     queueformat.Local(),
 	})
 	if err != nil {
 		return nil, err
@@ -56,23 +44,5 @@ func (cc *streamManagement) TransferQueue(ctx context.Context, queueID string) (
 	if resp == nil {
 		return nil, nil
 	}
-	elements := make([]streamqueue.Element, 0, len(resp.Elements))
-
-	for _, elem := range resp.Elements {
-		b := stravaganza.NewBuilderFromProto(elem.GetStanza())
-		stanza, err := b.BuildStanza()
-		if err != nil {
-			return nil, err
-		}
-		elements = append(elements, streamqueue.Element{
-			Stanza: stanza,
-			H:      elem.GetH(),
-		})
-	}
-	return &StreamQueue{
-		Elements: elements,
-		Nonce:    resp.GetNonce(),
-		InH:      resp.GetInH(),
-		OutH:     resp.GetOutH(),
-	}, nil
+	return queueformat.Decode(resp)
 }
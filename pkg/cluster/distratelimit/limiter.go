@@ -0,0 +1,197 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ortuman/jackal/pkg/cluster/kv"
+)
+
+const (
+	limiterKeyPrefix = "rl://"
+
+	maxSwapRetries = 8
+)
+
+// ErrContention will be returned by Allow when shared bucket state couldn't be updated
+// due to concurrent modifications from other cluster instances.
+var ErrContention = errors.New("distratelimit: too many concurrent bucket updates")
+
+// Config contains distributed rate limiter configuration.
+type Config struct {
+	// Rate is the number of tokens refilled per second into a bucket, shared among all cluster instances.
+	// A zero value disables limiting.
+	Rate float64 `fig:"rate"`
+
+	// Burst is the maximum number of tokens a bucket can hold.
+	Burst int `fig:"burst"`
+}
+
+// Enabled tells whether limiting has been configured.
+func (c Config) Enabled() bool {
+	return c.Rate > 0 && c.Burst > 0
+}
+
+// Limiter implements a token bucket rate limiter whose state is kept in the cluster KV store,
+// so that the same budget is enforced across all cluster instances.
+// In case no cluster KV store is available buckets are kept in memory, and limits are enforced per instance.
+type Limiter struct {
+	name  string
+	cfg   Config
+	kv    kv.KV
+	ttl   time.Duration
+	nowFn func() time.Time
+
+	mu         sync.Mutex
+	local      map[string]bucket
+	lastPruned time.Time
+}
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// New returns a new distributed rate limiter instance. name identifies the limited operation and
+// scopes its buckets among the ones of any other limiter sharing the same KV store.
+func New(name string, cfg Config, kvs kv.KV) *Limiter {
+	var ttl time.Duration
+	if cfg.Enabled() {
+		// an expired bucket is equivalent to a full one
+		ttl = time.Duration(float64(cfg.Burst) / cfg.Rate * float64(time.Second))
+		if ttl < time.Second {
+			ttl = time.Second
+		}
+	}
+	lim := &Limiter{
+		name:  name,
+		cfg:   cfg,
+		kv:    kvs,
+		ttl:   ttl,
+		nowFn: time.Now,
+	}
+	if kvs == nil || kv.IsNop(kvs) {
+		lim.local = make(map[string]bucket)
+	}
+	return lim
+}
+
+// Allow consumes a token from the bucket associated to key, returning false in case
+// there are no tokens left.
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, error) {
+	if !l.cfg.Enabled() {
+		return true, nil
+	}
+	if l.local != nil {
+		return l.allowLocal(key), nil
+	}
+	bucketKey := limiterKeyPrefix + l.name + "/" + key
+
+	for i := 0; i < maxSwapRetries; i++ {
+		prev, err := l.kv.Get(ctx, bucketKey)
+		if err != nil {
+			return false, err
+		}
+		now := l.nowFn()
+
+		tokens := float64(l.cfg.Burst)
+		if prev != nil {
+			b, err := decodeBucket(string(prev))
+			if err != nil {
+				return false, err
+			}
+			tokens = l.refill(b, now)
+		}
+		if tokens < 1 {
+			reportAllowed(l.name, false)
+			return false, nil
+		}
+		ok, err := l.kv.CompareAndSwap(ctx, bucketKey, prev, encodeBucket(tokens-1, now), l.ttl)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			reportAllowed(l.name, true)
+			return true, nil
+		}
+		// bucket was concurrently updated... try again
+	}
+	return false, ErrContention
+}
+
+func (l *Limiter) allowLocal(key string) bool {
+	now := l.nowFn()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// expired buckets are equivalent to full ones
+	if now.Sub(l.lastPruned) > l.ttl {
+		for k, b := range l.local {
+			if now.Sub(b.lastRefill) > l.ttl {
+				delete(l.local, k)
+			}
+		}
+		l.lastPruned = now
+	}
+	tokens := float64(l.cfg.Burst)
+	if b, ok := l.local[key]; ok {
+		tokens = l.refill(b, now)
+	}
+	if tokens < 1 {
+		reportAllowed(l.name, false)
+		return false
+	}
+	l.local[key] = bucket{tokens: tokens - 1, lastRefill: now}
+	reportAllowed(l.name, true)
+	return true
+}
+
+// refill returns the number of tokens available at now in a bucket.
+func (l *Limiter) refill(b bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	if elapsed < 0 {
+		elapsed = 0 // clock skew among instances
+	}
+	return math.Min(float64(l.cfg.Burst), b.tokens+elapsed*l.cfg.Rate)
+}
+
+func encodeBucket(tokens float64, lastRefill time.Time) string {
+	return strconv.FormatFloat(tokens, 'f', -1, 64) + "," + strconv.FormatInt(lastRefill.UnixNano(), 10)
+}
+
+func decodeBucket(s string) (bucket, error) {
+	ss := strings.Split(s, ",")
+	if len(ss) != 2 {
+		return bucket{}, fmt.Errorf("distratelimit: malformed bucket state: %s", s)
+	}
+	tokens, err := strconv.ParseFloat(ss[0], 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("distratelimit: malformed bucket tokens: %v", err)
+	}
+	nanos, err := strconv.ParseInt(ss[1], 10, 64)
+	if err != nil {
+		return bucket{}, fmt.Errorf("distratelimit: malformed bucket timestamp: %v", err)
+	}
+	return bucket{tokens: tokens, lastRefill: time.Unix(0, nanos)}, nil
+}
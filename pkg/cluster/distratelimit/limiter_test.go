@@ -0,0 +1,184 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ortuman/jackal/pkg/cluster/kv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter_SharedBudget(t *testing.T) {
+	// given
+	fkv := newFakeKV()
+
+	now := time.Now()
+	nowFn := func() time.Time { return now }
+
+	cfg := Config{Rate: 1, Burst: 4}
+	nodeA := New("auth", cfg, fkv)
+	nodeA.nowFn = nowFn
+	nodeB := New("auth", cfg, fkv)
+	nodeB.nowFn = nowFn
+
+	// when
+	var allowed int
+	for i := 0; i < 3; i++ {
+		okA, err := nodeA.Allow(context.Background(), "ortuman")
+		require.NoError(t, err)
+		okB, err := nodeB.Allow(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		if okA {
+			allowed++
+		}
+		if okB {
+			allowed++
+		}
+	}
+	okOther, _ := nodeB.Allow(context.Background(), "noelia")
+
+	now = now.Add(time.Second * 2)
+
+	okA1, _ := nodeA.Allow(context.Background(), "ortuman")
+	okB1, _ := nodeB.Allow(context.Background(), "ortuman")
+	okA2, _ := nodeA.Allow(context.Background(), "ortuman")
+
+	// then
+	require.Equal(t, 4, allowed)
+	require.True(t, okOther) // buckets are independent among keys
+
+	require.True(t, okA1)
+	require.True(t, okB1)
+	require.False(t, okA2)
+}
+
+func TestLimiter_ConcurrentNodes(t *testing.T) {
+	// given
+	fkv := newFakeKV()
+
+	cfg := Config{Rate: 0.001, Burst: 10}
+	nodes := []*Limiter{New("register", cfg, fkv), New("register", cfg, fkv)}
+
+	// when
+	var mu sync.Mutex
+	var allowed int
+
+	var wg sync.WaitGroup
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(l *Limiter) {
+			defer wg.Done()
+			for {
+				ok, err := l.Allow(context.Background(), "127.0.0.1")
+				if err == ErrContention {
+					continue
+				}
+				if ok {
+					mu.Lock()
+					allowed++
+					mu.Unlock()
+				}
+				return
+			}
+		}(nodes[i%2])
+	}
+	wg.Wait()
+
+	// then
+	require.Equal(t, 10, allowed)
+}
+
+func TestLimiter_LocalFallback(t *testing.T) {
+	// given
+	now := time.Now()
+
+	l := New("auth", Config{Rate: 1, Burst: 2}, kv.NewNop())
+	l.nowFn = func() time.Time { return now }
+
+	// when
+	ok1, _ := l.Allow(context.Background(), "127.0.0.1")
+	ok2, _ := l.Allow(context.Background(), "127.0.0.1")
+	ok3, _ := l.Allow(context.Background(), "127.0.0.1")
+
+	now = now.Add(time.Second * 5)
+
+	ok4, _ := l.Allow(context.Background(), "127.0.0.1")
+
+	// then
+	require.True(t, ok1)
+	require.True(t, ok2)
+	require.False(t, ok3) // nop KV must not disable limiting
+	require.True(t, ok4)
+
+	require.Len(t, l.local, 1) // expired bucket pruned before being refilled
+}
+
+func TestLimiter_Disabled(t *testing.T) {
+	// given
+	fkv := newFakeKV()
+	l := New("auth", Config{}, fkv)
+
+	// when
+	var denied bool
+	for i := 0; i < 100; i++ {
+		ok, err := l.Allow(context.Background(), "ortuman")
+		require.NoError(t, err)
+		denied = denied || !ok
+	}
+
+	// then
+	require.False(t, denied)
+	require.Len(t, fkv.vals, 0)
+}
+
+type fakeKV struct {
+	kv.KV // not implemented methods will panic
+
+	mu   sync.Mutex
+	vals map[string]string
+}
+
+func newFakeKV() *fakeKV {
+	return &fakeKV{vals: make(map[string]string)}
+}
+
+func (f *fakeKV) Get(_ context.Context, key string) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.vals[key]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(v), nil
+}
+
+func (f *fakeKV) CompareAndSwap(_ context.Context, key string, prevValue []byte, value string, _ time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.vals[key]
+	switch {
+	case prevValue == nil && ok:
+		return false, nil
+	case prevValue != nil && (!ok || v != string(prevValue)):
+		return false, nil
+	}
+	f.vals[key] = value
+	return true, nil
+}
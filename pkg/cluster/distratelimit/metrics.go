@@ -0,0 +1,58 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distratelimit
+
+import (
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	allowedOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "distratelimit",
+			Name:      "allowed_total",
+			Help:      "The total number of operations allowed by a distributed rate limiter.",
+		},
+		[]string{"instance", "name"},
+	)
+	deniedOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "distratelimit",
+			Name:      "denied_total",
+			Help:      "The total number of operations denied by a distributed rate limiter.",
+		},
+		[]string{"instance", "name"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(allowedOperations)
+	prometheus.MustRegister(deniedOperations)
+}
+
+func reportAllowed(name string, allowed bool) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"name":     name,
+	}
+	if allowed {
+		allowedOperations.With(metricLabel).Inc()
+		return
+	}
+	deniedOperations.With(metricLabel).Inc()
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"strconv"
@@ -33,11 +34,7 @@ import (
 	"google.golang.org/grpc/keepalive"
 )
 
-const (
-	leaseTTLInSeconds int64 = 10
-
-	etcdKVType = "etcd"
-)
+const etcdKVType = "etcd"
 
 // Config contains etcd configuration parameters.
 type Config struct {
@@ -49,6 +46,10 @@ type Config struct {
 	DialKeepAliveTimeout time.Duration `fig:"dial_keep_alive_timeout" default:"10s"`
 	KeepAliveTime        time.Duration `fig:"keep_alive" default:"10s"`
 	Timeout              time.Duration `fig:"keep_alive" default:"20m"`
+
+	// LeaseTTL defines the TTL of the lease shared by every stored key, refreshed as long as the instance
+	// is alive. Keys stored by a crashed instance (ie. its cluster member registration) expire after it.
+	LeaseTTL time.Duration `fig:"lease_ttl" default:"10s"`
 }
 
 // KV represents an etcd key-value store implementation.
@@ -92,6 +93,74 @@ func (k *KV) Get(ctx context.Context, key string) ([]byte, error) {
 	return getResp.Kvs[0].Value, nil
 }
 
+// CompareAndSwap atomically stores a new value associated to a given key only if its current
+// value equals prevValue, where a nil prevValue means key must not exist yet.
+// If ttl is greater than zero stored value expires after it. When swapping an existing key its
+// lease is reused and refreshed, so that no lease is granted per update.
+// Returns false in case comparison failed.
+func (k *KV) CompareAndSwap(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+	if prevValue == nil {
+		return k.create(ctx, key, value, ttl)
+	}
+	txnResp, err := k.cli.Txn(ctx).
+		If(etcdv3.Compare(etcdv3.Value(key), "=", string(prevValue))).
+		Then(etcdv3.OpPut(key, value, etcdv3.WithIgnoreLease()), etcdv3.OpGet(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	if !txnResp.Succeeded || ttl <= 0 {
+		return txnResp.Succeeded, nil
+	}
+	kvs := txnResp.Responses[1].GetResponseRange().GetKvs()
+	if len(kvs) == 0 {
+		return true, nil
+	}
+	if leaseID := etcdv3.LeaseID(kvs[0].Lease); leaseID != etcdv3.NoLease {
+		// value has already been swapped... a refresh failure just means it'll expire sooner
+		_, _ = k.cli.KeepAliveOnce(ctx, leaseID)
+		return true, nil
+	}
+	// stored value had no lease yet... attach a new one, unless it has been concurrently modified
+	resp, err := k.cli.Grant(ctx, ttlInSeconds(ttl))
+	if err != nil {
+		return false, err
+	}
+	modRev := kvs[0].ModRevision
+	attachResp, err := k.cli.Txn(ctx).
+		If(etcdv3.Compare(etcdv3.ModRevision(key), "=", modRev)).
+		Then(etcdv3.OpPut(key, value, etcdv3.WithLease(resp.ID))).
+		Commit()
+	if err != nil || !attachResp.Succeeded {
+		_, _ = k.cli.Revoke(ctx, resp.ID)
+	}
+	return true, err
+}
+
+func (k *KV) create(ctx context.Context, key string, value string, ttl time.Duration) (bool, error) {
+	var opts []etcdv3.OpOption
+	var leaseID etcdv3.LeaseID
+	if ttl > 0 {
+		resp, err := k.cli.Grant(ctx, ttlInSeconds(ttl))
+		if err != nil {
+			return false, err
+		}
+		leaseID = resp.ID
+		opts = append(opts, etcdv3.WithLease(leaseID))
+	}
+	txnResp, err := k.cli.Txn(ctx).
+		If(etcdv3.Compare(etcdv3.CreateRevision(key), "=", 0)).
+		Then(etcdv3.OpPut(key, value, opts...)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	if !txnResp.Succeeded && leaseID != 0 {
+		_, _ = k.cli.Revoke(ctx, leaseID)
+	}
+	return txnResp.Succeeded, nil
+}
+
 // GetPrefix retrieves all values whose key matches prefix.
 func (k *KV) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
 	getResp, err := k.cli.Get(ctx, prefix, etcdv3.WithPrefix())
@@ -147,7 +216,7 @@ func (k *KV) Start(ctx context.Context) error {
 	k.cli = cli
 
 	// create shared KV lease
-	resp, err := k.cli.Grant(ctx, leaseTTLInSeconds)
+	resp, err := k.cli.Grant(ctx, ttlInSeconds(k.cfg.LeaseTTL))
 	if err != nil {
 		return err
 	}
@@ -268,6 +337,10 @@ func toWatchResp(wResp *etcdv3.WatchResponse) kvtypes.WatchResp {
 	}
 }
 
+func ttlInSeconds(ttl time.Duration) int64 {
+	return int64(math.Max(math.Ceil(ttl.Seconds()), 1))
+}
+
 func shutdown() {
 	p, _ := os.FindProcess(os.Getpid())
 	_ = p.Signal(os.Interrupt)
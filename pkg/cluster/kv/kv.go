@@ -17,6 +17,7 @@ package kv
 import (
 	"context"
 	"fmt"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	etcdkv "github.com/ortuman/jackal/pkg/cluster/kv/etcd"
@@ -33,6 +34,11 @@ type KV interface {
 	// Get retrieves a value associated to a given key.
 	Get(ctx context.Context, key string) ([]byte, error)
 
+	// CompareAndSwap atomically stores a new value associated to a given key only if its current
+	// value equals prevValue, where a nil prevValue means key must not exist yet.
+	// If ttl is greater than zero stored value expires after it. Returns false in case comparison failed.
+	CompareAndSwap(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error)
+
 	// GetPrefix retrieves all values whose key matches prefix.
 	GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error)
 
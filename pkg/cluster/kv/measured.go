@@ -27,6 +27,7 @@ import (
 const (
 	putOpType    = "put"
 	getOpType    = "get"
+	casOpType    = "cas"
 	deleteOpType = "delete"
 	watchOpType  = "watch"
 )
@@ -57,6 +58,15 @@ func (m *Measured) Get(ctx context.Context, key string) ([]byte, error) {
 	return v, err
 }
 
+// CompareAndSwap atomically stores a new value associated to a given key only if its current
+// value equals prevValue.
+func (m *Measured) CompareAndSwap(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+	t0 := time.Now()
+	ok, err := m.kv.CompareAndSwap(ctx, key, prevValue, value, ttl)
+	reportMetric(casOpType, time.Since(t0).Seconds(), err == nil)
+	return ok, err
+}
+
 // GetPrefix retrieves all values whose key matches prefix.
 func (m *Measured) GetPrefix(ctx context.Context, prefix string) (map[string][]byte, error) {
 	t0 := time.Now()
@@ -16,6 +16,7 @@ package kv
 
 import (
 	"context"
+	"time"
 
 	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
 )
@@ -34,6 +35,10 @@ type nopKV struct{}
 func (k *nopKV) Put(_ context.Context, _ string, _ string) error { return nil }
 func (k *nopKV) Get(_ context.Context, _ string) ([]byte, error) { return nil, nil }
 
+func (k *nopKV) CompareAndSwap(_ context.Context, _ string, _ []byte, _ string, _ time.Duration) (bool, error) {
+	return true, nil
+}
+
 func (k *nopKV) GetPrefix(_ context.Context, _ string) (map[string][]byte, error) {
 	return nil, nil
 }
@@ -0,0 +1,342 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memberlist
+
+import (
+	"context"
+	"fmt"
+	stdlog "log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	hcmemberlist "github.com/hashicorp/memberlist"
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/ortuman/jackal/pkg/hook"
+	clustermodel "github.com/ortuman/jackal/pkg/model/cluster"
+	"github.com/ortuman/jackal/pkg/version"
+)
+
+const (
+	gossipMemberListType = "gossip"
+
+	gossipEventsBufferSize = 64
+
+	gossipUpdateNodeTimeout = time.Second * 5
+)
+
+// GossipConfig contains gossip (SWIM) memberlist configuration.
+type GossipConfig struct {
+	// BindAddr is the address gossip protocol listens on.
+	BindAddr string `fig:"bind_addr" default:"0.0.0.0"`
+
+	// BindPort is the port gossip protocol listens on, both for TCP and UDP.
+	BindPort int `fig:"bind_port" default:"7946"`
+
+	// AdvertiseAddr is the address advertised to the rest of members, both for gossip
+	// and cluster server connections. If not set, it's selected among local interface addresses.
+	AdvertiseAddr string `fig:"advertise_addr"`
+
+	// AdvertiseCIDR, if set, selects the advertised address among local interface addresses
+	// belonging to this network range. Ignored in case AdvertiseAddr is set.
+	AdvertiseCIDR string `fig:"advertise_cidr"`
+
+	// Join contains the gossip addresses (host:port) of the members to be contacted on startup.
+	Join []string `fig:"join"`
+
+	// ProbeInterval defines how often a random member is probed for liveness.
+	ProbeInterval time.Duration `fig:"probe_interval" default:"1s"`
+
+	// ProbeTimeout defines how long to wait for a probe ack before considering it failed.
+	ProbeTimeout time.Duration `fig:"probe_timeout" default:"500ms"`
+
+	// SuspicionMult scales the time a suspicious member is given to refute before being declared dead.
+	SuspicionMult int `fig:"suspicion_mult" default:"4"`
+
+	// LeaveTimeout defines how long to wait for the leave intent to be propagated on stop.
+	LeaveTimeout time.Duration `fig:"leave_timeout" default:"5s"`
+}
+
+// GossipMemberList keeps and manages cluster memberlist set using a SWIM gossip protocol,
+// in which members probe each other, so that failed instances are detected and pruned
+// without depending on a shared KV store.
+type GossipMemberList struct {
+	cfg        GossipConfig
+	localPort  int
+	instanceID string
+	hk         *hook.Hooks
+	logger     kitlog.Logger
+
+	list     *hcmemberlist.Memberlist
+	delegate *gossipDelegate
+	evCh     chan hcmemberlist.NodeEvent
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+	drainMu  sync.Mutex
+	draining uint32
+
+	mu      sync.RWMutex
+	members map[string]clustermodel.Member
+}
+
+// NewGossipMemberList will create a new GossipMemberList instance using the given configuration.
+func NewGossipMemberList(cfg GossipConfig, localPort int, hk *hook.Hooks, logger kitlog.Logger) *GossipMemberList {
+	return &GossipMemberList{
+		cfg:        cfg,
+		localPort:  localPort,
+		instanceID: instance.ID(),
+		hk:         hk,
+		logger:     logger,
+		delegate:   &gossipDelegate{},
+		evCh:       make(chan hcmemberlist.NodeEvent, gossipEventsBufferSize),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		members:    make(map[string]clustermodel.Member),
+	}
+}
+
+// Start is used to join a cluster by contacting configured gossip members.
+func (ml *GossipMemberList) Start(_ context.Context) error {
+	lm, err := ml.localMember(atomic.LoadUint32(&ml.draining) == 1)
+	if err != nil {
+		return err
+	}
+	if err := ml.updateLocalMeta(lm); err != nil {
+		return err
+	}
+	mlCfg := hcmemberlist.DefaultLANConfig()
+	mlCfg.Name = ml.instanceID
+	mlCfg.BindAddr = ml.cfg.BindAddr
+	mlCfg.BindPort = ml.cfg.BindPort
+	mlCfg.AdvertiseAddr = lm.Host
+	mlCfg.AdvertisePort = ml.cfg.BindPort
+	mlCfg.ProbeInterval = ml.cfg.ProbeInterval
+	mlCfg.ProbeTimeout = ml.cfg.ProbeTimeout
+	mlCfg.SuspicionMult = ml.cfg.SuspicionMult
+	mlCfg.Delegate = ml.delegate
+	mlCfg.Events = &hcmemberlist.ChannelEventDelegate{Ch: ml.evCh}
+	mlCfg.Logger = stdlog.New(kitlog.NewStdlibAdapter(level.Debug(ml.logger)), "", 0)
+
+	list, err := hcmemberlist.Create(mlCfg)
+	if err != nil {
+		return err
+	}
+	ml.list = list
+
+	go ml.loop()
+
+	if len(ml.cfg.Join) > 0 {
+		n, err := list.Join(ml.cfg.Join)
+		if err != nil {
+			_ = list.Shutdown()
+			close(ml.stopCh)
+			<-ml.doneCh
+			return err
+		}
+		level.Info(ml.logger).Log("msg", "joined gossip cluster", "contacted", n)
+	}
+	level.Info(ml.logger).Log("msg", "started memberlist", "type", gossipMemberListType, "bind_port", ml.cfg.BindPort)
+	return nil
+}
+
+// Stop propagates local instance leave intent and stops gossiping.
+func (ml *GossipMemberList) Stop(_ context.Context) error {
+	if err := ml.list.Leave(ml.cfg.LeaveTimeout); err != nil {
+		level.Warn(ml.logger).Log("msg", "failed to propagate memberlist leave", "err", err)
+	}
+	if err := ml.list.Shutdown(); err != nil {
+		return err
+	}
+	close(ml.stopCh)
+	<-ml.doneCh
+
+	level.Info(ml.logger).Log("msg", "stopped memberlist", "type", gossipMemberListType)
+	return nil
+}
+
+// SetDraining marks local member as draining (or not) by updating its gossip metadata,
+// so that the rest of members are notified.
+func (ml *GossipMemberList) SetDraining(_ context.Context, draining bool) error {
+	ml.drainMu.Lock()
+	defer ml.drainMu.Unlock()
+
+	wasDraining := atomic.LoadUint32(&ml.draining) == 1
+	if wasDraining == draining {
+		return nil
+	}
+	if err := ml.updateNode(draining); err != nil {
+		// restore previously advertised metadata, so that a failed attempt can be retried
+		if rErr := ml.updateNode(wasDraining); rErr != nil {
+			level.Warn(ml.logger).Log("msg", "failed to restore local instance metadata", "err", rErr)
+		}
+		return err
+	}
+	var val uint32
+	if draining {
+		val = 1
+	}
+	atomic.StoreUint32(&ml.draining, val)
+
+	level.Info(ml.logger).Log("msg", "updated local instance draining state", "draining", draining)
+	return nil
+}
+
+// GetMember returns cluster member info associated to an identifier.
+func (ml *GossipMemberList) GetMember(instanceID string) (m clustermodel.Member, ok bool) {
+	ml.mu.RLock()
+	defer ml.mu.RUnlock()
+	m, ok = ml.members[instanceID]
+	return
+}
+
+// GetMembers returns all cluster registered members.
+func (ml *GossipMemberList) GetMembers() map[string]clustermodel.Member {
+	ml.mu.RLock()
+	defer ml.mu.RUnlock()
+	res := make(map[string]clustermodel.Member)
+	for k, v := range ml.members {
+		res[k] = v
+	}
+	return res
+}
+
+func (ml *GossipMemberList) updateNode(draining bool) error {
+	lm, err := ml.localMember(draining)
+	if err != nil {
+		return err
+	}
+	if err := ml.updateLocalMeta(lm); err != nil {
+		return err
+	}
+	if ml.list == nil {
+		return nil
+	}
+	return ml.list.UpdateNode(gossipUpdateNodeTimeout)
+}
+
+func (ml *GossipMemberList) localMember(draining bool) (*clustermodel.Member, error) {
+	hostIP, err := getHostIP(ml.cfg.AdvertiseAddr, ml.cfg.AdvertiseCIDR)
+	if err != nil {
+		return nil, err
+	}
+	return &clustermodel.Member{
+		InstanceID: ml.instanceID,
+		Host:       hostIP,
+		Port:       ml.localPort,
+		APIVer:     version.ClusterAPIVersion,
+		Draining:   draining,
+	}, nil
+}
+
+func (ml *GossipMemberList) updateLocalMeta(lm *clustermodel.Member) error {
+	meta := encodeClusterMember(lm)
+	if len(meta) > hcmemberlist.MetaMaxSize {
+		return fmt.Errorf("memberlist: local member metadata exceeds %d bytes", hcmemberlist.MetaMaxSize)
+	}
+	ml.delegate.setMeta([]byte(meta))
+	return nil
+}
+
+func (ml *GossipMemberList) loop() {
+	defer close(ml.doneCh)
+	for {
+		select {
+		case ev := <-ml.evCh:
+			if err := ml.processEvent(context.Background(), ev); err != nil {
+				level.Warn(ml.logger).Log("msg", "failed to process memberlist changes", "err", err)
+			}
+
+		case <-ml.stopCh:
+			return
+		}
+	}
+}
+
+func (ml *GossipMemberList) processEvent(ctx context.Context, ev hcmemberlist.NodeEvent) error {
+	if ev.Node.Name == ml.instanceID {
+		return nil // ignore local instance events
+	}
+	var inf hook.MemberListInfo
+
+	switch ev.Event {
+	case hcmemberlist.NodeJoin, hcmemberlist.NodeUpdate:
+		m, err := decodeClusterMember(ev.Node.Name, string(ev.Node.Meta))
+		if err != nil {
+			return err
+		}
+		if !m.IsCompatible() {
+			ml.mu.Lock()
+			if _, ok := ml.members[m.InstanceID]; ok {
+				delete(ml.members, m.InstanceID)
+				inf.UnregisteredKeys = []string{m.InstanceID}
+			}
+			ml.mu.Unlock()
+
+			inf.Incompatible = []clustermodel.Member{*m}
+
+			logIncompatibleMember(ml.logger, *m)
+			break
+		}
+		ml.mu.Lock()
+		ml.members[m.InstanceID] = *m
+		ml.mu.Unlock()
+
+		inf.Registered = []clustermodel.Member{*m}
+
+		level.Info(ml.logger).Log("msg", "registered cluster member", "instance_id", m.InstanceID, "address", m.String(), "cluster_api_ver", m.APIVer.String())
+
+	case hcmemberlist.NodeLeave:
+		ml.mu.Lock()
+		delete(ml.members, ev.Node.Name)
+		ml.mu.Unlock()
+
+		inf.UnregisteredKeys = []string{ev.Node.Name}
+
+		level.Info(ml.logger).Log("msg", "unregistered cluster member", "instance_id", ev.Node.Name)
+
+	default:
+		return nil
+	}
+	_, err := ml.hk.Run(ctx, hook.MemberListUpdated, &hook.ExecutionContext{
+		Info:   &inf,
+		Sender: ml,
+	})
+	return err
+}
+
+// gossipDelegate advertises local member info as gossip node metadata.
+type gossipDelegate struct {
+	mu   sync.RWMutex
+	meta []byte
+}
+
+func (d *gossipDelegate) NodeMeta(_ int) []byte {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.meta
+}
+
+func (d *gossipDelegate) setMeta(meta []byte) {
+	d.mu.Lock()
+	d.meta = meta
+	d.mu.Unlock()
+}
+
+func (d *gossipDelegate) NotifyMsg(_ []byte)                {}
+func (d *gossipDelegate) GetBroadcasts(_, _ int) [][]byte   { return nil }
+func (d *gossipDelegate) LocalState(_ bool) []byte          { return nil }
+func (d *gossipDelegate) MergeRemoteState(_ []byte, _ bool) {}
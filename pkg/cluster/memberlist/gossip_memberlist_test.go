@@ -0,0 +1,190 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memberlist
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	hcmemberlist "github.com/hashicorp/memberlist"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGossipMemberList_ProcessEvents(t *testing.T) {
+	// given
+	hk := hook.NewHooks()
+
+	var mu sync.Mutex
+	var infos []*hook.MemberListInfo
+	hk.AddHook(hook.MemberListUpdated, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		mu.Lock()
+		infos = append(infos, execCtx.Info.(*hook.MemberListInfo))
+		mu.Unlock()
+		return nil
+	}, hook.DefaultPriority)
+
+	ml := NewGossipMemberList(GossipConfig{}, 14369, hk, kitlog.NewNopLogger())
+	ml.instanceID = "a1b2"
+
+	// when
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeJoin,
+		Node:  &hcmemberlist.Node{Name: "a1b2", Meta: []byte("a=10.0.0.1:14369 cv=v1.0.0")},
+	})
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeJoin,
+		Node:  &hcmemberlist.Node{Name: "b3fd", Meta: []byte("a=10.0.0.2:14369 cv=v1.0.0")},
+	})
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeJoin,
+		Node:  &hcmemberlist.Node{Name: "c5gl", Meta: []byte("a=10.0.0.3:14369 cv=v1.5.0 d=1")},
+	})
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeLeave,
+		Node:  &hcmemberlist.Node{Name: "b3fd"},
+	})
+
+	// then
+	ms := ml.GetMembers()
+	require.Len(t, ms, 1)
+
+	m, ok := ml.GetMember("c5gl")
+	require.True(t, ok)
+	require.Equal(t, "10.0.0.3", m.Host)
+	require.Equal(t, 14369, m.Port)
+	require.Equal(t, "v1.5.0", m.APIVer.String())
+	require.True(t, m.Draining)
+
+	require.Len(t, infos, 3) // local instance events are ignored
+	require.Equal(t, "b3fd", infos[0].Registered[0].InstanceID)
+	require.Equal(t, "c5gl", infos[1].Registered[0].InstanceID)
+	require.Equal(t, []string{"b3fd"}, infos[2].UnregisteredKeys)
+}
+
+func TestGossipMemberList_IncompatibleMember(t *testing.T) {
+	// given
+	hk := hook.NewHooks()
+
+	var infos []*hook.MemberListInfo
+	hk.AddHook(hook.MemberListUpdated, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		infos = append(infos, execCtx.Info.(*hook.MemberListInfo))
+		return nil
+	}, hook.DefaultPriority)
+
+	ml := NewGossipMemberList(GossipConfig{}, 14369, hk, kitlog.NewNopLogger())
+	ml.instanceID = "a1b2"
+
+	// when
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeJoin,
+		Node:  &hcmemberlist.Node{Name: "b3fd", Meta: []byte("a=10.0.0.2:14369 cv=v1.0.0")},
+	})
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeJoin,
+		Node:  &hcmemberlist.Node{Name: "c5gl", Meta: []byte("a=10.0.0.3:14369 cv=v2.0.0")},
+	})
+	_ = ml.processEvent(context.Background(), hcmemberlist.NodeEvent{
+		Event: hcmemberlist.NodeUpdate,
+		Node:  &hcmemberlist.Node{Name: "b3fd", Meta: []byte("a=10.0.0.2:14369 cv=v2.0.0")},
+	})
+
+	// then
+	require.Len(t, ml.GetMembers(), 0)
+
+	require.Len(t, infos, 3)
+	require.Len(t, infos[1].Registered, 0)
+	require.Equal(t, "c5gl", infos[1].Incompatible[0].InstanceID)
+
+	require.Equal(t, []string{"b3fd"}, infos[2].UnregisteredKeys)
+	require.Equal(t, "b3fd", infos[2].Incompatible[0].InstanceID)
+}
+
+func TestGossipMemberList_JoinAndLeave(t *testing.T) {
+	// given
+	portA, portB := freeLocalPort(t), freeLocalPort(t)
+
+	mlA := NewGossipMemberList(testGossipConfig(portA), 4312, hook.NewHooks(), kitlog.NewNopLogger())
+	mlA.instanceID = "a1b2"
+
+	cfgB := testGossipConfig(portB)
+	cfgB.Join = []string{fmt.Sprintf("127.0.0.1:%d", portA)}
+	mlB := NewGossipMemberList(cfgB, 4313, hook.NewHooks(), kitlog.NewNopLogger())
+	mlB.instanceID = "b3fd"
+
+	// when
+	require.NoError(t, mlA.Start(context.Background()))
+	require.NoError(t, mlB.Start(context.Background()))
+
+	// then
+	require.Eventually(t, func() bool {
+		_, okA := mlA.GetMember("b3fd")
+		_, okB := mlB.GetMember("a1b2")
+		return okA && okB
+	}, time.Second*5, time.Millisecond*50)
+
+	m, _ := mlA.GetMember("b3fd")
+	require.Equal(t, "127.0.0.1", m.Host)
+	require.Equal(t, 4313, m.Port) // cluster server port, not the gossip one
+
+	require.NoError(t, mlB.Stop(context.Background()))
+
+	require.Eventually(t, func() bool {
+		_, ok := mlA.GetMember("b3fd")
+		return !ok
+	}, time.Second*5, time.Millisecond*50)
+
+	require.NoError(t, mlA.Stop(context.Background()))
+}
+
+func TestNewMemberList(t *testing.T) {
+	ml, err := New(Config{Type: "gossip"}, 4312, nil, hook.NewHooks(), kitlog.NewNopLogger())
+	require.NoError(t, err)
+	require.IsType(t, &GossipMemberList{}, ml)
+
+	ml, err = New(Config{Type: "kv"}, 4312, nil, hook.NewHooks(), kitlog.NewNopLogger())
+	require.NoError(t, err)
+	require.IsType(t, &KVMemberList{}, ml)
+
+	_, err = New(Config{Type: "foo"}, 4312, nil, hook.NewHooks(), kitlog.NewNopLogger())
+	require.Error(t, err)
+}
+
+func testGossipConfig(port int) GossipConfig {
+	return GossipConfig{
+		BindAddr:      "127.0.0.1",
+		BindPort:      port,
+		AdvertiseAddr: "127.0.0.1",
+		ProbeInterval: time.Millisecond * 100,
+		ProbeTimeout:  time.Millisecond * 50,
+		SuspicionMult: 2,
+		LeaveTimeout:  time.Second,
+	}
+}
+
+func freeLocalPort(t *testing.T) int {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	return ln.Addr().(*net.TCPAddr).Port
+}
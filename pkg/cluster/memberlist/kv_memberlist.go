@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -37,11 +38,25 @@ const (
 	memberKeyPrefix   = "i://"
 	memberValueFormat = "a=%s cv=%s"
 
+	// drainingMemberFlag is appended to member value when the instance is being drained.
+	drainingMemberFlag = "d=1"
+
 	kvMemberListType = "kv"
 )
 
+// KVConfig contains KV memberlist configuration.
+type KVConfig struct {
+	// AdvertiseAddr is the address advertised to the rest of members for cluster server connections.
+	AdvertiseAddr string `fig:"advertise_addr"`
+
+	// AdvertiseCIDR, if set, selects the advertised address among local interface addresses
+	// belonging to this network range. Ignored in case AdvertiseAddr is set.
+	AdvertiseCIDR string `fig:"advertise_cidr"`
+}
+
 // KVMemberList keeps and manages cluster memberlist set.
 type KVMemberList struct {
+	cfg       KVConfig
 	localPort int
 	kv        kv.KV
 	ctx       context.Context
@@ -51,12 +66,15 @@ type KVMemberList struct {
 	mu        sync.RWMutex
 	members   map[string]clustermodel.Member
 	stopCh    chan struct{}
+	joinMu    sync.Mutex
+	draining  uint32
 }
 
 // NewKVMemberList will create a new KVMemberList instance using the given configuration.
-func NewKVMemberList(localPort int, kv kv.KV, hk *hook.Hooks, logger kitlog.Logger) *KVMemberList {
+func NewKVMemberList(cfg KVConfig, localPort int, kv kv.KV, hk *hook.Hooks, logger kitlog.Logger) *KVMemberList {
 	ctx, cancelFn := context.WithCancel(context.Background())
 	return &KVMemberList{
+		cfg:       cfg,
 		localPort: localPort,
 		kv:        kv,
 		members:   make(map[string]clustermodel.Member),
@@ -64,13 +82,12 @@ func NewKVMemberList(localPort int, kv kv.KV, hk *hook.Hooks, logger kitlog.Logg
 		ctxCancel: cancelFn,
 		hk:        hk,
 		logger:    logger,
-		stopCh:    make(chan struct{}),
 	}
 }
 
 // Start is used to join a cluster by registering instance member into the shared KV storage.
 func (ml *KVMemberList) Start(ctx context.Context) error {
-	if err := ml.join(ctx); err != nil {
+	if err := ml.join(ctx, ml.isDraining()); err != nil {
 		return err
 	}
 	level.Info(ml.logger).Log("msg", "registered local instance", "port", ml.localPort, "instance_id", instance.ID())
@@ -88,9 +105,10 @@ func (ml *KVMemberList) Start(ctx context.Context) error {
 func (ml *KVMemberList) Stop(ctx context.Context) error {
 	// stop watching changes...
 	ml.ctxCancel()
-	<-ml.stopCh
-
-	// unregister local instance
+	if ml.stopCh != nil {
+		<-ml.stopCh
+	}
+	// unregister local instance right away, instead of waiting for KV lease expiration
 	if err := ml.kv.Del(ctx, localMemberKey()); err != nil {
 		return err
 	}
@@ -100,6 +118,29 @@ func (ml *KVMemberList) Stop(ctx context.Context) error {
 	return nil
 }
 
+// SetDraining marks local member as draining (or not) by registering it again
+// into the shared KV storage, so that the rest of members are notified.
+func (ml *KVMemberList) SetDraining(ctx context.Context, draining bool) error {
+	ml.joinMu.Lock()
+	defer ml.joinMu.Unlock()
+
+	if ml.isDraining() == draining {
+		return nil
+	}
+	// only update local state once registration succeeded, so that a failed attempt can be retried
+	if err := ml.join(ctx, draining); err != nil {
+		return err
+	}
+	var val uint32
+	if draining {
+		val = 1
+	}
+	atomic.StoreUint32(&ml.draining, val)
+
+	level.Info(ml.logger).Log("msg", "updated local instance draining state", "draining", draining)
+	return nil
+}
+
 // GetMember returns cluster member info associated to an identifier.
 func (ml *KVMemberList) GetMember(instanceID string) (m clustermodel.Member, ok bool) {
 	ml.mu.RLock()
@@ -119,18 +160,24 @@ func (ml *KVMemberList) GetMembers() map[string]clustermodel.Member {
 	return res
 }
 
-func (ml *KVMemberList) join(ctx context.Context) error {
-	lm, err := ml.getLocalMember()
+func (ml *KVMemberList) isDraining() bool {
+	return atomic.LoadUint32(&ml.draining) == 1
+}
+
+func (ml *KVMemberList) join(ctx context.Context, draining bool) error {
+	lm, err := ml.getLocalMember(draining)
 	if err != nil {
 		return err
 	}
-	kvVal := fmt.Sprintf(memberValueFormat, lm.String(), lm.APIVer)
-	return ml.kv.Put(ctx, localMemberKey(), kvVal)
+	// registration is bound to the KV store lease, so it expires in case this instance crashes
+	return ml.kv.Put(ctx, localMemberKey(), encodeClusterMember(lm))
 }
 
 func (ml *KVMemberList) refreshMemberList(ctx context.Context) error {
 	ch := make(chan error, 1)
 
+	ml.stopCh = make(chan struct{})
+
 	go func() {
 		wCh := ml.kv.Watch(ml.ctx, memberKeyPrefix, false)
 
@@ -139,15 +186,26 @@ func (ml *KVMemberList) refreshMemberList(ctx context.Context) error {
 			ch <- err
 			return
 		}
+		var registered, incompatible []clustermodel.Member
+
 		ml.mu.Lock()
 		for _, m := range ms {
+			if !m.IsCompatible() {
+				incompatible = append(incompatible, m)
+				continue
+			}
 			ml.members[m.InstanceID] = m
+			registered = append(registered, m)
 		}
 		ml.mu.Unlock()
 
+		for _, m := range incompatible {
+			logIncompatibleMember(ml.logger, m)
+		}
 		// run updated member list hook
 		err = ml.runHook(ctx, &hook.MemberListInfo{
-			Registered: ms,
+			Registered:   registered,
+			Incompatible: incompatible,
 		})
 		if err != nil {
 			ch <- err
@@ -194,8 +252,8 @@ func (ml *KVMemberList) getMembers(ctx context.Context) ([]clustermodel.Member,
 	return res, nil
 }
 
-func (ml *KVMemberList) getLocalMember() (*clustermodel.Member, error) {
-	hostIP, err := getHostIP()
+func (ml *KVMemberList) getLocalMember(draining bool) (*clustermodel.Member, error) {
+	hostIP, err := getHostIP(ml.cfg.AdvertiseAddr, ml.cfg.AdvertiseCIDR)
 	if err != nil {
 		return nil, err
 	}
@@ -204,11 +262,12 @@ func (ml *KVMemberList) getLocalMember() (*clustermodel.Member, error) {
 		Host:       hostIP,
 		Port:       ml.localPort,
 		APIVer:     version.ClusterAPIVersion,
+		Draining:   draining,
 	}, nil
 }
 
 func (ml *KVMemberList) processKVEvents(ctx context.Context, kvEvents []kvtypes.WatchEvent) error {
-	var putMembers []clustermodel.Member
+	var putMembers, incompatibleMembers []clustermodel.Member
 	var delMemberKeys []string
 
 	ml.mu.Lock()
@@ -222,10 +281,19 @@ func (ml *KVMemberList) processKVEvents(ctx context.Context, kvEvents []kvtypes.
 			if err != nil {
 				return err
 			}
+			if !m.IsCompatible() {
+				if _, ok := ml.members[m.InstanceID]; ok {
+					delete(ml.members, m.InstanceID)
+					delMemberKeys = append(delMemberKeys, m.InstanceID)
+				}
+				incompatibleMembers = append(incompatibleMembers, *m)
+				logIncompatibleMember(ml.logger, *m)
+				continue
+			}
 			ml.members[m.InstanceID] = *m
 			putMembers = append(putMembers, *m)
 
-			level.Info(ml.logger).Log("msg", "registered cluster member", "instance_id", m.InstanceID, "address", m.String(), "cluster_api_ver", m.APIVer.String())
+			level.Info(ml.logger).Log("msg", "registered cluster member", "instance_id", m.InstanceID, "address", m.String(), "cluster_api_ver", m.APIVer.String(), "draining", m.Draining)
 
 		case kvtypes.Del:
 			memberKey := strings.TrimPrefix(ev.Key, memberKeyPrefix)
@@ -241,6 +309,7 @@ func (ml *KVMemberList) processKVEvents(ctx context.Context, kvEvents []kvtypes.
 	return ml.runHook(ctx, &hook.MemberListInfo{
 		Registered:       putMembers,
 		UnregisteredKeys: delMemberKeys,
+		Incompatible:     incompatibleMembers,
 	})
 }
 
@@ -252,6 +321,14 @@ func (ml *KVMemberList) runHook(ctx context.Context, inf *hook.MemberListInfo) e
 	return err
 }
 
+func encodeClusterMember(m *clustermodel.Member) string {
+	val := fmt.Sprintf(memberValueFormat, m.String(), m.APIVer)
+	if m.Draining {
+		val += " " + drainingMemberFlag
+	}
+	return val
+}
+
 func decodeClusterMember(key, val string) (*clustermodel.Member, error) {
 	instanceID := strings.TrimPrefix(key, memberKeyPrefix)
 
@@ -271,9 +348,19 @@ func decodeClusterMember(key, val string) (*clustermodel.Member, error) {
 		Host:       host,
 		Port:       port,
 		APIVer:     version.NewVersion(major, minor, patch),
+		Draining:   strings.HasSuffix(val, " "+drainingMemberFlag),
 	}, nil
 }
 
+func logIncompatibleMember(logger kitlog.Logger, m clustermodel.Member) {
+	level.Warn(logger).Log("msg", "discarded cluster member with incompatible cluster API version",
+		"instance_id", m.InstanceID,
+		"address", m.String(),
+		"cluster_api_ver", m.APIVer.String(),
+		"local_cluster_api_ver", version.ClusterAPIVersion.String(),
+	)
+}
+
 func localMemberKey() string {
 	return memberKeyPrefix + instance.ID()
 }
@@ -282,18 +369,52 @@ func isLocalMemberKey(k string) bool {
 	return k == localMemberKey()
 }
 
-func getHostIP() (string, error) {
+// getHostIP returns the local address advertised to the rest of cluster members.
+// In case neither advertiseAddr nor cidr are set, the first non-loopback IPv4 address is chosen,
+// falling back to the first global unicast IPv6 one.
+func getHostIP(advertiseAddr, cidr string) (string, error) {
+	if len(advertiseAddr) > 0 {
+		return advertiseAddr, nil
+	}
+	var ipNet *net.IPNet
+	if len(cidr) > 0 {
+		var err error
+		_, ipNet, err = net.ParseCIDR(cidr)
+		if err != nil {
+			return "", err
+		}
+	}
 	addresses, err := net.InterfaceAddrs()
 	if err != nil {
 		return "", err
 	}
+	return selectHostIP(addresses, ipNet)
+}
 
+func selectHostIP(addresses []net.Addr, cidr *net.IPNet) (string, error) {
+	var ips []net.IP
 	for _, addr := range addresses {
-		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
-			if ipNet.IP.To4() != nil {
-				return ipNet.IP.String(), nil
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	if cidr != nil {
+		for _, ip := range ips {
+			if cidr.Contains(ip) {
+				return ip.String(), nil
 			}
 		}
+		return "", fmt.Errorf("instance: no local ip within %s", cidr.String())
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() && ip.To4() != nil {
+			return ip.String(), nil
+		}
+	}
+	for _, ip := range ips {
+		if ip.To4() == nil && ip.IsGlobalUnicast() {
+			return ip.String(), nil
+		}
 	}
 	return "", errors.New("instance: failed to get local ip")
 }
@@ -16,7 +16,10 @@ package memberlist
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
@@ -24,6 +27,7 @@ import (
 	"github.com/ortuman/jackal/pkg/cluster/instance"
 	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/version"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,7 +47,7 @@ func TestMemberList_Join(t *testing.T) {
 			"i://b3fd":                           []byte("a=192.168.0.12:1456 cv=v1.0.0"),
 		}, nil
 	}
-	ml := NewKVMemberList(4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+	ml := NewKVMemberList(testKVConfig(), 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
 
 	// when
 	err := ml.Start(context.Background())
@@ -78,7 +82,7 @@ func TestMemberList_Leave(t *testing.T) {
 	kvMock.DelFunc = func(r context.Context, key string) error {
 		return nil
 	}
-	ml := NewKVMemberList(4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+	ml := NewKVMemberList(testKVConfig(), 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
 
 	// when
 	_ = ml.Start(context.Background())
@@ -92,6 +96,35 @@ func TestMemberList_Leave(t *testing.T) {
 	require.Len(t, kvMock.DelCalls(), 1)
 }
 
+func TestMemberList_StopNotStarted(t *testing.T) {
+	// given
+	kvMock := &kvMock{}
+
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		return errors.New("kv unavailable")
+	}
+	kvMock.DelFunc = func(r context.Context, key string) error {
+		return nil
+	}
+	ml := NewKVMemberList(testKVConfig(), 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	startErr := ml.Start(context.Background())
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- ml.Stop(context.Background()) }()
+
+	// then
+	require.NotNil(t, startErr)
+
+	select {
+	case err := <-errCh:
+		require.Nil(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "stop blocked after failed start")
+	}
+}
+
 func TestMemberList_WatchChanges(t *testing.T) {
 	// given
 	kvMock := &kvMock{}
@@ -117,7 +150,7 @@ func TestMemberList_WatchChanges(t *testing.T) {
 			"i://b3fd":                           []byte("a=192.168.0.12:1456 cv=v1.0.0"),
 		}, nil
 	}
-	ml := NewKVMemberList(4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+	ml := NewKVMemberList(testKVConfig(), 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
 
 	// when
 	_ = ml.Start(context.Background())
@@ -139,3 +172,212 @@ func TestMemberList_WatchChanges(t *testing.T) {
 	_, ok = ms["c5gl"]
 	require.True(t, ok)
 }
+
+func TestMemberList_IncompatibleMembers(t *testing.T) {
+	// given
+	kvMock := &kvMock{}
+
+	wCh := make(chan kvtypes.WatchResp)
+	kvMock.WatchFunc = func(ctx context.Context, prefix string, withPrevVal bool) <-chan kvtypes.WatchResp {
+		return wCh
+	}
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		return nil
+	}
+	kvMock.GetPrefixFunc = func(ctx context.Context, prefix string) (map[string][]byte, error) {
+		return map[string][]byte{
+			"i://b3fd": []byte("a=192.168.0.12:1456 cv=v1.2.0"),
+			"i://c5gl": []byte("a=192.168.0.14:1456 cv=v2.0.0"),
+		}, nil
+	}
+	hk := hook.NewHooks()
+
+	infCh := make(chan *hook.MemberListInfo, 2)
+	hk.AddHook(hook.MemberListUpdated, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		infCh <- execCtx.Info.(*hook.MemberListInfo)
+		return nil
+	}, hook.DefaultPriority)
+
+	ml := NewKVMemberList(testKVConfig(), 4312, kvMock, hk, kitlog.NewNopLogger())
+
+	// when
+	_ = ml.Start(context.Background())
+	startInf := <-infCh
+	startMembers := ml.GetMembers()
+
+	wCh <- kvtypes.WatchResp{
+		Events: []kvtypes.WatchEvent{
+			{Type: kvtypes.Put, Key: "i://b3fd", Val: []byte("a=192.168.0.12:1456 cv=v2.0.0")},
+		},
+	}
+	watchInf := <-infCh
+	watchMembers := ml.GetMembers()
+
+	// then
+	require.Len(t, startMembers, 1)
+	require.Contains(t, startMembers, "b3fd")
+
+	require.Len(t, startInf.Registered, 1)
+	require.Len(t, startInf.Incompatible, 1)
+	require.Equal(t, "c5gl", startInf.Incompatible[0].InstanceID)
+
+	require.Len(t, watchMembers, 0)
+
+	require.Len(t, watchInf.Registered, 0)
+	require.Equal(t, []string{"b3fd"}, watchInf.UnregisteredKeys) // upgraded to an incompatible version
+	require.Len(t, watchInf.Incompatible, 1)
+	require.Equal(t, "v2.0.0", watchInf.Incompatible[0].APIVer.String())
+}
+
+func TestMemberList_SetDraining(t *testing.T) {
+	// given
+	kvMock := &kvMock{}
+
+	var mu sync.Mutex
+	var putValues []string
+	kvMock.WatchFunc = func(ctx context.Context, prefix string, withPrevVal bool) <-chan kvtypes.WatchResp {
+		return make(chan kvtypes.WatchResp)
+	}
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		mu.Lock()
+		putValues = append(putValues, value)
+		mu.Unlock()
+		return nil
+	}
+	kvMock.GetPrefixFunc = func(ctx context.Context, prefix string) (map[string][]byte, error) {
+		return map[string][]byte{
+			"i://b3fd": []byte("a=192.168.0.12:1456 cv=v1.0.0 d=1"),
+		}, nil
+	}
+	cfg := testKVConfig()
+	cfg.AdvertiseAddr = "10.0.0.5"
+
+	ml := NewKVMemberList(cfg, 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	err := ml.Start(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, ml.SetDraining(context.Background(), true))
+	require.NoError(t, ml.SetDraining(context.Background(), true)) // no-op
+	require.NoError(t, ml.SetDraining(context.Background(), false))
+
+	m, ok := ml.GetMember("b3fd")
+
+	// then
+	require.True(t, ok)
+	require.True(t, m.Draining)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	cv := version.ClusterAPIVersion.String()
+	require.Equal(t, []string{
+		"a=10.0.0.5:4312 cv=" + cv,
+		"a=10.0.0.5:4312 cv=" + cv + " d=1",
+		"a=10.0.0.5:4312 cv=" + cv,
+	}, putValues)
+}
+
+func TestMemberList_SetDrainingFailure(t *testing.T) {
+	// given
+	kvMock := &kvMock{}
+
+	var putErr error
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		return putErr
+	}
+	cfg := testKVConfig()
+	cfg.AdvertiseAddr = "10.0.0.5"
+
+	ml := NewKVMemberList(cfg, 4312, kvMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	putErr = errors.New("kv: unavailable")
+	err1 := ml.SetDraining(context.Background(), true)
+
+	putErr = nil
+	err2 := ml.SetDraining(context.Background(), true)
+
+	// then
+	require.Error(t, err1)
+	require.NoError(t, err2)
+
+	require.True(t, ml.isDraining())
+	require.Len(t, kvMock.PutCalls(), 2)
+}
+
+func TestMemberList_SelectHostIP(t *testing.T) {
+	addrs := []net.Addr{
+		testIPNet("127.0.0.1/8"),
+		testIPNet("::1/128"),
+		testIPNet("fe80::1/64"),
+		testIPNet("172.17.0.1/16"),
+		testIPNet("10.10.0.5/24"),
+		testIPNet("fd00::5/64"),
+	}
+	var tests = []struct {
+		name   string
+		addrs  []net.Addr
+		cidr   string
+		expIP  string
+		expErr bool
+	}{
+		{name: "FirstIPv4", addrs: addrs, expIP: "172.17.0.1"},
+		{name: "IPv4CIDR", addrs: addrs, cidr: "10.10.0.0/16", expIP: "10.10.0.5"},
+		{name: "IPv6CIDR", addrs: addrs, cidr: "fd00::/8", expIP: "fd00::5"},
+		{name: "IPv6Only", addrs: []net.Addr{testIPNet("::1/128"), testIPNet("fe80::1/64"), testIPNet("fd00::5/64")}, expIP: "fd00::5"},
+		{name: "NoMatchingCIDR", addrs: addrs, cidr: "192.168.0.0/16", expErr: true},
+		{name: "LoopbackOnly", addrs: []net.Addr{testIPNet("127.0.0.1/8"), testIPNet("::1/128")}, expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var cidr *net.IPNet
+			if len(tt.cidr) > 0 {
+				_, cidr, _ = net.ParseCIDR(tt.cidr)
+			}
+
+			// when
+			ip, err := selectHostIP(tt.addrs, cidr)
+
+			// then
+			if tt.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expIP, ip)
+		})
+	}
+}
+
+func TestMemberList_AdvertiseAddr(t *testing.T) {
+	// given
+	cfg := testKVConfig()
+	cfg.AdvertiseAddr = "fd00::5"
+
+	ml := NewKVMemberList(cfg, 4312, &kvMock{}, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	lm, err := ml.getLocalMember(false)
+	require.NoError(t, err)
+
+	m, err := decodeClusterMember("i://b3fd", fmt.Sprintf(memberValueFormat, lm.String(), lm.APIVer))
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, "[fd00::5]:4312", lm.String())
+	require.Equal(t, "fd00::5", m.Host)
+	require.Equal(t, 4312, m.Port)
+}
+
+func testIPNet(cidr string) *net.IPNet {
+	ip, ipNet, _ := net.ParseCIDR(cidr)
+	ipNet.IP = ip
+	return ipNet
+}
+
+func testKVConfig() KVConfig {
+	return KVConfig{}
+}
@@ -16,7 +16,11 @@ package memberlist
 
 import (
 	"context"
+	"fmt"
 
+	kitlog "github.com/go-kit/log"
+	"github.com/ortuman/jackal/pkg/cluster/kv"
+	"github.com/ortuman/jackal/pkg/hook"
 	clustermodel "github.com/ortuman/jackal/pkg/model/cluster"
 )
 
@@ -28,9 +32,39 @@ type MemberList interface {
 	// GetMembers returns all cluster registered members.
 	GetMembers() map[string]clustermodel.Member
 
+	// SetDraining marks local member as draining (or not) for the rest of the cluster.
+	SetDraining(ctx context.Context, draining bool) error
+
 	// Start initializes memberlist.
 	Start(ctx context.Context) error
 
 	// Stop releases all underlying memberlist resources.
 	Stop(ctx context.Context) error
 }
+
+// Config defines cluster memberlist configuration.
+type Config struct {
+	// Type specifies how cluster members discover each other (kv or gossip).
+	Type string `fig:"type" default:"kv"`
+
+	// KV contains KV memberlist configuration.
+	KV KVConfig `fig:"kv"`
+
+	// Gossip contains gossip (SWIM) memberlist configuration.
+	Gossip GossipConfig `fig:"gossip"`
+}
+
+// New returns a new initialized MemberList instance.
+// localPort is the cluster server port advertised to the rest of members.
+func New(cfg Config, localPort int, kv kv.KV, hk *hook.Hooks, logger kitlog.Logger) (MemberList, error) {
+	switch cfg.Type {
+	case kvMemberListType:
+		return NewKVMemberList(cfg.KV, localPort, kv, hk, logger), nil
+
+	case gossipMemberListType:
+		return NewGossipMemberList(cfg.Gossip, localPort, hk, logger), nil
+
+	default:
+		return nil, fmt.Errorf("unrecognized memberlist type: %s", cfg.Type)
+	}
+}
@@ -40,6 +40,10 @@ func (ml *nopMemberList) GetMembers() map[string]clustermodel.Member {
 	return ml.members
 }
 
+func (ml *nopMemberList) SetDraining(_ context.Context, _ bool) error {
+	return nil
+}
+
 func (ml *nopMemberList) Start(_ context.Context) error {
 	return nil
 }
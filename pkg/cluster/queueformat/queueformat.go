@@ -0,0 +1,130 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queueformat
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackal-xmpp/stravaganza"
+	clusterpb "github.com/ortuman/jackal/pkg/cluster/pb"
+	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
+)
+
+const (
+	// Major is the major version of the transferred stream queue format.
+	// Nodes can only exchange stream queues sharing the same major version.
+	Major = 1
+
+	// Minor is the minor version of the transferred stream queue format.
+	//
+	// 1.0: legacy unversioned format
+	// 1.1: adds transferred element count
+	Minor = 1
+)
+
+// ErrIncompatible will be returned when a stream queue format major version is not supported.
+var ErrIncompatible = errors.New("queueformat: incompatible stream queue format")
+
+// Queue represents a transferred stream managed queue.
+type Queue struct {
+	// Elements contains the queue elements.
+	Elements []streamqueue.Element
+
+	// Nonce is the nonce queue byte slice.
+	Nonce []byte
+
+	// InH is the queue incoming h value.
+	InH uint32
+
+	// OutH is the queue outgoing h value.
+	OutH uint32
+}
+
+// Local returns the stream queue format supported by this node.
+func Local() *clusterpb.QueueFormat {
+	return &clusterpb.QueueFormat{Major: Major, Minor: Minor}
+}
+
+// IsCompatible tells whether a stream queue can be exchanged with a node supporting format f.
+// A nil format stands for the legacy unversioned one (1.0).
+func IsCompatible(f *clusterpb.QueueFormat) bool {
+	return f == nil || f.GetMajor() == Major
+}
+
+// Encode encodes a stream queue using the highest format version supported by both
+// this node and the requester, whose supported format is given by reqFormat.
+// Callers are expected to check reqFormat compatibility beforehand.
+func Encode(sq *Queue, reqFormat *clusterpb.QueueFormat) *clusterpb.TransferQueueResponse {
+	var resp clusterpb.TransferQueueResponse
+	for _, elem := range sq.Elements {
+		resp.Elements = append(resp.Elements, &clusterpb.QueueElement{
+			Stanza: elem.Stanza.Proto(),
+			H:      elem.H,
+		})
+	}
+	resp.Nonce = sq.Nonce
+	resp.InH = sq.InH
+	resp.OutH = sq.OutH
+
+	minor := uint32(Minor)
+	switch {
+	case reqFormat == nil:
+		return &resp // legacy requester
+	case reqFormat.GetMajor() == Major && reqFormat.GetMinor() < minor:
+		minor = reqFormat.GetMinor()
+	}
+	if minor >= 1 {
+		resp.ElementCount = uint32(len(resp.Elements))
+	}
+	resp.Format = &clusterpb.QueueFormat{Major: Major, Minor: minor}
+	return &resp
+}
+
+// Decode decodes a transferred stream queue.
+// Queues encoded by nodes running a newer minor version are decoded as well, ignoring unknown fields.
+func Decode(resp *clusterpb.TransferQueueResponse) (*Queue, error) {
+	var major, minor uint32 = 1, 0
+	if f := resp.GetFormat(); f != nil {
+		major, minor = f.GetMajor(), f.GetMinor()
+	}
+	if major != Major {
+		return nil, fmt.Errorf("%w: %d.%d", ErrIncompatible, major, minor)
+	}
+	if minor >= 1 && resp.GetElementCount() != uint32(len(resp.GetElements())) {
+		return nil, fmt.Errorf("queueformat: stream queue element count mismatch: got %d, expected %d",
+			len(resp.GetElements()), resp.GetElementCount(),
+		)
+	}
+	elements := make([]streamqueue.Element, 0, len(resp.GetElements()))
+
+	for _, elem := range resp.GetElements() {
+		b := stravaganza.NewBuilderFromProto(elem.GetStanza())
+		stanza, err := b.BuildStanza()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, streamqueue.Element{
+			Stanza: stanza,
+			H:      elem.GetH(),
+		})
+	}
+	return &Queue{
+		Elements: elements,
+		Nonce:    resp.GetNonce(),
+		InH:      resp.GetInH(),
+		OutH:     resp.GetOutH(),
+	}, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package queueformat
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	clusterpb "github.com/ortuman/jackal/pkg/cluster/pb"
+	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestDecode_LegacyFormat(t *testing.T) {
+	// given
+	legacyResp := &clusterpb.TransferQueueResponse{
+		Elements: []*clusterpb.QueueElement{
+			{Stanza: testQueueStanza("m1").Proto(), H: 1},
+			{Stanza: testQueueStanza("m2").Proto(), H: 2},
+		},
+		Nonce: []byte{1, 2, 3, 4},
+		InH:   5,
+		OutH:  2,
+	}
+	b, _ := proto.Marshal(legacyResp)
+
+	// when
+	var resp clusterpb.TransferQueueResponse
+	_ = proto.Unmarshal(b, &resp)
+
+	sq, err := Decode(&resp)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, sq.Elements, 2)
+	require.Equal(t, "m1", sq.Elements[0].Stanza.Attribute(stravaganza.ID))
+	require.Equal(t, uint32(2), sq.Elements[1].H)
+	require.Equal(t, []byte{1, 2, 3, 4}, sq.Nonce)
+	require.Equal(t, uint32(5), sq.InH)
+	require.Equal(t, uint32(2), sq.OutH)
+}
+
+func TestDecode_NewerMinorFormat(t *testing.T) {
+	// given
+	resp := &clusterpb.TransferQueueResponse{
+		Elements: []*clusterpb.QueueElement{
+			{Stanza: testQueueStanza("m1").Proto(), H: 1},
+		},
+		Nonce:        []byte{1, 2, 3, 4},
+		Format:       &clusterpb.QueueFormat{Major: Major, Minor: Minor + 1},
+		ElementCount: 1,
+	}
+
+	// when
+	sq, err := Decode(resp)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, sq.Elements, 1)
+}
+
+func TestDecode_IncompatibleFormat(t *testing.T) {
+	// given
+	resp := &clusterpb.TransferQueueResponse{
+		Format: &clusterpb.QueueFormat{Major: Major + 1},
+	}
+
+	// when
+	sq, err := Decode(resp)
+
+	// then
+	require.Nil(t, sq)
+	require.True(t, errors.Is(err, ErrIncompatible))
+}
+
+func TestDecode_ElementCountMismatch(t *testing.T) {
+	// given
+	resp := &clusterpb.TransferQueueResponse{
+		Elements: []*clusterpb.QueueElement{
+			{Stanza: testQueueStanza("m1").Proto(), H: 1},
+		},
+		Format:       &clusterpb.QueueFormat{Major: Major, Minor: 1},
+		ElementCount: 2,
+	}
+
+	// when
+	sq, err := Decode(resp)
+
+	// then
+	require.Nil(t, sq)
+	require.Error(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	sq := &Queue{
+		Elements: []streamqueue.Element{
+			{Stanza: testQueueStanza("m1"), H: 1},
+		},
+		Nonce: []byte{1, 2, 3, 4},
+		InH:   3,
+		OutH:  1,
+	}
+	var tcs = map[string]struct {
+		reqFormat    *clusterpb.QueueFormat
+		expFormat    *clusterpb.QueueFormat
+		expElemCount uint32
+	}{
+		"Legacy": {
+			reqFormat: nil,
+			expFormat: nil,
+		},
+		"PreviousMinor": {
+			reqFormat: &clusterpb.QueueFormat{Major: Major, Minor: 0},
+			expFormat: &clusterpb.QueueFormat{Major: Major, Minor: 0},
+		},
+		"SameMinor": {
+			reqFormat:    Local(),
+			expFormat:    Local(),
+			expElemCount: 1,
+		},
+		"NewerMinor": {
+			reqFormat:    &clusterpb.QueueFormat{Major: Major, Minor: Minor + 1},
+			expFormat:    Local(),
+			expElemCount: 1,
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// when
+			resp := Encode(sq, tCase.reqFormat)
+
+			// then
+			require.Len(t, resp.Elements, 1)
+			require.Equal(t, sq.Nonce, resp.Nonce)
+			require.Equal(t, sq.InH, resp.InH)
+			require.Equal(t, sq.OutH, resp.OutH)
+			require.Equal(t, tCase.expElemCount, resp.ElementCount)
+			require.True(t, proto.Equal(tCase.expFormat, resp.Format))
+
+			dsq, err := Decode(resp)
+			require.NoError(t, err)
+			require.Len(t, dsq.Elements, 1)
+		})
+	}
+}
+
+func TestIsCompatible(t *testing.T) {
+	require.True(t, IsCompatible(nil))
+	require.True(t, IsCompatible(&clusterpb.QueueFormat{Major: Major, Minor: Minor + 1}))
+	require.False(t, IsCompatible(&clusterpb.QueueFormat{Major: Major + 1}))
+}
+
+func testQueueStanza(id string) stravaganza.Stanza {
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute(stravaganza.ID, id)
+	b.WithAttribute(stravaganza.From, "noelia@jackal.im/yard")
+	b.WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+	return msg
+}
@@ -104,6 +104,27 @@ func TestResourceManager_GetResources(t *testing.T) {
 	require.Len(t, res, 3)
 }
 
+func TestResourceManager_GetAllResources(t *testing.T) {
+	// given
+	kvmock := &kvMock{}
+	kvmock.PutFunc = func(ctx context.Context, key string, value string) error { return nil }
+
+	h := NewKVManager(kvmock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	r0 := testResource("abc1234", 100, "ortuman", "yard")
+	r1 := testResource("bcd1234", 50, "noelia", "balcony")
+
+	_ = h.PutResource(context.Background(), r0)
+	_ = h.PutResource(context.Background(), r1)
+
+	// when
+	res, err := h.GetAllResources(context.Background())
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, res, 2)
+}
+
 func TestResourceManager_DelResource(t *testing.T) {
 	// given
 	kvmock := &kvMock{}
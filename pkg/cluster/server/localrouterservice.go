@@ -21,6 +21,7 @@ import (
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/c2s"
 	"github.com/ortuman/jackal/pkg/cluster/pb"
+	"github.com/ortuman/jackal/pkg/router"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -58,8 +59,17 @@ func (s *localRouterService) Route(_ context.Context, req *pb.LocalRouteRequest)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
-	_ = s.r.Route(st, req.GetUsername(), req.GetResource())
-	return &pb.LocalRouteResponse{}, nil
+	var result pb.DeliveryResult
+
+	switch err := s.r.Route(st, req.GetUsername(), req.GetResource()); err {
+	case nil:
+		result = pb.DeliveryResult_DELIVERY_RESULT_DELIVERED
+	case router.ErrResourceNotFound:
+		result = pb.DeliveryResult_DELIVERY_RESULT_NO_SUCH_RESOURCE
+	default:
+		result = pb.DeliveryResult_DELIVERY_RESULT_BOUNCED
+	}
+	return &pb.LocalRouteResponse{Result: result}, nil
 }
 
 func (s *localRouterService) Disconnect(_ context.Context, req *pb.LocalDisconnectRequest) (*pb.LocalDisconnectResponse, error) {
@@ -21,6 +21,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/cluster/pb"
+	"github.com/ortuman/jackal/pkg/router"
 	"github.com/stretchr/testify/require"
 )
 
@@ -46,6 +47,25 @@ func TestLocalRouterService_Route(t *testing.T) {
 	require.Equal(t, msg.String(), recvStanza.String())
 }
 
+func TestLocalRouterService_RouteNoSuchResource(t *testing.T) {
+	// given
+	lrMock := &localRouterMock{}
+	lrMock.RouteFunc = func(stanza stravaganza.Stanza, username string, resource string) error {
+		return router.ErrResourceNotFound
+	}
+
+	srv := &localRouterService{r: lrMock}
+
+	// when
+	msg := testMessageStanza()
+
+	resp, err := srv.Route(context.Background(), &pb.LocalRouteRequest{Stanza: msg.Proto()})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, pb.DeliveryResult_DELIVERY_RESULT_NO_SUCH_RESOURCE, resp.GetResult())
+}
+
 func TestLocalRouterService_Disconnect(t *testing.T) {
 	// given
 	lrMock := &localRouterMock{}
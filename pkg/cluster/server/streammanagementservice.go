@@ -16,9 +16,11 @@ package clusterserver
 
 import (
 	"context"
+	"fmt"
 
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/cluster/pb"
+	"github.com/ortuman/jackal/pkg/cluster/queueformat"
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 )
 
@@ -35,6 +37,10 @@ func (s *streamManagementService) TransferQueue(_ context.Context, req *pb.Trans
 	if s.stmQueueMap == nil {
 		return nil, nil // xep0198 not enabled
 	}
+	// check requester format before handing over the queue, so that it's kept in case it can't be transferred
+	if f := req.GetFormat(); !queueformat.IsCompatible(f) {
+		return nil, fmt.Errorf("%w: %d.%d", queueformat.ErrIncompatible, f.GetMajor(), f.GetMinor())
+	}
 	sq := s.stmQueueMap.Delete(req.Identifier)
 	if sq == nil {
 		return nil, nil
@@ -44,18 +50,12 @@ func (s *streamManagementService) TransferQueue(_ context.Context, req *pb.Trans
 		return nil, err
 	}
 	// transfer stream queue
-	var resp pb.TransferQueueResponse
-
-	elements := sq.Elements()
-	for _, elem := range elements {
-		resp.Elements = append(resp.Elements, &pb.QueueElement{
-			Stanza: elem.Stanza.Proto(),
-			H:      elem.H,
-		})
-	}
-	resp.Nonce = sq.Nonce()
-	resp.InH = sq.InboundH()
-	resp.OutH = sq.OutboundH()
-
-	return &resp, nil
+	resp := queueformat.Encode(&queueformat.Queue{
+		Elements: sq.Elements(),
+		Nonce:    sq.Nonce(),
+		InH:      sq.InboundH(),
+		OutH:     sq.OutboundH(),
+	}, req.GetFormat())
+
+	return resp, nil
 }
@@ -16,12 +16,14 @@ package clusterserver
 
 import (
 	"context"
+	"errors"
 	"math/rand"
 	"testing"
 	"time"
 
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/ortuman/jackal/pkg/cluster/pb"
+	"github.com/ortuman/jackal/pkg/cluster/queueformat"
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 	"github.com/stretchr/testify/require"
 )
@@ -53,6 +55,7 @@ func TestStreamManagementService_TransferQueue(t *testing.T) {
 		5,
 		10,
 		time.Second*5,
+		0,
 		time.Second*5,
 	)
 
@@ -74,4 +77,39 @@ func TestStreamManagementService_TransferQueue(t *testing.T) {
 	require.Equal(t, nonce, resp.Nonce)
 	require.Equal(t, uint32(5), resp.InH)
 	require.Equal(t, uint32(10), resp.OutH)
+	require.Nil(t, resp.Format) // legacy requester
+}
+
+func TestStreamManagementService_TransferQueueIncompatibleFormat(t *testing.T) {
+	// given
+	stmMock := &c2sStreamMock{}
+
+	q := streamqueue.New(
+		stmMock,
+		make([]byte, 16),
+		nil,
+		5,
+		10,
+		time.Second*5,
+		0,
+		time.Second*5,
+	)
+
+	sm := streamqueue.NewQueueMap()
+	sm.Set("q1", q)
+
+	srv := &streamManagementService{stmQueueMap: sm}
+
+	// when
+	resp, err := srv.TransferQueue(context.Background(), &pb.TransferQueueRequest{
+		Identifier: "q1",
+		Format:     &pb.QueueFormat{Major: queueformat.Major + 1},
+	})
+
+	// then
+	require.True(t, errors.Is(err, queueformat.ErrIncompatible))
+	require.Nil(t, resp)
+
+	require.Len(t, stmMock.DisconnectCalls(), 0)
+	require.NotNil(t, sm.Get("q1"))
 }
@@ -39,4 +39,8 @@ type ListenerConfig struct {
 
 	// MaxStanzaSize is the maximum size a listener incoming stanza may have.
 	MaxStanzaSize int
+
+	// DeliveryAcks, if true, the component will be notified about the delivery outcome
+	// of every routed stanza carrying an identifier.
+	DeliveryAcks bool `fig:"delivery_acks"`
 }
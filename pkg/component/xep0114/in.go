@@ -57,12 +57,21 @@ const (
 	disconnected
 )
 
+const deliveryAckNamespace = "urn:jackal:component:ack:0"
+
+const (
+	deliveredAckStatus = "delivered"
+	bouncedAckStatus   = "bounced"
+	queuedAckStatus    = "queued"
+)
+
 var disconnectTimeout = time.Second * 5
 
 type inConfig struct {
 	reqTimeout    time.Duration
 	maxStanzaSize int
 	secret        string
+	deliveryAcks  bool
 }
 
 type inComponent struct {
@@ -101,9 +110,8 @@ func newInComponent(
 	logger kitlog.Logger,
 	cfg inConfig,
 ) (*inComponent, error) {
-	// set default rate limiter
-	rLim := shapers.DefaultS2S().RateLimiter()
-	if err := tr.SetReadRateLimiter(rLim); err != nil {
+	// set default rate limiters
+	if err := shapers.DefaultS2S().ApplyRateLimiters(tr); err != nil {
 		return nil, err
 	}
 	// create session
@@ -306,14 +314,40 @@ func (s *inComponent) handleHandshaking(ctx context.Context, elem stravaganza.El
 func (s *inComponent) handleAuthenticated(ctx context.Context, elem stravaganza.Element) error {
 	switch stanza := elem.(type) {
 	case stravaganza.Stanza:
-		_, _ = s.router.Route(ctx, stanza)
-		return nil
+		return s.routeStanza(ctx, stanza)
 
 	default:
 		return s.disconnect(ctx, streamerror.E(streamerror.UnsupportedStanzaType))
 	}
 }
 
+func (s *inComponent) routeStanza(ctx context.Context, stanza stravaganza.Stanza) error {
+	targets, err := s.router.Route(ctx, stanza)
+	if err != nil {
+		return s.sendDeliveryAck(ctx, stanza, bouncedAckStatus)
+	}
+	if _, ok := stanza.(*stravaganza.Message); ok && len(targets) == 0 {
+		// message has been accepted without reaching any resource (e.g. queued offline)
+		return s.sendDeliveryAck(ctx, stanza, queuedAckStatus)
+	}
+	return s.sendDeliveryAck(ctx, stanza, deliveredAckStatus)
+}
+
+func (s *inComponent) sendDeliveryAck(ctx context.Context, stanza stravaganza.Stanza, status string) error {
+	id := stanza.Attribute(stravaganza.ID)
+	if !s.cfg.deliveryAcks || len(id) == 0 {
+		return nil
+	}
+	return s.sendElement(ctx, stravaganza.NewBuilder("ack").
+		WithAttribute(stravaganza.Namespace, deliveryAckNamespace).
+		WithAttribute(stravaganza.ID, id).
+		WithAttribute(stravaganza.From, stanza.Attribute(stravaganza.To)).
+		WithAttribute(stravaganza.To, stanza.Attribute(stravaganza.From)).
+		WithAttribute("status", status).
+		Build(),
+	)
+}
+
 func (s *inComponent) handleSessionError(ctx context.Context, err error) {
 	switch err {
 	case xmppparser.ErrStreamClosedByPeer:
@@ -428,8 +462,7 @@ func (s *inComponent) unregisterComponent(ctx context.Context) error {
 func (s *inComponent) updateTransportRateLimiter() error {
 	// update rate limiter
 	j := s.getJID()
-	rLim := s.shapers.MatchingJID(j).RateLimiter()
-	return s.tr.SetReadRateLimiter(rLim)
+	return s.shapers.MatchingJID(j).ApplyRateLimiters(s.tr)
 }
 
 func (s *inComponent) setJID(jd *jid.JID) {
@@ -30,6 +30,7 @@ import (
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/hook"
 	xmppparser "github.com/ortuman/jackal/pkg/parser"
+	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/transport"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
@@ -136,13 +137,28 @@ func TestInComponent_HandleSessionElement(t *testing.T) {
 	)
 	iq, _ := b.BuildIQ()
 
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute("id", "msg-1234").
+		WithAttribute("from", "upload.localhost").
+		WithAttribute("to", "ortuman@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Hi there!").
+				Build(),
+		).
+		BuildMessage()
+
+	resJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+
 	var tests = []struct {
 		name string
 
 		// input
 		state        inComponentState
 		sessionResFn func() (stravaganza.Element, error)
+		routeTargets []jid.JID
 		routeError   error
+		deliveryAcks bool
 
 		// expectations
 		expectedOutput string
@@ -193,6 +209,51 @@ func TestInComponent_HandleSessionElement(t *testing.T) {
 			expectedState: authenticated,
 			expectRouted:  true,
 		},
+		{
+			name:  "Route/DeliveredAck",
+			state: authenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return iq, nil
+			},
+			deliveryAcks:   true,
+			expectedOutput: `<ack xmlns='urn:jackal:component:ack:0' id='iq-1234' from='ortuman@jackal.im/balcony' to='upload.localhost' status='delivered'/>`,
+			expectedState:  authenticated,
+			expectRouted:   true,
+		},
+		{
+			name:  "Route/BouncedAck",
+			state: authenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return iq, nil
+			},
+			routeError:     router.ErrResourceNotFound,
+			deliveryAcks:   true,
+			expectedOutput: `<ack xmlns='urn:jackal:component:ack:0' id='iq-1234' from='ortuman@jackal.im/balcony' to='upload.localhost' status='bounced'/>`,
+			expectedState:  authenticated,
+		},
+		{
+			name:  "Route/MessageDeliveredAck",
+			state: authenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return msg, nil
+			},
+			routeTargets:   []jid.JID{*resJID},
+			deliveryAcks:   true,
+			expectedOutput: `<ack xmlns='urn:jackal:component:ack:0' id='msg-1234' from='ortuman@jackal.im' to='upload.localhost' status='delivered'/>`,
+			expectedState:  authenticated,
+			expectRouted:   true,
+		},
+		{
+			name:  "Route/MessageQueuedAck",
+			state: authenticated,
+			sessionResFn: func() (stravaganza.Element, error) {
+				return msg, nil
+			},
+			deliveryAcks:   true,
+			expectedOutput: `<ack xmlns='urn:jackal:component:ack:0' id='msg-1234' from='ortuman@jackal.im' to='upload.localhost' status='queued'/>`,
+			expectedState:  authenticated,
+			expectRouted:   true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -204,6 +265,7 @@ func TestInComponent_HandleSessionElement(t *testing.T) {
 			routerMock := &routerMock{}
 
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error {
 				return nil
 			}
@@ -244,8 +306,11 @@ func TestInComponent_HandleSessionElement(t *testing.T) {
 
 			var routed bool
 			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				if tt.routeError != nil {
+					return nil, tt.routeError
+				}
 				routed = true
-				return nil, nil
+				return tt.routeTargets, nil
 			}
 
 			stm := &inComponent{
@@ -253,6 +318,7 @@ func TestInComponent_HandleSessionElement(t *testing.T) {
 					reqTimeout:    time.Minute,
 					maxStanzaSize: 8192,
 					secret:        "a-secret-1",
+					deliveryAcks:  tt.deliveryAcks,
 				},
 				state:      uint32(tt.state),
 				rq:         runqueue.New(tt.name),
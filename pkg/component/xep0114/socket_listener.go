@@ -156,7 +156,7 @@ func (l *SocketListener) Stop(ctx context.Context) error {
 }
 
 func (l *SocketListener) handleConn(conn net.Conn) {
-	tr := transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout)
+	tr := transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout, 0, 0, 0)
 	stm, err := newInComponent(
 		tr,
 		l.hosts,
@@ -170,6 +170,7 @@ func (l *SocketListener) handleConn(conn net.Conn) {
 		inConfig{
 			reqTimeout:    l.cfg.RequestTimeout,
 			maxStanzaSize: l.cfg.MaxStanzaSize,
+			deliveryAcks:  l.cfg.DeliveryAcks,
 			secret:        l.secretKey,
 		},
 	)
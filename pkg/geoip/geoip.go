@@ -0,0 +1,86 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"context"
+	"net"
+)
+
+// Origin contains geolocation information associated to a connection origin.
+type Origin struct {
+	// Country is the ISO 3166-1 alpha-2 country code.
+	Country string
+
+	// ASN is the autonomous system number.
+	ASN uint32
+}
+
+// IsZero tells whether origin contains no geolocation information.
+func (o Origin) IsZero() bool {
+	return len(o.Country) == 0 && o.ASN == 0
+}
+
+// Provider defines GeoIP lookup provider interface.
+type Provider interface {
+	// Lookup returns the origin information associated to an IP address.
+	Lookup(ctx context.Context, ip net.IP) (Origin, error)
+}
+
+type nopProvider struct{}
+
+// NewNopProvider returns a Provider that never resolves any origin information.
+func NewNopProvider() Provider { return &nopProvider{} }
+
+func (p *nopProvider) Lookup(_ context.Context, _ net.IP) (Origin, error) { return Origin{}, nil }
+
+// Tag resolves addr origin information using p provider.
+// In case p is nil, addr is not an IP address or lookup fails a zero origin is returned.
+func Tag(ctx context.Context, p Provider, addr net.Addr) Origin {
+	if p == nil {
+		return Origin{}
+	}
+	ip := AddrIP(addr)
+	if ip == nil {
+		return Origin{}
+	}
+	origin, err := p.Lookup(ctx, ip)
+	if err != nil {
+		return Origin{}
+	}
+	return origin
+}
+
+// AddrIP returns the IP address contained in addr.
+// In case addr is nil or is not an IP address nil is returned.
+func AddrIP(addr net.Addr) net.IP {
+	if addr == nil {
+		return nil
+	}
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP
+	case *net.UDPAddr:
+		return a.IP
+	case *net.IPAddr:
+		return a.IP
+	default:
+		host, _, err := net.SplitHostPort(addr.String())
+		if err != nil {
+			return nil
+		}
+		return net.ParseIP(host)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geoip
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTag(t *testing.T) {
+	// given
+	pMock := &providerMock{}
+	pMock.LookupFunc = func(_ context.Context, ip net.IP) (Origin, error) {
+		if ip.Equal(net.ParseIP("203.0.113.7")) {
+			return Origin{Country: "ES", ASN: 64496}, nil
+		}
+		return Origin{}, errors.New("geoip: address not found")
+	}
+
+	// when
+	o0 := Tag(context.Background(), pMock, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 5222})
+	o1 := Tag(context.Background(), pMock, &net.TCPAddr{IP: net.ParseIP("198.51.100.1"), Port: 5222})
+
+	// then
+	require.Equal(t, Origin{Country: "ES", ASN: 64496}, o0)
+	require.True(t, o1.IsZero())
+}
+
+func TestTag_NilProvider(t *testing.T) {
+	// when
+	o := Tag(context.Background(), nil, &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 5222})
+
+	// then
+	require.True(t, o.IsZero())
+}
@@ -15,6 +15,8 @@
 package hook
 
 import (
+	"net"
+
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
 )
@@ -26,6 +28,10 @@ const (
 	// C2SStreamBinded hook runs when C2S stream is bounded.
 	C2SStreamBinded = "c2s.stream.binded"
 
+	// C2SStreamWillDisconnect hook runs when a bound C2S stream is about to be closed.
+	// Handlers may set the info Element value to an XMPP element that will be sent before closing the stream.
+	C2SStreamWillDisconnect = "c2s.stream.will_disconnect"
+
 	// C2SStreamDisconnected hook runs when a C2S connection is unregistered.
 	C2SStreamDisconnected = "c2s.stream.disconnected"
 
@@ -44,6 +50,9 @@ const (
 	// C2SStreamMessageReceived hook runs when a message stanza is received over a C2S stream.
 	C2SStreamMessageReceived = "c2s.stream.message_received"
 
+	// C2SStreamWhitespaceKeepAlive hook runs when a whitespace keepalive is received over a C2S stream.
+	C2SStreamWhitespaceKeepAlive = "c2s.stream.whitespace_keepalive"
+
 	// C2SStreamWillRouteElement hook runs when an XMPP element is about to be routed over a C2S stream.
 	C2SStreamWillRouteElement = "c2s.stream.will_route_element"
 
@@ -56,8 +65,22 @@ const (
 	// C2SStreamMessageRouted hook runs when a message stanza is successfully routed to one ore more C2S streams.
 	C2SStreamMessageRouted = "c2s.stream.message_routed"
 
+	// C2SStreamMessageBounced hook runs when a message stanza sent over a C2S stream is bounced back to its sender.
+	C2SStreamMessageBounced = "c2s.stream.message_bounced"
+
+	// C2SStreamWillSendElement hook runs when a XMPP element is about to be sent over a C2S stream.
+	// Halting its execution prevents the element from being sent.
+	C2SStreamWillSendElement = "c2s.stream.will_send_element"
+
 	// C2SStreamElementSent hook runs when a XMPP element is sent over a C2S stream.
 	C2SStreamElementSent = "c2s.stream.element_sent"
+
+	// C2SStreamClientStateChanged hook runs when a C2S client reports an active or inactive state (XEP-0352).
+	C2SStreamClientStateChanged = "c2s.stream.client_state_changed"
+
+	// C2SRouterMessageUndelivered hook runs when a message stanza could not be delivered because none of
+	// its destination resources was available at delivery time.
+	C2SRouterMessageUndelivered = "c2s.router.message_undelivered"
 )
 
 // C2SStreamInfo contains all info associated to a C2S stream event.
@@ -79,4 +102,12 @@ type C2SStreamInfo struct {
 
 	// DisconnectError contains the original error that caused stream disconnection.
 	DisconnectError error
+
+	// DisconnectReason is a short description of the disconnection cause.
+	// For stream errors it contains the stream error condition (e.g. policy-violation).
+	DisconnectReason string
+
+	// RemoteIP is the IP address of the connected client, as reported by the listener connection.
+	// Behind a PROXY protocol aware listener this is the original client address.
+	RemoteIP net.IP
 }
@@ -17,9 +17,11 @@ package hook
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
 	"reflect"
 	"sort"
+	"strings"
 	"sync"
 )
 
@@ -43,6 +45,18 @@ type Handler func(ctx context.Context, execCtx *ExecutionContext) error
 // ErrStopped error is returned by a handler to halt hook execution.
 var ErrStopped = errors.New("hook: execution stopped")
 
+// Errors aggregates the errors returned by hook handlers when running in error isolation mode.
+type Errors []error
+
+// Error satisfies error interface.
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("hook: %d handler(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
 // ExecutionContext defines a hook execution info context.
 type ExecutionContext struct {
 	Info   interface{}
@@ -50,35 +64,77 @@ type ExecutionContext struct {
 }
 
 type handler struct {
-	h Handler
-	p Priority
+	h   Handler
+	p   Priority
+	seq uint64
+}
+
+// Config contains hooks configuration options.
+type Config struct {
+	// ConcurrencyLimits defines the maximum number of concurrent executions per hook type.
+	// Hook types not present in the map are not bounded.
+	ConcurrencyLimits map[string]int `fig:"concurrency_limits"`
+
+	// IsolateErrors tells whether a failing handler should not prevent the remaining ones from running.
+	IsolateErrors bool `fig:"isolate_errors"`
 }
 
 // Hooks represents a set of module hook handlers.
 type Hooks struct {
 	mu       sync.RWMutex
 	handlers map[string][]handler
+	sems     map[string]chan struct{}
+	isolated bool
+	seq      uint64
 }
 
 // NewHooks returns a new initialized Hooks instance.
 func NewHooks() *Hooks {
 	return &Hooks{
 		handlers: make(map[string][]handler),
+		sems:     make(map[string]chan struct{}),
+	}
+}
+
+// SetConcurrencyLimit bounds the number of concurrent executions of a given hook.
+// Once the limit is reached Run blocks until a slot is released or context is done.
+// A Run invoked from within a handler of the same hook, using the handler context, reuses the slot
+// already held by its caller.
+// A non-positive limit removes any previously set bound.
+func (h *Hooks) SetConcurrencyLimit(hook string, limit int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if limit <= 0 {
+		delete(h.sems, hook)
+		return
 	}
+	h.sems[hook] = make(chan struct{}, limit)
+}
+
+// SetErrorIsolation sets hooks execution mode.
+// In strict mode (default) the first handler error halts execution and is returned by Run.
+// In isolation mode every handler runs regardless of previous failures and all errors are aggregated into Errors.
+func (h *Hooks) SetErrorIsolation(isolated bool) {
+	h.mu.Lock()
+	h.isolated = isolated
+	h.mu.Unlock()
 }
 
 // AddHook adds a new handler to a given hook providing an execution priority value.
-// hnd priority may be any number (including negative). Handlers with a higher priority are executed first.
+// hnd priority may be any number (including negative). Handlers with a higher priority are executed first,
+// while handlers sharing the same priority are executed in registration order.
 func (h *Hooks) AddHook(hook string, hnd Handler, priority Priority) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	handlers := h.handlers[hook]
 	handlers = append(handlers, handler{
-		h: hnd, p: priority,
+		h: hnd, p: priority, seq: h.seq,
 	})
+	h.seq++
 	// sort by priority
-	sort.Slice(handlers, func(i, j int) bool { return handlers[i].p > handlers[j].p })
+	sort.SliceStable(handlers, func(i, j int) bool { return handlers[i].p > handlers[j].p })
 
 	h.handlers[hook] = handlers
 }
@@ -99,12 +155,48 @@ func (h *Hooks) RemoveHook(hook string, hnd Handler) {
 	}
 }
 
+// Mark returns a marker identifying all handlers added from now on.
+func (h *Hooks) Mark() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.seq
+}
+
+// RemoveHooksSince removes all handlers added after mark was obtained.
+func (h *Hooks) RemoveHooksSince(mark uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for hook, handlers := range h.handlers {
+		kept := handlers[:0]
+		for _, handler := range handlers {
+			if handler.seq < mark {
+				kept = append(kept, handler)
+			}
+		}
+		h.handlers[hook] = kept
+	}
+}
+
 // Run invokes all hook handlers in order.
 // If halted return value is true no more handlers are invoked.
+// Returning ErrStopped from a handler halts execution regardless of error isolation mode.
 func (h *Hooks) Run(ctx context.Context, hook string, execCtx *ExecutionContext) (halted bool, err error) {
+	if sem := h.semaphore(hook); sem != nil && !holdsSlot(ctx, hook) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+		// nested runs of the same hook within handlers reuse the acquired slot
+		ctx = context.WithValue(ctx, heldSlotCtxKey{}, &heldSlot{hook: hook, parent: heldSlotFromContext(ctx)})
+	}
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
+	var errs Errors
+
 	handlers := h.handlers[hook]
 	for _, handler := range handlers {
 		err := handler.h(ctx, execCtx)
@@ -112,10 +204,47 @@ func (h *Hooks) Run(ctx context.Context, hook string, execCtx *ExecutionContext)
 		case err == nil:
 			break
 		case errors.Is(err, ErrStopped):
-			return true, nil
+			return true, errs.orNil()
+		case h.isolated:
+			errs = append(errs, err)
 		default:
 			return false, err
 		}
 	}
-	return false, nil
+	return false, errs.orNil()
+}
+
+func (h *Hooks) semaphore(hook string) chan struct{} {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.sems[hook]
+}
+
+func (e Errors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+type heldSlotCtxKey struct{}
+
+// heldSlot represents a hook concurrency slot acquired along a Run call chain.
+type heldSlot struct {
+	hook   string
+	parent *heldSlot
+}
+
+func heldSlotFromContext(ctx context.Context) *heldSlot {
+	hs, _ := ctx.Value(heldSlotCtxKey{}).(*heldSlot)
+	return hs
+}
+
+func holdsSlot(ctx context.Context, hook string) bool {
+	for hs := heldSlotFromContext(ctx); hs != nil; hs = hs.parent {
+		if hs.hook == hook {
+			return true
+		}
+	}
+	return false
 }
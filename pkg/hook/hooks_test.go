@@ -16,7 +16,11 @@ package hook
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -59,6 +63,35 @@ func TestHooks_Remove(t *testing.T) {
 	require.Len(t, h.handlers["h1"], 0)
 }
 
+func TestHooks_RemoveSince(t *testing.T) {
+	// given
+	h := NewHooks()
+
+	var ran []string
+	h.AddHook("h1", func(_ context.Context, _ *ExecutionContext) error {
+		ran = append(ran, "a")
+		return nil
+	}, 1)
+
+	// when
+	mark := h.Mark()
+	h.AddHook("h1", func(_ context.Context, _ *ExecutionContext) error {
+		ran = append(ran, "b")
+		return nil
+	}, 10)
+	h.AddHook("h2", func(_ context.Context, _ *ExecutionContext) error {
+		ran = append(ran, "c")
+		return nil
+	}, 1)
+	h.RemoveHooksSince(mark)
+
+	_, _ = h.Run(context.Background(), "h1", nil)
+	_, _ = h.Run(context.Background(), "h2", nil)
+
+	// then
+	require.Equal(t, []string{"a"}, ran)
+}
+
 func TestHooks_Run(t *testing.T) {
 	// given
 	h := NewHooks()
@@ -104,3 +137,189 @@ func TestHooks_HaltedRun(t *testing.T) {
 
 	require.Equal(t, 2, i)
 }
+
+func TestHooks_ConcurrencyLimit(t *testing.T) {
+	// given
+	h := NewHooks()
+	h.SetConcurrencyLimit("h1", 2)
+
+	var running, maxRunning, calls int32
+	h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond * 10)
+
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}, DefaultPriority)
+
+	// when
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = h.Run(context.Background(), "h1", &ExecutionContext{})
+		}()
+	}
+	wg.Wait()
+
+	// then
+	require.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+	require.Equal(t, int32(10), atomic.LoadInt32(&calls))
+}
+
+func TestHooks_ConcurrencyLimitContextDone(t *testing.T) {
+	// given
+	h := NewHooks()
+	h.SetConcurrencyLimit("h1", 1)
+
+	releaseCh := make(chan struct{})
+	startedCh := make(chan struct{})
+	h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+		close(startedCh)
+		<-releaseCh
+		return nil
+	}, DefaultPriority)
+
+	go func() { _, _ = h.Run(context.Background(), "h1", &ExecutionContext{}) }()
+	<-startedCh
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	_, err := h.Run(ctx, "h1", &ExecutionContext{})
+	close(releaseCh)
+
+	// then
+	require.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestHooks_ConcurrencyLimitReentrant(t *testing.T) {
+	// given
+	h := NewHooks()
+	h.SetConcurrencyLimit("h1", 1)
+
+	var depth int
+	h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+		depth++
+		if depth < 3 {
+			_, err := h.Run(ctx, "h1", execCtx)
+			return err
+		}
+		return nil
+	}, DefaultPriority)
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := h.Run(ctx, "h1", &ExecutionContext{})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 3, depth)
+
+	require.Len(t, h.sems["h1"], 0) // slot released
+}
+
+func TestHooks_RunOrder(t *testing.T) {
+	// given
+	h := NewHooks()
+
+	var order []string
+	mkHandler := func(name string) Handler {
+		return func(ctx context.Context, execCtx *ExecutionContext) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	h.AddHook("h1", mkHandler("d1"), DefaultPriority)
+	h.AddHook("h1", mkHandler("l1"), LowestPriority)
+	h.AddHook("h1", mkHandler("d2"), DefaultPriority)
+	h.AddHook("h1", mkHandler("h1"), HighestPriority)
+	h.AddHook("h1", mkHandler("d3"), DefaultPriority)
+
+	// when
+	halted, err := h.Run(context.Background(), "h1", &ExecutionContext{})
+
+	// then
+	require.False(t, halted)
+	require.Nil(t, err)
+	require.Equal(t, []string{"h1", "d1", "d2", "d3", "l1"}, order)
+}
+
+func TestHooks_RunErrors(t *testing.T) {
+	err1 := errors.New("err1")
+	err2 := errors.New("err2")
+
+	var tests = []struct {
+		name     string
+		isolated bool
+		expCalls int
+		expErr   error
+	}{
+		{name: "Strict", isolated: false, expCalls: 1, expErr: err1},
+		{name: "Isolated", isolated: true, expCalls: 3, expErr: Errors{err1, err2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			h := NewHooks()
+			h.SetErrorIsolation(tt.isolated)
+
+			var calls int
+			h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+				calls++
+				return err1
+			}, HighestPriority)
+			h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+				calls++
+				return err2
+			}, DefaultPriority)
+			h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+				calls++
+				return nil
+			}, LowestPriority)
+
+			// when
+			halted, err := h.Run(context.Background(), "h1", &ExecutionContext{})
+
+			// then
+			require.False(t, halted)
+			require.Equal(t, tt.expCalls, calls)
+			require.Equal(t, tt.expErr, err)
+		})
+	}
+}
+
+func TestHooks_RunIsolatedStopped(t *testing.T) {
+	// given
+	h := NewHooks()
+	h.SetErrorIsolation(true)
+
+	var calls int
+	h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+		calls++
+		return ErrStopped
+	}, HighestPriority)
+	h.AddHook("h1", func(ctx context.Context, execCtx *ExecutionContext) error {
+		calls++
+		return nil
+	}, DefaultPriority)
+
+	// when
+	halted, err := h.Run(context.Background(), "h1", &ExecutionContext{})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,26 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hook
+
+const (
+	// InstanceDrainingUpdated hook runs whenever local instance draining mode is enabled or disabled.
+	InstanceDrainingUpdated = "instance.draining_updated"
+)
+
+// InstanceInfo contains all info associated to a local instance event.
+type InstanceInfo struct {
+	// Draining tells whether local instance is being drained.
+	Draining bool
+}
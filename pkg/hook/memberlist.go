@@ -30,4 +30,7 @@ type MemberListInfo struct {
 
 	// UnregisteredKeys contains unregistered cluster members keys.
 	UnregisteredKeys []string
+
+	// Incompatible contains cluster members discarded because of an incompatible cluster API version.
+	Incompatible []clustermodel.Member
 }
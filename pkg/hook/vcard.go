@@ -29,6 +29,9 @@ type VCardInfo struct {
 	// Username is the name of the vCard user associated to this event.
 	Username string
 
+	// Host is the local domain the vCard update was addressed to.
+	Host string
+
 	// VCard is the vCard element associated to this event.
 	VCard stravaganza.Element
 }
@@ -18,6 +18,7 @@ import (
 	"crypto/tls"
 	"sort"
 	"sync"
+	"time"
 
 	tlsutil "github.com/ortuman/jackal/pkg/util/tls"
 )
@@ -29,6 +30,10 @@ type Hosts struct {
 	mu          sync.RWMutex
 	defaultHost string
 	hosts       map[string]tls.Certificate
+	maxSessions map[string]int
+	sessions    map[string]int
+	autoPrWins  map[string]time.Duration
+	flags       map[string]map[string]bool
 }
 
 // Configs contains a set of host configurations.
@@ -37,7 +42,26 @@ type Configs []Config
 // Config contains host configuration parameters.
 type Config struct {
 	Domain string `fig:"domain"`
-	TLS    struct {
+
+	// MaxSessions defines the total number of concurrent sessions allowed for this host
+	// across all listeners. Zero value means no limit.
+	MaxSessions int `fig:"max_sessions"`
+
+	// AutoPresence contains bind-time presence autosend configuration.
+	AutoPresence struct {
+		// Enabled tells whether an initial available presence should be broadcast on behalf of
+		// a bound client that didn't send one on its own.
+		Enabled bool `fig:"enabled"`
+
+		// Window defines how long to wait after resource binding for the client initial presence.
+		Window time.Duration `fig:"window" default:"5s"`
+	} `fig:"auto_presence"`
+
+	// FeatureFlags contains host default feature flag values.
+	// Accounts may override them through the repository per-account settings.
+	FeatureFlags map[string]bool `fig:"feature_flags"`
+
+	TLS struct {
 		CertFile       string `fig:"cert_file"`
 		PrivateKeyFile string `fig:"privkey_file"`
 	} `fig:"tls"`
@@ -46,7 +70,11 @@ type Config struct {
 // NewHosts creates and initializes a Hosts instance.
 func NewHosts(cfg Configs) (*Hosts, error) {
 	hs := &Hosts{
-		hosts: make(map[string]tls.Certificate),
+		hosts:       make(map[string]tls.Certificate),
+		maxSessions: make(map[string]int),
+		sessions:    make(map[string]int),
+		autoPrWins:  make(map[string]time.Duration),
+		flags:       make(map[string]map[string]bool),
 	}
 	if len(cfg) == 0 {
 		cer, err := tlsutil.LoadCertificate("", "", defaultDomain)
@@ -66,6 +94,13 @@ func NewHosts(cfg Configs) (*Hosts, error) {
 		} else {
 			hs.RegisterHost(config.Domain, cer)
 		}
+		hs.SetMaxSessions(config.Domain, config.MaxSessions)
+		if config.AutoPresence.Enabled {
+			hs.SetAutoPresenceWindow(config.Domain, config.AutoPresence.Window)
+		}
+		for flag, enabled := range config.FeatureFlags {
+			hs.SetFeatureFlag(config.Domain, flag, enabled)
+		}
 	}
 	return hs, nil
 }
@@ -112,6 +147,79 @@ func (hs *Hosts) HostNames() []string {
 	return ret
 }
 
+// SetMaxSessions sets the total number of concurrent sessions allowed for host h.
+// A non-positive value removes the limit.
+func (hs *Hosts) SetMaxSessions(h string, maxSessions int) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if maxSessions <= 0 {
+		delete(hs.maxSessions, h)
+		return
+	}
+	hs.maxSessions[h] = maxSessions
+}
+
+// AcquireSession reserves a session slot for host h.
+// Returns false in case host session budget has already been exhausted.
+func (hs *Hosts) AcquireSession(h string) bool {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if maxSessions, ok := hs.maxSessions[h]; ok && hs.sessions[h] >= maxSessions {
+		return false
+	}
+	hs.sessions[h]++
+	return true
+}
+
+// ReleaseSession releases a session slot previously acquired for host h.
+func (hs *Hosts) ReleaseSession(h string) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.sessions[h] <= 1 {
+		delete(hs.sessions, h)
+		return
+	}
+	hs.sessions[h]--
+}
+
+// SetAutoPresenceWindow enables initial presence autosend for host h, waiting window for the client
+// to send its own one after resource binding. A non-positive value disables it.
+func (hs *Hosts) SetAutoPresenceWindow(h string, window time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if window <= 0 {
+		delete(hs.autoPrWins, h)
+		return
+	}
+	hs.autoPrWins[h] = window
+}
+
+// AutoPresenceWindow returns host h initial presence autosend window.
+// Returns zero in case presence autosend is not enabled for the host.
+func (hs *Hosts) AutoPresenceWindow(h string) time.Duration {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return hs.autoPrWins[h]
+}
+
+// SetFeatureFlag sets host h default value for feature flag.
+func (hs *Hosts) SetFeatureFlag(h, flag string, enabled bool) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.flags[h] == nil {
+		hs.flags[h] = make(map[string]bool)
+	}
+	hs.flags[h][flag] = enabled
+}
+
+// FeatureFlag returns host h default value for feature flag.
+// Returns false in case the flag is not set for the host.
+func (hs *Hosts) FeatureFlag(h, flag string) bool {
+	hs.mu.RLock()
+	defer hs.mu.RUnlock()
+	return hs.flags[h][flag]
+}
+
 // Certificates returns all registered domain certificates.
 func (hs *Hosts) Certificates() []tls.Certificate {
 	hs.mu.RLock()
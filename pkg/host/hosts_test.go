@@ -17,6 +17,7 @@ package host
 import (
 	"crypto/tls"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -55,3 +56,71 @@ func TestHosts_Domains(t *testing.T) {
 	require.True(t, h.IsLocalHost("jackal.org"))
 	require.True(t, h.IsLocalHost("jackal.net"))
 }
+
+func TestHosts_SessionBudget(t *testing.T) {
+	// given
+	h := &Hosts{
+		hosts:       make(map[string]tls.Certificate),
+		maxSessions: make(map[string]int),
+		sessions:    make(map[string]int),
+	}
+	h.RegisterDefaultHost("jackal.im", tls.Certificate{})
+	h.RegisterHost("jackal.net", tls.Certificate{})
+
+	h.SetMaxSessions("jackal.im", 2)
+
+	// when
+	ok1 := h.AcquireSession("jackal.im")
+	ok2 := h.AcquireSession("jackal.im")
+	ok3 := h.AcquireSession("jackal.im")
+	okNet := h.AcquireSession("jackal.net")
+
+	h.ReleaseSession("jackal.im")
+	ok4 := h.AcquireSession("jackal.im")
+
+	// then
+	require.True(t, ok1)
+	require.True(t, ok2)
+	require.False(t, ok3) // budget exhausted
+	require.True(t, okNet)
+	require.True(t, ok4)
+}
+
+func TestHosts_AutoPresenceWindow(t *testing.T) {
+	// given
+	h := &Hosts{
+		hosts:      make(map[string]tls.Certificate),
+		autoPrWins: make(map[string]time.Duration),
+	}
+	h.RegisterDefaultHost("jackal.im", tls.Certificate{})
+	h.RegisterHost("jackal.net", tls.Certificate{})
+
+	// when
+	h.SetAutoPresenceWindow("jackal.im", time.Second)
+	h.SetAutoPresenceWindow("jackal.net", time.Second)
+	h.SetAutoPresenceWindow("jackal.net", 0)
+
+	// then
+	require.Equal(t, time.Second, h.AutoPresenceWindow("jackal.im"))
+	require.Equal(t, time.Duration(0), h.AutoPresenceWindow("jackal.net"))
+}
+
+func TestHosts_FeatureFlag(t *testing.T) {
+	// given
+	h := &Hosts{
+		hosts: make(map[string]tls.Certificate),
+		flags: make(map[string]map[string]bool),
+	}
+	h.RegisterDefaultHost("jackal.im", tls.Certificate{})
+	h.RegisterHost("jackal.net", tls.Certificate{})
+
+	// when
+	h.SetFeatureFlag("jackal.im", "carbons_default_on", true)
+	h.SetFeatureFlag("jackal.net", "carbons_default_on", false)
+
+	// then
+	require.True(t, h.FeatureFlag("jackal.im", "carbons_default_on"))
+	require.False(t, h.FeatureFlag("jackal.net", "carbons_default_on"))
+	require.False(t, h.FeatureFlag("jackal.im", "mam_enabled"))
+	require.False(t, h.FeatureFlag("jackal.org", "carbons_default_on"))
+}
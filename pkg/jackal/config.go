@@ -16,22 +16,39 @@ package jackal
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/kkyr/fig"
 	adminserver "github.com/ortuman/jackal/pkg/admin/server"
 	"github.com/ortuman/jackal/pkg/auth/pepper"
 	"github.com/ortuman/jackal/pkg/c2s"
+	clusterconnmanager "github.com/ortuman/jackal/pkg/cluster/connmanager"
 	"github.com/ortuman/jackal/pkg/cluster/kv"
+	"github.com/ortuman/jackal/pkg/cluster/memberlist"
 	clusterserver "github.com/ortuman/jackal/pkg/cluster/server"
 	"github.com/ortuman/jackal/pkg/component/xep0114"
+	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/module/offline"
+	"github.com/ortuman/jackal/pkg/module/roster"
+	"github.com/ortuman/jackal/pkg/module/scheduled"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	"github.com/ortuman/jackal/pkg/module/xep0054"
 	"github.com/ortuman/jackal/pkg/module/xep0092"
+	"github.com/ortuman/jackal/pkg/module/xep0115"
 	"github.com/ortuman/jackal/pkg/module/xep0198"
 	"github.com/ortuman/jackal/pkg/module/xep0199"
+	"github.com/ortuman/jackal/pkg/module/xep0202"
+	"github.com/ortuman/jackal/pkg/module/xep0258"
+	"github.com/ortuman/jackal/pkg/module/xep0280"
+	"github.com/ortuman/jackal/pkg/module/xep0363"
+	"github.com/ortuman/jackal/pkg/preflight"
+	"github.com/ortuman/jackal/pkg/router/policy"
 	"github.com/ortuman/jackal/pkg/s2s"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/storage"
+	"github.com/ortuman/jackal/pkg/tlsticket"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 )
 
 const (
@@ -43,6 +60,11 @@ const (
 type LoggerConfig struct {
 	Level  string `fig:"level" default:"debug"`
 	Format string `fig:"format"`
+
+	Redact struct {
+		Elements   []string `fig:"elements"`
+		Namespaces []string `fig:"namespaces"`
+	} `fig:"redact"`
 }
 
 // HTTPConfig defines HTTP configuration.
@@ -50,11 +72,20 @@ type HTTPConfig struct {
 	Port int `fig:"port" default:"6060"`
 }
 
+// DrainConfig defines instance draining configuration.
+type DrainConfig struct {
+	// Timeout defines the maximum amount of time shutdown waits for stream queues
+	// to be emptied when the instance is being drained.
+	Timeout time.Duration `fig:"timeout" default:"1m"`
+}
+
 // ClusterConfig defines cluster configuration.
 type ClusterConfig struct {
-	Type   string               `fig:"type" default:"none"`
-	KV     kv.Config            `fig:"kv"`
-	Server clusterserver.Config `fig:"server"`
+	Type       string                    `fig:"type" default:"none"`
+	KV         kv.Config                 `fig:"kv"`
+	MemberList memberlist.Config         `fig:"memberlist"`
+	Server     clusterserver.Config      `fig:"server"`
+	Conns      clusterconnmanager.Config `fig:"connections"`
 }
 
 // IsEnabled tells whether cluster config is enabled.
@@ -64,7 +95,8 @@ func (c ClusterConfig) IsEnabled() bool {
 
 // C2SConfig defines C2S subsystem configuration.
 type C2SConfig struct {
-	Listeners c2s.ListenersConfig `fig:"listeners"`
+	Listeners      c2s.ListenersConfig `fig:"listeners"`
+	SessionTickets tlsticket.Config    `fig:"session_tickets"`
 }
 
 // S2SConfig defines S2S subsystem configuration.
@@ -84,17 +116,54 @@ type ModulesConfig struct {
 	// Enabled specifies total set of enabled modules
 	Enabled []string `fig:"enabled"`
 
+	// Critical tells, by module name, whether a module start failure should abort server bootstrap.
+	// Otherwise, the failure is logged and the module is left disabled. Modules are critical by default.
+	Critical map[string]bool `fig:"critical"`
+
+	// IQTimeout specifies how long modules wait for a response to a server-originated IQ.
+	IQTimeout time.Duration `fig:"iq_timeout" default:"32s"`
+
+	// RSM specifies result set management (XEP-0059) paging defaults shared by all modules.
+	RSM rsm.Config `fig:"rsm"`
+
+	// Roster: roster management
+	Roster roster.Config `fig:"roster"`
+
+	// XEP-0030: Service Discovery
+	Disco xep0030.Config `fig:"disco"`
+
 	// Offline: offline storage
 	Offline offline.Config `fig:"offline"`
 
+	// Scheduled: scheduled stanza delivery
+	Scheduled scheduled.Config `fig:"scheduled"`
+
+	// XEP-0054: vcard-temp
+	VCard xep0054.Config `fig:"vcard"`
+
 	// XEP-0092: Software Version
 	Version xep0092.Config `fig:"version"`
 
+	// XEP-0115: Entity Capabilities
+	Caps xep0115.Config `fig:"caps"`
+
 	// XEP-0198: Stream Management
 	Stream xep0198.Config `fig:"stream"`
 
 	// XEP-0199: XMPP Ping
 	Ping xep0199.Config `fig:"ping"`
+
+	// XEP-0202: Entity Time
+	Time xep0202.Config `fig:"time"`
+
+	// XEP-0258: Security Labels in XMPP
+	SecLabel xep0258.Config `fig:"seclabel"`
+
+	// XEP-0280: Message Carbons
+	Carbons xep0280.Config `fig:"carbons"`
+
+	// XEP-0363: HTTP File Upload
+	Upload xep0363.Config `fig:"upload"`
 }
 
 // Config defines jackal application configuration.
@@ -103,6 +172,7 @@ type Config struct {
 
 	Logger  LoggerConfig  `fig:"logger"`
 	Cluster ClusterConfig `fig:"cluster"`
+	Drain   DrainConfig   `fig:"drain"`
 
 	HTTP HTTPConfig `fig:"http"`
 
@@ -111,6 +181,11 @@ type Config struct {
 	Storage storage.Config     `fig:"storage"`
 	Hosts   host.Configs       `fig:"hosts"`
 	Shapers []shaper.Config    `fig:"shapers"`
+	Hooks   hook.Config        `fig:"hooks"`
+
+	RoutingPolicy policy.Config `fig:"routing_policy"`
+
+	Preflight preflight.Config `fig:"preflight"`
 
 	C2S        C2SConfig        `fig:"c2s"`
 	S2S        S2SConfig        `fig:"s2s"`
@@ -23,24 +23,35 @@ import (
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type httpServer struct {
-	port   int
-	srv    *http.Server
-	logger kitlog.Logger
+	port     int
+	srv      *http.Server
+	handlers map[string]http.Handler
+	logger   kitlog.Logger
 }
 
 func newHTTPServer(port int, logger kitlog.Logger) *httpServer {
-	return &httpServer{port: port, logger: logger}
+	return &httpServer{port: port, handlers: make(map[string]http.Handler), logger: logger}
+}
+
+// handle registers an additional handler for the given pattern. It must be called before starting the server.
+func (h *httpServer) handle(pattern string, hnd http.Handler) {
+	h.handlers[pattern] = hnd
 }
 
 func (h *httpServer) Start(_ context.Context) error {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.HandlerFor(
-		prometheus.DefaultGatherer,
+		prometheus.Gatherers{prometheus.DefaultGatherer, module.MetricsGatherer()},
+		promhttp.HandlerOpts{EnableOpenMetrics: true},
+	))
+	mux.Handle("/metrics/modules", promhttp.HandlerFor(
+		module.MetricsGatherer(),
 		promhttp.HandlerOpts{EnableOpenMetrics: true},
 	))
 	mux.Handle("/debug/pprof/", http.HandlerFunc(pprof.Index))
@@ -51,6 +62,10 @@ func (h *httpServer) Start(_ context.Context) error {
 
 	mux.Handle("/healthz", http.HandlerFunc(h.healthCheck))
 
+	for pattern, hnd := range h.handlers {
+		mux.Handle(pattern, hnd)
+	}
+
 	h.srv = &http.Server{Handler: mux}
 	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", h.port))
 	if err != nil {
@@ -24,6 +24,8 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -44,15 +46,20 @@ import (
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/component/extcomponentmanager"
 	"github.com/ortuman/jackal/pkg/component/xep0114"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/log"
 	"github.com/ortuman/jackal/pkg/module"
+	"github.com/ortuman/jackal/pkg/preflight"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/policy"
 	"github.com/ortuman/jackal/pkg/s2s"
+	"github.com/ortuman/jackal/pkg/session"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/storage"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/tlsticket"
 	"github.com/ortuman/jackal/pkg/util/crashreporter"
 	"github.com/ortuman/jackal/pkg/version"
 )
@@ -61,6 +68,9 @@ const (
 	defaultBootstrapTimeout = time.Minute
 	defaultShutdownTimeout  = time.Second * 30
 
+	drainToggleTimeout = time.Second * 10
+	drainCheckInterval = time.Millisecond * 500
+
 	envConfigFile = "JACKAL_CONFIG_FILE"
 )
 
@@ -96,8 +106,9 @@ type startStopper interface {
 
 // Jackal is the root data structure for Jackal.
 type Jackal struct {
-	output io.Writer
-	args   []string
+	output     io.Writer
+	args       []string
+	configFile string
 
 	peppers *pepper.Keys
 	hk      *hook.Hooks
@@ -109,7 +120,9 @@ type Jackal struct {
 	rep repository.Repository
 
 	shapers        shaper.Shapers
+	geoIP          geoip.Provider
 	hosts          *host.Hosts
+	ticketKeys     *tlsticket.Manager
 	clusterConnMng *clusterconnmanager.Manager
 
 	localRouter    *c2s.LocalRouter
@@ -120,6 +133,12 @@ type Jackal struct {
 	comps          *component.Components
 	stmQueueMap    *streamqueue.QueueMap
 	extCompMng     *extcomponentmanager.Manager
+	c2sListeners   []*c2s.SocketListener
+	s2sListeners   []*s2s.SocketListener
+
+	drainCfg DrainConfig
+	drainMu  sync.RWMutex
+	draining bool
 
 	starters []starter
 	stoppers []stopper
@@ -137,6 +156,7 @@ func New(output io.Writer, args []string) *Jackal {
 		waitStopCh: make(chan os.Signal, 1),
 		kv:         kv.NewNop(),
 		memberList: memberlist.NewNop(),
+		geoIP:      geoip.NewNopProvider(),
 	}
 }
 
@@ -184,8 +204,11 @@ func (j *Jackal) Run() error {
 	if err != nil {
 		return err
 	}
+	j.configFile = configFile
+
 	// init logger
 	j.logger = log.NewDefaultLogger(cfg.Logger.Level, cfg.Logger.Format)
+	session.SetLogRedactor(session.NewRedactor(cfg.Logger.Redact.Elements, cfg.Logger.Redact.Namespaces))
 
 	level.Info(j.logger).Log("msg", "jackal is starting...",
 		"version", version.Version,
@@ -214,6 +237,10 @@ func (j *Jackal) Run() error {
 
 	// init hooks
 	j.hk = hook.NewHooks()
+	j.hk.SetErrorIsolation(cfg.Hooks.IsolateErrors)
+	for hk, limit := range cfg.Hooks.ConcurrencyLimits {
+		j.hk.SetConcurrencyLimit(hk, limit)
+	}
 
 	// init cluster
 	if err := j.initCluster(cfg.Cluster); err != nil {
@@ -232,7 +259,7 @@ func (j *Jackal) Run() error {
 		return err
 	}
 	j.initS2SOut(cfg.S2S.Out)
-	j.initRouters()
+	j.initRouters(cfg.S2S.Out, cfg.RoutingPolicy)
 
 	// init components & modules
 	j.initComponents()
@@ -242,6 +269,7 @@ func (j *Jackal) Run() error {
 	}
 
 	// init admin server
+	j.drainCfg = cfg.Drain
 	j.initAdminServer(cfg.Admin)
 
 	// init cluster server
@@ -249,13 +277,29 @@ func (j *Jackal) Run() error {
 		j.initClusterServer(cfg.Cluster.Server)
 	}
 
+	// init preflight checks (run once storage and cluster are up, before accepting any connection)
+	j.initPreflight(cfg)
+
+	// init HTTP server (stopped after listeners, so that held BOSH requests are released first)
+	httpSrv := newHTTPServer(cfg.HTTP.Port, j.logger)
+	j.registerStartStopper(httpSrv)
+
 	// init C2S/S2S listeners
+	j.initSessionTickets(cfg.C2S.SessionTickets)
+
 	if err := j.initListeners(cfg.C2S.Listeners, cfg.S2S.Listeners, cfg.Components.Listeners, cfg.Components.Secret); err != nil {
 		return err
 	}
-	// init HTTP server
-	j.registerStartStopper(newHTTPServer(cfg.HTTP.Port, j.logger))
-
+	for _, ln := range j.c2sListeners {
+		if path, hnd := ln.HTTPHandler(); hnd != nil {
+			httpSrv.handle(path, hnd) // BOSH listeners
+		}
+	}
+	for _, mod := range j.mods.AllModules() {
+		if hp, ok := mod.(module.HTTPHandlerProvider); ok {
+			httpSrv.handle(hp.HTTPHandler())
+		}
+	}
 	if err := j.bootstrap(); err != nil {
 		return err
 	}
@@ -276,14 +320,18 @@ func (j *Jackal) initCluster(cfg ClusterConfig) error {
 		fallthrough
 
 	case noneClusterType:
-		j.memberList = memberlist.NewKVMemberList(cfg.Server.Port, j.kv, j.hk, j.logger)
+		ml, err := memberlist.New(cfg.MemberList, cfg.Server.Port, j.kv, j.hk, j.logger)
+		if err != nil {
+			return err
+		}
+		j.memberList = ml
 		j.resMng = resourcemanager.NewKVManager(j.kv, j.hk, j.logger)
 
 	default:
 		return fmt.Errorf("unrecognized cluster type: %s", cfg.Type)
 	}
 	// init cluster connection manager
-	j.clusterConnMng = clusterconnmanager.NewManager(j.hk, j.logger)
+	j.clusterConnMng = clusterconnmanager.NewManager(cfg.Conns, j.hk, j.logger)
 
 	j.registerStartStopper(j.clusterConnMng)
 	j.registerStartStopper(j.resMng)
@@ -321,13 +369,22 @@ func (j *Jackal) initHosts(configs host.Configs) error {
 }
 
 func (j *Jackal) initShapers(configs []shaper.Config) error {
-	j.shapers = make(shaper.Shapers, 0)
+	shapers, err := j.newShapers(configs)
+	if err != nil {
+		return err
+	}
+	j.shapers = shapers
+	return nil
+}
+
+func (j *Jackal) newShapers(configs []shaper.Config) (shaper.Shapers, error) {
+	shapers := make(shaper.Shapers, 0)
 	for _, cfg := range configs {
 		shp, err := shaper.New(cfg)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		j.shapers = append(j.shapers, shp)
+		shapers = append(shapers, shp)
 
 		level.Info(j.logger).Log("msg", "registered shaper configuration",
 			"name", cfg.Name,
@@ -336,7 +393,7 @@ func (j *Jackal) initShapers(configs []shaper.Config) error {
 			"burst", cfg.Rate.Burst,
 		)
 	}
-	return nil
+	return shapers, nil
 }
 
 func (j *Jackal) initListeners(
@@ -353,15 +410,19 @@ func (j *Jackal) initListeners(
 		j.comps,
 		j.mods,
 		j.resMng,
+		j.kv,
 		j.rep,
 		j.peppers,
 		j.shapers,
+		j.geoIP,
+		j.ticketKeys,
 		j.hk,
 		j.logger,
 	)
 	for _, ln := range c2sListeners {
 		j.registerStartStopper(ln)
 	}
+	j.c2sListeners = c2sListeners
 
 	// s2s listeners
 	if len(s2sListenersCfg) > 0 {
@@ -384,6 +445,7 @@ func (j *Jackal) initListeners(
 		for _, ln := range s2sListeners {
 			j.registerStartStopper(ln)
 		}
+		j.s2sListeners = s2sListeners
 	}
 
 	// external component listeners
@@ -404,21 +466,34 @@ func (j *Jackal) initListeners(
 	return nil
 }
 
+func (j *Jackal) initSessionTickets(cfg tlsticket.Config) {
+	j.ticketKeys = tlsticket.NewManager(cfg, j.kv, j.logger)
+	j.registerStartStopper(j.ticketKeys)
+}
+
 func (j *Jackal) initS2SOut(cfg s2s.OutConfig) {
 	j.s2sOutProvider = s2s.NewOutProvider(cfg, j.hosts, j.kv, j.shapers, j.hk, j.logger)
 	j.registerStartStopper(j.s2sOutProvider)
 }
 
-func (j *Jackal) initRouters() {
+func (j *Jackal) initRouters(cfg s2s.OutConfig, policyCfg policy.Config) {
 	// init C2S router
 	j.localRouter = c2s.NewLocalRouter(j.hosts)
 	j.clusterRouter = clusterrouter.New(j.clusterConnMng)
 
 	c2sRouter := c2s.NewRouter(j.localRouter, j.clusterRouter, j.resMng, j.rep, j.hk, j.logger)
-	s2sRouter := s2s.NewRouter(j.s2sOutProvider)
+	s2sRouter := s2s.NewRouter(j.s2sOutProvider, cfg.HopLimit, j.logger)
 
+	// init routing policy
+	var routingPolicy router.Policy
+	if len(policyCfg.Address) > 0 {
+		policyCl := policy.NewClient(policyCfg, j.logger)
+		j.registerStartStopper(policyCl)
+
+		routingPolicy = policyCl
+	}
 	// init global router
-	j.router = router.New(j.hosts, c2sRouter, s2sRouter)
+	j.router = router.New(j.hosts, c2sRouter, s2sRouter, routingPolicy)
 	j.registerStartStopper(j.router)
 	return
 }
@@ -446,13 +521,13 @@ func (j *Jackal) initModules(cfg ModulesConfig) error {
 		}
 		mods = append(mods, fn(j, &cfg))
 	}
-	j.mods = module.NewModules(mods, j.hosts, j.router, j.hk, j.logger)
+	j.mods = module.NewModules(mods, cfg.Critical, j.hosts, j.router, j.hk, j.logger)
 	j.registerStartStopper(j.mods)
 	return nil
 }
 
 func (j *Jackal) initAdminServer(cfg adminserver.Config) {
-	adminSrv := adminserver.New(cfg, j.rep, j.peppers, j.hk, j.logger)
+	adminSrv := adminserver.New(cfg, j.rep, j.peppers, j.resMng, j.localRouter, j.memberList, j.stmQueueMap, j.mods, j, j.hk, j.logger)
 	j.registerStartStopper(adminSrv)
 }
 
@@ -462,6 +537,24 @@ func (j *Jackal) initClusterServer(cfg clusterserver.Config) {
 	return
 }
 
+func (j *Jackal) initPreflight(cfg *Config) {
+	checks := []preflight.Check{
+		preflight.StorageCheck(j.rep),
+	}
+	if cfg.Cluster.Type == kvClusterType {
+		checks = append(checks, preflight.KVCheck(j.kv))
+	}
+	for _, hCfg := range cfg.Hosts {
+		if len(hCfg.TLS.CertFile) > 0 {
+			checks = append(checks, preflight.CertificateCheck(hCfg.Domain, hCfg.TLS.CertFile, hCfg.TLS.PrivateKeyFile))
+		}
+		if !cfg.Preflight.SkipDNS {
+			checks = append(checks, preflight.DNSCheck(hCfg.Domain))
+		}
+	}
+	j.registerStartStopper(preflight.New(cfg.Preflight, checks, j.logger))
+}
+
 func (j *Jackal) registerStartStopper(ss startStopper) {
 	if ss == nil {
 		return
@@ -494,7 +587,74 @@ func (j *Jackal) bootstrap() error {
 	}
 }
 
+// SetDraining enables or disables instance draining mode. While draining, local member is marked
+// as such in the cluster memberlist and listeners reject new incoming connections.
+func (j *Jackal) SetDraining(ctx context.Context, draining bool) error {
+	j.drainMu.Lock()
+	defer j.drainMu.Unlock()
+
+	if j.draining == draining {
+		return nil
+	}
+	if err := j.memberList.SetDraining(ctx, draining); err != nil {
+		return err
+	}
+	for _, ln := range j.c2sListeners {
+		ln.SetDraining(draining)
+	}
+	for _, ln := range j.s2sListeners {
+		ln.SetDraining(draining)
+	}
+	j.draining = draining
+
+	level.Info(j.logger).Log("msg", "updated instance draining mode", "draining", draining)
+
+	_, err := j.hk.Run(ctx, hook.InstanceDrainingUpdated, &hook.ExecutionContext{
+		Info:   &hook.InstanceInfo{Draining: draining},
+		Sender: j,
+	})
+	return err
+}
+
+// IsDraining tells whether instance is being drained.
+func (j *Jackal) IsDraining() bool {
+	j.drainMu.RLock()
+	defer j.drainMu.RUnlock()
+	return j.draining
+}
+
+func (j *Jackal) waitForDrain() {
+	if j.stmQueueMap == nil {
+		return
+	}
+	level.Info(j.logger).Log("msg", "waiting for stream queues to be drained...", "timeout", j.drainCfg.Timeout)
+
+	tm := time.NewTimer(j.drainCfg.Timeout)
+	defer tm.Stop()
+
+	tc := time.NewTicker(drainCheckInterval)
+	defer tc.Stop()
+
+	for {
+		pending := pendingQueueElements(j.stmQueueMap)
+		if pending == 0 {
+			level.Info(j.logger).Log("msg", "stream queues drained")
+			return
+		}
+		select {
+		case <-tc.C:
+		case <-tm.C:
+			level.Warn(j.logger).Log("msg", "drain timeout expired", "pending_elements", pending)
+			return
+		}
+	}
+}
+
 func (j *Jackal) shutdown() error {
+	// if draining, give clients a chance to acknowledge pending stanzas
+	if j.IsDraining() {
+		j.waitForDrain()
+	}
 	// wait until shutdown has been completed
 	ctx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
 	defer cancel()
@@ -519,6 +679,60 @@ func (j *Jackal) shutdown() error {
 }
 
 func (j *Jackal) waitForStopSignal() os.Signal {
-	signal.Notify(j.waitStopCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
-	return <-j.waitStopCh
+	signal.Notify(j.waitStopCh, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		sig := <-j.waitStopCh
+		switch sig {
+		case syscall.SIGHUP:
+			level.Info(j.logger).Log("msg", "received reload signal... reloading configuration...")
+
+			if err := j.reload(); err != nil {
+				level.Warn(j.logger).Log("msg", "failed to reload configuration", "err", err)
+			}
+
+		case syscall.SIGUSR1:
+			draining := !j.IsDraining()
+			level.Info(j.logger).Log("msg", "received drain signal... toggling draining mode...", "draining", draining)
+
+			ctx, cancel := context.WithTimeout(context.Background(), drainToggleTimeout)
+			if err := j.SetDraining(ctx, draining); err != nil {
+				level.Warn(j.logger).Log("msg", "failed to toggle draining mode", "err", err)
+			}
+			cancel()
+
+		default:
+			return sig
+		}
+	}
+}
+
+func (j *Jackal) reload() error {
+	cfg, err := loadConfig(j.configFile)
+	if err != nil {
+		return err
+	}
+	shapers, err := j.newShapers(cfg.Shapers)
+	if err != nil {
+		return err
+	}
+	// update listener shaper bindings
+	for _, ln := range j.c2sListeners {
+		for _, lnCfg := range cfg.C2S.Listeners {
+			if lnCfg.BindAddr+":"+strconv.Itoa(lnCfg.Port) != ln.BindAddress() {
+				continue
+			}
+			ln.UpdateShaperBinding(lnCfg.Shaper, shapers)
+			break
+		}
+	}
+	return nil
+}
+
+func pendingQueueElements(stmQueueMap *streamqueue.QueueMap) int {
+	var count int
+	stmQueueMap.Range(func(_ string, sq *streamqueue.Queue) bool {
+		count += sq.Len()
+		return true
+	})
+	return count
 }
@@ -18,18 +18,23 @@ import (
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/module/offline"
 	"github.com/ortuman/jackal/pkg/module/roster"
+	"github.com/ortuman/jackal/pkg/module/scheduled"
 	"github.com/ortuman/jackal/pkg/module/xep0012"
 	"github.com/ortuman/jackal/pkg/module/xep0030"
 	"github.com/ortuman/jackal/pkg/module/xep0049"
 	"github.com/ortuman/jackal/pkg/module/xep0054"
 	"github.com/ortuman/jackal/pkg/module/xep0092"
 	"github.com/ortuman/jackal/pkg/module/xep0115"
+	"github.com/ortuman/jackal/pkg/module/xep0172"
 	"github.com/ortuman/jackal/pkg/module/xep0191"
 	"github.com/ortuman/jackal/pkg/module/xep0198"
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 	"github.com/ortuman/jackal/pkg/module/xep0199"
 	"github.com/ortuman/jackal/pkg/module/xep0202"
+	"github.com/ortuman/jackal/pkg/module/xep0258"
 	"github.com/ortuman/jackal/pkg/module/xep0280"
+	"github.com/ortuman/jackal/pkg/module/xep0352"
+	"github.com/ortuman/jackal/pkg/module/xep0363"
 )
 
 var defaultModules = []string{
@@ -45,19 +50,31 @@ var defaultModules = []string{
 	xep0198.ModuleName,
 	xep0199.ModuleName,
 	xep0280.ModuleName,
+	xep0352.ModuleName,
 }
 
 var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	// Roster
 	// (https://xmpp.org/rfcs/rfc6121.html#roster)
-	roster.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return roster.New(j.router, j.hosts, j.resMng, j.rep, j.hk, j.logger)
+	roster.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		rosterCfg := cfg.Roster
+		if rosterCfg.DefaultPageSize == 0 {
+			rosterCfg.DefaultPageSize = cfg.RSM.DefaultPageSize
+		}
+		if rosterCfg.MaxPageSize == 0 {
+			rosterCfg.MaxPageSize = cfg.RSM.MaxPageSize
+		}
+		return roster.New(rosterCfg, j.router, j.hosts, j.resMng, j.rep, j.hk, j.logger)
 	},
 	// Offline
 	// (https://xmpp.org/extensions/xep-0160.html)
 	offline.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
 		return offline.New(cfg.Offline, j.router, j.hosts, j.resMng, j.rep, j.hk, j.logger)
 	},
+	// Scheduled stanza delivery
+	scheduled.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return scheduled.New(cfg.Scheduled, j.router, j.rep, j.hk, j.logger)
+	},
 	// XEP-0012: Last Activity
 	// (https://xmpp.org/extensions/xep-0012.html)
 	xep0012.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
@@ -65,18 +82,25 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	},
 	// XEP-0030: Service Discovery
 	// (https://xmpp.org/extensions/xep-0030.html)
-	xep0030.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0030.New(j.router, j.comps, j.rep, j.resMng, j.hk, j.logger)
+	xep0030.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		discoCfg := cfg.Disco
+		if discoCfg.DefaultPageSize == 0 {
+			discoCfg.DefaultPageSize = cfg.RSM.DefaultPageSize
+		}
+		if discoCfg.MaxPageSize == 0 {
+			discoCfg.MaxPageSize = cfg.RSM.MaxPageSize
+		}
+		return xep0030.New(discoCfg, j.router, j.comps, j.rep, j.resMng, j.hk, j.logger)
 	},
 	// XEP-0049: Private XML Storage
 	// (https://xmpp.org/extensions/xep-0049.html)
 	xep0049.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0049.New(j.router, j.rep, j.hk, j.logger)
+		return xep0049.New(j.router, j.rep, j.hk, module.NewMetrics(xep0049.ModuleName), j.logger)
 	},
 	// XEP-0054: vcard-temp
 	// (https://xmpp.org/extensions/xep-0054.html)
-	xep0054.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0054.New(j.router, j.rep, j.hk, j.logger)
+	xep0054.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0054.New(cfg.VCard, j.router, j.rep, j.hk, j.logger)
 	},
 	// XEP-0092: Software Version
 	// (https://xmpp.org/extensions/xep-0092.html)
@@ -85,8 +109,13 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	},
 	// XEP-0115: Entity Capabilities
 	// (https://xmpp.org/extensions/xep-0115.html)
-	xep0115.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0115.New(j.router, j.rep, j.hk, j.logger)
+	xep0115.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0115.New(cfg.Caps, cfg.IQTimeout, j.router, j.resMng, j.rep, j.hk, j.logger)
+	},
+	// XEP-0172: User Nickname
+	// (https://xmpp.org/extensions/xep-0172.html)
+	xep0172.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
+		return xep0172.New(j.router, j.rep, j.hk, j.logger)
 	},
 	// XEP-0191: Blocking Command
 	// (https://xmpp.org/extensions/xep-0191.html)
@@ -97,21 +126,40 @@ var modFns = map[string]func(a *Jackal, cfg *ModulesConfig) module.Module{
 	// (https://xmpp.org/extensions/xep-0198.html)
 	xep0198.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
 		j.stmQueueMap = streamqueue.NewQueueMap()
-		return xep0198.New(cfg.Stream, j.stmQueueMap, j.clusterConnMng, j.router, j.hosts, j.resMng, j.hk, j.logger)
+		return xep0198.New(cfg.Stream, j.stmQueueMap, j.clusterConnMng, j.router, j.hosts, j.resMng, j.rep, j.shapers, j.hk, j.logger)
 	},
 	// XEP-0199: XMPP Ping
 	// (https://xmpp.org/extensions/xep-0199.html)
 	xep0199.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
-		return xep0199.New(cfg.Ping, j.router, j.hk, j.logger)
+		pingCfg := cfg.Ping
+		if pingCfg.AckTimeout == 0 {
+			pingCfg.AckTimeout = cfg.IQTimeout
+		}
+		return xep0199.New(pingCfg, j.router, j.hk, module.NewMetrics(xep0199.ModuleName), j.logger)
 	},
 	// XEP-0202: Entity Time
 	// (https://xmpp.org/extensions/xep-0202.html)
-	xep0202.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0202.New(j.router, j.logger)
+	xep0202.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0202.New(cfg.Time, j.router, j.logger)
+	},
+	// XEP-0258: Security Labels in XMPP
+	// (https://xmpp.org/extensions/xep-0258.html)
+	xep0258.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0258.New(cfg.SecLabel, j.router, j.hk, j.logger)
 	},
 	// XEP-0280: Message Carbons
 	// (https://xmpp.org/extensions/xep-0280.html)
-	xep0280.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
-		return xep0280.New(j.router, j.hosts, j.resMng, j.hk, j.logger)
+	xep0280.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0280.New(cfg.Carbons, j.router, j.hosts, j.resMng, j.rep, j.hk, j.logger)
+	},
+	// XEP-0352: Client State Indication
+	// (https://xmpp.org/extensions/xep-0352.html)
+	xep0352.ModuleName: func(j *Jackal, _ *ModulesConfig) module.Module {
+		return xep0352.New(j.hk, j.logger)
+	},
+	// XEP-0363: HTTP File Upload
+	// (https://xmpp.org/extensions/xep-0363.html)
+	xep0363.ModuleName: func(j *Jackal, cfg *ModulesConfig) module.Module {
+		return xep0363.New(cfg.Upload, j.router, j.hosts, j.logger)
 	},
 }
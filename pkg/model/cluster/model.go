@@ -15,7 +15,8 @@
 package clustermodel
 
 import (
-	"fmt"
+	"net"
+	"strconv"
 
 	"github.com/ortuman/jackal/pkg/version"
 )
@@ -26,9 +27,18 @@ type Member struct {
 	Host       string
 	Port       int
 	APIVer     *version.SemanticVersion
+
+	// Draining tells whether member is being drained, and thus shouldn't be given new work.
+	Draining bool
 }
 
 // String returns Member string representation.
 func (m *Member) String() string {
-	return fmt.Sprintf("%s:%d", m.Host, m.Port)
+	return net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+}
+
+// IsCompatible tells whether member cluster API version is compatible with the local one.
+// Members are compatible as long as they share the same major version.
+func (m *Member) IsCompatible() bool {
+	return m.APIVer.Major() == version.ClusterAPIVersion.Major()
 }
@@ -0,0 +1,27 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduledmodel
+
+import "github.com/golang/protobuf/proto"
+
+// MarshalBinary satisfies encoding.BinaryMarshaler interface.
+func (x *Stanza) MarshalBinary() (data []byte, err error) {
+	return proto.Marshal(x)
+}
+
+// UnmarshalBinary satisfies encoding.BinaryUnmarshaler interface.
+func (x *Stanza) UnmarshalBinary(data []byte) error {
+	return proto.Unmarshal(data, x)
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package module
+
+import (
+	"context"
+
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+)
+
+// Info describes the capabilities advertised by a module.
+type Info struct {
+	// Name is the module name.
+	Name string
+
+	// Started tells whether the module has been successfully started.
+	Started bool
+
+	// Namespaces contains the iq namespaces matched by the module.
+	Namespaces []string
+
+	// ServerFeatures contains the features advertised by the module on behalf of the server.
+	ServerFeatures []string
+
+	// AccountFeatures contains the features advertised by the module on behalf of every account.
+	AccountFeatures []string
+
+	// Identities contains the disco identities advertised by the module.
+	Identities []discomodel.Identity
+}
+
+// Inspect returns the capabilities advertised by mod.
+func Inspect(ctx context.Context, mod Module) (Info, error) {
+	srvFeatures, err := mod.ServerFeatures(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+	accFeatures, err := mod.AccountFeatures(ctx)
+	if err != nil {
+		return Info{}, err
+	}
+	inf := Info{
+		Name:            mod.Name(),
+		ServerFeatures:  srvFeatures,
+		AccountFeatures: accFeatures,
+	}
+	if nsProv, ok := mod.(NamespacesProvider); ok {
+		inf.Namespaces = nsProv.Namespaces()
+	}
+	if idProv, ok := mod.(IdentitiesProvider); ok {
+		inf.Identities = idProv.Identities()
+	}
+	return inf, nil
+}
+
+// Inspect returns the capabilities advertised by all configured modules, preserving their configuration order.
+func (m *Modules) Inspect(ctx context.Context) ([]Info, error) {
+	infos := make([]Info, 0, len(m.mods))
+	for _, mod := range m.mods {
+		inf, err := Inspect(ctx, mod)
+		if err != nil {
+			return nil, err
+		}
+		inf.Started = m.IsStarted(mod.Name())
+		infos = append(infos, inf)
+	}
+	return infos, nil
+}
@@ -27,7 +27,26 @@ type iqProcessor interface {
 	IQProcessor
 }
 
+//go:generate moq -out iq_matcher_module.mock_test.go . iqMatcherModule
+type iqMatcherModule interface {
+	IQProcessor
+	IQMatcher
+}
+
 //go:generate moq -out module.mock_test.go . module
 type module interface {
 	Module
 }
+
+//go:generate moq -out compactor_module.mock_test.go . compactorModule
+type compactorModule interface {
+	Module
+	StreamFeaturesCompactor
+}
+
+//go:generate moq -out introspectable_module.mock_test.go . introspectableModule
+type introspectableModule interface {
+	IQProcessor
+	NamespacesProvider
+	IdentitiesProvider
+}
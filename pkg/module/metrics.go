@@ -0,0 +1,72 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package module
+
+import (
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	moduleCounters = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "module",
+			Name:      "counter_total",
+			Help:      "The total number of module reported events.",
+		},
+		[]string{"instance", "module", "name"},
+	)
+)
+
+func init() {
+	metricsRegistry.MustRegister(moduleCounters)
+}
+
+// MetricsGatherer returns the gatherer containing all module level metrics.
+func MetricsGatherer() prometheus.Gatherer {
+	return metricsRegistry
+}
+
+// Metrics is a helper type used by modules to report its own counters.
+// All reported values are labeled with the owner module name.
+type Metrics struct {
+	moduleName string
+}
+
+// NewMetrics returns a new Metrics instance bound to moduleName.
+func NewMetrics(moduleName string) *Metrics {
+	return &Metrics{moduleName: moduleName}
+}
+
+// Counter returns the module labeled counter associated to name.
+func (m *Metrics) Counter(name string) prometheus.Counter {
+	return moduleCounters.With(prometheus.Labels{
+		"instance": instance.ID(),
+		"module":   m.moduleName,
+		"name":     name,
+	})
+}
+
+// IncCounter increments by one the module counter associated to name.
+// Calling IncCounter on a nil Metrics is a no-op.
+func (m *Metrics) IncCounter(name string) {
+	if m == nil {
+		return
+	}
+	m.Counter(name).Inc()
+}
@@ -0,0 +1,40 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package module
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics_IncCounter(t *testing.T) {
+	// given
+	m1 := NewMetrics("mod1")
+	m2 := NewMetrics("mod2")
+
+	// when
+	m1.IncCounter("processed")
+	m1.IncCounter("processed")
+	m2.IncCounter("processed")
+
+	var nilMetrics *Metrics
+	nilMetrics.IncCounter("processed")
+
+	// then
+	require.Equal(t, float64(2), testutil.ToFloat64(m1.Counter("processed")))
+	require.Equal(t, float64(1), testutil.ToFloat64(m2.Counter("processed")))
+}
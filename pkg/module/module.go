@@ -16,6 +16,8 @@ package module
 
 import (
 	"context"
+	"net/http"
+	"sync"
 
 	"github.com/go-kit/log/level"
 
@@ -25,6 +27,7 @@ import (
 	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	"github.com/ortuman/jackal/pkg/router"
 )
 
@@ -61,30 +64,83 @@ type IQProcessor interface {
 	ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error
 }
 
+// IQMatcher is implemented by iq processor modules that need to inspect the whole iq, and not only
+// its child namespace, to tell whether they should process it.
+type IQMatcher interface {
+	// MatchesIQ tells whether an iq matching module namespace should be processed by this module.
+	MatchesIQ(iq *stravaganza.IQ) bool
+}
+
+// StreamFeaturesCompactor is implemented by modules able to stand for the whole set of module stream features
+// with a compact representation, which clients can later resolve through server service discovery.
+type StreamFeaturesCompactor interface {
+	// CompactsStreamFeatures tells whether module stream features should be replaced by the compactor one.
+	CompactsStreamFeatures() bool
+}
+
+// NamespacesProvider is implemented by iq processor modules able to enumerate the iq namespaces they match,
+// so that they can be listed by module introspection.
+type NamespacesProvider interface {
+	// Namespaces returns all iq child namespaces matched by the module.
+	Namespaces() []string
+}
+
+// IdentitiesProvider is implemented by modules advertising disco identities on behalf of the server.
+type IdentitiesProvider interface {
+	// Identities returns all disco identities advertised by the module.
+	Identities() []discomodel.Identity
+}
+
+// HTTPHandlerProvider is implemented by modules serving HTTP requests through the server HTTP endpoint.
+type HTTPHandlerProvider interface {
+	// HTTPHandler returns the module HTTP handler along with the path pattern it should be served at.
+	HTTPHandler() (string, http.Handler)
+}
+
+// CompactsStreamFeatures tells whether any of mods replaces module stream features with a compact representation.
+func CompactsStreamFeatures(mods []Module) bool {
+	for _, mod := range mods {
+		if isCompactor(mod) {
+			return true
+		}
+	}
+	return false
+}
+
 // Modules is the global module hub.
 type Modules struct {
 	mods         []Module
+	critical     map[string]bool
 	iqProcessors []IQProcessor
 	hosts        hosts
 	router       router.Router
 	hk           *hook.Hooks
 	logger       kitlog.Logger
+
+	mu      sync.RWMutex
+	started map[string]bool
+	failed  map[Module]bool
 }
 
 // NewModules returns a new initialized Modules instance.
+//
+// critical tells, by module name, whether a module start failure should abort server bootstrap.
+// Otherwise, the failure is logged and the module is left disabled. Modules not present in the map are critical.
 func NewModules(
 	mods []Module,
+	critical map[string]bool,
 	hosts *host.Hosts,
 	router router.Router,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Modules {
 	m := &Modules{
-		mods:   mods,
-		hosts:  hosts,
-		router: router,
-		hk:     hk,
-		logger: logger,
+		mods:     mods,
+		critical: critical,
+		hosts:    hosts,
+		router:   router,
+		hk:       hk,
+		logger:   logger,
 	}
 	m.setupModules()
 	return m
@@ -95,9 +151,18 @@ func (m *Modules) Start(ctx context.Context) error {
 	// start modules
 	var modNames []string
 	for _, mod := range m.mods {
+		mark := m.hk.Mark()
 		if err := mod.Start(ctx); err != nil {
-			return err
+			if m.isCritical(mod) {
+				return err
+			}
+			// failed modules are never stopped... unregister any hook added before failing
+			m.hk.RemoveHooksSince(mark)
+			m.setFailed(mod)
+			level.Warn(m.logger).Log("msg", "failed to start non-critical module", "module", mod.Name(), "err", err)
+			continue
 		}
+		m.setStarted(mod.Name(), true)
 		modNames = append(modNames, mod.Name())
 	}
 	level.Info(m.logger).Log("msg", "started modules",
@@ -118,9 +183,13 @@ func (m *Modules) Stop(ctx context.Context) error {
 	// stop modules
 	var modNames []string
 	for _, mod := range m.mods {
+		if m.isFailed(mod) {
+			continue // never started
+		}
 		if err := mod.Stop(ctx); err != nil {
 			return err
 		}
+		m.setStarted(mod.Name(), false)
 		modNames = append(modNames, mod.Name())
 	}
 	level.Info(m.logger).Log("msg", "stopped modules",
@@ -147,7 +216,10 @@ func (m *Modules) IsModuleIQ(iq *stravaganza.IQ) bool {
 func (m *Modules) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	ns := iq.AllChildren()[0].Attribute(stravaganza.Namespace)
 	for _, iqHnd := range m.iqProcessors {
-		if !iqHnd.MatchesNamespace(ns, iq.ToJID().IsServer()) {
+		if !iqHnd.MatchesNamespace(ns, iq.ToJID().IsServer()) || m.isFailed(iqHnd) {
+			continue
+		}
+		if mt, ok := iqHnd.(IQMatcher); ok && !mt.MatchesIQ(iq) {
 			continue
 		}
 		return iqHnd.ProcessIQ(ctx, iq)
@@ -159,9 +231,21 @@ func (m *Modules) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 }
 
 // StreamFeatures returns stream features of all registered modules.
+// In case a module compacts stream features, only compactor stream features will be returned.
 func (m *Modules) StreamFeatures(ctx context.Context, domain string) ([]stravaganza.Element, error) {
-	var sfs []stravaganza.Element
+	var mods []Module
 	for _, mod := range m.mods {
+		if !m.isFailed(mod) {
+			mods = append(mods, mod)
+		}
+	}
+	compact := CompactsStreamFeatures(mods)
+
+	var sfs []stravaganza.Element
+	for _, mod := range mods {
+		if compact && !isCompactor(mod) {
+			continue // resolvable through server service discovery
+		}
 		sf, err := mod.StreamFeature(ctx, domain)
 		if err != nil {
 			return nil, err
@@ -188,6 +272,37 @@ func (m *Modules) AllModules() []Module {
 	return m.mods
 }
 
+// IsStarted tells whether a specific module has been successfully started.
+func (m *Modules) IsStarted(moduleName string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.started[moduleName]
+}
+
+func (m *Modules) setStarted(moduleName string, started bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.started == nil {
+		m.started = make(map[string]bool)
+	}
+	m.started[moduleName] = started
+}
+
+func (m *Modules) setFailed(mod Module) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failed == nil {
+		m.failed = make(map[Module]bool)
+	}
+	m.failed[mod] = true
+}
+
+func (m *Modules) isFailed(mod Module) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failed[mod]
+}
+
 func (m *Modules) setupModules() {
 	for _, mod := range m.mods {
 		iqPr, ok := mod.(IQProcessor)
@@ -196,3 +311,13 @@ func (m *Modules) setupModules() {
 		}
 	}
 }
+
+func (m *Modules) isCritical(mod Module) bool {
+	critical, ok := m.critical[mod.Name()]
+	return !ok || critical
+}
+
+func isCompactor(mod Module) bool {
+	c, ok := mod.(StreamFeaturesCompactor)
+	return ok && c.CompactsStreamFeatures()
+}
@@ -16,12 +16,14 @@ package module
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	kitlog "github.com/go-kit/log"
 
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/ortuman/jackal/pkg/hook"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	"github.com/stretchr/testify/require"
 )
 
@@ -48,6 +50,101 @@ func TestModules_StartStop(t *testing.T) {
 	require.Len(t, iqPrMock.StopCalls(), 1)
 }
 
+func TestModules_NonCriticalStartFailure(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.NameFunc = func() string { return "m0" }
+	modMock.StartFunc = func(ctx context.Context) error { return nil }
+	modMock.StopFunc = func(ctx context.Context) error { return nil }
+	modMock.StreamFeatureFunc = func(_ context.Context, _ string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("sm").Build(), nil
+	}
+	hk := hook.NewHooks()
+
+	var hookRun bool
+	nonCriticalMock := &moduleMock{}
+	nonCriticalMock.NameFunc = func() string { return "m1" }
+	nonCriticalMock.StartFunc = func(ctx context.Context) error {
+		hk.AddHook(hook.C2SStreamBinded, func(_ context.Context, _ *hook.ExecutionContext) error {
+			hookRun = true
+			return nil
+		}, hook.DefaultPriority)
+		return errors.New("integration unavailable")
+	}
+
+	var startedNames []string
+	hk.AddHook(hook.ModulesStarted, func(_ context.Context, execCtx *hook.ExecutionContext) error {
+		startedNames = execCtx.Info.(*hook.ModulesInfo).ModuleNames
+		return nil
+	}, hook.DefaultPriority)
+
+	mods := &Modules{
+		mods:     []Module{modMock, nonCriticalMock},
+		critical: map[string]bool{"m1": false},
+		hk:       hk,
+		logger:   kitlog.NewNopLogger(),
+	}
+
+	// when
+	startErr := mods.Start(context.Background())
+	m0Started, m1Started := mods.IsStarted("m0"), mods.IsStarted("m1")
+
+	_, _ = hk.Run(context.Background(), hook.C2SStreamBinded, &hook.ExecutionContext{})
+
+	sfs, _ := mods.StreamFeatures(context.Background(), "jackal.im")
+	stopErr := mods.Stop(context.Background())
+
+	// then
+	require.NoError(t, startErr)
+	require.NoError(t, stopErr)
+
+	require.True(t, m0Started)
+	require.False(t, m1Started)
+	require.Equal(t, []string{"m0"}, startedNames)
+
+	require.Len(t, sfs, 1)
+	require.Len(t, nonCriticalMock.StreamFeatureCalls(), 0)
+	require.Len(t, nonCriticalMock.StopCalls(), 0)
+
+	require.False(t, hookRun)
+}
+
+func TestModules_CriticalStartFailure(t *testing.T) {
+	// given
+	criticalMock := &moduleMock{}
+	criticalMock.NameFunc = func() string { return "m0" }
+	criticalMock.StartFunc = func(ctx context.Context) error { return errors.New("storage unavailable") }
+
+	modMock := &moduleMock{}
+	modMock.NameFunc = func() string { return "m1" }
+	modMock.StartFunc = func(ctx context.Context) error { return errors.New("failed") }
+
+	var tests = []struct {
+		name string
+		mod  Module
+	}{
+		{name: "DeclaredCritical", mod: criticalMock},
+		{name: "Undeclared", mod: modMock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mods := &Modules{
+				mods:     []Module{tt.mod},
+				critical: map[string]bool{"m0": true},
+				hk:       hook.NewHooks(),
+				logger:   kitlog.NewNopLogger(),
+			}
+
+			// when
+			err := mods.Start(context.Background())
+
+			// then
+			require.Error(t, err)
+			require.False(t, mods.IsStarted(tt.mod.Name()))
+		})
+	}
+}
+
 func TestModules_ProcessIQ(t *testing.T) {
 	// given
 	iqPrMock := &iqProcessorMock{}
@@ -93,3 +190,126 @@ func TestModules_ProcessIQ(t *testing.T) {
 	require.Len(t, iqPrMock.MatchesNamespaceCalls(), 1)
 	require.Len(t, iqPrMock.ProcessIQCalls(), 1)
 }
+
+func TestModules_ProcessIQMatcher(t *testing.T) {
+	// given
+	iqMatcherMock := &iqMatcherModuleMock{}
+	iqMatcherMock.MatchesNamespaceFunc = func(namespace string, _ bool) bool {
+		return namespace == "http://jabber.org/protocol/pubsub"
+	}
+	iqMatcherMock.MatchesIQFunc = func(_ *stravaganza.IQ) bool { return false }
+
+	iqPrMock := &iqProcessorMock{}
+	iqPrMock.MatchesNamespaceFunc = func(namespace string, _ bool) bool {
+		return namespace == "http://jabber.org/protocol/pubsub"
+	}
+	iqPrMock.ProcessIQFunc = func(ctx context.Context, iq *stravaganza.IQ) error {
+		return nil
+	}
+	mods := &Modules{
+		iqProcessors: []IQProcessor{iqMatcherMock, iqPrMock},
+		hk:           hook.NewHooks(),
+		logger:       kitlog.NewNopLogger(),
+	}
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "iq0001").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/res0001").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, "http://jabber.org/protocol/pubsub").
+				Build(),
+		).
+		BuildIQ()
+
+	_ = mods.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, iqMatcherMock.MatchesIQCalls(), 1)
+	require.Len(t, iqMatcherMock.ProcessIQCalls(), 0)
+	require.Len(t, iqPrMock.ProcessIQCalls(), 1)
+}
+
+func TestModules_CompactStreamFeatures(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.StreamFeatureFunc = func(_ context.Context, _ string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("sm").
+			WithAttribute(stravaganza.Namespace, "urn:xmpp:sm:3").
+			Build(), nil
+	}
+	compactorMock := &compactorModuleMock{}
+	compactorMock.StreamFeatureFunc = func(_ context.Context, _ string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("c").
+			WithAttribute(stravaganza.Namespace, "http://jabber.org/protocol/caps").
+			Build(), nil
+	}
+
+	mods := &Modules{
+		mods:   []Module{modMock, compactorMock},
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	compactorMock.CompactsStreamFeaturesFunc = func() bool { return false }
+	fullSFs, _ := mods.StreamFeatures(context.Background(), "jackal.im")
+
+	compactorMock.CompactsStreamFeaturesFunc = func() bool { return true }
+	compactSFs, _ := mods.StreamFeatures(context.Background(), "jackal.im")
+
+	// then
+	require.Len(t, fullSFs, 2)
+
+	require.Len(t, compactSFs, 1)
+	require.Equal(t, "c", compactSFs[0].Name())
+	require.Len(t, modMock.StreamFeatureCalls(), 1)
+}
+
+func TestModules_Inspect(t *testing.T) {
+	// given
+	intMock := &introspectableModuleMock{}
+	intMock.NameFunc = func() string { return "m0" }
+	intMock.ServerFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"jabber:iq:version"}, nil
+	}
+	intMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) { return nil, nil }
+	intMock.NamespacesFunc = func() []string { return []string{"jabber:iq:version"} }
+	intMock.IdentitiesFunc = func() []discomodel.Identity {
+		return []discomodel.Identity{{Category: "server", Type: "im"}}
+	}
+
+	modMock := &moduleMock{}
+	modMock.NameFunc = func() string { return "m1" }
+	modMock.ServerFeaturesFunc = func(_ context.Context) ([]string, error) { return nil, nil }
+	modMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"urn:xmpp:ping"}, nil
+	}
+
+	mods := &Modules{
+		mods: []Module{intMock, modMock},
+	}
+	mods.setStarted("m0", true)
+
+	// when
+	infos, err := mods.Inspect(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+
+	require.Equal(t, Info{
+		Name:           "m0",
+		Started:        true,
+		Namespaces:     []string{"jabber:iq:version"},
+		ServerFeatures: []string{"jabber:iq:version"},
+		Identities:     []discomodel.Identity{{Category: "server", Type: "im"}},
+	}, infos[0])
+	require.Equal(t, Info{
+		Name:            "m1",
+		AccountFeatures: []string{"urn:xmpp:ping"},
+	}, infos[1])
+}
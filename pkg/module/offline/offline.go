@@ -17,6 +17,7 @@ package offline
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	kitlog "github.com/go-kit/log"
@@ -28,6 +29,8 @@ import (
 	"github.com/ortuman/jackal/pkg/host"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/util/clock"
+	"github.com/ortuman/jackal/pkg/util/dedupe"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -35,6 +38,8 @@ const (
 	offlineFeature = "msgoffline"
 
 	hintsNamespace = "urn:xmpp:hints"
+
+	maxDedupeKeys = 16384
 )
 
 // ModuleName represents offline module name.
@@ -44,6 +49,17 @@ const ModuleName = "offline"
 type Config struct {
 	// QueueSize defines maximum offline queue size.
 	QueueSize int `fig:"queue_size" default:"200"`
+
+	// DedupeWindow, if set, defines the time window during which messages carrying the same
+	// XEP-0359 origin-id (or stanza-id) will be stored only once.
+	DedupeWindow time.Duration `fig:"dedupe_window"`
+
+	// DeliveryBatchSize defines the maximum number of offline messages delivered at once
+	// when a user becomes available. Zero value delivers the whole queue at once.
+	DeliveryBatchSize int `fig:"delivery_batch_size"`
+
+	// DeliveryInterval defines the amount of time waited between consecutive delivery batches.
+	DeliveryInterval time.Duration `fig:"delivery_interval" default:"1s"`
 }
 
 // Offline represents offline module type.
@@ -53,8 +69,18 @@ type Offline struct {
 	router router.Router
 	resMng resourcemanager.Manager
 	rep    repository.Repository
+	dd     *dedupe.Cache
 	hk     *hook.Hooks
 	logger kitlog.Logger
+
+	mu         sync.Mutex
+	deliveries map[string]*offlineDelivery
+}
+
+// offlineDelivery holds the offline messages of a user pending to be delivered in upcoming batches.
+type offlineDelivery struct {
+	tm *time.Timer
+	ms []*stravaganza.Message
 }
 
 // New creates and initializes a new Offline instance.
@@ -67,14 +93,20 @@ func New(
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Offline {
+	var dd *dedupe.Cache
+	if cfg.DedupeWindow > 0 {
+		dd = dedupe.New(cfg.DedupeWindow, maxDedupeKeys)
+	}
 	return &Offline{
-		cfg:    cfg,
-		router: router,
-		hosts:  hosts,
-		resMng: resMng,
-		rep:    rep,
-		hk:     hk,
-		logger: kitlog.With(logger, "module", ModuleName),
+		cfg:        cfg,
+		router:     router,
+		hosts:      hosts,
+		resMng:     resMng,
+		rep:        rep,
+		dd:         dd,
+		hk:         hk,
+		logger:     kitlog.With(logger, "module", ModuleName),
+		deliveries: make(map[string]*offlineDelivery),
 	}
 }
 
@@ -98,6 +130,7 @@ func (m *Offline) AccountFeatures(_ context.Context) ([]string, error) { return
 func (m *Offline) Start(_ context.Context) error {
 	m.hk.AddHook(hook.C2SStreamWillRouteElement, m.onWillRouteElement, hook.LowestPriority)
 	m.hk.AddHook(hook.S2SInStreamWillRouteElement, m.onWillRouteElement, hook.LowestPriority)
+	m.hk.AddHook(hook.C2SRouterMessageUndelivered, m.onMessageUndelivered, hook.LowestPriority)
 
 	m.hk.AddHook(hook.C2SStreamPresenceReceived, m.onC2SPresenceRecv, hook.DefaultPriority)
 	m.hk.AddHook(hook.UserDeleted, m.onUserDeleted, hook.DefaultPriority)
@@ -107,13 +140,29 @@ func (m *Offline) Start(_ context.Context) error {
 }
 
 // Stop stops offline module.
-func (m *Offline) Stop(_ context.Context) error {
+func (m *Offline) Stop(ctx context.Context) error {
 	m.hk.RemoveHook(hook.C2SStreamWillRouteElement, m.onWillRouteElement)
 	m.hk.RemoveHook(hook.S2SInStreamWillRouteElement, m.onWillRouteElement)
+	m.hk.RemoveHook(hook.C2SRouterMessageUndelivered, m.onMessageUndelivered)
 
 	m.hk.RemoveHook(hook.C2SStreamPresenceReceived, m.onC2SPresenceRecv)
 	m.hk.RemoveHook(hook.UserDeleted, m.onUserDeleted)
 
+	// cancel pending delivery batches, keeping undelivered messages queued
+	m.mu.Lock()
+	deliveries := m.deliveries
+	m.deliveries = make(map[string]*offlineDelivery)
+	m.mu.Unlock()
+
+	for username, d := range deliveries {
+		if !d.tm.Stop() {
+			continue // batch already being delivered
+		}
+		if err := m.requeueOfflineMessages(ctx, username, d.ms); err != nil {
+			level.Warn(m.logger).Log("msg", "failed to requeue offline messages", "username", username, "err", err)
+		}
+	}
+
 	level.Info(m.logger).Log("msg", "stopped offline module")
 	return nil
 }
@@ -145,6 +194,19 @@ func (m *Offline) onWillRouteElement(ctx context.Context, execCtx *hook.Executio
 	return m.archiveMessage(ctx, msg)
 }
 
+func (m *Offline) onMessageUndelivered(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	msg, ok := inf.Element.(*stravaganza.Message)
+	if !ok || !isMessageArchievable(msg) {
+		return nil
+	}
+	if !m.hosts.IsLocalHost(msg.ToJID().Domain()) {
+		return nil
+	}
+	return m.archiveMessage(ctx, msg)
+}
+
 func (m *Offline) onC2SPresenceRecv(ctx context.Context, execCtx *hook.ExecutionContext) error {
 	inf := execCtx.Info.(*hook.C2SStreamInfo)
 
@@ -173,30 +235,130 @@ func (m *Offline) onUserDeleted(ctx context.Context, execCtx *hook.ExecutionCont
 }
 
 func (m *Offline) deliverOfflineMessages(ctx context.Context, username string) error {
+	m.mu.Lock()
+	_, delivering := m.deliveries[username]
+	m.mu.Unlock()
+	if delivering {
+		return nil // already being delivered in batches
+	}
+	ms, err := m.takeOfflineMessages(ctx, username)
+	if err != nil {
+		return err
+	}
+	m.deliverOfflineBatch(ctx, username, ms)
+	return nil
+}
+
+// takeOfflineMessages fetches and clears user offline queue at once.
+func (m *Offline) takeOfflineMessages(ctx context.Context, username string) ([]*stravaganza.Message, error) {
 	lockID := offlineQueueLockID(username)
 
 	if err := m.rep.Lock(ctx, lockID); err != nil {
-		return err
+		return nil, err
 	}
 	defer func() { _ = m.rep.Unlock(ctx, lockID) }()
 
 	ms, err := m.rep.FetchOfflineMessages(ctx, username)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(ms) == 0 {
 		// empty queue... we're done here
-		return nil
+		return nil, nil
 	}
 	if err := m.rep.DeleteOfflineMessages(ctx, username); err != nil {
-		return err
+		return nil, err
+	}
+	return ms, nil
+}
+
+// deliverOfflineBatch routes the first batch of ms, scheduling the delivery of the remaining ones.
+func (m *Offline) deliverOfflineBatch(ctx context.Context, username string, ms []*stravaganza.Message) {
+	if len(ms) == 0 {
+		return
+	}
+	batch, rest := ms, []*stravaganza.Message(nil)
+	if bs := m.cfg.DeliveryBatchSize; bs > 0 && len(ms) > bs {
+		batch, rest = ms[:bs], ms[bs:]
 	}
 	// route offline messages
-	for _, msg := range ms {
+	for _, msg := range batch {
 		_, _ = m.router.Route(ctx, msg)
 	}
-	level.Info(m.logger).Log("msg", "delivered offline messages", "queue_size", len(ms), "username", username)
+	level.Info(m.logger).Log("msg", "delivered offline messages", "batch_size", len(batch), "pending", len(rest), "username", username)
+
+	if len(rest) > 0 {
+		m.scheduleDelivery(username, rest)
+	}
+}
+
+func (m *Offline) scheduleDelivery(username string, ms []*stravaganza.Message) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := &offlineDelivery{ms: ms}
+	d.tm = time.AfterFunc(m.cfg.DeliveryInterval, func() {
+		m.mu.Lock()
+		if m.deliveries[username] != d {
+			m.mu.Unlock()
+			return // delivery cancelled
+		}
+		delete(m.deliveries, username)
+		m.mu.Unlock()
+
+		if err := m.deliverPending(context.Background(), username, d.ms); err != nil {
+			level.Warn(m.logger).Log("msg", "failed to deliver offline messages", "username", username, "err", err)
+		}
+	})
+	m.deliveries[username] = d
+}
 
+func (m *Offline) deliverPending(ctx context.Context, username string, ms []*stravaganza.Message) error {
+	// stop delivering in case the user is not available anymore
+	rss, err := m.resMng.GetResources(ctx, username)
+	if err != nil {
+		_ = m.requeueOfflineMessages(ctx, username, ms)
+		return err
+	}
+	var isAvailable bool
+	for _, res := range rss {
+		if res.IsAvailable() && res.Priority() >= 0 {
+			isAvailable = true
+			break
+		}
+	}
+	if !isAvailable {
+		return m.requeueOfflineMessages(ctx, username, ms)
+	}
+	m.deliverOfflineBatch(ctx, username, ms)
+	return nil
+}
+
+// requeueOfflineMessages puts back undelivered messages ahead of those queued in the meantime.
+func (m *Offline) requeueOfflineMessages(ctx context.Context, username string, ms []*stravaganza.Message) error {
+	lockID := offlineQueueLockID(username)
+
+	if err := m.rep.Lock(ctx, lockID); err != nil {
+		return err
+	}
+	defer func() { _ = m.rep.Unlock(ctx, lockID) }()
+
+	queued, err := m.rep.FetchOfflineMessages(ctx, username)
+	if err != nil {
+		return err
+	}
+	if len(queued) > 0 {
+		if err := m.rep.DeleteOfflineMessages(ctx, username); err != nil {
+			return err
+		}
+	}
+	for _, msgs := range [][]*stravaganza.Message{ms, queued} {
+		for _, msg := range msgs {
+			if err := m.rep.InsertOfflineMessage(ctx, msg, username); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -211,19 +373,32 @@ func (m *Offline) archiveMessage(ctx context.Context, msg *stravaganza.Message)
 	}
 	defer func() { _ = m.rep.Unlock(ctx, lockID) }()
 
+	// discard already stored message retransmissions
+	var dedupeKey string
+	if m.dd != nil {
+		k, ok := dedupe.StanzaKey(msg)
+		if ok && m.dd.Seen(k) {
+			level.Info(m.logger).Log("msg", "discarded duplicated offline message", "id", msg.Attribute(stravaganza.ID), "username", username)
+			return hook.ErrStopped // already handled
+		}
+		dedupeKey = k
+	}
 	qSize, err := m.rep.CountOfflineMessages(ctx, username)
 	if err != nil {
+		m.forgetDedupeKey(dedupeKey)
 		return err
 	}
 	if qSize == m.cfg.QueueSize { // offline queue is full
+		m.forgetDedupeKey(dedupeKey)
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(msg, stanzaerror.ServiceUnavailable))
 		return hook.ErrStopped // already handled
 	}
 	// add delay info
-	dMsg := xmpputil.MakeDelayMessage(msg, time.Now(), toJID.Domain(), "Offline Storage")
+	dMsg := xmpputil.MakeDelayMessage(msg, clock.Now(), toJID.Domain(), "Offline Storage")
 
 	// enqueue offline message
 	if err := m.rep.InsertOfflineMessage(ctx, dMsg, username); err != nil {
+		m.forgetDedupeKey(dedupeKey)
 		return err
 	}
 	_, err = m.hk.Run(ctx, hook.OfflineMessageArchived, &hook.ExecutionContext{
@@ -241,6 +416,13 @@ func (m *Offline) archiveMessage(ctx context.Context, msg *stravaganza.Message)
 	return hook.ErrStopped // already handled
 }
 
+func (m *Offline) forgetDedupeKey(k string) {
+	if m.dd == nil || len(k) == 0 {
+		return
+	}
+	m.dd.Forget(k)
+}
+
 func isMessageArchievable(msg *stravaganza.Message) bool {
 	if msg.ChildNamespace("no-store", hintsNamespace) != nil {
 		return false
@@ -17,13 +17,17 @@ package offline
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/ortuman/jackal/pkg/util/dedupe"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 	"github.com/stretchr/testify/require"
 )
@@ -81,6 +85,122 @@ func TestOffline_ArchiveOfflineMessage(t *testing.T) {
 	require.Len(t, repMock.InsertOfflineMessageCalls(), 1)
 }
 
+func TestOffline_ArchiveUndeliveredMessage(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	repMock.CountOfflineMessagesFunc = func(ctx context.Context, username string) (int, error) {
+		return 0, nil
+	}
+	repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+		return nil
+	}
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100},
+		hosts:  hostsMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SRouterMessageUndelivered, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: msg,
+		},
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted)
+	require.Len(t, repMock.InsertOfflineMessageCalls(), 1)
+	require.Equal(t, "ortuman", repMock.InsertOfflineMessageCalls()[0].Username)
+}
+
+func TestOffline_ArchiveOfflineMessageDedupe(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	repMock.CountOfflineMessagesFunc = func(ctx context.Context, username string) (int, error) {
+		return 0, nil
+	}
+	repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+		return nil
+	}
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	resManagerMock := &resourceManagerMock{}
+	resManagerMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:    Config{QueueSize: 100, DedupeWindow: time.Minute},
+		hosts:  hostsMock,
+		resMng: resManagerMock,
+		rep:    repMock,
+		dd:     dedupe.New(time.Minute, maxDedupeKeys),
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	buildMessage := func(originID string) *stravaganza.Message {
+		b := stravaganza.NewMessageBuilder()
+		b.WithAttribute("from", "noelia@jackal.im/yard")
+		b.WithAttribute("to", "ortuman@jackal.im/balcony")
+		b.WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("I'll give thee a wind.").
+				Build(),
+		)
+		b.WithChild(
+			stravaganza.NewBuilder("origin-id").
+				WithAttribute(stravaganza.Namespace, "urn:xmpp:sid:0").
+				WithAttribute("id", originID).
+				Build(),
+		)
+		msg, _ := b.BuildMessage()
+		return msg
+	}
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	for _, originID := range []string{"de305d54", "de305d54", "5f3dbc5e"} {
+		halted, err := hk.Run(context.Background(), hook.C2SStreamWillRouteElement, &hook.ExecutionContext{
+			Info: &hook.C2SStreamInfo{
+				Element: buildMessage(originID),
+			},
+		})
+		require.True(t, halted)
+		require.Nil(t, err)
+	}
+
+	// then
+	require.Len(t, repMock.InsertOfflineMessageCalls(), 2)
+}
+
 func TestOffline_ArchiveOfflineMessageQueueFull(t *testing.T) {
 	// given
 	routerMock := &routerMock{}
@@ -214,3 +334,184 @@ func TestOffline_DeliverOfflineMessages(t *testing.T) {
 
 	require.Equal(t, `<message from='noelia@jackal.im/yard' to='ortuman@jackal.im/balcony'><body>I&#39;ll give thee a wind.</body></message>`, output.String())
 }
+
+func TestOffline_DeliverOfflineMessagesInBatches(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		available   bool
+		expectedIDs []string
+	}{
+		{name: "available", available: true, expectedIDs: []string{"m0", "m1", "m2", "m3", "m4"}},
+		{name: "unavailable", available: false, expectedIDs: []string{"m0", "m1"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			var mu sync.Mutex
+			var routedIDs []string
+
+			routerMock := &routerMock{}
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				mu.Lock()
+				routedIDs = append(routedIDs, stanza.Attribute(stravaganza.ID))
+				mu.Unlock()
+				return nil, nil
+			}
+			hostsMock := &hostsMock{}
+			hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+			var queue []*stravaganza.Message
+			for i := 0; i < 5; i++ {
+				msg, _ := stravaganza.NewMessageBuilder().
+					WithAttribute(stravaganza.ID, fmt.Sprintf("m%d", i)).
+					WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+					WithAttribute(stravaganza.To, "ortuman@jackal.im").
+					BuildMessage()
+				queue = append(queue, msg)
+			}
+			repMock := &repositoryMock{}
+			repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+			repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+			repMock.FetchOfflineMessagesFunc = func(ctx context.Context, username string) ([]*stravaganza.Message, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				return queue, nil
+			}
+			repMock.DeleteOfflineMessagesFunc = func(ctx context.Context, username string) error {
+				mu.Lock()
+				queue = nil
+				mu.Unlock()
+				return nil
+			}
+			repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+				mu.Lock()
+				queue = append(queue, message)
+				mu.Unlock()
+				return nil
+			}
+			fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+			toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+			pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.AvailableType, nil)
+
+			resMngMock := &resourceManagerMock{}
+			resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+				if !tc.available {
+					return nil, nil
+				}
+				return []c2smodel.ResourceDesc{
+					c2smodel.NewResourceDesc("inst-1", fromJID, pr, c2smodel.NewInfoMap()),
+				}, nil
+			}
+
+			hk := hook.NewHooks()
+			m := &Offline{
+				cfg:        Config{QueueSize: 100, DeliveryBatchSize: 2, DeliveryInterval: time.Millisecond * 10},
+				router:     routerMock,
+				hosts:      hostsMock,
+				resMng:     resMngMock,
+				rep:        repMock,
+				hk:         hk,
+				logger:     kitlog.NewNopLogger(),
+				deliveries: make(map[string]*offlineDelivery),
+			}
+
+			// when
+			_ = m.Start(context.Background())
+			defer func() { _ = m.Stop(context.Background()) }()
+
+			_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+				Info: &hook.C2SStreamInfo{
+					Element: pr,
+				},
+			})
+
+			// then
+			mu.Lock()
+			require.Equal(t, []string{"m0", "m1"}, routedIDs) // first batch
+			mu.Unlock()
+
+			require.Eventually(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return len(routedIDs) == len(tc.expectedIDs)
+			}, time.Second, time.Millisecond*10)
+
+			require.Eventually(t, func() bool {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				return len(m.deliveries) == 0
+			}, time.Second, time.Millisecond*10)
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Equal(t, tc.expectedIDs, routedIDs)
+			require.Len(t, queue, 5-len(tc.expectedIDs))
+			require.Len(t, repMock.InsertOfflineMessageCalls(), 5-len(tc.expectedIDs)) // undelivered messages requeued once
+		})
+	}
+}
+
+func TestOffline_StopRequeuesPendingBatches(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, nil
+	}
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	var queue []*stravaganza.Message
+	for i := 0; i < 3; i++ {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.ID, fmt.Sprintf("m%d", i)).
+			WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			BuildMessage()
+		queue = append(queue, msg)
+	}
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.FetchOfflineMessagesFunc = func(ctx context.Context, username string) ([]*stravaganza.Message, error) {
+		return queue, nil
+	}
+	repMock.DeleteOfflineMessagesFunc = func(ctx context.Context, username string) error {
+		queue = nil
+		return nil
+	}
+	repMock.InsertOfflineMessageFunc = func(ctx context.Context, message *stravaganza.Message, username string) error {
+		queue = append(queue, message)
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &Offline{
+		cfg:        Config{QueueSize: 100, DeliveryBatchSize: 1, DeliveryInterval: time.Hour},
+		router:     routerMock,
+		hosts:      hostsMock,
+		rep:        repMock,
+		hk:         hk,
+		logger:     kitlog.NewNopLogger(),
+		deliveries: make(map[string]*offlineDelivery),
+	}
+	fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.AvailableType, nil)
+
+	// when
+	_ = m.Start(context.Background())
+
+	_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: pr,
+		},
+	})
+	_ = m.Stop(context.Background())
+
+	// then
+	require.Len(t, routerMock.RouteCalls(), 1)
+
+	require.Len(t, queue, 2)
+	require.Equal(t, "m1", queue[0].Attribute(stravaganza.ID))
+	require.Equal(t, "m2", queue[1].Attribute(stravaganza.ID))
+}
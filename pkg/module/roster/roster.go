@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -33,6 +34,7 @@ import (
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -41,6 +43,12 @@ const (
 	rosterDidGoAvailableCtxKey = "ros:available"
 
 	rosterNamespace = "jabber:iq:roster"
+
+	defaultMaxPageSize = 100
+
+	// maxUpdateAttempts is the number of times a roster item update is applied before reporting a conflict,
+	// as long as the item keeps being concurrently modified.
+	maxUpdateAttempts = 3
 )
 
 const (
@@ -48,18 +56,54 @@ const (
 	ModuleName = "roster"
 )
 
+// Config contains roster module configuration options.
+type Config struct {
+	// PresenceVisibleToStrangers contains the set of local hosts whose users presence is visible
+	// to non-contacts by default. By default presence is only shared with subscribed contacts.
+	PresenceVisibleToStrangers []string `fig:"presence_visible_to_strangers"`
+
+	// DefaultPageSize defines the number of items returned per page when a roster is requested
+	// using result set management (XEP-0059) without specifying a maximum.
+	// If not set, the modules shared RSM default page size is used.
+	DefaultPageSize int `fig:"default_page_size"`
+
+	// MaxPageSize defines the maximum number of items returned per page
+	// when a roster is requested using result set management (XEP-0059).
+	// If not set, the modules shared RSM max page size is used.
+	MaxPageSize int `fig:"max_page_size"`
+
+	// SubscribeRate defines the rate limit applied to outbound subscription requests of every local account.
+	SubscribeRate SubscribeRateConfig `fig:"subscribe_rate"`
+}
+
+// SubscribeRateConfig contains outbound subscription request rate limiting options.
+type SubscribeRateConfig struct {
+	// Limit defines the number of subscription requests allowed per second. Zero disables rate limiting.
+	Limit float64 `fig:"limit"`
+
+	// Burst defines the maximum number of subscription requests allowed at once.
+	Burst int `fig:"burst" default:"10"`
+
+	// MaxDelay defines how long an excessive subscription request can be throttled before
+	// being rejected with a policy-violation error. Zero rejects excessive requests right away.
+	MaxDelay time.Duration `fig:"max_delay"`
+}
+
 // Roster represents a roster module type.
 type Roster struct {
+	cfg    Config
 	rep    repository.Repository
 	resMng resourcemanager.Manager
 	router router.Router
 	hosts  hosts
 	hk     *hook.Hooks
 	logger kitlog.Logger
+	subLim *subscribeLimiter
 }
 
 // New returns a new initialized Roster instance.
 func New(
+	cfg Config,
 	router router.Router,
 	hosts *host.Hosts,
 	resMng resourcemanager.Manager,
@@ -68,12 +112,14 @@ func New(
 	logger kitlog.Logger,
 ) *Roster {
 	return &Roster{
+		cfg:    cfg,
 		router: router,
 		rep:    rep,
 		resMng: resMng,
 		hosts:  hosts,
 		hk:     hk,
 		logger: kitlog.With(logger, "module", ModuleName),
+		subLim: newSubscribeLimiter(cfg.SubscribeRate),
 	}
 }
 
@@ -101,6 +147,11 @@ func (r *Roster) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == rosterNamespace
 }
 
+// Namespaces returns all iq namespaces matched by roster module.
+func (r *Roster) Namespaces() []string {
+	return []string{rosterNamespace}
+}
+
 // ProcessIQ process a roster iq.
 func (r *Roster) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -153,6 +204,9 @@ func (r *Roster) onPresenceRecv(ctx context.Context, execCtx *hook.ExecutionCont
 
 func (r *Roster) onUserDeleted(ctx context.Context, execCtx *hook.ExecutionContext) error {
 	inf := execCtx.Info.(*hook.UserInfo)
+	if r.subLim != nil {
+		r.subLim.forget(inf.Username)
+	}
 	return r.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
 		if err := tx.DeleteRosterNotifications(ctx, inf.Username); err != nil {
 			return err
@@ -181,9 +235,20 @@ func (r *Roster) processPresence(ctx context.Context, pr *stravaganza.Presence)
 
 func (r *Roster) sendRoster(ctx context.Context, iq *stravaganza.IQ) error {
 	q := iq.ChildNamespace("query", rosterNamespace)
-	if q == nil || q.ChildrenCount() > 0 {
+	if q == nil {
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	rsmReq, err := rsm.ParseRequest(q)
+	switch {
+	case errors.Is(err, rsm.ErrUnsupportedRequest):
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.FeatureNotImplemented))
+		return nil
+	case err != nil, rsmReq == nil && q.ChildrenCount() > 0, rsmReq != nil && q.ChildrenCount() > 1:
 		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
 		return nil
+	case rsmReq != nil:
+		return r.sendRosterPage(ctx, iq, rsmReq)
 	}
 	usrJID := iq.FromJID()
 
@@ -196,17 +261,7 @@ func (r *Roster) sendRoster(ctx context.Context, iq *stravaganza.IQ) error {
 	// return empty response in case version matches...
 	if ver > 0 && ver == parseVer(q.Attribute("ver")) {
 		_, _ = r.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
-		err = r.runHook(ctx, hook.RosterRequested, &hook.RosterInfo{
-			Username: usrJID.Node(),
-		})
-		if err != nil {
-			return err
-		}
-		stm, err := r.getStream(usrJID.Node(), usrJID.Resource())
-		if err != nil {
-			return err
-		}
-		return stm.SetInfoValue(ctx, rosterRequestedCtxKey, true)
+		return r.setRosterRequested(ctx, usrJID)
 	}
 	// ...return whole roster otherwise
 	items, err := r.rep.FetchRosterItems(ctx, usrJID.Node())
@@ -226,7 +281,60 @@ func (r *Roster) sendRoster(ctx context.Context, iq *stravaganza.IQ) error {
 
 	level.Info(r.logger).Log("msg", "fetched user roster", "jid", usrJID.String())
 
-	err = r.runHook(ctx, hook.RosterRequested, &hook.RosterInfo{
+	return r.setRosterRequested(ctx, usrJID)
+}
+
+func (r *Roster) sendRosterPage(ctx context.Context, iq *stravaganza.IQ, req *rsm.Request) error {
+	usrJID := iq.FromJID()
+
+	if req.CountOnly {
+		count, err := r.rep.CountRosterItems(ctx, usrJID.Node())
+		if err != nil {
+			_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		_, _ = r.router.Route(ctx, xmpputil.MakeResultIQ(iq, stravaganza.NewBuilder("query").
+			WithAttribute(stravaganza.Namespace, rosterNamespace).
+			WithChild(rsm.Result{Count: count}.Element()).
+			Build(),
+		))
+		return nil
+	}
+	// never materialize more than a page worth of items
+	limit := req.PageSize(rsm.Config{
+		DefaultPageSize: r.cfg.DefaultPageSize,
+		MaxPageSize:     r.cfg.MaxPageSize,
+	})
+	if limit <= 0 {
+		limit = defaultMaxPageSize
+	}
+	items, err := r.rep.FetchRosterItemsPaged(ctx, usrJID.Node(), req.After, limit)
+	if err != nil {
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	sb := stravaganza.NewBuilder("query").
+		WithAttribute(stravaganza.Namespace, rosterNamespace)
+	for _, item := range items {
+		sb.WithChild(encodeRosterItem(item))
+	}
+	res := rsm.Result{Count: -1}
+	if len(items) > 0 {
+		res.First = items[0].Jid
+		res.Last = items[len(items)-1].Jid
+	}
+	sb.WithChild(res.Element())
+
+	// route roster page
+	_, _ = r.router.Route(ctx, xmpputil.MakeResultIQ(iq, sb.Build()))
+
+	level.Info(r.logger).Log("msg", "fetched user roster page", "jid", usrJID.String(), "after", req.After, "count", len(items))
+
+	return r.setRosterRequested(ctx, usrJID)
+}
+
+func (r *Roster) setRosterRequested(ctx context.Context, usrJID *jid.JID) error {
+	err := r.runHook(ctx, hook.RosterRequested, &hook.RosterInfo{
 		Username: usrJID.Node(),
 	})
 	if err != nil {
@@ -255,52 +363,78 @@ func (r *Roster) updateRoster(ctx context.Context, iq *stravaganza.IQ) error {
 		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
 		return nil
 	}
+	if ri.Subscription != rostermodel.Remove && isSelfJID(iq.FromJID(), ri.Jid) {
+		// users cannot add themselves to their own roster
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAcceptable))
+		return nil
+	}
 	switch ri.Subscription {
 	case rostermodel.Remove:
-		if err := r.removeItem(ctx, ri, iq.FromJID().ToBareJID()); err != nil {
-			_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
-			return err
-		}
+		err = r.removeItem(ctx, ri, iq.FromJID().ToBareJID())
 	default:
-		if err := r.updateItem(ctx, ri, iq.FromJID().Node()); err != nil {
-			_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
-			return err
-		}
+		err = r.updateItem(ctx, ri, iq.FromJID().Node())
+	}
+	switch {
+	case err == nil:
+		_, _ = r.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+		return nil
+
+	case errors.Is(err, repository.ErrVersionConflict):
+		level.Warn(r.logger).Log("msg", "conflicting roster update", "jid", ri.Jid, "username", iq.FromJID().Node())
+
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Conflict))
+		return nil
+
+	default:
+		_, _ = r.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
 	}
-	_, _ = r.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
-	return nil
 }
 
 func (r *Roster) processSubscribe(ctx context.Context, presence *stravaganza.Presence) error {
 	userJID := presence.FromJID().ToBareJID()
 	contactJID := presence.ToJID().ToBareJID()
 
+	if userJID.String() == contactJID.String() {
+		level.Warn(r.logger).Log("msg", "ignored self-directed 'subscribe' presence", "username", userJID.Node())
+		return nil
+	}
+
 	if r.hosts.IsLocalHost(userJID.Domain()) {
-		usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
-		if err != nil {
+		allowed, err := r.shapeSubscribe(ctx, userJID, presence)
+		if err != nil || !allowed {
 			return err
 		}
-		if usrRi != nil {
-			switch usrRi.Subscription {
-			case rostermodel.To, rostermodel.Both:
-				return nil // already subscribed...
-			default:
-				if !usrRi.Ask {
+		var skip bool
+		err = retryOnVersionConflict(func() error {
+			usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
+			if err != nil {
+				return err
+			}
+			if usrRi != nil {
+				switch usrRi.Subscription {
+				case rostermodel.To, rostermodel.Both:
+					skip = true
+					return nil // already subscribed...
+				default:
+					if usrRi.Ask {
+						skip = true
+						return nil // notification already sent...
+					}
 					usrRi.Ask = true
-				} else {
-					return nil // notification already sent...
+				}
+			} else {
+				// create roster item if not previously created
+				usrRi = &rostermodel.Item{
+					Username:     userJID.Node(),
+					Jid:          contactJID.String(),
+					Subscription: rostermodel.None,
+					Ask:          true,
 				}
 			}
-		} else {
-			// create roster item if not previously created
-			usrRi = &rostermodel.Item{
-				Username:     userJID.Node(),
-				Jid:          contactJID.String(),
-				Subscription: rostermodel.None,
-				Ask:          true,
-			}
-		}
-		if err := r.upsertItem(ctx, usrRi); err != nil {
+			return r.upsertItem(ctx, usrRi)
+		})
+		if err != nil || skip {
 			return err
 		}
 	}
@@ -319,6 +453,32 @@ func (r *Roster) processSubscribe(ctx context.Context, presence *stravaganza.Pre
 	return nil
 }
 
+// shapeSubscribe applies outbound subscription request rate limit to a local user, identified by its bare JID.
+func (r *Roster) shapeSubscribe(ctx context.Context, userJID *jid.JID, presence *stravaganza.Presence) (bool, error) {
+	if r.subLim == nil {
+		return true, nil
+	}
+	delay, ok := r.subLim.reserve(userJID.String())
+	if !ok {
+		level.Warn(r.logger).Log("msg", "subscription request rate exceeded", "username", userJID.Node(), "jid", presence.ToJID())
+
+		_, _ = r.router.Route(ctx, xmpputil.MakePolicyViolationErrorStanza(presence))
+		return false, nil
+	}
+	if delay == 0 {
+		return true, nil
+	}
+	tm := time.NewTimer(delay)
+	defer tm.Stop()
+
+	select {
+	case <-tm.C:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
 func (r *Roster) processSubscribed(ctx context.Context, presence *stravaganza.Presence) error {
 	userJID := presence.ToJID().ToBareJID()
 	contactJID := presence.FromJID().ToBareJID()
@@ -328,27 +488,30 @@ func (r *Roster) processSubscribed(ctx context.Context, presence *stravaganza.Pr
 		if err != nil {
 			return err
 		}
-		cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
-		if err != nil {
-			return err
-		}
-		if cntRi != nil {
-			switch cntRi.Subscription {
-			case rostermodel.To:
-				cntRi.Subscription = rostermodel.Both
-			case rostermodel.None:
-				cntRi.Subscription = rostermodel.From
+		err = retryOnVersionConflict(func() error {
+			cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
+			if err != nil {
+				return err
 			}
-		} else {
-			// create roster item if not previously created
-			cntRi = &rostermodel.Item{
-				Username:     contactJID.Node(),
-				Jid:          userJID.String(),
-				Subscription: rostermodel.From,
-				Ask:          false,
+			if cntRi != nil {
+				switch cntRi.Subscription {
+				case rostermodel.To:
+					cntRi.Subscription = rostermodel.Both
+				case rostermodel.None:
+					cntRi.Subscription = rostermodel.From
+				}
+			} else {
+				// create roster item if not previously created
+				cntRi = &rostermodel.Item{
+					Username:     contactJID.Node(),
+					Jid:          userJID.String(),
+					Subscription: rostermodel.From,
+					Ask:          false,
+				}
 			}
-		}
-		if err := r.upsertItem(ctx, cntRi); err != nil {
+			return r.upsertItem(ctx, cntRi)
+		})
+		if err != nil {
 			return err
 		}
 	}
@@ -356,23 +519,26 @@ func (r *Roster) processSubscribed(ctx context.Context, presence *stravaganza.Pr
 	p := xmpputil.MakePresence(contactJID, userJID, stravaganza.SubscribedType, presence.AllChildren())
 
 	if r.hosts.IsLocalHost(userJID.Domain()) {
-		usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
-		if err != nil {
-			return err
-		}
-		if usrRi != nil {
+		var skip bool
+		err := retryOnVersionConflict(func() error {
+			usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
+			if err != nil || usrRi == nil {
+				return err
+			}
 			switch usrRi.Subscription {
 			case rostermodel.From:
 				usrRi.Subscription = rostermodel.Both
 			case rostermodel.None:
 				usrRi.Subscription = rostermodel.To
 			default:
+				skip = true
 				return nil
 			}
 			usrRi.Ask = false
-			if err := r.upsertItem(ctx, usrRi); err != nil {
-				return err
-			}
+			return r.upsertItem(ctx, usrRi)
+		})
+		if err != nil || skip {
+			return err
 		}
 	}
 	level.Info(r.logger).Log("msg", "processed 'subscribed' presence", "jid", contactJID, "username", userJID.Node())
@@ -387,12 +553,15 @@ func (r *Roster) processUnsubscribe(ctx context.Context, presence *stravaganza.P
 
 	var usrSub string
 	if r.hosts.IsLocalHost(userJID.Domain()) {
-		usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
-		if err != nil {
-			return err
-		}
-		usrSub = rostermodel.None
-		if usrRi != nil {
+		err := retryOnVersionConflict(func() error {
+			usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
+			if err != nil {
+				return err
+			}
+			usrSub = rostermodel.None
+			if usrRi == nil {
+				return nil
+			}
 			usrSub = usrRi.Subscription
 			switch usrSub {
 			case rostermodel.Both:
@@ -400,29 +569,31 @@ func (r *Roster) processUnsubscribe(ctx context.Context, presence *stravaganza.P
 			default:
 				usrRi.Subscription = rostermodel.None
 			}
-			if err := r.upsertItem(ctx, usrRi); err != nil {
-				return err
-			}
+			return r.upsertItem(ctx, usrRi)
+		})
+		if err != nil {
+			return err
 		}
 	}
 	// stamp the presence stanza of type "unsubscribe" with the users's bare JID as the 'from' address
 	p := xmpputil.MakePresence(userJID, contactJID, stravaganza.UnsubscribeType, presence.AllChildren())
 
 	if r.hosts.IsLocalHost(contactJID.Domain()) {
-		cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
-		if err != nil {
-			return err
-		}
-		if cntRi != nil {
+		err := retryOnVersionConflict(func() error {
+			cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
+			if err != nil || cntRi == nil {
+				return err
+			}
 			switch cntRi.Subscription {
 			case rostermodel.Both:
 				cntRi.Subscription = rostermodel.To
 			default:
 				cntRi.Subscription = rostermodel.None
 			}
-			if err := r.upsertItem(ctx, cntRi); err != nil {
-				return err
-			}
+			return r.upsertItem(ctx, cntRi)
+		})
+		if err != nil {
+			return err
 		}
 	}
 	_, _ = r.router.Route(ctx, p)
@@ -450,12 +621,15 @@ func (r *Roster) processUnsubscribed(ctx context.Context, presence *stravaganza.
 		if deleted {
 			goto routePresence
 		}
-		cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
-		if err != nil {
-			return err
-		}
-		cntSub = rostermodel.None
-		if cntRi != nil {
+		err = retryOnVersionConflict(func() error {
+			cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
+			if err != nil {
+				return err
+			}
+			cntSub = rostermodel.None
+			if cntRi == nil {
+				return nil
+			}
 			cntSub = cntRi.Subscription
 			switch cntSub {
 			case rostermodel.Both:
@@ -463,9 +637,10 @@ func (r *Roster) processUnsubscribed(ctx context.Context, presence *stravaganza.
 			default:
 				cntRi.Subscription = rostermodel.None
 			}
-			if err := r.upsertItem(ctx, cntRi); err != nil {
-				return err
-			}
+			return r.upsertItem(ctx, cntRi)
+		})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -474,11 +649,11 @@ routePresence:
 	p := xmpputil.MakePresence(contactJID, userJID, stravaganza.UnsubscribedType, presence.AllChildren())
 
 	if r.hosts.IsLocalHost(userJID.Domain()) {
-		usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
-		if err != nil {
-			return err
-		}
-		if usrRi != nil {
+		err := retryOnVersionConflict(func() error {
+			usrRi, err := r.rep.FetchRosterItem(ctx, userJID.Node(), contactJID.String())
+			if err != nil || usrRi == nil {
+				return err
+			}
 			if !usrRi.Ask { // pending out...
 				switch usrRi.Subscription {
 				case rostermodel.Both:
@@ -488,9 +663,10 @@ routePresence:
 				}
 			}
 			usrRi.Ask = false
-			if err := r.upsertItem(ctx, usrRi); err != nil {
-				return err
-			}
+			return r.upsertItem(ctx, usrRi)
+		})
+		if err != nil {
+			return err
 		}
 	}
 	_, _ = r.router.Route(ctx, p)
@@ -516,7 +692,7 @@ func (r *Roster) processProbe(ctx context.Context, presence *stravaganza.Presenc
 	if err != nil {
 		return err
 	}
-	if ri == nil || (ri.Subscription != rostermodel.Both && ri.Subscription != rostermodel.From) {
+	if !r.isPresenceVisible(ri, contactJID.Domain()) {
 		return nil // silently ignore
 	}
 	rss, err := r.resMng.GetResources(ctx, contactJID.Node())
@@ -600,12 +776,12 @@ func (r *Roster) processAvailability(ctx context.Context, presence *stravaganza.
 
 broadcastPresence:
 	for _, item := range items {
-		switch item.Subscription {
-		case rostermodel.From, rostermodel.Both:
-			itemJID, _ := jid.NewWithString(item.Jid, true)
-			p := xmpputil.MakePresence(presence.FromJID(), itemJID, presence.Type(), presence.AllChildren())
-			_, _ = r.router.Route(ctx, p)
+		if !r.isPresenceVisible(item, userJID.Domain()) {
+			continue // skip strangers
 		}
+		itemJID, _ := jid.NewWithString(item.Jid, true)
+		p := xmpputil.MakePresence(presence.FromJID(), itemJID, presence.Type(), presence.AllChildren())
+		_, _ = r.router.Route(ctx, p)
 	}
 	if isAvailable {
 		level.Info(r.logger).Log("msg", "processed 'available' presence", "jid", contactJID, "username", userJID.Node())
@@ -616,6 +792,12 @@ broadcastPresence:
 }
 
 func (r *Roster) updateItem(ctx context.Context, ri *rostermodel.Item, username string) error {
+	return retryOnVersionConflict(func() error {
+		return r.applyItemUpdate(ctx, ri, username)
+	})
+}
+
+func (r *Roster) applyItemUpdate(ctx context.Context, ri *rostermodel.Item, username string) error {
 	usrRi, err := r.rep.FetchRosterItem(ctx, username, ri.Jid)
 	if err != nil {
 		return err
@@ -678,16 +860,13 @@ func (r *Roster) removeItem(ctx context.Context, ri *rostermodel.Item, userJID *
 	}
 
 	if r.hosts.IsLocalHost(contactJID.Domain()) {
-		cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
-		if err != nil {
-			return err
-		}
-		if cntRi != nil {
-			if cntRi.Subscription == rostermodel.From || cntRi.Subscription == rostermodel.Both {
-				if err := r.routePresencesFrom(ctx, contactJID.Node(), userJID, stravaganza.UnavailableType); err != nil {
-					return err
-				}
+		var cntSub string
+		err := retryOnVersionConflict(func() error {
+			cntRi, err := r.rep.FetchRosterItem(ctx, contactJID.Node(), userJID.String())
+			if err != nil || cntRi == nil {
+				return err
 			}
+			cntSub = cntRi.Subscription
 			switch cntRi.Subscription {
 			case rostermodel.Both:
 				cntRi.Subscription = rostermodel.To
@@ -698,9 +877,15 @@ func (r *Roster) removeItem(ctx context.Context, ri *rostermodel.Item, userJID *
 
 			default:
 				cntRi.Subscription = rostermodel.None
-				if err := r.upsertItem(ctx, cntRi); err != nil {
-					return err
-				}
+				return r.upsertItem(ctx, cntRi)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if cntSub == rostermodel.From || cntSub == rostermodel.Both {
+			if err := r.routePresencesFrom(ctx, contactJID.Node(), userJID, stravaganza.UnavailableType); err != nil {
+				return err
 			}
 		}
 	}
@@ -741,6 +926,19 @@ func (r *Roster) upsertItem(ctx context.Context, ri *rostermodel.Item) error {
 	})
 }
 
+// retryOnVersionConflict runs fn, which is expected to read the roster items it modifies,
+// as long as it fails because of a concurrent roster item update, up to maxUpdateAttempts times.
+func retryOnVersionConflict(fn func() error) error {
+	var err error
+	for i := 0; i < maxUpdateAttempts; i++ {
+		err = fn()
+		if !errors.Is(err, repository.ErrVersionConflict) {
+			return err
+		}
+	}
+	return err
+}
+
 func (r *Roster) deleteItem(ctx context.Context, ri *rostermodel.Item) error {
 	err := r.rep.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
 		ver, err := tx.TouchRosterVersion(ctx, ri.Username)
@@ -829,6 +1027,18 @@ func (r *Roster) routePresencesFrom(ctx context.Context, username string, toJID
 	return nil
 }
 
+func (r *Roster) isPresenceVisible(ri *rostermodel.Item, domain string) bool {
+	if ri != nil && (ri.Subscription == rostermodel.From || ri.Subscription == rostermodel.Both) {
+		return true
+	}
+	for _, h := range r.cfg.PresenceVisibleToStrangers {
+		if h == domain {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Roster) getStream(username, resource string) (stream.C2S, error) {
 	stm := r.router.C2S().LocalStream(username, resource)
 	if stm == nil {
@@ -904,6 +1114,14 @@ func encodeRosterItem(ri *rostermodel.Item) stravaganza.Element {
 	return b.Build()
 }
 
+func isSelfJID(userJID *jid.JID, j string) bool {
+	itemJID, err := jid.NewWithString(j, false)
+	if err != nil {
+		return false
+	}
+	return itemJID.ToBareJID().String() == userJID.ToBareJID().String()
+}
+
 func parseVer(ver string) int {
 	if len(ver) > 0 && ver[0] == 'v' {
 		v, _ := strconv.Atoi(ver[1:])
@@ -16,8 +16,10 @@ package roster
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
@@ -28,6 +30,7 @@ import (
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +116,164 @@ func TestRoster_SendRoster(t *testing.T) {
 	require.Len(t, stmMock.SetInfoValueCalls(), 1)
 }
 
+func TestRoster_SendRosterPaged(t *testing.T) {
+	// given
+	var rosterItems []*rostermodel.Item
+	for i := 0; i < 250; i++ {
+		rosterItems = append(rosterItems, &rostermodel.Item{
+			Username: "ortuman",
+			Jid:      fmt.Sprintf("contact%03d@jackal.im", i),
+		})
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemsPagedFunc = func(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+		var page []*rostermodel.Item
+		for _, itm := range rosterItems {
+			if itm.Jid > afterJID && len(page) < limit {
+				page = append(page, itm)
+			}
+		}
+		return page, nil
+	}
+	stmMock := &c2sStreamMock{}
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		return nil
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.LocalStreamFunc = func(username string, resource string) stream.C2S {
+		return stmMock
+	}
+	routerMock := &routerMock{}
+
+	var respStanza stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanza = stanza
+		return nil, nil
+	}
+	routerMock.C2SFunc = func() router.C2SRouter {
+		return c2sRouterMock
+	}
+	r := &Roster{
+		cfg:    Config{MaxPageSize: 50},
+		rep:    repMock,
+		router: routerMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	var fetchedJIDs []string
+
+	var after string
+	for {
+		sb := stravaganza.NewBuilder("set").
+			WithAttribute(stravaganza.Namespace, rsm.Namespace).
+			WithChild(stravaganza.NewBuilder("max").WithText("100").Build())
+		if len(after) > 0 {
+			sb.WithChild(stravaganza.NewBuilder("after").WithText(after).Build())
+		}
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "id1234").
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, rosterNamespace).
+					WithChild(sb.Build()).
+					Build(),
+			).
+			BuildIQ()
+		_ = r.ProcessIQ(context.Background(), iq)
+
+		// then
+		require.Equal(t, stravaganza.ResultType, respStanza.Attribute(stravaganza.Type))
+
+		query := respStanza.ChildNamespace("query", rosterNamespace)
+		require.NotNil(t, query)
+
+		items := query.Children("item")
+		require.LessOrEqual(t, len(items), 50) // bounded by server page size
+
+		set := query.ChildNamespace("set", rsm.Namespace)
+		require.NotNil(t, set)
+
+		if len(items) == 0 {
+			break
+		}
+		for _, itm := range items {
+			fetchedJIDs = append(fetchedJIDs, itm.Attribute("jid"))
+		}
+		require.Equal(t, items[len(items)-1].Attribute("jid"), set.Child("last").Text())
+		after = set.Child("last").Text()
+	}
+	require.Len(t, fetchedJIDs, 250)
+	require.Len(t, repMock.FetchRosterItemsPagedCalls(), 6)
+	require.Len(t, repMock.FetchRosterItemsCalls(), 0)
+
+	for i, jd := range fetchedJIDs {
+		require.Equal(t, rosterItems[i].Jid, jd)
+	}
+}
+
+func TestRoster_SendRosterCount(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.CountRosterItemsFunc = func(ctx context.Context, username string) (int, error) {
+		return 250, nil
+	}
+	routerMock := &routerMock{}
+
+	var respStanza stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanza = stanza
+		return nil, nil
+	}
+	r := &Roster{
+		cfg:    Config{MaxPageSize: 50},
+		rep:    repMock,
+		router: routerMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, rosterNamespace).
+				WithChild(
+					stravaganza.NewBuilder("set").
+						WithAttribute(stravaganza.Namespace, rsm.Namespace).
+						WithChild(stravaganza.NewBuilder("max").WithText("0").Build()).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	_ = r.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.NotNil(t, respStanza)
+	require.Equal(t, stravaganza.ResultType, respStanza.Attribute(stravaganza.Type))
+
+	query := respStanza.ChildNamespace("query", rosterNamespace)
+	require.NotNil(t, query)
+	require.Len(t, query.Children("item"), 0)
+
+	set := query.ChildNamespace("set", rsm.Namespace)
+	require.NotNil(t, set)
+	require.Nil(t, set.Child("first"))
+	require.Equal(t, "250", set.Child("count").Text())
+
+	require.Len(t, repMock.CountRosterItemsCalls(), 1)
+	require.Len(t, repMock.FetchRosterItemsPagedCalls(), 0)
+}
+
 func TestRoster_UpdateItem(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
@@ -209,6 +370,148 @@ func TestRoster_UpdateItem(t *testing.T) {
 	require.Equal(t, stravaganza.ResultType, resIQ.Attribute("type"))
 }
 
+func TestRoster_UpdateItemVersionConflict(t *testing.T) {
+	for _, tc := range []struct {
+		name           string
+		conflicts      int
+		expectedType   string
+		expectedUpsert int
+	}{
+		{name: "RetrySucceeds", conflicts: 1, expectedType: stravaganza.ResultType, expectedUpsert: 2},
+		{name: "Conflict", conflicts: maxUpdateAttempts, expectedType: stravaganza.ErrorType, expectedUpsert: maxUpdateAttempts},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			var storedVer int32 = 1
+
+			repMock := &repositoryMock{}
+			repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+				return &rostermodel.Item{
+					Username:     username,
+					Jid:          jid,
+					Subscription: rostermodel.Both,
+					Version:      storedVer,
+				}, nil
+			}
+			var conflicts int
+
+			txMock := &txMock{}
+			txMock.TouchRosterVersionFunc = func(ctx context.Context, username string) (int, error) {
+				return 1, nil
+			}
+			txMock.UpsertRosterItemFunc = func(ctx context.Context, ri *rostermodel.Item) error {
+				if conflicts < tc.conflicts {
+					conflicts++
+					storedVer++ // concurrently modified
+					return repository.ErrVersionConflict
+				}
+				require.Equal(t, storedVer, ri.Version)
+				require.Equal(t, rostermodel.Both, ri.Subscription)
+				return nil
+			}
+			repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+				return f(ctx, txMock)
+			}
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+			resMngMock := &resourceManagerMock{}
+			resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+				return nil, nil
+			}
+			r := &Roster{
+				rep:    repMock,
+				resMng: resMngMock,
+				router: routerMock,
+				hosts:  &hostsMock{},
+				hk:     hook.NewHooks(),
+				logger: kitlog.NewNopLogger(),
+			}
+			// when
+			iq, _ := stravaganza.NewIQBuilder().
+				WithAttribute(stravaganza.ID, "id1234").
+				WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+				WithAttribute(stravaganza.To, "ortuman@jackal.im").
+				WithAttribute(stravaganza.Type, stravaganza.SetType).
+				WithChild(
+					stravaganza.NewBuilder("query").
+						WithAttribute(stravaganza.Namespace, rosterNamespace).
+						WithChild(
+							stravaganza.NewBuilder("item").
+								WithAttribute("name", "Buddy").
+								WithAttribute("jid", "hamlet@jackal.im").
+								Build(),
+						).
+						Build(),
+				).
+				BuildIQ()
+			err := r.ProcessIQ(context.Background(), iq)
+
+			// then
+			require.Nil(t, err)
+			require.Len(t, txMock.UpsertRosterItemCalls(), tc.expectedUpsert)
+
+			require.Len(t, respStanzas, 1)
+			require.Equal(t, tc.expectedType, respStanzas[0].Attribute(stravaganza.Type))
+			if tc.expectedType == stravaganza.ErrorType {
+				require.NotNil(t, respStanzas[0].Child("error").Child("conflict"))
+			}
+		})
+	}
+}
+
+func TestRoster_UpdateSelfItem(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+
+	r := &Roster{
+		rep:    repMock,
+		router: routerMock,
+		hosts:  &hostsMock{},
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, rosterNamespace).
+				WithChild(
+					stravaganza.NewBuilder("item").
+						WithAttribute("name", "Myself").
+						WithAttribute("jid", "ortuman@jackal.im").
+						WithAttribute("subscription", "none").
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	_ = r.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+	require.NotNil(t, respStanzas[0].Child("error").Child("not-acceptable"))
+
+	require.Len(t, repMock.InTransactionCalls(), 0)
+}
+
 func TestRoster_RemoveItem(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
@@ -409,6 +712,212 @@ func TestRoster_Subscribe(t *testing.T) {
 	require.Equal(t, stravaganza.SubscribeType, subscribePr.Attribute("type"))
 }
 
+func TestRoster_SelfSubscribe(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+
+	hMock := &hostsMock{}
+	hMock.IsLocalHostFunc = func(h string) bool {
+		return h == "jackal.im"
+	}
+
+	hk := hook.NewHooks()
+	r := &Roster{
+		rep:    repMock,
+		router: routerMock,
+		hosts:  hMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	// when
+	fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.SubscribeType, nil)
+
+	_ = r.Start(context.Background())
+	_, err := hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{Element: pr},
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, respStanzas, 0)
+
+	require.Len(t, repMock.FetchRosterItemCalls(), 0)
+	require.Len(t, repMock.UpsertRosterNotificationCalls(), 0)
+}
+
+func TestRoster_SubscribeRateLimit(t *testing.T) {
+	var tcs = map[string]struct {
+		cfg              SubscribeRateConfig
+		expSubscriptions int
+		expRejections    int
+	}{
+		"Unlimited": {
+			expSubscriptions: 4,
+		},
+		"Rejected": {
+			cfg:              SubscribeRateConfig{Limit: 0.1, Burst: 2},
+			expSubscriptions: 2,
+			expRejections:    2,
+		},
+		"Throttled": {
+			cfg:              SubscribeRateConfig{Limit: 50, Burst: 2, MaxDelay: time.Second},
+			expSubscriptions: 4,
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			repMock := &repositoryMock{}
+			repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+				return nil, nil
+			}
+			txMock := &txMock{}
+			txMock.TouchRosterVersionFunc = func(ctx context.Context, username string) (int, error) {
+				return 2, nil
+			}
+			txMock.UpsertRosterItemFunc = func(ctx context.Context, ri *rostermodel.Item) error {
+				return nil
+			}
+			repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+				return f(ctx, txMock)
+			}
+			repMock.UpsertRosterNotificationFunc = func(ctx context.Context, rn *rostermodel.Notification) error {
+				return nil
+			}
+
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+
+			hMock := &hostsMock{}
+			hMock.IsLocalHostFunc = func(h string) bool {
+				return h == "jackal.im"
+			}
+			resMngMock := &resourceManagerMock{}
+			resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+				return nil, nil
+			}
+
+			hk := hook.NewHooks()
+			r := &Roster{
+				rep:    repMock,
+				resMng: resMngMock,
+				router: routerMock,
+				hosts:  hMock,
+				hk:     hk,
+				logger: kitlog.NewNopLogger(),
+				subLim: newSubscribeLimiter(tCase.cfg),
+			}
+			// when
+			_ = r.Start(context.Background())
+
+			fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+			for _, contact := range []string{"noelia@jackal.im", "juliet@jackal.im", "romeo@jabber.org", "mercutio@jabber.org"} {
+				toJID, _ := jid.NewWithString(contact, true)
+
+				pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.SubscribeType, nil)
+				_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+					Info: &hook.C2SStreamInfo{Element: pr},
+				})
+			}
+
+			// then
+			var subscriptions, rejections int
+			for _, stanza := range respStanzas {
+				switch stanza.Attribute(stravaganza.Type) {
+				case stravaganza.SubscribeType:
+					subscriptions++
+				case stravaganza.ErrorType:
+					require.Equal(t, "ortuman@jackal.im/balcony", stanza.Attribute(stravaganza.To))
+					require.NotNil(t, stanza.Child("error").ChildNamespace("policy-violation", "urn:ietf:params:xml:ns:xmpp-stanzas"))
+					rejections++
+				}
+			}
+			require.Equal(t, tCase.expSubscriptions, subscriptions)
+			require.Equal(t, tCase.expRejections, rejections)
+		})
+	}
+}
+
+func TestRoster_SubscribeRateLimitOnlyOutboundSubscribe(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+		return nil, nil
+	}
+	repMock.FetchRosterNotificationFunc = func(ctx context.Context, contact string, jid string) (*rostermodel.Notification, error) {
+		return nil, nil
+	}
+	repMock.UpsertRosterNotificationFunc = func(ctx context.Context, rn *rostermodel.Notification) error {
+		return nil
+	}
+	txMock := &txMock{}
+	txMock.TouchRosterVersionFunc = func(ctx context.Context, username string) (int, error) {
+		return 2, nil
+	}
+	txMock.UpsertRosterItemFunc = func(ctx context.Context, ri *rostermodel.Item) error {
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hMock := &hostsMock{}
+	hMock.IsLocalHostFunc = func(h string) bool {
+		return h == "jackal.im"
+	}
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+	r := &Roster{
+		rep:    repMock,
+		resMng: resMngMock,
+		router: routerMock,
+		hosts:  hMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+		subLim: newSubscribeLimiter(SubscribeRateConfig{Limit: 0.1, Burst: 1}),
+	}
+	localJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+	remoteJID, _ := jid.NewWithString("ortuman@jabber.org/yard", true)
+	contactJID, _ := jid.NewWithString("noelia@jackal.im", true)
+
+	// when
+	for _, tp := range []string{stravaganza.SubscribedType, stravaganza.UnsubscribeType, stravaganza.UnsubscribedType} {
+		require.NoError(t, r.processPresence(context.Background(), xmpputil.MakePresence(localJID, contactJID, tp, nil)))
+	}
+	// a remote sender sharing the local user node name must not drain local user budget
+	require.NoError(t, r.processPresence(context.Background(), xmpputil.MakePresence(remoteJID, contactJID, stravaganza.SubscribeType, nil)))
+
+	respStanzas = nil
+	require.NoError(t, r.processPresence(context.Background(), xmpputil.MakePresence(localJID, contactJID, stravaganza.SubscribeType, nil)))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.SubscribeType, respStanzas[0].Attribute(stravaganza.Type))
+}
+
 func TestRoster_Subscribed(t *testing.T) {
 	// given
 	var mtx sync.RWMutex
@@ -522,6 +1031,101 @@ func TestRoster_Subscribed(t *testing.T) {
 	require.Equal(t, stravaganza.AvailableType, availPr.Attribute("type"))
 }
 
+func TestRoster_SubscribedVersionConflict(t *testing.T) {
+	// given
+	var mtx sync.RWMutex
+
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+		switch {
+		case username == "ortuman" && jid == "noelia@jackal.im":
+			return &rostermodel.Item{
+				Username:     "ortuman",
+				Jid:          "noelia@jackal.im",
+				Subscription: rostermodel.From,
+			}, nil
+		case username == "noelia" && jid == "ortuman@jackal.im":
+			return &rostermodel.Item{
+				Username:     "noelia",
+				Jid:          "ortuman@jackal.im",
+				Subscription: rostermodel.To,
+			}, nil
+		}
+		return nil, nil
+	}
+	var conflicted bool
+
+	txMock := &txMock{}
+	txMock.TouchRosterVersionFunc = func(ctx context.Context, username string) (int, error) {
+		return 2, nil
+	}
+	txMock.UpsertRosterItemFunc = func(ctx context.Context, ri *rostermodel.Item) error {
+		if !conflicted {
+			conflicted = true
+			return repository.ErrVersionConflict
+		}
+		return nil
+	}
+	repMock.InTransactionFunc = func(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+		return f(ctx, txMock)
+	}
+	repMock.FetchRosterNotificationFunc = func(ctx context.Context, contact string, jid string) (*rostermodel.Notification, error) {
+		return nil, nil
+	}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+
+	hMock := &hostsMock{}
+	hMock.IsLocalHostFunc = func(h string) bool {
+		return h == "jackal.im"
+	}
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+		return nil, nil
+	}
+
+	hk := hook.NewHooks()
+	r := &Roster{
+		rep:    repMock,
+		resMng: resMngMock,
+		router: routerMock,
+		hosts:  hMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	// when
+	fromJID, _ := jid.NewWithString("noelia@jackal.im/yard", true)
+	toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.SubscribedType, nil)
+
+	_ = r.Start(context.Background())
+	_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{Element: pr},
+	})
+
+	// then
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	require.Len(t, repMock.FetchRosterItemCalls(), 3)
+	require.Len(t, txMock.UpsertRosterItemCalls(), 3)
+
+	require.Len(t, respStanzas, 1)
+
+	subscribedPr, ok := respStanzas[0].(*stravaganza.Presence)
+	require.True(t, ok)
+	require.Equal(t, "ortuman@jackal.im", subscribedPr.Attribute("to"))
+	require.Equal(t, stravaganza.SubscribedType, subscribedPr.Attribute("type"))
+}
+
 func TestRoster_Unsubscribe(t *testing.T) {
 	// given
 	var mtx sync.RWMutex
@@ -945,3 +1549,85 @@ func TestRoster_Available(t *testing.T) {
 	require.Equal(t, "noelia@jackal.im", availPr1.Attribute("to"))
 	require.Equal(t, stravaganza.AvailableType, availPr1.Attribute("type"))
 }
+
+func TestRoster_StrangerPresence(t *testing.T) {
+	var tests = []struct {
+		name string
+
+		// input
+		cfg Config
+
+		// expectations
+		expectedRecipients []string
+	}{
+		{
+			name:               "Default",
+			cfg:                Config{},
+			expectedRecipients: []string{"noelia@jackal.im"},
+		},
+		{
+			name:               "VisibleToStrangers",
+			cfg:                Config{PresenceVisibleToStrangers: []string{"jackal.im"}},
+			expectedRecipients: []string{"noelia@jackal.im", "romeo@jackal.im"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			var mtx sync.RWMutex
+
+			repMock := &repositoryMock{}
+			repMock.FetchRosterItemsFunc = func(ctx context.Context, username string) ([]*rostermodel.Item, error) {
+				return []*rostermodel.Item{
+					{
+						Username:     "ortuman",
+						Jid:          "noelia@jackal.im",
+						Subscription: rostermodel.Both,
+					},
+					{
+						Username:     "ortuman",
+						Jid:          "romeo@jackal.im",
+						Subscription: rostermodel.None,
+					},
+				}, nil
+			}
+			routerMock := &routerMock{}
+			var recipients []string
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				mtx.Lock()
+				defer mtx.Unlock()
+				recipients = append(recipients, stanza.Attribute(stravaganza.To))
+				return nil, nil
+			}
+			hMock := &hostsMock{}
+			hMock.IsLocalHostFunc = func(h string) bool {
+				return h == "jackal.im"
+			}
+			hk := hook.NewHooks()
+			r := &Roster{
+				cfg:    tt.cfg,
+				rep:    repMock,
+				router: routerMock,
+				hosts:  hMock,
+				hk:     hk,
+				logger: kitlog.NewNopLogger(),
+			}
+			// when
+			fromJID, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+			toJID, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+			pr := xmpputil.MakePresence(fromJID, toJID, stravaganza.UnavailableType, nil)
+
+			_ = r.Start(context.Background())
+			_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+				Info: &hook.C2SStreamInfo{Element: pr},
+			})
+
+			// then
+			mtx.RLock()
+			defer mtx.RUnlock()
+
+			require.Equal(t, tt.expectedRecipients, recipients)
+		})
+	}
+}
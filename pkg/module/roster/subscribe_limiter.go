@@ -0,0 +1,118 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roster
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// subscribeLimiter keeps track of per-account outbound subscription request rate, keyed by account bare JID.
+type subscribeLimiter struct {
+	limit    rate.Limit
+	burst    int
+	maxDelay time.Duration
+	idleTime time.Duration
+	nowFn    func() time.Time
+
+	mu        sync.Mutex
+	lims      map[string]*accountLimiter
+	lastSweep time.Time
+}
+
+type accountLimiter struct {
+	lim      *rate.Limiter
+	lastSeen time.Time
+}
+
+func newSubscribeLimiter(cfg SubscribeRateConfig) *subscribeLimiter {
+	if cfg.Limit <= 0 {
+		return nil
+	}
+	burst := cfg.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	return &subscribeLimiter{
+		limit:    rate.Limit(cfg.Limit),
+		burst:    burst,
+		maxDelay: cfg.MaxDelay,
+		// an account limiter idle for this long is fully refilled, thus equivalent to a new one
+		idleTime: time.Duration(float64(burst) / cfg.Limit * float64(time.Second)),
+		nowFn:    time.Now,
+		lims:     make(map[string]*accountLimiter),
+	}
+}
+
+// reserve reserves a subscription request slot for the account identified by bareJID, returning how long
+// the request should be delayed. Returns false if the request exceeds the configured rate and must be rejected.
+func (l *subscribeLimiter) reserve(bareJID string) (time.Duration, bool) {
+	now := l.nowFn()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	al := l.lims[bareJID]
+	if al == nil {
+		al = &accountLimiter{lim: rate.NewLimiter(l.limit, l.burst)}
+		l.lims[bareJID] = al
+	}
+	al.lastSeen = now
+
+	r := al.lim.ReserveN(now, 1)
+	if !r.OK() {
+		return 0, false
+	}
+	delay := r.DelayFrom(now)
+	if delay > l.maxDelay {
+		r.CancelAt(now)
+		return 0, false
+	}
+	return delay, true
+}
+
+// forget discards username limiter state, for any of the local domains.
+func (l *subscribeLimiter) forget(username string) {
+	l.mu.Lock()
+	for bareJID := range l.lims {
+		if strings.HasPrefix(bareJID, username+"@") {
+			delete(l.lims, bareJID)
+		}
+	}
+	l.mu.Unlock()
+}
+
+func (l *subscribeLimiter) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.lims)
+}
+
+func (l *subscribeLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTime {
+		return
+	}
+	for bareJID, al := range l.lims {
+		if now.Sub(al.lastSeen) >= l.idleTime+l.maxDelay {
+			delete(l.lims, bareJID)
+		}
+	}
+	l.lastSweep = now
+}
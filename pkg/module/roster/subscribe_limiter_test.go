@@ -0,0 +1,92 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeLimiter_Reserve(t *testing.T) {
+	// given
+	tm := time.Now()
+
+	l := newSubscribeLimiter(SubscribeRateConfig{Limit: 1, Burst: 2})
+	l.nowFn = func() time.Time { return tm }
+
+	// when
+	_, ok0 := l.reserve("ortuman@jackal.im")
+	_, ok1 := l.reserve("ortuman@jackal.im")
+	_, ok2 := l.reserve("ortuman@jackal.im") // exceeds burst
+	_, ok3 := l.reserve("noelia@jackal.im")
+
+	tm = tm.Add(time.Second) // one request refilled
+	_, ok4 := l.reserve("ortuman@jackal.im")
+
+	// then
+	require.True(t, ok0)
+	require.True(t, ok1)
+	require.False(t, ok2)
+	require.True(t, ok3)
+	require.True(t, ok4)
+}
+
+func TestSubscribeLimiter_Throttle(t *testing.T) {
+	// given
+	tm := time.Now()
+
+	l := newSubscribeLimiter(SubscribeRateConfig{Limit: 1, Burst: 1, MaxDelay: time.Second})
+	l.nowFn = func() time.Time { return tm }
+
+	// when
+	d0, ok0 := l.reserve("ortuman@jackal.im")
+	d1, ok1 := l.reserve("ortuman@jackal.im")
+	_, ok2 := l.reserve("ortuman@jackal.im") // exceeds max delay
+
+	// then
+	require.True(t, ok0)
+	require.Zero(t, d0)
+	require.True(t, ok1)
+	require.Equal(t, time.Second, d1)
+	require.False(t, ok2)
+}
+
+func TestSubscribeLimiter_EvictIdle(t *testing.T) {
+	// given
+	tm := time.Now()
+
+	l := newSubscribeLimiter(SubscribeRateConfig{Limit: 1, Burst: 2})
+	l.nowFn = func() time.Time { return tm }
+
+	// when
+	_, _ = l.reserve("ortuman@jackal.im")
+	_, _ = l.reserve("noelia@jackal.im")
+	l.forget("noelia")
+	len0 := l.len()
+
+	tm = tm.Add(time.Second * 2) // fully refilled
+	_, _ = l.reserve("juliet@jackal.im")
+	len1 := l.len()
+
+	// then
+	require.Equal(t, 1, len0)
+	require.Equal(t, 1, len1)
+}
+
+func TestSubscribeLimiter_Disabled(t *testing.T) {
+	require.Nil(t, newSubscribeLimiter(SubscribeRateConfig{}))
+}
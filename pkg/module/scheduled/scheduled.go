@@ -0,0 +1,274 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduled
+
+import (
+	"context"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/hook"
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const scheduledNamespace = "urn:jackal:scheduled:0"
+
+const (
+	dispatchLockID = "scheduled:dispatch:lock"
+
+	dispatchUnlockTimeout = time.Second * 5
+)
+
+// ModuleName represents scheduled module name.
+const ModuleName = "scheduled"
+
+// Config contains scheduled module configuration options.
+type Config struct {
+	// Interval defines how often due scheduled stanzas are dispatched.
+	Interval time.Duration `fig:"interval" default:"1s"`
+
+	// BatchSize defines the maximum number of stanzas dispatched on every interval.
+	BatchSize int `fig:"batch_size" default:"100"`
+
+	// MaxDelay defines the maximum allowed time between scheduling and delivery.
+	MaxDelay time.Duration `fig:"max_delay" default:"720h"`
+}
+
+// Scheduled represents scheduled stanza delivery module type.
+// Due stanzas are persisted into the repository, so that scheduled deliveries survive server restarts.
+type Scheduled struct {
+	cfg    Config
+	router router.Router
+	rep    repository.Repository
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	doneCh chan chan struct{}
+}
+
+// New returns a new initialized Scheduled instance.
+func New(
+	cfg Config,
+	router router.Router,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Scheduled {
+	return &Scheduled{
+		cfg:    cfg,
+		router: router,
+		rep:    rep,
+		hk:     hk,
+		logger: kitlog.With(logger, "module", ModuleName),
+		doneCh: make(chan chan struct{}),
+	}
+}
+
+// Name returns scheduled module name.
+func (m *Scheduled) Name() string { return ModuleName }
+
+// StreamFeature returns scheduled module stream feature.
+func (m *Scheduled) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns scheduled module server disco features.
+func (m *Scheduled) ServerFeatures(_ context.Context) ([]string, error) { return nil, nil }
+
+// AccountFeatures returns scheduled module account disco features.
+func (m *Scheduled) AccountFeatures(_ context.Context) ([]string, error) {
+	return []string{scheduledNamespace}, nil
+}
+
+// MatchesNamespace tells whether namespace matches scheduled module.
+func (m *Scheduled) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if serverTarget {
+		return false
+	}
+	return namespace == scheduledNamespace
+}
+
+// Namespaces returns all iq namespaces matched by scheduled module.
+func (m *Scheduled) Namespaces() []string {
+	return []string{scheduledNamespace}
+}
+
+// ProcessIQ process a scheduled iq.
+func (m *Scheduled) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	toJID := iq.ToJID()
+	if toJID.Node() != fromJID.Node() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	sch := iq.ChildNamespace("schedule", scheduledNamespace)
+	switch {
+	case iq.IsSet() && sch != nil:
+		return m.scheduleStanza(ctx, iq, sch)
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+// Start starts scheduled module.
+func (m *Scheduled) Start(_ context.Context) error {
+	m.hk.AddHook(hook.UserDeleted, m.onUserDeleted, hook.DefaultPriority)
+
+	go m.loop()
+
+	level.Info(m.logger).Log("msg", "started scheduled module")
+	return nil
+}
+
+// Stop stops scheduled module.
+func (m *Scheduled) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.UserDeleted, m.onUserDeleted)
+
+	ch := make(chan struct{})
+	m.doneCh <- ch
+	<-ch
+
+	level.Info(m.logger).Log("msg", "stopped scheduled module")
+	return nil
+}
+
+func (m *Scheduled) onUserDeleted(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.UserInfo)
+	return m.rep.DeleteScheduledStanzas(ctx, inf.Username)
+}
+
+func (m *Scheduled) scheduleStanza(ctx context.Context, iq *stravaganza.IQ, sch stravaganza.Element) error {
+	dueAt, err := time.Parse(time.RFC3339, sch.Attribute("stamp"))
+	if err != nil || sch.ChildrenCount() != 1 {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	if dueAt.After(time.Now().Add(m.cfg.MaxDelay)) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAcceptable))
+		return nil
+	}
+	msg, err := m.buildScheduledMessage(iq, sch.AllChildren()[0])
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	username := iq.FromJID().Node()
+	st := &scheduledmodel.Stanza{
+		Id:       uuid.New().String(),
+		Username: username,
+		Stanza:   msg.Proto(),
+		DueAt:    timestamppb.New(dueAt),
+	}
+	if err := m.rep.UpsertScheduledStanza(ctx, st); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	level.Info(m.logger).Log("msg", "scheduled stanza", "id", st.Id, "username", username, "due_at", dueAt)
+
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, stravaganza.NewBuilder("scheduled").
+		WithAttribute(stravaganza.Namespace, scheduledNamespace).
+		WithAttribute(stravaganza.ID, st.Id).
+		Build(),
+	))
+	return nil
+}
+
+func (m *Scheduled) buildScheduledMessage(iq *stravaganza.IQ, elem stravaganza.Element) (*stravaganza.Message, error) {
+	fromJID := iq.FromJID()
+
+	// scheduled stanzas are always sent on behalf of the requesting user
+	to := elem.Attribute(stravaganza.To)
+	if len(to) == 0 {
+		to = fromJID.ToBareJID().String()
+	}
+	return stravaganza.NewBuilderFromElement(elem).
+		WithAttribute(stravaganza.From, fromJID.ToBareJID().String()).
+		WithAttribute(stravaganza.To, to).
+		BuildMessage()
+}
+
+func (m *Scheduled) loop() {
+	tc := time.NewTicker(m.cfg.Interval)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Interval)
+			if err := m.dispatchDueStanzas(ctx); err != nil {
+				level.Warn(m.logger).Log("msg", "failed to dispatch scheduled stanzas", "err", err)
+			}
+			cancel()
+
+		case ch := <-m.doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (m *Scheduled) dispatchDueStanzas(ctx context.Context) error {
+	// holding the dispatch lock guarantees that a single cluster instance
+	// fires due stanzas at a time, hence each one of them is delivered only once.
+	if err := m.rep.Lock(ctx, dispatchLockID); err != nil {
+		if ctx.Err() != nil {
+			// lock is being held by another instance
+			level.Debug(m.logger).Log("msg", "scheduled stanzas dispatch lock not acquired")
+			return nil
+		}
+		return err
+	}
+	defer m.unlockDispatch()
+
+	sts, err := m.rep.FetchDueScheduledStanzas(ctx, time.Now(), m.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, st := range sts {
+		msg, err := stravaganza.NewBuilderFromProto(st.Stanza).BuildMessage()
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "discarded malformed scheduled stanza", "id", st.Id, "err", err)
+		} else {
+			_, _ = m.router.Route(ctx, msg)
+		}
+		// delete once routed, so that a stanza is never lost in case of failure
+		if err := m.rep.DeleteScheduledStanza(ctx, st.Id); err != nil {
+			return err
+		}
+		if msg != nil {
+			level.Info(m.logger).Log("msg", "dispatched scheduled stanza", "id", st.Id, "username", st.Username)
+		}
+	}
+	return nil
+}
+
+func (m *Scheduled) unlockDispatch() {
+	// dispatch context might have already expired at this point
+	ctx, cancel := context.WithTimeout(context.Background(), dispatchUnlockTimeout)
+	defer cancel()
+
+	if err := m.rep.Unlock(ctx, dispatchLockID); err != nil {
+		level.Warn(m.logger).Log("msg", "failed to release scheduled stanzas dispatch lock", "err", err)
+	}
+}
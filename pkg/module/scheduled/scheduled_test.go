@@ -0,0 +1,250 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduled
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestScheduled_ScheduleStanza(t *testing.T) {
+	// given
+	var stored *scheduledmodel.Stanza
+
+	repMock := &repositoryMock{}
+	repMock.UpsertScheduledStanzaFunc = func(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+		stored = stanza
+		return nil
+	}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := &Scheduled{
+		cfg:    Config{MaxDelay: time.Hour * 24},
+		rep:    repMock,
+		router: routerMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+	dueAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testScheduleIQ(dueAt))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+
+	sch := respStanzas[0].ChildNamespace("scheduled", scheduledNamespace)
+	require.NotNil(t, sch)
+
+	require.NotNil(t, stored)
+	require.Equal(t, sch.Attribute(stravaganza.ID), stored.Id)
+	require.Equal(t, "ortuman", stored.Username)
+	require.Equal(t, dueAt, stored.DueAt.AsTime())
+
+	msg, err := stravaganza.NewBuilderFromProto(stored.Stanza).BuildMessage()
+	require.NoError(t, err)
+	require.Equal(t, "ortuman@jackal.im", msg.Attribute(stravaganza.From))
+	require.Equal(t, "noelia@jackal.im", msg.Attribute(stravaganza.To))
+}
+
+func TestScheduled_ScheduleStanzaExceedingMaxDelay(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := &Scheduled{
+		cfg:    Config{MaxDelay: time.Minute},
+		rep:    repMock,
+		router: routerMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testScheduleIQ(time.Now().Add(time.Hour)))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+	require.Len(t, repMock.UpsertScheduledStanzaCalls(), 0)
+}
+
+func TestScheduled_DispatchDueStanzas(t *testing.T) {
+	// given
+	msg := testMessage()
+
+	var mu sync.Mutex
+	sts := map[string]*scheduledmodel.Stanza{
+		"sch1": {Id: "sch1", Username: "ortuman", Stanza: msg.Proto(), DueAt: timestamppb.New(time.Now().Add(time.Millisecond * 100))},
+		"sch2": {Id: "sch2", Username: "ortuman", Stanza: msg.Proto(), DueAt: timestamppb.New(time.Now().Add(time.Hour))},
+	}
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error { return nil }
+	repMock.FetchDueScheduledStanzasFunc = func(ctx context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		var retVal []*scheduledmodel.Stanza
+		for _, st := range sts {
+			if !st.DueAt.AsTime().After(t) {
+				retVal = append(retVal, st)
+			}
+		}
+		return retVal, nil
+	}
+	repMock.DeleteScheduledStanzaFunc = func(ctx context.Context, id string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(sts, id)
+		return nil
+	}
+	routerMock := &routerMock{}
+
+	var routed []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		routed = append(routed, stanza)
+		return nil, nil
+	}
+	m := New(Config{Interval: time.Millisecond * 20, BatchSize: 10}, routerMock, repMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	_ = m.Start(context.Background())
+
+	mu.Lock()
+	require.Len(t, routed, 0)
+	mu.Unlock()
+
+	time.Sleep(time.Millisecond * 300)
+
+	_ = m.Stop(context.Background())
+
+	// then
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, routed, 1)
+	require.Equal(t, "Remember the meeting", routed[0].Child("body").Text())
+
+	require.Len(t, sts, 1)
+	require.NotNil(t, sts["sch2"])
+
+	require.True(t, len(repMock.LockCalls()) > 0)
+	require.Len(t, repMock.LockCalls(), len(repMock.UnlockCalls()))
+}
+
+func TestScheduled_DispatchDueStanzasExpiredLockContext(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.LockFunc = func(ctx context.Context, lockID string) error { return nil }
+
+	var unlockCtxErr error
+	repMock.UnlockFunc = func(ctx context.Context, lockID string) error {
+		unlockCtxErr = ctx.Err()
+		return nil
+	}
+	var routed bool
+	repMock.DeleteScheduledStanzaFunc = func(ctx context.Context, id string) error {
+		if !routed {
+			return errors.New("deleted before being routed")
+		}
+		return nil
+	}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		routed = true
+		return nil, nil
+	}
+	m := New(Config{Interval: time.Second, BatchSize: 10}, routerMock, repMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repMock.FetchDueScheduledStanzasFunc = func(_ context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+		cancel() // dispatch context expires while holding the lock
+		return []*scheduledmodel.Stanza{
+			{Id: "sch1", Username: "ortuman", Stanza: testMessage().Proto()},
+		}, nil
+	}
+
+	// when
+	err := m.dispatchDueStanzas(ctx)
+
+	// then
+	require.Nil(t, err)
+	require.True(t, routed)
+	require.Len(t, repMock.UnlockCalls(), 1)
+	require.Nil(t, unlockCtxErr)
+}
+
+func testMessage() *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im").
+		WithAttribute(stravaganza.To, "noelia@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Remember the meeting").
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
+
+func testScheduleIQ(dueAt time.Time) *stravaganza.IQ {
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithAttribute(stravaganza.ID, "1001").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("schedule").
+				WithAttribute(stravaganza.Namespace, scheduledNamespace).
+				WithAttribute("stamp", dueAt.Format(time.RFC3339)).
+				WithChild(
+					stravaganza.NewBuilder("message").
+						WithAttribute(stravaganza.To, "noelia@jackal.im").
+						WithChild(
+							stravaganza.NewBuilder("body").
+								WithText("Remember the meeting").
+								Build(),
+						).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	return iq
+}
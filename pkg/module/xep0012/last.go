@@ -97,6 +97,11 @@ func (m *Last) MatchesNamespace(namespace string, _ bool) bool {
 	return namespace == lastActivityNamespace
 }
 
+// Namespaces returns all iq namespaces matched by last activity module.
+func (m *Last) Namespaces() []string {
+	return []string{lastActivityNamespace}
+}
+
 // ProcessIQ process a last activity info iq.
 func (m *Last) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
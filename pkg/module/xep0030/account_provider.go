@@ -79,9 +79,30 @@ func (p *accountProvider) Features(ctx context.Context, toJID, fromJID *jid.JID,
 			return nil, err
 		}
 		features = append(features, accFeatures...)
+
+		rfp, ok := mod.(ResourceFeaturesProvider)
+		if !ok {
+			continue
+		}
+		resFeatures, err := rfp.ResourceFeatures(ctx, toJID.Node())
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, resFeatures...)
 	}
 	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
-	return features, nil
+	return uniqueFeatures(features), nil
+}
+
+func uniqueFeatures(features []discomodel.Feature) []discomodel.Feature {
+	var ret []discomodel.Feature
+	for i, f := range features {
+		if i > 0 && f == features[i-1] {
+			continue
+		}
+		ret = append(ret, f)
+	}
+	return ret
 }
 
 func (p *accountProvider) Forms(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error) {
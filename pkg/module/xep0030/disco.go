@@ -28,9 +28,11 @@ import (
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/hook"
 	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/module/xep0004"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -39,7 +41,10 @@ const (
 	discoItemsNamespace = "http://jabber.org/protocol/disco#items"
 )
 
-var errSubscriptionRequired = errors.New("xep0030: subscription required")
+var (
+	errSubscriptionRequired = errors.New("xep0030: subscription required")
+	errItemNotFound         = errors.New("xep0030: item not found")
+)
 
 // InfoProvider represents a general entity disco info provider interface.
 type InfoProvider interface {
@@ -56,6 +61,27 @@ type InfoProvider interface {
 	Forms(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error)
 }
 
+// ItemsPager is implemented by info providers able to fetch a single page of items,
+// so that the whole item list doesn't need to be built to answer a paged disco items request.
+type ItemsPager interface {
+	// ItemsPage returns the page of items requested by req along with its result set.
+	ItemsPage(ctx context.Context, toJID, fromJID *jid.JID, node string, req *rsm.Request, cfg rsm.Config) ([]discomodel.Item, *rsm.Result, error)
+}
+
+// ResourceFeaturesProvider is implemented by modules reporting features advertised by the
+// available resources of an account, which are merged into the account disco info.
+type ResourceFeaturesProvider interface {
+	// ResourceFeatures returns the features advertised by username available resources.
+	ResourceFeatures(ctx context.Context, username string) ([]discomodel.Feature, error)
+}
+
+// ServerFormsProvider is implemented by modules publishing extended service discovery
+// information (XEP-0128) as part of the server disco info.
+type ServerFormsProvider interface {
+	// ServerForms returns the data forms to be included into server disco info.
+	ServerForms(ctx context.Context) ([]xep0004.DataForm, error)
+}
+
 const (
 	// ModuleName represents disco module name.
 	ModuleName = "disco"
@@ -64,8 +90,26 @@ const (
 	XEPNumber = "0030"
 )
 
+// Config contains disco module configuration options.
+type Config struct {
+	// DefaultPageSize defines the number of items returned per page when disco items are requested
+	// using result set management (XEP-0059) without specifying a maximum.
+	// If not set, the modules shared RSM default page size is used.
+	DefaultPageSize int `fig:"default_page_size"`
+
+	// MaxPageSize defines the maximum number of items returned per page
+	// when disco items are requested using result set management (XEP-0059).
+	// If not set, the modules shared RSM max page size is used.
+	MaxPageSize int `fig:"max_page_size"`
+
+	// ModuleMatrix, if true, every started module is listed under the server "urn:jackal:modules:0" disco node,
+	// along with the namespaces it matches and the features and identities it advertises.
+	ModuleMatrix bool `fig:"module_matrix"`
+}
+
 // Disco represents a disco info (XEP-0030) module type.
 type Disco struct {
+	cfg        Config
 	router     router.Router
 	components components
 	rosRep     repository.Roster
@@ -80,6 +124,7 @@ type Disco struct {
 
 // New returns a new initialized disco module instance.
 func New(
+	cfg Config,
 	router router.Router,
 	components *component.Components,
 	rosRep repository.Roster,
@@ -88,6 +133,7 @@ func New(
 	logger kitlog.Logger,
 ) *Disco {
 	return &Disco{
+		cfg:        cfg,
 		router:     router,
 		components: components,
 		rosRep:     rosRep,
@@ -107,7 +153,7 @@ func (m *Disco) StreamFeature(_ context.Context, _ string) (stravaganza.Element,
 
 // ServerFeatures returns server disco features.
 func (m *Disco) ServerFeatures(_ context.Context) ([]string, error) {
-	return []string{discoInfoNamespace, discoItemsNamespace}, nil
+	return []string{discoInfoNamespace, discoItemsNamespace, rsm.Namespace}, nil
 }
 
 // AccountFeatures returns account disco features.
@@ -120,6 +166,16 @@ func (m *Disco) MatchesNamespace(namespace string, _ bool) bool {
 	return namespace == discoInfoNamespace || namespace == discoItemsNamespace
 }
 
+// Namespaces returns all iq namespaces matched by disco module.
+func (m *Disco) Namespaces() []string {
+	return []string{discoInfoNamespace, discoItemsNamespace}
+}
+
+// Identities returns disco identities advertised by disco module on behalf of the server.
+func (m *Disco) Identities() []discomodel.Identity {
+	return []discomodel.Identity{serverIdentity}
+}
+
 // ProcessIQ process a disco info iq.
 func (m *Disco) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -162,11 +218,13 @@ func (m *Disco) AccountProvider() InfoProvider {
 }
 
 func (m *Disco) onModulesStarted(ctx context.Context, execCtx *hook.ExecutionContext) error {
-	mods := execCtx.Sender.(modules)
-
+	mods := execCtx.Sender.(modules).AllModules()
+	if inf, ok := execCtx.Info.(*hook.ModulesInfo); ok {
+		mods = startedModules(mods, inf.ModuleNames) // leave out non-critical modules that failed to start
+	}
 	m.mu.Lock()
-	m.srvProv = newServerProvider(mods.AllModules(), m.components)
-	m.accProv = newAccountProvider(mods.AllModules(), m.rosRep, m.resMng)
+	m.srvProv = newServerProvider(mods, m.components, m.cfg.ModuleMatrix)
+	m.accProv = newAccountProvider(mods, m.rosRep, m.resMng)
 	m.mu.Unlock()
 
 	_, err := m.hk.Run(ctx, hook.DiscoProvidersStarted, &hook.ExecutionContext{
@@ -224,6 +282,9 @@ func (m *Disco) sendDiscoInfo(ctx context.Context, prov InfoProvider, toJID, fro
 	}
 	sb := stravaganza.NewBuilder("query").
 		WithAttribute(stravaganza.Namespace, discoInfoNamespace)
+	if len(node) > 0 {
+		sb.WithAttribute("node", node)
+	}
 
 	identities := prov.Identities(ctx, toJID, fromJID, node)
 	for _, identity := range identities {
@@ -257,13 +318,25 @@ func (m *Disco) sendDiscoInfo(ctx context.Context, prov InfoProvider, toJID, fro
 }
 
 func (m *Disco) sendDiscoItems(ctx context.Context, prov InfoProvider, toJID, fromJID *jid.JID, node string, iq *stravaganza.IQ) error {
-	items, err := prov.Items(ctx, toJID, fromJID, node)
+	rsmReq, err := rsm.ParseRequest(iq.Child("query"))
+	switch {
+	case errors.Is(err, rsm.ErrUnsupportedRequest):
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.FeatureNotImplemented))
+		return nil
+	case err != nil:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	items, rsmRes, err := m.fetchItems(ctx, prov, toJID, fromJID, node, rsmReq)
 	switch {
 	case err == nil:
 		break
 	case errors.Is(err, errSubscriptionRequired):
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.SubscriptionRequired))
 		return nil
+	case errors.Is(err, errItemNotFound):
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.ItemNotFound))
+		return nil
 	default:
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
 		return err
@@ -282,6 +355,96 @@ func (m *Disco) sendDiscoItems(ctx context.Context, prov InfoProvider, toJID, fr
 		}
 		qb.WithChild(itemB.Build())
 	}
+	if rsmRes != nil {
+		qb.WithChild(rsmRes.Element())
+	}
 	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, qb.Build()))
 	return nil
 }
+
+func (m *Disco) fetchItems(ctx context.Context, prov InfoProvider, toJID, fromJID *jid.JID, node string, rsmReq *rsm.Request) ([]discomodel.Item, *rsm.Result, error) {
+	if rsmReq == nil {
+		items, err := prov.Items(ctx, toJID, fromJID, node)
+		return items, nil, err
+	}
+	rsmCfg := rsm.Config{
+		DefaultPageSize: m.cfg.DefaultPageSize,
+		MaxPageSize:     m.cfg.MaxPageSize,
+	}
+	// let the provider materialize only the requested page, whenever possible
+	if pager, ok := prov.(ItemsPager); ok {
+		return pager.ItemsPage(ctx, toJID, fromJID, node, rsmReq, rsmCfg)
+	}
+	items, err := prov.Items(ctx, toJID, fromJID, node)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pageItems(itemUIDs(items), func(i int) discomodel.Item { return items[i] }, rsmReq, rsmCfg)
+}
+
+func startedModules(mods []module.Module, startedNames []string) []module.Module {
+	started := make(map[string]struct{}, len(startedNames))
+	for _, name := range startedNames {
+		started[name] = struct{}{}
+	}
+	var res []module.Module
+	for _, mod := range mods {
+		if _, ok := started[mod.Name()]; ok {
+			res = append(res, mod)
+		}
+	}
+	return res
+}
+
+// pageItems returns the page of items requested by req, where uids contains the ordered UIDs of all items
+// and itemAt returns the item at a given position, so that only page items need to be built.
+func pageItems(uids []string, itemAt func(i int) discomodel.Item, req *rsm.Request, cfg rsm.Config) ([]discomodel.Item, *rsm.Result, error) {
+	res := &rsm.Result{Count: len(uids)}
+	if req.CountOnly {
+		return nil, res, nil
+	}
+	start := 0
+	if len(req.After) > 0 {
+		start = -1
+		for i, uid := range uids {
+			if uid == req.After {
+				start = i + 1
+				break
+			}
+		}
+		if start == -1 {
+			return nil, nil, errItemNotFound
+		}
+	}
+	end := len(uids)
+	if limit := req.PageSize(cfg); limit > 0 && start+limit < end {
+		end = start + limit
+	}
+	if start == end {
+		return nil, res, nil
+	}
+	page := make([]discomodel.Item, 0, end-start)
+	for i := start; i < end; i++ {
+		page = append(page, itemAt(i))
+	}
+	res.First = uids[start]
+	res.Last = uids[end-1]
+	return page, res, nil
+}
+
+func itemUIDs(items []discomodel.Item) []string {
+	uids := make([]string, 0, len(items))
+	for _, item := range items {
+		uids = append(uids, itemUID(item))
+	}
+	return uids
+}
+
+// itemUID returns the result set UID of item.
+// Node is taken into account, since items sharing the same JID may be published under different nodes.
+func itemUID(item discomodel.Item) string {
+	if len(item.Node) > 0 {
+		return item.Jid + "#" + item.Node
+	}
+	return item.Jid
+}
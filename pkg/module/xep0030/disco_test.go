@@ -25,8 +25,11 @@ import (
 	"github.com/ortuman/jackal/pkg/component"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
 	"github.com/ortuman/jackal/pkg/module"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 	"github.com/stretchr/testify/require"
 )
 
@@ -90,7 +93,80 @@ func TestDisco_GetServerInfo(t *testing.T) {
 	require.Equal(t, "jackal", identity.Attribute("name"))
 
 	features := query.Children("feature")
-	require.Len(t, features, 4)
+	require.Len(t, features, 5)
+}
+
+func TestDisco_GetServerInfoExtensions(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.ServerFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"urn:xmpp:http:upload:0"}, nil
+	}
+	extMod := &extendedInfoModule{
+		moduleMock: modMock,
+		identities: []discomodel.Identity{{Category: "store", Type: "file", Name: "HTTP File Upload"}},
+		forms: []xep0004.DataForm{{
+			Type: xep0004.Result,
+			Fields: xep0004.Fields{
+				{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{"urn:xmpp:http:upload:0"}},
+				{Var: "max-file-size", Values: []string{"5242880"}},
+			},
+		}},
+	}
+
+	routerMock := &routerMock{}
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	d := &Disco{
+		router: routerMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = d.Start(context.Background())
+	defer func() { _ = d.Stop(context.Background()) }()
+
+	modsMock := &modulesMock{}
+	modsMock.AllModulesFunc = func() []module.Module {
+		return []module.Module{extMod, d}
+	}
+	_, _ = hk.Run(context.Background(), hook.ModulesStarted, &hook.ExecutionContext{
+		Sender: modsMock,
+	})
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				Build(),
+		).
+		BuildIQ()
+	_ = d.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+
+	query := respStanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+
+	identities := query.Children("identity")
+	require.Len(t, identities, 2) // server identity is not duplicated
+	require.Equal(t, "server", identities[0].Attribute("category"))
+	require.Equal(t, "store", identities[1].Attribute("category"))
+	require.Equal(t, "file", identities[1].Attribute("type"))
+
+	form, err := xep0004.NewFormFromElement(query.ChildNamespace("x", xep0004.FormNamespace))
+	require.NoError(t, err)
+	require.Equal(t, "urn:xmpp:http:upload:0", form.Fields.ValueForFieldOfType(xep0004.FormType, xep0004.Hidden))
+	require.Equal(t, "5242880", form.Fields.ValueForField("max-file-size"))
 }
 
 func TestDisco_GetServerItems(t *testing.T) {
@@ -161,6 +237,100 @@ func TestDisco_GetServerItems(t *testing.T) {
 	require.Equal(t, "host.jackal.im", items[0].Attribute("jid"))
 }
 
+func TestDisco_GetModuleMatrix(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.NameFunc = func() string { return "m0" }
+	modMock.ServerFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"https://jackal.im#feature-1"}, nil
+	}
+	modMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) { return nil, nil }
+
+	routerMock := &routerMock{}
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	d := &Disco{
+		cfg:    Config{ModuleMatrix: true},
+		router: routerMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = d.Start(context.Background())
+	defer func() { _ = d.Stop(context.Background()) }()
+
+	modsMock := &modulesMock{}
+	modsMock.AllModulesFunc = func() []module.Module {
+		return []module.Module{modMock, d}
+	}
+	_, _ = hk.Run(context.Background(), hook.ModulesStarted, &hook.ExecutionContext{
+		Sender: modsMock,
+	})
+
+	discoIQ := func(namespace, node string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "id1234").
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("query").
+					WithAttribute(stravaganza.Namespace, namespace).
+					WithAttribute("node", node).
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// when
+	_ = d.ProcessIQ(context.Background(), discoIQ(discoItemsNamespace, modulesNode))
+	_ = d.ProcessIQ(context.Background(), discoIQ(discoInfoNamespace, modulesNode+"#disco"))
+	_ = d.ProcessIQ(context.Background(), discoIQ(discoInfoNamespace, modulesNode+"#m0"))
+
+	// then
+	require.Len(t, respStanzas, 3)
+
+	items := respStanzas[0].ChildNamespace("query", discoItemsNamespace).Children("item")
+	require.Len(t, items, 2)
+	require.Equal(t, "m0", items[0].Attribute("name"))
+	require.Equal(t, "urn:jackal:modules:0#m0", items[0].Attribute("node"))
+	require.Equal(t, "disco", items[1].Attribute("name"))
+	require.Equal(t, "jackal.im", items[1].Attribute("jid"))
+
+	query := respStanzas[1].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+	require.Equal(t, "urn:jackal:modules:0#disco", query.Attribute("node"))
+
+	identity := query.Child("identity")
+	require.NotNil(t, identity)
+	require.Equal(t, "server", identity.Attribute("category"))
+
+	var features []string
+	for _, f := range query.Children("feature") {
+		features = append(features, f.Attribute("var"))
+	}
+	require.Equal(t, []string{discoInfoNamespace, discoItemsNamespace, rsm.Namespace}, features)
+
+	form, err := xep0004.NewFormFromElement(query.ChildNamespace("x", xep0004.FormNamespace))
+	require.NoError(t, err)
+	require.Len(t, form.Fields, 4)
+	require.Equal(t, "namespaces", form.Fields[1].Var)
+	require.Equal(t, []string{discoInfoNamespace, discoItemsNamespace}, form.Fields[1].Values)
+
+	// module with no identities falls back to server identity
+	query = respStanzas[2].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+
+	identities := query.Children("identity")
+	require.Len(t, identities, 1)
+	require.Equal(t, "server", identities[0].Attribute("category"))
+	require.Equal(t, "im", identities[0].Attribute("type"))
+}
+
 func TestDisco_GetAccountInfo(t *testing.T) {
 	// given
 	modMock := &moduleMock{}
@@ -304,3 +474,206 @@ func TestDisco_GetAccountItems(t *testing.T) {
 
 	require.Equal(t, "noelia@jackal.im/chamber", items[0].Attribute("jid"))
 }
+
+func TestDisco_PageItems(t *testing.T) {
+	// given
+	items := []discomodel.Item{
+		{Jid: "a.jackal.im"},
+		{Jid: "b.jackal.im"},
+		{Jid: "c.jackal.im"},
+		{Jid: "d.jackal.im"},
+		{Jid: "e.jackal.im"},
+	}
+
+	// when
+	page1, res1, _ := testPageItems(items, &rsm.Request{Max: 2}, rsm.Config{})
+	page2, res2, _ := testPageItems(items, &rsm.Request{Max: 2, After: res1.Last}, rsm.Config{})
+	page3, res3, _ := testPageItems(items, &rsm.Request{Max: 2, After: res2.Last}, rsm.Config{})
+	page4, _, _ := testPageItems(items, &rsm.Request{Max: 2, After: res3.Last}, rsm.Config{})
+
+	// then
+	require.Equal(t, []discomodel.Item{{Jid: "a.jackal.im"}, {Jid: "b.jackal.im"}}, page1)
+	require.Equal(t, []discomodel.Item{{Jid: "c.jackal.im"}, {Jid: "d.jackal.im"}}, page2)
+	require.Equal(t, []discomodel.Item{{Jid: "e.jackal.im"}}, page3)
+	require.Len(t, page4, 0)
+
+	require.Equal(t, "a.jackal.im", res1.First)
+	require.Equal(t, "b.jackal.im", res1.Last)
+	require.Equal(t, 5, res1.Count)
+}
+
+func TestDisco_PageItemsBounded(t *testing.T) {
+	// given
+	items := []discomodel.Item{
+		{Jid: "a.jackal.im"},
+		{Jid: "b.jackal.im"},
+		{Jid: "c.jackal.im"},
+		{Jid: "d.jackal.im"},
+		{Jid: "e.jackal.im"},
+	}
+	cfg := rsm.Config{DefaultPageSize: 2, MaxPageSize: 3}
+
+	// when
+	clampedPage, _, _ := testPageItems(items, &rsm.Request{Max: 10000}, cfg)
+	defaultPage, _, _ := testPageItems(items, &rsm.Request{}, cfg)
+
+	// then
+	require.Len(t, clampedPage, 3)
+	require.Len(t, defaultPage, 2)
+}
+
+func TestDisco_PageItemsCountOnly(t *testing.T) {
+	// given
+	items := []discomodel.Item{
+		{Jid: "a.jackal.im"},
+		{Jid: "b.jackal.im"},
+		{Jid: "c.jackal.im"},
+	}
+
+	// when
+	page, res, err := testPageItems(items, &rsm.Request{CountOnly: true}, rsm.Config{DefaultPageSize: 2})
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, page, 0)
+	require.Equal(t, &rsm.Result{Count: 3}, res)
+}
+
+func TestDisco_PageItemsUnknownAfter(t *testing.T) {
+	// given
+	items := []discomodel.Item{
+		{Jid: "a.jackal.im"},
+		{Jid: "b.jackal.im"},
+	}
+
+	// when
+	page, res, err := testPageItems(items, &rsm.Request{After: "z.jackal.im"}, rsm.Config{})
+
+	// then
+	require.Equal(t, errItemNotFound, err)
+	require.Nil(t, page)
+	require.Nil(t, res)
+}
+
+func TestDisco_PageItemsSameJIDNodes(t *testing.T) {
+	// given
+	items := []discomodel.Item{
+		{Jid: "jackal.im", Node: modulesNode + "#disco"},
+		{Jid: "jackal.im", Node: modulesNode + "#roster"},
+	}
+
+	// when
+	page1, res1, _ := testPageItems(items, &rsm.Request{Max: 1}, rsm.Config{})
+	page2, _, _ := testPageItems(items, &rsm.Request{Max: 1, After: res1.Last}, rsm.Config{})
+
+	// then
+	require.Equal(t, items[:1], page1)
+	require.Equal(t, items[1:], page2)
+}
+
+func TestDisco_GetServerItemsPage(t *testing.T) {
+	// given
+	compsMock := &componentsMock{}
+	compsMock.AllComponentsFunc = func() []component.Component {
+		var comps []component.Component
+		for _, host := range []string{"c.jackal.im", "a.jackal.im", "b.jackal.im"} {
+			host := host
+			compMock := &componentMock{}
+			compMock.HostFunc = func() string { return host }
+			compMock.NameFunc = func() string { return "" }
+			comps = append(comps, compMock)
+		}
+		return comps
+	}
+	prov := newServerProvider(nil, compsMock, false)
+
+	// when
+	page, res, err := prov.ItemsPage(context.Background(), nil, nil, "", &rsm.Request{Max: 1, After: "a.jackal.im"}, rsm.Config{})
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []discomodel.Item{{Jid: "b.jackal.im"}}, page)
+	require.Equal(t, &rsm.Result{First: "b.jackal.im", Last: "b.jackal.im", Count: 3}, res)
+}
+
+func testPageItems(items []discomodel.Item, req *rsm.Request, cfg rsm.Config) ([]discomodel.Item, *rsm.Result, error) {
+	return pageItems(itemUIDs(items), func(i int) discomodel.Item { return items[i] }, req, cfg)
+}
+
+func TestDisco_GetAccountResourceFeatures(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return []string{"urn:xmpp:ping"}, nil
+	}
+	resFeaturesModMock := &resourceFeaturesModuleMock{}
+	resFeaturesModMock.AccountFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return nil, nil
+	}
+	resFeaturesModMock.ResourceFeaturesFunc = func(_ context.Context, _ string) ([]string, error) {
+		return []string{"urn:xmpp:jingle:1", "urn:xmpp:ping"}, nil
+	}
+
+	routerMock := &routerMock{}
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	d := &Disco{
+		router: routerMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	_ = d.Start(context.Background())
+	defer func() { _ = d.Stop(context.Background()) }()
+
+	modsMock := &modulesMock{}
+	modsMock.AllModulesFunc = func() []module.Module {
+		return []module.Module{modMock, resFeaturesModMock}
+	}
+	_, _ = hk.Run(context.Background(), hook.ModulesStarted, &hook.ExecutionContext{
+		Sender: modsMock,
+	})
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				Build(),
+		).
+		BuildIQ()
+	_ = d.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+
+	query := respStanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, query)
+
+	features := query.Children("feature")
+	require.Len(t, features, 2)
+	require.Equal(t, "urn:xmpp:jingle:1", features[0].Attribute("var"))
+	require.Equal(t, "urn:xmpp:ping", features[1].Attribute("var"))
+
+	require.Len(t, resFeaturesModMock.ResourceFeaturesCalls(), 1)
+	require.Equal(t, "ortuman", resFeaturesModMock.ResourceFeaturesCalls()[0].Username)
+}
+
+type extendedInfoModule struct {
+	*moduleMock
+	identities []discomodel.Identity
+	forms      []xep0004.DataForm
+}
+
+func (m *extendedInfoModule) Identities() []discomodel.Identity { return m.identities }
+
+func (m *extendedInfoModule) ServerForms(_ context.Context) ([]xep0004.DataForm, error) {
+	return m.forms, nil
+}
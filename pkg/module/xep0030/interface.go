@@ -15,8 +15,11 @@
 package xep0030
 
 import (
+	"context"
+
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/component"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
@@ -56,3 +59,9 @@ type discoComponent interface {
 type discoModule interface {
 	module.Module
 }
+
+//go:generate moq -out resourcefeaturesmodule.mock_test.go . resourceFeaturesModule:resourceFeaturesModuleMock
+type resourceFeaturesModule interface {
+	module.Module
+	ResourceFeatures(ctx context.Context, username string) ([]discomodel.Feature, error)
+}
@@ -17,33 +17,67 @@ package xep0030
 import (
 	"context"
 	"sort"
+	"strings"
 
+	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	discomodel "github.com/ortuman/jackal/pkg/model/disco"
 	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/util/rsm"
 )
 
+// modulesNode is the server disco node under which the module matrix is published.
+const modulesNode = "urn:jackal:modules:0"
+
+var serverIdentity = discomodel.Identity{Type: "im", Category: "server", Name: "jackal"}
+
 type serverProvider struct {
-	mods  []module.Module
-	comps components
+	mods      []module.Module
+	comps     components
+	modMatrix bool
 }
 
 func newServerProvider(
 	mods []module.Module,
 	comps components,
+	modMatrix bool,
 ) *serverProvider {
 	return &serverProvider{
-		mods:  mods,
-		comps: comps,
+		mods:      mods,
+		comps:     comps,
+		modMatrix: modMatrix,
 	}
 }
 
-func (p *serverProvider) Identities(_ context.Context, _, _ *jid.JID, _ string) []discomodel.Identity {
-	return []discomodel.Identity{{Type: "im", Category: "server", Name: "jackal"}}
+func (p *serverProvider) Identities(ctx context.Context, _, _ *jid.JID, node string) []discomodel.Identity {
+	if mod, ok := p.nodeModule(node); ok {
+		inf, err := module.Inspect(ctx, mod)
+		if err != nil || len(inf.Identities) == 0 {
+			// a disco info response must contain at least one identity
+			return []discomodel.Identity{serverIdentity}
+		}
+		return inf.Identities
+	}
+	identities := []discomodel.Identity{serverIdentity}
+	for _, mod := range p.mods {
+		ip, ok := mod.(module.IdentitiesProvider)
+		if !ok {
+			continue
+		}
+		for _, identity := range ip.Identities() {
+			if !containsIdentity(identities, identity) {
+				identities = append(identities, identity)
+			}
+		}
+	}
+	return identities
 }
 
-func (p *serverProvider) Items(_ context.Context, _, _ *jid.JID, _ string) ([]discomodel.Item, error) {
+func (p *serverProvider) Items(_ context.Context, toJID, _ *jid.JID, node string) ([]discomodel.Item, error) {
+	if p.modMatrix && node == modulesNode {
+		return p.moduleItems(toJID), nil
+	}
 	var items []discomodel.Item
 	for _, comp := range p.comps.AllComponents() {
 		items = append(items, discomodel.Item{
@@ -55,7 +89,35 @@ func (p *serverProvider) Items(_ context.Context, _, _ *jid.JID, _ string) ([]di
 	return items, nil
 }
 
-func (p *serverProvider) Features(ctx context.Context, _, _ *jid.JID, _ string) ([]discomodel.Feature, error) {
+func (p *serverProvider) ItemsPage(_ context.Context, toJID, _ *jid.JID, node string, req *rsm.Request, cfg rsm.Config) ([]discomodel.Item, *rsm.Result, error) {
+	if p.modMatrix && node == modulesNode {
+		items := p.moduleItems(toJID)
+		return pageItems(itemUIDs(items), func(i int) discomodel.Item { return items[i] }, req, cfg)
+	}
+	comps := p.comps.AllComponents()
+
+	// sort component hosts, building only the items belonging to requested page
+	hosts := make([]string, 0, len(comps))
+	compIdx := make(map[string]int, len(comps))
+	for i, comp := range comps {
+		hosts = append(hosts, comp.Host())
+		compIdx[comp.Host()] = i
+	}
+	sort.Strings(hosts)
+
+	return pageItems(hosts, func(i int) discomodel.Item {
+		comp := comps[compIdx[hosts[i]]]
+		return discomodel.Item{
+			Jid:  comp.Host(),
+			Name: comp.Name(),
+		}
+	}, req, cfg)
+}
+
+func (p *serverProvider) Features(ctx context.Context, toJID, _ *jid.JID, node string) ([]discomodel.Feature, error) {
+	if mod, ok := p.nodeModule(node); ok {
+		return moduleFeatures(ctx, mod)
+	}
 	var features []discomodel.Feature
 	for _, mod := range p.mods {
 		srvFeatures, err := mod.ServerFeatures(ctx)
@@ -64,10 +126,135 @@ func (p *serverProvider) Features(ctx context.Context, _, _ *jid.JID, _ string)
 		}
 		features = append(features, srvFeatures...)
 	}
+	if module.CompactsStreamFeatures(p.mods) {
+		// advertise namespaces of the stream features left out of stream negotiation
+		sfFeatures, err := p.streamFeatures(ctx, toJID.Domain())
+		if err != nil {
+			return nil, err
+		}
+		features = append(features, sfFeatures...)
+	}
 	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return dedupFeatures(features), nil
+}
+
+func (p *serverProvider) streamFeatures(ctx context.Context, domain string) ([]discomodel.Feature, error) {
+	var features []discomodel.Feature
+	for _, mod := range p.mods {
+		if c, ok := mod.(module.StreamFeaturesCompactor); ok && c.CompactsStreamFeatures() {
+			continue
+		}
+		sf, err := mod.StreamFeature(ctx, domain)
+		if err != nil {
+			return nil, err
+		}
+		if sf == nil {
+			continue
+		}
+		if ns := sf.Attribute(stravaganza.Namespace); len(ns) > 0 {
+			features = append(features, ns)
+		}
+	}
 	return features, nil
 }
 
-func (p *serverProvider) Forms(ctx context.Context, toJID, fromJID *jid.JID, node string) ([]xep0004.DataForm, error) {
-	return nil, nil
+func (p *serverProvider) Forms(ctx context.Context, _, _ *jid.JID, node string) ([]xep0004.DataForm, error) {
+	if len(node) == 0 {
+		return p.serverForms(ctx)
+	}
+	mod, ok := p.nodeModule(node)
+	if !ok {
+		return nil, nil
+	}
+	inf, err := module.Inspect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	return []xep0004.DataForm{{
+		Type: xep0004.Result,
+		Fields: xep0004.Fields{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{modulesNode}},
+			{Var: "namespaces", Type: xep0004.TextMulti, Values: inf.Namespaces},
+			{Var: "server_features", Type: xep0004.TextMulti, Values: inf.ServerFeatures},
+			{Var: "account_features", Type: xep0004.TextMulti, Values: inf.AccountFeatures},
+		},
+	}}, nil
+}
+
+func (p *serverProvider) serverForms(ctx context.Context) ([]xep0004.DataForm, error) {
+	var forms []xep0004.DataForm
+	for _, mod := range p.mods {
+		fp, ok := mod.(ServerFormsProvider)
+		if !ok {
+			continue
+		}
+		modForms, err := fp.ServerForms(ctx)
+		if err != nil {
+			return nil, err
+		}
+		forms = append(forms, modForms...)
+	}
+	return forms, nil
+}
+
+func (p *serverProvider) moduleItems(toJID *jid.JID) []discomodel.Item {
+	items := make([]discomodel.Item, 0, len(p.mods))
+	for _, mod := range p.mods {
+		items = append(items, discomodel.Item{
+			Jid:  toJID.Domain(),
+			Name: mod.Name(),
+			Node: modulesNode + "#" + mod.Name(),
+		})
+	}
+	return items
+}
+
+// nodeModule returns the module whose capabilities are published under node, if any.
+func (p *serverProvider) nodeModule(node string) (module.Module, bool) {
+	if !p.modMatrix || !strings.HasPrefix(node, modulesNode+"#") {
+		return nil, false
+	}
+	modName := strings.TrimPrefix(node, modulesNode+"#")
+	for _, mod := range p.mods {
+		if mod.Name() == modName {
+			return mod, true
+		}
+	}
+	return nil, false
+}
+
+func moduleFeatures(ctx context.Context, mod module.Module) ([]discomodel.Feature, error) {
+	inf, err := module.Inspect(ctx, mod)
+	if err != nil {
+		return nil, err
+	}
+	var features []discomodel.Feature
+	features = append(features, inf.Namespaces...)
+	features = append(features, inf.ServerFeatures...)
+	features = append(features, inf.AccountFeatures...)
+
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return dedupFeatures(features), nil
+}
+
+func dedupFeatures(features []discomodel.Feature) []discomodel.Feature {
+	if len(features) == 0 {
+		return features
+	}
+	res := features[:1]
+	for _, f := range features[1:] {
+		if f != res[len(res)-1] {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func containsIdentity(identities []discomodel.Identity, identity discomodel.Identity) bool {
+	for _, i := range identities {
+		if i == identity {
+			return true
+		}
+	}
+	return false
 }
@@ -23,6 +23,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
@@ -38,12 +39,19 @@ const (
 	XEPNumber = "0049"
 )
 
+const (
+	getPrivateCounter   = "private_get"
+	setPrivateCounter   = "private_set"
+	privateErrorCounter = "private_error"
+)
+
 // Private represents a private (XEP-0049) module type.
 type Private struct {
-	router router.Router
-	rep    repository.Private
-	hk     *hook.Hooks
-	logger kitlog.Logger
+	router  router.Router
+	rep     repository.Private
+	hk      *hook.Hooks
+	metrics *module.Metrics
+	logger  kitlog.Logger
 }
 
 // New returns a new initialized Private instance.
@@ -51,13 +59,15 @@ func New(
 	router router.Router,
 	rep repository.Private,
 	hk *hook.Hooks,
+	metrics *module.Metrics,
 	logger kitlog.Logger,
 ) *Private {
 	return &Private{
-		rep:    rep,
-		router: router,
-		hk:     hk,
-		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		rep:     rep,
+		router:  router,
+		hk:      hk,
+		metrics: metrics,
+		logger:  kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
 	}
 }
 
@@ -83,6 +93,11 @@ func (m *Private) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == privateNamespace
 }
 
+// Namespaces returns all iq namespaces matched by private module.
+func (m *Private) Namespaces() []string {
+	return []string{privateNamespace}
+}
+
 // ProcessIQ process a private iq.
 func (m *Private) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	fromJid := iq.FromJID()
@@ -95,8 +110,10 @@ func (m *Private) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	q := iq.ChildNamespace("query", privateNamespace)
 	switch {
 	case iq.IsGet() && q != nil:
+		m.metrics.IncCounter(getPrivateCounter)
 		return m.getPrivate(ctx, iq, q)
 	case iq.IsSet() && q != nil:
+		m.metrics.IncCounter(setPrivateCounter)
 		return m.setPrivate(ctx, iq, q)
 	default:
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
@@ -143,6 +160,7 @@ func (m *Private) getPrivate(ctx context.Context, iq *stravaganza.IQ, q stravaga
 	prvElem, err := m.rep.FetchPrivate(ctx, ns, username)
 	if err != nil {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		m.metrics.IncCounter(privateErrorCounter)
 		return err
 	}
 	level.Info(m.logger).Log("msg", "fetched private XML", "username", username, "namespace", ns)
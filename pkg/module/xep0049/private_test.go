@@ -22,6 +22,8 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -88,6 +90,51 @@ func TestPrivate_GetPrivate(t *testing.T) {
 	require.Equal(t, reqNS, "exodus:prefs")
 }
 
+func TestPrivate_GetPrivateMetrics(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchPrivateFunc = func(ctx context.Context, namespace, username string) (stravaganza.Element, error) {
+		return nil, nil
+	}
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, nil
+	}
+	metrics := module.NewMetrics(ModuleName)
+	initialCount := testutil.ToFloat64(metrics.Counter(getPrivateCounter))
+
+	// when
+	p := &Private{
+		rep:     repMock,
+		router:  routerMock,
+		hk:      hook.NewHooks(),
+		metrics: metrics,
+		logger:  kitlog.NewNopLogger(),
+	}
+	reqIQ, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithAttribute(stravaganza.ID, "1001").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, privateNamespace).
+				WithChild(
+					stravaganza.NewBuilder("exodus").
+						WithAttribute(stravaganza.Namespace, "exodus:prefs").
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+
+	_ = p.ProcessIQ(context.Background(), reqIQ)
+
+	// then
+	require.Equal(t, initialCount+1, testutil.ToFloat64(metrics.Counter(getPrivateCounter)))
+	require.Equal(t, float64(0), testutil.ToFloat64(metrics.Counter(setPrivateCounter)))
+}
+
 func TestPrivate_SetPrivate(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
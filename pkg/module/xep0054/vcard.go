@@ -16,6 +16,9 @@ package xep0054
 
 import (
 	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
 
 	kitlog "github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -37,8 +40,16 @@ const (
 	XEPNumber = "0054"
 )
 
+// Config contains vCard module configuration options.
+type Config struct {
+	// PhotoMaxSize defines the maximum size in bytes a vCard photo may have.
+	// A value of zero means no limit.
+	PhotoMaxSize int `fig:"photo_max_size" default:"262144"`
+}
+
 // VCard represents a vCard (XEP-0054) module type.
 type VCard struct {
+	cfg    Config
 	rep    repository.VCard
 	router router.Router
 	hk     *hook.Hooks
@@ -47,12 +58,14 @@ type VCard struct {
 
 // New returns a new initialized VCard instance.
 func New(
+	cfg Config,
 	router router.Router,
 	rep repository.Repository,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *VCard {
 	return &VCard{
+		cfg:    cfg,
 		router: router,
 		rep:    rep,
 		hk:     hk,
@@ -83,6 +96,11 @@ func (m *VCard) MatchesNamespace(namespace string, _ bool) bool {
 	return namespace == vCardNamespace
 }
 
+// Namespaces returns all iq namespaces matched by vCard module.
+func (m *VCard) Namespaces() []string {
+	return []string{vCardNamespace}
+}
+
 // ProcessIQ process a vCard iq.
 func (m *VCard) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -144,6 +162,7 @@ func (m *VCard) getVCard(ctx context.Context, iq *stravaganza.IQ) error {
 	_, err = m.hk.Run(ctx, hook.VCardFetched, &hook.ExecutionContext{
 		Info: &hook.VCardInfo{
 			Username: toJID.Node(),
+			Host:     toJID.Domain(),
 			VCard:    vCard,
 		},
 		Sender: m,
@@ -165,6 +184,10 @@ func (m *VCard) setVCard(ctx context.Context, iq *stravaganza.IQ) error {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
 		return nil
 	}
+	if !m.isValidPhoto(vCard) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAcceptable))
+		return nil
+	}
 	err := m.rep.UpsertVCard(ctx, vCard, toJID.Node())
 	if err != nil {
 		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
@@ -178,9 +201,42 @@ func (m *VCard) setVCard(ctx context.Context, iq *stravaganza.IQ) error {
 	_, err = m.hk.Run(ctx, hook.VCardUpdated, &hook.ExecutionContext{
 		Info: &hook.VCardInfo{
 			Username: toJID.Node(),
+			Host:     toJID.Domain(),
 			VCard:    vCard,
 		},
 		Sender: m,
 	})
 	return err
 }
+
+func (m *VCard) isValidPhoto(vCard stravaganza.Element) bool {
+	photo := vCard.Child("PHOTO")
+	if photo == nil {
+		return true
+	}
+	binVal := photo.Child("BINVAL")
+	if binVal == nil {
+		return true // external photo reference
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(binVal.Text()), ""))
+	if err != nil {
+		return false
+	}
+	if m.cfg.PhotoMaxSize > 0 && len(data) > m.cfg.PhotoMaxSize {
+		return false
+	}
+	// sniff actual image format
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return false
+	}
+	typ := photo.Child("TYPE")
+	if typ == nil || len(strings.TrimSpace(typ.Text())) == 0 {
+		return true
+	}
+	declaredType := strings.ToLower(strings.TrimSpace(typ.Text()))
+	if declaredType == "image/jpg" {
+		declaredType = "image/jpeg"
+	}
+	return declaredType == contentType
+}
@@ -16,6 +16,7 @@ package xep0054
 
 import (
 	"context"
+	"encoding/base64"
 	"testing"
 
 	kitlog "github.com/go-kit/log"
@@ -142,3 +143,97 @@ func TestVCard_SetVCard(t *testing.T) {
 	require.Equal(t, stravaganza.ResultType, resIQ.Attribute("type"))
 	require.Len(t, resIQ.AllChildren(), 0)
 }
+
+func TestVCard_SetVCardInvalidPhoto(t *testing.T) {
+	pngData := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+	var tests = []struct {
+		name          string
+		photoType     string
+		photoData     []byte
+		expectedError string
+	}{
+		{
+			name:          "Valid",
+			photoType:     "image/png",
+			photoData:     pngData,
+			expectedError: "",
+		},
+		{
+			name:          "Oversized",
+			photoType:     "image/png",
+			photoData:     append(pngData, make([]byte, 128)...),
+			expectedError: "not-acceptable",
+		},
+		{
+			name:          "TypeMismatch",
+			photoType:     "image/jpeg",
+			photoData:     pngData,
+			expectedError: "not-acceptable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			repMock := &repositoryMock{}
+			repMock.UpsertVCardFunc = func(ctx context.Context, vCard stravaganza.Element, username string) error {
+				return nil
+			}
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+
+			v := &VCard{
+				cfg:    Config{PhotoMaxSize: 128},
+				rep:    repMock,
+				router: routerMock,
+				hk:     hook.NewHooks(),
+				logger: kitlog.NewNopLogger(),
+			}
+			// when
+			iq, _ := stravaganza.NewIQBuilder().
+				WithAttribute(stravaganza.ID, "id1234").
+				WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+				WithAttribute(stravaganza.To, "ortuman@jackal.im").
+				WithAttribute(stravaganza.Type, stravaganza.SetType).
+				WithChild(
+					stravaganza.NewBuilder("vCard").
+						WithAttribute(stravaganza.Namespace, vCardNamespace).
+						WithChild(
+							stravaganza.NewBuilder("PHOTO").
+								WithChild(
+									stravaganza.NewBuilder("TYPE").
+										WithText(tt.photoType).
+										Build(),
+								).
+								WithChild(
+									stravaganza.NewBuilder("BINVAL").
+										WithText(base64.StdEncoding.EncodeToString(tt.photoData)).
+										Build(),
+								).
+								Build(),
+						).
+						Build(),
+				).
+				BuildIQ()
+			_ = v.ProcessIQ(context.Background(), iq)
+
+			// then
+			require.Len(t, respStanzas, 1)
+
+			resIQ := respStanzas[0]
+			if len(tt.expectedError) > 0 {
+				require.Equal(t, stravaganza.ErrorType, resIQ.Attribute(stravaganza.Type))
+				require.NotNil(t, resIQ.Child("error").Child(tt.expectedError))
+				require.Len(t, repMock.UpsertVCardCalls(), 0)
+			} else {
+				require.Equal(t, stravaganza.ResultType, resIQ.Attribute(stravaganza.Type))
+				require.Len(t, repMock.UpsertVCardCalls(), 1)
+			}
+		})
+	}
+}
@@ -47,6 +47,33 @@ const (
 type Config struct {
 	// ShowOS tells whether OS info should be revealed or not.
 	ShowOS bool `fig:"show_os"`
+
+	// Hosts contains per host software version overrides.
+	Hosts []HostConfig `fig:"hosts"`
+}
+
+// HostConfig contains host specific software version options.
+type HostConfig struct {
+	// Domain is the host domain the override applies to.
+	Domain string `fig:"domain"`
+
+	// Name overrides the reported software name.
+	Name string `fig:"name"`
+
+	// Version overrides the reported software version.
+	Version string `fig:"version"`
+
+	// HideOS tells whether OS info should be suppressed for this host, regardless of ShowOS value.
+	HideOS bool `fig:"hide_os"`
+}
+
+func (c *Config) hostConfig(domain string) *HostConfig {
+	for i := range c.Hosts {
+		if c.Hosts[i].Domain == domain {
+			return &c.Hosts[i]
+		}
+	}
+	return nil
 }
 
 // Version represents a version (XEP-0092) module type.
@@ -92,6 +119,11 @@ func (v *Version) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == versionNamespace
 }
 
+// Namespaces returns all iq namespaces matched by version module.
+func (v *Version) Namespaces() []string {
+	return []string{versionNamespace}
+}
+
 // ProcessIQ process a version iq.
 func (v *Version) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -122,20 +154,33 @@ func (v *Version) getVersion(ctx context.Context, iq *stravaganza.IQ) error {
 		_, _ = v.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
 		return nil
 	}
+	name := "jackal"
+	ver := strings.TrimPrefix(version.Version.String(), "v")
+	showOS := v.cfg.ShowOS
+
+	if hc := v.cfg.hostConfig(iq.ToJID().Domain()); hc != nil {
+		if len(hc.Name) > 0 {
+			name = hc.Name
+		}
+		if len(hc.Version) > 0 {
+			ver = hc.Version
+		}
+		showOS = showOS && !hc.HideOS
+	}
 	// send version info
 	qb := stravaganza.NewBuilder("query")
 	qb.WithAttribute(stravaganza.Namespace, versionNamespace)
 	qb.WithChild(
 		stravaganza.NewBuilder("name").
-			WithText("jackal").
+			WithText(name).
 			Build(),
 	)
 	qb.WithChild(
 		stravaganza.NewBuilder("version").
-			WithText(strings.TrimPrefix(version.Version.String(), "v")).
+			WithText(ver).
 			Build(),
 	)
-	if v.cfg.ShowOS {
+	if showOS {
 		qb.WithChild(
 			stravaganza.NewBuilder("os").
 				WithText(v.osInfo).
@@ -95,3 +95,95 @@ func TestVersion_GetVersion(t *testing.T) {
 	require.Equal(t, strings.TrimPrefix(version.Version.String(), "v"), ver.Text())
 	require.Equal(t, "Darwin 12.2.0", os.Text())
 }
+
+func TestVersion_GetVersionHostOverride(t *testing.T) {
+	// given
+	getOSInfo = func(ctx context.Context) string {
+		return "Darwin 12.2.0"
+	}
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	v := &Version{
+		cfg: Config{
+			ShowOS: true,
+			Hosts: []HostConfig{
+				{Domain: "jabber.org", Name: "jabberd", Version: "2.7.0"},
+				{Domain: "jackal.im", Name: "jackal-im", Version: "1.0.0", HideOS: true},
+			},
+		},
+		router: routerMock,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, versionNamespace).
+				Build(),
+		).
+		BuildIQ()
+
+	_ = v.Start(context.Background())
+	_ = v.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+
+	query := respStanzas[0].ChildNamespace("query", versionNamespace)
+	require.NotNil(t, query)
+
+	require.Equal(t, "jackal-im", query.Child("name").Text())
+	require.Equal(t, "1.0.0", query.Child("version").Text())
+	require.Nil(t, query.Child("os"))
+}
+
+func TestVersion_GetVersionHideOS(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	v := &Version{
+		cfg:    Config{ShowOS: false},
+		router: routerMock,
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, versionNamespace).
+				Build(),
+		).
+		BuildIQ()
+
+	_ = v.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+
+	query := respStanzas[0].ChildNamespace("query", versionNamespace)
+	require.NotNil(t, query)
+
+	require.Equal(t, "jackal", query.Child("name").Text())
+	require.Equal(t, strings.TrimPrefix(version.Version.String(), "v"), query.Child("version").Text())
+	require.Nil(t, query.Child("os"))
+}
@@ -0,0 +1,136 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0115
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+)
+
+const (
+	unionAccountFeatures        = "union"
+	intersectionAccountFeatures = "intersection"
+)
+
+type accountFeatures struct {
+	sig      string
+	features []discomodel.Feature
+}
+
+// ResourceFeatures returns the features advertised through entity capabilities by the available
+// resources of username, combined according to the configured account features mode.
+func (m *Capabilities) ResourceFeatures(ctx context.Context, username string) ([]discomodel.Feature, error) {
+	if m.cfg.AccountFeatures != unionAccountFeatures && m.cfg.AccountFeatures != intersectionAccountFeatures {
+		return nil, nil
+	}
+	rss, err := m.resMng.GetResources(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	var cis []capsInfo
+	var sigs []string
+	for _, res := range rss {
+		ci, ok := resourceCapsInfo(res)
+		if !ok {
+			continue
+		}
+		cis = append(cis, ci)
+		sigs = append(sigs, res.JID().Resource()+"|"+ci.node+"#"+ci.ver)
+	}
+	if len(cis) == 0 {
+		m.invalidateAccountFeatures(username)
+		return nil, nil
+	}
+	sort.Strings(sigs)
+	sig := strings.Join(sigs, "\n")
+
+	// return cached features in case online resources presence didn't change
+	m.accMu.RLock()
+	af, ok := m.accFeatures[username]
+	m.accMu.RUnlock()
+	if ok && af.sig == sig {
+		return af.features, nil
+	}
+	var capsList []*capsmodel.Capabilities
+	complete := true
+	for _, ci := range cis {
+		caps, err := m.rep.FetchCapabilities(ctx, ci.node, ci.ver)
+		if err != nil {
+			return nil, err
+		}
+		if caps == nil {
+			complete = false // not yet verified
+			continue
+		}
+		capsList = append(capsList, caps)
+	}
+	features := combineFeatures(capsList, m.cfg.AccountFeatures == intersectionAccountFeatures)
+
+	if complete {
+		m.accMu.Lock()
+		m.accFeatures[username] = accountFeatures{sig: sig, features: features}
+		m.accMu.Unlock()
+	}
+	return features, nil
+}
+
+func (m *Capabilities) invalidateAccountFeatures(username string) {
+	m.accMu.Lock()
+	delete(m.accFeatures, username)
+	m.accMu.Unlock()
+}
+
+func resourceCapsInfo(res c2smodel.ResourceDesc) (capsInfo, bool) {
+	if !res.IsAvailable() {
+		return capsInfo{}, false
+	}
+	caps := res.Presence().ChildNamespace("c", capabilitiesFeature)
+	if caps == nil {
+		return capsInfo{}, false
+	}
+	return capsInfo{
+		hash: caps.Attribute("hash"),
+		node: caps.Attribute("node"),
+		ver:  caps.Attribute("ver"),
+	}, true
+}
+
+func combineFeatures(capsList []*capsmodel.Capabilities, intersect bool) []discomodel.Feature {
+	counts := make(map[string]int)
+	for _, caps := range capsList {
+		seen := make(map[string]struct{}, len(caps.Features))
+		for _, f := range caps.Features {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			counts[f]++
+		}
+	}
+	var features []discomodel.Feature
+	for f, cnt := range counts {
+		if intersect && cnt != len(capsList) {
+			continue
+		}
+		features = append(features, f)
+	}
+	sort.Strings(features)
+	return features
+}
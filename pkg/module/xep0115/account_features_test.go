@@ -0,0 +1,120 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0115
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities_ResourceFeatures(t *testing.T) {
+	// given
+	var rss []c2smodel.ResourceDesc
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return rss, nil
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchCapabilitiesFunc = func(_ context.Context, node string, ver string) (*capsmodel.Capabilities, error) {
+		switch ver {
+		case "v1":
+			return &capsmodel.Capabilities{Node: node, Ver: ver, Features: []string{"urn:xmpp:ping", "urn:xmpp:receipts"}}, nil
+		case "v2":
+			return &capsmodel.Capabilities{Node: node, Ver: ver, Features: []string{"urn:xmpp:jingle:1", "urn:xmpp:ping"}}, nil
+		}
+		return nil, nil
+	}
+	c := &Capabilities{
+		cfg:         Config{AccountFeatures: unionAccountFeatures},
+		resMng:      resMngMock,
+		rep:         repMock,
+		accFeatures: make(map[string]accountFeatures),
+	}
+
+	// when
+	rss = []c2smodel.ResourceDesc{testResourceDesc("ortuman@jackal.im/yard", "v1")}
+	fs1, err1 := c.ResourceFeatures(context.Background(), "ortuman")
+
+	rss = append(rss, testResourceDesc("ortuman@jackal.im/balcony", "v2"))
+	fs2, err2 := c.ResourceFeatures(context.Background(), "ortuman")
+	fs3, err3 := c.ResourceFeatures(context.Background(), "ortuman") // cached
+
+	rss = rss[:1]
+	fs4, err4 := c.ResourceFeatures(context.Background(), "ortuman")
+
+	// then
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.NoError(t, err3)
+	require.NoError(t, err4)
+
+	require.Equal(t, []string{"urn:xmpp:ping", "urn:xmpp:receipts"}, fs1)
+	require.Equal(t, []string{"urn:xmpp:jingle:1", "urn:xmpp:ping", "urn:xmpp:receipts"}, fs2)
+	require.Equal(t, fs2, fs3)
+	require.Equal(t, []string{"urn:xmpp:ping", "urn:xmpp:receipts"}, fs4)
+
+	require.Len(t, repMock.FetchCapabilitiesCalls(), 4)
+}
+
+func TestCapabilities_ResourceFeaturesIntersection(t *testing.T) {
+	// given
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourcesFunc = func(_ context.Context, _ string) ([]c2smodel.ResourceDesc, error) {
+		return []c2smodel.ResourceDesc{
+			testResourceDesc("ortuman@jackal.im/yard", "v1"),
+			testResourceDesc("ortuman@jackal.im/balcony", "v2"),
+		}, nil
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchCapabilitiesFunc = func(_ context.Context, node string, ver string) (*capsmodel.Capabilities, error) {
+		if ver == "v1" {
+			return &capsmodel.Capabilities{Node: node, Ver: ver, Features: []string{"urn:xmpp:ping", "urn:xmpp:receipts"}}, nil
+		}
+		return &capsmodel.Capabilities{Node: node, Ver: ver, Features: []string{"urn:xmpp:jingle:1", "urn:xmpp:ping"}}, nil
+	}
+	c := &Capabilities{
+		cfg:         Config{AccountFeatures: intersectionAccountFeatures},
+		resMng:      resMngMock,
+		rep:         repMock,
+		accFeatures: make(map[string]accountFeatures),
+	}
+
+	// when
+	fs, err := c.ResourceFeatures(context.Background(), "ortuman")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, []string{"urn:xmpp:ping"}, fs)
+}
+
+func testResourceDesc(jidStr, ver string) c2smodel.ResourceDesc {
+	jd, _ := jid.NewWithString(jidStr, true)
+	cElem := stravaganza.NewBuilder("c").
+		WithAttribute(stravaganza.Namespace, capabilitiesFeature).
+		WithAttribute("hash", "sha-1").
+		WithAttribute("node", "http://dino.im").
+		WithAttribute("ver", ver).
+		Build()
+	pr := xmpputil.MakePresence(jd, jd.ToBareJID(), stravaganza.AvailableType, []stravaganza.Element{cElem})
+	return c2smodel.NewResourceDesc("inst-1", jd, pr, c2smodel.NewInfoMap())
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/hook"
 	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
 	discomodel "github.com/ortuman/jackal/pkg/model/disco"
@@ -42,6 +43,7 @@ import (
 	"github.com/ortuman/jackal/pkg/module/xep0030"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/ortuman/jackal/pkg/util/iqtracker"
 )
 
 const (
@@ -82,33 +84,55 @@ const (
 	XEPNumber = "0115"
 )
 
+// Config contains entity capabilities module configuration options.
+type Config struct {
+	// AccountFeatures specifies how features advertised by the available resources of an account
+	// are combined into the account disco info. Valid values are "union", "intersection" and "none".
+	AccountFeatures string `fig:"account_features" default:"union"`
+
+	// CompactStreamFeatures, if true, advertises the server entity capabilities as the only module stream feature.
+	// Namespaces of the omitted stream features are advertised through server disco info instead,
+	// so that clients not recognizing the capabilities hash can resolve the full feature set.
+	CompactStreamFeatures bool `fig:"compact_stream_features"`
+}
+
 // Capabilities represents entity capabilities (XEP-0115) module type.
 type Capabilities struct {
+	cfg    Config
 	router router.Router
+	resMng resourcemanager.Manager
 	rep    repository.Capabilities
 	hk     *hook.Hooks
 	logger kitlog.Logger
+	reqs   *iqtracker.Tracker
 
 	mu      sync.RWMutex
-	reqs    map[string]capsInfo
-	clrTms  map[string]*time.Timer
 	srvProv xep0030.InfoProvider
+
+	accMu       sync.RWMutex
+	accFeatures map[string]accountFeatures
 }
 
 // New creates and initializes a new Capabilities instance.
+// Disco info requests left unanswered after iqTimeout are discarded.
 func New(
+	cfg Config,
+	iqTimeout time.Duration,
 	router router.Router,
+	resMng resourcemanager.Manager,
 	rep repository.Capabilities,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Capabilities {
 	return &Capabilities{
-		router: router,
-		rep:    rep,
-		hk:     hk,
-		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
-		reqs:   make(map[string]capsInfo),
-		clrTms: make(map[string]*time.Timer),
+		cfg:         cfg,
+		router:      router,
+		resMng:      resMng,
+		rep:         rep,
+		hk:          hk,
+		logger:      kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		reqs:        iqtracker.New(iqTimeout),
+		accFeatures: make(map[string]accountFeatures),
 	}
 }
 
@@ -138,6 +162,12 @@ func (m *Capabilities) StreamFeature(ctx context.Context, domain string) (strava
 		Build(), nil
 }
 
+// CompactsStreamFeatures tells whether module stream features should be replaced by
+// the server entity capabilities element.
+func (m *Capabilities) CompactsStreamFeatures() bool {
+	return m.cfg.CompactStreamFeatures
+}
+
 // ServerFeatures returns entity capabilities module server disco features.
 func (m *Capabilities) ServerFeatures(_ context.Context) ([]string, error) {
 	return nil, nil
@@ -168,6 +198,8 @@ func (m *Capabilities) Stop(_ context.Context) error {
 	m.hk.RemoveHook(hook.S2SInStreamIQReceived, m.onS2SIQRecv)
 	m.hk.RemoveHook(hook.DiscoProvidersStarted, m.onDiscoProvidersStarted)
 
+	m.reqs.Stop()
+
 	level.Info(m.logger).Log("msg", "stopped capabilities module")
 	return nil
 }
@@ -175,6 +207,9 @@ func (m *Capabilities) Stop(_ context.Context) error {
 func (m *Capabilities) onC2SPresenceRecv(ctx context.Context, execCtx *hook.ExecutionContext) error {
 	inf := execCtx.Info.(*hook.C2SStreamInfo)
 	pr := inf.Element.(*stravaganza.Presence)
+	if pr.ToJID().IsBare() && pr.FromJID().IsFull() {
+		m.invalidateAccountFeatures(pr.FromJID().Node()) // presence broadcast
+	}
 	return m.processPresence(ctx, pr)
 }
 
@@ -235,22 +270,11 @@ func (m *Capabilities) processPresence(ctx context.Context, pr *stravaganza.Pres
 }
 
 func (m *Capabilities) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
-	reqID := iq.Attribute(stravaganza.ID)
-
-	m.mu.Lock()
-	if tm := m.clrTms[reqID]; tm != nil {
-		tm.Stop()
-	}
-	nv, ok := m.reqs[reqID]
+	nv, ok := m.reqs.Resolve(iq.Attribute(stravaganza.ID))
 	if !ok {
-		m.mu.Unlock()
 		return nil
 	}
-	delete(m.reqs, reqID)
-	delete(m.clrTms, reqID)
-	m.mu.Unlock()
-
-	if err := m.processDiscoInfo(ctx, iq, nv); err != nil {
+	if err := m.processDiscoInfo(ctx, iq, nv.(capsInfo)); err != nil {
 		level.Warn(m.logger).Log("msg", "failed to verify disco info", "err", err)
 	}
 	return nil
@@ -258,13 +282,7 @@ func (m *Capabilities) processIQ(ctx context.Context, iq *stravaganza.IQ) error
 
 func (m *Capabilities) requestDiscoInfo(ctx context.Context, fromJID, toJID *jid.JID, ci capsInfo) {
 	reqID := uuid.New().String()
-
-	m.mu.Lock()
-	m.reqs[reqID] = ci
-	m.clrTms[reqID] = time.AfterFunc(time.Minute, func() {
-		m.clearPendingReq(reqID) // discard pending request
-	})
-	m.mu.Unlock()
+	m.reqs.Track(reqID, ci, nil) // discarded if left unanswered
 
 	discoIQ, _ := stravaganza.NewIQBuilder().
 		WithAttribute(stravaganza.ID, reqID).
@@ -340,13 +358,6 @@ func (m *Capabilities) processDiscoInfo(ctx context.Context, iq *stravaganza.IQ,
 	return nil
 }
 
-func (m *Capabilities) clearPendingReq(reqID string) {
-	m.mu.Lock()
-	delete(m.reqs, reqID)
-	delete(m.clrTms, reqID)
-	m.mu.Unlock()
-}
-
 func validateIdentities(identities []discomodel.Identity) error {
 	ids := make(map[string]int, len(identities))
 	for _, identity := range identities {
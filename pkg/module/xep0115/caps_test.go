@@ -17,6 +17,7 @@ package xep0115
 import (
 	"context"
 	"crypto/sha1"
+	"crypto/sha256"
 	"testing"
 	"time"
 
@@ -27,7 +28,10 @@ import (
 	"github.com/ortuman/jackal/pkg/hook"
 	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
 	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/module/xep0030"
+	"github.com/ortuman/jackal/pkg/util/iqtracker"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 	"github.com/stretchr/testify/require"
 )
@@ -52,8 +56,7 @@ func TestCapabilities_RequestDiscoInfo(t *testing.T) {
 		router: routerMock,
 		hk:     hk,
 		logger: kitlog.NewNopLogger(),
-		reqs:   make(map[string]capsInfo),
-		clrTms: make(map[string]*time.Timer),
+		reqs:   iqtracker.New(time.Minute),
 	}
 	// when
 	_ = c.Start(context.Background())
@@ -104,14 +107,13 @@ func TestCapabilities_ProcessDiscoInfo(t *testing.T) {
 		router: routerMock,
 		hk:     hk,
 		logger: kitlog.NewNopLogger(),
-		reqs:   make(map[string]capsInfo),
-		clrTms: make(map[string]*time.Timer),
+		reqs:   iqtracker.New(time.Minute),
 	}
-	c.reqs["id1234"] = capsInfo{
+	c.reqs.Track("id1234", capsInfo{
 		node: "http://dino.im",
 		ver:  "14j4+I88rSOWIY4WwJiIYgYqXrI=",
 		hash: "sha-1",
-	}
+	}, nil)
 
 	discoIQ, _ := stravaganza.NewBuilder("iq").
 		WithAttribute(stravaganza.ID, "id1234").
@@ -154,6 +156,172 @@ func TestCapabilities_ProcessDiscoInfo(t *testing.T) {
 	require.Len(t, recvCaps.Features, 2)
 }
 
+func TestCapabilities_DiscoInfoRequestTimeout(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.CapabilitiesExistFunc = func(ctx context.Context, node string, ver string) (bool, error) {
+		return false, nil
+	}
+	repMock.UpsertCapabilitiesFunc = func(ctx context.Context, caps *capsmodel.Capabilities) error {
+		return nil
+	}
+	routerMock := &routerMock{}
+
+	var reqID string
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		reqID = stanza.Attribute(stravaganza.ID)
+		return nil, nil
+	}
+
+	hk := hook.NewHooks()
+	c := &Capabilities{
+		rep:    repMock,
+		router: routerMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+		reqs:   iqtracker.New(time.Millisecond * 50),
+	}
+	// when
+	_ = c.Start(context.Background())
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	jd0, _ := jid.NewWithString("noelia@jackal.im/yard", true)
+	jd1, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	cElem := stravaganza.NewBuilder("c").
+		WithAttribute(stravaganza.Namespace, capabilitiesFeature).
+		WithAttribute("hash", "sha-1").
+		WithAttribute("node", "http://dino.im").
+		WithAttribute("ver", "14j4+I88rSOWIY4WwJiIYgYqXrI=").
+		Build()
+
+	pr := xmpputil.MakePresence(jd0, jd1, stravaganza.AvailableType, []stravaganza.Element{cElem})
+	_, _ = hk.Run(context.Background(), hook.C2SStreamPresenceReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: pr,
+		},
+	})
+	pendingReqs := c.reqs.Len()
+
+	time.Sleep(time.Millisecond * 150) // wait until request times out
+
+	discoIQ, _ := stravaganza.NewBuilder("iq").
+		WithAttribute(stravaganza.ID, reqID).
+		WithAttribute(stravaganza.Type, stravaganza.ResultType).
+		WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				WithChild(
+					stravaganza.NewBuilder("feature").
+						WithAttribute("var", "http://jabber.org/protocol/disco#info").
+						Build(),
+				).
+				WithChild(
+					stravaganza.NewBuilder("feature").
+						WithAttribute("var", "http://jabber.org/protocol/disco#items").
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+
+	_, _ = hk.Run(context.Background(), hook.C2SStreamIQReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: discoIQ,
+		},
+	})
+
+	// then
+	require.Equal(t, 1, pendingReqs)
+	require.Equal(t, 0, c.reqs.Len())
+	require.Len(t, repMock.UpsertCapabilitiesCalls(), 0)
+}
+
+func TestCapabilities_CompactStreamFeatures(t *testing.T) {
+	// given
+	modMock := &moduleMock{}
+	modMock.NameFunc = func() string { return "stream" }
+	modMock.StreamFeatureFunc = func(_ context.Context, _ string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("sm").
+			WithAttribute(stravaganza.Namespace, "urn:xmpp:sm:3").
+			Build(), nil
+	}
+	modMock.ServerFeaturesFunc = func(_ context.Context) ([]string, error) {
+		return nil, nil
+	}
+
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+
+	hk := hook.NewHooks()
+	c := &Capabilities{
+		cfg:    Config{CompactStreamFeatures: true},
+		router: routerMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+		reqs:   iqtracker.New(time.Minute),
+	}
+	d := xep0030.New(xep0030.Config{}, routerMock, nil, nil, nil, hk, kitlog.NewNopLogger())
+
+	_ = c.Start(context.Background())
+	defer func() { _ = c.Stop(context.Background()) }()
+
+	_ = d.Start(context.Background())
+	defer func() { _ = d.Stop(context.Background()) }()
+
+	mods := module.NewModules([]module.Module{modMock, c, d}, nil, nil, routerMock, hk, kitlog.NewNopLogger())
+	_, _ = hk.Run(context.Background(), hook.ModulesStarted, &hook.ExecutionContext{
+		Sender: mods,
+	})
+
+	// when
+	sfs, _ := mods.StreamFeatures(context.Background(), "jackal.im")
+
+	require.Len(t, sfs, 1)
+	cElem := sfs[0]
+	node := cElem.Attribute("node") + "#" + cElem.Attribute("ver")
+
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "id1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithChild(
+			stravaganza.NewBuilder("query").
+				WithAttribute(stravaganza.Namespace, discoInfoNamespace).
+				WithAttribute("node", node).
+				Build(),
+		).
+		BuildIQ()
+	_ = d.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Equal(t, "c", cElem.Name())
+	require.Equal(t, capabilitiesFeature, cElem.Attribute(stravaganza.Namespace))
+
+	require.Len(t, respStanzas, 1)
+
+	q := respStanzas[0].ChildNamespace("query", discoInfoNamespace)
+	require.NotNil(t, q)
+	require.Equal(t, node, q.Attribute("node"))
+
+	var features []discomodel.Feature
+	for _, f := range q.Children("feature") {
+		features = append(features, f.Attribute("var"))
+	}
+	require.Contains(t, features, "urn:xmpp:sm:3")
+
+	identities := []discomodel.Identity{{Type: "im", Category: "server", Name: "jackal"}}
+	require.Equal(t, computeVer(identities, features, nil, sha256.New), cElem.Attribute("ver"))
+}
+
 func TestCapabilities_ComputeSimpleVerificationString(t *testing.T) {
 	// given
 	identities := []discomodel.Identity{
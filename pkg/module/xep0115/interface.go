@@ -15,6 +15,8 @@
 package xep0115
 
 import (
+	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/storage/repository"
 )
@@ -28,3 +30,13 @@ type capsRepository interface {
 type globalRouter interface {
 	router.Router
 }
+
+//go:generate moq -out resourcemanager.mock_test.go . resourceManager
+type resourceManager interface {
+	resourcemanager.Manager
+}
+
+//go:generate moq -out module.mock_test.go . capsModule:moduleMock
+type capsModule interface {
+	module.Module
+}
@@ -0,0 +1,314 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0172
+
+import (
+	"context"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	pubSubNamespace      = "http://jabber.org/protocol/pubsub"
+	pubSubEventNamespace = "http://jabber.org/protocol/pubsub#event"
+	nickNamespace        = "http://jabber.org/protocol/nick"
+	vCardNamespace       = "vcard-temp"
+)
+
+const (
+	// ModuleName represents user nickname module name.
+	ModuleName = "nick"
+
+	// XEPNumber represents user nickname XEP number.
+	XEPNumber = "0172"
+)
+
+// Nick represents a user nickname (XEP-0172) module type.
+//
+// Nickname PEP node is backed by vCard NICKNAME field, so that clients using either
+// mechanism will observe consistent data.
+type Nick struct {
+	router router.Router
+	rep    repository.Repository
+	hk     *hook.Hooks
+	logger kitlog.Logger
+}
+
+// New returns a new initialized Nick instance.
+func New(
+	router router.Router,
+	rep repository.Repository,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *Nick {
+	return &Nick{
+		router: router,
+		rep:    rep,
+		hk:     hk,
+		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+	}
+}
+
+// Name returns user nickname module name.
+func (m *Nick) Name() string { return ModuleName }
+
+// StreamFeature returns user nickname module stream feature.
+func (m *Nick) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns user nickname server disco features.
+func (m *Nick) ServerFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// AccountFeatures returns user nickname account disco features.
+func (m *Nick) AccountFeatures(_ context.Context) ([]string, error) {
+	return []string{nickNamespace}, nil
+}
+
+// MatchesNamespace tells whether namespace matches user nickname module.
+func (m *Nick) MatchesNamespace(namespace string, serverTarget bool) bool {
+	return namespace == pubSubNamespace && !serverTarget
+}
+
+// MatchesIQ tells whether iq addresses the user nickname pubsub node.
+func (m *Nick) MatchesIQ(iq *stravaganza.IQ) bool {
+	ps := iq.ChildNamespace("pubsub", pubSubNamespace)
+	if ps == nil {
+		return false
+	}
+	return isNickNode(ps.Child("items")) || isNickNode(ps.Child("publish"))
+}
+
+// Namespaces returns all iq namespaces matched by user nickname module.
+func (m *Nick) Namespaces() []string {
+	return []string{pubSubNamespace}
+}
+
+// ProcessIQ process a user nickname iq.
+func (m *Nick) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	ps := iq.ChildNamespace("pubsub", pubSubNamespace)
+	if ps == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	switch {
+	case iq.IsGet() && isNickNode(ps.Child("items")):
+		return m.getNick(ctx, iq)
+	case iq.IsSet() && isNickNode(ps.Child("publish")):
+		return m.publishNick(ctx, iq, ps.Child("publish"))
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.FeatureNotImplemented))
+		return nil
+	}
+}
+
+// Start starts user nickname module.
+func (m *Nick) Start(_ context.Context) error {
+	m.hk.AddHook(hook.VCardUpdated, m.onVCardUpdated, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started nick module")
+	return nil
+}
+
+// Stop stops user nickname module.
+func (m *Nick) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.VCardUpdated, m.onVCardUpdated)
+
+	level.Info(m.logger).Log("msg", "stopped nick module")
+	return nil
+}
+
+func (m *Nick) onVCardUpdated(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	if execCtx.Sender == m {
+		return nil // avoid re-publishing our own updates
+	}
+	inf := execCtx.Info.(*hook.VCardInfo)
+	if inf.VCard == nil || len(inf.Username) == 0 {
+		return nil
+	}
+	nick := inf.VCard.Child("NICKNAME")
+	if nick == nil {
+		return nil
+	}
+	userJID, err := jid.New(inf.Username, inf.Host, "", true)
+	if err != nil {
+		return err
+	}
+	return m.notifyNick(ctx, userJID, nick.Text())
+}
+
+func (m *Nick) getNick(ctx context.Context, iq *stravaganza.IQ) error {
+	fromJID := iq.FromJID()
+	toJID := iq.ToJID()
+
+	if fromJID.Node() != toJID.Node() {
+		// only presence subscribed contacts are allowed to retrieve user nickname
+		ri, err := m.rep.FetchRosterItem(ctx, toJID.Node(), fromJID.ToBareJID().String())
+		if err != nil {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+			return err
+		}
+		if ri == nil || (ri.Subscription != rostermodel.From && ri.Subscription != rostermodel.Both) {
+			_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAuthorized))
+			return nil
+		}
+	}
+	vCard, err := m.rep.FetchVCard(ctx, toJID.Node())
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	itemsB := stravaganza.NewBuilder("items").
+		WithAttribute("node", nickNamespace)
+
+	if vCard != nil {
+		if nick := vCard.Child("NICKNAME"); nick != nil {
+			itemsB.WithChild(nickItem(nick.Text()))
+		}
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, stravaganza.NewBuilder("pubsub").
+		WithAttribute(stravaganza.Namespace, pubSubNamespace).
+		WithChild(itemsB.Build()).
+		Build(),
+	))
+	level.Info(m.logger).Log("msg", "fetched nick", "username", fromJID.Node(), "nick_owner", toJID.Node())
+	return nil
+}
+
+func (m *Nick) publishNick(ctx context.Context, iq *stravaganza.IQ, publish stravaganza.Element) error {
+	fromJID := iq.FromJID()
+	toJID := iq.ToJID()
+	if fromJID.Node() != toJID.Node() {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+		return nil
+	}
+	var nick stravaganza.Element
+	if item := publish.Child("item"); item != nil {
+		nick = item.ChildNamespace("nick", nickNamespace)
+	}
+	if nick == nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+	username := toJID.Node()
+
+	// update vCard NICKNAME field
+	vCard, err := m.rep.FetchVCard(ctx, username)
+	if err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	vCard = setVCardNickname(vCard, nick.Text())
+
+	if err := m.rep.UpsertVCard(ctx, vCard, username); err != nil {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.InternalServerError))
+		return err
+	}
+	level.Info(m.logger).Log("msg", "published nick", "username", username)
+
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, nil))
+
+	if err := m.notifyNick(ctx, toJID.ToBareJID(), nick.Text()); err != nil {
+		return err
+	}
+	// run vCard updated hook
+	_, err = m.hk.Run(ctx, hook.VCardUpdated, &hook.ExecutionContext{
+		Info: &hook.VCardInfo{
+			Username: username,
+			Host:     toJID.Domain(),
+			VCard:    vCard,
+		},
+		Sender: m,
+	})
+	return err
+}
+
+func (m *Nick) notifyNick(ctx context.Context, userJID *jid.JID, nick string) error {
+	items, err := m.rep.FetchRosterItems(ctx, userJID.Node())
+	if err != nil {
+		return err
+	}
+	recipients := []*jid.JID{userJID}
+	for _, itm := range items {
+		if itm.Subscription != rostermodel.From && itm.Subscription != rostermodel.Both {
+			continue
+		}
+		contactJID, err := jid.NewWithString(itm.Jid, true)
+		if err != nil {
+			continue
+		}
+		recipients = append(recipients, contactJID)
+	}
+	for _, recipient := range recipients {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.From, userJID.String()).
+			WithAttribute(stravaganza.To, recipient.String()).
+			WithAttribute(stravaganza.Type, stravaganza.HeadlineType).
+			WithChild(
+				stravaganza.NewBuilder("event").
+					WithAttribute(stravaganza.Namespace, pubSubEventNamespace).
+					WithChild(
+						stravaganza.NewBuilder("items").
+							WithAttribute("node", nickNamespace).
+							WithChild(nickItem(nick)).
+							Build(),
+					).
+					Build(),
+			).
+			BuildMessage()
+		_, _ = m.router.Route(ctx, msg)
+	}
+	return nil
+}
+
+func setVCardNickname(vCard stravaganza.Element, nick string) stravaganza.Element {
+	var b *stravaganza.Builder
+	if vCard != nil {
+		b = stravaganza.NewBuilderFromElement(vCard).WithoutChildren("NICKNAME")
+	} else {
+		b = stravaganza.NewBuilder("vCard").WithAttribute(stravaganza.Namespace, vCardNamespace)
+	}
+	return b.WithChild(
+		stravaganza.NewBuilder("NICKNAME").
+			WithText(nick).
+			Build(),
+	).Build()
+}
+
+func nickItem(nick string) stravaganza.Element {
+	return stravaganza.NewBuilder("item").
+		WithChild(
+			stravaganza.NewBuilder("nick").
+				WithAttribute(stravaganza.Namespace, nickNamespace).
+				WithText(nick).
+				Build(),
+		).
+		Build()
+}
+
+func isNickNode(elem stravaganza.Element) bool {
+	return elem != nil && elem.Attribute("node") == nickNamespace
+}
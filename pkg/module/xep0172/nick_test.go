@@ -0,0 +1,265 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0172
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/module/xep0054"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNick_PublishNick(t *testing.T) {
+	// given
+	repMock, routerMock, outStanzas := setupMocks()
+
+	var mu sync.Mutex
+	var storedVCard stravaganza.Element
+	repMock.FetchVCardFunc = func(ctx context.Context, username string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("vCard").
+			WithAttribute(stravaganza.Namespace, vCardNamespace).
+			WithChild(stravaganza.NewBuilder("FN").WithText("Miguel Ángel").Build()).
+			WithChild(stravaganza.NewBuilder("NICKNAME").WithText("ortu").Build()).
+			Build(), nil
+	}
+	repMock.UpsertVCardFunc = func(ctx context.Context, vCard stravaganza.Element, username string) error {
+		mu.Lock()
+		storedVCard = vCard
+		mu.Unlock()
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &Nick{
+		router: routerMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	vc := xep0054.New(xep0054.Config{}, routerMock, repMock, hk, kitlog.NewNopLogger())
+
+	_ = m.Start(context.Background())
+	_ = vc.Start(context.Background())
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "pub1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("pubsub").
+				WithAttribute(stravaganza.Namespace, pubSubNamespace).
+				WithChild(
+					stravaganza.NewBuilder("publish").
+						WithAttribute("node", nickNamespace).
+						WithChild(nickItem("ortuman")).
+						Build(),
+				).
+				Build(),
+		).
+		BuildIQ()
+	_ = m.ProcessIQ(context.Background(), iq)
+
+	// then
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Len(t, repMock.UpsertVCardCalls(), 1) // no update loops
+	require.NotNil(t, storedVCard)
+	require.Equal(t, "ortuman", storedVCard.Child("NICKNAME").Text())
+	require.Equal(t, "Miguel Ángel", storedVCard.Child("FN").Text())
+
+	stanzas := outStanzas()
+	require.Len(t, stanzas, 3) // result + owner and contact notifications
+
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Attribute(stravaganza.Type))
+	require.Equal(t, "ortuman@jackal.im", stanzas[1].Attribute(stravaganza.To))
+	require.Equal(t, "noelia@jackal.im", stanzas[2].Attribute(stravaganza.To))
+
+	nick := stanzas[2].ChildNamespace("event", pubSubEventNamespace).Child("items").Child("item").Child("nick")
+	require.NotNil(t, nick)
+	require.Equal(t, "ortuman", nick.Text())
+}
+
+func TestNick_VCardUpdated(t *testing.T) {
+	// given
+	repMock, routerMock, outStanzas := setupMocks()
+	repMock.UpsertVCardFunc = func(ctx context.Context, vCard stravaganza.Element, username string) error {
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &Nick{
+		router: routerMock,
+		rep:    repMock,
+		hk:     hk,
+		logger: kitlog.NewNopLogger(),
+	}
+	vc := xep0054.New(xep0054.Config{}, routerMock, repMock, hk, kitlog.NewNopLogger())
+
+	_ = m.Start(context.Background())
+	_ = vc.Start(context.Background())
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, "vc1").
+		WithAttribute(stravaganza.From, "ortuman@jackal.net/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.net").
+		WithAttribute(stravaganza.Type, stravaganza.SetType).
+		WithChild(
+			stravaganza.NewBuilder("vCard").
+				WithAttribute(stravaganza.Namespace, vCardNamespace).
+				WithChild(stravaganza.NewBuilder("NICKNAME").WithText("ortuman").Build()).
+				Build(),
+		).
+		BuildIQ()
+	_ = vc.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, repMock.UpsertVCardCalls(), 1) // no update loops
+
+	stanzas := outStanzas()
+	require.Len(t, stanzas, 3) // result + owner and contact notifications
+
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Attribute(stravaganza.Type))
+	require.Equal(t, stravaganza.HeadlineType, stanzas[1].Attribute(stravaganza.Type))
+	require.Equal(t, "ortuman@jackal.net", stanzas[1].Attribute(stravaganza.From))
+
+	nick := stanzas[2].ChildNamespace("event", pubSubEventNamespace).Child("items").Child("item").Child("nick")
+	require.NotNil(t, nick)
+	require.Equal(t, "ortuman", nick.Text())
+}
+
+func TestNick_GetNick(t *testing.T) {
+	// given
+	repMock, routerMock, outStanzas := setupMocks()
+	repMock.FetchVCardFunc = func(ctx context.Context, username string) (stravaganza.Element, error) {
+		return stravaganza.NewBuilder("vCard").
+			WithAttribute(stravaganza.Namespace, vCardNamespace).
+			WithChild(stravaganza.NewBuilder("NICKNAME").WithText("ortu").Build()).
+			Build(), nil
+	}
+	repMock.FetchRosterItemFunc = func(ctx context.Context, username string, jid string) (*rostermodel.Item, error) {
+		if jid == "noelia@jackal.im" {
+			return &rostermodel.Item{Username: username, Jid: jid, Subscription: rostermodel.Both}, nil
+		}
+		return nil, nil
+	}
+	m := &Nick{
+		router: routerMock,
+		rep:    repMock,
+		hk:     hook.NewHooks(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	getIQ := func(from string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "get1").
+			WithAttribute(stravaganza.From, from).
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("pubsub").
+					WithAttribute(stravaganza.Namespace, pubSubNamespace).
+					WithChild(
+						stravaganza.NewBuilder("items").
+							WithAttribute("node", nickNamespace).
+							Build(),
+					).
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+	_ = m.ProcessIQ(context.Background(), getIQ("noelia@jackal.im/hall"))
+	_ = m.ProcessIQ(context.Background(), getIQ("stranger@jackal.im/hall"))
+
+	// then
+	stanzas := outStanzas()
+	require.Len(t, stanzas, 2)
+
+	require.Equal(t, stravaganza.ResultType, stanzas[0].Attribute(stravaganza.Type))
+	nick := stanzas[0].ChildNamespace("pubsub", pubSubNamespace).Child("items").Child("item").Child("nick")
+	require.NotNil(t, nick)
+	require.Equal(t, "ortu", nick.Text())
+
+	require.Equal(t, stravaganza.ErrorType, stanzas[1].Attribute(stravaganza.Type))
+}
+
+func setupMocks() (*repositoryMock, *routerMock, func() []stravaganza.Stanza) {
+	var mu sync.Mutex
+	var outStanzas []stravaganza.Stanza
+
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		mu.Lock()
+		outStanzas = append(outStanzas, stanza)
+		mu.Unlock()
+		return nil, nil
+	}
+	repMock := &repositoryMock{}
+	repMock.FetchVCardFunc = func(ctx context.Context, username string) (stravaganza.Element, error) {
+		return nil, nil
+	}
+	repMock.FetchRosterItemsFunc = func(ctx context.Context, username string) ([]*rostermodel.Item, error) {
+		return []*rostermodel.Item{
+			{Username: username, Jid: "noelia@jackal.im", Subscription: rostermodel.Both},
+			{Username: username, Jid: "romeo@jackal.im", Subscription: rostermodel.To},
+		}, nil
+	}
+	return repMock, routerMock, func() []stravaganza.Stanza {
+		mu.Lock()
+		defer mu.Unlock()
+		return outStanzas
+	}
+}
+
+func TestNick_MatchesIQ(t *testing.T) {
+	// given
+	m := &Nick{}
+
+	pubSubIQ := func(child, node string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "ps1").
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			WithAttribute(stravaganza.Type, stravaganza.GetType).
+			WithChild(
+				stravaganza.NewBuilder("pubsub").
+					WithAttribute(stravaganza.Namespace, pubSubNamespace).
+					WithChild(
+						stravaganza.NewBuilder(child).
+							WithAttribute("node", node).
+							Build(),
+					).
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// then
+	require.True(t, m.MatchesIQ(pubSubIQ("items", nickNamespace)))
+	require.True(t, m.MatchesIQ(pubSubIQ("publish", nickNamespace)))
+	require.False(t, m.MatchesIQ(pubSubIQ("items", "urn:xmpp:avatar:metadata")))
+	require.False(t, m.MatchesIQ(pubSubIQ("subscribe", nickNamespace)))
+}
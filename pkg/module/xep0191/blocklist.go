@@ -107,6 +107,11 @@ func (m *BlockList) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == blockListNamespace
 }
 
+// Namespaces returns all iq namespaces matched by blocklist module.
+func (m *BlockList) Namespaces() []string {
+	return []string{blockListNamespace}
+}
+
 // ProcessIQ process a blocklist iq.
 func (m *BlockList) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	fromJID := iq.FromJID()
@@ -19,6 +19,7 @@ import (
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 )
 
 //go:generate moq -out router.mock_test.go . globalRouter:routerMock
@@ -36,6 +37,11 @@ type resourceManager interface {
 	resourcemanager.Manager
 }
 
+//go:generate moq -out repository.mock_test.go . streamRepository:repositoryMock
+type streamRepository interface {
+	repository.Stream
+}
+
 //go:generate moq -out clusterconnmanager.mock_test.go . clusterConnManager
 type clusterConnManager interface {
 	GetConnection(instanceID string) (clusterconnmanager.Conn, error)
@@ -0,0 +1,91 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0198
+
+import (
+	"time"
+
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const reportTotalQueuesInterval = time.Second * 30
+
+const (
+	resumeSuccess            = "success"
+	resumeRemote             = "remote"
+	resumeItemNotFound       = "item_not_found"
+	resumeUnexpectedRequest  = "unexpected_request"
+	resumeResourceConstraint = "resource_constraint"
+)
+
+var (
+	xep0198Resumes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "xep0198",
+			Name:      "resume_total",
+			Help:      "The total number of stream resumption attempts.",
+		},
+		[]string{"instance", "result"},
+	)
+	xep0198ResumeReplayedStanzasBucket = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "jackal",
+			Subsystem: "xep0198",
+			Name:      "resume_replayed_stanzas_bucket",
+			Help:      "Bucketed histogram of the number of stanzas replayed on stream resumption.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+		},
+		[]string{"instance"},
+	)
+	xep0198TotalQueues = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "jackal",
+			Subsystem: "xep0198",
+			Name:      "total_queues",
+			Help:      "Total retained stream queues.",
+		},
+		[]string{"instance"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(xep0198Resumes)
+	prometheus.MustRegister(xep0198ResumeReplayedStanzasBucket)
+	prometheus.MustRegister(xep0198TotalQueues)
+}
+
+func reportResume(result string) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"result":   result,
+	}
+	xep0198Resumes.With(metricLabel).Inc()
+}
+
+func reportResumeReplayedStanzas(count int) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	xep0198ResumeReplayedStanzasBucket.With(metricLabel).Observe(float64(count))
+}
+
+func reportTotalQueues(totalQueues int) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	xep0198TotalQueues.With(metricLabel).Set(float64(totalQueues))
+}
@@ -15,7 +15,6 @@
 package streamqueue
 
 import (
-	"math"
 	"sync"
 	"time"
 
@@ -64,6 +63,37 @@ func (qm *QueueMap) Delete(k string) *Queue {
 	return q
 }
 
+// DeleteIfMatches deletes the Queue value associated to k key only if it's still q.
+// It returns true in case the value has been deleted.
+func (qm *QueueMap) DeleteIfMatches(k string, q *Queue) bool {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+	if qm.queues[k] != q {
+		return false
+	}
+	delete(qm.queues, k)
+	return true
+}
+
+// Len returns the number of queues present in the map.
+func (qm *QueueMap) Len() int {
+	qm.mu.RLock()
+	defer qm.mu.RUnlock()
+	return len(qm.queues)
+}
+
+// Range calls f sequentially for each key and Queue present in the map.
+// If f returns false, range stops the iteration.
+func (qm *QueueMap) Range(f func(k string, q *Queue) bool) {
+	qm.mu.RLock()
+	defer qm.mu.RUnlock()
+	for k, q := range qm.queues {
+		if !f(k, q) {
+			return
+		}
+	}
+}
+
 // Element defines a stream queue element type.
 type Element struct {
 	// Stanza contains the element stanza.
@@ -77,18 +107,34 @@ type Element struct {
 type Queue struct {
 	stm               stream.C2S
 	nc                []byte
-	reqAckInterval    time.Duration
+	minReqAckInterval time.Duration
+	maxReqAckInterval time.Duration
 	waitForAckTimeout time.Duration
 
-	mu       sync.RWMutex
-	elements []Element
-	outH     uint32
-	inH      uint32
-	rTm      *time.Timer
-	discTm   *time.Timer
+	mu             sync.RWMutex
+	elements       []Element
+	outH           uint32
+	inH            uint32
+	reqAckInterval time.Duration
+	rSentAt        time.Time
+	rPending       bool
+	rDeferring     bool
+	rDeferred      bool
+	rTm            *time.Timer
+	discTm         *time.Timer
+	ackWaiters     []ackWaiter
+}
+
+type ackWaiter struct {
+	sentAt time.Time
+	ch     chan time.Duration
 }
 
 // New creates and initializes a new Queue instance.
+//
+// In case maxRequestAckInterval is greater than requestAckInterval, the interval at which
+// acknowledgement is requested doubles up to maxRequestAckInterval every time an ack arrives promptly,
+// and it's reset to requestAckInterval whenever an ack is late. Otherwise, a fixed interval is used.
 func New(
 	stm stream.C2S,
 	nonce []byte,
@@ -96,6 +142,7 @@ func New(
 	inH uint32,
 	outH uint32,
 	requestAckInterval time.Duration,
+	maxRequestAckInterval time.Duration,
 	waitForAckTimeout time.Duration,
 ) *Queue {
 	sq := &Queue{
@@ -104,6 +151,8 @@ func New(
 		elements:          elements,
 		inH:               inH,
 		outH:              outH,
+		minReqAckInterval: requestAckInterval,
+		maxReqAckInterval: maxRequestAckInterval,
 		reqAckInterval:    requestAckInterval,
 		waitForAckTimeout: waitForAckTimeout,
 	}
@@ -162,9 +211,18 @@ func (q *Queue) Acknowledge(h uint32) {
 	if discTm := q.discTm; discTm != nil {
 		discTm.Stop() // cancel disconnection timeout
 	}
+	if q.rPending {
+		q.rPending = false
+		q.backoff(time.Since(q.rSentAt))
+	}
+	for _, w := range q.ackWaiters {
+		w.ch <- time.Since(w.sentAt)
+	}
+	q.ackWaiters = nil
+
 	j := -1
 	for i, e := range q.elements {
-		if e.H <= h {
+		if isAcked(e.H, h) {
 			j = i
 		}
 	}
@@ -211,6 +269,13 @@ func (q *Queue) OutboundH() uint32 {
 	return q.outH
 }
 
+// RequestAckInterval returns the current acknowledgement request interval.
+func (q *Queue) RequestAckInterval() time.Duration {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.reqAckInterval
+}
+
 // ScheduleR schedules and r stanza sending.
 func (q *Queue) ScheduleR() {
 	q.mu.RLock()
@@ -228,17 +293,65 @@ func (q *Queue) CancelTimers() {
 	}
 }
 
+// DeferRequestAck tells whether acknowledgement requests should be deferred.
+// When deferral is turned off, a request that became due in the meantime is sent right away.
+func (q *Queue) DeferRequestAck(deferred bool) {
+	q.mu.Lock()
+	q.rDeferring = deferred
+	if deferred {
+		q.mu.Unlock()
+		return
+	}
+	sendR := q.rDeferred
+	q.rDeferred = false
+	q.setRTimer()
+	q.mu.Unlock()
+
+	if sendR {
+		q.RequestAck()
+	}
+}
+
 // RequestAck sends an r stanza to the queue internal stream.
 func (q *Queue) RequestAck() {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	if q.rDeferring {
+		q.rDeferred = true
+		return
+	}
+	q.sendR()
+}
+
+// ForceRequestAck sends an r stanza to the queue internal stream right away, regardless of any deferral.
+// Returned channel receives the elapsed time until the next acknowledgement arrives.
+func (q *Queue) ForceRequestAck() <-chan time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ch := make(chan time.Duration, 1)
+	q.ackWaiters = append(q.ackWaiters, ackWaiter{
+		sentAt: time.Now(),
+		ch:     ch,
+	})
+	q.sendR()
+	return ch
+}
+
+func (q *Queue) sendR() {
 	r := stravaganza.NewBuilder("r").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		Build()
 	q.stm.SendElement(r)
 
+	q.rPending = true
+	q.rSentAt = time.Now()
+
 	// schedule disconnect
+	if discTm := q.discTm; discTm != nil {
+		discTm.Stop() // superseded by the new request
+	}
 	q.discTm = time.AfterFunc(q.waitForAckTimeout, func() {
 		q.stm.Disconnect(streamerror.E(streamerror.ConnectionTimeout))
 	})
@@ -249,9 +362,31 @@ func (q *Queue) setRTimer() {
 	q.rTm = time.AfterFunc(q.reqAckInterval, q.RequestAck)
 }
 
-func incH(h uint32) uint32 {
-	if h == math.MaxUint32-1 {
-		return 0
+// backoff updates the acknowledgement request interval given the time elapsed
+// between an r stanza and its corresponding ack.
+// An ack is considered prompt when received within half of the ack wait timeout.
+func (q *Queue) backoff(ackDelay time.Duration) {
+	if q.maxReqAckInterval <= q.minReqAckInterval {
+		return // fixed interval
+	}
+	if ackDelay > q.waitForAckTimeout/2 {
+		q.reqAckInterval = q.minReqAckInterval
+		return
 	}
+	q.reqAckInterval *= 2
+	if q.reqAckInterval > q.maxReqAckInterval {
+		q.reqAckInterval = q.maxReqAckInterval
+	}
+}
+
+// incH returns the h value following h, wrapping around to zero once 2^32 is reached.
+func incH(h uint32) uint32 {
 	return h + 1
 }
+
+// isAcked reports whether an element with elemH value is covered by an ack with h value.
+// Values are compared using serial number arithmetic (RFC 1982), so that h is treated as a modular
+// counter and acks keep working once the counter wraps around 2^32.
+func isAcked(elemH, h uint32) bool {
+	return int32(h-elemH) >= 0
+}
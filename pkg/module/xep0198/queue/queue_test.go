@@ -0,0 +1,221 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package streamqueue
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/stretchr/testify/require"
+)
+
+type c2sStreamStub struct {
+	stream.C2S
+	sent []stravaganza.Element
+}
+
+func (s *c2sStreamStub) SendElement(elem stravaganza.Element) <-chan error {
+	s.sent = append(s.sent, elem)
+	return nil
+}
+
+func TestQueue_ForceRequestAck(t *testing.T) {
+	// given
+	stm := &c2sStreamStub{}
+
+	q := New(stm, nil, nil, 0, 0, time.Hour, 0, time.Minute)
+	defer q.CancelTimers()
+
+	q.DeferRequestAck(true)
+
+	// when
+	ackCh := q.ForceRequestAck()
+
+	time.Sleep(time.Millisecond * 20)
+	q.Acknowledge(0)
+
+	// then
+	require.Len(t, stm.sent, 1)
+	require.Equal(t, "r", stm.sent[0].Name())
+	require.Equal(t, streamNamespace, stm.sent[0].Attribute(stravaganza.Namespace))
+
+	select {
+	case latency := <-ackCh:
+		require.GreaterOrEqual(t, latency, time.Millisecond*20)
+	default:
+		require.Fail(t, "ack latency not reported")
+	}
+}
+
+func TestQueue_AckBackoff(t *testing.T) {
+	// given
+	q := New(nil, nil, nil, 0, 0, time.Hour, time.Hour*5, time.Minute)
+	defer q.CancelTimers()
+
+	ackAfter := func(d time.Duration) {
+		q.mu.Lock()
+		q.rPending = true
+		q.rSentAt = time.Now().Add(-d)
+		q.mu.Unlock()
+
+		q.Acknowledge(0)
+	}
+
+	// when
+	ackAfter(time.Second) // prompt
+	ivl1 := q.RequestAckInterval()
+
+	ackAfter(time.Second) // prompt
+	ivl2 := q.RequestAckInterval()
+
+	ackAfter(time.Second) // prompt (capped)
+	ivl3 := q.RequestAckInterval()
+
+	ackAfter(time.Second * 45) // late
+	ivl4 := q.RequestAckInterval()
+
+	q.Acknowledge(0) // unsolicited
+	ivl5 := q.RequestAckInterval()
+
+	// then
+	require.Equal(t, time.Hour*2, ivl1)
+	require.Equal(t, time.Hour*4, ivl2)
+	require.Equal(t, time.Hour*5, ivl3)
+	require.Equal(t, time.Hour, ivl4)
+	require.Equal(t, time.Hour, ivl5)
+}
+
+func TestQueue_FixedAckInterval(t *testing.T) {
+	// given
+	q := New(nil, nil, nil, 0, 0, time.Hour, 0, time.Minute)
+	defer q.CancelTimers()
+
+	// when
+	q.mu.Lock()
+	q.rPending = true
+	q.rSentAt = time.Now()
+	q.mu.Unlock()
+
+	q.Acknowledge(0)
+
+	// then
+	require.Equal(t, time.Hour, q.RequestAckInterval())
+}
+
+func TestQueue_Acknowledge(t *testing.T) {
+	var tests = []struct {
+		name         string
+		firstH       uint32
+		count        int
+		ackH         uint32
+		expectedLeft []uint32
+	}{
+		{
+			name:         "Partial",
+			firstH:       1,
+			count:        4,
+			ackH:         2,
+			expectedLeft: []uint32{3, 4},
+		},
+		{
+			name:         "Stale",
+			firstH:       10,
+			count:        2,
+			ackH:         5,
+			expectedLeft: []uint32{10, 11},
+		},
+		{
+			name:         "UpToMaxH",
+			firstH:       math.MaxUint32 - 1,
+			count:        3,
+			ackH:         math.MaxUint32,
+			expectedLeft: []uint32{0},
+		},
+		{
+			name:         "WrappedPartial",
+			firstH:       math.MaxUint32 - 4,
+			count:        11,
+			ackH:         2,
+			expectedLeft: []uint32{3, 4, 5},
+		},
+		{
+			name:         "WrappedAll",
+			firstH:       math.MaxUint32 - 4,
+			count:        11,
+			ackH:         5,
+			expectedLeft: nil,
+		},
+		{
+			name:         "WrappedStale",
+			firstH:       3,
+			count:        2,
+			ackH:         math.MaxUint32 - 1,
+			expectedLeft: []uint32{3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			q := New(nil, nil, nil, 0, tt.firstH-1, time.Hour, 0, time.Minute)
+			defer q.CancelTimers()
+
+			for i := 0; i < tt.count; i++ {
+				msg, _ := stravaganza.NewMessageBuilder().
+					WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+					WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony").
+					WithAttribute(stravaganza.ID, strconv.Itoa(i)).
+					BuildMessage()
+				q.HandleOut(msg)
+			}
+
+			// when
+			q.Acknowledge(tt.ackH)
+
+			// then
+			var left []uint32
+			for _, e := range q.Elements() {
+				left = append(left, e.H)
+			}
+			require.Equal(t, tt.expectedLeft, left)
+		})
+	}
+}
+
+func TestQueue_OutboundHWrapAround(t *testing.T) {
+	// given
+	q := New(nil, nil, nil, 0, math.MaxUint32-1, time.Hour, 0, time.Minute)
+	defer q.CancelTimers()
+
+	b := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/balcony")
+	msg1, _ := b.WithAttribute(stravaganza.ID, "1").BuildMessage()
+	msg2, _ := b.WithAttribute(stravaganza.ID, "2").BuildMessage()
+
+	// when
+	q.HandleOut(msg1)
+	h1 := q.OutboundH()
+
+	q.HandleOut(msg2)
+	h2 := q.OutboundH()
+
+	// then
+	require.Equal(t, uint32(math.MaxUint32), h1)
+	require.Equal(t, uint32(0), h2)
+}
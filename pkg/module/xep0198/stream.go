@@ -39,20 +39,28 @@ import (
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/hook"
 	"github.com/ortuman/jackal/pkg/host"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/ortuman/jackal/pkg/module/xep0352"
 	xmppparser "github.com/ortuman/jackal/pkg/parser"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/shaper"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
 	streamNamespace     = "urn:xmpp:sm:3"
 	xmppStanzaNamespace = "urn:ietf:params:xml:ns:xmpp-stanzas"
+	resumeHintNamespace = "urn:jackal:sm:hint:0"
 
 	enabledInfoKey = "xep0198:enabled"
 
-	badRequest        = "bad-request"
-	unexpectedRequest = "unexpected-request"
-	itemNotFound      = "item-not-found"
+	badRequest         = "bad-request"
+	unexpectedRequest  = "unexpected-request"
+	itemNotFound       = "item-not-found"
+	resourceConstraint = "resource-constraint"
 
 	nonceLength = 24
 
@@ -74,6 +82,7 @@ const (
 type Config struct {
 	// HibernateTime defines the amount of time a stream
 	// can stay in disconnected state before being terminated.
+	// Its value is advertised to clients as the maximum resumption time.
 	HibernateTime time.Duration `fig:"hibernate_time" default:"3m"`
 
 	// RequestAckInterval defines the period of stream inactivity
@@ -85,23 +94,61 @@ type Config struct {
 
 	// MaxQueueSize defines maximum number of unacknowledged stanzas.
 	// When the limit is reached the c2s stream is terminated.
+	// A matching shaper max_queue_size value takes precedence over this one.
 	MaxQueueSize int `fig:"max_queue_size" default:"250"`
+
+	// AckEveryNInbound defines the number of inbound stanzas after which
+	// an unsolicited "a" stanza will be sent. Zero value disables it.
+	AckEveryNInbound int `fig:"ack_every_n_inbound"`
+
+	// ResumeLocation defines the peer location (host:port) that SM-enabled clients
+	// are encouraged to resume on when this node is drained.
+	// Empty value disables resumption hints.
+	ResumeLocation string `fig:"resume_location"`
+
+	// MaxConcurrentResumptions defines the maximum number of stream resumptions processed at once.
+	// Exceeding ones will wait for a free slot. Zero value means no limit.
+	MaxConcurrentResumptions int `fig:"max_concurrent_resumptions"`
+
+	// ResumeQueueTimeout defines the maximum amount of time a resumption may wait for a free slot
+	// before failing with a resource-constraint error.
+	ResumeQueueTimeout time.Duration `fig:"resume_queue_timeout" default:"10s"`
+
+	// AckBackoff enables exponential backoff of acknowledgement requests. The request interval
+	// doubles from RequestAckInterval up to MaxRequestAckInterval whenever an ack arrives promptly,
+	// and it's reset to RequestAckInterval whenever an ack is late.
+	AckBackoff bool `fig:"ack_backoff"`
+
+	// MaxRequestAckInterval defines the acknowledgement request interval ceiling when AckBackoff is enabled.
+	MaxRequestAckInterval time.Duration `fig:"max_request_ack_interval" default:"10m"`
+
+	// PersistQueues tells whether stream queues should be stored into the repository on shutdown
+	// and restored on startup, so that streams can be resumed across server restarts.
+	// Queues are restored by the instance that stored them, hence a stable instance identifier is required.
+	PersistQueues bool `fig:"persist_queues"`
 }
 
 // Stream represents a stream (XEP-0198) module type.
 type Stream struct {
-	cfg    Config
-	router router.Router
-	hosts  *host.Hosts
-	resMng resourcemanager.Manager
-	hk     *hook.Hooks
-	logger kitlog.Logger
+	cfg     Config
+	router  router.Router
+	hosts   *host.Hosts
+	resMng  resourcemanager.Manager
+	rep     repository.Stream
+	shapers shaper.Shapers
+	hk      *hook.Hooks
+	logger  kitlog.Logger
 
 	stmQueueMap    *streamqueue.QueueMap
 	clusterConnMng clusterConnManager
-
-	mu      sync.RWMutex
-	termTms map[string]*time.Timer
+	resumeSlots    chan struct{}
+
+	mu        sync.RWMutex
+	termTms   map[string]*time.Timer
+	termDls   map[string]time.Time
+	restored  map[string]*streammodel.Queue
+	restoreTm *time.Timer
+	doneCh    chan chan struct{}
 }
 
 // New returns a new initialized Stream instance.
@@ -112,20 +159,30 @@ func New(
 	router router.Router,
 	hosts *host.Hosts,
 	resMng resourcemanager.Manager,
+	rep repository.Stream,
+	shapers shaper.Shapers,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Stream {
-	return &Stream{
+	m := &Stream{
 		cfg:            cfg,
 		router:         router,
 		hosts:          hosts,
 		resMng:         resMng,
+		rep:            rep,
+		shapers:        shapers,
 		stmQueueMap:    stmQueueMap,
 		clusterConnMng: clusterConnMng,
 		termTms:        make(map[string]*time.Timer),
+		termDls:        make(map[string]time.Time),
+		restored:       make(map[string]*streammodel.Queue),
 		hk:             hk,
 		logger:         kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
 	}
+	if cfg.MaxConcurrentResumptions > 0 {
+		m.resumeSlots = make(chan struct{}, cfg.MaxConcurrentResumptions)
+	}
+	return m
 }
 
 // Name returns stream module name.
@@ -149,23 +206,51 @@ func (m *Stream) AccountFeatures(_ context.Context) ([]string, error) {
 }
 
 // Start starts stream module.
-func (m *Stream) Start(_ context.Context) error {
+func (m *Stream) Start(ctx context.Context) error {
+	if m.cfg.PersistQueues {
+		if err := m.restoreQueues(ctx); err != nil {
+			return err
+		}
+	}
 	m.hk.AddHook(hook.C2SStreamElementReceived, m.onElementRecv, hook.DefaultPriority)
 	m.hk.AddHook(hook.C2SStreamElementSent, m.onElementSent, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamWillDisconnect, m.onWillDisconnect, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamClientStateChanged, m.onClientStateChanged, hook.DefaultPriority)
 	m.hk.AddHook(hook.C2SStreamDisconnected, m.onDisconnect, hook.LowestPriority)
 	m.hk.AddHook(hook.C2SStreamTerminated, m.onTerminate, hook.LowestPriority)
+	m.hk.AddHook(hook.InstanceDrainingUpdated, m.onDrainingUpdated, hook.DefaultPriority)
+
+	m.doneCh = make(chan chan struct{})
+	go m.reportMetrics()
 
 	level.Info(m.logger).Log("msg", "started stream module")
 	return nil
 }
 
 // Stop stops stream module.
-func (m *Stream) Stop(_ context.Context) error {
+func (m *Stream) Stop(ctx context.Context) error {
 	m.hk.RemoveHook(hook.C2SStreamElementReceived, m.onElementRecv)
 	m.hk.RemoveHook(hook.C2SStreamElementSent, m.onElementSent)
+	m.hk.RemoveHook(hook.C2SStreamWillDisconnect, m.onWillDisconnect)
+	m.hk.RemoveHook(hook.C2SStreamClientStateChanged, m.onClientStateChanged)
 	m.hk.RemoveHook(hook.C2SStreamDisconnected, m.onDisconnect)
 	m.hk.RemoveHook(hook.C2SStreamTerminated, m.onTerminate)
+	m.hk.RemoveHook(hook.InstanceDrainingUpdated, m.onDrainingUpdated)
+
+	if m.doneCh != nil { // nil when Start failed or never ran
+		ch := make(chan struct{})
+		m.doneCh <- ch
+		<-ch
+		m.doneCh = nil
+	}
+	m.sendFinalAcks()
+	m.sendResumeHints()
 
+	if m.cfg.PersistQueues {
+		if err := m.persistQueues(ctx); err != nil {
+			return err
+		}
+	}
 	level.Info(m.logger).Log("msg", "stopped stream module")
 	return nil
 }
@@ -188,6 +273,10 @@ func (m *Stream) onElementRecv(ctx context.Context, execCtx *hook.ExecutionConte
 		return nil
 	}
 	sq.HandleIn()
+
+	if n := m.cfg.AckEveryNInbound; n > 0 && sq.InboundH()%uint32(n) == 0 {
+		m.sendA(stm, sq.InboundH())
+	}
 	return nil
 }
 
@@ -207,7 +296,7 @@ func (m *Stream) onElementSent(_ context.Context, execCtx *hook.ExecutionContext
 
 	qLen := sq.Len()
 	switch {
-	case qLen >= m.cfg.MaxQueueSize:
+	case qLen >= m.maxQueueSize(stm):
 		_ = sq.GetStream().Disconnect(streamerror.E(streamerror.PolicyViolation))
 
 		level.Info(m.logger).Log("msg", "max queue size reached",
@@ -220,6 +309,53 @@ func (m *Stream) onElementSent(_ context.Context, execCtx *hook.ExecutionContext
 	return nil
 }
 
+// maxQueueSize returns the maximum queue size applicable to stm, giving precedence to the value
+// defined by the shaper matching its JID, connection origin and account tier, if any.
+func (m *Stream) maxQueueSize(stm stream.C2S) int {
+	inf := stm.Info()
+	shp := m.shapers.MatchingJIDOriginAndTier(stm.JID(), shaper.OriginFromInfo(inf), shaper.TierFromInfo(inf))
+	if shp.MaxQueueSize > 0 {
+		return shp.MaxQueueSize
+	}
+	return m.cfg.MaxQueueSize
+}
+
+func (m *Stream) onWillDisconnect(_ context.Context, execCtx *hook.ExecutionContext) error {
+	stm := execCtx.Sender.(stream.C2S)
+	if !stm.Info().Bool(enabledInfoKey) {
+		return nil
+	}
+	sq := m.stmQueueMap.Get(queueKey(stm.JID()))
+	if sq == nil {
+		return nil
+	}
+	// let the client know which stanzas were processed before closing the stream
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	inf.Element = ackElement(sq.InboundH())
+	return nil
+}
+
+func (m *Stream) onDrainingUpdated(_ context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.InstanceInfo)
+	if !inf.Draining {
+		return nil
+	}
+	// instance is about to go away... let clients move elsewhere in advance
+	m.sendResumeHints()
+	return nil
+}
+
+func (m *Stream) onClientStateChanged(_ context.Context, execCtx *hook.ExecutionContext) error {
+	stm := execCtx.Sender.(stream.C2S)
+	sq := m.stmQueueMap.Get(queueKey(stm.JID()))
+	if sq == nil {
+		return nil
+	}
+	// do not request acks while client is inactive
+	sq.DeferRequestAck(xep0352.IsInactive(stm.Info()))
+	return nil
+}
+
 func (m *Stream) onDisconnect(_ context.Context, execCtx *hook.ExecutionContext) error {
 	stm := execCtx.Sender.(stream.C2S)
 	if !stm.Info().Bool(enabledInfoKey) {
@@ -240,6 +376,7 @@ func (m *Stream) onDisconnect(_ context.Context, execCtx *hook.ExecutionContext)
 	}
 	// schedule stream termination
 	m.mu.Lock()
+	m.termDls[inf.ID] = time.Now().Add(m.cfg.HibernateTime)
 	m.termTms[inf.ID] = time.AfterFunc(m.cfg.HibernateTime, func() {
 		_ = stm.Disconnect(nil)
 
@@ -276,14 +413,54 @@ func (m *Stream) onTerminate(_ context.Context, execCtx *hook.ExecutionContext)
 		tm.Stop()
 	}
 	delete(m.termTms, inf.ID)
+	delete(m.termDls, inf.ID)
 	m.mu.Unlock()
 
 	return nil
 }
 
+func (m *Stream) sendFinalAcks() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.stmQueueMap.Range(func(_ string, sq *streamqueue.Queue) bool {
+		stm := sq.GetStream()
+		if _, ok := m.termTms[stm.ID().String()]; ok {
+			return true // hibernated stream
+		}
+		m.sendElement(stm, ackElement(sq.InboundH()), nil)
+		return true
+	})
+}
+
+func (m *Stream) sendResumeHints() {
+	loc := m.cfg.ResumeLocation
+	if len(loc) == 0 {
+		return
+	}
+	// on drain, and on stop as modules are stopped before c2s router, every SM-enabled
+	// stream is still connected at this point... let clients know where to resume.
+	hint := stravaganza.NewBuilder("resume-hint").
+		WithAttribute(stravaganza.Namespace, resumeHintNamespace).
+		WithAttribute("location", loc).
+		Build()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	m.stmQueueMap.Range(func(_ string, sq *streamqueue.Queue) bool {
+		stm := sq.GetStream()
+		if _, ok := m.termTms[stm.ID().String()]; ok {
+			return true // hibernated stream
+		}
+		m.sendElement(stm, hint, nil)
+		return true
+	})
+}
+
 func (m *Stream) processCmd(ctx context.Context, cmd stravaganza.Element, stm stream.C2S) error {
 	if cmd.ChildrenCount() > 0 {
-		sendFailedReply(badRequest, "Malformed element", stm)
+		m.sendFailedReply(badRequest, "Malformed element", stm)
 		return nil
 	}
 	h, _ := strconv.ParseUint(cmd.Attribute("h"), 10, 32)
@@ -300,18 +477,18 @@ func (m *Stream) processCmd(ctx context.Context, cmd stravaganza.Element, stm st
 		m.handleR(stm)
 	default:
 		errText := fmt.Sprintf("Unknown tag %s qualified by namespace '%s'", cmd.Name(), streamNamespace)
-		sendFailedReply(badRequest, errText, stm)
+		m.sendFailedReply(badRequest, errText, stm)
 	}
 	return nil
 }
 
 func (m *Stream) handleEnable(ctx context.Context, stm stream.C2S) error {
 	if !stm.IsBinded() {
-		sendFailedReply(unexpectedRequest, "", stm)
+		m.sendFailedReply(unexpectedRequest, "", stm)
 		return nil
 	}
 	if stm.Info().Bool(enabledInfoKey) {
-		sendFailedReply(unexpectedRequest, "Stream management is already enabled", stm)
+		m.sendFailedReply(unexpectedRequest, "Stream management is already enabled", stm)
 		return nil
 	}
 	if err := stm.SetInfoValue(ctx, enabledInfoKey, true); err != nil {
@@ -330,18 +507,30 @@ func (m *Stream) handleEnable(ctx context.Context, stm stream.C2S) error {
 		0,
 		0,
 		m.cfg.RequestAckInterval,
+		m.maxRequestAckInterval(),
 		m.cfg.WaitForAckTimeout,
 	)
-	m.stmQueueMap.Set(queueKey(stm.JID()), sq)
+	qk := queueKey(stm.JID())
+	m.stmQueueMap.Set(qk, sq)
+
+	if xep0352.IsInactive(stm.Info()) {
+		sq.DeferRequestAck(true)
+	}
 
 	smID := encodeSMID(stm.JID(), nonce)
 
-	stm.SendElement(stravaganza.NewBuilder("enabled").
+	enabled := stravaganza.NewBuilder("enabled").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		WithAttribute("id", smID).
 		WithAttribute("resume", "true").
-		Build(),
-	)
+		WithAttribute("max", strconv.Itoa(m.maxResumptionTime())).
+		Build()
+	m.sendElement(stm, enabled, func() {
+		// client never got to know about the enabled session... do not keep it resumable
+		if m.stmQueueMap.DeleteIfMatches(qk, sq) {
+			sq.CancelTimers()
+		}
+	})
 	level.Info(m.logger).Log("msg", "enabled stream management",
 		"smID", smID, "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
 	)
@@ -350,9 +539,18 @@ func (m *Stream) handleEnable(ctx context.Context, stm stream.C2S) error {
 
 func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, prevSMID string) error {
 	if !stm.IsAuthenticated() {
-		sendFailedReply(unexpectedRequest, "", stm)
+		m.sendFailedReply(unexpectedRequest, "", stm)
+		reportResume(resumeUnexpectedRequest)
+		return nil
+	}
+	// wait for a free resumption slot
+	if !m.acquireResumeSlot(ctx, stm) {
+		m.sendFailedReply(resourceConstraint, "Too many concurrent resumptions", stm)
+		reportResume(resumeResourceConstraint)
 		return nil
 	}
+	defer m.releaseResumeSlot()
+
 	// perform stream resumption
 	jd, nonce, err := decodeSMID(prevSMID)
 	if err != nil {
@@ -363,18 +561,30 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 	if err != nil {
 		return err
 	}
-	if res == nil {
-		sendFailedReply(itemNotFound, "", stm)
-		return nil
-	}
 	var sq *streamqueue.Queue
 
 	qk := queueKey(jd)
+	result := resumeSuccess
 
-	if res.InstanceID() == instance.ID() { // local retained queue
+	switch {
+	case res == nil: // queue restored from repository?
+		rq := m.takeRestoredQueue(qk, nonce)
+		if rq == nil {
+			m.sendFailedReply(itemNotFound, "", stm)
+			reportResume(resumeItemNotFound)
+			return nil
+		}
+		sq, res, err = m.rehydrateQueue(stm, rq)
+		if err != nil {
+			return err
+		}
+		level.Info(m.logger).Log("msg", "stream queue restored", "key", qk)
+
+	case res.InstanceID() == instance.ID(): // local retained queue
 		sq = m.stmQueueMap.Get(qk)
-		if sq == nil {
-			sendFailedReply(itemNotFound, "", stm)
+		if sq == nil || m.isHibernationExpired(sq.GetStream()) {
+			m.sendFailedReply(itemNotFound, "", stm)
+			reportResume(resumeItemNotFound)
 			return nil
 		}
 		// disconnect hibernated c2s stream
@@ -384,7 +594,7 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 		// set new stream
 		sq.SetStream(stm)
 
-	} else { // transfer retained queue from internal cluster instance
+	default: // transfer retained queue from internal cluster instance
 		conn, err := m.clusterConnMng.GetConnection(res.InstanceID())
 		if err != nil {
 			return err
@@ -400,17 +610,20 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 			resp.InH,
 			resp.OutH,
 			m.cfg.RequestAckInterval,
+			m.maxRequestAckInterval(),
 			m.cfg.WaitForAckTimeout,
 		)
 
 		level.Info(m.logger).Log(
 			"msg", "stream queue transferred", "key", qk, "from", res.InstanceID(), "to", instance.ID(),
 		)
+		result = resumeRemote
 	}
 
 	// invalid smID?
 	if !jd.MatchesWithOptions(stm.JID(), jid.MatchesBare) || bytes.Compare(sq.Nonce(), nonce) != 0 {
-		sendFailedReply(itemNotFound, "", stm)
+		m.sendFailedReply(itemNotFound, "", stm)
+		reportResume(resumeItemNotFound)
 		return nil
 	}
 
@@ -421,22 +634,236 @@ func (m *Stream) handleResume(ctx context.Context, stm stream.C2S, h uint32, pre
 	if err := stm.Resume(ctx, res.JID(), res.Presence(), res.Info()); err != nil {
 		return err
 	}
-	stm.SendElement(stravaganza.NewBuilder("resumed").
+	resumed := stravaganza.NewBuilder("resumed").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		WithAttribute("h", strconv.FormatUint(uint64(sq.InboundH()), 10)).
 		WithAttribute("previd", prevSMID).
-		Build(),
-	)
+		Build()
+	m.sendElement(stm, resumed, func() {
+		// client never got to know about the resumed session... do not keep it bound to a broken stream
+		if m.stmQueueMap.DeleteIfMatches(qk, sq) {
+			sq.CancelTimers()
+		}
+	})
 	sq.Acknowledge(h)
+	replayed := sq.Len()
 	sq.SendPending()
 	sq.ScheduleR()
 
+	if xep0352.IsInactive(res.Info()) {
+		sq.DeferRequestAck(true)
+	}
+
+	reportResume(result)
+	reportResumeReplayedStanzas(replayed)
+
 	level.Info(m.logger).Log("msg", "resumed stream",
 		"smID", prevSMID, "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
 	)
 	return nil
 }
 
+func (m *Stream) reportMetrics() {
+	tc := time.NewTicker(reportTotalQueuesInterval)
+	defer tc.Stop()
+
+	for {
+		select {
+		case <-tc.C:
+			reportTotalQueues(m.stmQueueMap.Len())
+
+		case ch := <-m.doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+// maxResumptionTime returns the advertised maximum resumption time in seconds.
+// Value is rounded down, so that clients never attempt to resume an already terminated stream.
+func (m *Stream) maxResumptionTime() int {
+	return int(m.cfg.HibernateTime / time.Second)
+}
+
+func (m *Stream) isHibernationExpired(stm stream.C2S) bool {
+	m.mu.RLock()
+	dl, ok := m.termDls[stm.ID().String()]
+	m.mu.RUnlock()
+	return ok && !time.Now().Before(dl)
+}
+
+func (m *Stream) maxRequestAckInterval() time.Duration {
+	if !m.cfg.AckBackoff {
+		return 0 // fixed interval
+	}
+	return m.cfg.MaxRequestAckInterval
+}
+
+func (m *Stream) persistQueues(ctx context.Context) error {
+	var qs []*streammodel.Queue
+
+	persistedAt := timestamppb.Now()
+	m.stmQueueMap.Range(func(k string, sq *streamqueue.Queue) bool {
+		stm := sq.GetStream()
+
+		var presence *stravaganza.PBElement
+		if pr := stm.Presence(); pr != nil {
+			presence = pr.Proto()
+		}
+		q := &streammodel.Queue{
+			Key:         k,
+			InstanceId:  instance.ID(),
+			Jid:         stm.JID().String(),
+			Presence:    presence,
+			Info:        stm.Info().Map(),
+			Nonce:       sq.Nonce(),
+			InH:         sq.InboundH(),
+			OutH:        sq.OutboundH(),
+			PersistedAt: persistedAt,
+		}
+		for _, elem := range sq.Elements() {
+			q.Elements = append(q.Elements, &streammodel.QueueElement{
+				Stanza: elem.Stanza.Proto(),
+				H:      elem.H,
+			})
+		}
+		qs = append(qs, q)
+		return true
+	})
+	// keep restored queues not yet resumed
+	m.mu.Lock()
+	if m.restoreTm != nil {
+		m.restoreTm.Stop()
+	}
+	for _, q := range m.restored {
+		qs = append(qs, q)
+	}
+	m.restored = make(map[string]*streammodel.Queue)
+	m.mu.Unlock()
+
+	for _, q := range qs {
+		if err := m.rep.UpsertStreamQueue(ctx, q); err != nil {
+			return err
+		}
+	}
+	level.Info(m.logger).Log("msg", "persisted stream queues", "count", len(qs))
+	return nil
+}
+
+func (m *Stream) restoreQueues(ctx context.Context) error {
+	qs, err := m.rep.FetchStreamQueues(ctx, instance.ID())
+	if err != nil {
+		return err
+	}
+	if err := m.rep.DeleteStreamQueues(ctx, instance.ID()); err != nil {
+		return err
+	}
+	// queues left behind by instances that never came back are no longer resumable
+	if err := m.rep.DeleteExpiredStreamQueues(ctx, time.Now().Add(-m.cfg.HibernateTime)); err != nil {
+		return err
+	}
+	if len(qs) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	for _, q := range qs {
+		m.restored[q.Key] = q
+	}
+	// restored queues are resumable as long as a hibernated stream would be
+	m.restoreTm = time.AfterFunc(m.cfg.HibernateTime, func() {
+		m.mu.Lock()
+		m.restored = make(map[string]*streammodel.Queue)
+		m.mu.Unlock()
+	})
+	m.mu.Unlock()
+
+	level.Info(m.logger).Log("msg", "restored stream queues", "count", len(qs))
+	return nil
+}
+
+func (m *Stream) takeRestoredQueue(qk string, nonce []byte) *streammodel.Queue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.restored[qk]
+	if q == nil || !bytes.Equal(q.Nonce, nonce) {
+		return nil
+	}
+	delete(m.restored, qk)
+	return q
+}
+
+func (m *Stream) rehydrateQueue(stm stream.C2S, q *streammodel.Queue) (*streamqueue.Queue, c2smodel.ResourceDesc, error) {
+	jd, err := jid.NewWithString(q.Jid, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pr *stravaganza.Presence
+	if q.Presence != nil {
+		pr, err = stravaganza.NewBuilderFromProto(q.Presence).BuildPresence()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	elements := make([]streamqueue.Element, 0, len(q.Elements))
+	for _, elem := range q.Elements {
+		stanza, err := stravaganza.NewBuilderFromProto(elem.Stanza).BuildStanza()
+		if err != nil {
+			return nil, nil, err
+		}
+		elements = append(elements, streamqueue.Element{
+			Stanza: stanza,
+			H:      elem.H,
+		})
+	}
+	sq := streamqueue.New(
+		stm,
+		q.Nonce,
+		elements,
+		q.InH,
+		q.OutH,
+		m.cfg.RequestAckInterval,
+		m.maxRequestAckInterval(),
+		m.cfg.WaitForAckTimeout,
+	)
+	res := c2smodel.NewResourceDesc(instance.ID(), jd, pr, c2smodel.NewInfoMapFromMap(q.Info))
+	return sq, res, nil
+}
+
+func (m *Stream) acquireResumeSlot(ctx context.Context, stm stream.C2S) bool {
+	if m.resumeSlots == nil {
+		return true
+	}
+	select {
+	case m.resumeSlots <- struct{}{}:
+		return true
+	default:
+	}
+	level.Info(m.logger).Log("msg", "resumption delayed: concurrency limit reached",
+		"id", stm.ID(), "username", stm.Username(),
+	)
+	tm := time.NewTimer(m.cfg.ResumeQueueTimeout)
+	defer tm.Stop()
+
+	select {
+	case m.resumeSlots <- struct{}{}:
+		return true
+	case <-tm.C:
+	case <-ctx.Done():
+	}
+	level.Warn(m.logger).Log("msg", "resumption rejected: no free slot available",
+		"id", stm.ID(), "username", stm.Username(),
+	)
+	return false
+}
+
+func (m *Stream) releaseResumeSlot() {
+	if m.resumeSlots == nil {
+		return
+	}
+	<-m.resumeSlots
+}
+
 func (m *Stream) handleA(stm stream.C2S, h uint32) {
 	sq := m.stmQueueMap.Get(queueKey(stm.JID()))
 	if sq == nil {
@@ -464,14 +891,14 @@ func (m *Stream) handleR(stm stream.C2S) {
 	level.Info(m.logger).Log("msg", "stanza ack requested",
 		"id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
 	)
-	a := stravaganza.NewBuilder("a").
-		WithAttribute(stravaganza.Namespace, streamNamespace).
-		WithAttribute("h", strconv.FormatUint(uint64(sq.InboundH()), 10)).
-		Build()
-	stm.SendElement(a)
+	m.sendA(stm, sq.InboundH())
 }
 
-func sendFailedReply(reason string, text string, stm stream.C2S) {
+func (m *Stream) sendA(stm stream.C2S, h uint32) {
+	m.sendElement(stm, ackElement(h), nil)
+}
+
+func (m *Stream) sendFailedReply(reason string, text string, stm stream.C2S) {
 	sb := stravaganza.NewBuilder("failed").
 		WithAttribute(stravaganza.Namespace, streamNamespace).
 		WithChild(
@@ -487,7 +914,27 @@ func sendFailedReply(reason string, text string, stm stream.C2S) {
 				Build(),
 		)
 	}
-	_ = stm.SendElement(sb.Build())
+	m.sendElement(stm, sb.Build(), nil)
+}
+
+// sendElement sends elem to stm, logging any delivery failure and invoking onErr (if any)
+// so that callers can compensate stream management state.
+func (m *Stream) sendElement(stm stream.C2S, elem stravaganza.Element, onErr func()) {
+	stream.OnSendError(stm.SendElement(elem), stm.Done(), func(err error) {
+		level.Warn(m.logger).Log("msg", "failed to send stream management element",
+			"element", elem.Name(), "id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(), "err", err,
+		)
+		if onErr != nil {
+			onErr()
+		}
+	})
+}
+
+func ackElement(h uint32) stravaganza.Element {
+	return stravaganza.NewBuilder("a").
+		WithAttribute(stravaganza.Namespace, streamNamespace).
+		WithAttribute("h", strconv.FormatUint(uint64(h), 10)).
+		Build()
 }
 
 func encodeSMID(jd *jid.JID, nonce []byte) string {
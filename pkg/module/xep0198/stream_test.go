@@ -16,7 +16,10 @@ package xep0198
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
@@ -29,11 +32,16 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/hook"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
 	streamqueue "github.com/ortuman/jackal/pkg/module/xep0198/queue"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/shaper"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -94,6 +102,7 @@ func TestStream_Enable(t *testing.T) {
 	stmMock.InfoFunc = func() c2smodel.Info { return c2smodel.NewInfoMap() }
 
 	var sentEl stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
 	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
 		sentEl = elem
 		return nil
@@ -129,6 +138,7 @@ func TestStream_Enable(t *testing.T) {
 
 	require.Equal(t, "enabled", sentEl.Name())
 	require.Equal(t, streamNamespace, sentEl.Attribute(stravaganza.Namespace))
+	require.Equal(t, "60", sentEl.Attribute("max"))
 
 	sq := sm.stmQueueMap.Get(queueKey(jd))
 	require.NotNil(t, sq)
@@ -136,11 +146,191 @@ func TestStream_Enable(t *testing.T) {
 	sq.CancelTimers()
 }
 
+func TestStream_EnableSendError(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		return nil
+	}
+	stmMock.IsBindedFunc = func() bool { return true }
+	stmMock.InfoFunc = func() c2smodel.Info { return c2smodel.NewInfoMap() }
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		errCh := make(chan error, 1)
+		errCh <- errors.New("broken pipe")
+		return errCh
+	}
+
+	logger := &testLogger{}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      logger,
+	}
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("enable").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				Build(),
+		},
+		Sender: stmMock,
+	})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return sm.stmQueueMap.Get(queueKey(jd)) == nil
+	}, time.Second, time.Millisecond*10)
+
+	require.True(t, logger.contains("failed to send stream management element"))
+}
+
+func TestStream_EnableSendAborted(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	doneCh := make(chan struct{})
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		return nil
+	}
+	stmMock.IsBindedFunc = func() bool { return true }
+	stmMock.InfoFunc = func() c2smodel.Info { return c2smodel.NewInfoMap() }
+	stmMock.DoneFunc = func() <-chan struct{} { return doneCh }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		return make(chan error, 1) // never resolved
+	}
+
+	logger := &testLogger{}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      logger,
+	}
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	_, _ = hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("enable").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				Build(),
+		},
+		Sender: stmMock,
+	})
+	require.NotNil(t, sm.stmQueueMap.Get(queueKey(jd)))
+
+	close(doneCh) // stream terminated
+
+	// then
+	require.Eventually(t, func() bool {
+		return sm.stmQueueMap.Get(queueKey(jd)) == nil
+	}, time.Second, time.Millisecond*10)
+
+	require.True(t, logger.contains("failed to send stream management element"))
+}
+
+func TestStream_EnableAlreadyEnabled(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	inf := c2smodel.NewInfoMap()
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+		inf.SetBool(k, val.(bool))
+		return nil
+	}
+	stmMock.IsBindedFunc = func() bool { return true }
+	stmMock.InfoFunc = func() c2smodel.Info { return inf }
+
+	var sentElements []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sentElements = append(sentElements, elem)
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	enable := func() {
+		_, _ = hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+			Info: &hook.C2SStreamInfo{
+				Element: stravaganza.NewBuilder("enable").
+					WithAttribute(stravaganza.Namespace, streamNamespace).
+					Build(),
+			},
+			Sender: stmMock,
+		})
+	}
+	enable()
+	sq0 := sm.stmQueueMap.Get(queueKey(jd))
+
+	enable()
+	sq1 := sm.stmQueueMap.Get(queueKey(jd))
+
+	// then
+	require.Len(t, sentElements, 2)
+
+	require.Equal(t, "enabled", sentElements[0].Name())
+	require.Equal(t, "failed", sentElements[1].Name())
+	require.Equal(t, streamNamespace, sentElements[1].Attribute(stravaganza.Namespace))
+	require.NotNil(t, sentElements[1].ChildNamespace(unexpectedRequest, xmppStanzaNamespace))
+
+	require.NotNil(t, sq0)
+	require.True(t, sq0 == sq1) // queue not replaced
+
+	sq0.CancelTimers()
+}
+
 func TestStream_InStanza(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
 	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
 	stmMock.JIDFunc = func() *jid.JID { return jd }
 	stmMock.InfoFunc = func() c2smodel.Info {
 		return c2smodel.NewInfoMapFromMap(
@@ -156,7 +346,7 @@ func TestStream_InStanza(t *testing.T) {
 		logger:      kitlog.NewNopLogger(),
 	}
 	sq := streamqueue.New(
-		stmMock, nil, nil, 0, 0, time.Second, time.Minute,
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
 	)
 	sm.stmQueueMap.Set(queueKey(jd), sq)
 
@@ -191,11 +381,80 @@ func TestStream_InStanza(t *testing.T) {
 	require.Equal(t, uint32(1), sq.InboundH())
 }
 
+func TestStream_InStanzaAckEveryN(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	var sentEls []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sentEls = append(sentEls, elem)
+		return nil
+	}
+
+	cfg := testSMConfig()
+	cfg.AckEveryNInbound = 3
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         cfg,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/yard")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	testMsg, _ := b.BuildMessage()
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	for i := 0; i < 4; i++ {
+		_, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+			Info:   &hook.C2SStreamInfo{Element: testMsg},
+			Sender: stmMock,
+		})
+		require.Nil(t, err)
+	}
+
+	// then
+	require.Len(t, sentEls, 1)
+	require.Equal(t, "a", sentEls[0].Name())
+	require.Equal(t, streamNamespace, sentEls[0].Attribute(stravaganza.Namespace))
+	require.Equal(t, "3", sentEls[0].Attribute("h"))
+}
+
 func TestStream_OutStanza(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
 	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
 	stmMock.JIDFunc = func() *jid.JID { return jd }
 	stmMock.InfoFunc = func() c2smodel.Info {
 		return c2smodel.NewInfoMapFromMap(
@@ -211,7 +470,7 @@ func TestStream_OutStanza(t *testing.T) {
 		logger:      kitlog.NewNopLogger(),
 	}
 	sq := streamqueue.New(
-		stmMock, nil, nil, 0, 0, time.Second, time.Minute,
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
 	)
 	sm.stmQueueMap.Set(queueKey(jd), sq)
 
@@ -254,6 +513,8 @@ func TestStream_OutStanzaMaxQueueSizeReached(t *testing.T) {
 
 	stmMock := &c2sStreamMock{}
 	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
 	stmMock.JIDFunc = func() *jid.JID { return jd }
 	stmMock.UsernameFunc = func() string { return jd.Node() }
 	stmMock.ResourceFunc = func() string { return jd.Resource() }
@@ -290,7 +551,7 @@ func TestStream_OutStanzaMaxQueueSizeReached(t *testing.T) {
 	testMsg2, _ := b.BuildMessage()
 
 	sq := streamqueue.New(
-		stmMock, nil, nil, 0, 0, time.Second, time.Minute,
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
 	)
 	sq.HandleOut(testMsg1)
 
@@ -315,87 +576,107 @@ func TestStream_OutStanzaMaxQueueSizeReached(t *testing.T) {
 	require.Equal(t, streamerror.PolicyViolation, streamErr.Reason)
 }
 
-func TestStream_SendR(t *testing.T) {
+func TestStream_MaxQueueSizeTierAndOriginShapers(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
-	stmMock := &c2sStreamMock{}
-	stmMock.IDFunc = func() stream.C2SID { return 1234 }
-	stmMock.JIDFunc = func() *jid.JID { return jd }
-	stmMock.UsernameFunc = func() string { return jd.Node() }
-	stmMock.ResourceFunc = func() string { return jd.Resource() }
-	stmMock.InfoFunc = func() c2smodel.Info {
-		return c2smodel.NewInfoMapFromMap(
-			map[string]string{enabledInfoKey: "true"},
-		)
-	}
-	sendCh := make(chan stravaganza.Element, 1)
-	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
-		sendCh <- elem
-		return nil
-	}
+	var premiumCfg shaper.Config
+	premiumCfg.Name = "premium"
+	premiumCfg.MaxQueueSize = 100
+	premiumCfg.Matching.JID.RegEx = ".*"
+	premiumCfg.Matching.Tier = "premium"
+	premiumShp, _ := shaper.New(premiumCfg)
+
+	var spainCfg shaper.Config
+	spainCfg.Name = "spain"
+	spainCfg.MaxQueueSize = 50
+	spainCfg.Matching.JID.RegEx = ".*"
+	spainCfg.Matching.Origin.Country = []string{"ES"}
+	spainShp, _ := shaper.New(spainCfg)
+
+	cfg := testSMConfig()
+	cfg.MaxQueueSize = 10
 
-	hk := hook.NewHooks()
 	sm := &Stream{
-		cfg:         testSMConfig(),
-		stmQueueMap: streamqueue.NewQueueMap(),
-		hk:          hk,
-		logger:      kitlog.NewNopLogger(),
+		cfg:     cfg,
+		shapers: shaper.Shapers{premiumShp, spainShp},
+		logger:  kitlog.NewNopLogger(),
+	}
+	newStream := func(origin geoip.Origin, tier string) stream.C2S {
+		inf := c2smodel.NewInfoMap()
+		shaper.SetOriginInfo(inf, origin)
+		shaper.SetTierInfo(inf, tier)
+
+		stmMock := &c2sStreamMock{}
+		stmMock.JIDFunc = func() *jid.JID { return jd }
+		stmMock.InfoFunc = func() c2smodel.Info { return inf }
+		return stmMock
 	}
-	sq := streamqueue.New(
-		stmMock, nil, nil, 0, 0, time.Millisecond*500, time.Minute,
-	)
-	sm.stmQueueMap.Set(queueKey(jd), sq)
-	defer sq.CancelTimers()
 
 	// when
-	var sentEl stravaganza.Element
-	select {
-	case el := <-sendCh:
-		sentEl = el
-
-	case <-time.After(time.Second * 5):
-		require.Fail(t, "Failed to receive R element")
-		return
-	}
+	premiumSize := sm.maxQueueSize(newStream(geoip.Origin{Country: "ES"}, "premium"))
+	spainSize := sm.maxQueueSize(newStream(geoip.Origin{Country: "ES"}, ""))
+	defaultSize := sm.maxQueueSize(newStream(geoip.Origin{Country: "FR"}, ""))
 
 	// then
-	require.NotNil(t, sentEl)
-
-	require.Equal(t, "r", sentEl.Name())
-	require.Equal(t, streamNamespace, sentEl.Attribute(stravaganza.Namespace))
+	require.Equal(t, 100, premiumSize)
+	require.Equal(t, 50, spainSize)
+	require.Equal(t, 10, defaultSize)
 }
 
-func TestStream_HandleR(t *testing.T) {
+func TestStream_OutStanzaShaperMaxQueueSize(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
 	stmMock := &c2sStreamMock{}
 	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
 	stmMock.JIDFunc = func() *jid.JID { return jd }
-	stmMock.UsernameFunc = func() string { return jd.Node() }
-	stmMock.ResourceFunc = func() string { return jd.Resource() }
 	stmMock.InfoFunc = func() c2smodel.Info {
 		return c2smodel.NewInfoMapFromMap(
 			map[string]string{enabledInfoKey: "true"},
 		)
 	}
-	var sentEl stravaganza.Element
-	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
-		sentEl = elem
+	var disconnected bool
+	stmMock.DisconnectFunc = func(_ *streamerror.Error) <-chan error {
+		disconnected = true
 		return nil
 	}
 
+	cfg := testSMConfig()
+	cfg.MaxQueueSize = 1
+
+	var shpCfg shaper.Config
+	shpCfg.Name = "bots"
+	shpCfg.MaxQueueSize = 10
+	shpCfg.Matching.JID.RegEx = "^ortuman@jackal.im"
+	shp, _ := shaper.New(shpCfg)
+
 	hk := hook.NewHooks()
 	sm := &Stream{
-		cfg:         testSMConfig(),
+		cfg:         cfg,
 		stmQueueMap: streamqueue.NewQueueMap(),
+		shapers:     shaper.Shapers{shp},
 		hk:          hk,
 		logger:      kitlog.NewNopLogger(),
 	}
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "ortuman@jackal.im/yard")
+	b.WithAttribute("to", "noelia@jackal.im/yard")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	testMsg1, _ := b.BuildMessage()
+	testMsg2, _ := b.BuildMessage()
+
 	sq := streamqueue.New(
-		stmMock, nil, nil, 10, 0, time.Second, time.Minute,
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
 	)
+	sq.HandleOut(testMsg1)
+
 	sm.stmQueueMap.Set(queueKey(jd), sq)
 
 	sq.CancelTimers() // do not send R
@@ -405,23 +686,339 @@ func TestStream_HandleR(t *testing.T) {
 	_ = sm.Start(context.Background())
 	defer func() { _ = sm.Stop(context.Background()) }()
 
-	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
-		Info: &hook.C2SStreamInfo{
-			Element: stravaganza.NewBuilder("r").
-				WithAttribute(stravaganza.Namespace, streamNamespace).
-				Build(),
-		},
+	_, err := hk.Run(context.Background(), hook.C2SStreamElementSent, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{Element: testMsg2},
 		Sender: stmMock,
 	})
 
 	// then
-	require.True(t, halted)
 	require.Nil(t, err)
 
-	require.Equal(t, "a", sentEl.Name())
+	require.False(t, disconnected)
+	require.Equal(t, 2, sq.Len())
+}
+
+func TestStream_StopNotStarted(t *testing.T) {
+	// given
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	errCh := make(chan error, 1)
+	go func() { errCh <- sm.Stop(context.Background()) }()
+
+	// then
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "stop blocked on a not started module")
+	}
+}
+
+func TestStream_DeferAckRequestWhileInactive(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	var mu sync.Mutex
+	inactive := "true"
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		mu.Lock()
+		defer mu.Unlock()
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true", "xep0352:inactive": inactive},
+		)
+	}
+	var sentEls []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		mu.Lock()
+		defer mu.Unlock()
+		sentEls = append(sentEls, elem)
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Hour, 0, time.Hour,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+	defer sq.CancelTimers()
+
+	stateChanged := func() {
+		_, err := hk.Run(context.Background(), hook.C2SStreamClientStateChanged, &hook.ExecutionContext{
+			Info:   &hook.C2SStreamInfo{},
+			Sender: stmMock,
+		})
+		require.Nil(t, err)
+	}
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	stateChanged()
+	sq.RequestAck()
+
+	mu.Lock()
+	deferredCount := len(sentEls)
+	inactive = "false"
+	mu.Unlock()
+
+	stateChanged()
+
+	// then
+	mu.Lock()
+	defer mu.Unlock()
+
+	require.Equal(t, 0, deferredCount)
+	require.Len(t, sentEls, 1)
+	require.Equal(t, "r", sentEls[0].Name())
+}
+
+func TestStream_FinalAckOnWillDisconnect(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 3, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	inf := &hook.C2SStreamInfo{}
+	_, err := hk.Run(context.Background(), hook.C2SStreamWillDisconnect, &hook.ExecutionContext{
+		Info:   inf,
+		Sender: stmMock,
+	})
+
+	// then
+	require.Nil(t, err)
+
+	require.NotNil(t, inf.Element)
+	require.Equal(t, "a", inf.Element.Name())
+	require.Equal(t, streamNamespace, inf.Element.Attribute(stravaganza.Namespace))
+	require.Equal(t, "3", inf.Element.Attribute("h"))
+}
+
+func TestStream_SendR(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	sendCh := make(chan stravaganza.Element, 1)
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sendCh <- elem
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Millisecond*500, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+	defer sq.CancelTimers()
+
+	// when
+	var sentEl stravaganza.Element
+	select {
+	case el := <-sendCh:
+		sentEl = el
+
+	case <-time.After(time.Second * 5):
+		require.Fail(t, "Failed to receive R element")
+		return
+	}
+
+	// then
+	require.NotNil(t, sentEl)
+
+	require.Equal(t, "r", sentEl.Name())
+	require.Equal(t, streamNamespace, sentEl.Attribute(stravaganza.Namespace))
+}
+
+func TestStream_HandleR(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	var sentEl stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sentEl = elem
+		return nil
+	}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 10, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("r").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				Build(),
+		},
+		Sender: stmMock,
+	})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.Equal(t, "a", sentEl.Name())
 	require.Equal(t, "10", sentEl.Attribute("h"))
 }
 
+func TestStream_HandleRSendError(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(
+			map[string]string{enabledInfoKey: "true"},
+		)
+	}
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		errCh := make(chan error, 1)
+		errCh <- errors.New("broken pipe")
+		return errCh
+	}
+
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/balcony")
+	b.WithAttribute("id", uuid.New().String())
+	testMsg, _ := b.BuildMessage()
+
+	logger := &testLogger{}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      logger,
+	}
+	sq := streamqueue.New(
+		stmMock, nil, []streamqueue.Element{{Stanza: testMsg, H: 11}}, 10, 11, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("r").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				Build(),
+		},
+		Sender: stmMock,
+	})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return logger.contains("failed to send stream management element")
+	}, time.Second, time.Millisecond*10)
+
+	require.Equal(t, sq, sm.stmQueueMap.Get(queueKey(jd)))
+	require.Equal(t, uint32(10), sq.InboundH())
+	require.Equal(t, uint32(11), sq.OutboundH())
+	require.Equal(t, 1, sq.Len())
+}
+
 func TestStream_HandleA(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
@@ -437,6 +1034,7 @@ func TestStream_HandleA(t *testing.T) {
 		)
 	}
 	var sentEl stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
 	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
 		sentEl = elem
 		return nil
@@ -476,7 +1074,7 @@ func TestStream_HandleA(t *testing.T) {
 		logger:      kitlog.NewNopLogger(),
 	}
 	sq := streamqueue.New(
-		stmMock, nil, elements, 0, 0, time.Second, time.Minute,
+		stmMock, nil, elements, 0, 0, time.Second, 0, time.Minute,
 	)
 	sm.stmQueueMap.Set(queueKey(jd), sq)
 
@@ -522,6 +1120,7 @@ func TestStream_Resume(t *testing.T) {
 	stmMock.DisconnectFunc = func(_ *streamerror.Error) <-chan error { return nil }
 
 	sndElements := make([]stravaganza.Element, 0)
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
 	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
 		sndElements = append(sndElements, elem)
 		return nil
@@ -570,6 +1169,7 @@ func TestStream_Resume(t *testing.T) {
 	}
 	var streamErr *streamerror.Error
 	oldStmMock := &c2sStreamMock{}
+	oldStmMock.IDFunc = func() stream.C2SID { return 1 }
 	oldStmMock.DisconnectFunc = func(sErr *streamerror.Error) <-chan error {
 		streamErr = sErr
 		errCh := make(chan error, 1)
@@ -579,7 +1179,7 @@ func TestStream_Resume(t *testing.T) {
 
 	nc := testNonce()
 	sq := streamqueue.New(
-		oldStmMock, nc, elements, 10, 0, time.Second, time.Minute,
+		oldStmMock, nc, elements, 10, 0, time.Second, 0, time.Minute,
 	)
 	sm.stmQueueMap.Set(queueKey(jd), sq)
 
@@ -588,6 +1188,8 @@ func TestStream_Resume(t *testing.T) {
 
 	smID := encodeSMID(jd, nc)
 
+	prevReported := reportedResumes(resumeSuccess)
+
 	// when
 	_ = sm.Start(context.Background())
 	defer func() { _ = sm.Stop(context.Background()) }()
@@ -608,6 +1210,7 @@ func TestStream_Resume(t *testing.T) {
 	require.Nil(t, err)
 
 	require.True(t, resumed)
+	require.Equal(t, float64(1), reportedResumes(resumeSuccess)-prevReported)
 
 	require.Equal(t, streamerror.Conflict, streamErr.Reason)
 
@@ -621,7 +1224,7 @@ func TestStream_Resume(t *testing.T) {
 	require.Equal(t, msgID, sndElements[1].Attribute(stravaganza.ID))
 }
 
-func TestStream_ResumeRemote(t *testing.T) {
+func TestStream_ResumeSendError(t *testing.T) {
 	// given
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
@@ -633,9 +1236,173 @@ func TestStream_ResumeRemote(t *testing.T) {
 	stmMock.ResourceFunc = func() string { return jd.Resource() }
 	stmMock.DisconnectFunc = func(_ *streamerror.Error) <-chan error { return nil }
 
-	sndElements := make([]stravaganza.Element, 0)
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
 	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
-		sndElements = append(sndElements, elem)
+		if elem.Name() != "resumed" {
+			return nil
+		}
+		errCh := make(chan error, 1)
+		errCh <- errors.New("broken pipe")
+		return errCh
+	}
+	stmMock.ResumeFunc = func(ctx context.Context, jd *jid.JID, pr *stravaganza.Presence, inf c2smodel.Info) error {
+		return nil
+	}
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourceFunc = func(ctx context.Context, username string, resource string) (c2smodel.ResourceDesc, error) {
+		return c2smodel.NewResourceDesc(
+			instance.ID(),
+			jd,
+			xmpputil.MakePresence(jd, jd.ToBareJID(), stravaganza.AvailableType, nil),
+			c2smodel.NewInfoMapFromMap(
+				map[string]string{enabledInfoKey: "true"},
+			),
+		), nil
+	}
+
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "ortuman@jackal.im/yard")
+	b.WithAttribute("to", "noelia@jackal.im/yard")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msgID := uuid.New().String()
+	b.WithAttribute("id", msgID)
+	testMsg, _ := b.BuildMessage()
+
+	elements := []streamqueue.Element{
+		{Stanza: testMsg, H: 22},
+	}
+
+	logger := &testLogger{}
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		hk:          hk,
+		logger:      logger,
+	}
+	oldStmMock := &c2sStreamMock{}
+	oldStmMock.IDFunc = func() stream.C2SID { return 1 }
+	oldStmMock.DisconnectFunc = func(sErr *streamerror.Error) <-chan error {
+		errCh := make(chan error, 1)
+		errCh <- nil
+		return errCh
+	}
+
+	nc := testNonce()
+	sq := streamqueue.New(
+		oldStmMock, nc, elements, 10, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	smID := encodeSMID(jd, nc)
+
+	// when
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("resume").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				WithAttribute("previd", smID).
+				WithAttribute("h", "21").
+				Build(),
+		},
+		Sender: stmMock,
+	})
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.Eventually(t, func() bool {
+		return sm.stmQueueMap.Get(queueKey(jd)) == nil
+	}, time.Second, time.Millisecond*10)
+
+	require.True(t, logger.contains("failed to send stream management element"))
+}
+
+func TestStream_ResumeHibernationExpired(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IsAuthenticatedFunc = func() bool { return true }
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+
+	var sndElements []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
+		return nil
+	}
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourceFunc = func(ctx context.Context, username string, resource string) (c2smodel.ResourceDesc, error) {
+		return c2smodel.NewResourceDesc(instance.ID(), jd, nil, c2smodel.NewInfoMap()), nil
+	}
+
+	sm := &Stream{
+		cfg:         testSMConfig(),
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		termDls:     make(map[string]time.Time),
+		logger:      kitlog.NewNopLogger(),
+	}
+	oldStmMock := &c2sStreamMock{}
+	oldStmMock.IDFunc = func() stream.C2SID { return 1 }
+
+	nc := testNonce()
+	sq := streamqueue.New(
+		oldStmMock, nc, nil, 10, 0, time.Second, 0, time.Minute,
+	)
+	sq.CancelTimers() // do not send R
+
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+	sm.termDls[oldStmMock.ID().String()] = time.Now().Add(-time.Second) // hibernation timer elapsed
+
+	// when
+	err := sm.handleResume(context.Background(), stmMock, 0, encodeSMID(jd, nc))
+
+	// then
+	require.Nil(t, err)
+
+	require.Len(t, sndElements, 1)
+	require.Equal(t, "failed", sndElements[0].Name())
+	require.NotNil(t, sndElements[0].Child(itemNotFound))
+
+	require.Len(t, oldStmMock.DisconnectCalls(), 0)
+}
+
+func TestStream_ResumeRemote(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IsAuthenticatedFunc = func() bool { return true }
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.DisconnectFunc = func(_ *streamerror.Error) <-chan error { return nil }
+
+	sndElements := make([]stravaganza.Element, 0)
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
 		return nil
 	}
 	var resumed bool
@@ -704,6 +1471,8 @@ func TestStream_ResumeRemote(t *testing.T) {
 
 	smID := encodeSMID(jd, nc)
 
+	prevReported := reportedResumes(resumeRemote)
+
 	// when
 	_ = sm.Start(context.Background())
 	defer func() { _ = sm.Stop(context.Background()) }()
@@ -723,7 +1492,10 @@ func TestStream_ResumeRemote(t *testing.T) {
 	require.True(t, halted)
 	require.Nil(t, err)
 
+	sm.stmQueueMap.Get(queueKey(jd)).CancelTimers() // do not send R
+
 	require.True(t, resumed)
+	require.Equal(t, float64(1), reportedResumes(resumeRemote)-prevReported)
 
 	require.Len(t, sndElements, 2)
 
@@ -735,6 +1507,399 @@ func TestStream_ResumeRemote(t *testing.T) {
 	require.Equal(t, msgID, sndElements[1].Attribute(stravaganza.ID))
 }
 
+func TestStream_ResumeHintOnDrain(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+
+	var sndElements []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
+		return nil
+	}
+
+	cfg := testSMConfig()
+	cfg.ResumeLocation = "node2.jackal.im:5222"
+
+	sm := &Stream{
+		cfg:         cfg,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		termDls:     make(map[string]time.Time),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	// when
+	_ = sm.Start(context.Background())
+	_ = sm.Stop(context.Background())
+
+	// then
+	require.Len(t, sndElements, 2)
+
+	require.Equal(t, "a", sndElements[0].Name())
+
+	hint := sndElements[1]
+	require.Equal(t, "resume-hint", hint.Name())
+	require.Equal(t, resumeHintNamespace, hint.Attribute(stravaganza.Namespace))
+	require.Equal(t, "node2.jackal.im:5222", hint.Attribute("location"))
+}
+
+func TestStream_ResumeHintOnDrainStart(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+
+	var sndElements []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
+		return nil
+	}
+
+	cfg := testSMConfig()
+	cfg.ResumeLocation = "node2.jackal.im:5222"
+
+	hk := hook.NewHooks()
+	sm := &Stream{
+		cfg:         cfg,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		termDls:     make(map[string]time.Time),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	sq := streamqueue.New(
+		stmMock, nil, nil, 0, 0, time.Second, 0, time.Minute,
+	)
+	sm.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+	defer sq.CancelTimers()
+
+	_ = sm.Start(context.Background())
+	defer func() { _ = sm.Stop(context.Background()) }()
+
+	// when
+	_, err := hk.Run(context.Background(), hook.InstanceDrainingUpdated, &hook.ExecutionContext{
+		Info: &hook.InstanceInfo{Draining: true},
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, sndElements, 1)
+
+	hint := sndElements[0]
+	require.Equal(t, "resume-hint", hint.Name())
+	require.Equal(t, "node2.jackal.im:5222", hint.Attribute("location"))
+}
+
+func TestStream_ResumePersistedQueue(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+	pr := xmpputil.MakePresence(jd, jd.ToBareJID(), stravaganza.AvailableType, nil)
+
+	var persisted []*streammodel.Queue
+	repMock := &repositoryMock{}
+	repMock.UpsertStreamQueueFunc = func(_ context.Context, queue *streammodel.Queue) error {
+		persisted = append(persisted, queue)
+		return nil
+	}
+	repMock.FetchStreamQueuesFunc = func(_ context.Context, _ string) ([]*streammodel.Queue, error) {
+		return persisted, nil
+	}
+	repMock.DeleteStreamQueuesFunc = func(_ context.Context, _ string) error {
+		return nil
+	}
+	repMock.DeleteExpiredStreamQueuesFunc = func(_ context.Context, _ time.Time) error {
+		return nil
+	}
+
+	oldStmMock := &c2sStreamMock{}
+	oldStmMock.IDFunc = func() stream.C2SID { return 1234 }
+	oldStmMock.JIDFunc = func() *jid.JID { return jd }
+	oldStmMock.PresenceFunc = func() *stravaganza.Presence { return pr }
+	oldStmMock.InfoFunc = func() c2smodel.Info {
+		return c2smodel.NewInfoMapFromMap(map[string]string{enabledInfoKey: "true"})
+	}
+	oldStmMock.DoneFunc = func() <-chan struct{} { return nil }
+	oldStmMock.SendElementFunc = func(_ stravaganza.Element) <-chan error { return nil }
+
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/yard")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("I'll give thee a wind.").
+			Build(),
+	)
+	msgID := uuid.New().String()
+	b.WithAttribute("id", msgID)
+	testMsg, _ := b.BuildMessage()
+
+	cfg := testSMConfig()
+	cfg.PersistQueues = true
+
+	sm1 := &Stream{
+		cfg:         cfg,
+		rep:         repMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		termDls:     make(map[string]time.Time),
+		restored:    make(map[string]*streammodel.Queue),
+		hk:          hook.NewHooks(),
+		logger:      kitlog.NewNopLogger(),
+	}
+	nc := testNonce()
+	sq := streamqueue.New(
+		oldStmMock, nc, []streamqueue.Element{{Stanza: testMsg, H: 3}}, 7, 3, time.Second, 0, time.Minute,
+	)
+	sm1.stmQueueMap.Set(queueKey(jd), sq)
+
+	sq.CancelTimers() // do not send R
+
+	_ = sm1.Start(context.Background())
+	_ = sm1.Stop(context.Background()) // server shutdown
+
+	// server restart
+	stmMock := &c2sStreamMock{}
+	stmMock.IsAuthenticatedFunc = func() bool { return true }
+	stmMock.IDFunc = func() stream.C2SID { return 5678 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+
+	sndElements := make([]stravaganza.Element, 0)
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
+		return nil
+	}
+	var resumedJID *jid.JID
+	var resumedPr *stravaganza.Presence
+	stmMock.ResumeFunc = func(ctx context.Context, jd *jid.JID, pr *stravaganza.Presence, inf c2smodel.Info) error {
+		resumedJID = jd
+		resumedPr = pr
+		return nil
+	}
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourceFunc = func(ctx context.Context, username string, resource string) (c2smodel.ResourceDesc, error) {
+		return nil, nil // resource lost on restart
+	}
+	hk := hook.NewHooks()
+	sm2 := &Stream{
+		cfg:         cfg,
+		rep:         repMock,
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		termTms:     make(map[string]*time.Timer),
+		termDls:     make(map[string]time.Time),
+		restored:    make(map[string]*streammodel.Queue),
+		hk:          hk,
+		logger:      kitlog.NewNopLogger(),
+	}
+	smID := encodeSMID(jd, nc)
+
+	// when
+	_ = sm2.Start(context.Background())
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			Element: stravaganza.NewBuilder("resume").
+				WithAttribute(stravaganza.Namespace, streamNamespace).
+				WithAttribute("previd", smID).
+				WithAttribute("h", "2").
+				Build(),
+		},
+		Sender: stmMock,
+	})
+	rsq := sm2.stmQueueMap.Get(queueKey(jd))
+	if rsq != nil {
+		rsq.CancelTimers()
+	}
+
+	// then
+	require.True(t, halted)
+	require.Nil(t, err)
+
+	require.Len(t, repMock.UpsertStreamQueueCalls(), 1)
+	require.Len(t, repMock.DeleteStreamQueuesCalls(), 2)
+	require.Len(t, repMock.DeleteExpiredStreamQueuesCalls(), 2)
+	require.NotNil(t, persisted[0].PersistedAt)
+
+	require.NotNil(t, resumedJID)
+	require.Equal(t, jd.String(), resumedJID.String())
+	require.NotNil(t, resumedPr)
+	require.True(t, resumedPr.IsAvailable())
+
+	require.Len(t, sndElements, 2)
+
+	require.Equal(t, "resumed", sndElements[0].Name())
+	require.Equal(t, smID, sndElements[0].Attribute("previd"))
+	require.Equal(t, "7", sndElements[0].Attribute("h"))
+
+	require.Equal(t, msgID, sndElements[1].Attribute(stravaganza.ID))
+
+	require.NotNil(t, rsq)
+	require.Equal(t, uint32(3), rsq.OutboundH())
+	require.Len(t, sm2.restored, 0)
+}
+
+func TestStream_ResumeConcurrencyLimit(t *testing.T) {
+	// given
+	const resumptions = 6
+
+	var mu sync.Mutex
+	var inFlight, maxInFlight int
+
+	resMngMock := &resourceManagerMock{}
+	resMngMock.GetResourceFunc = func(ctx context.Context, username string, resource string) (c2smodel.ResourceDesc, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond * 25) // simulate queue transfer
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil, nil
+	}
+	cfg := testSMConfig()
+	cfg.MaxConcurrentResumptions = 2
+	cfg.ResumeQueueTimeout = time.Second * 5
+
+	sm := &Stream{
+		cfg:         cfg,
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		resumeSlots: make(chan struct{}, cfg.MaxConcurrentResumptions),
+		logger:      kitlog.NewNopLogger(),
+	}
+
+	// when
+	var wg sync.WaitGroup
+	replies := make([]stravaganza.Element, resumptions)
+
+	for i := 0; i < resumptions; i++ {
+		jd, _ := jid.NewWithString(fmt.Sprintf("ortuman@jackal.im/yard%d", i), true)
+
+		idx := i
+
+		stmMock := &c2sStreamMock{}
+		stmMock.IsAuthenticatedFunc = func() bool { return true }
+		stmMock.IDFunc = func() stream.C2SID { return stream.C2SID(idx) }
+		stmMock.UsernameFunc = func() string { return jd.Node() }
+
+		stmMock.DoneFunc = func() <-chan struct{} { return nil }
+		stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+			replies[idx] = elem
+			return nil
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = sm.handleResume(context.Background(), stmMock, 0, encodeSMID(jd, testNonce()))
+		}()
+	}
+	wg.Wait()
+
+	// then
+	require.Equal(t, 2, maxInFlight)
+	require.Len(t, sm.resumeSlots, 0)
+
+	for _, reply := range replies {
+		require.NotNil(t, reply)
+		require.Equal(t, "failed", reply.Name())
+		require.NotNil(t, reply.Child(itemNotFound)) // every resumption got eventually processed
+	}
+}
+
+func TestStream_ResumeQueueTimeout(t *testing.T) {
+	// given
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IsAuthenticatedFunc = func() bool { return true }
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+
+	var sndElements []stravaganza.Element
+	stmMock.DoneFunc = func() <-chan struct{} { return nil }
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sndElements = append(sndElements, elem)
+		return nil
+	}
+	cfg := testSMConfig()
+	cfg.MaxConcurrentResumptions = 1
+	cfg.ResumeQueueTimeout = time.Millisecond * 50
+
+	resMngMock := &resourceManagerMock{}
+	sm := &Stream{
+		cfg:         cfg,
+		resMng:      resMngMock,
+		stmQueueMap: streamqueue.NewQueueMap(),
+		resumeSlots: make(chan struct{}, cfg.MaxConcurrentResumptions),
+		logger:      kitlog.NewNopLogger(),
+	}
+	sm.resumeSlots <- struct{}{} // busy slot
+
+	// when
+	err := sm.handleResume(context.Background(), stmMock, 0, encodeSMID(jd, testNonce()))
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, resMngMock.GetResourceCalls(), 0)
+
+	require.Len(t, sndElements, 1)
+	require.Equal(t, "failed", sndElements[0].Name())
+	require.NotNil(t, sndElements[0].Child(resourceConstraint))
+}
+
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Log(keyvals ...interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := 0; i < len(keyvals)-1; i += 2 {
+		if keyvals[i] == "msg" {
+			l.msgs = append(l.msgs, fmt.Sprint(keyvals[i+1]))
+		}
+	}
+	return nil
+}
+
+func (l *testLogger) contains(msg string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range l.msgs {
+		if m == msg {
+			return true
+		}
+	}
+	return false
+}
+
 func testSMConfig() Config {
 	return Config{
 		HibernateTime:      time.Minute,
@@ -751,3 +1916,7 @@ func testNonce() []byte {
 	}
 	return nonce
 }
+
+func reportedResumes(result string) float64 {
+	return testutil.ToFloat64(xep0198Resumes.With(prometheus.Labels{"instance": instance.ID(), "result": result}))
+}
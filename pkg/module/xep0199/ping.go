@@ -29,8 +29,10 @@ import (
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/util/iqtracker"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -50,10 +52,17 @@ const (
 	killAction = "kill"
 )
 
+const (
+	pingReceivedCounter = "ping_received"
+	pingSentCounter     = "ping_sent"
+	pingTimeoutCounter  = "ping_timeout"
+)
+
 // Config contains ping module configuration options.
 type Config struct {
 	// AckTimeout tells how long should we wait until considering a client to be disconnected.
-	AckTimeout time.Duration `fig:"ack_timeout" default:"32s"`
+	// If not set, the modules shared IQ timeout is used.
+	AckTimeout time.Duration `fig:"ack_timeout"`
 	// Interval tells how often pings should be sent to clients.
 	Interval time.Duration `fig:"interval" default:"1m"`
 	// SendPings tells whether server pings should be sent.
@@ -64,25 +73,29 @@ type Config struct {
 
 // Ping represents ping (XEP-0199) module type.
 type Ping struct {
-	cfg    Config
-	router router.Router
-	hk     *hook.Hooks
-	logger kitlog.Logger
+	cfg     Config
+	router  router.Router
+	hk      *hook.Hooks
+	metrics *module.Metrics
+	logger  kitlog.Logger
+	acks    *iqtracker.Tracker
 
 	mu         sync.RWMutex
 	pingTimers map[string]*time.Timer
-	ackTimers  map[string]*time.Timer
+	ackIDs     map[string]string
 }
 
 // New returns a new initialized ping instance.
-func New(cfg Config, router router.Router, hk *hook.Hooks, logger kitlog.Logger) *Ping {
+func New(cfg Config, router router.Router, hk *hook.Hooks, metrics *module.Metrics, logger kitlog.Logger) *Ping {
 	return &Ping{
 		cfg:        cfg,
 		router:     router,
 		hk:         hk,
+		metrics:    metrics,
 		logger:     kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		acks:       iqtracker.New(cfg.AckTimeout),
 		pingTimers: make(map[string]*time.Timer),
-		ackTimers:  make(map[string]*time.Timer),
+		ackIDs:     make(map[string]string),
 	}
 }
 
@@ -110,6 +123,7 @@ func (p *Ping) Start(_ context.Context) error {
 		p.hk.AddHook(hook.C2SStreamBinded, p.onBinded, hook.DefaultPriority)
 		p.hk.AddHook(hook.C2SStreamDisconnected, p.onDisconnect, hook.HighestPriority)
 		p.hk.AddHook(hook.C2SStreamElementReceived, p.onRecvElement, hook.HighestPriority)
+		p.hk.AddHook(hook.C2SStreamWhitespaceKeepAlive, p.onRecvElement, hook.HighestPriority)
 	}
 	level.Info(p.logger).Log("msg", "started ping module")
 	return nil
@@ -121,7 +135,10 @@ func (p *Ping) Stop(_ context.Context) error {
 		p.hk.RemoveHook(hook.C2SStreamBinded, p.onBinded)
 		p.hk.RemoveHook(hook.C2SStreamDisconnected, p.onDisconnect)
 		p.hk.RemoveHook(hook.C2SStreamElementReceived, p.onRecvElement)
+		p.hk.RemoveHook(hook.C2SStreamWhitespaceKeepAlive, p.onRecvElement)
 	}
+	p.acks.Stop()
+
 	level.Info(p.logger).Log("msg", "stopped ping module")
 	return nil
 }
@@ -131,10 +148,16 @@ func (p *Ping) MatchesNamespace(namespace string, _ bool) bool {
 	return namespace == pingNamespace
 }
 
+// Namespaces returns all iq namespaces matched by ping module.
+func (p *Ping) Namespaces() []string {
+	return []string{pingNamespace}
+}
+
 // ProcessIQ process a ping iq.
 func (p *Ping) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
 	case isPingIQ(iq):
+		p.metrics.IncCounter(pingReceivedCounter)
 		return p.sendPongReply(ctx, iq)
 	default:
 		_, _ = p.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
@@ -182,8 +205,10 @@ func (p *Ping) schedulePing(jd *jid.JID) {
 }
 
 func (p *Ping) sendPing(jd *jid.JID) {
+	reqID := uuid.New().String()
+
 	iq, _ := stravaganza.NewIQBuilder().
-		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.ID, reqID).
 		WithAttribute(stravaganza.Type, stravaganza.GetType).
 		WithAttribute(stravaganza.From, jd.Domain()).
 		WithAttribute(stravaganza.To, jd.String()).
@@ -199,18 +224,30 @@ func (p *Ping) sendPing(jd *jid.JID) {
 	defer cancel()
 
 	_, _ = p.router.Route(ctx, iq)
+	p.metrics.IncCounter(pingSentCounter)
 
-	// schedule ack timeout
+	// track ping request until acknowledged
 	p.mu.Lock()
-	p.ackTimers[jd.String()] = time.AfterFunc(p.cfg.AckTimeout, func() {
-		p.timeout(jd)
-	})
+	p.ackIDs[jd.String()] = reqID
 	p.mu.Unlock()
 
+	p.acks.Track(reqID, jd, func(_ interface{}) {
+		p.timeout(jd, reqID)
+	})
+
 	level.Info(p.logger).Log("msg", "sent ping", "jid", jd.String())
 }
 
-func (p *Ping) timeout(jd *jid.JID) {
+func (p *Ping) timeout(jd *jid.JID, reqID string) {
+	jk := jd.String()
+	p.mu.Lock()
+	if p.ackIDs[jk] == reqID {
+		delete(p.ackIDs, jk)
+	}
+	p.mu.Unlock()
+
+	p.metrics.IncCounter(pingTimeoutCounter)
+
 	// perform timeout action
 	switch p.cfg.TimeoutAction {
 	case killAction:
@@ -227,11 +264,11 @@ func (p *Ping) cancelTimers(jd *jid.JID) {
 	if tm := p.pingTimers[jk]; tm != nil {
 		tm.Stop()
 	}
-	if tm := p.ackTimers[jk]; tm != nil {
-		tm.Stop()
+	if reqID, ok := p.ackIDs[jk]; ok {
+		p.acks.Resolve(reqID)
 	}
 	delete(p.pingTimers, jk)
-	delete(p.ackTimers, jk)
+	delete(p.ackIDs, jk)
 	p.mu.Unlock()
 }
 
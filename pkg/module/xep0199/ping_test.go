@@ -27,6 +27,7 @@ import (
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/module"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
 	"github.com/stretchr/testify/require"
@@ -41,7 +42,7 @@ func TestPing_Pong(t *testing.T) {
 		_ = stanza.ToXML(outBuf, true)
 		return nil, nil
 	}
-	p := New(Config{}, routerMock, &hook.Hooks{}, kitlog.NewNopLogger())
+	p := New(Config{}, routerMock, &hook.Hooks{}, module.NewMetrics(ModuleName), kitlog.NewNopLogger())
 
 	// when
 	iq, _ := stravaganza.NewIQBuilder().
@@ -77,7 +78,7 @@ func TestPing_SendPing(t *testing.T) {
 	p := New(Config{
 		Interval:  time.Millisecond * 500,
 		SendPings: true,
-	}, routerMock, hk, kitlog.NewNopLogger())
+	}, routerMock, hk, module.NewMetrics(ModuleName), kitlog.NewNopLogger())
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
 	// when
@@ -123,7 +124,7 @@ func TestPing_Timeout(t *testing.T) {
 		AckTimeout:    time.Millisecond * 250,
 		SendPings:     true,
 		TimeoutAction: killAction,
-	}, routerMock, hk, kitlog.NewNopLogger())
+	}, routerMock, hk, module.NewMetrics(ModuleName), kitlog.NewNopLogger())
 	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
 
 	// when
@@ -138,4 +139,64 @@ func TestPing_Timeout(t *testing.T) {
 
 	// then
 	require.Len(t, c2sStream.DisconnectCalls(), 1)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	require.Equal(t, 0, p.acks.Len())
+	require.Len(t, p.ackIDs, 0)
+}
+
+func TestPing_Ack(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		return nil, nil
+	}
+	c2sStream := &streamMock{}
+	c2sStream.IsBindedFunc = func() bool { return true }
+	c2sStream.DisconnectFunc = func(streamErr *streamerror.Error) <-chan error {
+		return nil
+	}
+	c2sRouterMock := &c2sRouterMock{}
+	c2sRouterMock.LocalStreamFunc = func(username string, resource string) stream.C2S {
+		return c2sStream
+	}
+	routerMock.C2SFunc = func() router.C2SRouter {
+		return c2sRouterMock
+	}
+
+	hk := hook.NewHooks()
+	p := New(Config{
+		Interval:      time.Millisecond * 250,
+		AckTimeout:    time.Millisecond * 500,
+		SendPings:     true,
+		TimeoutAction: killAction,
+	}, routerMock, hk, module.NewMetrics(ModuleName), kitlog.NewNopLogger())
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	// when
+	_ = p.Start(context.Background())
+	_, _ = hk.Run(context.Background(), hook.C2SStreamBinded, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			ID:  "c2s1",
+			JID: jd,
+		},
+	})
+	time.Sleep(time.Millisecond * 350) // wait until ping is triggered
+
+	pendingAcks := p.acks.Len()
+
+	_, _ = hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			ID:  "c2s1",
+			JID: jd,
+		},
+		Sender: c2sStream,
+	})
+	_ = p.Stop(context.Background())
+
+	// then
+	require.Equal(t, 1, pendingAcks)
+	require.Equal(t, 0, p.acks.Len())
+	require.Len(t, c2sStream.DisconnectCalls(), 0)
 }
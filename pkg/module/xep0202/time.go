@@ -16,6 +16,7 @@ package xep0202
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/go-kit/log/level"
@@ -25,10 +26,16 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/util/clock"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
-const timeNamespace = "urn:xmpp:time"
+const (
+	timeNamespace = "urn:xmpp:time"
+	skewNamespace = "urn:jackal:time:skew:0"
+)
+
+const skewQueryTimeout = time.Second * 5
 
 const (
 	// ModuleName represents time module name.
@@ -38,22 +45,46 @@ const (
 	XEPNumber = "0202"
 )
 
+// Config contains time module configuration options.
+type Config struct {
+	// ReportSkew tells whether or not server perceived clock skew relative to
+	// NTP reference time should be included in entity time responses, when known.
+	ReportSkew bool `fig:"report_skew"`
+
+	// NTPServer specifies the NTP server address used to measure clock skew.
+	NTPServer string `fig:"ntp_server" default:"pool.ntp.org:123"`
+
+	// SkewCheckInterval tells how often clock skew should be measured.
+	SkewCheckInterval time.Duration `fig:"skew_check_interval" default:"15m"`
+}
+
 // Time represents a last activity (XEP-0202) module type.
 type Time struct {
-	router router.Router
-	tmFn   func() time.Time
-	logger kitlog.Logger
+	cfg       Config
+	router    router.Router
+	tmFn      func() time.Time
+	skewFn    func() (time.Duration, bool)
+	setSkewFn func(time.Duration)
+	queryFn   func(ctx context.Context, addr string) (time.Duration, error)
+	logger    kitlog.Logger
+	doneCh    chan chan struct{}
 }
 
 // New returns a new initialized Time instance.
 func New(
+	cfg Config,
 	router router.Router,
 	logger kitlog.Logger,
 ) *Time {
 	return &Time{
-		router: router,
-		tmFn:   time.Now,
-		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		cfg:       cfg,
+		router:    router,
+		tmFn:      clock.Now,
+		skewFn:    clock.Skew,
+		setSkewFn: clock.SetSkew,
+		queryFn:   clock.QueryOffset,
+		logger:    kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		doneCh:    make(chan chan struct{}),
 	}
 }
 
@@ -77,12 +108,20 @@ func (m *Time) AccountFeatures(_ context.Context) ([]string, error) {
 
 // Start starts time module.
 func (m *Time) Start(_ context.Context) error {
+	if m.cfg.ReportSkew {
+		go m.skewLoop()
+	}
 	level.Info(m.logger).Log("msg", "started time module")
 	return nil
 }
 
 // Stop stops time module.
 func (m *Time) Stop(_ context.Context) error {
+	if m.cfg.ReportSkew {
+		ch := make(chan struct{})
+		m.doneCh <- ch
+		<-ch
+	}
 	level.Info(m.logger).Log("msg", "stopped time module")
 	return nil
 }
@@ -95,6 +134,11 @@ func (m *Time) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == timeNamespace
 }
 
+// Namespaces returns all iq namespaces matched by time module.
+func (m *Time) Namespaces() []string {
+	return []string{timeNamespace}
+}
+
 // ProcessIQ process a time iq.
 func (m *Time) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -110,11 +154,50 @@ func (m *Time) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 func (m *Time) reportServerTime(ctx context.Context, iq *stravaganza.IQ) {
 	tm := m.tmFn()
 
-	resIQ := xmpputil.MakeResultIQ(iq, stravaganza.NewBuilder("time").
+	tb := stravaganza.NewBuilder("time").
 		WithAttribute(stravaganza.Namespace, timeNamespace).
 		WithChild(stravaganza.NewBuilder("tzo").WithText(tm.Format("-07:00")).Build()).
-		WithChild(stravaganza.NewBuilder("utc").WithText(tm.UTC().Format("2006-01-02T15:04:05Z")).Build()).
-		Build(),
-	)
+		WithChild(stravaganza.NewBuilder("utc").WithText(clock.FormatStamp(tm)).Build())
+
+	if m.cfg.ReportSkew {
+		if skew, ok := m.skewFn(); ok {
+			tb.WithChild(stravaganza.NewBuilder("skew").
+				WithAttribute(stravaganza.Namespace, skewNamespace).
+				WithAttribute("ms", strconv.FormatInt(skew.Milliseconds(), 10)).
+				Build(),
+			)
+		}
+	}
+	resIQ := xmpputil.MakeResultIQ(iq, tb.Build())
 	_, _ = m.router.Route(ctx, resIQ)
 }
+
+func (m *Time) skewLoop() {
+	tc := time.NewTicker(m.cfg.SkewCheckInterval)
+	defer tc.Stop()
+
+	m.measureSkew()
+	for {
+		select {
+		case <-tc.C:
+			m.measureSkew()
+
+		case ch := <-m.doneCh:
+			close(ch)
+			return
+		}
+	}
+}
+
+func (m *Time) measureSkew() {
+	ctx, cancel := context.WithTimeout(context.Background(), skewQueryTimeout)
+	defer cancel()
+
+	skew, err := m.queryFn(ctx, m.cfg.NTPServer)
+	if err != nil {
+		level.Warn(m.logger).Log("msg", "failed to measure clock skew", "ntp_server", m.cfg.NTPServer, "err", err)
+		return
+	}
+	m.setSkewFn(skew)
+	level.Debug(m.logger).Log("msg", "measured clock skew", "ntp_server", m.cfg.NTPServer, "skew", skew)
+}
@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/google/uuid"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/jackal-xmpp/stravaganza/jid"
@@ -39,6 +40,9 @@ func TestTime_GetTime(t *testing.T) {
 		tmFn: func() time.Time {
 			return time.Date(1984, 01, 03, 00, 00, 00, 00, time.UTC)
 		},
+		skewFn: func() (time.Duration, bool) {
+			return time.Second, true
+		},
 	}
 
 	// when
@@ -69,4 +73,85 @@ func TestTime_GetTime(t *testing.T) {
 
 	require.Equal(t, "+00:00", tzo.Text())
 	require.Equal(t, "1984-01-03T00:00:00Z", utc.Text())
+
+	require.Nil(t, tm.ChildNamespace("skew", skewNamespace))
+}
+
+func TestTime_GetTimeWithSkew(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := &Time{
+		cfg:    Config{ReportSkew: true},
+		router: routerMock,
+		tmFn: func() time.Time {
+			return time.Date(1984, 01, 03, 00, 00, 00, 00, time.UTC)
+		},
+		skewFn: func() (time.Duration, bool) {
+			return -1500 * time.Millisecond, true
+		},
+	}
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/chamber").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("time").
+				WithAttribute(stravaganza.Namespace, timeNamespace).
+				Build(),
+		).
+		BuildIQ()
+	_ = m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.Len(t, respStanzas, 1)
+
+	tm := respStanzas[0].ChildNamespace("time", timeNamespace)
+	require.NotNil(t, tm)
+
+	require.Equal(t, "1984-01-03T00:00:00Z", tm.Child("utc").Text())
+
+	skew := tm.ChildNamespace("skew", skewNamespace)
+	require.NotNil(t, skew)
+	require.Equal(t, "-1500", skew.Attribute("ms"))
+}
+
+func TestTime_MeasureSkew(t *testing.T) {
+	// given
+	skewCh := make(chan time.Duration, 1)
+
+	m := New(Config{
+		ReportSkew:        true,
+		NTPServer:         "ntp.jackal.im:123",
+		SkewCheckInterval: time.Hour,
+	}, nil, kitlog.NewNopLogger())
+
+	var ntpServer string
+	m.queryFn = func(_ context.Context, addr string) (time.Duration, error) {
+		ntpServer = addr
+		return 250 * time.Millisecond, nil
+	}
+	m.setSkewFn = func(skew time.Duration) { skewCh <- skew }
+
+	// when
+	_ = m.Start(context.Background())
+
+	var skew time.Duration
+	select {
+	case skew = <-skewCh:
+	case <-time.After(time.Second):
+	}
+	_ = m.Stop(context.Background())
+
+	// then
+	require.Equal(t, "ntp.jackal.im:123", ntpServer)
+	require.Equal(t, 250*time.Millisecond, skew)
 }
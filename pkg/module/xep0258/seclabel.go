@@ -0,0 +1,254 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0258
+
+import (
+	"context"
+	"strings"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/ortuman/jackal/pkg/router"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const (
+	secLabelNamespace = "urn:xmpp:sec-label:0"
+	catalogNamespace  = "urn:xmpp:sec-label:catalog:2"
+	essNamespace      = "urn:xmpp:sec-label:ess:0"
+)
+
+const (
+	// ModuleName represents security labels module name.
+	ModuleName = "seclabel"
+
+	// XEPNumber represents security labels XEP number.
+	XEPNumber = "0258"
+)
+
+// LabelConfig defines a security label catalog item.
+type LabelConfig struct {
+	// Selector defines the catalog item selector (ie. "Classified|SECRET").
+	Selector string `fig:"selector"`
+
+	// DisplayMarking defines the label human readable marking.
+	DisplayMarking string `fig:"display_marking"`
+
+	// FgColor defines display marking foreground color.
+	FgColor string `fig:"fg_color"`
+
+	// BgColor defines display marking background color.
+	BgColor string `fig:"bg_color"`
+
+	// ESSLabel defines the base64 encoded ESS security label.
+	ESSLabel string `fig:"ess_label"`
+
+	// Default tells whether this item is the catalog default one.
+	Default bool `fig:"default"`
+}
+
+// Config contains security labels module configuration options.
+type Config struct {
+	// CatalogName defines the advertised catalog name.
+	CatalogName string `fig:"catalog_name" default:"Default"`
+
+	// CatalogDesc defines the advertised catalog description.
+	CatalogDesc string `fig:"catalog_desc"`
+
+	// Labels defines the set of labels contained in the catalog.
+	Labels []LabelConfig `fig:"labels"`
+}
+
+// SecLabel represents a security labels (XEP-0258) module type.
+type SecLabel struct {
+	cfg    Config
+	router router.Router
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	essLabels map[string]struct{}
+}
+
+// New returns a new initialized SecLabel instance.
+func New(
+	cfg Config,
+	router router.Router,
+	hk *hook.Hooks,
+	logger kitlog.Logger,
+) *SecLabel {
+	essLabels := make(map[string]struct{}, len(cfg.Labels))
+	for _, lb := range cfg.Labels {
+		essLabels[strings.TrimSpace(lb.ESSLabel)] = struct{}{}
+	}
+	return &SecLabel{
+		cfg:       cfg,
+		router:    router,
+		hk:        hk,
+		essLabels: essLabels,
+		logger:    kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+	}
+}
+
+// Name returns security labels module name.
+func (m *SecLabel) Name() string { return ModuleName }
+
+// StreamFeature returns security labels module stream feature.
+func (m *SecLabel) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns security labels server disco features.
+func (m *SecLabel) ServerFeatures(_ context.Context) ([]string, error) {
+	return []string{secLabelNamespace, catalogNamespace}, nil
+}
+
+// AccountFeatures returns security labels account disco features.
+func (m *SecLabel) AccountFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// MatchesNamespace tells whether namespace matches security labels module.
+func (m *SecLabel) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if !serverTarget {
+		return false
+	}
+	return namespace == catalogNamespace
+}
+
+// Namespaces returns all iq namespaces matched by security labels module.
+func (m *SecLabel) Namespaces() []string {
+	return []string{catalogNamespace}
+}
+
+// ProcessIQ process a security labels iq.
+func (m *SecLabel) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	switch {
+	case iq.IsGet():
+		return m.getCatalog(ctx, iq)
+	case iq.IsSet():
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.Forbidden))
+	}
+	return nil
+}
+
+// Start starts security labels module.
+func (m *SecLabel) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamWillRouteElement, m.onElementWillRoute, hook.HighestPriority)
+	m.hk.AddHook(hook.S2SInStreamWillRouteElement, m.onElementWillRoute, hook.HighestPriority)
+
+	level.Info(m.logger).Log("msg", "started seclabel module")
+	return nil
+}
+
+// Stop stops security labels module.
+func (m *SecLabel) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamWillRouteElement, m.onElementWillRoute)
+	m.hk.RemoveHook(hook.S2SInStreamWillRouteElement, m.onElementWillRoute)
+
+	level.Info(m.logger).Log("msg", "stopped seclabel module")
+	return nil
+}
+
+func (m *SecLabel) onElementWillRoute(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	var elem stravaganza.Element
+
+	switch inf := execCtx.Info.(type) {
+	case *hook.C2SStreamInfo:
+		elem = inf.Element
+	case *hook.S2SStreamInfo:
+		elem = inf.Element
+	}
+	stanza, ok := elem.(stravaganza.Stanza)
+	if !ok {
+		return nil
+	}
+	secLabel := stanza.ChildNamespace("securitylabel", secLabelNamespace)
+	if secLabel == nil {
+		return nil
+	}
+	if m.isValidLabel(secLabel) {
+		return nil
+	}
+	if xmpputil.IsBounceable(stanza) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(stanza, stanzaerror.NotAcceptable))
+	}
+	level.Info(m.logger).Log("msg", "rejected stanza with invalid security label",
+		"from", stanza.FromJID().String(), "to", stanza.ToJID().String(),
+	)
+	return hook.ErrStopped
+}
+
+func (m *SecLabel) isValidLabel(secLabel stravaganza.Element) bool {
+	lb := secLabel.Child("label")
+	if lb == nil {
+		return false
+	}
+	ess := lb.ChildNamespace("esssecuritylabel", essNamespace)
+	if ess == nil {
+		return false
+	}
+	_, ok := m.essLabels[strings.TrimSpace(ess.Text())]
+	return ok
+}
+
+func (m *SecLabel) getCatalog(ctx context.Context, iq *stravaganza.IQ) error {
+	cb := stravaganza.NewBuilder("catalog").
+		WithAttribute(stravaganza.Namespace, catalogNamespace).
+		WithAttribute(stravaganza.To, iq.ToJID().Domain()).
+		WithAttribute("name", m.cfg.CatalogName).
+		WithAttribute("restrict", "false")
+	if len(m.cfg.CatalogDesc) > 0 {
+		cb.WithAttribute("desc", m.cfg.CatalogDesc)
+	}
+	for _, lb := range m.cfg.Labels {
+		ib := stravaganza.NewBuilder("item").
+			WithAttribute("selector", lb.Selector).
+			WithChild(secLabelElement(lb))
+		if lb.Default {
+			ib.WithAttribute("default", "true")
+		}
+		cb.WithChild(ib.Build())
+	}
+	_, _ = m.router.Route(ctx, xmpputil.MakeResultIQ(iq, cb.Build()))
+	return nil
+}
+
+func secLabelElement(lb LabelConfig) stravaganza.Element {
+	dmb := stravaganza.NewBuilder("displaymarking").
+		WithText(lb.DisplayMarking)
+	if len(lb.FgColor) > 0 {
+		dmb.WithAttribute("fgcolor", lb.FgColor)
+	}
+	if len(lb.BgColor) > 0 {
+		dmb.WithAttribute("bgcolor", lb.BgColor)
+	}
+	return stravaganza.NewBuilder("securitylabel").
+		WithAttribute(stravaganza.Namespace, secLabelNamespace).
+		WithChild(dmb.Build()).
+		WithChild(
+			stravaganza.NewBuilder("label").
+				WithChild(
+					stravaganza.NewBuilder("esssecuritylabel").
+						WithAttribute(stravaganza.Namespace, essNamespace).
+						WithText(lb.ESSLabel).
+						Build(),
+				).
+				Build(),
+		).
+		Build()
+}
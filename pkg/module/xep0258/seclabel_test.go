@@ -0,0 +1,258 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0258
+
+import (
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecLabel_GetCatalog(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := New(testConfig(), routerMock, hook.NewHooks(), kitlog.NewNopLogger())
+
+	// when
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/chamber").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithChild(
+			stravaganza.NewBuilder("catalog").
+				WithAttribute(stravaganza.Namespace, catalogNamespace).
+				WithAttribute(stravaganza.To, "jackal.im").
+				Build(),
+		).
+		BuildIQ()
+	_ = m.ProcessIQ(context.Background(), iq)
+
+	// then
+	require.True(t, m.MatchesNamespace(catalogNamespace, true))
+	require.False(t, m.MatchesNamespace(catalogNamespace, false))
+
+	features, _ := m.ServerFeatures(context.Background())
+	require.Equal(t, []string{secLabelNamespace, catalogNamespace}, features)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+
+	catalog := respStanzas[0].ChildNamespace("catalog", catalogNamespace)
+	require.NotNil(t, catalog)
+	require.Equal(t, "Default", catalog.Attribute("name"))
+
+	items := catalog.Children("item")
+	require.Len(t, items, 2)
+
+	require.Equal(t, "Classified|SECRET", items[0].Attribute("selector"))
+	require.Equal(t, "", items[0].Attribute("default"))
+	require.Equal(t, "true", items[1].Attribute("default"))
+
+	secLabel := items[0].ChildNamespace("securitylabel", secLabelNamespace)
+	require.NotNil(t, secLabel)
+	require.Equal(t, "SECRET", secLabel.Child("displaymarking").Text())
+	require.Equal(t, "red", secLabel.Child("displaymarking").Attribute("bgcolor"))
+	require.Equal(t, "MQYCAQQGASk=", secLabel.Child("label").ChildNamespace("esssecuritylabel", essNamespace).Text())
+}
+
+func TestSecLabel_ValidateLabel(t *testing.T) {
+	var tests = []struct {
+		name        string
+		label       stravaganza.Element
+		expRejected bool
+	}{
+		{
+			name:        "Unlabeled",
+			expRejected: false,
+		},
+		{
+			name:        "Valid",
+			label:       testLabel("MQYCAQQGASk="),
+			expRejected: false,
+		},
+		{
+			name:        "Unknown",
+			label:       testLabel("MQYCAQQGAAA="),
+			expRejected: true,
+		},
+		{
+			name: "Malformed",
+			label: stravaganza.NewBuilder("securitylabel").
+				WithAttribute(stravaganza.Namespace, secLabelNamespace).
+				Build(),
+			expRejected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+			hk := hook.NewHooks()
+			m := New(testConfig(), routerMock, hk, kitlog.NewNopLogger())
+
+			b := stravaganza.NewMessageBuilder().
+				WithAttribute(stravaganza.ID, uuid.New().String()).
+				WithAttribute(stravaganza.From, "ortuman@jackal.im/chamber").
+				WithAttribute(stravaganza.To, "noelia@jackal.im").
+				WithChild(
+					stravaganza.NewBuilder("body").
+						WithText("I'll give thee a wind.").
+						Build(),
+				)
+			if tt.label != nil {
+				b.WithChild(tt.label)
+			}
+			msg, _ := b.BuildMessage()
+
+			// when
+			_ = m.Start(context.Background())
+			defer func() { _ = m.Stop(context.Background()) }()
+
+			halted, err := hk.Run(context.Background(), hook.C2SStreamWillRouteElement, &hook.ExecutionContext{
+				Info: &hook.C2SStreamInfo{Element: msg},
+			})
+
+			// then
+			require.Nil(t, err)
+			require.Equal(t, tt.expRejected, halted)
+
+			if !tt.expRejected {
+				require.Len(t, respStanzas, 0)
+				return
+			}
+			require.Len(t, respStanzas, 1)
+			require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+
+			errElem := respStanzas[0].Child("error")
+			require.NotNil(t, errElem)
+			require.NotNil(t, errElem.Child("not-acceptable"))
+		})
+	}
+}
+
+func TestSecLabel_ValidateS2SLabel(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	m := New(testConfig(), routerMock, hk, kitlog.NewNopLogger())
+
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, "romeo@jabber.org/balcony").
+		WithAttribute(stravaganza.To, "noelia@jackal.im").
+		WithChild(testLabel("MQYCAQQGAAA=")).
+		BuildMessage()
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.S2SInStreamWillRouteElement, &hook.ExecutionContext{
+		Info: &hook.S2SStreamInfo{Element: msg},
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted)
+
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, "romeo@jabber.org/balcony", respStanzas[0].Attribute(stravaganza.To))
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+}
+
+func TestSecLabel_ValidateErrorLabel(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	hk := hook.NewHooks()
+	m := New(testConfig(), routerMock, hk, kitlog.NewNopLogger())
+
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.From, "romeo@jabber.org/balcony").
+		WithAttribute(stravaganza.To, "noelia@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.ErrorType).
+		WithChild(testLabel("MQYCAQQGAAA=")).
+		BuildMessage()
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.S2SInStreamWillRouteElement, &hook.ExecutionContext{
+		Info: &hook.S2SStreamInfo{Element: msg},
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted) // dropped
+
+	require.Len(t, respStanzas, 0)
+}
+
+func testConfig() Config {
+	return Config{
+		CatalogName: "Default",
+		Labels: []LabelConfig{
+			{
+				Selector:       "Classified|SECRET",
+				DisplayMarking: "SECRET",
+				FgColor:        "black",
+				BgColor:        "red",
+				ESSLabel:       "MQYCAQQGASk=",
+			},
+			{
+				Selector:       "Unclassified|UNCLASSIFIED",
+				DisplayMarking: "UNCLASSIFIED",
+				ESSLabel:       "MQYCAQQGASM=",
+				Default:        true,
+			},
+		},
+	}
+}
+
+func testLabel(essLabel string) stravaganza.Element {
+	return secLabelElement(LabelConfig{DisplayMarking: "SECRET", ESSLabel: essLabel})
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/ortuman/jackal/pkg/host"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
 	"github.com/ortuman/jackal/pkg/router"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
@@ -47,29 +49,46 @@ const (
 
 	// XEPNumber represents carbons XEP number.
 	XEPNumber = "0280"
+
+	// DefaultOnFlag represents the account feature flag that enables carbons on every bound resource
+	// without requiring the client to explicitly request it.
+	DefaultOnFlag = "carbons_default_on"
 )
 
+// Config contains carbons module configuration options.
+type Config struct {
+	// CopyErrors tells whether message errors bounced back to a resource should also be copied
+	// to the rest of the sender's carbons enabled resources.
+	CopyErrors bool `fig:"copy_errors"`
+}
+
 // Carbons represents carbons (XEP-0280) module type.
 type Carbons struct {
+	cfg    Config
 	hosts  hosts
 	router router.Router
 	resMng resourcemanager.Manager
+	rep    repository.AccountSettings
 	hk     *hook.Hooks
 	logger kitlog.Logger
 }
 
 // New returns a new initialized carbons instance.
 func New(
+	cfg Config,
 	router router.Router,
 	hosts *host.Hosts,
 	resMng resourcemanager.Manager,
+	rep repository.AccountSettings,
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 ) *Carbons {
 	return &Carbons{
+		cfg:    cfg,
 		hosts:  hosts,
 		router: router,
 		resMng: resMng,
+		rep:    rep,
 		hk:     hk,
 		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
 	}
@@ -99,6 +118,10 @@ func (p *Carbons) Start(_ context.Context) error {
 	p.hk.AddHook(hook.S2SInStreamWillRouteElement, p.onS2SElementWillRoute, hook.DefaultPriority)
 	p.hk.AddHook(hook.C2SStreamMessageRouted, p.onC2SMessageRouted, hook.DefaultPriority)
 	p.hk.AddHook(hook.S2SInStreamMessageRouted, p.onS2SMessageRouted, hook.DefaultPriority)
+	p.hk.AddHook(hook.C2SStreamBinded, p.onBinded, hook.DefaultPriority)
+	if p.cfg.CopyErrors {
+		p.hk.AddHook(hook.C2SStreamMessageBounced, p.onC2SMessageBounced, hook.DefaultPriority)
+	}
 
 	level.Info(p.logger).Log("msg", "started carbons module")
 	return nil
@@ -110,6 +133,10 @@ func (p *Carbons) Stop(_ context.Context) error {
 	p.hk.RemoveHook(hook.S2SInStreamWillRouteElement, p.onS2SElementWillRoute)
 	p.hk.RemoveHook(hook.C2SStreamMessageRouted, p.onC2SMessageRouted)
 	p.hk.RemoveHook(hook.S2SInStreamMessageRouted, p.onS2SMessageRouted)
+	p.hk.RemoveHook(hook.C2SStreamBinded, p.onBinded)
+	if p.cfg.CopyErrors {
+		p.hk.RemoveHook(hook.C2SStreamMessageBounced, p.onC2SMessageBounced)
+	}
 
 	level.Info(p.logger).Log("msg", "stopped carbons module")
 	return nil
@@ -123,6 +150,11 @@ func (p *Carbons) MatchesNamespace(namespace string, serverTarget bool) bool {
 	return namespace == carbonsNamespace
 }
 
+// Namespaces returns all iq namespaces matched by carbons module.
+func (p *Carbons) Namespaces() []string {
+	return []string{carbonsNamespace}
+}
+
 // ProcessIQ process a carbons iq.
 func (p *Carbons) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	switch {
@@ -176,6 +208,45 @@ func (p *Carbons) onS2SMessageRouted(ctx context.Context, execCtx *hook.Executio
 	return p.processMessage(ctx, msg, nil)
 }
 
+func (p *Carbons) onC2SMessageBounced(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	msg, ok := inf.Element.(*stravaganza.Message)
+	if !ok {
+		return nil
+	}
+	return p.routeErrorCC(ctx, msg)
+}
+
+func (p *Carbons) onBinded(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+
+	defaultOn, err := p.isDefaultOn(ctx, inf.JID)
+	if err != nil {
+		return err
+	}
+	if !defaultOn {
+		return nil
+	}
+	stm := execCtx.Sender.(stream.C2S)
+	if err := stm.SetInfoValue(ctx, carbonsEnabledCtxKey, true); err != nil {
+		return err
+	}
+	level.Info(p.logger).Log("msg", "enabled carbons copy by default", "username", inf.JID.Node(), "resource", inf.JID.Resource())
+	return nil
+}
+
+func (p *Carbons) isDefaultOn(ctx context.Context, jd *jid.JID) (bool, error) {
+	flags, err := p.rep.FetchAccountFlags(ctx, jd.Node())
+	if err != nil {
+		return false, err
+	}
+	if enabled, ok := flags[DefaultOnFlag]; ok {
+		return enabled, nil
+	}
+	return p.hosts.FeatureFlag(jd.Domain(), DefaultOnFlag), nil
+}
+
 func (p *Carbons) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	fromJID := iq.FromJID()
 	if !p.hosts.IsLocalHost(fromJID.Domain()) {
@@ -213,6 +284,9 @@ func (p *Carbons) setCarbonsEnabled(ctx context.Context, username, resource stri
 }
 
 func (p *Carbons) processMessage(ctx context.Context, msg *stravaganza.Message, ignoringTargets []jid.JID) error {
+	if p.cfg.CopyErrors && isErrorMessage(msg) {
+		return p.routeErrorCC(ctx, msg)
+	}
 	if !isEligibleMessage(msg) || isPrivateMessage(msg) || isCCMessage(msg) {
 		return nil
 	}
@@ -260,6 +334,28 @@ func (p *Carbons) routeReceivedCC(ctx context.Context, msg *stravaganza.Message,
 	return nil
 }
 
+func (p *Carbons) routeErrorCC(ctx context.Context, msg *stravaganza.Message) error {
+	if isCCMessage(msg) {
+		return nil
+	}
+	toJID := msg.ToJID()
+	if !toJID.IsFullWithUser() || !p.hosts.IsLocalHost(toJID.Domain()) {
+		return nil
+	}
+	// error has already been delivered to the resource that originated the message
+	rss, err := p.getFilteredResources(ctx, toJID.Node(), []jid.JID{*toJID})
+	if err != nil {
+		return err
+	}
+	for _, res := range rss {
+		if !res.Info().Bool(carbonsEnabledCtxKey) {
+			continue
+		}
+		_, _ = p.router.Route(ctx, receivedMsgCC(msg, res.JID()))
+	}
+	return nil
+}
+
 func (p *Carbons) getFilteredResources(ctx context.Context, username string, ignoringJIDs []jid.JID) ([]c2smodel.ResourceDesc, error) {
 	rs, err := p.resMng.GetResources(ctx, username)
 	if err != nil {
@@ -296,6 +392,10 @@ func isEligibleMessage(msg *stravaganza.Message) bool {
 	return false
 }
 
+func isErrorMessage(msg *stravaganza.Message) bool {
+	return msg.Attribute(stravaganza.Type) == stravaganza.ErrorType
+}
+
 func stripMessagePrivate(msg *stravaganza.Message) *stravaganza.Message {
 	if msg.ChildNamespace("private", carbonsNamespace) == nil {
 		return msg
@@ -304,6 +304,113 @@ func TestCarbons_ReceivedCC(t *testing.T) {
 	require.NotNil(t, routedMsg.ChildNamespace("received", carbonsNamespace))
 }
 
+func TestCarbons_CopyErrors(t *testing.T) {
+	var tcs = map[string]struct {
+		copyErrors  bool
+		hook        string
+		expRecipCCs []string
+	}{
+		"LocalBounceEnabled": {
+			copyErrors:  true,
+			hook:        hook.C2SStreamMessageBounced,
+			expRecipCCs: []string{"ortuman@jackal.im/chamber"},
+		},
+		"LocalBounceDisabled": {
+			copyErrors: false,
+			hook:       hook.C2SStreamMessageBounced,
+		},
+		"RemoteErrorEnabled": {
+			copyErrors:  true,
+			hook:        hook.S2SInStreamMessageRouted,
+			expRecipCCs: []string{"ortuman@jackal.im/chamber"},
+		},
+		"RemoteErrorDisabled": {
+			copyErrors: false,
+			hook:       hook.S2SInStreamMessageRouted,
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+
+			jd0, _ := jid.NewWithString("ortuman@jackal.im/balcony", true)
+			jd1, _ := jid.NewWithString("ortuman@jackal.im/hall", true)
+			jd2, _ := jid.NewWithString("ortuman@jackal.im/chamber", true)
+
+			resManagerMock := &resourceManagerMock{}
+			resManagerMock.GetResourcesFunc = func(ctx context.Context, username string) ([]c2smodel.ResourceDesc, error) {
+				return []c2smodel.ResourceDesc{
+					c2smodel.NewResourceDesc("i0", jd0, nil, c2smodel.NewInfoMapFromMap(map[string]string{carbonsEnabledCtxKey: "true"})),
+					c2smodel.NewResourceDesc("i0", jd1, nil, c2smodel.NewInfoMapFromMap(map[string]string{carbonsEnabledCtxKey: "false"})),
+					c2smodel.NewResourceDesc("i0", jd2, nil, c2smodel.NewInfoMapFromMap(map[string]string{carbonsEnabledCtxKey: "true"})),
+				}, nil
+			}
+
+			hMock := &hostsMock{}
+			hMock.IsLocalHostFunc = func(h string) bool {
+				return h == "jackal.im"
+			}
+
+			hk := hook.NewHooks()
+			c := &Carbons{
+				cfg:    Config{CopyErrors: tCase.copyErrors},
+				router: routerMock,
+				resMng: resManagerMock,
+				hosts:  hMock,
+				hk:     hk,
+				logger: kitlog.NewNopLogger(),
+			}
+
+			b := stravaganza.NewMessageBuilder()
+			b.WithAttribute("id", "i1234")
+			b.WithAttribute("from", "noelia@jabber.org")
+			b.WithAttribute("to", "ortuman@jackal.im/balcony")
+			b.WithAttribute("type", "error")
+			b.WithChild(
+				stravaganza.NewBuilder("body").
+					WithText("I'll give thee a wind.").
+					Build(),
+			)
+			b.WithChild(
+				stravaganza.NewBuilder("error").
+					WithAttribute("type", "cancel").
+					Build(),
+			)
+			errMsg, _ := b.BuildMessage()
+
+			// when
+			_ = c.Start(context.Background())
+			defer func() { _ = c.Stop(context.Background()) }()
+
+			var inf interface{}
+			switch tCase.hook {
+			case hook.C2SStreamMessageBounced:
+				inf = &hook.C2SStreamInfo{JID: jd0, Element: errMsg}
+			default:
+				inf = &hook.S2SStreamInfo{Element: errMsg}
+			}
+			_, _ = hk.Run(context.Background(), tCase.hook, &hook.ExecutionContext{
+				Info: inf,
+			})
+
+			// then
+			var recipCCs []string
+			for _, stanza := range respStanzas {
+				require.NotNil(t, stanza.ChildNamespace("received", carbonsNamespace))
+				recipCCs = append(recipCCs, stanza.Attribute(stravaganza.To))
+			}
+			require.Equal(t, tCase.expRecipCCs, recipCCs)
+		})
+	}
+}
+
 func TestCarbons_InterceptStanza(t *testing.T) {
 	// given
 	hk := hook.NewHooks()
@@ -342,3 +449,65 @@ func TestCarbons_InterceptStanza(t *testing.T) {
 	require.Nil(t, err)
 	require.Nil(t, hInf.Element.ChildNamespace("private", carbonsNamespace))
 }
+
+func TestCarbons_DefaultOn(t *testing.T) {
+	tests := []struct {
+		name            string
+		accountFlags    map[string]bool
+		hostDefault     bool
+		expectedEnabled bool
+	}{
+		{name: "AccountEnabled", accountFlags: map[string]bool{DefaultOnFlag: true}, hostDefault: false, expectedEnabled: true},
+		{name: "AccountDisabled", accountFlags: map[string]bool{DefaultOnFlag: false}, hostDefault: true, expectedEnabled: false},
+		{name: "HostDefaultEnabled", accountFlags: map[string]bool{}, hostDefault: true, expectedEnabled: true},
+		{name: "HostDefaultDisabled", accountFlags: nil, hostDefault: false, expectedEnabled: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			stmMock := &c2sStreamMock{}
+
+			var setK string
+			var setVal interface{}
+			stmMock.SetInfoValueFunc = func(ctx context.Context, k string, val interface{}) error {
+				setK = k
+				setVal = val
+				return nil
+			}
+			repMock := &repositoryMock{}
+			repMock.FetchAccountFlagsFunc = func(ctx context.Context, username string) (map[string]bool, error) {
+				return tt.accountFlags, nil
+			}
+			hMock := &hostsMock{}
+			hMock.FeatureFlagFunc = func(h, flag string) bool {
+				return h == "jackal.im" && flag == DefaultOnFlag && tt.hostDefault
+			}
+			c := &Carbons{
+				hosts:  hMock,
+				rep:    repMock,
+				hk:     hook.NewHooks(),
+				logger: kitlog.NewNopLogger(),
+			}
+			jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+			// when
+			err := c.onBinded(context.Background(), &hook.ExecutionContext{
+				Info:   &hook.C2SStreamInfo{JID: jd},
+				Sender: stmMock,
+			})
+
+			// then
+			require.Nil(t, err)
+
+			require.Len(t, repMock.FetchAccountFlagsCalls(), 1)
+			require.Equal(t, "ortuman", repMock.FetchAccountFlagsCalls()[0].Username)
+
+			if !tt.expectedEnabled {
+				require.Len(t, stmMock.SetInfoValueCalls(), 0)
+				return
+			}
+			require.Equal(t, carbonsEnabledCtxKey, setK)
+			require.Equal(t, true, setVal)
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/ortuman/jackal/pkg/cluster/resourcemanager"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 )
 
 //go:generate moq -out router.mock_test.go . globalRouter:routerMock
@@ -38,6 +39,12 @@ type c2sStream interface {
 //go:generate moq -out hosts.mock_test.go . hosts
 type hosts interface {
 	IsLocalHost(h string) bool
+	FeatureFlag(h, flag string) bool
+}
+
+//go:generate moq -out repository.mock_test.go . accountSettingsRepository:repositoryMock
+type accountSettingsRepository interface {
+	repository.AccountSettings
 }
 
 //go:generate moq -out resourcemanager.mock_test.go . resourceManager
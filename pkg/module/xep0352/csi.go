@@ -0,0 +1,262 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0352
+
+import (
+	"context"
+	"sync"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/ortuman/jackal/pkg/router/stream"
+)
+
+const csiNamespace = "urn:xmpp:csi:0"
+
+const (
+	// ModuleName represents client state indication module name.
+	ModuleName = "csi"
+
+	// XEPNumber represents client state indication XEP number.
+	XEPNumber = "0352"
+)
+
+const inactiveInfoKey = "xep0352:inactive"
+
+// IsInactive tells whether a C2S stream client reported an inactive state, given its info.
+func IsInactive(inf c2smodel.Info) bool {
+	return inf.Bool(inactiveInfoKey)
+}
+
+// CSI represents a client state indication (XEP-0352) module type.
+type CSI struct {
+	hk     *hook.Hooks
+	logger kitlog.Logger
+
+	mu       sync.Mutex
+	deferred map[string]*deferredPresences
+}
+
+// New returns a new initialized CSI instance.
+func New(hk *hook.Hooks, logger kitlog.Logger) *CSI {
+	return &CSI{
+		hk:       hk,
+		logger:   kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+		deferred: make(map[string]*deferredPresences),
+	}
+}
+
+// Name returns CSI module name.
+func (m *CSI) Name() string { return ModuleName }
+
+// StreamFeature returns CSI module stream feature.
+func (m *CSI) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return stravaganza.NewBuilder("csi").
+		WithAttribute(stravaganza.Namespace, csiNamespace).
+		Build(), nil
+}
+
+// ServerFeatures returns CSI server disco features.
+func (m *CSI) ServerFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// AccountFeatures returns CSI account disco features.
+func (m *CSI) AccountFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// Start starts CSI module.
+func (m *CSI) Start(_ context.Context) error {
+	m.hk.AddHook(hook.C2SStreamElementReceived, m.onElementRecv, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamWillSendElement, m.onWillSendElement, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamDisconnected, m.onDisconnect, hook.DefaultPriority)
+	m.hk.AddHook(hook.C2SStreamTerminated, m.onTerminate, hook.DefaultPriority)
+
+	level.Info(m.logger).Log("msg", "started csi module")
+	return nil
+}
+
+// Stop stops CSI module.
+func (m *CSI) Stop(_ context.Context) error {
+	m.hk.RemoveHook(hook.C2SStreamElementReceived, m.onElementRecv)
+	m.hk.RemoveHook(hook.C2SStreamWillSendElement, m.onWillSendElement)
+	m.hk.RemoveHook(hook.C2SStreamDisconnected, m.onDisconnect)
+	m.hk.RemoveHook(hook.C2SStreamTerminated, m.onTerminate)
+
+	level.Info(m.logger).Log("msg", "stopped csi module")
+	return nil
+}
+
+func (m *CSI) onElementRecv(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	if inf.Element.Attribute(stravaganza.Namespace) != csiNamespace {
+		return nil
+	}
+	stm := execCtx.Sender.(stream.C2S)
+	if !stm.IsBinded() {
+		return nil // client state can only be reported once resource is bound
+	}
+	switch inf.Element.Name() {
+	case "active":
+		if err := m.setInactive(ctx, stm, false); err != nil {
+			return err
+		}
+		m.flushDeferred(stm)
+
+	case "inactive":
+		if err := m.setInactive(ctx, stm, true); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+	_, err := m.hk.Run(ctx, hook.C2SStreamClientStateChanged, &hook.ExecutionContext{
+		Info: &hook.C2SStreamInfo{
+			ID:      stm.ID().String(),
+			JID:     stm.JID(),
+			Element: inf.Element,
+		},
+		Sender: stm,
+	})
+	if err != nil {
+		return err
+	}
+	return hook.ErrStopped // already handled
+}
+
+func (m *CSI) onWillSendElement(_ context.Context, execCtx *hook.ExecutionContext) error {
+	inf := execCtx.Info.(*hook.C2SStreamInfo)
+	pr, ok := inf.Element.(*stravaganza.Presence)
+	if !ok || !isDeferrable(pr) {
+		return nil
+	}
+	stm := execCtx.Sender.(stream.C2S)
+	if !stm.IsBinded() || !IsInactive(stm.Info()) {
+		return nil
+	}
+	m.mu.Lock()
+	dp := m.deferred[deferredKey(stm)]
+	if dp == nil {
+		dp = newDeferredPresences()
+		m.deferred[deferredKey(stm)] = dp
+	}
+	dp.add(pr)
+	m.mu.Unlock()
+
+	return hook.ErrStopped // send it later
+}
+
+func (m *CSI) onDisconnect(ctx context.Context, execCtx *hook.ExecutionContext) error {
+	stm := execCtx.Sender.(stream.C2S)
+	if stm.JID() == nil || !IsInactive(stm.Info()) {
+		return nil
+	}
+	// client is gone... stop holding presences and hand them over to the stream,
+	// so that they get queued in case it's resumed later on.
+	if err := m.setInactive(ctx, stm, false); err != nil {
+		return err
+	}
+	m.flushDeferred(stm)
+	return nil
+}
+
+func (m *CSI) onTerminate(_ context.Context, execCtx *hook.ExecutionContext) error {
+	stm := execCtx.Sender.(stream.C2S)
+	if stm.JID() == nil {
+		return nil
+	}
+	m.mu.Lock()
+	delete(m.deferred, deferredKey(stm))
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *CSI) setInactive(ctx context.Context, stm stream.C2S, inactive bool) error {
+	if IsInactive(stm.Info()) == inactive {
+		return nil
+	}
+	if err := stm.SetInfoValue(ctx, inactiveInfoKey, inactive); err != nil {
+		return err
+	}
+	level.Info(m.logger).Log("msg", "client state changed", "inactive", inactive,
+		"id", stm.ID(), "username", stm.Username(), "resource", stm.Resource(),
+	)
+	return nil
+}
+
+func (m *CSI) flushDeferred(stm stream.C2S) {
+	if stm.JID() == nil {
+		return
+	}
+	m.mu.Lock()
+	dp := m.deferred[deferredKey(stm)]
+	delete(m.deferred, deferredKey(stm))
+	m.mu.Unlock()
+
+	if dp == nil {
+		return
+	}
+	for _, pr := range dp.presences() {
+		stm.SendElement(pr)
+	}
+}
+
+// isDeferrable tells whether a presence stanza is an availability update that can be coalesced.
+func isDeferrable(pr *stravaganza.Presence) bool {
+	return pr.IsAvailable() || pr.IsUnavailable()
+}
+
+func deferredKey(stm stream.C2S) string {
+	return stm.JID().String()
+}
+
+// deferredPresences keeps the latest availability presence of every sender, in arrival order.
+type deferredPresences struct {
+	order []string
+	prs   map[string]*stravaganza.Presence
+}
+
+func newDeferredPresences() *deferredPresences {
+	return &deferredPresences{
+		prs: make(map[string]*stravaganza.Presence),
+	}
+}
+
+func (dp *deferredPresences) add(pr *stravaganza.Presence) {
+	from := pr.Attribute(stravaganza.From)
+	if _, ok := dp.prs[from]; ok {
+		// keep latest presence only, in its updated position
+		for i, k := range dp.order {
+			if k == from {
+				dp.order = append(dp.order[:i], dp.order[i+1:]...)
+				break
+			}
+		}
+	}
+	dp.order = append(dp.order, from)
+	dp.prs[from] = pr
+}
+
+func (dp *deferredPresences) presences() []*stravaganza.Presence {
+	prs := make([]*stravaganza.Presence, 0, len(dp.order))
+	for _, k := range dp.order {
+		prs = append(prs, dp.prs[k])
+	}
+	return prs
+}
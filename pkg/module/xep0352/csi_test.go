@@ -0,0 +1,219 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0352
+
+import (
+	"context"
+	"testing"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/hook"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/ortuman/jackal/pkg/router/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSI_Inactive(t *testing.T) {
+	// given
+	stmMock := newTestStream(false)
+
+	hk := hook.NewHooks()
+	m := &CSI{
+		hk:       hk,
+		deferred: make(map[string]*deferredPresences),
+		logger:   kitlog.NewNopLogger(),
+	}
+	var stateChanged bool
+	hk.AddHook(hook.C2SStreamClientStateChanged, func(_ context.Context, _ *hook.ExecutionContext) error {
+		stateChanged = true
+		return nil
+	}, hook.DefaultPriority)
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{Element: csiElement("inactive")},
+		Sender: stmMock,
+	})
+
+	// then
+	require.Nil(t, err)
+	require.True(t, halted)
+	require.True(t, stateChanged)
+
+	require.Len(t, stmMock.SetInfoValueCalls(), 1)
+	require.True(t, IsInactive(stmMock.Info()))
+}
+
+func TestCSI_NotBinded(t *testing.T) {
+	// given
+	stmMock := newTestStream(false)
+	stmMock.IsBindedFunc = func() bool { return false }
+
+	hk := hook.NewHooks()
+	m := &CSI{
+		hk:       hk,
+		deferred: make(map[string]*deferredPresences),
+		logger:   kitlog.NewNopLogger(),
+	}
+
+	// when
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	halted, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{Element: csiElement("inactive")},
+		Sender: stmMock,
+	})
+
+	// then
+	require.Nil(t, err)
+	require.False(t, halted)
+	require.Len(t, stmMock.SetInfoValueCalls(), 0)
+}
+
+func TestCSI_DeferPresencesUntilActive(t *testing.T) {
+	// given
+	stmMock := newTestStream(true)
+
+	var sent []stravaganza.Element
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sent = append(sent, elem)
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &CSI{
+		hk:       hk,
+		deferred: make(map[string]*deferredPresences),
+		logger:   kitlog.NewNopLogger(),
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	willSend := func(elem stravaganza.Element) bool {
+		halted, err := hk.Run(context.Background(), hook.C2SStreamWillSendElement, &hook.ExecutionContext{
+			Info:   &hook.C2SStreamInfo{Element: elem},
+			Sender: stmMock,
+		})
+		require.Nil(t, err)
+		return halted
+	}
+	pr1 := testPresence("noelia@jackal.im/yard", "")
+	pr2 := testPresence("romeo@jackal.im/balcony", "")
+	pr3 := testPresence("noelia@jackal.im/yard", stravaganza.UnavailableType)
+	sub := testPresence("juliet@jackal.im/chamber", stravaganza.SubscribeType)
+
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im/yard").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/yard").
+		BuildMessage()
+
+	// when
+	deferred := []bool{willSend(pr1), willSend(pr2), willSend(pr3), willSend(sub), willSend(msg)}
+
+	_, err := hk.Run(context.Background(), hook.C2SStreamElementReceived, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{Element: csiElement("active")},
+		Sender: stmMock,
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, []bool{true, true, true, false, false}, deferred)
+
+	require.False(t, IsInactive(stmMock.Info()))
+	require.Equal(t, []stravaganza.Element{pr2, pr3}, sent)
+
+	require.False(t, willSend(pr1)) // no longer deferred
+}
+
+func TestCSI_FlushPresencesOnDisconnect(t *testing.T) {
+	// given
+	stmMock := newTestStream(true)
+
+	var sent []stravaganza.Element
+	stmMock.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		sent = append(sent, elem)
+		return nil
+	}
+	hk := hook.NewHooks()
+	m := &CSI{
+		hk:       hk,
+		deferred: make(map[string]*deferredPresences),
+		logger:   kitlog.NewNopLogger(),
+	}
+	_ = m.Start(context.Background())
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	pr := testPresence("noelia@jackal.im/yard", "")
+	halted, _ := hk.Run(context.Background(), hook.C2SStreamWillSendElement, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{Element: pr},
+		Sender: stmMock,
+	})
+	require.True(t, halted)
+
+	// when
+	_, err := hk.Run(context.Background(), hook.C2SStreamDisconnected, &hook.ExecutionContext{
+		Info:   &hook.C2SStreamInfo{},
+		Sender: stmMock,
+	})
+
+	// then
+	require.Nil(t, err)
+
+	require.False(t, IsInactive(stmMock.Info()))
+	require.Equal(t, []stravaganza.Element{pr}, sent)
+	require.Len(t, m.deferred, 0)
+}
+
+func newTestStream(inactive bool) *c2sStreamMock {
+	jd, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+
+	inf := c2smodel.NewInfoMap()
+	inf.SetBool(inactiveInfoKey, inactive)
+
+	stmMock := &c2sStreamMock{}
+	stmMock.IDFunc = func() stream.C2SID { return 1234 }
+	stmMock.JIDFunc = func() *jid.JID { return jd }
+	stmMock.UsernameFunc = func() string { return jd.Node() }
+	stmMock.ResourceFunc = func() string { return jd.Resource() }
+	stmMock.IsBindedFunc = func() bool { return true }
+	stmMock.InfoFunc = func() c2smodel.Info { return inf }
+	stmMock.SetInfoValueFunc = func(_ context.Context, k string, val interface{}) error {
+		inf.SetBool(k, val.(bool))
+		return nil
+	}
+	return stmMock
+}
+
+func csiElement(name string) stravaganza.Element {
+	return stravaganza.NewBuilder(name).
+		WithAttribute(stravaganza.Namespace, csiNamespace).
+		Build()
+}
+
+func testPresence(from, typ string) *stravaganza.Presence {
+	b := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, from).
+		WithAttribute(stravaganza.To, "ortuman@jackal.im/yard")
+	if len(typ) > 0 {
+		b.WithAttribute(stravaganza.Type, typ)
+	}
+	pr, _ := b.BuildPresence()
+	return pr
+}
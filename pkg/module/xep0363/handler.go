@@ -0,0 +1,144 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"crypto/hmac"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+)
+
+// HTTPHandler returns the HTTP handler used to upload and download files, along with the path it should be served at.
+func (m *Upload) HTTPHandler() (string, http.Handler) {
+	return m.cfg.Path, http.StripPrefix(m.cfg.Path, http.HandlerFunc(m.serveHTTP))
+}
+
+func (m *Upload) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	key, ok := parseKey(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		m.putFile(w, r, key)
+	case http.MethodGet, http.MethodHead:
+		m.getFile(w, r, key)
+	case http.MethodOptions:
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, PUT, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, OPTIONS")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *Upload) putFile(w http.ResponseWriter, r *http.Request, key string) {
+	qs := r.URL.Query()
+
+	size, err := strconv.ParseInt(qs.Get("size"), 10, 64)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	expiresAt, err := strconv.ParseInt(qs.Get("expires"), 10, 64)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	expToken := m.signSlot(key, size, r.Header.Get("Content-Type"), expiresAt)
+	if !hmac.Equal([]byte(expToken), []byte(qs.Get("token"))) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	if m.nowFn().Unix() > expiresAt {
+		http.Error(w, "upload slot expired", http.StatusForbidden)
+		return
+	}
+	if r.ContentLength != size {
+		http.Error(w, "content length mismatch", http.StatusBadRequest)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, size)
+
+	switch err := m.storage.Put(r.Context(), key, body); {
+	case err == nil:
+		w.WriteHeader(http.StatusCreated)
+
+		level.Info(m.logger).Log("msg", "file uploaded", "key", key, "size", size)
+
+	case errors.Is(err, ErrFileExists):
+		http.Error(w, "file already uploaded", http.StatusConflict)
+
+	default:
+		level.Warn(m.logger).Log("msg", "failed to store uploaded file", "key", key, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func (m *Upload) getFile(w http.ResponseWriter, r *http.Request, key string) {
+	rc, err := m.storage.Get(r.Context(), key)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		level.Warn(m.logger).Log("msg", "failed to fetch uploaded file", "key", key, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = rc.Close() }()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if len(contentType) == 0 {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'")
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	if _, err := io.Copy(w, rc); err != nil {
+		level.Debug(m.logger).Log("msg", "failed to serve uploaded file", "key", key, "err", err)
+	}
+}
+
+// parseKey validates and returns the storage key referenced by an (unprefixed) request path.
+func parseKey(p string) (string, bool) {
+	slotID, filename, ok := strings.Cut(p, "/")
+	if !ok {
+		return "", false
+	}
+	if _, err := uuid.Parse(slotID); err != nil {
+		return "", false
+	}
+	if !isValidFilename(filename) {
+		return "", false
+	}
+	return slotID + "/" + filename, true
+}
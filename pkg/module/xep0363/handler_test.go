@@ -0,0 +1,148 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload_PutAndGet(t *testing.T) {
+	// given
+	m, putURL, getURL := testSlot(t, "hello.txt", "text/plain", 11)
+	_, hnd := m.HTTPHandler()
+
+	// when
+	putRec := httptest.NewRecorder()
+	putReq := httptest.NewRequest(http.MethodPut, putURL, strings.NewReader("hello world"))
+	putReq.Header.Set("Content-Type", "text/plain")
+	hnd.ServeHTTP(putRec, putReq)
+
+	replayRec := httptest.NewRecorder()
+	replayReq := httptest.NewRequest(http.MethodPut, putURL, strings.NewReader("hello xmpp!"))
+	replayReq.Header.Set("Content-Type", "text/plain")
+	hnd.ServeHTTP(replayRec, replayReq)
+
+	getRec := httptest.NewRecorder()
+	hnd.ServeHTTP(getRec, httptest.NewRequest(http.MethodGet, getURL, nil))
+
+	// then
+	require.Equal(t, http.StatusCreated, putRec.Code)
+	require.Equal(t, http.StatusConflict, replayRec.Code)
+
+	require.Equal(t, http.StatusOK, getRec.Code)
+	require.Equal(t, "hello world", getRec.Body.String())
+	require.True(t, strings.HasPrefix(getRec.Header().Get("Content-Type"), "text/plain"))
+	require.Equal(t, "nosniff", getRec.Header().Get("X-Content-Type-Options"))
+}
+
+func TestUpload_PutRejected(t *testing.T) {
+	var tests = []struct {
+		name        string
+		modifyURL   func(u *url.URL)
+		contentType string
+		body        string
+		elapsed     time.Duration
+		expCode     int
+	}{
+		{name: "InvalidToken", modifyURL: func(u *url.URL) {
+			qs := u.Query()
+			qs.Set("token", strings.Repeat("0", 64))
+			u.RawQuery = qs.Encode()
+		}, contentType: "text/plain", body: "hello world", expCode: http.StatusForbidden},
+		{name: "TamperedSize", modifyURL: func(u *url.URL) {
+			qs := u.Query()
+			qs.Set("size", "12")
+			u.RawQuery = qs.Encode()
+		}, contentType: "text/plain", body: "hello world!", expCode: http.StatusForbidden},
+		{name: "ContentTypeMismatch", contentType: "text/html", body: "hello world", expCode: http.StatusForbidden},
+		{name: "Expired", contentType: "text/plain", body: "hello world", elapsed: time.Minute * 10, expCode: http.StatusForbidden},
+		{name: "SizeMismatch", contentType: "text/plain", body: "hello", expCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			m, putURL, getURL := testSlot(t, "hello.txt", "text/plain", 11)
+			_, hnd := m.HTTPHandler()
+
+			u, _ := url.Parse(putURL)
+			if tt.modifyURL != nil {
+				tt.modifyURL(u)
+			}
+			m.nowFn = func() time.Time {
+				return time.Unix(1620000000, 0).Add(tt.elapsed)
+			}
+
+			// when
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, u.String(), strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			hnd.ServeHTTP(rec, req)
+
+			getRec := httptest.NewRecorder()
+			hnd.ServeHTTP(getRec, httptest.NewRequest(http.MethodGet, getURL, nil))
+
+			// then
+			require.Equal(t, tt.expCode, rec.Code)
+			require.Equal(t, http.StatusNotFound, getRec.Code)
+		})
+	}
+}
+
+func TestUpload_InvalidPath(t *testing.T) {
+	// given
+	m := &Upload{cfg: Config{Path: "/upload/"}, storage: NewLocalStorage(t.TempDir()), logger: kitlog.NewNopLogger()}
+	_, hnd := m.HTTPHandler()
+
+	// when
+	rec := httptest.NewRecorder()
+	hnd.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/upload/not-a-slot/..", nil))
+
+	// then
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func testSlot(t *testing.T, filename, contentType string, size int) (m *Upload, putURL, getURL string) {
+	t.Helper()
+
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m = testUpload(routerMock)
+	m.storage = NewLocalStorage(t.TempDir())
+
+	_ = m.ProcessIQ(context.Background(), testRequestIQ(filename, strconv.Itoa(size), contentType))
+	require.Len(t, respStanzas, 1)
+
+	slot := respStanzas[0].ChildNamespace("slot", uploadNamespace)
+	require.NotNil(t, slot)
+
+	return m, slot.Child("put").Attribute("url"), slot.Child("get").Attribute("url")
+}
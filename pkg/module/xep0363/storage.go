@@ -0,0 +1,116 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const localStorageType = "local"
+
+var (
+	// ErrFileNotFound will be returned by Storage Get method in case there's no file stored under a given key.
+	ErrFileNotFound = errors.New("xep0363: file not found")
+
+	// ErrFileExists will be returned by Storage Put method in case a file was already stored under a given key.
+	ErrFileExists = errors.New("xep0363: file already exists")
+)
+
+// Storage represents an uploaded files storage.
+type Storage interface {
+	// Put stores the content read from r under key.
+	Put(ctx context.Context, key string, r io.Reader) error
+
+	// Get returns a reader over the content stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// StorageConfig contains uploaded files storage configuration.
+type StorageConfig struct {
+	// Type is the storage type. Only "local" is currently supported.
+	Type string `fig:"type" default:"local"`
+
+	// Local contains local filesystem storage configuration.
+	Local struct {
+		// Dir is the directory uploaded files are stored at.
+		Dir string `fig:"dir" default:"./uploads"`
+	} `fig:"local"`
+}
+
+// NewStorage returns a new Storage instance based on cfg configuration.
+func NewStorage(cfg StorageConfig) (Storage, error) {
+	switch cfg.Type {
+	case localStorageType:
+		return NewLocalStorage(cfg.Local.Dir), nil
+	default:
+		return nil, fmt.Errorf("xep0363: unrecognized storage type: %s", cfg.Type)
+	}
+}
+
+// LocalStorage stores uploaded files in the local filesystem.
+type LocalStorage struct {
+	dir string
+}
+
+// NewLocalStorage returns a new LocalStorage instance rooted at dir.
+func NewLocalStorage(dir string) *LocalStorage {
+	return &LocalStorage{dir: dir}
+}
+
+// Put stores the content read from r under key.
+func (s *LocalStorage) Put(_ context.Context, key string, r io.Reader) error {
+	fPath := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(fPath), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fPath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// link fails in case target already exists, so that uploaded files are never overwritten
+	if err := os.Link(tmp.Name(), fPath); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return ErrFileExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Get returns a reader over the content stored under key.
+func (s *LocalStorage) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrFileNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
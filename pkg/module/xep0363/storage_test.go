@@ -0,0 +1,60 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage_PutAndGet(t *testing.T) {
+	// given
+	st := NewLocalStorage(t.TempDir())
+
+	// when
+	err1 := st.Put(context.Background(), "b3fd/note.txt", strings.NewReader("first"))
+	err2 := st.Put(context.Background(), "b3fd/note.txt", strings.NewReader("second"))
+
+	rc, err3 := st.Get(context.Background(), "b3fd/note.txt")
+	require.NoError(t, err3)
+	defer func() { _ = rc.Close() }()
+
+	b, _ := io.ReadAll(rc)
+
+	_, err4 := st.Get(context.Background(), "b3fd/missing.txt")
+
+	// then
+	require.NoError(t, err1)
+	require.Equal(t, ErrFileExists, err2)
+	require.Equal(t, "first", string(b))
+	require.Equal(t, ErrFileNotFound, err4)
+}
+
+func TestNewStorage(t *testing.T) {
+	var cfg StorageConfig
+	cfg.Type = "local"
+	cfg.Local.Dir = t.TempDir()
+
+	st, err := NewStorage(cfg)
+	require.NoError(t, err)
+	require.IsType(t, &LocalStorage{}, st)
+
+	_, err = NewStorage(StorageConfig{Type: "foo"})
+	require.Error(t, err)
+}
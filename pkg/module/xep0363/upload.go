@@ -0,0 +1,257 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/ortuman/jackal/pkg/host"
+	discomodel "github.com/ortuman/jackal/pkg/model/disco"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/ortuman/jackal/pkg/router"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+)
+
+const uploadNamespace = "urn:xmpp:http:upload:0"
+
+const (
+	// ModuleName represents HTTP file upload module name.
+	ModuleName = "upload"
+
+	// XEPNumber represents HTTP file upload XEP number.
+	XEPNumber = "0363"
+)
+
+const maxFileSizeField = "max-file-size"
+
+var errMissingSecret = errors.New("xep0363: upload secret not configured")
+
+// Config contains HTTP file upload module configuration options.
+type Config struct {
+	// BaseURL is the externally reachable URL slot URLs are built upon (e.g. https://upload.jackal.im).
+	// Usually points to a TLS terminating reverse proxy in front of the HTTP server.
+	BaseURL string `fig:"base_url"`
+
+	// Path is the HTTP server path upload handler is served at.
+	Path string `fig:"path" default:"/upload/"`
+
+	// MaxFileSize is the maximum allowed size in bytes of an uploaded file.
+	MaxFileSize int64 `fig:"max_file_size" default:"10485760"`
+
+	// SlotTTL defines for how long a requested slot can be used to upload a file.
+	SlotTTL time.Duration `fig:"slot_ttl" default:"5m"`
+
+	// Secret is the key used to sign upload URLs, so that only requested slots are accepted.
+	Secret string `fig:"secret"`
+
+	// Storage contains uploaded files storage configuration.
+	Storage StorageConfig `fig:"storage"`
+}
+
+// Upload represents an HTTP file upload (XEP-0363) module type.
+type Upload struct {
+	cfg     Config
+	router  router.Router
+	hosts   hosts
+	storage Storage
+	nowFn   func() time.Time
+	logger  kitlog.Logger
+}
+
+// New returns a new initialized Upload instance.
+func New(
+	cfg Config,
+	router router.Router,
+	hosts *host.Hosts,
+	logger kitlog.Logger,
+) *Upload {
+	return &Upload{
+		cfg:    cfg,
+		router: router,
+		hosts:  hosts,
+		nowFn:  time.Now,
+		logger: kitlog.With(logger, "module", ModuleName, "xep", XEPNumber),
+	}
+}
+
+// Name returns HTTP file upload module name.
+func (m *Upload) Name() string { return ModuleName }
+
+// StreamFeature returns HTTP file upload module stream feature.
+func (m *Upload) StreamFeature(_ context.Context, _ string) (stravaganza.Element, error) {
+	return nil, nil
+}
+
+// ServerFeatures returns HTTP file upload server disco features.
+func (m *Upload) ServerFeatures(_ context.Context) ([]string, error) {
+	return []string{uploadNamespace}, nil
+}
+
+// AccountFeatures returns HTTP file upload account disco features.
+func (m *Upload) AccountFeatures(_ context.Context) ([]string, error) {
+	return nil, nil
+}
+
+// Identities returns HTTP file upload server disco identities.
+func (m *Upload) Identities() []discomodel.Identity {
+	return []discomodel.Identity{{Category: "store", Type: "file", Name: "HTTP File Upload"}}
+}
+
+// ServerForms returns HTTP file upload server disco extended info forms, advertising max file size.
+func (m *Upload) ServerForms(_ context.Context) ([]xep0004.DataForm, error) {
+	return []xep0004.DataForm{{
+		Type: xep0004.Result,
+		Fields: xep0004.Fields{
+			{Var: xep0004.FormType, Type: xep0004.Hidden, Values: []string{uploadNamespace}},
+			{Var: maxFileSizeField, Values: []string{strconv.FormatInt(m.cfg.MaxFileSize, 10)}},
+		},
+	}}, nil
+}
+
+// Start starts HTTP file upload module.
+func (m *Upload) Start(_ context.Context) error {
+	if len(m.cfg.Secret) == 0 {
+		return errMissingSecret
+	}
+	if m.storage == nil {
+		st, err := NewStorage(m.cfg.Storage)
+		if err != nil {
+			return err
+		}
+		m.storage = st
+	}
+	level.Info(m.logger).Log("msg", "started upload module", "max_file_size", m.cfg.MaxFileSize)
+	return nil
+}
+
+// Stop stops HTTP file upload module.
+func (m *Upload) Stop(_ context.Context) error {
+	level.Info(m.logger).Log("msg", "stopped upload module")
+	return nil
+}
+
+// MatchesNamespace tells whether namespace matches HTTP file upload module.
+func (m *Upload) MatchesNamespace(namespace string, serverTarget bool) bool {
+	if !serverTarget {
+		return false
+	}
+	return namespace == uploadNamespace
+}
+
+// Namespaces returns all iq namespaces matched by HTTP file upload module.
+func (m *Upload) Namespaces() []string {
+	return []string{uploadNamespace}
+}
+
+// ProcessIQ process an HTTP file upload iq.
+func (m *Upload) ProcessIQ(ctx context.Context, iq *stravaganza.IQ) error {
+	switch {
+	case iq.IsGet() && iq.ChildNamespace("request", uploadNamespace) != nil:
+		m.requestSlot(ctx, iq)
+		return nil
+	default:
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return nil
+	}
+}
+
+func (m *Upload) requestSlot(ctx context.Context, iq *stravaganza.IQ) {
+	if !m.hosts.IsLocalHost(iq.FromJID().Domain()) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.NotAllowed))
+		return
+	}
+	req := iq.ChildNamespace("request", uploadNamespace)
+
+	filename := req.Attribute("filename")
+	size, err := strconv.ParseInt(req.Attribute("size"), 10, 64)
+	if err != nil || size <= 0 || !isValidFilename(filename) {
+		_, _ = m.router.Route(ctx, xmpputil.MakeErrorStanza(iq, stanzaerror.BadRequest))
+		return
+	}
+	if size > m.cfg.MaxFileSize {
+		se := stanzaerror.E(stanzaerror.NotAcceptable, iq)
+		se.Text = "File too large"
+		se.ApplicationElement = stravaganza.NewBuilder("file-too-large").
+			WithAttribute(stravaganza.Namespace, uploadNamespace).
+			WithChild(
+				stravaganza.NewBuilder(maxFileSizeField).
+					WithText(strconv.FormatInt(m.cfg.MaxFileSize, 10)).
+					Build(),
+			).
+			Build()
+		errStanza, _ := se.Stanza(false)
+
+		_, _ = m.router.Route(ctx, errStanza)
+		return
+	}
+	key := uuid.New().String() + "/" + filename
+	expiresAt := m.nowFn().Add(m.cfg.SlotTTL).Unix()
+
+	getURL := strings.TrimSuffix(m.cfg.BaseURL, "/") + m.cfg.Path + escapeKey(key)
+
+	qs := url.Values{}
+	qs.Set("size", strconv.FormatInt(size, 10))
+	qs.Set("expires", strconv.FormatInt(expiresAt, 10))
+	qs.Set("token", m.signSlot(key, size, req.Attribute("content-type"), expiresAt))
+	putURL := getURL + "?" + qs.Encode()
+
+	resIQ := xmpputil.MakeResultIQ(iq, stravaganza.NewBuilder("slot").
+		WithAttribute(stravaganza.Namespace, uploadNamespace).
+		WithChild(stravaganza.NewBuilder("put").WithAttribute("url", putURL).Build()).
+		WithChild(stravaganza.NewBuilder("get").WithAttribute("url", getURL).Build()).
+		Build(),
+	)
+	_, _ = m.router.Route(ctx, resIQ)
+
+	level.Info(m.logger).Log("msg", "upload slot granted", "jid", iq.FromJID().String(), "key", key, "size", size)
+}
+
+// signSlot returns the hex encoded HMAC-SHA256 signature of a slot parameters.
+func (m *Upload) signSlot(key string, size int64, contentType string, expiresAt int64) string {
+	mac := hmac.New(sha256.New, []byte(m.cfg.Secret))
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(size, 10)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(contentType))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatInt(expiresAt, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func isValidFilename(filename string) bool {
+	if len(filename) == 0 || filename == "." || filename == ".." {
+		return false
+	}
+	return !strings.ContainsAny(filename, "/\\\x00")
+}
+
+func escapeKey(key string) string {
+	slotID, filename, _ := strings.Cut(key, "/")
+	return slotID + "/" + url.PathEscape(filename)
+}
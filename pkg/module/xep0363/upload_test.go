@@ -0,0 +1,200 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xep0363
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/google/uuid"
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/module/xep0004"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload_RequestSlot(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := testUpload(routerMock)
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testRequestIQ("my photo.jpg", "23456", "image/jpeg"))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ResultType, respStanzas[0].Attribute(stravaganza.Type))
+
+	slot := respStanzas[0].ChildNamespace("slot", uploadNamespace)
+	require.NotNil(t, slot)
+
+	getURL := slot.Child("get").Attribute("url")
+	require.True(t, strings.HasPrefix(getURL, "https://upload.jackal.im/upload/"))
+	require.True(t, strings.HasSuffix(getURL, "/my%20photo.jpg"))
+
+	putURL, err := url.Parse(slot.Child("put").Attribute("url"))
+	require.NoError(t, err)
+	require.Equal(t, getURL, "https://upload.jackal.im"+putURL.EscapedPath())
+
+	qs := putURL.Query()
+	require.Equal(t, "23456", qs.Get("size"))
+	require.Equal(t, "1620000300", qs.Get("expires"))
+
+	key := strings.TrimPrefix(putURL.Path, "/upload/")
+	require.Equal(t, m.signSlot(key, 23456, "image/jpeg", 1620000300), qs.Get("token"))
+}
+
+func TestUpload_FileTooLarge(t *testing.T) {
+	// given
+	routerMock := &routerMock{}
+
+	var respStanzas []stravaganza.Stanza
+	routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+		respStanzas = append(respStanzas, stanza)
+		return nil, nil
+	}
+	m := testUpload(routerMock)
+	m.cfg.MaxFileSize = 1024
+
+	// when
+	_ = m.ProcessIQ(context.Background(), testRequestIQ("movie.mp4", "2048", "video/mp4"))
+
+	// then
+	require.Len(t, respStanzas, 1)
+	require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+
+	errEl := respStanzas[0].Child("error")
+	require.NotNil(t, errEl)
+	require.NotNil(t, errEl.Child(stanzaerror.NotAcceptable.String()))
+
+	tooLarge := errEl.ChildNamespace("file-too-large", uploadNamespace)
+	require.NotNil(t, tooLarge)
+	require.Equal(t, "1024", tooLarge.Child("max-file-size").Text())
+}
+
+func TestUpload_InvalidRequest(t *testing.T) {
+	var tests = []struct {
+		name     string
+		from     string
+		filename string
+		size     string
+		expErr   stanzaerror.Reason
+	}{
+		{name: "RemoteSender", from: "ortuman@jabber.org/yard", filename: "a.txt", size: "10", expErr: stanzaerror.NotAllowed},
+		{name: "EmptyFilename", from: "ortuman@jackal.im/yard", filename: "", size: "10", expErr: stanzaerror.BadRequest},
+		{name: "PathFilename", from: "ortuman@jackal.im/yard", filename: "../a.txt", size: "10", expErr: stanzaerror.BadRequest},
+		{name: "InvalidSize", from: "ortuman@jackal.im/yard", filename: "a.txt", size: "-1", expErr: stanzaerror.BadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			routerMock := &routerMock{}
+
+			var respStanzas []stravaganza.Stanza
+			routerMock.RouteFunc = func(ctx context.Context, stanza stravaganza.Stanza) ([]jid.JID, error) {
+				respStanzas = append(respStanzas, stanza)
+				return nil, nil
+			}
+			m := testUpload(routerMock)
+
+			// when
+			iq := testRequestIQ(tt.filename, tt.size, "")
+			iq, _ = stravaganza.NewBuilderFromElement(iq).
+				WithAttribute(stravaganza.From, tt.from).
+				BuildIQ()
+			_ = m.ProcessIQ(context.Background(), iq)
+
+			// then
+			require.Len(t, respStanzas, 1)
+			require.Equal(t, stravaganza.ErrorType, respStanzas[0].Attribute(stravaganza.Type))
+			require.NotNil(t, respStanzas[0].Child("error").Child(tt.expErr.String()))
+		})
+	}
+}
+
+func TestUpload_ServerForms(t *testing.T) {
+	// given
+	m := &Upload{cfg: Config{MaxFileSize: 1024}}
+
+	// when
+	forms, _ := m.ServerForms(context.Background())
+
+	// then
+	require.Len(t, forms, 1)
+	require.Equal(t, xep0004.Result, forms[0].Type)
+	require.Equal(t, uploadNamespace, forms[0].Fields.ValueForFieldOfType(xep0004.FormType, xep0004.Hidden))
+	require.Equal(t, "1024", forms[0].Fields.ValueForField(maxFileSizeField))
+}
+
+func TestUpload_MissingSecret(t *testing.T) {
+	// given
+	m := &Upload{cfg: Config{}, logger: kitlog.NewNopLogger()}
+
+	// when
+	err := m.Start(context.Background())
+
+	// then
+	require.Equal(t, errMissingSecret, err)
+}
+
+func testUpload(router *routerMock) *Upload {
+	hostsMock := &hostsMock{}
+	hostsMock.IsLocalHostFunc = func(h string) bool { return h == "jackal.im" }
+
+	return &Upload{
+		cfg: Config{
+			BaseURL:     "https://upload.jackal.im/",
+			Path:        "/upload/",
+			MaxFileSize: 1024 * 1024,
+			SlotTTL:     time.Minute * 5,
+			Secret:      "s3cr3t",
+		},
+		router: router,
+		hosts:  hostsMock,
+		nowFn: func() time.Time {
+			return time.Unix(1620000000, 0)
+		},
+		logger: kitlog.NewNopLogger(),
+	}
+}
+
+func testRequestIQ(filename, size, contentType string) *stravaganza.IQ {
+	rb := stravaganza.NewBuilder("request").
+		WithAttribute(stravaganza.Namespace, uploadNamespace).
+		WithAttribute("filename", filename).
+		WithAttribute("size", size)
+	if len(contentType) > 0 {
+		rb.WithAttribute("content-type", contentType)
+	}
+	iq, _ := stravaganza.NewIQBuilder().
+		WithAttribute(stravaganza.ID, uuid.New().String()).
+		WithAttribute(stravaganza.Type, stravaganza.GetType).
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/chamber").
+		WithAttribute(stravaganza.To, "jackal.im").
+		WithChild(rb.Build()).
+		BuildIQ()
+	return iq
+}
@@ -15,6 +15,7 @@
 package xmppparser
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -29,6 +30,9 @@ const (
 	streamName = "stream"
 )
 
+// DefaultMaxDepth defines the maximum element nesting depth applied when none is configured.
+const DefaultMaxDepth = 64
+
 // ParsingMode defines the way in which special parsed element
 // should be considered or not according to the reader nature.
 type ParsingMode int
@@ -44,6 +48,13 @@ const (
 // ErrTooLargeStanza will be returned Parse when the size of the incoming stanza is too large.
 var ErrTooLargeStanza = errors.New("parser: too large stanza")
 
+// ErrTooDeepStanza will be returned by Parse when the nesting depth of the incoming stanza is too deep.
+var ErrTooDeepStanza = errors.New("parser: too deep stanza")
+
+// ErrAssemblyBufferExceeded will be returned by Parse when the unparsed bytes of the incoming stanza
+// exceed the configured assembly buffer size.
+var ErrAssemblyBufferExceeded = errors.New("parser: stanza assembly buffer exceeded")
+
 // ErrStreamClosedByPeer will be returned by Parse when stream closed element is parsed.
 var ErrStreamClosedByPeer = errors.New("parser: stream closed by peer")
 
@@ -56,17 +67,52 @@ type Parser struct {
 	pIndex        int
 	inElement     bool
 	lastOffset    int64
+	lastElemSize  int64
+	readBytes     int64
 	maxStanzaSize int64
+	maxAsmSize    int64
+	maxDepth      int
+	wsHnd         func()
 }
 
 // New creates an empty Parser instance.
 func New(reader io.Reader, mode ParsingMode, maxStanzaSize int) *Parser {
-	return &Parser{
+	p := &Parser{
 		mode:          mode,
-		dec:           xml.NewDecoder(reader),
 		pIndex:        rootElementIndex,
 		maxStanzaSize: int64(maxStanzaSize),
+		maxDepth:      DefaultMaxDepth,
+	}
+	kr := &keepAliveReader{Reader: reader, p: p}
+	if br, ok := reader.(io.ByteReader); ok {
+		// keep decoder from buffering ahead, so that no input is lost when replacing the parser on stream restart
+		p.dec = xml.NewDecoder(&keepAliveByteReader{keepAliveReader: kr, br: br})
+	} else {
+		p.dec = xml.NewDecoder(kr)
+	}
+	return p
+}
+
+// SetWhitespaceKeepAliveHandler establishes the handler to be invoked whenever a whitespace
+// keepalive is read in between top level elements.
+// Only applies to SocketStream parsing mode.
+func (p *Parser) SetWhitespaceKeepAliveHandler(hnd func()) {
+	p.wsHnd = hnd
+}
+
+// SetMaxDepth establishes the maximum element nesting depth an incoming stanza may have.
+// If depth is not a positive value DefaultMaxDepth will be used.
+func (p *Parser) SetMaxDepth(depth int) {
+	if depth <= 0 {
+		depth = DefaultMaxDepth
 	}
+	p.maxDepth = depth
+}
+
+// SetMaxAssemblyBufferSize establishes the maximum number of bytes that can be read from the underlying reader
+// and kept unparsed while assembling an incoming stanza. A non-positive size means no limit.
+func (p *Parser) SetMaxAssemblyBufferSize(size int) {
+	p.maxAsmSize = int64(size)
 }
 
 // Parse parses next available XML element from reader.
@@ -83,6 +129,10 @@ func (p *Parser) Parse() (stravaganza.Element, error) {
 		}
 		switch t1 := t.(type) {
 		case xml.StartElement:
+			// check max nesting depth limit
+			if len(p.stack) >= p.maxDepth {
+				return nil, ErrTooDeepStanza
+			}
 			p.startElement(t1)
 			if p.mode == SocketStream && t1.Name.Local == streamName && t1.Name.Space == streamName {
 				if err := p.closeElement(xmlName(t1.Name.Space, t1.Name.Local)); err != nil {
@@ -114,13 +164,28 @@ func (p *Parser) Parse() (stravaganza.Element, error) {
 	}
 
 done:
-	p.lastOffset = p.dec.InputOffset()
+	off := p.dec.InputOffset()
+	p.lastElemSize = off - p.lastOffset
+	p.lastOffset = off
 	elem := p.nextElement
 	p.nextElement = nil
 
 	return elem, nil
 }
 
+// LastElementSize returns the number of bytes read from the underlying reader
+// to parse the last element returned by Parse.
+func (p *Parser) LastElementSize() int {
+	return int(p.lastElemSize)
+}
+
+func (p *Parser) isWhitespaceKeepAlive(b []byte) bool {
+	if p.mode != SocketStream || p.wsHnd == nil || p.pIndex != rootElementIndex {
+		return false
+	}
+	return len(bytes.Trim(b, " \t\r\n")) == 0
+}
+
 func (p *Parser) startElement(t xml.StartElement) {
 	name := xmlName(t.Name.Space, t.Name.Local)
 
@@ -166,6 +231,76 @@ func (p *Parser) closeElement(name string) error {
 	return nil
 }
 
+type keepAliveReader struct {
+	io.Reader
+	p *Parser
+}
+
+func (r *keepAliveReader) Read(b []byte) (n int, err error) {
+	if r.p.maxAsmSize > 0 {
+		// never buffer more unparsed bytes than allowed
+		avail := r.p.maxAsmSize - (r.p.readBytes - r.p.lastOffset)
+		if avail <= 0 {
+			return 0, ErrAssemblyBufferExceeded
+		}
+		if int64(len(b)) > avail {
+			b = b[:avail]
+		}
+	}
+	n, err = r.Reader.Read(b)
+	r.p.readBytes += int64(n)
+	if n > 0 && r.p.isWhitespaceKeepAlive(b[:n]) {
+		r.p.wsHnd()
+	}
+	return
+}
+
+type keepAliveByteReader struct {
+	*keepAliveReader
+	br io.ByteReader
+
+	inMarkup bool
+	quote    byte
+	inWs     bool
+}
+
+func (r *keepAliveByteReader) ReadByte() (byte, error) {
+	if r.p.maxAsmSize > 0 && r.p.readBytes-r.p.lastOffset >= r.p.maxAsmSize {
+		return 0, ErrAssemblyBufferExceeded
+	}
+	c, err := r.br.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	r.p.readBytes++
+
+	// a whitespace run outside markup is reported as a single keepalive
+	switch {
+	case r.inMarkup:
+		switch {
+		case r.quote != 0:
+			if c == r.quote {
+				r.quote = 0
+			}
+		case c == '"' || c == '\'':
+			r.quote = c
+		case c == '>':
+			r.inMarkup = false
+		}
+	case c == '<':
+		r.inMarkup = true
+		r.inWs = false
+	case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+		if !r.inWs && r.p.isWhitespaceKeepAlive([]byte{c}) {
+			r.p.wsHnd()
+		}
+		r.inWs = true
+	default:
+		r.inWs = false
+	}
+	return c, nil
+}
+
 func xmlName(space, local string) string {
 	if len(space) > 0 {
 		return fmt.Sprintf("%s:%s", space, local)
@@ -15,6 +15,8 @@
 package xmppparser
 
 import (
+	"bufio"
+	"io"
 	"strings"
 	"testing"
 
@@ -39,6 +41,78 @@ func TestParser_ErrTooLargeStanzaRead(t *testing.T) {
 	require.Equal(t, ErrTooLargeStanza, err1)
 }
 
+func TestParser_ErrAssemblyBufferExceeded(t *testing.T) {
+	// given
+	docSrc := `<a/><b>` + strings.Repeat("x", 64) + `</b>`
+	p := New(strings.NewReader(docSrc), SocketStream, 0)
+	p.SetMaxAssemblyBufferSize(32)
+
+	// when
+	a, err0 := p.Parse()
+	b, err1 := p.Parse()
+
+	// then
+	require.Nil(t, err0)
+	require.NotNil(t, a)
+	require.Equal(t, "<a/>", a.String())
+
+	require.Nil(t, b)
+	require.Equal(t, ErrAssemblyBufferExceeded, err1)
+}
+
+func TestParser_AssemblyBufferWithinLimit(t *testing.T) {
+	// given
+	docSrc := `<a>hi</a><b>there</b><c/>`
+	p := New(strings.NewReader(docSrc), SocketStream, 0)
+	p.SetMaxAssemblyBufferSize(12)
+
+	// when
+	var names []string
+	for {
+		elem, err := p.Parse()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		names = append(names, elem.Name())
+	}
+
+	// then
+	require.Equal(t, []string{"a", "b", "c"}, names)
+}
+
+func TestParser_ErrTooDeepStanza(t *testing.T) {
+	// given
+	docSrc := `<a><b><c/></b></a><a><b><c><d/></c></b></a>`
+	p := New(strings.NewReader(docSrc), SocketStream, 1024)
+	p.SetMaxDepth(3)
+
+	// when
+	a0, err0 := p.Parse()
+	a1, err1 := p.Parse()
+
+	// then
+	require.Nil(t, err0)
+	require.NotNil(t, a0)
+	require.Equal(t, "<a><b><c/></b></a>", a0.String())
+
+	require.Nil(t, a1)
+	require.Equal(t, ErrTooDeepStanza, err1)
+}
+
+func TestParser_DefaultMaxDepth(t *testing.T) {
+	// given
+	docSrc := strings.Repeat("<a>", DefaultMaxDepth+1) + strings.Repeat("</a>", DefaultMaxDepth+1)
+	p := New(strings.NewReader(docSrc), SocketStream, 0)
+
+	// when
+	elem, err := p.Parse()
+
+	// then
+	require.Nil(t, elem)
+	require.Equal(t, ErrTooDeepStanza, err)
+}
+
 func TestParser_ParseSeveralElements(t *testing.T) {
 	// given
 	docSrc := `<?xml version="1.0" encoding="UTF-8"?><a/><b/><c/>`
@@ -95,3 +169,107 @@ func TestParser_Stream(t *testing.T) {
 
 	require.Equal(t, ErrStreamClosedByPeer, err)
 }
+
+func TestParser_WhitespaceKeepAlive(t *testing.T) {
+	// given
+	r := &chunkReader{chunks: []string{
+		`<stream:stream xmlns:stream="http://etherx.jabber.org/streams" version="1.0" xmlns="jabber:client">`,
+		" ",
+		"\n",
+		"<message>",
+		"  ",
+		"</message>",
+	}}
+	p := New(r, SocketStream, 1024)
+
+	var keepAlives int
+	p.SetWhitespaceKeepAliveHandler(func() { keepAlives++ })
+
+	// when
+	_, err0 := p.Parse()
+	elem, err1 := p.Parse()
+
+	// then
+	require.Nil(t, err0)
+	require.Nil(t, err1)
+	require.Equal(t, "message", elem.Name())
+	require.Equal(t, 2, keepAlives)
+}
+
+func TestParser_WhitespaceKeepAliveByteReader(t *testing.T) {
+	// given
+	docSrc := `<stream:stream xmlns:stream="http://etherx.jabber.org/streams" version="1.0" xmlns="jabber:client">` +
+		" \n<message>  </message>\t<iq type='a > b'/>"
+	p := New(bufio.NewReader(strings.NewReader(docSrc)), SocketStream, 1024)
+
+	var keepAlives int
+	p.SetWhitespaceKeepAliveHandler(func() { keepAlives++ })
+
+	// when
+	_, err0 := p.Parse()
+	elem, err1 := p.Parse()
+	_, err2 := p.Parse()
+
+	// then
+	require.Nil(t, err0)
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+	require.Equal(t, "message", elem.Name())
+	require.Equal(t, 2, keepAlives)
+}
+
+func TestParser_PipelinedStreamRestart(t *testing.T) {
+	// given
+	openStreamXML := `<stream:stream xmlns:stream="http://etherx.jabber.org/streams" version="1.0" xmlns="jabber:client" to="localhost">`
+	br := bufio.NewReader(strings.NewReader(`<a xmlns='x'/>` + openStreamXML + `<b/>`))
+
+	// when
+	p := New(br, SocketStream, 1024)
+	a, err0 := p.Parse()
+
+	p = New(br, SocketStream, 1024) // stream restart
+	stm, err1 := p.Parse()
+	b, err2 := p.Parse()
+
+	// then
+	require.Nil(t, err0)
+	require.Equal(t, "a", a.Name())
+
+	require.Nil(t, err1)
+	require.Equal(t, "stream:stream", stm.Name())
+
+	require.Nil(t, err2)
+	require.Equal(t, "b", b.Name())
+}
+
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(b []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func TestParser_LastElementSize(t *testing.T) {
+	// given
+	docSrc := `<a/><message><body>hi</body></message>`
+	p := New(strings.NewReader(docSrc), DefaultMode, 0)
+
+	// when
+	_, err1 := p.Parse()
+	size1 := p.LastElementSize()
+
+	_, err2 := p.Parse()
+	size2 := p.LastElementSize()
+
+	// then
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	require.Equal(t, 4, size1)
+	require.Equal(t, len(docSrc)-4, size2)
+}
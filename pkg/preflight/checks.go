@@ -0,0 +1,114 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	// probeUsername is looked up to verify storage is reachable and its schema is in place.
+	probeUsername = "preflight"
+
+	// probeKey is looked up to verify KV store is reachable.
+	probeKey = "preflight"
+
+	xmppClientService = "xmpp-client"
+)
+
+// StorageCheck returns a check verifying rep storage can be queried.
+func StorageCheck(rep userRepository) Check {
+	return Check{
+		Name: "storage",
+		Run: func(ctx context.Context) error {
+			if _, err := rep.UserExists(ctx, probeUsername); err != nil {
+				return fmt.Errorf("unable to query storage: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// KVCheck returns a check verifying kv store can be queried.
+func KVCheck(kv kvGetter) Check {
+	return Check{
+		Name: "kv",
+		Run: func(ctx context.Context) error {
+			if _, err := kv.Get(ctx, probeKey); err != nil {
+				return fmt.Errorf("unable to query kv store: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// CertificateCheck returns a check verifying certFile and keyFile contain a parseable key pair
+// whose certificate is currently valid.
+func CertificateCheck(domain, certFile, keyFile string) Check {
+	return Check{
+		Name: "tls " + domain,
+		Run: func(_ context.Context) error {
+			return checkCertificate(certFile, keyFile, time.Now())
+		},
+	}
+}
+
+// DNSCheck returns a check verifying domain can be resolved, either through its
+// client SRV records or its address records.
+func DNSCheck(domain string) Check {
+	return dnsCheck(domain, net.DefaultResolver)
+}
+
+func dnsCheck(domain string, r resolver) Check {
+	return Check{
+		Name: "dns " + domain,
+		Run: func(ctx context.Context) error {
+			if _, addrs, err := r.LookupSRV(ctx, xmppClientService, "tcp", domain); err == nil && len(addrs) > 0 {
+				return nil
+			}
+			addrs, err := r.LookupHost(ctx, domain)
+			if err != nil {
+				return fmt.Errorf("unable to resolve domain: %w", err)
+			}
+			if len(addrs) == 0 {
+				return fmt.Errorf("no addresses found for domain %s", domain)
+			}
+			return nil
+		},
+	}
+}
+
+func checkCertificate(certFile, keyFile string, now time.Time) error {
+	cer, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load certificate %s: %w", certFile, err)
+	}
+	leaf, err := x509.ParseCertificate(cer.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("unable to parse certificate %s: %w", certFile, err)
+	}
+	switch {
+	case now.Before(leaf.NotBefore):
+		return fmt.Errorf("certificate %s is not valid before %s", certFile, leaf.NotBefore.Format(time.RFC3339))
+	case now.After(leaf.NotAfter):
+		return fmt.Errorf("certificate %s expired at %s", certFile, leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Now()
+
+	var tests = []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		expErr    string
+	}{
+		{name: "Valid", notBefore: now.Add(-time.Hour), notAfter: now.Add(time.Hour)},
+		{name: "Expired", notBefore: now.Add(-time.Hour * 2), notAfter: now.Add(-time.Hour), expErr: "expired at"},
+		{name: "NotYetValid", notBefore: now.Add(time.Hour), notAfter: now.Add(time.Hour * 2), expErr: "is not valid before"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			certFile, keyFile := testCertificate(t, tt.notBefore, tt.notAfter)
+
+			// when
+			err := checkCertificate(certFile, keyFile, now)
+
+			// then
+			if len(tt.expErr) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.expErr)
+		})
+	}
+}
+
+func TestCheckCertificate_Unparseable(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0600))
+	require.NoError(t, os.WriteFile(keyFile, []byte("not a key"), 0600))
+
+	// when
+	err := checkCertificate(certFile, keyFile, time.Now())
+
+	// then
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unable to load certificate "+certFile)
+}
+
+func TestDNSCheck(t *testing.T) {
+	var tests = []struct {
+		name     string
+		srvAddrs []*net.SRV
+		srvErr   error
+		addrs    []string
+		hostErr  error
+		expErr   bool
+	}{
+		{name: "SRV", srvAddrs: []*net.SRV{{Target: "xmpp.jackal.im.", Port: 5222}}},
+		{name: "Host", srvErr: errors.New("no such host"), addrs: []string{"10.0.0.1"}},
+		{name: "Unresolvable", srvErr: errors.New("no such host"), hostErr: errors.New("no such host"), expErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			rMock := &resolverMock{}
+			rMock.LookupSRVFunc = func(ctx context.Context, service string, proto string, name string) (string, []*net.SRV, error) {
+				return "", tt.srvAddrs, tt.srvErr
+			}
+			rMock.LookupHostFunc = func(ctx context.Context, host string) ([]string, error) {
+				return tt.addrs, tt.hostErr
+			}
+			chk := dnsCheck("jackal.im", rMock)
+
+			// when
+			err := chk.Run(context.Background())
+
+			// then
+			require.Equal(t, "dns jackal.im", chk.Name)
+			if tt.expErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func testCertificate(t *testing.T, notBefore, notAfter time.Time) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "jackal.im"},
+		DNSNames:     []string{"jackal.im"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
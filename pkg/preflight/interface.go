@@ -0,0 +1,36 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"net"
+)
+
+//go:generate moq -out user_repository.mock_test.go . userRepository
+type userRepository interface {
+	UserExists(ctx context.Context, username string) (bool, error)
+}
+
+//go:generate moq -out kv.mock_test.go . kvGetter:kvMock
+type kvGetter interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+//go:generate moq -out resolver.mock_test.go . resolver
+type resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// Config contains preflight configuration.
+type Config struct {
+	// Disabled tells whether startup checks should be skipped.
+	Disabled bool `fig:"disabled"`
+
+	// Timeout defines the maximum amount of time a single check is allowed to run.
+	Timeout time.Duration `fig:"timeout" default:"10s"`
+
+	// SkipDNS tells whether configured host domains resolution should not be verified.
+	SkipDNS bool `fig:"skip_dns"`
+}
+
+// Check represents a single startup check.
+type Check struct {
+	// Name identifies the check within the preflight report.
+	Name string
+
+	// Run performs the check, returning a descriptive error in case it doesn't pass.
+	Run func(ctx context.Context) error
+}
+
+// Failure represents a failed check.
+type Failure struct {
+	Check string
+	Err   error
+}
+
+// Error is returned by Start in case one or more checks didn't pass.
+type Error struct {
+	Failures []Failure
+	total    int
+}
+
+// Error satisfies error interface.
+func (e *Error) Error() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "preflight: %d of %d checks failed", len(e.Failures), e.total)
+	for _, f := range e.Failures {
+		_, _ = fmt.Fprintf(&sb, "\n  - %s: %v", f.Check, f.Err)
+	}
+	return sb.String()
+}
+
+// Preflight runs a set of startup checks, reporting every failed one altogether.
+type Preflight struct {
+	cfg    Config
+	checks []Check
+	logger kitlog.Logger
+}
+
+// New returns a new initialized Preflight instance.
+func New(cfg Config, checks []Check, logger kitlog.Logger) *Preflight {
+	return &Preflight{
+		cfg:    cfg,
+		checks: checks,
+		logger: logger,
+	}
+}
+
+// Start runs all preflight checks concurrently.
+// An *Error describing every failed check is returned in case any of them doesn't pass.
+func (p *Preflight) Start(ctx context.Context) error {
+	if p.cfg.Disabled {
+		return nil
+	}
+	errs := make([]error, len(p.checks))
+
+	var wg sync.WaitGroup
+	for i, chk := range p.checks {
+		wg.Add(1)
+		go func(i int, chk Check) {
+			defer wg.Done()
+
+			chkCtx := ctx
+			if p.cfg.Timeout > 0 {
+				var cancel context.CancelFunc
+				chkCtx, cancel = context.WithTimeout(ctx, p.cfg.Timeout)
+				defer cancel()
+			}
+			errs[i] = chk.Run(chkCtx)
+		}(i, chk)
+	}
+	wg.Wait()
+
+	// keep checks registration order, so that reports are deterministic
+	var failures []Failure
+	for i, chk := range p.checks {
+		if errs[i] != nil {
+			failures = append(failures, Failure{Check: chk.Name, Err: errs[i]})
+			continue
+		}
+		level.Debug(p.logger).Log("msg", "preflight check passed", "check", chk.Name)
+	}
+	if len(failures) > 0 {
+		return &Error{Failures: failures, total: len(p.checks)}
+	}
+	level.Info(p.logger).Log("msg", "preflight checks passed", "count", len(p.checks))
+	return nil
+}
+
+// Stop satisfies stopper interface.
+func (p *Preflight) Stop(_ context.Context) error { return nil }
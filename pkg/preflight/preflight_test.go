@@ -0,0 +1,116 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreflight_Pass(t *testing.T) {
+	// given
+	certFile, keyFile := testCertificate(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	repMock := &userRepositoryMock{}
+	repMock.UserExistsFunc = func(ctx context.Context, username string) (bool, error) {
+		return false, nil
+	}
+	p := New(Config{Timeout: time.Second}, []Check{
+		StorageCheck(repMock),
+		CertificateCheck("jackal.im", certFile, keyFile),
+	}, kitlog.NewNopLogger())
+
+	// when
+	err := p.Start(context.Background())
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, repMock.UserExistsCalls(), 1)
+}
+
+func TestPreflight_AggregatedFailures(t *testing.T) {
+	// given
+	expiredCertFile, expiredKeyFile := testCertificate(t, time.Now().Add(-time.Hour*2), time.Now().Add(-time.Hour))
+
+	repMock := &userRepositoryMock{}
+	repMock.UserExistsFunc = func(ctx context.Context, username string) (bool, error) {
+		return false, errors.New("dial tcp 127.0.0.1:5432: connect: connection refused")
+	}
+	kvMock := &kvMock{}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		return nil, nil
+	}
+	p := New(Config{Timeout: time.Second}, []Check{
+		StorageCheck(repMock),
+		KVCheck(kvMock),
+		CertificateCheck("jackal.im", expiredCertFile, expiredKeyFile),
+		CertificateCheck("jabber.org", filepath.Join(t.TempDir(), "missing.crt"), filepath.Join(t.TempDir(), "missing.key")),
+	}, kitlog.NewNopLogger())
+
+	// when
+	err := p.Start(context.Background())
+
+	// then
+	var pfErr *Error
+	require.True(t, errors.As(err, &pfErr))
+	require.Len(t, pfErr.Failures, 3)
+
+	require.Equal(t, "storage", pfErr.Failures[0].Check)
+	require.Equal(t, "tls jackal.im", pfErr.Failures[1].Check)
+	require.Equal(t, "tls jabber.org", pfErr.Failures[2].Check)
+
+	require.Contains(t, err.Error(), "preflight: 3 of 4 checks failed")
+	require.Contains(t, err.Error(), "storage: unable to query storage: dial tcp 127.0.0.1:5432: connect: connection refused")
+	require.Contains(t, err.Error(), "expired at")
+	require.Contains(t, err.Error(), "unable to load certificate")
+}
+
+func TestPreflight_Disabled(t *testing.T) {
+	// given
+	p := New(Config{Disabled: true}, []Check{
+		{Name: "failing", Run: func(_ context.Context) error { return errors.New("failed") }},
+	}, kitlog.NewNopLogger())
+
+	// when
+	err := p.Start(context.Background())
+
+	// then
+	require.NoError(t, err)
+}
+
+func TestPreflight_Timeout(t *testing.T) {
+	// given
+	p := New(Config{Timeout: time.Millisecond * 50}, []Check{
+		KVCheck(&kvMock{
+			GetFunc: func(ctx context.Context, key string) ([]byte, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		}),
+	}, kitlog.NewNopLogger())
+
+	// when
+	err := p.Start(context.Background())
+
+	// then
+	require.Error(t, err)
+	require.True(t, errors.Is(err.(*Error).Failures[0].Err, context.DeadlineExceeded))
+}
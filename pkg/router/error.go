@@ -32,4 +32,18 @@ var (
 	// ErrRemoteServerTimeout will be returned by Route method if maximum amount of time to establish remote connection
 	// was reached.
 	ErrRemoteServerTimeout = errors.New("router: remote server timeout")
+
+	// ErrStanzaBounced will be returned by Route method if a cluster instance could not deliver the stanza
+	// to the destination resource.
+	ErrStanzaBounced = errors.New("router: stanza bounced")
+
+	// ErrBounceLoop will be returned by Route method if stanza has been bounced back too many times.
+	ErrBounceLoop = errors.New("router: bounce loop detected")
+
+	// ErrHopLimitExceeded will be returned by Route method if stanza has been routed to a remote server
+	// too many times.
+	ErrHopLimitExceeded = errors.New("router: hop limit exceeded")
+
+	// ErrPolicyDenied will be returned by Route method if routing policy denied stanza delivery.
+	ErrPolicyDenied = errors.New("router: denied by routing policy")
 )
@@ -0,0 +1,186 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/router"
+	policypb "github.com/ortuman/jackal/pkg/router/policy/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	errMissingStanza   = errors.New("policy: missing modified stanza")
+	errModifiedAddress = errors.New("policy: modified stanza addresses do not match the evaluated ones")
+	errMissingDecision = errors.New("policy: missing decision")
+)
+
+// Config contains external routing policy configuration.
+type Config struct {
+	// Address is the policy service gRPC address. Empty value disables routing policy evaluation.
+	Address string `fig:"address"`
+
+	// IsSecure tells whether policy service connection should be secured.
+	IsSecure bool `fig:"is_secure"`
+
+	// Timeout is the maximum amount of time to wait for a policy decision.
+	Timeout time.Duration `fig:"timeout" default:"250ms"`
+
+	// FailOpen tells whether stanzas should be delivered when the policy service
+	// cannot be reached or does not answer in time. Otherwise, they are denied.
+	FailOpen bool `fig:"fail_open"`
+}
+
+// Client is an external routing policy client.
+type Client struct {
+	cfg    Config
+	cc     *grpc.ClientConn
+	cl     policypb.PolicyClient
+	logger kitlog.Logger
+}
+
+// NewClient returns a new external routing policy client.
+func NewClient(cfg Config, logger kitlog.Logger) *Client {
+	return &Client{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Evaluate consults the policy service and returns the stanza to be routed.
+// router.ErrPolicyDenied is returned in case stanza delivery has been denied.
+func (c *Client) Evaluate(ctx context.Context, stanza stravaganza.Stanza) (stravaganza.Stanza, error) {
+	st, err := c.evaluate(ctx, stanza)
+	if err != nil && !errors.Is(err, router.ErrPolicyDenied) {
+		level.Warn(c.logger).Log("msg", "failed to evaluate routing policy",
+			"id", stanza.Attribute(stravaganza.ID),
+			"from", stanza.FromJID().String(),
+			"to", stanza.ToJID().String(),
+			"fail_open", c.cfg.FailOpen,
+			"err", err,
+		)
+		if c.cfg.FailOpen {
+			return stanza, nil
+		}
+		return nil, router.ErrPolicyDenied
+	}
+	return st, err
+}
+
+func (c *Client) evaluate(ctx context.Context, stanza stravaganza.Stanza) (stravaganza.Stanza, error) {
+	if c.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+		defer cancel()
+	}
+	resp, err := c.cl.Evaluate(ctx, &policypb.EvaluateRequest{
+		Stanza: stanza.Proto(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp.GetDecision() {
+	case policypb.Decision_DECISION_UNSPECIFIED:
+		return nil, errMissingDecision
+
+	case policypb.Decision_DECISION_ALLOW:
+		return stanza, nil
+
+	case policypb.Decision_DECISION_DENY:
+		return nil, router.ErrPolicyDenied
+
+	case policypb.Decision_DECISION_MODIFY:
+		return buildModifiedStanza(stanza, resp.GetStanza())
+
+	default:
+		return nil, fmt.Errorf("policy: unrecognized decision: %v", resp.GetDecision())
+	}
+}
+
+func buildModifiedStanza(stanza stravaganza.Stanza, pbStanza *stravaganza.PBElement) (stravaganza.Stanza, error) {
+	if pbStanza == nil {
+		return nil, errMissingStanza
+	}
+	if pbStanza.GetName() != stanza.Name() {
+		return nil, fmt.Errorf("policy: unexpected modified stanza name: %s", pbStanza.GetName())
+	}
+	// keep original stanza type, as routing consumers might rely on it
+	var st stravaganza.Stanza
+	var err error
+
+	b := stravaganza.NewBuilderFromProto(pbStanza)
+	switch stanza.(type) {
+	case *stravaganza.IQ:
+		st, err = b.BuildIQ()
+	case *stravaganza.Message:
+		st, err = b.BuildMessage()
+	case *stravaganza.Presence:
+		st, err = b.BuildPresence()
+	default:
+		st, err = b.BuildStanza()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// policy service is not allowed to reroute stanzas
+	if st.Attribute(stravaganza.From) != stanza.Attribute(stravaganza.From) ||
+		st.Attribute(stravaganza.To) != stanza.Attribute(stravaganza.To) {
+		return nil, errModifiedAddress
+	}
+	return st, nil
+}
+
+// Start dials policy service gRPC connection.
+func (c *Client) Start(ctx context.Context) error {
+	var opts = []grpc.DialOption{
+		grpc.WithBalancerName(roundrobin.Name),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                time.Second * 10,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithUnaryInterceptor(grpc_prometheus.UnaryClientInterceptor),
+		grpc.WithStreamInterceptor(grpc_prometheus.StreamClientInterceptor),
+	}
+	if !c.cfg.IsSecure {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	cc, err := grpc.DialContext(ctx, c.cfg.Address, opts...)
+	if err != nil {
+		return err
+	}
+	c.cc = cc
+	c.cl = policypb.NewPolicyClient(cc)
+
+	level.Info(c.logger).Log("msg", "started routing policy client", "address", c.cfg.Address, "fail_open", c.cfg.FailOpen)
+	return nil
+}
+
+// Stop closes underlying gRPC connection.
+func (c *Client) Stop(_ context.Context) error {
+	if c.cc == nil {
+		return nil // not started
+	}
+	return c.cc.Close()
+}
@@ -0,0 +1,200 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/ortuman/jackal/pkg/router"
+	policypb "github.com/ortuman/jackal/pkg/router/policy/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestClient_Allow(t *testing.T) {
+	// given
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		return &policypb.EvaluateResponse{Decision: policypb.Decision_DECISION_ALLOW}, nil
+	}
+	c := &Client{cfg: Config{Timeout: time.Second}, cl: clMock, logger: kitlog.NewNopLogger()}
+
+	msg := testMessage()
+
+	// when
+	st, err := c.Evaluate(context.Background(), msg)
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, msg, st)
+
+	require.Len(t, clMock.EvaluateCalls(), 1)
+	require.Equal(t, "msg1234", clMock.EvaluateCalls()[0].In.GetStanza().GetAttributes()[0].GetValue())
+}
+
+func TestClient_Deny(t *testing.T) {
+	// given
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		return &policypb.EvaluateResponse{Decision: policypb.Decision_DECISION_DENY}, nil
+	}
+	// deny decisions must be honored regardless of fail open mode
+	c := &Client{cfg: Config{Timeout: time.Second, FailOpen: true}, cl: clMock, logger: kitlog.NewNopLogger()}
+
+	// when
+	st, err := c.Evaluate(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, st)
+	require.Equal(t, router.ErrPolicyDenied, err)
+}
+
+func TestClient_Modify(t *testing.T) {
+	// given
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		msg, _ := stravaganza.NewBuilderFromProto(in.GetStanza()).
+			WithoutChildren("body").
+			WithChild(stravaganza.NewBuilder("body").WithText("[filtered]").Build()).
+			BuildMessage()
+		return &policypb.EvaluateResponse{
+			Decision: policypb.Decision_DECISION_MODIFY,
+			Stanza:   msg.Proto(),
+		}, nil
+	}
+	c := &Client{cfg: Config{Timeout: time.Second}, cl: clMock, logger: kitlog.NewNopLogger()}
+
+	// when
+	st, err := c.Evaluate(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, err)
+
+	msg, ok := st.(*stravaganza.Message)
+	require.True(t, ok)
+	require.Equal(t, "msg1234", msg.Attribute(stravaganza.ID))
+	require.Equal(t, "[filtered]", msg.Child("body").Text())
+}
+
+func TestClient_ModifyAddresses(t *testing.T) {
+	// given
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		pbStanza := proto.Clone(in.GetStanza()).(*stravaganza.PBElement)
+		msg, _ := stravaganza.NewBuilderFromProto(pbStanza).
+			WithAttribute(stravaganza.To, "mallory@jackal.im").
+			BuildMessage()
+		return &policypb.EvaluateResponse{
+			Decision: policypb.Decision_DECISION_MODIFY,
+			Stanza:   msg.Proto(),
+		}, nil
+	}
+	c := &Client{cfg: Config{Timeout: time.Second}, cl: clMock, logger: kitlog.NewNopLogger()}
+
+	// when
+	st, err := c.Evaluate(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, st)
+	require.Equal(t, router.ErrPolicyDenied, err)
+}
+
+func TestClient_UnspecifiedDecision(t *testing.T) {
+	// given
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		return &policypb.EvaluateResponse{}, nil
+	}
+	c := &Client{cfg: Config{Timeout: time.Second}, cl: clMock, logger: kitlog.NewNopLogger()}
+
+	// when
+	st, err := c.Evaluate(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, st)
+	require.Equal(t, router.ErrPolicyDenied, err)
+}
+
+func TestClient_StopNotStarted(t *testing.T) {
+	// given
+	c := NewClient(Config{}, kitlog.NewNopLogger())
+
+	// when
+	err := c.Stop(context.Background())
+
+	// then
+	require.Nil(t, err)
+}
+
+func TestClient_TimeoutFailOpen(t *testing.T) {
+	// given
+	c := &Client{
+		cfg:    Config{Timeout: time.Millisecond * 50, FailOpen: true},
+		cl:     blockingGrpcClient(),
+		logger: kitlog.NewNopLogger(),
+	}
+	msg := testMessage()
+
+	// when
+	st, err := c.Evaluate(context.Background(), msg)
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, msg, st)
+}
+
+func TestClient_TimeoutFailClosed(t *testing.T) {
+	// given
+	c := &Client{
+		cfg:    Config{Timeout: time.Millisecond * 50},
+		cl:     blockingGrpcClient(),
+		logger: kitlog.NewNopLogger(),
+	}
+
+	// when
+	st, err := c.Evaluate(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, st)
+	require.Equal(t, router.ErrPolicyDenied, err)
+}
+
+func blockingGrpcClient() *policyGrpcClientMock {
+	clMock := &policyGrpcClientMock{}
+	clMock.EvaluateFunc = func(ctx context.Context, in *policypb.EvaluateRequest, opts ...grpc.CallOption) (*policypb.EvaluateResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return clMock
+}
+
+func testMessage() *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, "msg1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Hi!").
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
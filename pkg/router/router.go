@@ -23,8 +23,12 @@ import (
 	"github.com/ortuman/jackal/pkg/host"
 	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
 	"github.com/ortuman/jackal/pkg/router/stream"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
+// maxBounceDepth is the maximum number of nested errors a routed stanza may carry.
+const maxBounceDepth = 2
+
 // Router defines global router interface.
 type Router interface {
 
@@ -45,6 +49,13 @@ type Router interface {
 	Stop(ctx context.Context) error
 }
 
+// Policy defines a routing policy consulted before delivering a stanza.
+type Policy interface {
+	// Evaluate returns the stanza to be routed in place of the passed one.
+	// ErrPolicyDenied is returned in case stanza must not be delivered.
+	Evaluate(ctx context.Context, stanza stravaganza.Stanza) (stravaganza.Stanza, error)
+}
+
 // RoutingOptions represents C2S routing options mask.
 type RoutingOptions int8
 
@@ -95,17 +106,20 @@ type S2SRouter interface {
 }
 
 type router struct {
-	hosts *host.Hosts
-	c2s   C2SRouter
-	s2s   S2SRouter
+	hosts  *host.Hosts
+	c2s    C2SRouter
+	s2s    S2SRouter
+	policy Policy
 }
 
 // New creates a new router instance given a set of hosts, C2S and S2s routers.
-func New(hosts *host.Hosts, c2sRouter C2SRouter, s2sRouter S2SRouter) Router {
+// In case policy is not nil, it will be consulted before routing any stanza.
+func New(hosts *host.Hosts, c2sRouter C2SRouter, s2sRouter S2SRouter, policy Policy) Router {
 	return &router{
-		hosts: hosts,
-		c2s:   c2sRouter,
-		s2s:   s2sRouter,
+		hosts:  hosts,
+		c2s:    c2sRouter,
+		s2s:    s2sRouter,
+		policy: policy,
 	}
 }
 
@@ -142,6 +156,16 @@ func (r *router) Stop(ctx context.Context) error {
 }
 
 func (r *router) route(ctx context.Context, stanza stravaganza.Stanza, routingOpts RoutingOptions) ([]jid.JID, error) {
+	if xmpputil.BounceDepth(stanza) > maxBounceDepth {
+		return nil, ErrBounceLoop // break error about error loop
+	}
+	if r.policy != nil {
+		var err error
+		stanza, err = r.policy.Evaluate(ctx, stanza)
+		if err != nil {
+			return nil, err
+		}
+	}
 	toJID := stanza.ToJID()
 	if r.hosts.IsLocalHost(toJID.Domain()) {
 		return r.c2s.Route(ctx, stanza, routingOpts)
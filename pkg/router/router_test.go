@@ -0,0 +1,199 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
+	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/host"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
+	"github.com/stretchr/testify/require"
+)
+
+type c2sRouterStub struct {
+	C2SRouter
+	routed []stravaganza.Stanza
+}
+
+func (r *c2sRouterStub) Route(_ context.Context, stanza stravaganza.Stanza, _ RoutingOptions) ([]jid.JID, error) {
+	r.routed = append(r.routed, stanza)
+	return []jid.JID{*stanza.ToJID()}, nil
+}
+
+type policyStub struct {
+	evaluate func(stanza stravaganza.Stanza) (stravaganza.Stanza, error)
+}
+
+func (p *policyStub) Evaluate(_ context.Context, stanza stravaganza.Stanza) (stravaganza.Stanza, error) {
+	return p.evaluate(stanza)
+}
+
+func TestRouter_PolicyAllowed(t *testing.T) {
+	// given
+	hs := testHosts(t)
+	hs.RegisterDefaultHost("jackal.im", tls.Certificate{})
+
+	c2sRouter := &c2sRouterStub{}
+	r := New(hs, c2sRouter, nil, &policyStub{
+		evaluate: func(stanza stravaganza.Stanza) (stravaganza.Stanza, error) {
+			return stanza, nil
+		},
+	})
+	msg := testMessage()
+
+	// when
+	targets, err := r.Route(context.Background(), msg)
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, []jid.JID{*msg.ToJID()}, targets)
+	require.Equal(t, []stravaganza.Stanza{msg}, c2sRouter.routed)
+}
+
+func TestRouter_PolicyDenied(t *testing.T) {
+	// given
+	hs := testHosts(t)
+	hs.RegisterDefaultHost("jackal.im", tls.Certificate{})
+
+	c2sRouter := &c2sRouterStub{}
+	r := New(hs, c2sRouter, nil, &policyStub{
+		evaluate: func(_ stravaganza.Stanza) (stravaganza.Stanza, error) {
+			return nil, ErrPolicyDenied
+		},
+	})
+
+	// when
+	targets, err := r.Route(context.Background(), testMessage())
+
+	// then
+	require.Equal(t, ErrPolicyDenied, err)
+	require.Nil(t, targets)
+	require.Len(t, c2sRouter.routed, 0)
+}
+
+func TestRouter_PolicyModified(t *testing.T) {
+	// given
+	hs := testHosts(t)
+	hs.RegisterDefaultHost("jackal.im", tls.Certificate{})
+
+	c2sRouter := &c2sRouterStub{}
+	r := New(hs, c2sRouter, nil, &policyStub{
+		evaluate: func(stanza stravaganza.Stanza) (stravaganza.Stanza, error) {
+			return stravaganza.NewBuilderFromElement(stanza).
+				WithoutChildren("body").
+				BuildMessage()
+		},
+	})
+
+	// when
+	_, err := r.Route(context.Background(), testMessage())
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, c2sRouter.routed, 1)
+	require.Nil(t, c2sRouter.routed[0].Child("body"))
+}
+
+func TestRouter_BounceLoop(t *testing.T) {
+	// given
+	hs := testHosts(t)
+	hs.RegisterDefaultHost("jackal.im", tls.Certificate{})
+
+	c2sRouter := &c2sRouterStub{}
+	r := New(hs, c2sRouter, nil, nil)
+
+	msg := testMessage()
+
+	// when
+	// misbehaving entities keep on bouncing errors back and forth
+	var errs []error
+
+	stanza := stravaganza.Stanza(msg)
+	for i := 0; i < 5; i++ {
+		_, err := r.Route(context.Background(), stanza)
+		errs = append(errs, err)
+
+		stanza = xmpputil.MakeErrorStanza(stanza, stanzaerror.ServiceUnavailable)
+	}
+
+	// then
+	require.Equal(t, []error{nil, nil, nil, ErrBounceLoop, ErrBounceLoop}, errs)
+	require.Len(t, c2sRouter.routed, 3)
+}
+
+func testMessage() *stravaganza.Message {
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, "msg1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Hi!").
+				Build(),
+		).
+		BuildMessage()
+	return msg
+}
+
+// testHosts returns a hosts set whose default localhost certificate is generated into a
+// test temporary directory, so that no key material is written into the package tree.
+func testHosts(t *testing.T) *host.Hosts {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+
+	var cfg host.Config
+	cfg.Domain = "localhost"
+	cfg.TLS.CertFile = filepath.Join(dir, "cert.pem")
+	cfg.TLS.PrivateKeyFile = filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(cfg.TLS.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(cfg.TLS.PrivateKeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	hs, err := host.NewHosts(host.Configs{cfg})
+	require.NoError(t, err)
+	return hs
+}
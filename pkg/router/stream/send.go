@@ -0,0 +1,61 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stream
+
+import "errors"
+
+// ErrSendAborted is reported to OnSendError callbacks in case the stream got terminated
+// before the send operation result was known.
+var ErrSendAborted = errors.New("stream: send aborted by stream termination")
+
+// OnSendError waits in background for a send operation result, invoking fn in case it failed.
+//
+// Stream send results are resolved by the stream's own execution queue, so awaiting them
+// synchronously from a hook handler would deadlock. Use this function instead whenever a failed send
+// needs to be reported or compensated.
+//
+// The wait is bounded by done, usually the stream Done channel, so that results never resolved
+// by a terminated stream are reported as ErrSendAborted.
+func OnSendError(errCh <-chan error, done <-chan struct{}, fn func(err error)) {
+	if errCh == nil {
+		return
+	}
+	// avoid spawning a goroutine if result is already known
+	select {
+	case err := <-errCh:
+		if err != nil {
+			fn(err)
+		}
+		return
+	default:
+	}
+	go func() {
+		select {
+		case err := <-errCh:
+			if err != nil {
+				fn(err)
+			}
+		case <-done:
+			select {
+			case err := <-errCh: // resolved right before termination
+				if err != nil {
+					fn(err)
+				}
+			default:
+				fn(ErrSendAborted)
+			}
+		}
+	}()
+}
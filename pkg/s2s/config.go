@@ -41,8 +41,22 @@ type ListenerConfig struct {
 	// MaxStanzaSize is the maximum size a listener incoming stanza may have.
 	MaxStanzaSize int `fig:"max_stanza_size" default:"1048576"`
 
+	// MaxStanzaDepth is the maximum element nesting depth a listener incoming stanza may have.
+	MaxStanzaDepth int `fig:"max_stanza_depth" default:"64"`
+
+	// MaxAssemblyBufferSize is the maximum number of unparsed bytes a listener connection may buffer
+	// while assembling an incoming stanza. Zero means no limit other than max stanza size.
+	MaxAssemblyBufferSize int `fig:"max_assembly_buffer_size"`
+
+	// ReadBufferSize is the size in bytes of the buffer incoming connection data is read through.
+	ReadBufferSize int `fig:"read_buffer_size" default:"4096"`
+
 	// DirectTLS, if true, tls.Listen will be used as network listener.
 	DirectTLS bool `fig:"direct_tls"`
+
+	// ProxyProtocol, if true, a PROXY protocol v2 header will be required at the beginning of every accepted
+	// connection, and the client address it conveys will be used as connection remote address.
+	ProxyProtocol bool `fig:"proxy_protocol"`
 }
 
 // OutConfig defines S2S out configuration.
@@ -61,4 +75,36 @@ type OutConfig struct {
 
 	// MaxStanzaSize is the maximum size a listener incoming stanza may have.
 	MaxStanzaSize int `fig:"max_stanza_size" default:"131072"`
+
+	// ReadBufferSize is the size in bytes of the buffer outgoing connection data is read through.
+	ReadBufferSize int `fig:"read_buffer_size" default:"4096"`
+
+	// SlowStart contains outgoing throughput ramp up configuration for newly federated remote domains.
+	SlowStart struct {
+		// Enabled tells whether or not slow start should be applied.
+		Enabled bool `fig:"enabled"`
+
+		// InitialRate defines the initial number of stanzas per second that can be sent to a new remote domain.
+		InitialRate float64 `fig:"initial_rate" default:"10"`
+
+		// MaxRate defines the stanzas per second rate at which slow start is considered completed.
+		MaxRate float64 `fig:"max_rate" default:"1000"`
+
+		// IdleTimeout defines how long a remote domain ramp up state is kept after its last sent stanza.
+		IdleTimeout time.Duration `fig:"idle_timeout" default:"1h"`
+	} `fig:"slow_start"`
+
+	// HopLimit contains federation routing loop prevention configuration.
+	HopLimit HopLimitConfig `fig:"hop_limit"`
+}
+
+// HopLimitConfig defines the maximum number of S2S hops a stanza may make along its routing chain.
+type HopLimitConfig struct {
+	// Max defines the maximum number of S2S hops a stanza may make. Stanzas received from a remote server
+	// account for one hop, and every route towards a remote domain adds another one.
+	// Exceeding stanzas are dropped. A zero value means no limit.
+	Max int `fig:"max"`
+
+	// Domains overrides the maximum number of hops for specific remote domains.
+	Domains map[string]int `fig:"domains"`
 }
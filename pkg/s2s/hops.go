@@ -0,0 +1,30 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import "context"
+
+type hopsCtxKey struct{}
+
+// withHops returns a copy of ctx carrying the number of S2S hops a stanza has already made.
+func withHops(ctx context.Context, hops int) context.Context {
+	return context.WithValue(ctx, hopsCtxKey{}, hops)
+}
+
+// hopsFromContext returns the number of S2S hops carried by ctx.
+func hopsFromContext(ctx context.Context) int {
+	hops, _ := ctx.Value(hopsCtxKey{}).(int)
+	return hops
+}
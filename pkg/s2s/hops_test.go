@@ -0,0 +1,37 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHops_Context(t *testing.T) {
+	// given
+	ctx := context.Background()
+
+	// when
+	hops0 := hopsFromContext(ctx)
+	hops1 := hopsFromContext(withHops(ctx, 1))
+	hops2 := hopsFromContext(withHops(withHops(ctx, 1), 2))
+
+	// then
+	require.Equal(t, 0, hops0)
+	require.Equal(t, 1, hops1)
+	require.Equal(t, 2, hops2)
+}
@@ -41,6 +41,7 @@ import (
 	xmppsession "github.com/ortuman/jackal/pkg/session"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
+	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 )
 
 type inState uint32
@@ -55,10 +56,13 @@ const (
 var inDisconnectTimeout = time.Second * 5
 
 type inConfig struct {
-	reqTimeout    time.Duration
-	maxStanzaSize int
-	directTLS     bool
-	tlsConfig     *tls.Config
+	reqTimeout     time.Duration
+	maxStanzaSize  int
+	maxStanzaDepth int
+	maxAsmBufSize  int
+	directTLS      bool
+	tlsConfig      *tls.Config
+	draining       bool
 }
 
 type inS2S struct {
@@ -80,6 +84,7 @@ type inS2S struct {
 	discTm       *time.Timer
 	doneCh       chan struct{}
 	sendDisabled bool
+	tlsPending   bool
 
 	mu     sync.RWMutex
 	state  inState
@@ -103,9 +108,8 @@ func newInS2S(
 	logger kitlog.Logger,
 	cfg inConfig,
 ) (*inS2S, error) {
-	// set default rate limiter
-	rLim := shapers.DefaultS2S().RateLimiter()
-	if err := tr.SetReadRateLimiter(rLim); err != nil {
+	// set default rate limiters
+	if err := shapers.DefaultS2S().ApplyRateLimiters(tr); err != nil {
 		return nil, err
 	}
 	// create session
@@ -118,7 +122,9 @@ func newInS2S(
 		tr,
 		hosts,
 		xmppsession.Config{
-			MaxStanzaSize: cfg.maxStanzaSize,
+			MaxStanzaSize:         cfg.maxStanzaSize,
+			MaxStanzaDepth:        cfg.maxStanzaDepth,
+			MaxAssemblyBufferSize: cfg.maxAsmBufSize,
 		},
 		sLogger,
 	)
@@ -211,8 +217,13 @@ func (s *inS2S) handleSessionResult(elem stravaganza.Element, sErr error) {
 		ctx, cancel := s.requestContext()
 		defer cancel()
 
+		// every element received from a remote server has already made one S2S hop
+		ctx = withHops(ctx, 1)
+
 		switch {
 		case sErr == nil && elem != nil:
+			s.tlsPending = false // STARTTLS handshake succeeded
+
 			err := s.handleElement(ctx, elem)
 			if err != nil {
 				level.Warn(s.logger).Log("msg", "failed to process incoming S2S session element", "err", err)
@@ -254,6 +265,8 @@ func (s *inS2S) handleElement(ctx context.Context, elem stravaganza.Element) err
 }
 
 func (s *inS2S) handleConnecting(ctx context.Context, elem stravaganza.Element) error {
+	isInitialStream := len(s.target) == 0
+
 	// open stream session
 	s.target = elem.Attribute(stravaganza.To)
 	if len(s.target) == 0 {
@@ -265,6 +278,10 @@ func (s *inS2S) handleConnecting(ctx context.Context, elem stravaganza.Element)
 	s.jd, _ = jid.New("", s.sender, "", true)
 	s.session.SetFromJID(s.jd)
 
+	if isInitialStream && s.cfg.draining {
+		level.Info(s.logger).Log("msg", "rejected S2S stream: instance is draining")
+		return s.disconnect(ctx, streamerror.E(streamerror.SystemShutdown))
+	}
 	fb := stravaganza.NewBuilder("stream:features")
 	fb.WithAttribute("xmlns:stream", streamNamespace)
 	fb.WithAttribute("version", "1.0")
@@ -404,13 +421,16 @@ func (s *inS2S) processIQ(ctx context.Context, iq *stravaganza.IQ) error {
 	_, err = s.router.Route(ctx, outIQ)
 	switch err {
 	case router.ErrResourceNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, iq).Element())
+		return s.sendStanzaError(ctx, stanzaerror.ServiceUnavailable, iq)
 
 	case router.ErrRemoteServerNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerNotFound, iq).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerNotFound, iq)
 
 	case router.ErrRemoteServerTimeout:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerTimeout, iq).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerTimeout, iq)
+
+	case router.ErrPolicyDenied:
+		return s.sendStanzaError(ctx, stanzaerror.NotAllowed, iq)
 
 	case nil:
 		_, err = s.runHook(ctx, hook.S2SInStreamIQRouted, &hook.S2SStreamInfo{
@@ -467,16 +487,19 @@ sendMsg:
 		goto sendMsg
 
 	case router.ErrNotExistingAccount:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, message).Element())
+		return s.sendStanzaError(ctx, stanzaerror.ServiceUnavailable, message)
 
 	case router.ErrRemoteServerNotFound:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerNotFound, message).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerNotFound, message)
 
 	case router.ErrRemoteServerTimeout:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.RemoteServerTimeout, message).Element())
+		return s.sendStanzaError(ctx, stanzaerror.RemoteServerTimeout, message)
+
+	case router.ErrPolicyDenied:
+		return s.sendStanzaError(ctx, stanzaerror.NotAllowed, message)
 
-	case router.ErrUserNotAvailable:
-		return s.sendElement(ctx, stanzaerror.E(stanzaerror.ServiceUnavailable, message).Element())
+	case router.ErrUserNotAvailable, router.ErrStanzaBounced:
+		return s.sendStanzaError(ctx, stanzaerror.ServiceUnavailable, message)
 
 	case nil:
 		_, err = s.runHook(ctx, hook.S2SInStreamMessageRouted, &hook.S2SStreamInfo{
@@ -716,6 +739,7 @@ func (s *inS2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) e
 		Certificates: s.hosts.Certificates(),
 	}, false)
 	s.flags.setSecured()
+	s.tlsPending = true
 
 	level.Info(s.logger).Log("msg", "secured S2S incoming stream", "sender", s.sender, "target", s.target)
 
@@ -724,6 +748,14 @@ func (s *inS2S) proceedStartTLS(ctx context.Context, elem stravaganza.Element) e
 }
 
 func (s *inS2S) handleSessionError(ctx context.Context, err error) {
+	if s.tlsPending {
+		// no element could be read after STARTTLS proceed: TLS handshake failed
+		s.tlsPending = false
+
+		reason := transport.TLSHandshakeFailureReason(err)
+		reportTLSHandshakeFailure(reason)
+		level.Warn(s.logger).Log("msg", "failed to complete S2S TLS handshake", "reason", reason, "err", err)
+	}
 	switch err {
 	case xmppparser.ErrStreamClosedByPeer:
 		_ = s.session.Close(ctx)
@@ -740,8 +772,7 @@ func (s *inS2S) restartSession() {
 }
 
 func (s *inS2S) updateRateLimiter() error {
-	rLim := s.shapers.MatchingJID(s.jd).RateLimiter()
-	return s.tr.SetReadRateLimiter(rLim)
+	return s.shapers.MatchingJID(s.jd).ApplyRateLimiters(s.tr)
 }
 
 func (s *inS2S) disconnect(ctx context.Context, streamErr *streamerror.Error) error {
@@ -815,6 +846,13 @@ func (s *inS2S) sendElement(ctx context.Context, elem stravaganza.Element) error
 	return err
 }
 
+func (s *inS2S) sendStanzaError(ctx context.Context, reason stanzaerror.Reason, stanza stravaganza.Stanza) error {
+	if !xmpputil.IsBounceable(stanza) {
+		return nil // never reply an error with another error
+	}
+	return s.sendElement(ctx, stanzaerror.E(reason, stanza).Element())
+}
+
 func (s *inS2S) setState(state inState) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
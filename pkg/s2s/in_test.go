@@ -86,6 +86,52 @@ func TestInS2S_Disconnect(t *testing.T) {
 	require.Len(t, trMock.CloseCalls(), 1)
 }
 
+func TestInS2S_Draining(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.CloseFunc = func() error { return nil }
+
+	hMock := &hostsMock{}
+	hMock.DefaultHostNameFunc = func() string { return "localhost" }
+
+	sessMock := &sessionMock{}
+
+	sendBuf := bytes.NewBuffer(nil)
+	sessMock.SetFromJIDFunc = func(ssJID *jid.JID) {}
+	sessMock.OpenStreamFunc = func(ctx context.Context) error { return nil }
+	sessMock.SendFunc = func(ctx context.Context, element stravaganza.Element) error {
+		_ = element.ToXML(sendBuf, true)
+		return nil
+	}
+	sessMock.CloseFunc = func(ctx context.Context) error { return nil }
+
+	s := &inS2S{
+		cfg:     inConfig{draining: true},
+		state:   inConnecting,
+		session: sessMock,
+		tr:      trMock,
+		hosts:   hMock,
+		rq:      runqueue.New("in_s2s:test"),
+		doneCh:  make(chan struct{}),
+		inHub:   NewInHub(kitlog.NewNopLogger()),
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+	// when
+	err := s.handleConnecting(context.Background(), stravaganza.NewBuilder("stream:stream").
+		WithAttribute(stravaganza.To, "localhost").
+		WithAttribute(stravaganza.From, "jabber.org").
+		Build(),
+	)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, `<stream:error><system-shutdown xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></stream:error>`, sendBuf.String())
+	require.Len(t, sessMock.OpenStreamCalls(), 1)
+	require.Len(t, trMock.CloseCalls(), 1)
+	require.Equal(t, inDisconnected, s.getState())
+}
+
 func TestInS2S_HandleSessionElement(t *testing.T) {
 	var tests = []struct {
 		name string
@@ -334,6 +380,7 @@ func TestInS2S_HandleSessionElement(t *testing.T) {
 			trMock.TypeFunc = func() transport.Type { return transport.Socket }
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error { return nil }
 
 			// hosts mock
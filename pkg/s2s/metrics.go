@@ -106,6 +106,15 @@ var (
 		},
 		[]string{"instance"},
 	)
+	s2sTLSHandshakeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "s2s",
+			Name:      "tls_handshake_failures_total",
+			Help:      "The total number of failed incoming TLS handshakes by failure reason.",
+		},
+		[]string{"instance", "reason"},
+	)
 )
 
 func init() {
@@ -118,6 +127,7 @@ func init() {
 	prometheus.MustRegister(s2sIncomingRequestDurationBucket)
 	prometheus.MustRegister(s2sIncomingTotalConnections)
 	prometheus.MustRegister(s2sOutgoingTotalConnections)
+	prometheus.MustRegister(s2sTLSHandshakeFailures)
 }
 
 func reportIncomingConnectionRegistered() {
@@ -182,3 +192,11 @@ func reportTotalOutgoingConnections(totalConns int) {
 	}
 	s2sOutgoingTotalConnections.With(metricLabel).Set(float64(totalConns))
 }
+
+func reportTLSHandshakeFailure(reason string) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+		"reason":   reason,
+	}
+	s2sTLSHandshakeFailures.With(metricLabel).Inc()
+}
@@ -93,6 +93,7 @@ type outConfig struct {
 	dialTimeout   time.Duration
 	reqTimeout    time.Duration
 	maxStanzaSize int
+	rdBufSize     int
 }
 
 type outS2S struct {
@@ -108,6 +109,7 @@ type outS2S struct {
 	hosts    *host.Hosts
 	tlsCfg   *tls.Config
 	onClose  func(s *outS2S)
+	slowSt   *slowStart
 	dbResCh  chan stream.DialbackResult
 	shapers  shaper.Shapers
 	hk       *hook.Hooks
@@ -118,6 +120,8 @@ type outS2S struct {
 	state        outState
 	flags        flags
 	pendingQueue []stravaganza.Element
+	slowQueue    []stravaganza.Element
+	slowTm       *time.Timer
 }
 
 func newOutS2S(
@@ -130,6 +134,7 @@ func newOutS2S(
 	hk *hook.Hooks,
 	logger kitlog.Logger,
 	onClose func(s *outS2S),
+	slowSt *slowStart,
 	cfg outConfig,
 ) *outS2S {
 	stm := &outS2S{
@@ -140,6 +145,7 @@ func newOutS2S(
 		tlsCfg:  tlsCfg,
 		cfg:     cfg,
 		onClose: onClose,
+		slowSt:  slowSt,
 		kv:      kv,
 		shapers: shapers,
 		hk:      hk,
@@ -218,11 +224,18 @@ func (s *outS2S) dial(ctx context.Context) error {
 	}
 	level.Info(s.logger).Log("msg", "dialed S2S remote connection", "direct_tls", usesTLS)
 
-	s.tr = transport.NewSocketTransport(conn, 0, 0)
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		tr, err := transport.NewTLSSocketTransport(tlsConn, 0, 0, s.cfg.rdBufSize, 0, 0)
+		if err != nil {
+			return err
+		}
+		s.tr = tr
+	} else {
+		s.tr = transport.NewSocketTransport(conn, 0, 0, s.cfg.rdBufSize, 0, 0)
+	}
 
-	// set default rate limiter
-	rLim := s.shapers.DefaultS2S().RateLimiter()
-	if err := s.tr.SetReadRateLimiter(rLim); err != nil {
+	// set default rate limiters
+	if err := s.shapers.DefaultS2S().ApplyRateLimiters(s.tr); err != nil {
 		return err
 	}
 	s.session = xmppsession.New(
@@ -486,6 +499,9 @@ func (s *outS2S) handleAuthorizingDialbackKey(ctx context.Context, elem stravaga
 }
 
 func (s *outS2S) handleSessionError(ctx context.Context, err error) {
+	if s.slowSt != nil && err != xmppparser.ErrStreamClosedByPeer {
+		s.slowSt.onError()
+	}
 	switch err {
 	case xmppparser.ErrStreamClosedByPeer:
 		_ = s.session.Close(ctx)
@@ -501,7 +517,7 @@ func (s *outS2S) finishAuthentication(ctx context.Context) error {
 
 	// send pending elements
 	for _, elem := range s.pendingQueue {
-		if err := s.sendElement(ctx, elem); err != nil {
+		if err := s.sendStanzaElement(ctx, elem); err != nil {
 			return err
 		}
 	}
@@ -530,13 +546,69 @@ func (s *outS2S) disconnect(ctx context.Context, streamErr *streamerror.Error) e
 func (s *outS2S) sendOrEnqueueElement(ctx context.Context, elem stravaganza.Element) error {
 	switch s.getState() {
 	case outAuthenticated:
-		return s.sendElement(ctx, elem)
+		return s.sendStanzaElement(ctx, elem)
 	default:
 		s.pendingQueue = append(s.pendingQueue, elem)
 	}
 	return nil
 }
 
+func (s *outS2S) sendStanzaElement(ctx context.Context, elem stravaganza.Element) error {
+	if s.slowSt == nil {
+		return s.sendElement(ctx, elem)
+	}
+	s.slowQueue = append(s.slowQueue, elem)
+	if s.slowTm != nil {
+		return nil // already waiting for a send slot
+	}
+	return s.sendSlowQueue(ctx)
+}
+
+func (s *outS2S) sendSlowQueue(ctx context.Context) error {
+	for len(s.slowQueue) > 0 {
+		if delay := s.slowSt.reserve(); delay > 0 {
+			s.slowTm = time.AfterFunc(delay, func() {
+				s.rq.Run(s.sendSlowQueueHead)
+			})
+			return nil
+		}
+		if err := s.sendSlowQueueElement(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *outS2S) sendSlowQueueHead() {
+	s.slowTm = nil
+	if s.getState() != outAuthenticated {
+		return
+	}
+	ctx, cancel := s.requestContext()
+	defer cancel()
+
+	// send slot was already reserved when timer was armed
+	err := s.sendSlowQueueElement(ctx)
+	if err == nil {
+		err = s.sendSlowQueue(ctx)
+	}
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "failed to send throttled S2S stanza", "err", err, "id", s.ID())
+	}
+}
+
+func (s *outS2S) sendSlowQueueElement(ctx context.Context) error {
+	elem := s.slowQueue[0]
+	s.slowQueue = s.slowQueue[1:]
+
+	if err := s.sendElement(ctx, elem); err != nil {
+		s.slowSt.onError()
+		return err
+	}
+	s.slowSt.onSuccess()
+	return nil
+}
+
 func (s *outS2S) sendElement(ctx context.Context, elem stravaganza.Element) error {
 	err := s.session.Send(ctx, elem)
 	if err != nil {
@@ -558,6 +630,11 @@ func (s *outS2S) close(ctx context.Context) error {
 	// unregister S2S out stream
 	s.setState(outDisconnected)
 
+	if s.slowTm != nil {
+		s.slowTm.Stop()
+		s.slowTm = nil
+	}
+
 	if s.onClose != nil {
 		s.onClose(s)
 	}
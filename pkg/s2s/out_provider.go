@@ -45,6 +45,9 @@ type OutProvider struct {
 	outStreams map[string]s2sOut
 	doneCh     chan chan struct{}
 
+	ssMu       sync.Mutex
+	slowStarts map[string]*slowStart
+
 	newOutFn func(sender, target string) s2sOut
 	newDbFn  func(sender, target string, dbParam DialbackParams) s2sDialback
 }
@@ -66,6 +69,7 @@ func NewOutProvider(
 		hk:         hk,
 		logger:     logger,
 		outStreams: make(map[string]s2sOut),
+		slowStarts: make(map[string]*slowStart),
 		doneCh:     make(chan chan struct{}),
 	}
 	op.newOutFn = op.newOutS2S
@@ -204,11 +208,13 @@ func (p *OutProvider) newOutS2S(sender, target string) s2sOut {
 		p.hk,
 		p.logger,
 		p.unregister,
+		p.getSlowStart(target),
 		outConfig{
 			dbSecret:      p.cfg.DialbackSecret,
 			dialTimeout:   p.cfg.DialTimeout,
 			reqTimeout:    p.cfg.RequestTimeout,
 			maxStanzaSize: p.cfg.MaxStanzaSize,
+			rdBufSize:     p.cfg.ReadBufferSize,
 		},
 	)
 }
@@ -226,11 +232,41 @@ func (p *OutProvider) newDialbackS2S(sender, target string, dbParams DialbackPar
 			dialTimeout:   p.cfg.DialTimeout,
 			reqTimeout:    p.cfg.RequestTimeout,
 			maxStanzaSize: p.cfg.MaxStanzaSize,
+			rdBufSize:     p.cfg.ReadBufferSize,
 		},
 		dbParams,
 	)
 }
 
+func (p *OutProvider) getSlowStart(target string) *slowStart {
+	if !p.cfg.SlowStart.Enabled {
+		return nil
+	}
+	p.ssMu.Lock()
+	defer p.ssMu.Unlock()
+
+	ss := p.slowStarts[target]
+	if ss == nil {
+		p.evictIdleSlowStarts()
+
+		ss = newSlowStart(slowStartConfig{
+			initialRate: p.cfg.SlowStart.InitialRate,
+			maxRate:     p.cfg.SlowStart.MaxRate,
+		})
+		p.slowStarts[target] = ss
+	}
+	return ss
+}
+
+func (p *OutProvider) evictIdleSlowStarts() {
+	idleTm := time.Now().Add(-p.cfg.SlowStart.IdleTimeout)
+	for target, ss := range p.slowStarts {
+		if ss.idleSince(idleTm) {
+			delete(p.slowStarts, target)
+		}
+	}
+}
+
 func (p *OutProvider) tlsConfig(serverName string) *tls.Config {
 	return &tls.Config{
 		ServerName:   serverName,
@@ -75,3 +75,27 @@ func TestOutProvider_GetDialback(t *testing.T) {
 	require.Len(t, conn2.(*s2sDialbackMock).startCalls(), 1)
 	require.Len(t, conn2.(*s2sDialbackMock).dialCalls(), 1)
 }
+
+func TestOutProvider_EvictIdleSlowStarts(t *testing.T) {
+	// given
+	op := &OutProvider{
+		slowStarts: make(map[string]*slowStart),
+	}
+	op.cfg.SlowStart.Enabled = true
+	op.cfg.SlowStart.InitialRate = 10
+	op.cfg.SlowStart.MaxRate = 100
+	op.cfg.SlowStart.IdleTimeout = time.Hour
+
+	ss := op.getSlowStart("jabber.org")
+	ss.lastUsed = time.Now().Add(-time.Hour * 2)
+
+	op.getSlowStart("xmpp.org").reserve()
+
+	// when
+	op.getSlowStart("jackal.im")
+
+	// then
+	require.Len(t, op.slowStarts, 2)
+	require.NotContains(t, op.slowStarts, "jabber.org")
+	require.Contains(t, op.slowStarts, "xmpp.org")
+}
@@ -73,6 +73,50 @@ func TestOutS2S_SendElement(t *testing.T) {
 	require.Equal(t, `<auth xmlns='urn:ietf:params:xml:ns:xmpp-sasl'/>`, sendBuf.String())
 }
 
+func TestOutS2S_SendElementSlowStart(t *testing.T) {
+	// given
+	sessMock := &sessionMock{}
+
+	var mtx sync.RWMutex
+	var sent []string
+
+	sessMock.SendFunc = func(ctx context.Context, element stravaganza.Element) error {
+		mtx.Lock()
+		defer mtx.Unlock()
+		sent = append(sent, element.Attribute(stravaganza.ID))
+		return nil
+	}
+	s := &outS2S{
+		state:   outAuthenticated,
+		session: sessMock,
+		slowSt:  newSlowStart(slowStartConfig{initialRate: 10, maxRate: 100}),
+		rq:      runqueue.New("out_s2s:test"),
+		hk:      hook.NewHooks(),
+		logger:  kitlog.NewNopLogger(),
+	}
+	// when
+	for _, id := range []string{"m1", "m2", "m3"} {
+		s.SendElement(stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.ID, id).
+			Build(),
+		)
+	}
+	time.Sleep(time.Millisecond * 50)
+
+	mtx.Lock()
+	sent0 := append([]string(nil), sent...)
+	mtx.Unlock()
+
+	time.Sleep(time.Millisecond * 250)
+
+	// then
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	require.Equal(t, []string{"m1"}, sent0) // remaining stanzas are held without blocking
+	require.Equal(t, []string{"m1", "m2", "m3"}, sent)
+}
+
 func TestOutS2S_Disconnect(t *testing.T) {
 	// given
 	trMock := &transportMock{}
@@ -295,6 +339,7 @@ func TestOutS2S_HandleSessionElement(t *testing.T) {
 			trMock.TypeFunc = func() transport.Type { return transport.Socket }
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error { return nil }
 
 			stm := &outS2S{
@@ -398,6 +443,7 @@ func TestDialbackS2S_HandleSessionElement(t *testing.T) {
 			trMock.TypeFunc = func() transport.Type { return transport.Socket }
 			trMock.StartTLSFunc = func(cfg *tls.Config, asClient bool) {}
 			trMock.SetReadRateLimiterFunc = func(rLim *rate.Limiter) error { return nil }
+			trMock.SetWriteRateLimiterFunc = func(wLim *rate.Limiter) error { return nil }
 			trMock.CloseFunc = func() error { return nil }
 
 			stm := &outS2S{
@@ -18,18 +18,24 @@ import (
 	"context"
 	"errors"
 
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/ortuman/jackal/pkg/router"
 )
 
 type s2sRouter struct {
 	outProvider outProvider
+	hopLimitCfg HopLimitConfig
+	logger      kitlog.Logger
 }
 
 // NewRouter creates and returns an initialized S2S router.
-func NewRouter(outProvider *OutProvider) router.S2SRouter {
+func NewRouter(outProvider *OutProvider, hopLimitCfg HopLimitConfig, logger kitlog.Logger) router.S2SRouter {
 	return &s2sRouter{
 		outProvider: outProvider,
+		hopLimitCfg: hopLimitCfg,
+		logger:      logger,
 	}
 }
 
@@ -37,6 +43,18 @@ func (r *s2sRouter) Route(ctx context.Context, stanza stravaganza.Stanza, sender
 	remoteJID := stanza.ToJID()
 	targetDomain := remoteJID.Domain()
 
+	hops := hopsFromContext(ctx) + 1
+	if limit := r.hopLimit(targetDomain); limit > 0 && hops > limit {
+		level.Warn(r.logger).Log("msg", "dropped stanza exceeding S2S hop limit",
+			"reason", "possible routing loop",
+			"id", stanza.Attribute(stravaganza.ID),
+			"from", stanza.FromJID().String(),
+			"target", targetDomain,
+			"hops", hops,
+		)
+		return router.ErrHopLimitExceeded
+	}
+
 	stm, err := r.outProvider.GetOut(ctx, senderDomain, targetDomain)
 	switch {
 	case err == nil:
@@ -50,6 +68,13 @@ func (r *s2sRouter) Route(ctx context.Context, stanza stravaganza.Stanza, sender
 	return nil
 }
 
+func (r *s2sRouter) hopLimit(domain string) int {
+	if limit, ok := r.hopLimitCfg.Domains[domain]; ok {
+		return limit
+	}
+	return r.hopLimitCfg.Max
+}
+
 func (r *s2sRouter) Start(_ context.Context) error {
 	return nil
 }
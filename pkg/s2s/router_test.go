@@ -15,10 +15,12 @@
 package s2s
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"testing"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/router/stream"
@@ -81,6 +83,37 @@ func TestS2sRouter_RouteServerGenericError(t *testing.T) {
 	require.Equal(t, router.ErrRemoteServerNotFound, err)
 }
 
+func TestS2sRouter_RouteHopLimitExceeded(t *testing.T) {
+	// given
+	out := &s2sOutMock{}
+	out.SendElementFunc = func(elem stravaganza.Element) <-chan error {
+		return nil
+	}
+	op := &outProviderMock{}
+	op.GetOutFunc = func(ctx context.Context, sender string, target string) (stream.S2SOut, error) {
+		return out, nil
+	}
+	logBuf := bytes.NewBuffer(nil)
+
+	// when
+	r := &s2sRouter{
+		outProvider: op,
+		hopLimitCfg: HopLimitConfig{Max: 2},
+		logger:      kitlog.NewLogfmtLogger(logBuf),
+	}
+	var errs []error
+	for i := 0; i < 3; i++ {
+		errs = append(errs, r.Route(withHops(context.Background(), i), testMessageStanza(), "jackal.im"))
+	}
+
+	// then
+	require.Equal(t, []error{nil, nil, router.ErrHopLimitExceeded}, errs)
+	require.Len(t, out.SendElementCalls(), 2)
+
+	require.Contains(t, logBuf.String(), "dropped stanza exceeding S2S hop limit")
+	require.Contains(t, logBuf.String(), "hops=3")
+}
+
 func testMessageStanza() *stravaganza.Message {
 	b := stravaganza.NewMessageBuilder()
 	b.WithAttribute("from", "noelia@jackal.im/yard")
@@ -0,0 +1,97 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type slowStartConfig struct {
+	initialRate float64
+	maxRate     float64
+}
+
+// slowStart ramps up outgoing stanza throughput towards a remote domain.
+// Starting at an initial rate, every successfully sent stanza increases allowed rate by one stanza per second,
+// while every error halves it. Once max rate is reached throughput is no longer limited.
+type slowStart struct {
+	cfg slowStartConfig
+
+	mu       sync.RWMutex
+	rt       float64
+	lim      *rate.Limiter
+	done     bool
+	lastUsed time.Time
+}
+
+func newSlowStart(cfg slowStartConfig) *slowStart {
+	return &slowStart{
+		cfg:      cfg,
+		rt:       cfg.initialRate,
+		lim:      rate.NewLimiter(rate.Limit(cfg.initialRate), 1),
+		lastUsed: time.Now(),
+	}
+}
+
+// reserve books a send slot, returning how long the caller must wait before sending.
+func (s *slowStart) reserve() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUsed = time.Now()
+	if s.done {
+		return 0
+	}
+	return s.lim.ReserveN(s.lastUsed, 1).DelayFrom(s.lastUsed)
+}
+
+func (s *slowStart) idleSince(tm time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastUsed.Before(tm)
+}
+
+func (s *slowStart) onSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.setRate(s.rt + 1)
+}
+
+func (s *slowStart) onError() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setRate(s.rt / 2)
+}
+
+func (s *slowStart) rate() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.done {
+		return math.Inf(1)
+	}
+	return s.rt
+}
+
+func (s *slowStart) setRate(rt float64) {
+	s.rt = math.Max(s.cfg.initialRate, math.Min(rt, s.cfg.maxRate))
+	s.done = s.rt >= s.cfg.maxRate
+	s.lim.SetLimit(rate.Limit(s.rt))
+}
@@ -0,0 +1,79 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package s2s
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowStart_InitialRate(t *testing.T) {
+	// given
+	ss := newSlowStart(slowStartConfig{initialRate: 10, maxRate: 100})
+
+	// when
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		delays = append(delays, ss.reserve())
+	}
+
+	// then
+	require.Equal(t, time.Duration(0), delays[0])
+	require.Greater(t, delays[1], time.Millisecond*50)
+	require.Greater(t, delays[2], time.Millisecond*150)
+	require.Equal(t, float64(10), ss.rate())
+}
+
+func TestSlowStart_RampUp(t *testing.T) {
+	// given
+	ss := newSlowStart(slowStartConfig{initialRate: 10, maxRate: 15})
+
+	// when
+	for i := 0; i < 3; i++ {
+		ss.onSuccess()
+	}
+	rt0 := ss.rate()
+
+	for i := 0; i < 3; i++ {
+		ss.onSuccess()
+	}
+	rt1 := ss.rate()
+
+	// then
+	require.Equal(t, float64(13), rt0)
+	require.Equal(t, math.Inf(1), rt1) // slow start completed
+}
+
+func TestSlowStart_BackOff(t *testing.T) {
+	// given
+	ss := newSlowStart(slowStartConfig{initialRate: 10, maxRate: 100})
+	for i := 0; i < 30; i++ {
+		ss.onSuccess()
+	}
+
+	// when
+	ss.onError()
+	rt0 := ss.rate()
+
+	ss.onError()
+	rt1 := ss.rate()
+
+	// then
+	require.Equal(t, float64(20), rt0)
+	require.Equal(t, float64(10), rt1) // never below initial rate
+}
@@ -34,6 +34,7 @@ import (
 	"github.com/ortuman/jackal/pkg/router"
 	"github.com/ortuman/jackal/pkg/shaper"
 	"github.com/ortuman/jackal/pkg/transport"
+	"github.com/ortuman/jackal/pkg/util/proxyproto"
 )
 
 const (
@@ -55,8 +56,9 @@ type SocketListener struct {
 	logger        kitlog.Logger
 	connHandlerFn func(conn net.Conn)
 
-	ln     net.Listener
-	active uint32
+	ln       net.Listener
+	active   uint32
+	draining uint32
 }
 
 // NewListeners creates and initializes a set of S2S listeners based of cfg configuration.
@@ -135,6 +137,9 @@ func (l *SocketListener) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if l.cfg.ProxyProtocol {
+		ln = proxyproto.NewListener(ln, l.cfg.ConnectTimeout)
+	}
 	if l.cfg.DirectTLS {
 		ln = tls.NewListener(ln, l.getTLSConfig())
 	}
@@ -168,8 +173,40 @@ func (l *SocketListener) Stop(ctx context.Context) error {
 	return nil
 }
 
+// SetDraining sets listener draining mode. While draining, new incoming streams are rejected
+// with a system-shutdown stream error.
+func (l *SocketListener) SetDraining(draining bool) {
+	var val uint32
+	if draining {
+		val = 1
+	}
+	atomic.StoreUint32(&l.draining, val)
+}
+
 func (l *SocketListener) handleConn(conn net.Conn) {
-	tr := transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout)
+	if pc, ok := conn.(*proxyproto.Conn); ok {
+		// reject connection before any XML is parsed
+		if err := pc.ReadHeader(); err != nil {
+			level.Warn(l.logger).Log("msg", "failed to read S2S PROXY protocol header", "err", err)
+			_ = conn.Close()
+			return
+		}
+	}
+	var tr transport.Transport
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// direct TLS: handshake completes before any XML is read
+		var err error
+		tr, err = transport.NewTLSSocketTransport(tlsConn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout, l.cfg.ReadBufferSize, 0, 0)
+		if err != nil {
+			reason := transport.TLSHandshakeFailureReason(err)
+			reportTLSHandshakeFailure(reason)
+			level.Warn(l.logger).Log("msg", "failed to complete S2S TLS handshake", "reason", reason, "err", err)
+			_ = conn.Close()
+			return
+		}
+	} else {
+		tr = transport.NewSocketTransport(conn, l.cfg.ConnectTimeout, l.cfg.KeepAliveTimeout, l.cfg.ReadBufferSize, 0, 0)
+	}
 	stm, err := newInS2S(
 		tr,
 		l.hosts,
@@ -181,12 +218,15 @@ func (l *SocketListener) handleConn(conn net.Conn) {
 		l.kv,
 		l.shapers,
 		l.hk,
-		l.logger,
+		kitlog.With(l.logger, "remote_address", conn.RemoteAddr().String()),
 		inConfig{
-			reqTimeout:    l.cfg.RequestTimeout,
-			maxStanzaSize: l.cfg.MaxStanzaSize,
-			directTLS:     l.cfg.DirectTLS,
-			tlsConfig:     l.getTLSConfig(),
+			reqTimeout:     l.cfg.RequestTimeout,
+			maxStanzaSize:  l.cfg.MaxStanzaSize,
+			maxStanzaDepth: l.cfg.MaxStanzaDepth,
+			maxAsmBufSize:  l.cfg.MaxAssemblyBufferSize,
+			directTLS:      l.cfg.DirectTLS,
+			tlsConfig:      l.getTLSConfig(),
+			draining:       atomic.LoadUint32(&l.draining) == 1,
 		},
 	)
 	if err != nil {
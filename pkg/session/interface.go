@@ -33,4 +33,6 @@ type sessionTransport interface {
 //go:generate moq -out xmppparser.mock_test.go . xmppParser
 type xmppParser interface {
 	Parse() (stravaganza.Element, error)
+	LastElementSize() int
+	SetWhitespaceKeepAliveHandler(hnd func())
 }
@@ -0,0 +1,95 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"sync/atomic"
+
+	"github.com/jackal-xmpp/stravaganza"
+)
+
+// redactedPlaceholder replaces the content of redacted elements.
+const redactedPlaceholder = "[REDACTED]"
+
+var logRedactor atomic.Value
+
+// SetLogRedactor sets the redactor applied to stanzas before being logged.
+func SetLogRedactor(r *Redactor) {
+	logRedactor.Store(r)
+}
+
+// Redactor replaces the content of sensitive elements, such as message bodies,
+// end-to-end encrypted payloads or credentials, with a placeholder.
+type Redactor struct {
+	names      map[string]struct{}
+	namespaces map[string]struct{}
+}
+
+// NewRedactor returns a redactor matching elements either by name or by namespace.
+func NewRedactor(names, namespaces []string) *Redactor {
+	r := &Redactor{
+		names:      make(map[string]struct{}, len(names)),
+		namespaces: make(map[string]struct{}, len(namespaces)),
+	}
+	for _, name := range names {
+		r.names[name] = struct{}{}
+	}
+	for _, ns := range namespaces {
+		r.namespaces[ns] = struct{}{}
+	}
+	return r
+}
+
+// Redact returns a copy of elem in which the content of every matching element has been replaced.
+// Element names and attributes are preserved, so that routing information remains available.
+func (r *Redactor) Redact(elem stravaganza.Element) stravaganza.Element {
+	if r == nil || (len(r.names) == 0 && len(r.namespaces) == 0) {
+		return elem
+	}
+	redacted, _ := r.redact(elem)
+	return redacted
+}
+
+func (r *Redactor) redact(elem stravaganza.Element) (stravaganza.Element, bool) {
+	b := stravaganza.NewBuilder(elem.Name()).
+		WithAttributes(elem.AllAttributes()...)
+
+	if r.matches(elem) {
+		return b.WithText(redactedPlaceholder).Build(), true
+	}
+	var changed bool
+	for _, child := range elem.AllChildren() {
+		rChild, ok := r.redact(child)
+		b.WithChild(rChild)
+		changed = changed || ok
+	}
+	if !changed {
+		return elem, false
+	}
+	return b.WithText(elem.Text()).Build(), true
+}
+
+func (r *Redactor) matches(elem stravaganza.Element) bool {
+	if _, ok := r.names[elem.Name()]; ok {
+		return true
+	}
+	_, ok := r.namespaces[elem.Attribute(stravaganza.Namespace)]
+	return ok
+}
+
+func redactForLog(elem stravaganza.Element) stravaganza.Element {
+	r, _ := logRedactor.Load().(*Redactor)
+	return r.Redact(elem)
+}
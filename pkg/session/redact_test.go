@@ -0,0 +1,85 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package session
+
+import (
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	// given
+	elem := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.ID, "msg-1").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("I'll give thee a wind.").
+				Build(),
+		).
+		WithChild(
+			stravaganza.NewBuilder("encrypted").
+				WithAttribute(stravaganza.Namespace, "eu.siacs.conversations.axolotl").
+				WithChild(stravaganza.NewBuilder("payload").WithText("c2VjcmV0").Build()).
+				Build(),
+		).
+		WithChild(
+			stravaganza.NewBuilder("origin-id").
+				WithAttribute(stravaganza.Namespace, "urn:xmpp:sid:0").
+				WithAttribute(stravaganza.ID, "o-1").
+				Build(),
+		).
+		Build()
+
+	r := NewRedactor([]string{"body"}, []string{"eu.siacs.conversations.axolotl"})
+
+	// when
+	redacted := r.Redact(elem)
+
+	// then
+	require.Equal(t, "ortuman@jackal.im/yard", redacted.Attribute(stravaganza.From))
+	require.Equal(t, "noelia@jackal.im/balcony", redacted.Attribute(stravaganza.To))
+	require.Equal(t, "msg-1", redacted.Attribute(stravaganza.ID))
+
+	require.Equal(t, redactedPlaceholder, redacted.Child("body").Text())
+
+	enc := redacted.ChildNamespace("encrypted", "eu.siacs.conversations.axolotl")
+	require.NotNil(t, enc)
+	require.Equal(t, redactedPlaceholder, enc.Text())
+	require.Nil(t, enc.Child("payload"))
+
+	require.Equal(t, "o-1", redacted.ChildNamespace("origin-id", "urn:xmpp:sid:0").Attribute(stravaganza.ID))
+
+	// original element remains untouched
+	require.Equal(t, "I'll give thee a wind.", elem.Child("body").Text())
+}
+
+func TestRedactor_NoMatch(t *testing.T) {
+	// given
+	elem := stravaganza.NewBuilder("presence").
+		WithChild(stravaganza.NewBuilder("status").WithText("away").Build()).
+		Build()
+
+	r := NewRedactor([]string{"body"}, nil)
+
+	// when
+	redacted := r.Redact(elem)
+
+	// then
+	require.True(t, redacted == elem)
+}
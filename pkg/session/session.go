@@ -51,6 +51,8 @@ const (
 	jabberServerNamespace    = "jabber:server"
 	jabberComponentNamespace = "jabber:component:accept"
 	streamNamespace          = "http://etherx.jabber.org/streams"
+	framingNamespace         = "urn:ietf:params:xml:ns:xmpp-framing"
+	httpBindNamespace        = "http://jabber.org/protocol/httpbind"
 	dialbackNamespace        = "jabber:server:dialback"
 )
 
@@ -81,8 +83,20 @@ type Config struct {
 	// MaxStanzaSize defines the maximum stanza size that can be read from the session transport.
 	MaxStanzaSize int
 
+	// MaxStanzaDepth defines the maximum element nesting depth of a stanza read from the session transport.
+	// If not set, xmppparser.DefaultMaxDepth will be used.
+	MaxStanzaDepth int
+
+	// MaxAssemblyBufferSize defines the maximum number of unparsed bytes buffered while assembling an incoming stanza.
+	// If not set, assembly buffer size will only be bounded by MaxStanzaSize.
+	MaxAssemblyBufferSize int
+
 	// IsOut defines whether or not this is an initiating entity session.
 	IsOut bool
+
+	// OverwriteSpoofedFrom defines whether a C2S stanza 'from' address not matching the session JID
+	// should be overwritten with the authenticated JID instead of failing with an invalid-from stream error.
+	OverwriteSpoofedFrom bool
 }
 
 // Session represents an XMPP session between two peers.
@@ -99,6 +113,7 @@ type Session struct {
 	jd       jid.JID
 	opened   bool
 	started  bool
+	wsHnd    func()
 }
 
 // New creates a new session instance.
@@ -109,7 +124,7 @@ func New(typ Type, identifier string, tr transport.Transport, hosts *host.Hosts,
 		cfg:    cfg,
 		hosts:  hosts,
 		tr:     tr,
-		pr:     getParser(tr, cfg.MaxStanzaSize),
+		pr:     getParser(tr, cfg),
 		logger: logger,
 	}
 	if !ss.cfg.IsOut {
@@ -151,6 +166,16 @@ func (ss *Session) OpenStream(ctx context.Context) error {
 		}
 		buf.WriteString(`<?xml version='1.0'?>`)
 
+	case transport.WebSocket:
+		b = stravaganza.NewBuilder("open")
+		b.WithAttribute(stravaganza.Namespace, framingNamespace)
+		b.WithAttribute(stravaganza.Version, "1.0")
+
+	case transport.BOSH:
+		// stream attributes are conveyed by the BOSH session creation response body
+		ss.opened = true
+		return nil
+
 	default:
 		return errUnsupportedTransport
 	}
@@ -164,7 +189,7 @@ func (ss *Session) OpenStream(ctx context.Context) error {
 	}
 
 	elem := b.Build()
-	if err := elem.ToXML(buf, false); err != nil {
+	if err := elem.ToXML(buf, ss.tr.Type() == transport.WebSocket); err != nil {
 		return err
 	}
 	if err := ss.sendString(ctx, buf.String()); err != nil {
@@ -214,9 +239,13 @@ func (ss *Session) Close(ctx context.Context) error {
 	switch ss.tr.Type() {
 	case transport.Socket:
 		outStr = "</stream:stream>"
+	case transport.WebSocket:
+		outStr = "<close xmlns='" + framingNamespace + "'/>"
 	}
-	if err := ss.sendString(ctx, outStr); err != nil {
-		return err
+	if len(outStr) > 0 {
+		if err := ss.sendString(ctx, outStr); err != nil {
+			return err
+		}
 	}
 	ss.opened = false
 	ss.started = false
@@ -226,9 +255,12 @@ func (ss *Session) Close(ctx context.Context) error {
 // Send writes an XML element to the underlying session transport.
 func (ss *Session) Send(ctx context.Context, elem stravaganza.Element) error {
 	if logStanzas {
-		level.Debug(ss.logger).Log("msg", fmt.Sprintf("SND: %v", elem))
+		level.Debug(ss.logger).Log("msg", fmt.Sprintf("SND: %v", redactForLog(elem)))
 	}
 	ss.setWriteDeadline(ctx)
+	if typ := ss.tr.Type(); typ == transport.WebSocket || typ == transport.BOSH {
+		elem = ss.qualifyElement(elem)
+	}
 	if err := elem.ToXML(ss.tr, true); err != nil {
 		return err
 	}
@@ -244,11 +276,14 @@ func (ss *Session) Receive() (stravaganza.Element, error) {
 	switch {
 	case elem != nil:
 		if logStanzas {
-			level.Debug(ss.logger).Log("msg", fmt.Sprintf("RCV: %v", elem))
+			level.Debug(ss.logger).Log("msg", fmt.Sprintf("RCV: %v", redactForLog(elem)))
 		}
 		if elem.Name() == "stream:error" {
 			return nil, nil // ignore stream error incoming element
 		}
+		if ss.isClosingElement(elem) {
+			return nil, xmppparser.ErrStreamClosedByPeer
+		}
 	default:
 		return nil, nil
 	}
@@ -268,13 +303,26 @@ func (ss *Session) Receive() (stravaganza.Element, error) {
 	return ss.buildStanza(elem)
 }
 
+// LastElementSize returns the size in bytes of the last received element, as read from the wire.
+func (ss *Session) LastElementSize() int {
+	return ss.pr.LastElementSize()
+}
+
+// SetWhitespaceKeepAliveHandler establishes the handler to be invoked whenever
+// a whitespace keepalive is received.
+func (ss *Session) SetWhitespaceKeepAliveHandler(hnd func()) {
+	ss.wsHnd = hnd
+	ss.pr.SetWhitespaceKeepAliveHandler(hnd)
+}
+
 // Reset resets session internal state.
 func (ss *Session) Reset(tr transport.Transport) error {
 	if !ss.cfg.IsOut {
 		ss.streamID = uuid.New().String()
 	}
 	ss.tr = tr
-	ss.pr = getParser(tr, ss.cfg.MaxStanzaSize)
+	ss.pr = getParser(tr, ss.cfg)
+	ss.pr.SetWhitespaceKeepAliveHandler(ss.wsHnd)
 	ss.opened = false
 	ss.started = false
 	return nil
@@ -303,6 +351,22 @@ func (ss *Session) validateStreamElement(elem stravaganza.Element) error {
 		if ns != ss.namespace() || streamNs != streamNamespace {
 			return streamerror.E(streamerror.InvalidNamespace)
 		}
+
+	case transport.WebSocket:
+		if elem.Name() != "open" {
+			return streamerror.E(streamerror.UnsupportedStanzaType)
+		}
+		if elem.Attribute(stravaganza.Namespace) != framingNamespace {
+			return streamerror.E(streamerror.InvalidNamespace)
+		}
+
+	case transport.BOSH:
+		if elem.Name() != "body" {
+			return streamerror.E(streamerror.UnsupportedStanzaType)
+		}
+		if elem.Attribute(stravaganza.Namespace) != httpBindNamespace {
+			return streamerror.E(streamerror.InvalidNamespace)
+		}
 	}
 	switch ss.typ {
 	case ComponentSession:
@@ -367,6 +431,41 @@ func (ss *Session) validateNamespace(elem stravaganza.Element) error {
 	return streamerror.E(streamerror.InvalidNamespace)
 }
 
+func (ss *Session) isClosingElement(elem stravaganza.Element) bool {
+	switch {
+	case elem.Name() == "close" && elem.Attribute(stravaganza.Namespace) == framingNamespace:
+		return ss.tr.Type() == transport.WebSocket
+
+	case elem.Name() == "body" && elem.Attribute(stravaganza.Namespace) == httpBindNamespace:
+		return elem.Attribute(stravaganza.Type) == "terminate" && ss.tr.Type() == transport.BOSH
+	}
+	return false
+}
+
+// qualifyElement declares the namespaces a top level element inherits from the stream header in socket transports,
+// since every WebSocket message must be a standalone XML document (RFC 7395, section 3.3.3)
+// and BOSH bodies are qualified by the httpbind namespace (XEP-0206, section 8).
+func (ss *Session) qualifyElement(elem stravaganza.Element) stravaganza.Element {
+	switch {
+	case strings.HasPrefix(elem.Name(), "stream:"):
+		if len(elem.Attribute(stravaganza.StreamNamespace)) > 0 {
+			return elem
+		}
+		return stravaganza.NewBuilderFromElement(elem).
+			WithAttribute(stravaganza.StreamNamespace, streamNamespace).
+			Build()
+
+	case stravaganza.IsStanza(elem):
+		if len(elem.Attribute(stravaganza.Namespace)) > 0 {
+			return elem
+		}
+		return stravaganza.NewBuilderFromElement(elem).
+			WithAttribute(stravaganza.Namespace, ss.namespace()).
+			Build()
+	}
+	return elem
+}
+
 func (ss *Session) setWriteDeadline(ctx context.Context) {
 	d, ok := ctx.Deadline()
 	if !ok {
@@ -392,12 +491,13 @@ func (ss *Session) extractAddresses(elem stravaganza.Element) (fromJID *jid.JID,
 	switch ss.typ {
 	case C2SSession:
 		// do not validate 'from' address until full user JID has been set
-		if ss.jd.IsFullWithUser() {
-			if len(from) > 0 && !ss.isValidFrom(from) {
+		if ss.jd.IsFullWithUser() && len(from) > 0 && !ss.isValidFrom(from) {
+			if !ss.cfg.OverwriteSpoofedFrom {
 				return nil, nil, streamerror.E(streamerror.InvalidFrom)
 			}
+			level.Warn(ss.logger).Log("msg", "overwriting spoofed 'from' address", "from", from, "jid", ss.jd.String())
 		}
-		fromJID = &ss.jd
+		fromJID = &ss.jd // always stamp authenticated JID
 
 	default:
 		j, err := jid.NewWithString(from, false)
@@ -441,13 +541,18 @@ func (ss *Session) isValidFrom(from string) bool {
 	return validFrom
 }
 
-func getParser(tr transport.Transport, maxStanzaSize int) *xmppparser.Parser {
+func getParser(tr transport.Transport, cfg Config) *xmppparser.Parser {
 	var pm xmppparser.ParsingMode
 	switch tr.Type() {
 	case transport.Socket:
 		pm = xmppparser.SocketStream
+	case transport.WebSocket, transport.BOSH:
+		pm = xmppparser.DefaultMode // every message carries complete elements
 	}
-	return xmppparser.New(tr, pm, maxStanzaSize)
+	pr := xmppparser.New(tr, pm, cfg.MaxStanzaSize)
+	pr.SetMaxDepth(cfg.MaxStanzaDepth)
+	pr.SetMaxAssemblyBufferSize(cfg.MaxAssemblyBufferSize)
+	return pr
 }
 
 func mapErrorToSessionError(err error) error {
@@ -468,6 +573,11 @@ func mapErrorToSessionError(err error) error {
 			Build()
 		return se
 
+	case xmppparser.ErrTooDeepStanza, xmppparser.ErrAssemblyBufferExceeded:
+		se := streamerror.E(streamerror.PolicyViolation)
+		se.Err = err
+		return se
+
 	default:
 		switch err := err.(type) {
 		case *xml.SyntaxError:
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"testing"
 
+	kitlog "github.com/go-kit/log"
 	"github.com/jackal-xmpp/stravaganza"
 	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
 	streamerror "github.com/jackal-xmpp/stravaganza/errors/stream"
@@ -194,6 +195,61 @@ func TestSession_Send(t *testing.T) {
 	require.Equal(t, expectedOutput, buf.String())
 }
 
+func TestSession_SendRedactedLog(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.Socket }
+	trMock.FlushFunc = func() error { return nil }
+	trMock.WriteStringFunc = func(s string) (int, error) { return len(s), nil }
+	trMock.WriteFunc = func(p []byte) (int, error) { return len(p), nil }
+
+	logBuf := bytes.NewBuffer(nil)
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  &hostsMock{},
+		pr:     &xmppParserMock{},
+		jd:     *ssJID,
+		opened: true,
+		logger: kitlog.NewLogfmtLogger(logBuf),
+	}
+
+	logStanzas = true
+	SetLogRedactor(NewRedactor([]string{"body"}, nil))
+	defer func() {
+		logStanzas = false
+		SetLogRedactor(nil)
+	}()
+
+	msg := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithAttribute(stravaganza.ID, "msg-1").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("a secret message").
+				Build(),
+		).
+		Build()
+
+	// when
+	err := ss.Send(context.Background(), msg)
+
+	// then
+	require.Nil(t, err)
+
+	logOutput := logBuf.String()
+	require.NotContains(t, logOutput, "a secret message")
+	require.Contains(t, logOutput, redactedPlaceholder)
+	require.Contains(t, logOutput, "ortuman@jackal.im/yard")
+	require.Contains(t, logOutput, "noelia@jackal.im/balcony")
+	require.Contains(t, logOutput, "msg-1")
+}
+
 func TestSession_ReceiveStreamSuccess(t *testing.T) {
 	// given
 	hMock := &hostsMock{}
@@ -314,6 +370,90 @@ func TestSession_ReceiveSuccess(t *testing.T) {
 	require.Equal(t, "message", elem.Name())
 }
 
+func TestSession_ReceiveC2SAddresses(t *testing.T) {
+	var tcs = map[string]struct {
+		from          string
+		to            string
+		overwriteFrom bool
+		expFrom       string
+		expTo         string
+		expErr        streamerror.Reason
+	}{
+		"MissingFrom": {
+			to:      "noelia@jackal.im/balcony",
+			expFrom: "ortuman@jackal.im/yard",
+			expTo:   "noelia@jackal.im/balcony",
+		},
+		"BareFrom": {
+			from:    "ortuman@jackal.im",
+			to:      "noelia@jackal.im/balcony",
+			expFrom: "ortuman@jackal.im/yard",
+			expTo:   "noelia@jackal.im/balcony",
+		},
+		"MissingTo": {
+			from:    "ortuman@jackal.im/yard",
+			expFrom: "ortuman@jackal.im/yard",
+			expTo:   "ortuman@jackal.im",
+		},
+		"SpoofedFromRejected": {
+			from:   "noelia@jackal.im/balcony",
+			to:     "romeo@jackal.im",
+			expErr: streamerror.InvalidFrom,
+		},
+		"SpoofedFromOverwritten": {
+			from:          "noelia@jackal.im/balcony",
+			to:            "romeo@jackal.im",
+			overwriteFrom: true,
+			expFrom:       "ortuman@jackal.im/yard",
+			expTo:         "romeo@jackal.im",
+		},
+	}
+	for tName, tCase := range tcs {
+		t.Run(tName, func(t *testing.T) {
+			// given
+			prMock := &xmppParserMock{}
+
+			ssJID, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+			ss := Session{
+				typ:     C2SSession,
+				id:      "ss-1",
+				cfg:     Config{MaxStanzaSize: 4096, OverwriteSpoofedFrom: tCase.overwriteFrom},
+				tr:      &transportMock{},
+				hosts:   &hostsMock{},
+				pr:      prMock,
+				jd:      *ssJID,
+				opened:  true,
+				started: true,
+				logger:  kitlog.NewNopLogger(),
+			}
+			prMock.ParseFunc = func() (stravaganza.Element, error) {
+				b := stravaganza.NewBuilder("message")
+				if len(tCase.from) > 0 {
+					b.WithAttribute(stravaganza.From, tCase.from)
+				}
+				if len(tCase.to) > 0 {
+					b.WithAttribute(stravaganza.To, tCase.to)
+				}
+				return b.Build(), nil
+			}
+
+			// when
+			elem, err := ss.Receive()
+
+			// then
+			if tCase.expErr > 0 {
+				se, ok := err.(*streamerror.Error)
+				require.True(t, ok)
+				require.Equal(t, tCase.expErr, se.Reason)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tCase.expFrom, elem.Attribute(stravaganza.From))
+			require.Equal(t, tCase.expTo, elem.Attribute(stravaganza.To))
+		})
+	}
+}
+
 func TestSession_ReceiveStreamError(t *testing.T) {
 	// given
 	prMock := &xmppParserMock{}
@@ -345,24 +485,38 @@ func TestSession_ReceiveStreamError(t *testing.T) {
 	prMock.ParseFunc = func() (stravaganza.Element, error) { return nil, &xml.SyntaxError{} }
 	_, err3 := ss.Receive()
 
+	prMock.ParseFunc = func() (stravaganza.Element, error) { return nil, xmppparser.ErrTooDeepStanza }
+	_, err4 := ss.Receive()
+
+	prMock.ParseFunc = func() (stravaganza.Element, error) { return nil, xmppparser.ErrAssemblyBufferExceeded }
+	_, err5 := ss.Receive()
+
 	// then
 	require.NotNil(t, err0)
 	require.NotNil(t, err1)
 	require.NotNil(t, err2)
 	require.NotNil(t, err3)
+	require.NotNil(t, err4)
+	require.NotNil(t, err5)
 
 	require.Equal(t, errFoo, err0)
 
 	se1, ok1 := err1.(*streamerror.Error)
 	se2, ok2 := err2.(*streamerror.Error)
 	se3, ok3 := err3.(*streamerror.Error)
+	se4, ok4 := err4.(*streamerror.Error)
+	se5, ok5 := err5.(*streamerror.Error)
 	require.True(t, ok1)
 	require.True(t, ok2)
 	require.True(t, ok3)
+	require.True(t, ok4)
+	require.True(t, ok5)
 
 	require.Equal(t, streamerror.PolicyViolation, se1.Reason)
 	require.Equal(t, streamerror.PolicyViolation, se2.Reason)
 	require.Equal(t, streamerror.InvalidXML, se3.Reason)
+	require.Equal(t, streamerror.PolicyViolation, se4.Reason)
+	require.Equal(t, streamerror.PolicyViolation, se5.Reason)
 }
 
 func TestSession_ReceiveUnsupportedStanza(t *testing.T) {
@@ -396,3 +550,201 @@ func TestSession_ReceiveUnsupportedStanza(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, stanzaerror.BadRequest, se.Reason)
 }
+
+func TestSession_OpenWebSocketStream(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+	trMock.FlushFunc = func() error { return nil }
+
+	buf := bytes.NewBuffer(nil)
+	trMock.WriteStringFunc = func(s string) (int, error) {
+		return buf.WriteString(s)
+	}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:      C2SSession,
+		id:       "ss-1",
+		streamID: "stm-1",
+		cfg:      Config{MaxStanzaSize: 4096},
+		tr:       trMock,
+		hosts:    &hostsMock{},
+		pr:       &xmppParserMock{},
+		jd:       *ssJID,
+	}
+
+	// when
+	err := ss.OpenStream(context.Background())
+	_ = ss.Close(context.Background())
+
+	// then
+	require.Nil(t, err)
+
+	expectedOutput := `<open xmlns='urn:ietf:params:xml:ns:xmpp-framing' version='1.0' from='jackal.im' id='stm-1'/>` +
+		`<close xmlns='urn:ietf:params:xml:ns:xmpp-framing'/>`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestSession_SendWebSocket(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+	trMock.FlushFunc = func() error { return nil }
+
+	buf := bytes.NewBuffer(nil)
+	trMock.WriteStringFunc = func(s string) (int, error) {
+		return buf.WriteString(s)
+	}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  &hostsMock{},
+		pr:     &xmppParserMock{},
+		jd:     *ssJID,
+		opened: true,
+	}
+
+	// when
+	err1 := ss.Send(context.Background(), stravaganza.NewBuilder("stream:features").Build())
+	err2 := ss.Send(context.Background(), stravaganza.NewBuilder("presence").Build())
+	err3 := ss.Send(context.Background(), stravaganza.NewBuilder("r").WithAttribute(stravaganza.Namespace, "urn:xmpp:sm:3").Build())
+
+	// then
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+	require.Nil(t, err3)
+
+	expectedOutput := `<stream:features xmlns:stream='http://etherx.jabber.org/streams'/>` +
+		`<presence xmlns='jabber:client'/>` +
+		`<r xmlns='urn:xmpp:sm:3'/>`
+	require.Equal(t, expectedOutput, buf.String())
+}
+
+func TestSession_ReceiveWebSocket(t *testing.T) {
+	// given
+	hMock := &hostsMock{}
+	trMock := &transportMock{}
+	prMock := &xmppParserMock{}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  hMock,
+		pr:     prMock,
+		jd:     *ssJID,
+		opened: true,
+	}
+	hMock.IsLocalHostFunc = func(domain string) bool { return domain == "jackal.im" }
+	trMock.TypeFunc = func() transport.Type { return transport.WebSocket }
+
+	elems := []stravaganza.Element{
+		stravaganza.NewBuilder("open").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Version, "1.0").
+			Build(),
+		stravaganza.NewBuilder("close").
+			WithAttribute(stravaganza.Namespace, framingNamespace).
+			Build(),
+	}
+	prMock.ParseFunc = func() (stravaganza.Element, error) {
+		elem := elems[0]
+		elems = elems[1:]
+		return elem, nil
+	}
+
+	// when
+	openElem, err1 := ss.Receive()
+	_, err2 := ss.Receive()
+
+	// then
+	require.Nil(t, err1)
+	require.NotNil(t, openElem)
+	require.Equal(t, "open", openElem.Name())
+
+	require.Equal(t, xmppparser.ErrStreamClosedByPeer, err2)
+}
+
+func TestSession_OpenBOSHStream(t *testing.T) {
+	// given
+	trMock := &transportMock{}
+	trMock.TypeFunc = func() transport.Type { return transport.BOSH }
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:   C2SSession,
+		id:    "ss-1",
+		cfg:   Config{MaxStanzaSize: 4096},
+		tr:    trMock,
+		hosts: &hostsMock{},
+		pr:    &xmppParserMock{},
+		jd:    *ssJID,
+	}
+
+	// when
+	openErr := ss.OpenStream(context.Background())
+	closeErr := ss.Close(context.Background())
+
+	// then
+	require.Nil(t, openErr)
+	require.Nil(t, closeErr)
+
+	require.Len(t, trMock.WriteStringCalls(), 0) // stream attributes are conveyed by the BOSH session
+}
+
+func TestSession_ReceiveBOSH(t *testing.T) {
+	// given
+	hMock := &hostsMock{}
+	trMock := &transportMock{}
+	prMock := &xmppParserMock{}
+
+	ssJID, _ := jid.NewWithString("jackal.im", true)
+	ss := Session{
+		typ:    C2SSession,
+		id:     "ss-1",
+		cfg:    Config{MaxStanzaSize: 4096},
+		tr:     trMock,
+		hosts:  hMock,
+		pr:     prMock,
+		jd:     *ssJID,
+		opened: true,
+	}
+	hMock.IsLocalHostFunc = func(domain string) bool { return domain == "jackal.im" }
+	trMock.TypeFunc = func() transport.Type { return transport.BOSH }
+
+	elems := []stravaganza.Element{
+		stravaganza.NewBuilder("body").
+			WithAttribute(stravaganza.Namespace, httpBindNamespace).
+			WithAttribute(stravaganza.To, "jackal.im").
+			WithAttribute(stravaganza.Version, "1.0").
+			Build(),
+		stravaganza.NewBuilder("body").
+			WithAttribute(stravaganza.Namespace, httpBindNamespace).
+			WithAttribute(stravaganza.Type, "terminate").
+			Build(),
+	}
+	prMock.ParseFunc = func() (stravaganza.Element, error) {
+		elem := elems[0]
+		elems = elems[1:]
+		return elem, nil
+	}
+
+	// when
+	openElem, err1 := ss.Receive()
+	_, err2 := ss.Receive()
+
+	// then
+	require.Nil(t, err1)
+	require.NotNil(t, openElem)
+	require.Equal(t, "body", openElem.Name())
+
+	require.Equal(t, xmppparser.ErrStreamClosedByPeer, err2)
+}
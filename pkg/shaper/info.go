@@ -0,0 +1,50 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"github.com/ortuman/jackal/pkg/geoip"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+)
+
+const (
+	countryInfoKey = "geo:country"
+	asnInfoKey     = "geo:asn"
+	tierInfoKey    = "shaper:tier"
+)
+
+// SetOriginInfo stores a C2S stream connection origin into its info map.
+func SetOriginInfo(inf *c2smodel.InfoMap, origin geoip.Origin) {
+	inf.SetString(countryInfoKey, origin.Country)
+	inf.SetInt(asnInfoKey, int(origin.ASN))
+}
+
+// OriginFromInfo returns a C2S stream connection origin, given its info.
+func OriginFromInfo(inf c2smodel.Info) geoip.Origin {
+	return geoip.Origin{
+		Country: inf.String(countryInfoKey),
+		ASN:     uint32(inf.Int(asnInfoKey)),
+	}
+}
+
+// SetTierInfo stores a C2S stream account tier into its info map.
+func SetTierInfo(inf *c2smodel.InfoMap, tier string) {
+	inf.SetString(tierInfoKey, tier)
+}
+
+// TierFromInfo returns a C2S stream account tier, given its info.
+func TierFromInfo(inf c2smodel.Info) string {
+	return inf.String(tierInfoKey)
+}
@@ -0,0 +1,39 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"testing"
+
+	"github.com/ortuman/jackal/pkg/geoip"
+	c2smodel "github.com/ortuman/jackal/pkg/model/c2s"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfo_OriginAndTier(t *testing.T) {
+	// given
+	inf := c2smodel.NewInfoMap()
+
+	// when
+	SetOriginInfo(inf, geoip.Origin{Country: "ES", ASN: 64496})
+	SetTierInfo(inf, "premium")
+
+	// then
+	require.Equal(t, geoip.Origin{Country: "ES", ASN: 64496}, OriginFromInfo(inf))
+	require.Equal(t, "premium", TierFromInfo(inf))
+
+	require.True(t, OriginFromInfo(c2smodel.NewInfoMap()).IsZero())
+	require.Empty(t, TierFromInfo(c2smodel.NewInfoMap()))
+}
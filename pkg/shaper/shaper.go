@@ -15,11 +15,22 @@
 package shaper
 
 import (
+	"math"
+	"sort"
+	"strings"
+
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/util/stringmatcher"
 	"golang.org/x/time/rate"
 )
 
+const defaultBytesPerToken = 1024
+
+// TierFlagPrefix is the prefix of the account feature flag that assigns an account to a shaper tier.
+// For instance, an account with 'tier_premium' flag enabled belongs to 'premium' tier.
+const TierFlagPrefix = "tier_"
+
 var defaultC2SShaper = Shaper{
 	MaxSessions: 3,
 	rateLimit:   131072,
@@ -36,8 +47,22 @@ type Shapers []Shaper
 
 // MatchingJID returns the shaper that should be applied to a given JID.
 func (ss Shapers) MatchingJID(j *jid.JID) *Shaper {
+	return ss.MatchingJIDAndOrigin(j, geoip.Origin{})
+}
+
+// MatchingJIDAndOrigin returns the shaper that should be applied to a given JID connected from origin.
+func (ss Shapers) MatchingJIDAndOrigin(j *jid.JID, origin geoip.Origin) *Shaper {
+	return ss.MatchingJIDOriginAndTier(j, origin, "")
+}
+
+// MatchingJIDOriginAndTier returns the shaper that should be applied to a given JID connected from origin,
+// whose account belongs to tier. Empty tier value only matches shapers not restricted to any tier.
+func (ss Shapers) MatchingJIDOriginAndTier(j *jid.JID, origin geoip.Origin, tier string) *Shaper {
 	for _, s := range ss {
-		if s.jidMatcher.Matches(j.String()) {
+		if len(s.tier) > 0 && s.tier != tier {
+			continue
+		}
+		if s.jidMatcher.Matches(j.String()) && s.matchesOrigin(origin) {
 			return &s
 		}
 	}
@@ -47,6 +72,51 @@ func (ss Shapers) MatchingJID(j *jid.JID) *Shaper {
 	return &defaultC2SShaper
 }
 
+// Bound returns a shaper collection in which the shaper identified by name applies to every JID.
+// In case no shaper with such name is found the original collection is returned.
+func (ss Shapers) Bound(name string) Shapers {
+	if len(name) == 0 {
+		return ss
+	}
+	for _, s := range ss {
+		if s.Name != name {
+			continue
+		}
+		s.jidMatcher = stringmatcher.Any
+		s.countries, s.asns = nil, nil
+		s.tier = ""
+		return Shapers{s}
+	}
+	return ss
+}
+
+// Tiered tells whether any shaper of the collection is restricted to an account tier.
+func (ss Shapers) Tiered() bool {
+	for _, s := range ss {
+		if len(s.tier) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AccountTier returns the tier an account belongs to given its feature flags.
+// In case more than one tier flag is enabled the first one in lexicographical order is chosen.
+func AccountTier(flags map[string]bool) string {
+	var tiers []string
+	for flag, enabled := range flags {
+		if !enabled || !strings.HasPrefix(flag, TierFlagPrefix) {
+			continue
+		}
+		tiers = append(tiers, strings.TrimPrefix(flag, TierFlagPrefix))
+	}
+	if len(tiers) == 0 {
+		return ""
+	}
+	sort.Strings(tiers)
+	return tiers[0]
+}
+
 // DefaultC2S returns C2S default shaper.
 func (ss Shapers) DefaultC2S() *Shaper {
 	return &defaultC2SShaper
@@ -57,6 +127,12 @@ func (ss Shapers) DefaultS2S() *Shaper {
 	return &defaultS2SShaper
 }
 
+// RateLimitable represents a connection whose traffic can be rate limited.
+type RateLimitable interface {
+	SetReadRateLimiter(rLim *rate.Limiter) error
+	SetWriteRateLimiter(wLim *rate.Limiter) error
+}
+
 // Shaper represents a connection traffic constraint set.
 type Shaper struct {
 	// Name is the shaper name.
@@ -65,23 +141,65 @@ type Shaper struct {
 	// MaxSessions represents maximum sessions count.
 	MaxSessions int
 
-	rateLimit, burst int
-	jidMatcher       stringmatcher.Matcher
+	// MaxQueueSize represents the maximum number of unacknowledged stanzas held for a stream management
+	// enabled session. Zero value means the stream management module value applies.
+	MaxQueueSize int
+
+	rateLimit, burst           int
+	writeRateLimit, writeBurst int
+	stanzaCfg                  stanzaCfg
+	jidMatcher                 stringmatcher.Matcher
+	countries                  []string
+	asns                       []int
+	tier                       string
 }
 
 // Config contains Shaper configuration parameters.
 type Config struct {
 	Name        string `fig:"name"`
 	MaxSessions int    `fig:"max_sessions" default:"10"`
-	Rate        struct {
+	// MaxQueueSize, if set, overrides the XEP-0198 module max_queue_size value for matching sessions.
+	MaxQueueSize int `fig:"max_queue_size"`
+	Rate         struct {
 		Limit int `fig:"limit" default:"1000"`
+		// Burst defines the amount of bytes allowed to be read at once exceeding the rate limit.
+		// If not set, it defaults to a second worth of traffic.
 		Burst int `fig:"burst" default:"0"`
 	} `fig:"rate"`
+	// WriteRate contains outgoing traffic rate limit configuration.
+	WriteRate struct {
+		// Limit defines the amount of bytes per second allowed to be written. Zero value disables write shaping.
+		Limit int `fig:"limit"`
+		// Burst defines the amount of bytes allowed to be written at once exceeding the rate limit.
+		// If not set, it defaults to a second worth of traffic.
+		Burst int `fig:"burst"`
+	} `fig:"write_rate"`
+	// Stanza contains stanza cost-based shaping configuration.
+	Stanza struct {
+		// Limit defines the number of tokens per second granted to a connection.
+		// Zero value disables stanza shaping.
+		Limit float64 `fig:"limit"`
+		// Burst defines the maximum number of tokens that can be consumed at once.
+		// If not set, it defaults to a second worth of tokens.
+		Burst int `fig:"burst"`
+		// BytesPerToken defines the stanza size accounted by every token.
+		// Each stanza costs one token plus an additional one for every BytesPerToken bytes.
+		BytesPerToken int `fig:"bytes_per_token" default:"1024"`
+	} `fig:"stanza"`
 	Matching struct {
 		JID struct {
 			In    []string `fig:"in"`
 			RegEx string   `fig:"regex"`
 		}
+		// Origin restricts shaper to connections originated from a set of countries or ASNs.
+		// Requires a GeoIP provider to be configured.
+		Origin struct {
+			Country []string `fig:"country"`
+			ASN     []int    `fig:"asn"`
+		} `fig:"origin"`
+		// Tier restricts shaper to authenticated sessions whose account belongs to a tier,
+		// as assigned through the 'tier_<name>' account feature flag.
+		Tier string `fig:"tier"`
 	} `fig:"matching"`
 }
 
@@ -100,12 +218,35 @@ func New(cfg Config) (Shaper, error) {
 	default:
 		jidMatcher = stringmatcher.Any
 	}
+	burst := cfg.Rate.Burst
+	if burst == 0 {
+		burst = cfg.Rate.Limit
+	}
+	writeBurst := cfg.WriteRate.Burst
+	if writeBurst == 0 {
+		writeBurst = cfg.WriteRate.Limit
+	}
+	stzBurst := cfg.Stanza.Burst
+	if stzBurst == 0 {
+		stzBurst = int(math.Ceil(cfg.Stanza.Limit))
+	}
 	return Shaper{
-		Name:        cfg.Name,
-		MaxSessions: cfg.MaxSessions,
-		rateLimit:   cfg.Rate.Limit,
-		burst:       cfg.Rate.Burst,
-		jidMatcher:  jidMatcher,
+		Name:           cfg.Name,
+		MaxSessions:    cfg.MaxSessions,
+		MaxQueueSize:   cfg.MaxQueueSize,
+		rateLimit:      cfg.Rate.Limit,
+		burst:          burst,
+		writeRateLimit: cfg.WriteRate.Limit,
+		writeBurst:     writeBurst,
+		jidMatcher:     jidMatcher,
+		countries:      cfg.Matching.Origin.Country,
+		asns:           cfg.Matching.Origin.ASN,
+		tier:           cfg.Matching.Tier,
+		stanzaCfg: stanzaCfg{
+			limit:         cfg.Stanza.Limit,
+			burst:         stzBurst,
+			bytesPerToken: cfg.Stanza.BytesPerToken,
+		},
 	}, nil
 }
 
@@ -113,3 +254,63 @@ func New(cfg Config) (Shaper, error) {
 func (s *Shaper) RateLimiter() *rate.Limiter {
 	return rate.NewLimiter(rate.Limit(s.rateLimit), s.burst)
 }
+
+// ApplyRateLimiters sets both read and write shaper rate limiters on c.
+func (s *Shaper) ApplyRateLimiters(c RateLimitable) error {
+	if err := c.SetReadRateLimiter(s.RateLimiter()); err != nil {
+		return err
+	}
+	return c.SetWriteRateLimiter(s.WriteRateLimiter())
+}
+
+// WriteRateLimiter returns a new write rate limiter configured with shaper parameters.
+// Returns nil in case write shaping is not enabled.
+func (s *Shaper) WriteRateLimiter() *rate.Limiter {
+	if s.writeRateLimit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(s.writeRateLimit), s.writeBurst)
+}
+
+// StanzaLimiter returns a new size-aware stanza limiter configured with shaper parameters.
+// Returns nil in case stanza shaping is not enabled.
+func (s *Shaper) StanzaLimiter() *StanzaLimiter {
+	if s.stanzaCfg.limit <= 0 {
+		return nil
+	}
+	return NewStanzaLimiter(s.stanzaCfg.limit, s.stanzaCfg.burst, s.stanzaCfg.bytesPerToken)
+}
+
+func (s *Shaper) matchesOrigin(origin geoip.Origin) bool {
+	if len(s.countries) > 0 {
+		var found bool
+		for _, country := range s.countries {
+			if strings.EqualFold(country, origin.Country) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(s.asns) > 0 {
+		var found bool
+		for _, asn := range s.asns {
+			if uint32(asn) == origin.ASN {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type stanzaCfg struct {
+	limit         float64
+	burst         int
+	bytesPerToken int
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/geoip"
 	"github.com/ortuman/jackal/pkg/util/stringmatcher"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
@@ -41,6 +42,79 @@ func TestShaper_RateLimiter(t *testing.T) {
 	require.Equal(t, 1000, rLim.Burst())
 }
 
+func TestShaper_DefaultBurst(t *testing.T) {
+	// given
+	var cfg Config
+	cfg.Name = "foo"
+	cfg.Rate.Limit = 2000
+
+	// when
+	s, err := New(cfg)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, 2000, s.RateLimiter().Burst())
+}
+
+func TestShaper_WriteRateLimiter(t *testing.T) {
+	// given
+	var cfg Config
+	cfg.Name = "foo"
+	cfg.Rate.Limit = 2000
+	cfg.WriteRate.Limit = 4000
+
+	var cfg2 Config
+	cfg2.Name = "bar"
+	cfg2.Rate.Limit = 2000
+
+	// when
+	s, _ := New(cfg)
+	s2, _ := New(cfg2)
+
+	// then
+	wLim := s.WriteRateLimiter()
+	require.NotNil(t, wLim)
+	require.Equal(t, rate.Limit(4000), wLim.Limit())
+	require.Equal(t, 4000, wLim.Burst())
+
+	require.Nil(t, s2.WriteRateLimiter())
+}
+
+type fakeRateLimitable struct {
+	rLim, wLim *rate.Limiter
+}
+
+func (f *fakeRateLimitable) SetReadRateLimiter(rLim *rate.Limiter) error {
+	f.rLim = rLim
+	return nil
+}
+
+func (f *fakeRateLimitable) SetWriteRateLimiter(wLim *rate.Limiter) error {
+	f.wLim = wLim
+	return nil
+}
+
+func TestShaper_ApplyRateLimiters(t *testing.T) {
+	// given
+	var cfg Config
+	cfg.Name = "foo"
+	cfg.Rate.Limit = 2000
+	cfg.WriteRate.Limit = 4000
+	cfg.WriteRate.Burst = 1000
+
+	s, _ := New(cfg)
+	c := &fakeRateLimitable{}
+
+	// when
+	err := s.ApplyRateLimiters(c)
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, rate.Limit(2000), c.rLim.Limit())
+	require.Equal(t, rate.Limit(4000), c.wLim.Limit())
+	require.Equal(t, 1000, c.wLim.Burst())
+}
+
 func TestShapers_MatchingJID(t *testing.T) {
 	// given
 	var ss Shapers
@@ -63,6 +137,104 @@ func TestShapers_MatchingJID(t *testing.T) {
 	require.Equal(t, 1000, rLim.Burst())
 }
 
+func TestShapers_MatchingJIDAndOrigin(t *testing.T) {
+	// given
+	var cfg0, cfg1 Config
+	cfg0.Name = "restricted"
+	cfg0.Rate.Limit = 1000
+	cfg0.Matching.Origin.Country = []string{"XX"}
+
+	cfg1.Name = "normal"
+	cfg1.Rate.Limit = 4000
+
+	s0, _ := New(cfg0)
+	s1, _ := New(cfg1)
+	ss := Shapers{s0, s1}
+
+	j, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	// when
+	sh0 := ss.MatchingJIDAndOrigin(j, geoip.Origin{Country: "xx", ASN: 64496})
+	sh1 := ss.MatchingJIDAndOrigin(j, geoip.Origin{Country: "ES"})
+	sh2 := ss.MatchingJID(j)
+
+	// then
+	require.Equal(t, "restricted", sh0.Name)
+	require.Equal(t, "normal", sh1.Name)
+	require.Equal(t, "normal", sh2.Name)
+}
+
+func TestShapers_MatchingJIDOriginAndTier(t *testing.T) {
+	// given
+	var cfg0, cfg1 Config
+	cfg0.Name = "premium"
+	cfg0.MaxSessions = 10
+	cfg0.Rate.Limit = 8000
+	cfg0.Matching.Tier = "premium"
+
+	cfg1.Name = "free"
+	cfg1.MaxSessions = 2
+	cfg1.Rate.Limit = 1000
+
+	s0, _ := New(cfg0)
+	s1, _ := New(cfg1)
+	ss := Shapers{s0, s1}
+
+	j, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	// when
+	sh0 := ss.MatchingJIDOriginAndTier(j, geoip.Origin{}, "premium")
+	sh1 := ss.MatchingJIDOriginAndTier(j, geoip.Origin{}, "gold")
+	sh2 := ss.MatchingJIDAndOrigin(j, geoip.Origin{})
+
+	// then
+	require.True(t, ss.Tiered())
+	require.False(t, Shapers{s1}.Tiered())
+
+	require.Equal(t, "premium", sh0.Name)
+	require.Equal(t, "free", sh1.Name)
+	require.Equal(t, "free", sh2.Name)
+}
+
+func TestAccountTier(t *testing.T) {
+	require.Equal(t, "", AccountTier(nil))
+	require.Equal(t, "", AccountTier(map[string]bool{"carbons_default_on": true, "tier_premium": false}))
+	require.Equal(t, "premium", AccountTier(map[string]bool{"carbons_default_on": true, "tier_premium": true}))
+	require.Equal(t, "gold", AccountTier(map[string]bool{"tier_premium": true, "tier_gold": true}))
+}
+
+func TestShapers_Bound(t *testing.T) {
+	// given
+	var ss Shapers
+	ss = append(ss, Shaper{
+		Name:        "foo",
+		MaxSessions: 5,
+		rateLimit:   2000,
+		burst:       1000,
+		jidMatcher:  stringmatcher.Any,
+	})
+	ss = append(ss, Shaper{
+		Name:        "bar",
+		MaxSessions: 10,
+		rateLimit:   4000,
+		burst:       2000,
+		jidMatcher:  stringmatcher.NewStringMatcher([]string{"noelia@jackal.im"}),
+	})
+
+	j, _ := jid.NewWithString("ortuman@jackal.im", true)
+
+	// when
+	bound := ss.Bound("bar")
+	unbound := ss.Bound("baz")
+
+	// then
+	require.Len(t, bound, 1)
+	require.Equal(t, "bar", bound.MatchingJID(j).Name)
+	require.Equal(t, rate.Limit(4000), bound.MatchingJID(j).RateLimiter().Limit())
+
+	require.Equal(t, ss, unbound)
+}
+
 func TestShapers_Default(t *testing.T) {
 	// given
 	ss := new(Shapers)
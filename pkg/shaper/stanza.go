@@ -0,0 +1,82 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// StanzaLimiter throttles incoming stanzas using a size-aware cost function,
+// so that a few huge stanzas are throttled the same way as many small ones.
+type StanzaLimiter struct {
+	lim           *rate.Limiter
+	bytesPerToken int
+	nowFn         func() time.Time
+}
+
+// NewStanzaLimiter returns a new stanza limiter granting limit tokens per second, up to burst tokens at once.
+// Every stanza costs one token plus an additional one for every bytesPerToken bytes of its size.
+func NewStanzaLimiter(limit float64, burst, bytesPerToken int) *StanzaLimiter {
+	if bytesPerToken <= 0 {
+		bytesPerToken = defaultBytesPerToken
+	}
+	return &StanzaLimiter{
+		lim:           rate.NewLimiter(rate.Limit(limit), burst),
+		bytesPerToken: bytesPerToken,
+		nowFn:         time.Now,
+	}
+}
+
+// Cost returns the number of tokens consumed by a stanza of size bytes.
+// Costs exceeding limiter burst are truncated, so that a single huge stanza drains the whole bucket.
+func (l *StanzaLimiter) Cost(size int) int {
+	cost := 1 + size/l.bytesPerToken
+	if burst := l.lim.Burst(); cost > burst {
+		return burst
+	}
+	return cost
+}
+
+// Allow consumes the tokens needed to accept a stanza of size bytes only if they're immediately available.
+func (l *StanzaLimiter) Allow(size int) bool {
+	return l.lim.AllowN(l.nowFn(), l.Cost(size))
+}
+
+// Reserve consumes the tokens needed to accept a stanza of size bytes, returning the time the caller should wait
+// before processing it. In case the required delay exceeds the time needed to refill the limiter burst
+// allowance, no token is consumed and false is returned.
+func (l *StanzaLimiter) Reserve(size int) (time.Duration, bool) {
+	now := l.nowFn()
+
+	r := l.lim.ReserveN(now, l.Cost(size))
+	if !r.OK() {
+		return 0, false
+	}
+	delay := r.DelayFrom(now)
+	if delay > l.maxDelay() {
+		r.CancelAt(now)
+		return 0, false
+	}
+	return delay, true
+}
+
+func (l *StanzaLimiter) maxDelay() time.Duration {
+	if l.lim.Limit() <= 0 {
+		return 0
+	}
+	return time.Duration(float64(l.lim.Burst()) / float64(l.lim.Limit()) * float64(time.Second))
+}
@@ -0,0 +1,134 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shaper
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStanzaLimiter_Cost(t *testing.T) {
+	// given
+	l := NewStanzaLimiter(10, 20, 100)
+
+	// then
+	require.Equal(t, 1, l.Cost(0))
+	require.Equal(t, 1, l.Cost(99))
+	require.Equal(t, 2, l.Cost(100))
+	require.Equal(t, 5, l.Cost(450))
+	require.Equal(t, 20, l.Cost(1<<20)) // truncated to burst
+}
+
+func TestStanzaLimiter_LargeStanzasThrottledSooner(t *testing.T) {
+	// given
+	now := time.Now()
+
+	newLimiter := func() *StanzaLimiter {
+		l := NewStanzaLimiter(1, 10, 200)
+		l.nowFn = func() time.Time { return now }
+		return l
+	}
+	smallSize := 10
+	largeSize := 800
+
+	smallLim := newLimiter()
+	largeLim := newLimiter()
+
+	// when
+	acceptedUntilThrottled := func(l *StanzaLimiter, size int) int {
+		var n int
+		for {
+			delay, ok := l.Reserve(size)
+			if !ok || delay > 0 {
+				return n
+			}
+			n++
+		}
+	}
+	smallCount := acceptedUntilThrottled(smallLim, smallSize)
+	largeCount := acceptedUntilThrottled(largeLim, largeSize)
+
+	// then
+	require.Equal(t, 1, smallLim.Cost(smallSize))
+	require.Equal(t, 5, largeLim.Cost(largeSize))
+
+	require.Equal(t, 10, smallCount)
+	require.Equal(t, 2, largeCount)
+}
+
+func TestStanzaLimiter_ExceededDelay(t *testing.T) {
+	// given
+	now := time.Now()
+
+	l := NewStanzaLimiter(1, 4, 100)
+	l.nowFn = func() time.Time { return now }
+
+	// when
+	delay1, ok1 := l.Reserve(350) // drains the bucket
+	delay2, ok2 := l.Reserve(350) // waits for a full refill
+	_, ok3 := l.Reserve(350)      // beyond max throttle delay
+
+	// then
+	require.True(t, ok1)
+	require.Zero(t, delay1)
+
+	require.True(t, ok2)
+	require.Equal(t, time.Second*4, delay2)
+
+	require.False(t, ok3)
+}
+
+func TestStanzaLimiter_Allow(t *testing.T) {
+	// given
+	now := time.Now()
+
+	l := NewStanzaLimiter(1, 2, 100)
+	l.nowFn = func() time.Time { return now }
+
+	// when
+	ok1 := l.Allow(0)
+	ok2 := l.Allow(0)
+	ok3 := l.Allow(0)
+
+	// then
+	require.True(t, ok1)
+	require.True(t, ok2)
+	require.False(t, ok3)
+}
+
+func TestShaper_StanzaLimiter(t *testing.T) {
+	// given
+	var cfg Config
+	cfg.Name = "foo"
+	cfg.Rate.Limit = 2000
+
+	disabled, _ := New(cfg)
+
+	cfg.Stanza.Limit = 50
+	cfg.Stanza.BytesPerToken = 512
+	enabled, _ := New(cfg)
+
+	// when
+	disabledLim := disabled.StanzaLimiter()
+	enabledLim := enabled.StanzaLimiter()
+
+	// then
+	require.Nil(t, disabledLim)
+	require.NotNil(t, enabledLim)
+	require.Equal(t, 50, enabledLim.lim.Burst())
+	require.Equal(t, 2, enabledLim.Cost(512))
+}
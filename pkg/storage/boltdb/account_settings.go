@@ -0,0 +1,136 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltDBAccountSettingsRep struct {
+	tx *bolt.Tx
+}
+
+func newAccountSettingsRep(tx *bolt.Tx) *boltDBAccountSettingsRep {
+	return &boltDBAccountSettingsRep{tx: tx}
+}
+
+func (r *boltDBAccountSettingsRep) UpsertAccountFlag(_ context.Context, username, flag string, enabled bool) error {
+	v := accountFlag(enabled)
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: accountFlagsBucketKey(username),
+		key:    flag,
+		obj:    &v,
+	}
+	return op.do()
+}
+
+func (r *boltDBAccountSettingsRep) FetchAccountFlags(_ context.Context, username string) (map[string]bool, error) {
+	retVal := make(map[string]bool)
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: accountFlagsBucketKey(username),
+		iterFn: func(k, b []byte) error {
+			var v accountFlag
+			if err := v.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal[string(k)] = bool(v)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBAccountSettingsRep) DeleteAccountFlag(_ context.Context, username, flag string) error {
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: accountFlagsBucketKey(username),
+		key:    flag,
+	}
+	return op.do()
+}
+
+func (r *boltDBAccountSettingsRep) DeleteAccountFlags(_ context.Context, username string) error {
+	exists := bucketExistsOp{
+		tx:     r.tx,
+		bucket: accountFlagsBucketKey(username),
+	}
+	if !exists.do() {
+		return nil
+	}
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: accountFlagsBucketKey(username),
+	}
+	return op.do()
+}
+
+func accountFlagsBucketKey(username string) string {
+	return fmt.Sprintf("acf:%s", username)
+}
+
+type accountFlag bool
+
+func (f *accountFlag) MarshalBinary() ([]byte, error) {
+	if *f {
+		return []byte{1}, nil
+	}
+	return []byte{0}, nil
+}
+
+func (f *accountFlag) UnmarshalBinary(b []byte) error {
+	if len(b) != 1 {
+		return fmt.Errorf("boltdb: invalid account flag value length: %d", len(b))
+	}
+	*f = b[0] != 0
+	return nil
+}
+
+// UpsertAccountFlag satisfies repository.AccountSettings interface.
+func (r *Repository) UpsertAccountFlag(ctx context.Context, username, flag string, enabled bool) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newAccountSettingsRep(tx).UpsertAccountFlag(ctx, username, flag, enabled)
+	})
+}
+
+// FetchAccountFlags satisfies repository.AccountSettings interface.
+func (r *Repository) FetchAccountFlags(ctx context.Context, username string) (flags map[string]bool, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		flags, err = newAccountSettingsRep(tx).FetchAccountFlags(ctx, username)
+		return err
+	})
+	return
+}
+
+// DeleteAccountFlag satisfies repository.AccountSettings interface.
+func (r *Repository) DeleteAccountFlag(ctx context.Context, username, flag string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newAccountSettingsRep(tx).DeleteAccountFlag(ctx, username, flag)
+	})
+}
+
+// DeleteAccountFlags satisfies repository.AccountSettings interface.
+func (r *Repository) DeleteAccountFlags(ctx context.Context, username string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newAccountSettingsRep(tx).DeleteAccountFlags(ctx, username)
+	})
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBoltDB_UpsertAndFetchAccountFlags(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBAccountSettingsRep{tx: tx}
+
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "carbons_default_on", true))
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "mam_enabled", true))
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "mam_enabled", false))
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "noelia", "mam_enabled", true))
+
+		flags, err := rep.FetchAccountFlags(context.Background(), "ortuman")
+		require.NoError(t, err)
+
+		require.Equal(t, map[string]bool{"carbons_default_on": true, "mam_enabled": false}, flags)
+
+		flags, err = rep.FetchAccountFlags(context.Background(), "romeo")
+		require.NoError(t, err)
+
+		require.Len(t, flags, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteAccountFlags(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBAccountSettingsRep{tx: tx}
+
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "carbons_default_on", true))
+		require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "mam_enabled", true))
+
+		require.NoError(t, rep.DeleteAccountFlag(context.Background(), "ortuman", "mam_enabled"))
+
+		flags, err := rep.FetchAccountFlags(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Equal(t, map[string]bool{"carbons_default_on": true}, flags)
+
+		require.NoError(t, rep.DeleteAccountFlags(context.Background(), "ortuman"))
+		require.NoError(t, rep.DeleteAccountFlags(context.Background(), "noelia")) // no flags set
+
+		flags, err = rep.FetchAccountFlags(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Len(t, flags, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
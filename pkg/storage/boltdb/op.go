@@ -157,3 +157,31 @@ func (op iterKeysOp) do() error {
 	}
 	return nil
 }
+
+type pageKeysOp struct {
+	tx     *bolt.Tx
+	bucket string
+	after  string
+	limit  int
+	iterFn func(k, b []byte) error
+}
+
+func (op pageKeysOp) do() error {
+	b := op.tx.Bucket([]byte(op.bucket))
+	if b == nil {
+		return nil
+	}
+	c := b.Cursor()
+
+	k, v := c.Seek([]byte(op.after))
+	if k != nil && string(k) == op.after {
+		k, v = c.Next()
+	}
+	for n := 0; k != nil && n < op.limit; k, v = c.Next() {
+		if err := op.iterFn(k, v); err != nil {
+			return err
+		}
+		n++
+	}
+	return nil
+}
@@ -39,6 +39,9 @@ type Repository struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 
 	cfg Config
@@ -71,6 +74,12 @@ func (r *Repository) InTransaction(ctx context.Context, f func(ctx context.Conte
 	return tx.Commit()
 }
 
+// WithTx runs f function serially.
+// BoltDB does not support binding an outer transaction to the context, hence no atomicity is guaranteed.
+func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return f(ctx)
+}
+
 // Start implements Start interface method.
 func (r *Repository) Start(_ context.Context) error {
 	db, err := bolt.Open(r.cfg.Path, 0600, &bolt.Options{Timeout: time.Second})
@@ -21,6 +21,7 @@ import (
 
 	"github.com/golang/protobuf/proto"
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 	bolt "go.etcd.io/bbolt"
 )
 
@@ -86,14 +87,28 @@ func (r *boltDBRosterRep) FetchRosterVersion(_ context.Context, username string)
 	}
 }
 
-func (r *boltDBRosterRep) UpsertRosterItem(_ context.Context, ri *rostermodel.Item) error {
+func (r *boltDBRosterRep) UpsertRosterItem(ctx context.Context, ri *rostermodel.Item) error {
+	storedRi, err := r.FetchRosterItem(ctx, ri.Username, ri.Jid)
+	if err != nil {
+		return err
+	}
+	if storedRi.GetVersion() != ri.Version {
+		return repository.ErrVersionConflict
+	}
+	newRi := proto.Clone(ri).(*rostermodel.Item)
+	newRi.Version++
+
 	op := upsertKeyOp{
 		tx:     r.tx,
 		bucket: rosterItemsBucketKey(ri.Username),
 		key:    ri.Jid,
-		obj:    ri,
+		obj:    newRi,
 	}
-	return op.do()
+	if err := op.do(); err != nil {
+		return err
+	}
+	ri.Version = newRi.Version
+	return nil
 }
 
 func (r *boltDBRosterRep) DeleteRosterItem(_ context.Context, username, jid string) error {
@@ -166,6 +181,38 @@ func (r *boltDBRosterRep) FetchRosterItemsInGroups(_ context.Context, username s
 	return retVal, nil
 }
 
+func (r *boltDBRosterRep) FetchRosterItemsPaged(_ context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+	var retVal []*rostermodel.Item
+
+	// bucket keys are iterated in byte-sorted order
+	op := pageKeysOp{
+		tx:     r.tx,
+		bucket: rosterItemsBucketKey(username),
+		after:  afterJID,
+		limit:  limit,
+		iterFn: func(_, b []byte) error {
+			var itm rostermodel.Item
+			if err := proto.Unmarshal(b, &itm); err != nil {
+				return err
+			}
+			retVal = append(retVal, &itm)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBRosterRep) CountRosterItems(_ context.Context, username string) (int, error) {
+	op := countKeysOp{
+		tx:     r.tx,
+		bucket: rosterItemsBucketKey(username),
+	}
+	return op.do()
+}
+
 func (r *boltDBRosterRep) FetchRosterItem(_ context.Context, username, jid string) (*rostermodel.Item, error) {
 	op := fetchKeyOp{
 		tx:     r.tx,
@@ -351,6 +398,24 @@ func (r *Repository) FetchRosterItemsInGroups(ctx context.Context, username stri
 	return
 }
 
+// FetchRosterItemsPaged satisfies repository.Roster interface.
+func (r *Repository) FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) (items []*rostermodel.Item, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		items, err = newRosterRep(tx).FetchRosterItemsPaged(ctx, username, afterJID, limit)
+		return err
+	})
+	return
+}
+
+// CountRosterItems satisfies repository.Roster interface.
+func (r *Repository) CountRosterItems(ctx context.Context, username string) (c int, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		c, err = newRosterRep(tx).CountRosterItems(ctx, username)
+		return err
+	})
+	return
+}
+
 // FetchRosterItem satisfies repository.Roster interface.
 func (r *Repository) FetchRosterItem(ctx context.Context, username, jid string) (item *rostermodel.Item, err error) {
 	err = r.db.View(func(tx *bolt.Tx) error {
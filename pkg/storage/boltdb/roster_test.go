@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 	"github.com/stretchr/testify/require"
 	bolt "go.etcd.io/bbolt"
 )
@@ -104,6 +105,114 @@ func TestBoltDB_RosterItems(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestBoltDB_RosterItemVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBRosterRep{tx: tx}
+
+		ri := &rostermodel.Item{
+			Username:     "ortuman",
+			Jid:          "noelia@jackal.im",
+			Subscription: rostermodel.None,
+		}
+		require.NoError(t, rep.UpsertRosterItem(context.Background(), ri))
+		require.Equal(t, int32(1), ri.Version)
+
+		// concurrent insertion based on a missing item
+		err := rep.UpsertRosterItem(context.Background(), &rostermodel.Item{
+			Username:     "ortuman",
+			Jid:          "noelia@jackal.im",
+			Subscription: rostermodel.To,
+		})
+		require.Equal(t, repository.ErrVersionConflict, err)
+
+		// concurrent update based on a stale version
+		stale := &rostermodel.Item{
+			Username:     "ortuman",
+			Jid:          "noelia@jackal.im",
+			Subscription: rostermodel.From,
+			Version:      1,
+		}
+		ri.Subscription = rostermodel.To
+		require.NoError(t, rep.UpsertRosterItem(context.Background(), ri))
+		require.Equal(t, int32(2), ri.Version)
+
+		err = rep.UpsertRosterItem(context.Background(), stale)
+		require.Equal(t, repository.ErrVersionConflict, err)
+		require.Equal(t, int32(1), stale.Version)
+
+		// retry with current version
+		current, err := rep.FetchRosterItem(context.Background(), "ortuman", "noelia@jackal.im")
+		require.NoError(t, err)
+		require.Equal(t, rostermodel.To, current.Subscription)
+
+		current.Subscription = rostermodel.Both
+		require.NoError(t, rep.UpsertRosterItem(context.Background(), current))
+		require.Equal(t, int32(3), current.Version)
+
+		stored, err := rep.FetchRosterItem(context.Background(), "ortuman", "noelia@jackal.im")
+		require.NoError(t, err)
+		require.Equal(t, rostermodel.Both, stored.Subscription)
+		require.Equal(t, int32(3), stored.Version)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_RosterItemsPaged(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBRosterRep{tx: tx}
+
+		for _, jd := range []string{"e@jackal.im", "a@jackal.im", "d@jackal.im", "c@jackal.im", "b@jackal.im"} {
+			err := rep.UpsertRosterItem(context.Background(), &rostermodel.Item{
+				Username: "ortuman",
+				Jid:      jd,
+			})
+			require.NoError(t, err)
+		}
+		var jids []string
+
+		afterJID := ""
+		for {
+			items, err := rep.FetchRosterItemsPaged(context.Background(), "ortuman", afterJID, 2)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(items), 2)
+
+			if len(items) == 0 {
+				break
+			}
+			for _, itm := range items {
+				jids = append(jids, itm.Jid)
+			}
+			afterJID = items[len(items)-1].Jid
+		}
+		require.Equal(t, []string{"a@jackal.im", "b@jackal.im", "c@jackal.im", "d@jackal.im", "e@jackal.im"}, jids)
+
+		count, err := rep.CountRosterItems(context.Background(), "ortuman")
+		require.NoError(t, err)
+		require.Equal(t, 5, count)
+
+		// cursor item no longer stored
+		items, err := rep.FetchRosterItemsPaged(context.Background(), "ortuman", "bb@jackal.im", 2)
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		require.Equal(t, "c@jackal.im", items[0].Jid)
+		require.Equal(t, "d@jackal.im", items[1].Jid)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestBoltDB_RosterNotifications(t *testing.T) {
 	t.Parallel()
 
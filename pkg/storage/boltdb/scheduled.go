@@ -0,0 +1,169 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	scheduledBucket    = "scheduled"
+	scheduledIDsBucket = "scheduled:ids"
+)
+
+type boltDBScheduledRep struct {
+	tx *bolt.Tx
+}
+
+func newScheduledRep(tx *bolt.Tx) *boltDBScheduledRep {
+	return &boltDBScheduledRep{tx: tx}
+}
+
+func (r *boltDBScheduledRep) UpsertScheduledStanza(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+	if err := r.DeleteScheduledStanza(ctx, stanza.Id); err != nil {
+		return err
+	}
+	dueKey := scheduledDueKey(stanza.DueAt.AsTime(), stanza.Id)
+
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: scheduledBucket,
+		key:    dueKey,
+		obj:    stanza,
+	}
+	if err := op.do(); err != nil {
+		return err
+	}
+	// index by identifier
+	b, err := r.tx.CreateBucketIfNotExists([]byte(scheduledIDsBucket))
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(stanza.Id), []byte(dueKey))
+}
+
+func (r *boltDBScheduledRep) FetchDueScheduledStanzas(_ context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+	b := r.tx.Bucket([]byte(scheduledBucket))
+	if b == nil {
+		return nil, nil
+	}
+	var retVal []*scheduledmodel.Stanza
+
+	maxKey := []byte(scheduledDueKeyPrefix(t))
+
+	c := b.Cursor()
+	for k, v := c.First(); k != nil && len(retVal) < limit; k, v = c.Next() {
+		if string(k[:len(maxKey)]) > string(maxKey) {
+			break
+		}
+		var st scheduledmodel.Stanza
+		if err := st.UnmarshalBinary(v); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &st)
+	}
+	return retVal, nil
+}
+
+func (r *boltDBScheduledRep) DeleteScheduledStanza(_ context.Context, id string) error {
+	ib := r.tx.Bucket([]byte(scheduledIDsBucket))
+	if ib == nil {
+		return nil
+	}
+	dueKey := ib.Get([]byte(id))
+	if dueKey == nil {
+		return nil
+	}
+	op := delKeyOp{
+		tx:     r.tx,
+		bucket: scheduledBucket,
+		key:    string(dueKey),
+	}
+	if err := op.do(); err != nil {
+		return err
+	}
+	return ib.Delete([]byte(id))
+}
+
+func (r *boltDBScheduledRep) DeleteScheduledStanzas(ctx context.Context, username string) error {
+	var ids []string
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: scheduledBucket,
+		iterFn: func(_, b []byte) error {
+			var st scheduledmodel.Stanza
+			if err := st.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			if st.Username == username {
+				ids = append(ids, st.Id)
+			}
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := r.DeleteScheduledStanza(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scheduledDueKeyPrefix(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+func scheduledDueKey(t time.Time, id string) string {
+	return fmt.Sprintf("%s:%s", scheduledDueKeyPrefix(t), id)
+}
+
+// UpsertScheduledStanza satisfies repository.Scheduled interface.
+func (r *Repository) UpsertScheduledStanza(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newScheduledRep(tx).UpsertScheduledStanza(ctx, stanza)
+	})
+}
+
+// FetchDueScheduledStanzas satisfies repository.Scheduled interface.
+func (r *Repository) FetchDueScheduledStanzas(ctx context.Context, t time.Time, limit int) (sts []*scheduledmodel.Stanza, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		sts, err = newScheduledRep(tx).FetchDueScheduledStanzas(ctx, t, limit)
+		return err
+	})
+	return
+}
+
+// DeleteScheduledStanza satisfies repository.Scheduled interface.
+func (r *Repository) DeleteScheduledStanza(ctx context.Context, id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newScheduledRep(tx).DeleteScheduledStanza(ctx, id)
+	})
+}
+
+// DeleteScheduledStanzas satisfies repository.Scheduled interface.
+func (r *Repository) DeleteScheduledStanzas(ctx context.Context, username string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newScheduledRep(tx).DeleteScheduledStanzas(ctx, username)
+	})
+}
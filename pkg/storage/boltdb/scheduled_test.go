@@ -0,0 +1,157 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBoltDB_UpsertAndFetchDueScheduledStanzas(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBScheduledRep{tx: tx}
+
+		st0 := testScheduledStanza("sch0", "ortuman", now.Add(-time.Minute))
+		st1 := testScheduledStanza("sch1", "ortuman", now.Add(-time.Second))
+		st2 := testScheduledStanza("sch2", "noelia", now.Add(time.Hour))
+
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), st2))
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), st1))
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), st0))
+
+		sts, err := rep.FetchDueScheduledStanzas(context.Background(), now, 10)
+		require.NoError(t, err)
+
+		require.Len(t, sts, 2)
+		require.Equal(t, "sch0", sts[0].Id)
+		require.Equal(t, "sch1", sts[1].Id)
+
+		sts, err = rep.FetchDueScheduledStanzas(context.Background(), now, 1)
+		require.NoError(t, err)
+		require.Len(t, sts, 1)
+
+		// reschedule
+		st1.DueAt = timestamppb.New(now.Add(time.Hour))
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), st1))
+
+		sts, err = rep.FetchDueScheduledStanzas(context.Background(), now, 10)
+		require.NoError(t, err)
+		require.Len(t, sts, 1)
+		require.Equal(t, "sch0", sts[0].Id)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteScheduledStanza(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBScheduledRep{tx: tx}
+
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), testScheduledStanza("sch0", "ortuman", now)))
+		require.NoError(t, rep.DeleteScheduledStanza(context.Background(), "sch0"))
+
+		sts, err := rep.FetchDueScheduledStanzas(context.Background(), now, 10)
+		require.NoError(t, err)
+		require.Len(t, sts, 0)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteScheduledStanzas(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	now := time.Now()
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBScheduledRep{tx: tx}
+
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), testScheduledStanza("sch0", "ortuman", now)))
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), testScheduledStanza("sch1", "noelia", now)))
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), testScheduledStanza("sch2", "ortuman", now)))
+
+		require.NoError(t, rep.DeleteScheduledStanzas(context.Background(), "ortuman"))
+
+		sts, err := rep.FetchDueScheduledStanzas(context.Background(), now, 10)
+		require.NoError(t, err)
+		require.Len(t, sts, 1)
+		require.Equal(t, "sch1", sts[0].Id)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_ScheduledStanzasPersistence(t *testing.T) {
+	t.Parallel()
+
+	dbPath := t.TempDir() + "/test.db"
+	now := time.Now()
+
+	db, err := bolt.Open(dbPath, 0666, nil)
+	require.NoError(t, err)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		return newScheduledRep(tx).UpsertScheduledStanza(context.Background(), testScheduledStanza("sch0", "ortuman", now))
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// reopen database
+	db, err = bolt.Open(dbPath, 0666, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { cleanUp(db) })
+
+	var sts []*scheduledmodel.Stanza
+	err = db.View(func(tx *bolt.Tx) error {
+		sts, err = newScheduledRep(tx).FetchDueScheduledStanzas(context.Background(), now, 10)
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, sts, 1)
+	require.Equal(t, "sch0", sts[0].Id)
+	require.Equal(t, "Remember the meeting", sts[0].Stanza.Elements[0].Text)
+}
+
+func testScheduledStanza(id, username string, dueAt time.Time) *scheduledmodel.Stanza {
+	return &scheduledmodel.Stanza{
+		Id:       id,
+		Username: username,
+		Stanza:   testMessageStanza("Remember the meeting").Proto(),
+		DueAt:    timestamppb.New(dueAt),
+	}
+}
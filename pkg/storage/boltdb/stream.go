@@ -0,0 +1,149 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	bolt "go.etcd.io/bbolt"
+)
+
+type boltDBStreamRep struct {
+	tx *bolt.Tx
+}
+
+func newStreamRep(tx *bolt.Tx) *boltDBStreamRep {
+	return &boltDBStreamRep{tx: tx}
+}
+
+func (r *boltDBStreamRep) UpsertStreamQueue(_ context.Context, queue *streammodel.Queue) error {
+	op := upsertKeyOp{
+		tx:     r.tx,
+		bucket: streamQueuesBucket(queue.InstanceId),
+		key:    queue.Key,
+		obj:    queue,
+	}
+	return op.do()
+}
+
+func (r *boltDBStreamRep) FetchStreamQueues(_ context.Context, instanceID string) ([]*streammodel.Queue, error) {
+	var retVal []*streammodel.Queue
+
+	op := iterKeysOp{
+		tx:     r.tx,
+		bucket: streamQueuesBucket(instanceID),
+		iterFn: func(_, b []byte) error {
+			var q streammodel.Queue
+			if err := q.UnmarshalBinary(b); err != nil {
+				return err
+			}
+			retVal = append(retVal, &q)
+			return nil
+		},
+	}
+	if err := op.do(); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+func (r *boltDBStreamRep) DeleteStreamQueues(_ context.Context, instanceID string) error {
+	bucket := streamQueuesBucket(instanceID)
+	if r.tx.Bucket([]byte(bucket)) == nil {
+		return nil
+	}
+	op := delBucketOp{
+		tx:     r.tx,
+		bucket: bucket,
+	}
+	return op.do()
+}
+
+func (r *boltDBStreamRep) DeleteExpiredStreamQueues(_ context.Context, t time.Time) error {
+	expired := make(map[string][][]byte)
+	err := r.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		if !strings.HasPrefix(string(name), streamQueuesBucketPrefix) {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var q streammodel.Queue
+			if err := q.UnmarshalBinary(v); err != nil {
+				return err
+			}
+			if q.GetPersistedAt().AsTime().Before(t) {
+				expired[string(name)] = append(expired[string(name)], k)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	for bucket, keys := range expired {
+		b := r.tx.Bucket([]byte(bucket))
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		if k, _ := b.Cursor().First(); k != nil {
+			continue // bucket still holds non expired queues
+		}
+		if err := r.tx.DeleteBucket([]byte(bucket)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const streamQueuesBucketPrefix = "streamqueues:"
+
+func streamQueuesBucket(instanceID string) string {
+	return fmt.Sprintf("%s%s", streamQueuesBucketPrefix, instanceID)
+}
+
+// UpsertStreamQueue satisfies repository.Stream interface.
+func (r *Repository) UpsertStreamQueue(ctx context.Context, queue *streammodel.Queue) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newStreamRep(tx).UpsertStreamQueue(ctx, queue)
+	})
+}
+
+// FetchStreamQueues satisfies repository.Stream interface.
+func (r *Repository) FetchStreamQueues(ctx context.Context, instanceID string) (qs []*streammodel.Queue, err error) {
+	err = r.db.View(func(tx *bolt.Tx) error {
+		qs, err = newStreamRep(tx).FetchStreamQueues(ctx, instanceID)
+		return err
+	})
+	return
+}
+
+// DeleteStreamQueues satisfies repository.Stream interface.
+func (r *Repository) DeleteStreamQueues(ctx context.Context, instanceID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newStreamRep(tx).DeleteStreamQueues(ctx, instanceID)
+	})
+}
+
+// DeleteExpiredStreamQueues satisfies repository.Stream interface.
+func (r *Repository) DeleteExpiredStreamQueues(ctx context.Context, t time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return newStreamRep(tx).DeleteExpiredStreamQueues(ctx, t)
+	})
+}
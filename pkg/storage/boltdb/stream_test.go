@@ -0,0 +1,122 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package boltdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestBoltDB_UpsertAndFetchStreamQueues(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBStreamRep{tx: tx}
+
+		q0 := &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i1", InH: 2, OutH: 1}
+		q1 := &streammodel.Queue{
+			Key:        "noelia@jackal.im/balcony",
+			InstanceId: "i1",
+			Elements: []*streammodel.QueueElement{
+				{Stanza: testMessageStanza("hi!").Proto(), H: 1},
+			},
+		}
+		q2 := &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i2"}
+
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), q0))
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), q1))
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), q2))
+
+		q0.OutH = 5
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), q0))
+
+		qs, err := rep.FetchStreamQueues(context.Background(), "i1")
+		require.NoError(t, err)
+
+		require.Len(t, qs, 2)
+		require.Equal(t, "noelia@jackal.im/balcony", qs[0].Key)
+		require.Len(t, qs[0].Elements, 1)
+		require.Equal(t, "ortuman@jackal.im/yard", qs[1].Key)
+		require.Equal(t, uint32(5), qs[1].OutH)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteStreamQueues(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBStreamRep{tx: tx}
+
+		require.NoError(t, rep.DeleteStreamQueues(context.Background(), "i1")) // nothing to delete
+
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i1"}))
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i2"}))
+
+		require.NoError(t, rep.DeleteStreamQueues(context.Background(), "i1"))
+
+		qs, err := rep.FetchStreamQueues(context.Background(), "i1")
+		require.NoError(t, err)
+		require.Len(t, qs, 0)
+
+		qs, err = rep.FetchStreamQueues(context.Background(), "i2")
+		require.NoError(t, err)
+		require.Len(t, qs, 1)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestBoltDB_DeleteExpiredStreamQueues(t *testing.T) {
+	t.Parallel()
+
+	db := setupDB(t)
+	t.Cleanup(func() { cleanUp(db) })
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		rep := boltDBStreamRep{tx: tx}
+
+		now := time.Now()
+		expiredAt := timestamppb.New(now.Add(-time.Hour))
+
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i1", PersistedAt: expiredAt}))
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "ortuman@jackal.im/yard", InstanceId: "i2", PersistedAt: expiredAt}))
+		require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "noelia@jackal.im/balcony", InstanceId: "i2", PersistedAt: timestamppb.New(now)}))
+
+		require.NoError(t, rep.DeleteExpiredStreamQueues(context.Background(), now.Add(-time.Minute)))
+
+		require.Nil(t, tx.Bucket([]byte(streamQueuesBucket("i1"))))
+
+		qs, err := rep.FetchStreamQueues(context.Background(), "i2")
+		require.NoError(t, err)
+		require.Len(t, qs, 1)
+		require.Equal(t, "noelia@jackal.im/balcony", qs[0].Key)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -28,19 +28,25 @@ type repTx struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 }
 
 func newRepTx(tx *bolt.Tx) *repTx {
 	return &repTx{
-		User:         newUserRep(tx),
-		Last:         newLastRep(tx),
-		Capabilities: newCapsRep(tx),
-		Offline:      newOfflineRep(tx),
-		BlockList:    newBlockListRep(tx),
-		Private:      newPrivateRep(tx),
-		Roster:       newRosterRep(tx),
-		VCard:        newVCardRep(tx),
-		Locker:       newLockerRep(),
+		User:            newUserRep(tx),
+		Last:            newLastRep(tx),
+		Capabilities:    newCapsRep(tx),
+		Offline:         newOfflineRep(tx),
+		BlockList:       newBlockListRep(tx),
+		Private:         newPrivateRep(tx),
+		Roster:          newRosterRep(tx),
+		VCard:           newVCardRep(tx),
+		Scheduled:       newScheduledRep(tx),
+		AccountSettings: newAccountSettingsRep(tx),
+		Stream:          newStreamRep(tx),
+		Locker:          newLockerRep(),
 	}
 }
@@ -68,6 +68,9 @@ type CachedRepository struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 
 	rep repository.Repository
@@ -84,18 +87,21 @@ func New(cfg Config, rep repository.Repository, logger kitlog.Logger) (repositor
 	c := rediscache.New(cfg.Redis, logger)
 
 	return &CachedRepository{
-		User:         &cachedUserRep{c: c, rep: rep, logger: logger},
-		Last:         &cachedLastRep{c: c, rep: rep, logger: logger},
-		Capabilities: &cachedCapsRep{c: c, rep: rep, logger: logger},
-		Private:      &cachedPrivateRep{c: c, rep: rep, logger: logger},
-		BlockList:    &cachedBlockListRep{c: c, rep: rep, logger: logger},
-		Roster:       &cachedRosterRep{c: c, rep: rep, logger: logger},
-		VCard:        &cachedVCardRep{c: c, rep: rep, logger: logger},
-		Offline:      rep,
-		Locker:       rep,
-		rep:          rep,
-		cache:        c,
-		logger:       logger,
+		User:            &cachedUserRep{c: c, rep: rep, logger: logger},
+		Last:            &cachedLastRep{c: c, rep: rep, logger: logger},
+		Capabilities:    &cachedCapsRep{c: c, rep: rep, logger: logger},
+		Private:         &cachedPrivateRep{c: c, rep: rep, logger: logger},
+		BlockList:       &cachedBlockListRep{c: c, rep: rep, logger: logger},
+		Roster:          &cachedRosterRep{c: c, rep: rep, logger: logger},
+		VCard:           &cachedVCardRep{c: c, rep: rep, logger: logger},
+		Offline:         rep,
+		Scheduled:       rep,
+		AccountSettings: rep,
+		Stream:          rep,
+		Locker:          rep,
+		rep:             rep,
+		cache:           c,
+		logger:          logger,
 	}, nil
 }
 
@@ -107,6 +113,11 @@ func (c *CachedRepository) InTransaction(ctx context.Context, f func(ctx context
 	})
 }
 
+// WithTx runs f function within a transaction scope bound to the passed context.
+func (c *CachedRepository) WithTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return c.rep.WithTx(ctx, f)
+}
+
 // Start starts cached repository component.
 func (c *CachedRepository) Start(ctx context.Context) error {
 	if err := c.cache.Start(ctx); err != nil {
@@ -166,6 +166,35 @@ func (c *cachedRosterRep) FetchRosterItemsInGroups(ctx context.Context, username
 	return nil, nil
 }
 
+func (c *cachedRosterRep) FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+	op := fetchOp{
+		c:         c.c,
+		namespace: rosterItemsNS(username),
+		key:       rosterItemsPageKey(afterJID, limit),
+		codec:     &rostermodel.Items{},
+		missFn: func(ctx context.Context) (model.Codec, error) {
+			items, err := c.rep.FetchRosterItemsPaged(ctx, username, afterJID, limit)
+			if err != nil {
+				return nil, err
+			}
+			return &rostermodel.Items{Items: items}, nil
+		},
+		logger: c.logger,
+	}
+	v, err := op.do(ctx)
+	switch {
+	case err != nil:
+		return nil, err
+	case v != nil:
+		return v.(*rostermodel.Items).Items, nil
+	}
+	return nil, nil
+}
+
+func (c *cachedRosterRep) CountRosterItems(ctx context.Context, username string) (int, error) {
+	return c.rep.CountRosterItems(ctx, username) // not worth caching, storage backends count items cheaply
+}
+
 func (c *cachedRosterRep) FetchRosterItem(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
 	op := fetchOp{
 		c:         c.c,
@@ -308,3 +337,7 @@ func rosterGroupsSliceKey(groups []string) string {
 	})
 	return fmt.Sprintf("groups:%s", strings.Join(sortedGroups, "|"))
 }
+
+func rosterItemsPageKey(afterJID string, limit int) string {
+	return fmt.Sprintf("page:%d:%s", limit, afterJID)
+}
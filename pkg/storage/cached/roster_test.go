@@ -257,6 +257,70 @@ func TestCachedRosterRep_FetchRosterItemsInGroups(t *testing.T) {
 	require.Len(t, repMock.FetchRosterItemsInGroupsCalls(), 1)
 }
 
+func TestCachedRosterRep_FetchRosterItemsPaged(t *testing.T) {
+	// given
+	var cacheNS, cacheKey string
+
+	cacheMock := &cacheMock{}
+	cacheMock.GetFunc = func(ctx context.Context, ns, k string) ([]byte, error) {
+		cacheNS = ns
+		cacheKey = k
+		return nil, nil
+	}
+	cacheMock.PutFunc = func(ctx context.Context, ns, k string, val []byte) error {
+		return nil
+	}
+
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemsPagedFunc = func(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+		return []*rostermodel.Item{
+			{Username: "u1", Jid: "foo@jackal.im"},
+		}, nil
+	}
+
+	// when
+	rep := cachedRosterRep{
+		c:   cacheMock,
+		rep: repMock,
+	}
+	items, err := rep.FetchRosterItemsPaged(context.Background(), "u1", "bar@jackal.im", 10)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "foo@jackal.im", items[0].Jid)
+
+	require.Equal(t, rosterItemsNS("u1"), cacheNS)
+	require.Equal(t, rosterItemsPageKey("bar@jackal.im", 10), cacheKey)
+	require.Len(t, cacheMock.GetCalls(), 1)
+	require.Len(t, cacheMock.PutCalls(), 1)
+	require.Len(t, repMock.FetchRosterItemsPagedCalls(), 1)
+}
+
+func TestCachedRosterRep_CountRosterItems(t *testing.T) {
+	// given
+	cacheMock := &cacheMock{}
+
+	repMock := &repositoryMock{}
+	repMock.CountRosterItemsFunc = func(ctx context.Context, username string) (int, error) {
+		return 3, nil
+	}
+
+	// when
+	rep := cachedRosterRep{
+		c:   cacheMock,
+		rep: repMock,
+	}
+	count, err := rep.CountRosterItems(context.Background(), "u1")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	require.Len(t, cacheMock.GetCalls(), 0)
+	require.Len(t, repMock.CountRosterItemsCalls(), 1)
+}
+
 func TestCachedRosterRep_FetchRosterItem(t *testing.T) {
 	// given
 	var cacheNS, cacheKey string
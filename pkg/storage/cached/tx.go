@@ -27,19 +27,25 @@ type cachedTx struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 }
 
 func newCacheTx(c Cache, tx repository.Transaction) *cachedTx {
 	return &cachedTx{
-		User:         &cachedUserRep{c: c, rep: tx},
-		Last:         &cachedLastRep{c: c, rep: tx},
-		Capabilities: &cachedCapsRep{c: c, rep: tx},
-		Private:      &cachedPrivateRep{c: c, rep: tx},
-		BlockList:    &cachedBlockListRep{c: c, rep: tx},
-		Roster:       &cachedRosterRep{c: c, rep: tx},
-		VCard:        &cachedVCardRep{c: c, rep: tx},
-		Offline:      tx,
-		Locker:       tx,
+		User:            &cachedUserRep{c: c, rep: tx},
+		Last:            &cachedLastRep{c: c, rep: tx},
+		Capabilities:    &cachedCapsRep{c: c, rep: tx},
+		Private:         &cachedPrivateRep{c: c, rep: tx},
+		BlockList:       &cachedBlockListRep{c: c, rep: tx},
+		Roster:          &cachedRosterRep{c: c, rep: tx},
+		VCard:           &cachedVCardRep{c: c, rep: tx},
+		Offline:         tx,
+		Scheduled:       tx,
+		AccountSettings: tx,
+		Stream:          tx,
+		Locker:          tx,
 	}
 }
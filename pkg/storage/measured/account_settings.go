@@ -0,0 +1,55 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredAccountSettingsRep struct {
+	rep  repository.AccountSettings
+	inTx bool
+}
+
+func (m *measuredAccountSettingsRep) UpsertAccountFlag(ctx context.Context, username, flag string, enabled bool) error {
+	t0 := time.Now()
+	err := m.rep.UpsertAccountFlag(ctx, username, flag, enabled)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredAccountSettingsRep) FetchAccountFlags(ctx context.Context, username string) (map[string]bool, error) {
+	t0 := time.Now()
+	flags, err := m.rep.FetchAccountFlags(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return flags, err
+}
+
+func (m *measuredAccountSettingsRep) DeleteAccountFlag(ctx context.Context, username, flag string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteAccountFlag(ctx, username, flag)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredAccountSettingsRep) DeleteAccountFlags(ctx context.Context, username string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteAccountFlags(ctx, username)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredAccountSettingsRep_UpsertAccountFlag(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertAccountFlagFunc = func(ctx context.Context, username, flag string, enabled bool) error {
+		return nil
+	}
+	m := &measuredAccountSettingsRep{rep: repMock}
+
+	// when
+	_ = m.UpsertAccountFlag(context.Background(), "ortuman", "carbons_default_on", true)
+
+	// then
+	require.Len(t, repMock.UpsertAccountFlagCalls(), 1)
+}
+
+func TestMeasuredAccountSettingsRep_FetchAccountFlags(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchAccountFlagsFunc = func(ctx context.Context, username string) (map[string]bool, error) {
+		return map[string]bool{"carbons_default_on": true}, nil
+	}
+	m := &measuredAccountSettingsRep{rep: repMock}
+
+	// when
+	flags, _ := m.FetchAccountFlags(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.FetchAccountFlagsCalls(), 1)
+	require.True(t, flags["carbons_default_on"])
+}
+
+func TestMeasuredAccountSettingsRep_DeleteAccountFlag(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteAccountFlagFunc = func(ctx context.Context, username, flag string) error {
+		return nil
+	}
+	m := &measuredAccountSettingsRep{rep: repMock}
+
+	// when
+	_ = m.DeleteAccountFlag(context.Background(), "ortuman", "carbons_default_on")
+
+	// then
+	require.Len(t, repMock.DeleteAccountFlagCalls(), 1)
+}
+
+func TestMeasuredAccountSettingsRep_DeleteAccountFlags(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteAccountFlagsFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := &measuredAccountSettingsRep{rep: repMock}
+
+	// when
+	_ = m.DeleteAccountFlags(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeleteAccountFlagsCalls(), 1)
+}
@@ -40,6 +40,9 @@ type Measured struct {
 	measuredPrivateRep
 	measuredRosterRep
 	measuredVCardRep
+	measuredScheduledRep
+	measuredAccountSettingsRep
+	measuredStreamRep
 	measuredLocker
 	rep repository.Repository
 }
@@ -47,16 +50,19 @@ type Measured struct {
 // New returns a new initialized Measured repository.
 func New(rep repository.Repository) repository.Repository {
 	return &Measured{
-		measuredUserRep:         measuredUserRep{rep: rep},
-		measuredLastRep:         measuredLastRep{rep: rep},
-		measuredCapabilitiesRep: measuredCapabilitiesRep{rep: rep},
-		measuredOfflineRep:      measuredOfflineRep{rep: rep},
-		measuredBlockListRep:    measuredBlockListRep{rep: rep},
-		measuredPrivateRep:      measuredPrivateRep{rep: rep},
-		measuredRosterRep:       measuredRosterRep{rep: rep},
-		measuredVCardRep:        measuredVCardRep{rep: rep},
-		measuredLocker:          measuredLocker{rep: rep},
-		rep:                     rep,
+		measuredUserRep:            measuredUserRep{rep: rep},
+		measuredLastRep:            measuredLastRep{rep: rep},
+		measuredCapabilitiesRep:    measuredCapabilitiesRep{rep: rep},
+		measuredOfflineRep:         measuredOfflineRep{rep: rep},
+		measuredBlockListRep:       measuredBlockListRep{rep: rep},
+		measuredPrivateRep:         measuredPrivateRep{rep: rep},
+		measuredRosterRep:          measuredRosterRep{rep: rep},
+		measuredVCardRep:           measuredVCardRep{rep: rep},
+		measuredScheduledRep:       measuredScheduledRep{rep: rep},
+		measuredAccountSettingsRep: measuredAccountSettingsRep{rep: rep},
+		measuredStreamRep:          measuredStreamRep{rep: rep},
+		measuredLocker:             measuredLocker{rep: rep},
+		rep:                        rep,
 	}
 }
 
@@ -68,6 +74,11 @@ func (m *Measured) InTransaction(ctx context.Context, f func(ctx context.Context
 	})
 }
 
+// WithTx runs f function within a transaction scope bound to the passed context.
+func (m *Measured) WithTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return m.rep.WithTx(ctx, f)
+}
+
 // Start initializes repository.
 func (m *Measured) Start(ctx context.Context) error {
 	return m.rep.Start(ctx)
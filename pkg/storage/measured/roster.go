@@ -76,6 +76,20 @@ func (m *measuredRosterRep) FetchRosterItemsInGroups(ctx context.Context, userna
 	return items, err
 }
 
+func (m *measuredRosterRep) FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+	t0 := time.Now()
+	items, err := m.rep.FetchRosterItemsPaged(ctx, username, afterJID, limit)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return items, err
+}
+
+func (m *measuredRosterRep) CountRosterItems(ctx context.Context, username string) (int, error) {
+	t0 := time.Now()
+	count, err := m.rep.CountRosterItems(ctx, username)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return count, err
+}
+
 func (m *measuredRosterRep) FetchRosterItem(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
 	t0 := time.Now()
 	itm, err := m.rep.FetchRosterItem(ctx, username, jid)
@@ -114,6 +114,36 @@ func TestMeasuredRosterRep_FetchRosterItemsInGroups(t *testing.T) {
 	require.Len(t, repMock.FetchRosterItemsInGroupsCalls(), 1)
 }
 
+func TestMeasuredRosterRep_FetchRosterItemsPaged(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchRosterItemsPagedFunc = func(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+		return nil, nil
+	}
+	m := &measuredRosterRep{rep: repMock}
+
+	// when
+	_, _ = m.FetchRosterItemsPaged(context.Background(), "ortuman", "noelia@jackal.im", 10)
+
+	// then
+	require.Len(t, repMock.FetchRosterItemsPagedCalls(), 1)
+}
+
+func TestMeasuredRosterRep_CountRosterItems(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.CountRosterItemsFunc = func(ctx context.Context, username string) (int, error) {
+		return 0, nil
+	}
+	m := &measuredRosterRep{rep: repMock}
+
+	// when
+	_, _ = m.CountRosterItems(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.CountRosterItemsCalls(), 1)
+}
+
 func TestMeasuredRosterRep_FetchRosterItem(t *testing.T) {
 	// given
 	repMock := &repositoryMock{}
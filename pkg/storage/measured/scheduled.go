@@ -0,0 +1,56 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredScheduledRep struct {
+	rep  repository.Scheduled
+	inTx bool
+}
+
+func (m *measuredScheduledRep) UpsertScheduledStanza(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+	t0 := time.Now()
+	err := m.rep.UpsertScheduledStanza(ctx, stanza)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredScheduledRep) FetchDueScheduledStanzas(ctx context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+	t0 := time.Now()
+	sts, err := m.rep.FetchDueScheduledStanzas(ctx, t, limit)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return sts, err
+}
+
+func (m *measuredScheduledRep) DeleteScheduledStanza(ctx context.Context, id string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteScheduledStanza(ctx, id)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredScheduledRep) DeleteScheduledStanzas(ctx context.Context, username string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteScheduledStanzas(ctx, username)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
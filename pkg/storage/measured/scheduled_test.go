@@ -0,0 +1,85 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredScheduledRep_UpsertScheduledStanza(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertScheduledStanzaFunc = func(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+		return nil
+	}
+	m := &measuredScheduledRep{rep: repMock}
+
+	// when
+	_ = m.UpsertScheduledStanza(context.Background(), &scheduledmodel.Stanza{})
+
+	// then
+	require.Len(t, repMock.UpsertScheduledStanzaCalls(), 1)
+}
+
+func TestMeasuredScheduledRep_FetchDueScheduledStanzas(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchDueScheduledStanzasFunc = func(ctx context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+		return []*scheduledmodel.Stanza{{Id: "sch1"}}, nil
+	}
+	m := &measuredScheduledRep{rep: repMock}
+
+	// when
+	sts, _ := m.FetchDueScheduledStanzas(context.Background(), time.Now(), 10)
+
+	// then
+	require.Len(t, repMock.FetchDueScheduledStanzasCalls(), 1)
+	require.Len(t, sts, 1)
+}
+
+func TestMeasuredScheduledRep_DeleteScheduledStanza(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteScheduledStanzaFunc = func(ctx context.Context, id string) error {
+		return nil
+	}
+	m := &measuredScheduledRep{rep: repMock}
+
+	// when
+	_ = m.DeleteScheduledStanza(context.Background(), "sch1")
+
+	// then
+	require.Len(t, repMock.DeleteScheduledStanzaCalls(), 1)
+}
+
+func TestMeasuredScheduledRep_DeleteScheduledStanzas(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteScheduledStanzasFunc = func(ctx context.Context, username string) error {
+		return nil
+	}
+	m := &measuredScheduledRep{rep: repMock}
+
+	// when
+	_ = m.DeleteScheduledStanzas(context.Background(), "ortuman")
+
+	// then
+	require.Len(t, repMock.DeleteScheduledStanzasCalls(), 1)
+}
@@ -0,0 +1,56 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type measuredStreamRep struct {
+	rep  repository.Stream
+	inTx bool
+}
+
+func (m *measuredStreamRep) UpsertStreamQueue(ctx context.Context, queue *streammodel.Queue) error {
+	t0 := time.Now()
+	err := m.rep.UpsertStreamQueue(ctx, queue)
+	reportOpMetric(upsertOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredStreamRep) FetchStreamQueues(ctx context.Context, instanceID string) ([]*streammodel.Queue, error) {
+	t0 := time.Now()
+	qs, err := m.rep.FetchStreamQueues(ctx, instanceID)
+	reportOpMetric(fetchOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return qs, err
+}
+
+func (m *measuredStreamRep) DeleteStreamQueues(ctx context.Context, instanceID string) error {
+	t0 := time.Now()
+	err := m.rep.DeleteStreamQueues(ctx, instanceID)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
+
+func (m *measuredStreamRep) DeleteExpiredStreamQueues(ctx context.Context, t time.Time) error {
+	t0 := time.Now()
+	err := m.rep.DeleteExpiredStreamQueues(ctx, t)
+	reportOpMetric(deleteOp, time.Since(t0).Seconds(), err == nil, m.inTx)
+	return err
+}
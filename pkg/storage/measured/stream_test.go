@@ -0,0 +1,85 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package measuredrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeasuredStreamRep_UpsertStreamQueue(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.UpsertStreamQueueFunc = func(ctx context.Context, queue *streammodel.Queue) error {
+		return nil
+	}
+	m := &measuredStreamRep{rep: repMock}
+
+	// when
+	_ = m.UpsertStreamQueue(context.Background(), &streammodel.Queue{})
+
+	// then
+	require.Len(t, repMock.UpsertStreamQueueCalls(), 1)
+}
+
+func TestMeasuredStreamRep_FetchStreamQueues(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.FetchStreamQueuesFunc = func(ctx context.Context, instanceID string) ([]*streammodel.Queue, error) {
+		return []*streammodel.Queue{{Key: "ortuman@jackal.im/yard"}}, nil
+	}
+	m := &measuredStreamRep{rep: repMock}
+
+	// when
+	qs, _ := m.FetchStreamQueues(context.Background(), "i1")
+
+	// then
+	require.Len(t, repMock.FetchStreamQueuesCalls(), 1)
+	require.Len(t, qs, 1)
+}
+
+func TestMeasuredStreamRep_DeleteStreamQueues(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteStreamQueuesFunc = func(ctx context.Context, instanceID string) error {
+		return nil
+	}
+	m := &measuredStreamRep{rep: repMock}
+
+	// when
+	_ = m.DeleteStreamQueues(context.Background(), "i1")
+
+	// then
+	require.Len(t, repMock.DeleteStreamQueuesCalls(), 1)
+}
+
+func TestMeasuredStreamRep_DeleteExpiredStreamQueues(t *testing.T) {
+	// given
+	repMock := &repositoryMock{}
+	repMock.DeleteExpiredStreamQueuesFunc = func(ctx context.Context, t time.Time) error {
+		return nil
+	}
+	m := &measuredStreamRep{rep: repMock}
+
+	// when
+	_ = m.DeleteExpiredStreamQueues(context.Background(), time.Now())
+
+	// then
+	require.Len(t, repMock.DeleteExpiredStreamQueuesCalls(), 1)
+}
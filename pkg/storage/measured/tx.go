@@ -25,19 +25,25 @@ type measuredTx struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 }
 
 func newMeasuredTx(tx repository.Transaction) *measuredTx {
 	return &measuredTx{
-		User:         &measuredUserRep{rep: tx, inTx: true},
-		Last:         &measuredLastRep{rep: tx, inTx: true},
-		Capabilities: &measuredCapabilitiesRep{rep: tx, inTx: true},
-		Offline:      &measuredOfflineRep{rep: tx, inTx: true},
-		BlockList:    &measuredBlockListRep{rep: tx, inTx: true},
-		Private:      &measuredPrivateRep{rep: tx, inTx: true},
-		Roster:       &measuredRosterRep{rep: tx, inTx: true},
-		VCard:        &measuredVCardRep{rep: tx, inTx: true},
-		Locker:       &measuredLocker{rep: tx, inTx: true},
+		User:            &measuredUserRep{rep: tx, inTx: true},
+		Last:            &measuredLastRep{rep: tx, inTx: true},
+		Capabilities:    &measuredCapabilitiesRep{rep: tx, inTx: true},
+		Offline:         &measuredOfflineRep{rep: tx, inTx: true},
+		BlockList:       &measuredBlockListRep{rep: tx, inTx: true},
+		Private:         &measuredPrivateRep{rep: tx, inTx: true},
+		Roster:          &measuredRosterRep{rep: tx, inTx: true},
+		VCard:           &measuredVCardRep{rep: tx, inTx: true},
+		Scheduled:       &measuredScheduledRep{rep: tx, inTx: true},
+		AccountSettings: &measuredAccountSettingsRep{rep: tx, inTx: true},
+		Stream:          &measuredStreamRep{rep: tx, inTx: true},
+		Locker:          &measuredLocker{rep: tx, inTx: true},
 	}
 }
@@ -0,0 +1,94 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	kitlog "github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLAccountSettings_UpsertFlag(t *testing.T) {
+	// given
+	s, mock := newAccountSettingsMock()
+	mock.ExpectExec(`INSERT INTO account_flags \(username,flag,enabled\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(username, flag\) DO UPDATE SET enabled = \$3`).
+		WithArgs("ortuman", "carbons_default_on", true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.UpsertAccountFlag(context.Background(), "ortuman", "carbons_default_on", true)
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLAccountSettings_FetchFlags(t *testing.T) {
+	// given
+	s, mock := newAccountSettingsMock()
+	mock.ExpectQuery(`SELECT flag, enabled FROM account_flags WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"flag", "enabled"}).
+				AddRow("carbons_default_on", true).
+				AddRow("mam_enabled", false),
+		)
+
+	// when
+	flags, err := s.FetchAccountFlags(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+	require.Equal(t, map[string]bool{"carbons_default_on": true, "mam_enabled": false}, flags)
+}
+
+func TestPgSQLAccountSettings_DeleteFlag(t *testing.T) {
+	// given
+	s, mock := newAccountSettingsMock()
+	mock.ExpectExec(`DELETE FROM account_flags WHERE \(username = \$1 AND flag = \$2\)`).
+		WithArgs("ortuman", "carbons_default_on").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteAccountFlag(context.Background(), "ortuman", "carbons_default_on")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func TestPgSQLAccountSettings_DeleteFlags(t *testing.T) {
+	// given
+	s, mock := newAccountSettingsMock()
+	mock.ExpectExec(`DELETE FROM account_flags WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// when
+	err := s.DeleteAccountFlags(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, mock.ExpectationsWereMet())
+	require.Nil(t, err)
+}
+
+func newAccountSettingsMock() (*pgSQLAccountSettingsRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLAccountSettingsRep{conn: s, logger: kitlog.NewNopLogger()}, sqlMock
+}
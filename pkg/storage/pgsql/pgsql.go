@@ -43,3 +43,51 @@ type rowsScanner interface {
 	rowScanner
 	Next() bool
 }
+
+type txKey struct{}
+
+func withTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// ctxConn routes context aware operations through the transaction bound to the context, if any.
+// Otherwise, operations are executed against the underlying database connection.
+type ctxConn struct {
+	db *sql.DB
+}
+
+func (c *ctxConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.db.Exec(query, args...)
+}
+
+func (c *ctxConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.connFor(ctx).ExecContext(ctx, query, args...)
+}
+
+func (c *ctxConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.db.Query(query, args...)
+}
+
+func (c *ctxConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.connFor(ctx).QueryContext(ctx, query, args...)
+}
+
+func (c *ctxConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.db.QueryRow(query, args...)
+}
+
+func (c *ctxConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.connFor(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (c *ctxConn) connFor(ctx context.Context) conn {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+	return c.db
+}
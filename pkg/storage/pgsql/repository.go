@@ -57,6 +57,9 @@ type Repository struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 
 	host string
@@ -79,13 +82,18 @@ func New(cfg Config, logger kitlog.Logger) *Repository {
 }
 
 // InTransaction generates a PgSQL transaction and completes it after it's being used by f function.
+// In case ctx is already bound to a transaction (see WithTx) f will join it, and its completion
+// will be delegated to the outermost scope.
 func (r *Repository) InTransaction(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+	if tx := txFromContext(ctx); tx != nil {
+		return f(ctx, newRepTx(tx))
+	}
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	repTx := newRepTx(tx)
-	if err := f(ctx, repTx); err != nil {
+	if err := f(withTx(ctx, tx), repTx); err != nil {
 		if err := tx.Rollback(); err != nil {
 			level.Warn(r.logger).Log("msg", "failed to rollback PgSQL transaction", "err", err)
 		}
@@ -94,6 +102,15 @@ func (r *Repository) InTransaction(ctx context.Context, f func(ctx context.Conte
 	return tx.Commit()
 }
 
+// WithTx binds a PgSQL transaction to the context passed to f function.
+// Every repository operation invoked using such context will be part of the same transaction,
+// which will be rolled back in case f returns an error.
+func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return r.InTransaction(ctx, func(ctx context.Context, _ repository.Transaction) error {
+		return f(ctx)
+	})
+}
+
 // Start implements Start interface method.
 func (r *Repository) Start(ctx context.Context) error {
 	db, err := sql.Open("postgres", r.dsn)
@@ -112,15 +129,7 @@ func (r *Repository) Start(ctx context.Context) error {
 	}
 	level.Info(r.logger).Log("msg", "dialed PgSQL connection", "host", r.host)
 
-	r.User = &pgSQLUserRep{conn: db, logger: r.logger}
-	r.Last = &pgSQLLastRep{conn: db, logger: r.logger}
-	r.Capabilities = &pgSQLCapabilitiesRep{conn: db, logger: r.logger}
-	r.Offline = &pgSQLOfflineRep{conn: db, logger: r.logger}
-	r.BlockList = &pgSQLBlockListRep{conn: db, logger: r.logger}
-	r.Private = &pgSQLPrivateRep{conn: db, logger: r.logger}
-	r.Roster = &pgSQLRosterRep{conn: db, logger: r.logger}
-	r.VCard = &pgSQLVCardRep{conn: db, logger: r.logger}
-	r.Locker = &pgSQLLocker{conn: db}
+	r.initReps()
 	return nil
 }
 
@@ -133,6 +142,23 @@ func (r *Repository) Stop(_ context.Context) error {
 	return nil
 }
 
+func (r *Repository) initReps() {
+	c := &ctxConn{db: r.db}
+
+	r.User = &pgSQLUserRep{conn: c, logger: r.logger}
+	r.Last = &pgSQLLastRep{conn: c, logger: r.logger}
+	r.Capabilities = &pgSQLCapabilitiesRep{conn: c, logger: r.logger}
+	r.Offline = &pgSQLOfflineRep{conn: c, logger: r.logger}
+	r.BlockList = &pgSQLBlockListRep{conn: c, logger: r.logger}
+	r.Private = &pgSQLPrivateRep{conn: c, logger: r.logger}
+	r.Roster = &pgSQLRosterRep{conn: c, logger: r.logger}
+	r.VCard = &pgSQLVCardRep{conn: c, logger: r.logger}
+	r.Scheduled = &pgSQLScheduledRep{conn: c, logger: r.logger}
+	r.AccountSettings = &pgSQLAccountSettingsRep{conn: c, logger: r.logger}
+	r.Stream = &pgSQLStreamRep{conn: c, logger: r.logger}
+	r.Locker = &pgSQLLocker{conn: c}
+}
+
 func closeRows(rows *sql.Rows, logger kitlog.Logger) {
 	if err := rows.Close(); err != nil {
 		level.Warn(logger).Log("msg", "failed to close SQL rows", "err", err)
@@ -0,0 +1,119 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	kitlog "github.com/go-kit/log"
+	lastmodel "github.com/ortuman/jackal/pkg/model/last"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLRepository_WithTxCommit(t *testing.T) {
+	// given
+	r, mock := newRepositoryMock()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO last \(username,seconds,status\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(username\) DO UPDATE SET seconds = \$2, status = \$3`).
+		WithArgs("ortuman", 1234, "Heading home").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM offline_messages WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// when
+	err := r.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := r.UpsertLast(ctx, &lastmodel.Last{Username: "ortuman", Seconds: 1234, Status: "Heading home"}); err != nil {
+			return err
+		}
+		return r.DeleteOfflineMessages(ctx, "ortuman")
+	})
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLRepository_WithTxRollback(t *testing.T) {
+	// given
+	stepErr := errors.New("pgsql: step failed")
+
+	r, mock := newRepositoryMock()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO last \(username,seconds,status\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(username\) DO UPDATE SET seconds = \$2, status = \$3`).
+		WithArgs("ortuman", 1234, "Heading home").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM offline_messages WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnError(stepErr)
+	mock.ExpectRollback()
+
+	// when
+	err := r.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := r.UpsertLast(ctx, &lastmodel.Last{Username: "ortuman", Seconds: 1234, Status: "Heading home"}); err != nil {
+			return err
+		}
+		return r.DeleteOfflineMessages(ctx, "ortuman")
+	})
+
+	// then
+	require.Equal(t, stepErr, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLRepository_WithTxJoinsTransaction(t *testing.T) {
+	// given
+	stepErr := errors.New("pgsql: step failed")
+
+	r, mock := newRepositoryMock()
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM last WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM offline_messages WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	// when
+	err := r.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := r.DeleteLast(ctx, "ortuman"); err != nil {
+			return err
+		}
+		err := r.InTransaction(ctx, func(ctx context.Context, tx repository.Transaction) error {
+			return tx.DeleteOfflineMessages(ctx, "ortuman")
+		})
+		if err != nil {
+			return err
+		}
+		return stepErr
+	})
+
+	// then
+	require.Equal(t, stepErr, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func newRepositoryMock() (*Repository, sqlmock.Sqlmock) {
+	db, sqlMock := newPgSQLMock()
+	r := &Repository{db: db, logger: kitlog.NewNopLogger()}
+	r.initReps()
+	return r, sqlMock
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	"github.com/lib/pq"
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 )
 
 const (
@@ -73,12 +74,23 @@ func (r *pgSQLRosterRep) FetchRosterVersion(ctx context.Context, username string
 func (r *pgSQLRosterRep) UpsertRosterItem(ctx context.Context, ri *rostermodel.Item) error {
 	q := sq.Insert(rosterItemsTableName).
 		Prefix(noLoadBalancePrefix).
-		Columns("username", "jid", "name", "subscription", "groups", "ask").
-		Values(ri.Username, ri.Jid, ri.Name, ri.Subscription, pq.Array(ri.Groups), ri.Ask).
-		Suffix("ON CONFLICT (username, jid) DO UPDATE SET name = $3, subscription = $4, groups = $5, ask = $6")
+		Columns("username", "jid", "name", "subscription", "groups", "ask", "version").
+		Values(ri.Username, ri.Jid, ri.Name, ri.Subscription, pq.Array(ri.Groups), ri.Ask, ri.Version+1).
+		Suffix("ON CONFLICT (username, jid) DO UPDATE SET name = $3, subscription = $4, groups = $5, ask = $6, version = $7").
+		Suffix("WHERE roster_items.version = ?", ri.Version).
+		Suffix("RETURNING version")
 
-	_, err := q.RunWith(r.conn).ExecContext(ctx)
-	return err
+	var ver int32
+	err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&ver)
+	switch err {
+	case nil:
+		ri.Version = ver
+		return nil
+	case sql.ErrNoRows:
+		return repository.ErrVersionConflict
+	default:
+		return err
+	}
 }
 
 func (r *pgSQLRosterRep) DeleteRosterItem(ctx context.Context, username, jid string) error {
@@ -98,7 +110,7 @@ func (r *pgSQLRosterRep) DeleteRosterItems(ctx context.Context, username string)
 }
 
 func (r *pgSQLRosterRep) FetchRosterItems(ctx context.Context, username string) ([]*rostermodel.Item, error) {
-	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask").
+	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
 		From(rosterItemsTableName).
 		Where(sq.Eq{"username": username}).
 		OrderBy("created_at DESC")
@@ -113,7 +125,7 @@ func (r *pgSQLRosterRep) FetchRosterItems(ctx context.Context, username string)
 }
 
 func (r *pgSQLRosterRep) FetchRosterItemsInGroups(ctx context.Context, username string, groups []string) ([]*rostermodel.Item, error) {
-	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask").
+	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
 		From(rosterItemsTableName).
 		Where(sq.Expr("username = $1 AND groups @> $2", username, pq.Array(groups))).
 		OrderBy("created_at DESC")
@@ -127,8 +139,37 @@ func (r *pgSQLRosterRep) FetchRosterItemsInGroups(ctx context.Context, username
 	return scanRosterItems(rows)
 }
 
+func (r *pgSQLRosterRep) FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
+		From(rosterItemsTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Gt{"jid": afterJID}}).
+		OrderBy("jid").
+		Limit(uint64(limit))
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	return scanRosterItems(rows)
+}
+
+func (r *pgSQLRosterRep) CountRosterItems(ctx context.Context, username string) (int, error) {
+	var count int
+
+	q := sq.Select("COUNT(*)").
+		From(rosterItemsTableName).
+		Where(sq.Eq{"username": username})
+
+	if err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (r *pgSQLRosterRep) FetchRosterItem(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
-	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask").
+	q := sq.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
 		From(rosterItemsTableName).
 		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"jid": jid}})
 
@@ -235,6 +276,7 @@ func scanRosterItem(scanner rowScanner) (*rostermodel.Item, error) {
 		&ri.Subscription,
 		pq.Array(&ri.Groups),
 		&ri.Ask,
+		&ri.Version,
 	)
 	if err != nil {
 		return nil, err
@@ -23,6 +23,7 @@ import (
 	"github.com/jackal-xmpp/stravaganza/jid"
 	"github.com/lib/pq"
 	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
 	xmpputil "github.com/ortuman/jackal/pkg/util/xmpp"
 	"github.com/stretchr/testify/require"
 )
@@ -68,22 +69,53 @@ func TestPgSQLRoster_FetchRosterVersion(t *testing.T) {
 func TestPgSQLRoster_UpsertRosterItem(t *testing.T) {
 	// given
 	s, mock := newRosterMock()
-	mock.ExpectExec(`INSERT INTO roster_items \(username,jid,name,subscription,groups,ask\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6\) ON CONFLICT \(username, jid\) DO UPDATE SET name = \$3, subscription = \$4, groups = \$5, ask = \$6`).
-		WithArgs("ortuman", "noelia@jackal.im", "Noelia", "both", `{"VIP","Buddies"}`, true).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`INSERT INTO roster_items \(username,jid,name,subscription,groups,ask,version\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) ON CONFLICT \(username, jid\) DO UPDATE SET name = \$3, subscription = \$4, groups = \$5, ask = \$6, version = \$7 WHERE roster_items.version = \$8 RETURNING version`).
+		WithArgs("ortuman", "noelia@jackal.im", "Noelia", "both", `{"VIP","Buddies"}`, true, 3, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
 
-	// when
-	err := s.UpsertRosterItem(context.Background(), &rostermodel.Item{
+	ri := &rostermodel.Item{
 		Username:     "ortuman",
 		Jid:          "noelia@jackal.im",
 		Name:         "Noelia",
 		Subscription: "both",
 		Groups:       []string{"VIP", "Buddies"},
 		Ask:          true,
-	})
+		Version:      2,
+	}
+
+	// when
+	err := s.UpsertRosterItem(context.Background(), ri)
 
 	// then
 	require.Nil(t, err)
+	require.Equal(t, int32(3), ri.Version)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLRoster_UpsertRosterItemVersionConflict(t *testing.T) {
+	// given
+	s, mock := newRosterMock()
+	mock.ExpectQuery(`INSERT INTO roster_items \(username,jid,name,subscription,groups,ask,version\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\) ON CONFLICT \(username, jid\) DO UPDATE SET name = \$3, subscription = \$4, groups = \$5, ask = \$6, version = \$7 WHERE roster_items.version = \$8 RETURNING version`).
+		WithArgs("ortuman", "noelia@jackal.im", "Noelia", "both", `{"Buddies"}`, false, 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"version"})) // stale version: nothing updated
+
+	ri := &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "noelia@jackal.im",
+		Name:         "Noelia",
+		Subscription: "both",
+		Groups:       []string{"Buddies"},
+		Version:      1,
+	}
+
+	// when
+	err := s.UpsertRosterItem(context.Background(), ri)
+
+	// then
+	require.Equal(t, repository.ErrVersionConflict, err)
+	require.Equal(t, int32(1), ri.Version)
+
 	require.Nil(t, mock.ExpectationsWereMet())
 }
 
@@ -126,9 +158,10 @@ func TestPgSQLRoster_FetchRosterItems(t *testing.T) {
 		"subscription",
 		"groups",
 		"ask",
+		"version",
 	}
 	s, mock := newRosterMock()
-	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask FROM roster_items WHERE username = \$1`).
+	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask, version FROM roster_items WHERE username = \$1`).
 		WithArgs("ortuman").
 		WillReturnRows(
 			sqlmock.NewRows(cols).AddRow(
@@ -138,6 +171,7 @@ func TestPgSQLRoster_FetchRosterItems(t *testing.T) {
 				"both",
 				pq.Array([]string{"VIP", "Buddies"}),
 				false,
+				3,
 			),
 		)
 
@@ -160,9 +194,10 @@ func TestPgSQLRoster_FetchItemsInGroups(t *testing.T) {
 		"subscription",
 		"groups",
 		"ask",
+		"version",
 	}
 	s, mock := newRosterMock()
-	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask FROM roster_items WHERE username = \$1 AND groups @> \$2`).
+	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask, version FROM roster_items WHERE username = \$1 AND groups @> \$2`).
 		WithArgs("ortuman", `{"VIP","Buddies"}`).
 		WillReturnRows(
 			sqlmock.NewRows(cols).AddRow(
@@ -172,6 +207,7 @@ func TestPgSQLRoster_FetchItemsInGroups(t *testing.T) {
 				"both",
 				pq.Array([]string{"VIP", "Buddies"}),
 				false,
+				3,
 			),
 		)
 
@@ -185,6 +221,55 @@ func TestPgSQLRoster_FetchItemsInGroups(t *testing.T) {
 	require.Nil(t, mock.ExpectationsWereMet())
 }
 
+func TestPgSQLRoster_FetchRosterItemsPaged(t *testing.T) {
+	// given
+	cols := []string{
+		"username",
+		"jid",
+		"name",
+		"subscription",
+		"groups",
+		"ask",
+		"version",
+	}
+	s, mock := newRosterMock()
+	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask, version FROM roster_items WHERE \(username = \$1 AND jid > \$2\) ORDER BY jid LIMIT 2`).
+		WithArgs("ortuman", "b@jackal.im").
+		WillReturnRows(
+			sqlmock.NewRows(cols).
+				AddRow("ortuman", "c@jackal.im", "c", "both", pq.Array([]string{}), false, 1).
+				AddRow("ortuman", "d@jackal.im", "d", "both", pq.Array([]string{}), false, 1),
+		)
+
+	// when
+	ris, err := s.FetchRosterItemsPaged(context.Background(), "ortuman", "b@jackal.im", 2)
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, ris, 2)
+	require.Equal(t, "c@jackal.im", ris[0].Jid)
+	require.Equal(t, "d@jackal.im", ris[1].Jid)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLRoster_CountRosterItems(t *testing.T) {
+	// given
+	s, mock := newRosterMock()
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM roster_items WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnRows(sqlmock.NewRows([]string{"COUNT(*)"}).AddRow(3))
+
+	// when
+	c, err := s.CountRosterItems(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 3, c)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
 func TestPgSQLRoster_FetchRosterItem(t *testing.T) {
 	// given
 	cols := []string{
@@ -194,9 +279,10 @@ func TestPgSQLRoster_FetchRosterItem(t *testing.T) {
 		"subscription",
 		"groups",
 		"ask",
+		"version",
 	}
 	s, mock := newRosterMock()
-	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask FROM roster_items WHERE \(username = \$1 AND jid = \$2\)`).
+	mock.ExpectQuery(`SELECT username, jid, name, subscription, groups, ask, version FROM roster_items WHERE \(username = \$1 AND jid = \$2\)`).
 		WithArgs("ortuman", "noelia@jackal.im").
 		WillReturnRows(
 			sqlmock.NewRows(cols).AddRow(
@@ -206,6 +292,7 @@ func TestPgSQLRoster_FetchRosterItem(t *testing.T) {
 				"both",
 				pq.Array([]string{"VIP", "Buddies"}),
 				false,
+				3,
 			),
 		)
 
@@ -0,0 +1,133 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestPgSQLScheduled_UpsertScheduledStanza(t *testing.T) {
+	// given
+	dueAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	st := testScheduledStanza(dueAt)
+
+	stBytes, _ := proto.Marshal(st.Stanza)
+
+	s, mock := newScheduledMock()
+	mock.ExpectExec(`INSERT INTO scheduled_stanzas \(id,username,stanza,due_at\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(id\) DO UPDATE SET username = \$2, stanza = \$3, due_at = \$4`).
+		WithArgs("sch1", "ortuman", stBytes, dueAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.UpsertScheduledStanza(context.Background(), st)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLScheduled_FetchDueScheduledStanzas(t *testing.T) {
+	// given
+	dueAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	st := testScheduledStanza(dueAt)
+
+	stBytes, _ := proto.Marshal(st.Stanza)
+
+	now := time.Date(2022, 6, 1, 11, 0, 0, 0, time.UTC)
+
+	s, mock := newScheduledMock()
+	mock.ExpectQuery(`SELECT id, username, stanza, due_at FROM scheduled_stanzas WHERE due_at <= \$1 ORDER BY due_at LIMIT 10`).
+		WithArgs(now).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "username", "stanza", "due_at"}).AddRow("sch1", "ortuman", stBytes, dueAt),
+		)
+
+	// when
+	sts, err := s.FetchDueScheduledStanzas(context.Background(), now, 10)
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, sts, 1)
+
+	require.Equal(t, "sch1", sts[0].Id)
+	require.Equal(t, "ortuman", sts[0].Username)
+	require.Equal(t, dueAt, sts[0].DueAt.AsTime())
+	require.Equal(t, "message", sts[0].Stanza.Name)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLScheduled_DeleteScheduledStanza(t *testing.T) {
+	// given
+	s, mock := newScheduledMock()
+	mock.ExpectExec(`DELETE FROM scheduled_stanzas WHERE id = \$1`).
+		WithArgs("sch1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.DeleteScheduledStanza(context.Background(), "sch1")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLScheduled_DeleteScheduledStanzas(t *testing.T) {
+	// given
+	s, mock := newScheduledMock()
+	mock.ExpectExec(`DELETE FROM scheduled_stanzas WHERE username = \$1`).
+		WithArgs("ortuman").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.DeleteScheduledStanzas(context.Background(), "ortuman")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func testScheduledStanza(dueAt time.Time) *scheduledmodel.Stanza {
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "ortuman@jackal.im/yard")
+	b.WithAttribute("to", "noelia@jackal.im")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText("Remember the meeting").
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+
+	return &scheduledmodel.Stanza{
+		Id:       "sch1",
+		Username: "ortuman",
+		Stanza:   msg.Proto(),
+		DueAt:    timestamppb.New(dueAt),
+	}
+}
+
+func newScheduledMock() (*pgSQLScheduledRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLScheduledRep{conn: s}, sqlMock
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+)
+
+const streamQueuesTableName = "stream_queues"
+
+type pgSQLStreamRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *pgSQLStreamRep) UpsertStreamQueue(ctx context.Context, queue *streammodel.Queue) error {
+	b, err := proto.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	q := sq.Insert(streamQueuesTableName).
+		Prefix(noLoadBalancePrefix).
+		Columns("instance_id", "queue_key", "queue").
+		Values(queue.InstanceId, queue.Key, b).
+		Suffix("ON CONFLICT (instance_id, queue_key) DO UPDATE SET queue = $3")
+
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLStreamRep) FetchStreamQueues(ctx context.Context, instanceID string) ([]*streammodel.Queue, error) {
+	q := sq.Select("queue").
+		From(streamQueuesTableName).
+		Where(sq.Eq{"instance_id": instanceID})
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*streammodel.Queue
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var sq streammodel.Queue
+		if err := proto.Unmarshal(b, &sq); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &sq)
+	}
+	return retVal, nil
+}
+
+func (r *pgSQLStreamRep) DeleteStreamQueues(ctx context.Context, instanceID string) error {
+	_, err := sq.Delete(streamQueuesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Eq{"instance_id": instanceID}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *pgSQLStreamRep) DeleteExpiredStreamQueues(ctx context.Context, t time.Time) error {
+	_, err := sq.Delete(streamQueuesTableName).
+		Prefix(noLoadBalancePrefix).
+		Where(sq.Lt{"updated_at": t}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pgsqlrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang/protobuf/proto"
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPgSQLStream_UpsertStreamQueue(t *testing.T) {
+	// given
+	q := testStreamQueue()
+	qBytes, _ := proto.Marshal(q)
+
+	s, mock := newStreamMock()
+	mock.ExpectExec(`INSERT INTO stream_queues \(instance_id,queue_key,queue\) VALUES \(\$1,\$2,\$3\) ON CONFLICT \(instance_id, queue_key\) DO UPDATE SET queue = \$3`).
+		WithArgs("i1", "ortuman@jackal.im/yard", qBytes).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.UpsertStreamQueue(context.Background(), q)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLStream_FetchStreamQueues(t *testing.T) {
+	// given
+	qBytes, _ := proto.Marshal(testStreamQueue())
+
+	s, mock := newStreamMock()
+	mock.ExpectQuery(`SELECT queue FROM stream_queues WHERE instance_id = \$1`).
+		WithArgs("i1").
+		WillReturnRows(sqlmock.NewRows([]string{"queue"}).AddRow(qBytes))
+
+	// when
+	qs, err := s.FetchStreamQueues(context.Background(), "i1")
+
+	// then
+	require.Nil(t, err)
+	require.Len(t, qs, 1)
+
+	require.Equal(t, "ortuman@jackal.im/yard", qs[0].Key)
+	require.Equal(t, []byte("n0nc3"), qs[0].Nonce)
+	require.Equal(t, uint32(3), qs[0].InH)
+	require.Len(t, qs[0].Elements, 1)
+
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLStream_DeleteStreamQueues(t *testing.T) {
+	// given
+	s, mock := newStreamMock()
+	mock.ExpectExec(`DELETE FROM stream_queues WHERE instance_id = \$1`).
+		WithArgs("i1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.DeleteStreamQueues(context.Background(), "i1")
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func TestPgSQLStream_DeleteExpiredStreamQueues(t *testing.T) {
+	// given
+	tm := time.Date(2022, 01, 01, 00, 00, 00, 00, time.UTC)
+
+	s, mock := newStreamMock()
+	mock.ExpectExec(`DELETE FROM stream_queues WHERE updated_at < \$1`).
+		WithArgs(tm).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// when
+	err := s.DeleteExpiredStreamQueues(context.Background(), tm)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+}
+
+func testStreamQueue() *streammodel.Queue {
+	return &streammodel.Queue{
+		Key:        "ortuman@jackal.im/yard",
+		InstanceId: "i1",
+		Jid:        "ortuman@jackal.im/yard",
+		Nonce:      []byte("n0nc3"),
+		InH:        3,
+		OutH:       1,
+		Elements: []*streammodel.QueueElement{
+			{Stanza: testScheduledStanza(time.Now()).Stanza, H: 1},
+		},
+	}
+}
+
+func newStreamMock() (*pgSQLStreamRep, sqlmock.Sqlmock) {
+	s, sqlMock := newPgSQLMock()
+	return &pgSQLStreamRep{conn: s}, sqlMock
+}
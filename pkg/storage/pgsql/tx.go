@@ -29,19 +29,25 @@ type repTx struct {
 	repository.Private
 	repository.Roster
 	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
 	repository.Locker
 }
 
 func newRepTx(tx *sql.Tx) *repTx {
 	return &repTx{
-		User:         &pgSQLUserRep{conn: tx},
-		Last:         &pgSQLLastRep{conn: tx},
-		Capabilities: &pgSQLCapabilitiesRep{conn: tx},
-		Offline:      &pgSQLOfflineRep{conn: tx},
-		BlockList:    &pgSQLBlockListRep{conn: tx},
-		Private:      &pgSQLPrivateRep{conn: tx},
-		Roster:       &pgSQLRosterRep{conn: tx},
-		VCard:        &pgSQLVCardRep{conn: tx},
-		Locker:       &pgSQLLocker{conn: tx},
+		User:            &pgSQLUserRep{conn: tx},
+		Last:            &pgSQLLastRep{conn: tx},
+		Capabilities:    &pgSQLCapabilitiesRep{conn: tx},
+		Offline:         &pgSQLOfflineRep{conn: tx},
+		BlockList:       &pgSQLBlockListRep{conn: tx},
+		Private:         &pgSQLPrivateRep{conn: tx},
+		Roster:          &pgSQLRosterRep{conn: tx},
+		VCard:           &pgSQLVCardRep{conn: tx},
+		Scheduled:       &pgSQLScheduledRep{conn: tx},
+		AccountSettings: &pgSQLAccountSettingsRep{conn: tx},
+		Stream:          &pgSQLStreamRep{conn: tx},
+		Locker:          &pgSQLLocker{conn: tx},
 	}
 }
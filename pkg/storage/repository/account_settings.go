@@ -0,0 +1,32 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import "context"
+
+// AccountSettings defines per-account feature flags repository operations.
+type AccountSettings interface {
+	// UpsertAccountFlag inserts or updates an account feature flag value.
+	UpsertAccountFlag(ctx context.Context, username, flag string, enabled bool) error
+
+	// FetchAccountFlags retrieves all feature flags explicitly set for an account.
+	FetchAccountFlags(ctx context.Context, username string) (map[string]bool, error)
+
+	// DeleteAccountFlag deletes an account feature flag, so that host default value applies again.
+	DeleteAccountFlag(ctx context.Context, username, flag string) error
+
+	// DeleteAccountFlags deletes all feature flags associated to an account.
+	DeleteAccountFlags(ctx context.Context, username string) error
+}
@@ -14,7 +14,14 @@
 
 package repository
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrVersionConflict will be returned by versioned write operations whenever the stored entity version
+// does not match the one the write was based on, meaning it has been concurrently modified.
+var ErrVersionConflict = errors.New("repository: version conflict")
 
 // Repository represents application repository interface.
 type Repository interface {
@@ -24,6 +31,11 @@ type Repository interface {
 	// In case f returns no error tx transaction will be committed.
 	InTransaction(ctx context.Context, f func(ctx context.Context, tx Transaction) error) error
 
+	// WithTx runs f function within a transaction scope bound to the passed context.
+	// Repository operations invoked using such context are committed or rolled back altogether
+	// on backends supporting it, while in any other case they're executed serially.
+	WithTx(ctx context.Context, f func(ctx context.Context) error) error
+
 	// Start initializes repository.
 	Start(ctx context.Context) error
 
@@ -46,5 +58,8 @@ type baseRepository interface {
 	Private
 	Roster
 	VCard
+	Scheduled
+	AccountSettings
+	Stream
 	Locker
 }
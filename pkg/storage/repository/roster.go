@@ -28,7 +28,9 @@ type Roster interface {
 	// FetchRosterVersion fetches user roster version.
 	FetchRosterVersion(ctx context.Context, username string) (int, error)
 
-	// UpsertRosterItem inserts a new roster item entity into repository.
+	// UpsertRosterItem inserts or updates a roster item entity into repository.
+	// The write only succeeds if ri version matches the stored item one, being zero for non existing items.
+	// ErrVersionConflict is returned otherwise. On success ri version is set to the newly stored one.
 	UpsertRosterItem(ctx context.Context, ri *rostermodel.Item) error
 
 	// DeleteRosterItem deletes a roster item entity from repository.
@@ -43,6 +45,13 @@ type Roster interface {
 	// FetchRosterItemsInGroups fetches from repository all roster item entities associated to a given user and a set of groups.
 	FetchRosterItemsInGroups(ctx context.Context, username string, groups []string) ([]*rostermodel.Item, error)
 
+	// FetchRosterItemsPaged fetches up to limit user roster items whose JID sorts after afterJID,
+	// in ascending JID order. An empty afterJID starts from the beginning of the roster.
+	FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error)
+
+	// CountRosterItems returns the number of roster items associated to a given user.
+	CountRosterItems(ctx context.Context, username string) (int, error)
+
 	// FetchRosterItem fetches from repository a roster item entity.
 	FetchRosterItem(ctx context.Context, username, jid string) (*rostermodel.Item, error)
 
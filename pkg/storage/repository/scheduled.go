@@ -0,0 +1,38 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+)
+
+// Scheduled defines scheduled stanza repository operations.
+type Scheduled interface {
+	// UpsertScheduledStanza inserts or updates a scheduled stanza entity.
+	UpsertScheduledStanza(ctx context.Context, stanza *scheduledmodel.Stanza) error
+
+	// FetchDueScheduledStanzas retrieves up to limit scheduled stanzas whose due time is not after t,
+	// ordered by due time.
+	FetchDueScheduledStanzas(ctx context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error)
+
+	// DeleteScheduledStanza deletes a scheduled stanza entity.
+	DeleteScheduledStanza(ctx context.Context, id string) error
+
+	// DeleteScheduledStanzas deletes all scheduled stanzas associated to a user.
+	DeleteScheduledStanzas(ctx context.Context, username string) error
+}
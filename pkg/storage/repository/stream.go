@@ -0,0 +1,37 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package repository
+
+import (
+	"context"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+)
+
+// Stream defines stream management queue repository operations.
+type Stream interface {
+	// UpsertStreamQueue inserts or updates a stream management queue.
+	UpsertStreamQueue(ctx context.Context, queue *streammodel.Queue) error
+
+	// FetchStreamQueues retrieves all stream management queues persisted by a cluster instance.
+	FetchStreamQueues(ctx context.Context, instanceID string) ([]*streammodel.Queue, error)
+
+	// DeleteStreamQueues deletes all stream management queues persisted by a cluster instance.
+	DeleteStreamQueues(ctx context.Context, instanceID string) error
+
+	// DeleteExpiredStreamQueues deletes all stream management queues persisted before t by any cluster instance.
+	DeleteExpiredStreamQueues(ctx context.Context, t time.Time) error
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+)
+
+const accountFlagsTableName = "account_flags"
+
+type sqliteAccountSettingsRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *sqliteAccountSettingsRep) UpsertAccountFlag(ctx context.Context, username, flag string, enabled bool) error {
+	_, err := sqb.Insert(accountFlagsTableName).
+		Columns("username", "flag", "enabled").
+		Values(username, flag, enabled).
+		Suffix("ON CONFLICT (username, flag) DO UPDATE SET enabled = excluded.enabled").
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteAccountSettingsRep) FetchAccountFlags(ctx context.Context, username string) (map[string]bool, error) {
+	q := sqb.Select("flag", "enabled").
+		From(accountFlagsTableName).
+		Where(sq.Eq{"username": username})
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	retVal := make(map[string]bool)
+	for rows.Next() {
+		var flag string
+		var enabled bool
+		if err := rows.Scan(&flag, &enabled); err != nil {
+			return nil, err
+		}
+		retVal[flag] = enabled
+	}
+	return retVal, nil
+}
+
+func (r *sqliteAccountSettingsRep) DeleteAccountFlag(ctx context.Context, username, flag string) error {
+	_, err := sqb.Delete(accountFlagsTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"flag": flag}}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteAccountSettingsRep) DeleteAccountFlags(ctx context.Context, username string) error {
+	_, err := sqb.Delete(accountFlagsTableName).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_AccountFlags(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "f1", true))
+	require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "f2", true))
+	require.NoError(t, rep.UpsertAccountFlag(context.Background(), "ortuman", "f2", false))
+
+	flags, err := rep.FetchAccountFlags(context.Background(), "ortuman")
+
+	// then
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"f1": true, "f2": false}, flags)
+
+	require.NoError(t, rep.DeleteAccountFlag(context.Background(), "ortuman", "f1"))
+
+	flags, err = rep.FetchAccountFlags(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"f2": false}, flags)
+
+	require.NoError(t, rep.DeleteAccountFlags(context.Background(), "ortuman"))
+
+	flags, err = rep.FetchAccountFlags(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Len(t, flags, 0)
+}
@@ -0,0 +1,55 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	blocklistmodel "github.com/ortuman/jackal/pkg/model/blocklist"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_BlockListItems(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	require.NoError(t, rep.UpsertBlockListItem(context.Background(), &blocklistmodel.Item{Username: "ortuman", Jid: "jabber.org"}))
+	require.NoError(t, rep.UpsertBlockListItem(context.Background(), &blocklistmodel.Item{Username: "ortuman", Jid: "jackal.im"}))
+	require.NoError(t, rep.UpsertBlockListItem(context.Background(), &blocklistmodel.Item{Username: "ortuman", Jid: "jabber.org"}))
+
+	items, err := rep.FetchBlockListItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.Len(t, items, 2)
+	require.Equal(t, "jabber.org", items[0].Jid)
+	require.Equal(t, "jackal.im", items[1].Jid)
+
+	require.NoError(t, rep.DeleteBlockListItem(context.Background(), &blocklistmodel.Item{Username: "ortuman", Jid: "jabber.org"}))
+
+	items, err = rep.FetchBlockListItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	require.NoError(t, rep.DeleteBlockListItems(context.Background(), "ortuman"))
+
+	items, err = rep.FetchBlockListItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Len(t, items, 0)
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	capsmodel "github.com/ortuman/jackal/pkg/model/caps"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_UpsertAndFetchCapabilities(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	err := rep.UpsertCapabilities(context.Background(), &capsmodel.Capabilities{
+		Node:     "http://jackal.im",
+		Ver:      "v1234",
+		Features: []string{"ns1", "ns2"},
+	})
+	require.NoError(t, err)
+
+	exists, err := rep.CapabilitiesExist(context.Background(), "http://jackal.im", "v1234")
+	require.NoError(t, err)
+
+	caps, err := rep.FetchCapabilities(context.Background(), "http://jackal.im", "v1234")
+	require.NoError(t, err)
+
+	// then
+	require.True(t, exists)
+	require.NotNil(t, caps)
+	require.Equal(t, []string{"ns1", "ns2"}, caps.Features)
+
+	caps, err = rep.FetchCapabilities(context.Background(), "http://jackal.im", "v5678")
+	require.NoError(t, err)
+	require.Nil(t, caps)
+}
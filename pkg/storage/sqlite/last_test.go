@@ -0,0 +1,52 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	lastmodel "github.com/ortuman/jackal/pkg/model/last"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_UpsertAndFetchLast(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	err := rep.UpsertLast(context.Background(), &lastmodel.Last{Username: "ortuman", Seconds: 1, Status: "Away"})
+	require.NoError(t, err)
+
+	err = rep.UpsertLast(context.Background(), &lastmodel.Last{Username: "ortuman", Seconds: 2, Status: "Gone"})
+	require.NoError(t, err)
+
+	last, err := rep.FetchLast(context.Background(), "ortuman")
+
+	// then
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	require.Equal(t, int64(2), last.Seconds)
+	require.Equal(t, "Gone", last.Status)
+
+	err = rep.DeleteLast(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	last, err = rep.FetchLast(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Nil(t, last)
+}
@@ -0,0 +1,65 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"sync"
+)
+
+// sqliteLocker implements named locks in-process, given that a SQLite database
+// is never shared among several cluster members.
+type sqliteLocker struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func newLocker() *sqliteLocker {
+	return &sqliteLocker{
+		locks: make(map[string]chan struct{}),
+	}
+}
+
+func (l *sqliteLocker) Lock(ctx context.Context, lockID string) error {
+	for {
+		l.mu.Lock()
+		ch, ok := l.locks[lockID]
+		if !ok {
+			l.locks[lockID] = make(chan struct{})
+			l.mu.Unlock()
+			return nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-ch: // wait and retry
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (l *sqliteLocker) Unlock(_ context.Context, lockID string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch, ok := l.locks[lockID]
+	if !ok {
+		return nil
+	}
+	delete(l.locks, lockID)
+	close(ch)
+	return nil
+}
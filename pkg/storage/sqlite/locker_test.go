@@ -0,0 +1,54 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_Locker(t *testing.T) {
+	t.Parallel()
+
+	// given
+	l := newLocker()
+	require.NoError(t, l.Lock(context.Background(), "lock-1"))
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	timeoutErr := l.Lock(ctx, "lock-1")
+	otherErr := l.Lock(context.Background(), "lock-2")
+
+	acquired := make(chan error, 1)
+	go func() { acquired <- l.Lock(context.Background(), "lock-1") }()
+
+	require.NoError(t, l.Unlock(context.Background(), "lock-1"))
+
+	// then
+	require.Equal(t, context.DeadlineExceeded, timeoutErr)
+	require.NoError(t, otherErr)
+
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "failed to acquire released lock")
+	}
+}
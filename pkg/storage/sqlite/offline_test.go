@@ -0,0 +1,51 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_InsertAndFetchOfflineMessages(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	require.NoError(t, rep.InsertOfflineMessage(context.Background(), testMessageStanza("message 1"), "ortuman"))
+	require.NoError(t, rep.InsertOfflineMessage(context.Background(), testMessageStanza("message 2"), "ortuman"))
+
+	count, err := rep.CountOfflineMessages(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	ms, err := rep.FetchOfflineMessages(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.Equal(t, 2, count)
+	require.Len(t, ms, 2)
+	require.Equal(t, "message 1", ms[0].Child("body").Text())
+	require.Equal(t, "message 2", ms[1].Child("body").Text())
+
+	require.NoError(t, rep.DeleteOfflineMessages(context.Background(), "ortuman"))
+
+	count, err = rep.CountOfflineMessages(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
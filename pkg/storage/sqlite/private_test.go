@@ -0,0 +1,50 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_UpsertAndFetchPrivate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	prv := stravaganza.NewBuilder("exodus").
+		WithAttribute(stravaganza.Namespace, "exodus:ns").
+		Build()
+
+	// when
+	require.NoError(t, rep.UpsertPrivate(context.Background(), prv, "exodus:ns", "ortuman"))
+
+	fetched, err := rep.FetchPrivate(context.Background(), "exodus:ns", "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.NotNil(t, fetched)
+	require.Equal(t, prv.String(), fetched.String())
+
+	require.NoError(t, rep.DeletePrivates(context.Background(), "ortuman"))
+
+	fetched, err = rep.FetchPrivate(context.Background(), "exodus:ns", "ortuman")
+	require.NoError(t, err)
+	require.Nil(t, fetched)
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"database/sql"
+	_ "embed" // schema
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/cockroachdb/errors"
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	_ "github.com/mattn/go-sqlite3" // SQLite driver
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+//go:embed schema.sql
+var schema string
+
+// sqb is the statement builder used by every SQLite query.
+// Its placeholder format is set explicitly, since other SQL repositories may override squirrel global one.
+var sqb = sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+// Config contains SQLite configuration value.
+type Config struct {
+	// Path is the SQLite database file path.
+	Path string `fig:"path" default:".jackal.sqlite"`
+
+	// BusyTimeout is the maximum amount of time a statement waits for a database lock to be released.
+	BusyTimeout time.Duration `fig:"busy_timeout" default:"5s"`
+
+	// MaxReadConns is the maximum number of connections used to serve concurrent read queries.
+	MaxReadConns int `fig:"max_read_conns" default:"4"`
+}
+
+// Repository represents a SQLite repository implementation.
+//
+// Database is opened in WAL mode, so that reads are served concurrently by a pool of read-only connections,
+// while every write and transaction is serialized through a single writer connection. This way concurrent
+// writers wait for their turn instead of failing with 'database is locked' errors.
+type Repository struct {
+	repository.User
+	repository.Last
+	repository.Capabilities
+	repository.Offline
+	repository.BlockList
+	repository.Private
+	repository.Roster
+	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
+	repository.Locker
+
+	cfg Config
+
+	wdb    *sql.DB
+	rdb    *sql.DB
+	locker *sqliteLocker
+	logger kitlog.Logger
+}
+
+// New creates and returns an initialized SQLite Repository instance.
+func New(cfg Config, logger kitlog.Logger) *Repository {
+	return &Repository{
+		cfg:    cfg,
+		locker: newLocker(),
+		logger: logger,
+	}
+}
+
+// InTransaction generates a SQLite transaction and completes it after it's being used by f function.
+// In case ctx is already bound to a transaction (see WithTx) f will join it, and its completion
+// will be delegated to the outermost scope.
+//
+// Transactions hold the single writer connection until completed, hence f must perform every
+// database write through the passed context or transaction.
+func (r *Repository) InTransaction(ctx context.Context, f func(ctx context.Context, tx repository.Transaction) error) error {
+	if tx := txFromContext(ctx); tx != nil {
+		return f(ctx, newRepTx(tx, r.locker))
+	}
+	tx, err := r.wdb.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	repTx := newRepTx(tx, r.locker)
+	if err := f(withTx(ctx, tx), repTx); err != nil {
+		if err := tx.Rollback(); err != nil {
+			level.Warn(r.logger).Log("msg", "failed to rollback SQLite transaction", "err", err)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithTx binds a SQLite transaction to the context passed to f function.
+// Every repository operation invoked using such context will be part of the same transaction,
+// which will be rolled back in case f returns an error.
+func (r *Repository) WithTx(ctx context.Context, f func(ctx context.Context) error) error {
+	return r.InTransaction(ctx, func(ctx context.Context, _ repository.Transaction) error {
+		return f(ctx)
+	})
+}
+
+// Start opens SQLite database and applies its schema.
+func (r *Repository) Start(ctx context.Context) error {
+	// immediate transactions take the write lock on begin, so that they never fail upgrading it
+	wdb, err := sql.Open("sqlite3", r.dsn("_txlock=immediate"))
+	if err != nil {
+		return errors.Wrap(err, "failed to open SQLite database")
+	}
+	wdb.SetMaxOpenConns(1)
+
+	if _, err := wdb.ExecContext(ctx, schema); err != nil {
+		_ = wdb.Close()
+		return errors.Wrap(err, "failed to apply SQLite schema")
+	}
+	rdb, err := sql.Open("sqlite3", r.dsn("_query_only=true"))
+	if err != nil {
+		_ = wdb.Close()
+		return errors.Wrap(err, "failed to open SQLite database")
+	}
+	rdb.SetMaxOpenConns(r.cfg.MaxReadConns)
+
+	r.wdb = wdb
+	r.rdb = rdb
+
+	level.Info(r.logger).Log("msg", "opened SQLite database", "path", r.cfg.Path)
+
+	r.initReps()
+	return nil
+}
+
+// Stop closes SQLite database and prevents new queries from starting.
+func (r *Repository) Stop(_ context.Context) error {
+	if err := r.rdb.Close(); err != nil {
+		return errors.Wrap(err, "failed to close SQLite database")
+	}
+	if err := r.wdb.Close(); err != nil {
+		return errors.Wrap(err, "failed to close SQLite database")
+	}
+	level.Info(r.logger).Log("msg", "closed SQLite database", "path", r.cfg.Path)
+	return nil
+}
+
+func (r *Repository) dsn(opts string) string {
+	return fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=%d&%s",
+		r.cfg.Path,
+		r.cfg.BusyTimeout.Milliseconds(),
+		opts,
+	)
+}
+
+func (r *Repository) initReps() {
+	c := &ctxConn{wdb: r.wdb, rdb: r.rdb}
+
+	r.User = &sqliteUserRep{conn: c, logger: r.logger}
+	r.Last = &sqliteLastRep{conn: c, logger: r.logger}
+	r.Capabilities = &sqliteCapabilitiesRep{conn: c, logger: r.logger}
+	r.Offline = &sqliteOfflineRep{conn: c, logger: r.logger}
+	r.BlockList = &sqliteBlockListRep{conn: c, logger: r.logger}
+	r.Private = &sqlitePrivateRep{conn: c, logger: r.logger}
+	r.Roster = &sqliteRosterRep{conn: c, logger: r.logger}
+	r.VCard = &sqliteVCardRep{conn: c, logger: r.logger}
+	r.Scheduled = &sqliteScheduledRep{conn: c, logger: r.logger}
+	r.AccountSettings = &sqliteAccountSettingsRep{conn: c, logger: r.logger}
+	r.Stream = &sqliteStreamRep{conn: c, logger: r.logger}
+	r.Locker = r.locker
+}
+
+func closeRows(rows *sql.Rows, logger kitlog.Logger) {
+	if err := rows.Close(); err != nil {
+		level.Warn(logger).Log("msg", "failed to close SQL rows", "err", err)
+	}
+}
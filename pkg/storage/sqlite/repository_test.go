@@ -0,0 +1,111 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	lastmodel "github.com/ortuman/jackal/pkg/model/last"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_InTransaction(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	err := rep.InTransaction(context.Background(), func(ctx context.Context, tx repository.Transaction) error {
+		return tx.UpsertLast(ctx, &lastmodel.Last{Username: "ortuman", Seconds: 1234, Status: "Gone"})
+	})
+
+	// then
+	require.NoError(t, err)
+
+	last, err := rep.FetchLast(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	require.Equal(t, int64(1234), last.Seconds)
+}
+
+func TestRepository_InTransactionRollback(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+	errAbort := errors.New("abort")
+
+	// when
+	err := rep.WithTx(context.Background(), func(ctx context.Context) error {
+		if err := rep.UpsertLast(ctx, &lastmodel.Last{Username: "ortuman", Seconds: 1234}); err != nil {
+			return err
+		}
+		return errAbort
+	})
+
+	// then
+	require.Equal(t, errAbort, err)
+
+	last, err := rep.FetchLast(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Nil(t, last)
+}
+
+func TestRepository_ConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	const n = 64
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2*n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			username := fmt.Sprintf("user-%d", i)
+			errCh <- rep.InTransaction(context.Background(), func(ctx context.Context, tx repository.Transaction) error {
+				if _, err := tx.TouchRosterVersion(ctx, username); err != nil {
+					return err
+				}
+				return tx.UpsertLast(ctx, &lastmodel.Last{Username: username, Seconds: int64(i)})
+			})
+			_, err := rep.FetchRosterVersion(context.Background(), username)
+			errCh <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errCh)
+
+	// then
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+	for i := 0; i < n; i++ {
+		ver, err := rep.FetchRosterVersion(context.Background(), fmt.Sprintf("user-%d", i))
+		require.NoError(t, err)
+		require.Equal(t, 1, ver)
+	}
+}
@@ -0,0 +1,329 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+const (
+	rosterVersionsTableName      = "roster_versions"
+	rosterItemsTableName         = "roster_items"
+	rosterNotificationsTableName = "roster_notifications"
+)
+
+type sqliteRosterRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *sqliteRosterRep) TouchRosterVersion(ctx context.Context, username string) (int, error) {
+	b := sqb.Insert(rosterVersionsTableName).
+		Columns("username").
+		Values(username).
+		Suffix("ON CONFLICT (username) DO UPDATE SET ver = roster_versions.ver + 1").
+		Suffix("RETURNING ver")
+
+	var ver int
+	err := b.RunWith(r.conn).QueryRowContext(ctx).Scan(&ver)
+	if err != nil {
+		return 0, err
+	}
+	return ver, nil
+}
+
+func (r *sqliteRosterRep) FetchRosterVersion(ctx context.Context, username string) (int, error) {
+	q := sqb.Select("ver").
+		From(rosterVersionsTableName).
+		Where(sq.Eq{"username": username})
+
+	var ver int
+	err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&ver)
+	switch err {
+	case nil:
+		return ver, nil
+	case sql.ErrNoRows:
+		return 0, nil
+	default:
+		return 0, err
+	}
+}
+
+func (r *sqliteRosterRep) UpsertRosterItem(ctx context.Context, ri *rostermodel.Item) error {
+	groups, err := stringList(ri.Groups)
+	if err != nil {
+		return err
+	}
+	q := sqb.Insert(rosterItemsTableName).
+		Columns("username", "jid", "name", "subscription", "groups", "ask", "version").
+		Values(ri.Username, ri.Jid, ri.Name, ri.Subscription, groups, ri.Ask, ri.Version+1).
+		Suffix("ON CONFLICT (username, jid) DO UPDATE SET name = excluded.name, subscription = excluded.subscription, groups = excluded.groups, ask = excluded.ask, version = excluded.version").
+		Suffix("WHERE roster_items.version = ?", ri.Version).
+		Suffix("RETURNING version")
+
+	var ver int32
+	err = q.RunWith(r.conn).QueryRowContext(ctx).Scan(&ver)
+	switch err {
+	case nil:
+		ri.Version = ver
+		return nil
+	case sql.ErrNoRows:
+		return repository.ErrVersionConflict
+	default:
+		return err
+	}
+}
+
+func (r *sqliteRosterRep) DeleteRosterItem(ctx context.Context, username, jid string) error {
+	_, err := sqb.Delete(rosterItemsTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"jid": jid}}).
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteRosterRep) DeleteRosterItems(ctx context.Context, username string) error {
+	_, err := sqb.Delete(rosterItemsTableName).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteRosterRep) FetchRosterItems(ctx context.Context, username string) ([]*rostermodel.Item, error) {
+	q := sqb.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
+		From(rosterItemsTableName).
+		Where(sq.Eq{"username": username}).
+		OrderBy("rowid DESC")
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	return scanRosterItems(rows)
+}
+
+func (r *sqliteRosterRep) FetchRosterItemsInGroups(ctx context.Context, username string, groups []string) ([]*rostermodel.Item, error) {
+	q := sqb.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
+		From(rosterItemsTableName).
+		Where(inGroups(username, groups)).
+		OrderBy("rowid DESC")
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	return scanRosterItems(rows)
+}
+
+func (r *sqliteRosterRep) FetchRosterItemsPaged(ctx context.Context, username, afterJID string, limit int) ([]*rostermodel.Item, error) {
+	q := sqb.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
+		From(rosterItemsTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Gt{"jid": afterJID}}).
+		OrderBy("jid").
+		Limit(uint64(limit))
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	return scanRosterItems(rows)
+}
+
+func (r *sqliteRosterRep) CountRosterItems(ctx context.Context, username string) (int, error) {
+	var count int
+
+	q := sqb.Select("COUNT(*)").
+		From(rosterItemsTableName).
+		Where(sq.Eq{"username": username})
+
+	if err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (r *sqliteRosterRep) FetchRosterItem(ctx context.Context, username, jid string) (*rostermodel.Item, error) {
+	q := sqb.Select("username", "jid", "name", "subscription", "groups", "ask", "version").
+		From(rosterItemsTableName).
+		Where(sq.And{sq.Eq{"username": username}, sq.Eq{"jid": jid}})
+
+	ri, err := scanRosterItem(q.RunWith(r.conn).QueryRowContext(ctx))
+	switch err {
+	case nil:
+		return ri, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *sqliteRosterRep) UpsertRosterNotification(ctx context.Context, rn *rostermodel.Notification) error {
+	prBytes, err := proto.Marshal(rn.Presence)
+	if err != nil {
+		return err
+	}
+	q := sqb.Insert(rosterNotificationsTableName).
+		Columns("contact", "jid", "presence").
+		Values(rn.Contact, rn.Jid, prBytes).
+		Suffix("ON CONFLICT (contact, jid) DO UPDATE SET presence = excluded.presence")
+
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteRosterRep) DeleteRosterNotification(ctx context.Context, contact, jid string) error {
+	q := sqb.Delete(rosterNotificationsTableName).
+		Where(sq.And{sq.Eq{"contact": contact}, sq.Eq{"jid": jid}})
+	_, err := q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteRosterRep) DeleteRosterNotifications(ctx context.Context, contact string) error {
+	q := sqb.Delete(rosterNotificationsTableName).
+		Where(sq.Eq{"contact": contact})
+	_, err := q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteRosterRep) FetchRosterNotification(ctx context.Context, contact string, jid string) (*rostermodel.Notification, error) {
+	q := sqb.Select("contact", "jid", "presence").
+		From(rosterNotificationsTableName).
+		Where(sq.And{sq.Eq{"contact": contact}, sq.Eq{"jid": jid}})
+
+	rn, err := scanRosterNotification(q.RunWith(r.conn).QueryRowContext(ctx))
+	switch err {
+	case nil:
+		return rn, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *sqliteRosterRep) FetchRosterNotifications(ctx context.Context, contact string) ([]*rostermodel.Notification, error) {
+	q := sqb.Select("contact", "jid", "presence").
+		From(rosterNotificationsTableName).
+		Where(sq.Eq{"contact": contact})
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	return scanRosterNotifications(rows)
+}
+
+func (r *sqliteRosterRep) FetchRosterGroups(ctx context.Context, username string) ([]string, error) {
+	q := sqb.Select("DISTINCT json_each.value").
+		From(rosterItemsTableName + ", json_each(roster_items.groups)").
+		Where(sq.Eq{"username": username})
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var groups []string
+	for rows.Next() {
+		var group string
+		if err := rows.Scan(&group); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// inGroups matches username roster items belonging to every one of the passed groups.
+func inGroups(username string, groups []string) sq.And {
+	cond := sq.And{sq.Eq{"username": username}}
+	for _, group := range groups {
+		cond = append(cond, sq.Expr("EXISTS (SELECT 1 FROM json_each(roster_items.groups) WHERE json_each.value = ?)", group))
+	}
+	return cond
+}
+
+func scanRosterItem(scanner rowScanner) (*rostermodel.Item, error) {
+	var ri rostermodel.Item
+	err := scanner.Scan(
+		&ri.Username,
+		&ri.Jid,
+		&ri.Name,
+		&ri.Subscription,
+		scanStringList(&ri.Groups),
+		&ri.Ask,
+		&ri.Version,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ri, nil
+}
+
+func scanRosterItems(scanner rowsScanner) ([]*rostermodel.Item, error) {
+	var ret []*rostermodel.Item
+	for scanner.Next() {
+		ri, err := scanRosterItem(scanner)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ri)
+	}
+	return ret, nil
+}
+
+func scanRosterNotification(scanner rowScanner) (*rostermodel.Notification, error) {
+	var rn rostermodel.Notification
+
+	var prBytes []byte
+	if err := scanner.Scan(&rn.Contact, &rn.Jid, &prBytes); err != nil {
+		return nil, err
+	}
+	var prProto stravaganza.PBElement
+	if err := proto.Unmarshal(prBytes, &prProto); err != nil {
+		return nil, err
+	}
+	rn.Presence = &prProto
+	return &rn, nil
+}
+
+func scanRosterNotifications(scanner rowsScanner) ([]*rostermodel.Notification, error) {
+	var ret []*rostermodel.Notification
+	for scanner.Next() {
+		rn, err := scanRosterNotification(scanner)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, rn)
+	}
+	return ret, nil
+}
@@ -0,0 +1,187 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	rostermodel "github.com/ortuman/jackal/pkg/model/roster"
+	"github.com/ortuman/jackal/pkg/storage/repository"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_TouchAndFetchRosterVersion(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	ver0, err := rep.FetchRosterVersion(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	ver1, err := rep.TouchRosterVersion(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	ver2, err := rep.TouchRosterVersion(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	ver, err := rep.FetchRosterVersion(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.Equal(t, 0, ver0)
+	require.Equal(t, 1, ver1)
+	require.Equal(t, 2, ver2)
+	require.Equal(t, 2, ver)
+}
+
+func TestSQLite_RosterItems(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	require.NoError(t, rep.UpsertRosterItem(context.Background(), &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "noelia@jackal.im",
+		Subscription: rostermodel.Both,
+		Groups:       []string{"VIP", "Family"},
+	}))
+	require.NoError(t, rep.UpsertRosterItem(context.Background(), &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "romeo@jackal.im",
+		Subscription: rostermodel.To,
+		Groups:       []string{"Family"},
+	}))
+	require.NoError(t, rep.UpsertRosterItem(context.Background(), &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "juliet@jackal.im",
+		Subscription: rostermodel.None,
+	}))
+
+	items, err := rep.FetchRosterItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	inGroups, err := rep.FetchRosterItemsInGroups(context.Background(), "ortuman", []string{"VIP", "Family"})
+	require.NoError(t, err)
+
+	paged, err := rep.FetchRosterItemsPaged(context.Background(), "ortuman", "juliet@jackal.im", 1)
+	require.NoError(t, err)
+
+	count, err := rep.CountRosterItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	groups, err := rep.FetchRosterGroups(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.Len(t, items, 3)
+	require.Equal(t, "juliet@jackal.im", items[0].Jid)
+	require.Nil(t, items[0].Groups)
+
+	require.Len(t, inGroups, 1)
+	require.Equal(t, "noelia@jackal.im", inGroups[0].Jid)
+	require.Equal(t, []string{"VIP", "Family"}, inGroups[0].Groups)
+
+	require.Len(t, paged, 1)
+	require.Equal(t, "noelia@jackal.im", paged[0].Jid)
+
+	require.Equal(t, 3, count)
+
+	require.ElementsMatch(t, []string{"VIP", "Family"}, groups)
+
+	require.NoError(t, rep.DeleteRosterItem(context.Background(), "ortuman", "romeo@jackal.im"))
+
+	ri, err := rep.FetchRosterItem(context.Background(), "ortuman", "romeo@jackal.im")
+	require.NoError(t, err)
+	require.Nil(t, ri)
+
+	require.NoError(t, rep.DeleteRosterItems(context.Background(), "ortuman"))
+
+	items, err = rep.FetchRosterItems(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Len(t, items, 0)
+}
+
+func TestSQLite_RosterItemVersionConflict(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	ri := &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "noelia@jackal.im",
+		Subscription: rostermodel.None,
+	}
+	require.NoError(t, rep.UpsertRosterItem(context.Background(), ri))
+	require.Equal(t, int32(1), ri.Version)
+
+	// when
+	stale := &rostermodel.Item{
+		Username:     "ortuman",
+		Jid:          "noelia@jackal.im",
+		Subscription: rostermodel.Both,
+	}
+	err := rep.UpsertRosterItem(context.Background(), stale)
+
+	// then
+	require.Equal(t, repository.ErrVersionConflict, err)
+
+	ri.Subscription = rostermodel.Both
+	require.NoError(t, rep.UpsertRosterItem(context.Background(), ri))
+	require.Equal(t, int32(2), ri.Version)
+}
+
+func TestSQLite_RosterNotifications(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	pr := stravaganza.NewPresenceBuilder().
+		WithAttribute(stravaganza.From, "noelia@jackal.im").
+		WithAttribute(stravaganza.To, "ortuman@jackal.im").
+		WithAttribute(stravaganza.Type, stravaganza.SubscribeType).
+		Build()
+
+	// when
+	require.NoError(t, rep.UpsertRosterNotification(context.Background(), &rostermodel.Notification{
+		Contact:  "ortuman",
+		Jid:      "noelia@jackal.im",
+		Presence: pr.Proto(),
+	}))
+
+	rn, err := rep.FetchRosterNotification(context.Background(), "ortuman", "noelia@jackal.im")
+	require.NoError(t, err)
+
+	rns, err := rep.FetchRosterNotifications(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.NotNil(t, rn)
+	require.Equal(t, "noelia@jackal.im", rn.Jid)
+	require.Len(t, rns, 1)
+
+	require.NoError(t, rep.DeleteRosterNotification(context.Background(), "ortuman", "noelia@jackal.im"))
+
+	rn, err = rep.FetchRosterNotification(context.Background(), "ortuman", "noelia@jackal.im")
+	require.NoError(t, err)
+	require.Nil(t, rn)
+}
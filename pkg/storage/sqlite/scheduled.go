@@ -0,0 +1,98 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/jackal-xmpp/stravaganza"
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const scheduledStanzasTableName = "scheduled_stanzas"
+
+type sqliteScheduledRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *sqliteScheduledRep) UpsertScheduledStanza(ctx context.Context, stanza *scheduledmodel.Stanza) error {
+	b, err := proto.Marshal(stanza.Stanza)
+	if err != nil {
+		return err
+	}
+	q := sqb.Insert(scheduledStanzasTableName).
+		Columns("id", "username", "stanza", "due_at").
+		Values(stanza.Id, stanza.Username, b, stanza.DueAt.AsTime().UnixNano()).
+		Suffix("ON CONFLICT (id) DO UPDATE SET username = excluded.username, stanza = excluded.stanza, due_at = excluded.due_at")
+
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteScheduledRep) FetchDueScheduledStanzas(ctx context.Context, t time.Time, limit int) ([]*scheduledmodel.Stanza, error) {
+	q := sqb.Select("id", "username", "stanza", "due_at").
+		From(scheduledStanzasTableName).
+		Where(sq.LtOrEq{"due_at": t.UnixNano()}).
+		OrderBy("due_at").
+		Limit(uint64(limit))
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*scheduledmodel.Stanza
+	for rows.Next() {
+		var st scheduledmodel.Stanza
+		var b []byte
+		var dueAt int64
+
+		if err := rows.Scan(&st.Id, &st.Username, &b, &dueAt); err != nil {
+			return nil, err
+		}
+		var stProto stravaganza.PBElement
+		if err := proto.Unmarshal(b, &stProto); err != nil {
+			return nil, err
+		}
+		st.Stanza = &stProto
+		st.DueAt = timestamppb.New(time.Unix(0, dueAt))
+
+		retVal = append(retVal, &st)
+	}
+	return retVal, nil
+}
+
+func (r *sqliteScheduledRep) DeleteScheduledStanza(ctx context.Context, id string) error {
+	_, err := sqb.Delete(scheduledStanzasTableName).
+		Where(sq.Eq{"id": id}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteScheduledRep) DeleteScheduledStanzas(ctx context.Context, username string) error {
+	_, err := sqb.Delete(scheduledStanzasTableName).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
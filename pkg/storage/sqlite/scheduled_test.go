@@ -0,0 +1,59 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	scheduledmodel "github.com/ortuman/jackal/pkg/model/scheduled"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSQLite_FetchDueScheduledStanzas(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	now := time.Now()
+	for _, st := range []*scheduledmodel.Stanza{
+		{Id: "s1", Username: "ortuman", Stanza: testMessageStanza("s1").Proto(), DueAt: timestamppb.New(now.Add(-time.Minute))},
+		{Id: "s2", Username: "ortuman", Stanza: testMessageStanza("s2").Proto(), DueAt: timestamppb.New(now.Add(-time.Hour))},
+		{Id: "s3", Username: "noelia", Stanza: testMessageStanza("s3").Proto(), DueAt: timestamppb.New(now.Add(time.Hour))},
+	} {
+		require.NoError(t, rep.UpsertScheduledStanza(context.Background(), st))
+	}
+
+	// when
+	sts, err := rep.FetchDueScheduledStanzas(context.Background(), now, 10)
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, sts, 2)
+	require.Equal(t, "s2", sts[0].Id)
+	require.Equal(t, "s1", sts[1].Id)
+	require.Equal(t, now.Add(-time.Hour).UnixNano(), sts[0].DueAt.AsTime().UnixNano())
+
+	require.NoError(t, rep.DeleteScheduledStanza(context.Background(), "s2"))
+	require.NoError(t, rep.DeleteScheduledStanzas(context.Background(), "ortuman"))
+
+	sts, err = rep.FetchDueScheduledStanzas(context.Background(), now.Add(2*time.Hour), 10)
+	require.NoError(t, err)
+	require.Len(t, sts, 1)
+	require.Equal(t, "s3", sts[0].Id)
+}
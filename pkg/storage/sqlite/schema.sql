@@ -0,0 +1,159 @@
+/*
+ Copyright 2022 The jackal Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+-- users
+
+CREATE TABLE IF NOT EXISTS users (
+    username         TEXT PRIMARY KEY,
+    h_sha_1          TEXT NOT NULL,
+    h_sha_256        TEXT NOT NULL,
+    h_sha_512        TEXT NOT NULL,
+    h_sha3_512       TEXT NOT NULL,
+    salt             TEXT NOT NULL,
+    iteration_count  INTEGER NOT NULL,
+    pepper_id        TEXT NOT NULL,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- last
+
+CREATE TABLE IF NOT EXISTS last (
+    username   TEXT PRIMARY KEY,
+    status     TEXT NOT NULL,
+    seconds    INTEGER NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- capabilities
+
+CREATE TABLE IF NOT EXISTS capabilities (
+    node       TEXT NOT NULL,
+    ver        TEXT NOT NULL,
+    features   TEXT, -- JSON array
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (node, ver)
+);
+
+-- offline_messages
+
+CREATE TABLE IF NOT EXISTS offline_messages (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    username   TEXT NOT NULL,
+    message    BLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS i_offline_messages_username ON offline_messages(username);
+
+-- blocklist_items
+
+CREATE TABLE IF NOT EXISTS blocklist_items (
+    username   TEXT NOT NULL,
+    jid        TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (username, jid)
+);
+
+-- private_storage
+
+CREATE TABLE IF NOT EXISTS private_storage (
+    username   TEXT NOT NULL,
+    namespace  TEXT NOT NULL,
+    data       BLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (username, namespace)
+);
+
+-- roster_notifications
+
+CREATE TABLE IF NOT EXISTS roster_notifications (
+    contact    TEXT NOT NULL,
+    jid        TEXT NOT NULL,
+    presence   BLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (contact, jid)
+);
+
+-- roster_items
+
+CREATE TABLE IF NOT EXISTS roster_items (
+    username     TEXT NOT NULL,
+    jid          TEXT NOT NULL,
+    name         TEXT NOT NULL,
+    subscription TEXT NOT NULL,
+    groups       TEXT, -- JSON array
+    ask          BOOLEAN NOT NULL,
+    version      INTEGER NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (username, jid)
+);
+
+-- roster_versions
+
+CREATE TABLE IF NOT EXISTS roster_versions (
+    username   TEXT PRIMARY KEY,
+    ver        INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- vcards
+
+CREATE TABLE IF NOT EXISTS vcards (
+    username   TEXT PRIMARY KEY,
+    vcard      BLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- scheduled_stanzas
+
+CREATE TABLE IF NOT EXISTS scheduled_stanzas (
+    id         TEXT PRIMARY KEY,
+    username   TEXT NOT NULL,
+    stanza     BLOB NOT NULL,
+    due_at     INTEGER NOT NULL, -- unix nanoseconds
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS i_scheduled_stanzas_username ON scheduled_stanzas(username);
+CREATE INDEX IF NOT EXISTS i_scheduled_stanzas_due_at ON scheduled_stanzas(due_at);
+
+-- account_flags
+
+CREATE TABLE IF NOT EXISTS account_flags (
+    username   TEXT NOT NULL,
+    flag       TEXT NOT NULL,
+    enabled    BOOLEAN NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (username, flag)
+);
+
+-- stream_queues
+
+CREATE TABLE IF NOT EXISTS stream_queues (
+    instance_id  TEXT NOT NULL,
+    queue_key    TEXT NOT NULL,
+    queue        BLOB NOT NULL,
+    persisted_at INTEGER NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (instance_id, queue_key)
+);
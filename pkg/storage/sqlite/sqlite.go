@@ -0,0 +1,145 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type conn interface {
+	execer
+	queryer
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type rowsScanner interface {
+	rowScanner
+	Next() bool
+}
+
+type txKey struct{}
+
+func withTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+func txFromContext(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// ctxConn routes context aware operations through the transaction bound to the context, if any.
+// Otherwise, read-only queries are served by the reader connection pool, and any other statement
+// is serialized through the writer connection.
+type ctxConn struct {
+	wdb *sql.DB
+	rdb *sql.DB
+}
+
+func (c *ctxConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return c.dbFor(query).Exec(query, args...)
+}
+
+func (c *ctxConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.connFor(ctx, query).ExecContext(ctx, query, args...)
+}
+
+func (c *ctxConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return c.dbFor(query).Query(query, args...)
+}
+
+func (c *ctxConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.connFor(ctx, query).QueryContext(ctx, query, args...)
+}
+
+func (c *ctxConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	return c.dbFor(query).QueryRow(query, args...)
+}
+
+func (c *ctxConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.connFor(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+func (c *ctxConn) connFor(ctx context.Context, query string) conn {
+	if tx := txFromContext(ctx); tx != nil {
+		return tx
+	}
+	return c.dbFor(query)
+}
+
+func (c *ctxConn) dbFor(query string) *sql.DB {
+	if isReadOnly(query) {
+		return c.rdb
+	}
+	return c.wdb // 'INSERT ... RETURNING' statements are queries too
+}
+
+func isReadOnly(query string) bool {
+	return strings.HasPrefix(strings.TrimSpace(query), "SELECT")
+}
+
+// stringList encodes a string slice as a JSON array column value.
+// Empty slices are stored as NULL.
+func stringList(ss []string) (interface{}, error) {
+	if len(ss) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(ss)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// scanStringList returns a destination that decodes a JSON array column value into ss.
+func scanStringList(ss *[]string) sql.Scanner {
+	return &stringListScanner{ss: ss}
+}
+
+type stringListScanner struct {
+	ss *[]string
+}
+
+func (s *stringListScanner) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*s.ss = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), s.ss)
+	case []byte:
+		return json.Unmarshal(v, s.ss)
+	default:
+		return fmt.Errorf("sqlite: unsupported string list type: %T", src)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	kitlog "github.com/go-kit/log"
+	"github.com/golang/protobuf/proto"
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+)
+
+const streamQueuesTableName = "stream_queues"
+
+type sqliteStreamRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *sqliteStreamRep) UpsertStreamQueue(ctx context.Context, queue *streammodel.Queue) error {
+	b, err := proto.Marshal(queue)
+	if err != nil {
+		return err
+	}
+	q := sqb.Insert(streamQueuesTableName).
+		Columns("instance_id", "queue_key", "queue", "persisted_at").
+		Values(queue.InstanceId, queue.Key, b, queue.GetPersistedAt().AsTime().UnixNano()).
+		Suffix("ON CONFLICT (instance_id, queue_key) DO UPDATE SET queue = excluded.queue, persisted_at = excluded.persisted_at")
+
+	_, err = q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteStreamRep) FetchStreamQueues(ctx context.Context, instanceID string) ([]*streammodel.Queue, error) {
+	q := sqb.Select("queue").
+		From(streamQueuesTableName).
+		Where(sq.Eq{"instance_id": instanceID})
+
+	rows, err := q.RunWith(r.conn).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(rows, r.logger)
+
+	var retVal []*streammodel.Queue
+	for rows.Next() {
+		var b []byte
+		if err := rows.Scan(&b); err != nil {
+			return nil, err
+		}
+		var sq streammodel.Queue
+		if err := proto.Unmarshal(b, &sq); err != nil {
+			return nil, err
+		}
+		retVal = append(retVal, &sq)
+	}
+	return retVal, nil
+}
+
+func (r *sqliteStreamRep) DeleteStreamQueues(ctx context.Context, instanceID string) error {
+	_, err := sqb.Delete(streamQueuesTableName).
+		Where(sq.Eq{"instance_id": instanceID}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteStreamRep) DeleteExpiredStreamQueues(ctx context.Context, t time.Time) error {
+	_, err := sqb.Delete(streamQueuesTableName).
+		Where(sq.Lt{"persisted_at": t.UnixNano()}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
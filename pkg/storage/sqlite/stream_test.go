@@ -0,0 +1,74 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	streammodel "github.com/ortuman/jackal/pkg/model/stream"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSQLite_StreamQueues(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	// when
+	require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "k1", InstanceId: "i1", Jid: "ortuman@jackal.im/yard"}))
+	require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "k2", InstanceId: "i1", Jid: "noelia@jackal.im/balcony"}))
+	require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "k3", InstanceId: "i2", Jid: "romeo@jackal.im/garden"}))
+
+	qs, err := rep.FetchStreamQueues(context.Background(), "i1")
+
+	// then
+	require.NoError(t, err)
+	require.Len(t, qs, 2)
+
+	require.NoError(t, rep.DeleteStreamQueues(context.Background(), "i1"))
+
+	qs, err = rep.FetchStreamQueues(context.Background(), "i1")
+	require.NoError(t, err)
+	require.Len(t, qs, 0)
+}
+
+func TestSQLite_DeleteExpiredStreamQueues(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	now := time.Now()
+	require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "k1", InstanceId: "i1", PersistedAt: timestamppb.New(now.Add(-time.Hour))}))
+	require.NoError(t, rep.UpsertStreamQueue(context.Background(), &streammodel.Queue{Key: "k2", InstanceId: "i2", PersistedAt: timestamppb.New(now)}))
+
+	// when
+	err := rep.DeleteExpiredStreamQueues(context.Background(), now.Add(-time.Minute))
+
+	// then
+	require.NoError(t, err)
+
+	qs, err := rep.FetchStreamQueues(context.Background(), "i1")
+	require.NoError(t, err)
+	require.Len(t, qs, 0)
+
+	qs, err = rep.FetchStreamQueues(context.Background(), "i2")
+	require.NoError(t, err)
+	require.Len(t, qs, 1)
+}
@@ -0,0 +1,53 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"database/sql"
+
+	"github.com/ortuman/jackal/pkg/storage/repository"
+)
+
+type repTx struct {
+	repository.User
+	repository.Last
+	repository.Capabilities
+	repository.Offline
+	repository.BlockList
+	repository.Private
+	repository.Roster
+	repository.VCard
+	repository.Scheduled
+	repository.AccountSettings
+	repository.Stream
+	repository.Locker
+}
+
+func newRepTx(tx *sql.Tx, locker *sqliteLocker) *repTx {
+	return &repTx{
+		User:            &sqliteUserRep{conn: tx},
+		Last:            &sqliteLastRep{conn: tx},
+		Capabilities:    &sqliteCapabilitiesRep{conn: tx},
+		Offline:         &sqliteOfflineRep{conn: tx},
+		BlockList:       &sqliteBlockListRep{conn: tx},
+		Private:         &sqlitePrivateRep{conn: tx},
+		Roster:          &sqliteRosterRep{conn: tx},
+		VCard:           &sqliteVCardRep{conn: tx},
+		Scheduled:       &sqliteScheduledRep{conn: tx},
+		AccountSettings: &sqliteAccountSettingsRep{conn: tx},
+		Stream:          &sqliteStreamRep{conn: tx},
+		Locker:          locker,
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"database/sql"
+
+	kitlog "github.com/go-kit/log"
+
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const (
+	usersTableName = "users"
+)
+
+type sqliteUserRep struct {
+	conn   conn
+	logger kitlog.Logger
+}
+
+func (r *sqliteUserRep) UpsertUser(ctx context.Context, user *usermodel.User) error {
+	cols := []string{
+		"username",
+		"h_sha_1",
+		"h_sha_256",
+		"h_sha_512",
+		"h_sha3_512",
+		"salt",
+		"iteration_count",
+		"pepper_id",
+	}
+	vals := []interface{}{
+		user.Username,
+		user.Scram.Sha1,
+		user.Scram.Sha256,
+		user.Scram.Sha512,
+		user.Scram.Sha3512,
+		user.Scram.Salt,
+		user.Scram.IterationCount,
+		user.Scram.PepperId,
+	}
+	q := sqb.Insert(usersTableName).
+		Columns(cols...).
+		Values(vals...).
+		Suffix("ON CONFLICT (username) DO UPDATE SET h_sha_1 = excluded.h_sha_1, h_sha_256 = excluded.h_sha_256, h_sha_512 = excluded.h_sha_512, h_sha3_512 = excluded.h_sha3_512, salt = excluded.salt, iteration_count = excluded.iteration_count, pepper_id = excluded.pepper_id")
+
+	_, err := q.RunWith(r.conn).ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteUserRep) DeleteUser(ctx context.Context, username string) error {
+	_, err := sqb.Delete(usersTableName).
+		Where(sq.Eq{"username": username}).
+		RunWith(r.conn).
+		ExecContext(ctx)
+	return err
+}
+
+func (r *sqliteUserRep) FetchUser(ctx context.Context, username string) (*usermodel.User, error) {
+	var usr usermodel.User
+	usr.Scram = &usermodel.Scram{}
+
+	cols := []string{
+		"username",
+		"h_sha_1",
+		"h_sha_256",
+		"h_sha_512",
+		"h_sha3_512",
+		"salt",
+		"iteration_count",
+		"pepper_id",
+	}
+	q := sqb.Select(cols...).
+		From(usersTableName).
+		Where(sq.Eq{"username": username})
+
+	err := q.RunWith(r.conn).
+		QueryRowContext(ctx).
+		Scan(
+			&usr.Username,
+			&usr.Scram.Sha1,
+			&usr.Scram.Sha256,
+			&usr.Scram.Sha512,
+			&usr.Scram.Sha3512,
+			&usr.Scram.Salt,
+			&usr.Scram.IterationCount,
+			&usr.Scram.PepperId,
+		)
+	switch err {
+	case nil:
+		return &usr, nil
+	case sql.ErrNoRows:
+		return nil, nil
+	default:
+		return nil, err
+	}
+}
+
+func (r *sqliteUserRep) UserExists(ctx context.Context, username string) (bool, error) {
+	q := sqb.Select("COUNT(*)").
+		From(usersTableName).
+		Where(sq.Eq{"username": username})
+
+	var count int
+	err := q.RunWith(r.conn).QueryRowContext(ctx).Scan(&count)
+	switch err {
+	case nil:
+		return count > 0, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	usermodel "github.com/ortuman/jackal/pkg/model/user"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_UpsertAndFetchUser(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+	usr := &usermodel.User{
+		Username: "ortuman",
+		Scram: &usermodel.Scram{
+			Sha1:           "v1",
+			Sha256:         "v2",
+			Sha512:         "v3",
+			Sha3512:        "v4",
+			Salt:           "salt",
+			IterationCount: 1024,
+			PepperId:       "v1",
+		},
+	}
+
+	// when
+	err := rep.UpsertUser(context.Background(), usr)
+	require.NoError(t, err)
+
+	usr.Scram.Salt = "salt-2"
+	err = rep.UpsertUser(context.Background(), usr)
+	require.NoError(t, err)
+
+	fetched, err := rep.FetchUser(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	exists, err := rep.UserExists(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.NotNil(t, fetched)
+	require.Equal(t, "salt-2", fetched.Scram.Salt)
+	require.Equal(t, int64(1024), int64(fetched.Scram.IterationCount))
+	require.True(t, exists)
+
+	err = rep.DeleteUser(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	fetched, err = rep.FetchUser(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Nil(t, fetched)
+}
@@ -0,0 +1,54 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	rep := New(Config{
+		Path:         fmt.Sprintf("%s/test.sqlite", t.TempDir()),
+		BusyTimeout:  time.Second * 5,
+		MaxReadConns: 4,
+	}, kitlog.NewNopLogger())
+
+	require.NoError(t, rep.Start(context.Background()))
+	t.Cleanup(func() { _ = rep.Stop(context.Background()) })
+
+	return rep
+}
+
+func testMessageStanza(body string) *stravaganza.Message {
+	b := stravaganza.NewMessageBuilder()
+	b.WithAttribute("from", "noelia@jackal.im/yard")
+	b.WithAttribute("to", "ortuman@jackal.im/balcony")
+	b.WithChild(
+		stravaganza.NewBuilder("body").
+			WithText(body).
+			Build(),
+	)
+	msg, _ := b.BuildMessage()
+	return msg
+}
@@ -0,0 +1,51 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqliterepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite_UpsertAndFetchVCard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	rep := setupRepository(t)
+
+	vCard := stravaganza.NewBuilder("vCard").
+		WithAttribute(stravaganza.Namespace, "vcard-temp").
+		WithChild(stravaganza.NewBuilder("FN").WithText("Miguel Ángel").Build()).
+		Build()
+
+	// when
+	require.NoError(t, rep.UpsertVCard(context.Background(), vCard, "ortuman"))
+
+	fetched, err := rep.FetchVCard(context.Background(), "ortuman")
+	require.NoError(t, err)
+
+	// then
+	require.NotNil(t, fetched)
+	require.Equal(t, "Miguel Ángel", fetched.Child("FN").Text())
+
+	require.NoError(t, rep.DeleteVCard(context.Background(), "ortuman"))
+
+	fetched, err = rep.FetchVCard(context.Background(), "ortuman")
+	require.NoError(t, err)
+	require.Nil(t, fetched)
+}
@@ -23,11 +23,13 @@ import (
 	measuredrepository "github.com/ortuman/jackal/pkg/storage/measured"
 	pgsqlrepository "github.com/ortuman/jackal/pkg/storage/pgsql"
 	"github.com/ortuman/jackal/pkg/storage/repository"
+	sqliterepository "github.com/ortuman/jackal/pkg/storage/sqlite"
 )
 
 const (
 	boltDBRepositoryType = "boltdb"
 	pgSQLRepositoryType  = "pgsql"
+	sqliteRepositoryType = "sqlite"
 )
 
 // Config contains generic storage configuration.
@@ -35,6 +37,7 @@ type Config struct {
 	Type   string                  `fig:"type" default:"boltdb"`
 	PgSQL  pgsqlrepository.Config  `fig:"pgsql"`
 	BoltDB boltdb.Config           `fig:"boltdb"`
+	SQLite sqliterepository.Config `fig:"sqlite"`
 	Cache  cachedrepository.Config `fig:"cache"`
 }
 
@@ -49,6 +52,9 @@ func New(cfg Config, logger kitlog.Logger) (repository.Repository, error) {
 	case boltDBRepositoryType:
 		rep = boltdb.New(cfg.BoltDB, logger)
 
+	case sqliteRepositoryType:
+		rep = sqliterepository.New(cfg.SQLite, logger)
+
 	default:
 		return nil, fmt.Errorf("unrecognized repository type: %s", cfg.Type)
 	}
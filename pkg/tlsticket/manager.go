@@ -0,0 +1,354 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsticket
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/ortuman/jackal/pkg/cluster/kv"
+	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
+)
+
+const ticketKeyPrefix = "tk://"
+
+// Config contains TLS session tickets configuration.
+type Config struct {
+	// Enabled tells whether cluster shared session ticket keys should be used.
+	Enabled bool `fig:"enabled"`
+
+	// RotationInterval defines how often a new ticket key is generated.
+	RotationInterval time.Duration `fig:"rotation_interval" default:"12h"`
+
+	// GracePeriod defines how long a rotated ticket key is still accepted to resume sessions.
+	GracePeriod time.Duration `fig:"grace_period" default:"24h"`
+}
+
+// Manager keeps a rotating set of TLS session ticket keys shared across the cluster,
+// so that sessions can be resumed regardless of the instance a client reconnects to.
+type Manager struct {
+	cfg    Config
+	kv     kv.KV
+	logger kitlog.Logger
+	tmFn   func() time.Time
+
+	mu   sync.RWMutex
+	keys map[int64][32]byte
+	cfgs []*tls.Config
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	stopCh    chan struct{}
+}
+
+// NewManager returns a new initialized Manager instance.
+func NewManager(cfg Config, kv kv.KV, logger kitlog.Logger) *Manager {
+	ctx, cancelFn := context.WithCancel(context.Background())
+	return &Manager{
+		cfg:       cfg,
+		kv:        kv,
+		logger:    logger,
+		tmFn:      time.Now,
+		keys:      make(map[int64][32]byte),
+		ctx:       ctx,
+		ctxCancel: cancelFn,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Register sets current session ticket keys into cfg and keeps them updated on every rotation.
+func (m *Manager) Register(cfg *tls.Config) {
+	if !m.cfg.Enabled {
+		return
+	}
+	m.mu.Lock()
+	m.cfgs = append(m.cfgs, cfg)
+	ks := m.sortedKeys()
+	m.mu.Unlock()
+
+	if len(ks) > 0 {
+		cfg.SetSessionTicketKeys(ks)
+	}
+}
+
+// Apply sets current session ticket keys into cfg.
+func (m *Manager) Apply(cfg *tls.Config) {
+	if !m.cfg.Enabled {
+		return
+	}
+	m.mu.RLock()
+	ks := m.sortedKeys()
+	m.mu.RUnlock()
+
+	if len(ks) > 0 {
+		cfg.SetSessionTicketKeys(ks)
+	}
+}
+
+// Start loads shared ticket keys and starts rotating them.
+func (m *Manager) Start(ctx context.Context) error {
+	if !m.cfg.Enabled {
+		close(m.stopCh)
+		return nil
+	}
+	if m.cfg.RotationInterval <= 0 {
+		return errors.New("tlsticket: rotation interval must be positive")
+	}
+	vs, err := m.kv.GetPrefix(ctx, ticketKeyPrefix)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	for k, v := range vs {
+		epoch, key, err := decodeTicketKey(k, v)
+		if err != nil {
+			level.Warn(m.logger).Log("msg", "failed to decode TLS ticket key", "err", err)
+			continue
+		}
+		m.keys[epoch] = key
+	}
+	m.mu.Unlock()
+
+	if err := m.rotate(ctx); err != nil {
+		return err
+	}
+	go m.loop()
+
+	level.Info(m.logger).Log("msg", "started TLS session ticket key manager",
+		"rotation_interval", m.cfg.RotationInterval,
+		"grace_period", m.cfg.GracePeriod,
+	)
+	return nil
+}
+
+// Stop stops rotating ticket keys.
+func (m *Manager) Stop(_ context.Context) error {
+	m.ctxCancel()
+	<-m.stopCh
+
+	level.Info(m.logger).Log("msg", "stopped TLS session ticket key manager")
+	return nil
+}
+
+func (m *Manager) loop() {
+	defer close(m.stopCh)
+
+	wCh := m.kv.Watch(m.ctx, ticketKeyPrefix, false)
+
+	tm := time.NewTimer(m.untilNextRotation())
+	defer tm.Stop()
+
+	for {
+		select {
+		case wResp, ok := <-wCh:
+			if !ok {
+				wCh = nil // no more changes to watch
+				continue
+			}
+			if err := wResp.Err; err != nil {
+				level.Warn(m.logger).Log("msg", "error occurred watching TLS ticket keys", "err", err)
+				continue
+			}
+			m.processKVEvents(wResp.Events)
+
+		case <-tm.C:
+			if err := m.rotate(m.ctx); err != nil {
+				level.Warn(m.logger).Log("msg", "failed to rotate TLS ticket key", "err", err)
+			}
+			tm.Reset(m.untilNextRotation())
+
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *Manager) rotate(ctx context.Context) error {
+	epoch := m.epoch()
+
+	m.mu.RLock()
+	_, ok := m.keys[epoch]
+	m.mu.RUnlock()
+
+	if !ok {
+		key, err := m.fetchOrCreateKey(ctx, epoch)
+		if err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.keys[epoch] = key
+		m.mu.Unlock()
+	}
+	// discard expired keys
+	var expired []int64
+
+	minEpoch := m.minEpoch(epoch)
+
+	m.mu.Lock()
+	for e := range m.keys {
+		if e < minEpoch {
+			delete(m.keys, e)
+			expired = append(expired, e)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, e := range expired {
+		if err := m.kv.Del(ctx, ticketKeyKey(e)); err != nil {
+			return err
+		}
+	}
+	m.applyKeys()
+	return nil
+}
+
+func (m *Manager) fetchOrCreateKey(ctx context.Context, epoch int64) ([32]byte, error) {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return key, err
+	}
+	// key is created atomically, so that a single one is generated per epoch across the whole cluster
+	kvKey := ticketKeyKey(epoch)
+	created, err := m.kv.CompareAndSwap(ctx, kvKey, nil, hex.EncodeToString(key[:]), 0)
+	if err != nil {
+		return key, err
+	}
+	if created {
+		level.Info(m.logger).Log("msg", "generated TLS ticket key", "epoch", epoch)
+		return key, nil
+	}
+	// another instance already generated current epoch key
+	v, err := m.kv.Get(ctx, kvKey)
+	if err != nil {
+		return key, err
+	}
+	if v == nil {
+		return key, fmt.Errorf("tlsticket: key not found for epoch %d", epoch)
+	}
+	_, key, err = decodeTicketKey(kvKey, v)
+	return key, err
+}
+
+func (m *Manager) processKVEvents(kvEvents []kvtypes.WatchEvent) {
+	minEpoch := m.minEpoch(m.epoch())
+
+	m.mu.Lock()
+	for _, ev := range kvEvents {
+		switch ev.Type {
+		case kvtypes.Put:
+			epoch, key, err := decodeTicketKey(ev.Key, ev.Val)
+			if err != nil {
+				level.Warn(m.logger).Log("msg", "failed to decode TLS ticket key", "err", err)
+				continue
+			}
+			if epoch < minEpoch {
+				continue
+			}
+			m.keys[epoch] = key
+
+		case kvtypes.Del:
+			epoch, err := decodeTicketKeyEpoch(ev.Key)
+			if err != nil {
+				continue
+			}
+			delete(m.keys, epoch)
+		}
+	}
+	m.mu.Unlock()
+
+	m.applyKeys()
+}
+
+func (m *Manager) applyKeys() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ks := m.sortedKeys()
+	if len(ks) == 0 {
+		return
+	}
+	for _, cfg := range m.cfgs {
+		cfg.SetSessionTicketKeys(ks)
+	}
+}
+
+// sortedKeys returns ticket keys from newest to oldest, being the first one used to issue new tickets.
+func (m *Manager) sortedKeys() [][32]byte {
+	epochs := make([]int64, 0, len(m.keys))
+	for e := range m.keys {
+		epochs = append(epochs, e)
+	}
+	sort.Slice(epochs, func(i, j int) bool { return epochs[i] > epochs[j] })
+
+	ks := make([][32]byte, 0, len(epochs))
+	for _, e := range epochs {
+		ks = append(ks, m.keys[e])
+	}
+	return ks
+}
+
+func (m *Manager) epoch() int64 {
+	return m.tmFn().UnixNano() / int64(m.cfg.RotationInterval)
+}
+
+func (m *Manager) minEpoch(epoch int64) int64 {
+	graceEpochs := int64(m.cfg.GracePeriod / m.cfg.RotationInterval)
+	if m.cfg.GracePeriod%m.cfg.RotationInterval != 0 {
+		graceEpochs++
+	}
+	return epoch - graceEpochs
+}
+
+func (m *Manager) untilNextRotation() time.Duration {
+	next := time.Unix(0, (m.epoch()+1)*int64(m.cfg.RotationInterval))
+	return next.Sub(m.tmFn())
+}
+
+func ticketKeyKey(epoch int64) string {
+	return ticketKeyPrefix + strconv.FormatInt(epoch, 10)
+}
+
+func decodeTicketKey(k string, v []byte) (int64, [32]byte, error) {
+	var key [32]byte
+
+	epoch, err := decodeTicketKeyEpoch(k)
+	if err != nil {
+		return 0, key, err
+	}
+	b, err := hex.DecodeString(string(v))
+	if err != nil {
+		return 0, key, err
+	}
+	if len(b) != len(key) {
+		return 0, key, fmt.Errorf("tlsticket: invalid key length: %d", len(b))
+	}
+	copy(key[:], b)
+	return epoch, key, nil
+}
+
+func decodeTicketKeyEpoch(k string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(k, ticketKeyPrefix), 10, 64)
+}
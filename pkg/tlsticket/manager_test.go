@@ -0,0 +1,363 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tlsticket
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kitlog "github.com/go-kit/log"
+	kvtypes "github.com/ortuman/jackal/pkg/cluster/kv/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_Rotation(t *testing.T) {
+	// given
+	kvMock, store := newKVMock()
+
+	clk := &testClock{tm: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	m := newManager(kvMock)
+	m.tmFn = clk.now
+
+	// when
+	err := m.Start(context.Background())
+	require.NoError(t, err)
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	k0 := m.sortedKeys()[0]
+
+	clk.add(time.Hour)
+	err0 := m.rotate(context.Background())
+
+	ks0 := m.sortedKeys()
+	kvLen0 := store.len()
+
+	clk.add(time.Hour * 3)
+	err1 := m.rotate(context.Background())
+
+	ks1 := m.sortedKeys()
+	kvLen1 := store.len()
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+
+	require.Len(t, ks0, 2)
+	require.Equal(t, k0, ks0[1])
+	require.NotEqual(t, k0, ks0[0])
+	require.Equal(t, 2, kvLen0)
+
+	require.Len(t, ks1, 1)
+	require.NotContains(t, ks1, k0)
+	require.Equal(t, 1, kvLen1)
+}
+
+func TestManager_SharedKeys(t *testing.T) {
+	// given
+	kvMock, _ := newKVMock()
+
+	clk := &testClock{tm: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	m0 := newManager(kvMock)
+	m0.tmFn = clk.now
+
+	m1 := newManager(kvMock)
+	m1.tmFn = clk.now
+
+	// when
+	require.NoError(t, m0.Start(context.Background()))
+	defer func() { _ = m0.Stop(context.Background()) }()
+
+	require.NoError(t, m1.Start(context.Background()))
+	defer func() { _ = m1.Stop(context.Background()) }()
+
+	// then
+	require.Len(t, kvMock.CompareAndSwapCalls(), 1)
+	require.Equal(t, m0.sortedKeys(), m1.sortedKeys())
+}
+
+func TestManager_ConcurrentRotation(t *testing.T) {
+	// given
+	kvMock, store := newKVMock()
+
+	// hold back key creation until both managers generated their own candidate key
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	casFn := kvMock.CompareAndSwapFunc
+	kvMock.CompareAndSwapFunc = func(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+		wg.Done()
+		wg.Wait()
+		return casFn(ctx, key, prevValue, value, ttl)
+	}
+	clk := &testClock{tm: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	m0 := newManager(kvMock)
+	m0.tmFn = clk.now
+
+	m1 := newManager(kvMock)
+	m1.tmFn = clk.now
+
+	// when
+	errCh := make(chan error, 2)
+	for _, m := range []*Manager{m0, m1} {
+		m := m
+		go func() { errCh <- m.rotate(context.Background()) }()
+	}
+	err0 := <-errCh
+	err1 := <-errCh
+
+	// then
+	require.NoError(t, err0)
+	require.NoError(t, err1)
+
+	require.Equal(t, 1, store.len())
+	require.Len(t, m0.sortedKeys(), 1)
+	require.Equal(t, m0.sortedKeys(), m1.sortedKeys())
+}
+
+func TestManager_ProcessKVEvents(t *testing.T) {
+	// given
+	kvMock, _ := newKVMock()
+
+	clk := &testClock{tm: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	m := newManager(kvMock)
+	m.tmFn = clk.now
+
+	srvCfg := &tls.Config{}
+	m.Register(srvCfg)
+
+	epoch := m.epoch()
+
+	// when
+	m.processKVEvents([]kvtypes.WatchEvent{
+		{Type: kvtypes.Put, Key: ticketKeyKey(epoch - 1), Val: []byte(strings.Repeat("ab", 32))},
+		{Type: kvtypes.Put, Key: ticketKeyKey(epoch), Val: []byte(strings.Repeat("cd", 32))},
+		{Type: kvtypes.Put, Key: ticketKeyKey(epoch - 10), Val: []byte(strings.Repeat("ef", 32))}, // expired
+	})
+	ks0 := m.sortedKeys()
+
+	m.processKVEvents([]kvtypes.WatchEvent{
+		{Type: kvtypes.Del, Key: ticketKeyKey(epoch - 1)},
+	})
+	ks1 := m.sortedKeys()
+
+	// then
+	require.Len(t, ks0, 2)
+	require.Equal(t, byte(0xcd), ks0[0][0])
+	require.Equal(t, byte(0xab), ks0[1][0])
+
+	require.Len(t, ks1, 1)
+	require.Equal(t, byte(0xcd), ks1[0][0])
+}
+
+func TestManager_ResumeAfterRotation(t *testing.T) {
+	// given
+	kvMock, _ := newKVMock()
+
+	clk := &testClock{tm: time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	m := newManager(kvMock)
+	m.tmFn = clk.now
+
+	require.NoError(t, m.Start(context.Background()))
+	defer func() { _ = m.Stop(context.Background()) }()
+
+	cert := selfSignedCertificate(t)
+	cliCfg := &tls.Config{
+		ServerName:         "localhost",
+		InsecureSkipVerify: true,
+		ClientSessionCache: tls.NewLRUClientSessionCache(1),
+	}
+	newServerConfig := func() *tls.Config {
+		srvCfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+		m.Apply(srvCfg)
+		return srvCfg
+	}
+
+	// when
+	resumed0 := handshake(t, newServerConfig(), cliCfg)
+
+	// ticket issued before rotation is accepted within the grace period
+	clk.add(time.Hour)
+	require.NoError(t, m.rotate(context.Background()))
+
+	resumed1 := handshake(t, newServerConfig(), cliCfg)
+
+	// ticket issued before rotation is rejected once grace period elapsed
+	clk.add(time.Hour * 4)
+	require.NoError(t, m.rotate(context.Background()))
+
+	resumed2 := handshake(t, newServerConfig(), cliCfg)
+
+	// then
+	require.False(t, resumed0)
+	require.True(t, resumed1)
+	require.False(t, resumed2)
+}
+
+func newManager(kv kvStorage) *Manager {
+	return NewManager(Config{
+		Enabled:          true,
+		RotationInterval: time.Hour,
+		GracePeriod:      time.Hour * 2,
+	}, kv, kitlog.NewNopLogger())
+}
+
+type testClock struct {
+	mu sync.Mutex
+	tm time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tm
+}
+
+func (c *testClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tm = c.tm.Add(d)
+}
+
+type kvStore struct {
+	mu sync.Mutex
+	m  map[string][]byte
+}
+
+func (s *kvStore) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.m)
+}
+
+func newKVMock() (*kvMock, *kvStore) {
+	store := &kvStore{m: make(map[string][]byte)}
+
+	kvMock := &kvMock{}
+	kvMock.PutFunc = func(ctx context.Context, key string, value string) error {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		store.m[key] = []byte(value)
+		return nil
+	}
+	kvMock.GetFunc = func(ctx context.Context, key string) ([]byte, error) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return store.m[key], nil
+	}
+	kvMock.CompareAndSwapFunc = func(ctx context.Context, key string, prevValue []byte, value string, ttl time.Duration) (bool, error) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		curValue, ok := store.m[key]
+		if (prevValue == nil && ok) || (prevValue != nil && string(curValue) != string(prevValue)) {
+			return false, nil
+		}
+		store.m[key] = []byte(value)
+		return true, nil
+	}
+	kvMock.GetPrefixFunc = func(ctx context.Context, prefix string) (map[string][]byte, error) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		res := make(map[string][]byte)
+		for k, v := range store.m {
+			if strings.HasPrefix(k, prefix) {
+				res[k] = v
+			}
+		}
+		return res, nil
+	}
+	kvMock.DelFunc = func(ctx context.Context, key string) error {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		delete(store.m, key)
+		return nil
+	}
+	kvMock.WatchFunc = func(ctx context.Context, prefix string, withPrevVal bool) <-chan kvtypes.WatchResp {
+		wCh := make(chan kvtypes.WatchResp)
+		close(wCh)
+		return wCh
+	}
+	return kvMock, store
+}
+
+func handshake(t *testing.T, srvCfg, cliCfg *tls.Config) bool {
+	t.Helper()
+
+	srvConn, cliConn := net.Pipe()
+
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() { _ = srvConn.Close() }()
+
+		srv := tls.Server(srvConn, srvCfg)
+
+		if err := srv.Handshake(); err != nil {
+			errCh <- err
+			return
+		}
+		_, err := srv.Write([]byte{1})
+		errCh <- err
+	}()
+
+	defer func() { _ = cliConn.Close() }()
+
+	cli := tls.Client(cliConn, cliCfg)
+
+	require.NoError(t, cli.Handshake())
+
+	// read server payload so that any session ticket gets processed
+	_, err := cli.Read(make([]byte, 1))
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+
+	return cli.ConnectionState().DidResume
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	require.NoError(t, err)
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  priv,
+	}
+}
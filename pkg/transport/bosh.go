@@ -0,0 +1,243 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/ortuman/jackal/pkg/util/ratelimiter"
+	"golang.org/x/time/rate"
+)
+
+// BOSHTransport is a stream transport whose input is fed by BOSH HTTP requests (XEP-0124, XEP-0206),
+// and whose flushed output is queued until delivered in the body of the next HTTP response.
+type BOSHTransport struct {
+	pr        *io.PipeReader
+	pw        *io.PipeWriter
+	lr        *ratelimiter.Reader
+	rd        io.Reader
+	peerCerts []*x509.Certificate
+
+	mu         sync.Mutex
+	wb         bytes.Buffer
+	out        bytes.Buffer
+	pendingCh  chan struct{}
+	doneCh     chan struct{}
+	closed     bool
+	held       int
+	inactivity time.Duration
+	inactTm    *time.Timer
+	kaHnd      func()
+}
+
+// NewBOSHTransport creates a BOSH class stream transport.
+// Once no HTTP request has been held for inactivity time, transport keep-alive deadline handler will be invoked.
+func NewBOSHTransport(inactivity time.Duration, peerCerts []*x509.Certificate) *BOSHTransport {
+	pr, pw := io.Pipe()
+	lr := ratelimiter.NewReader(pr)
+	return &BOSHTransport{
+		pr:         pr,
+		pw:         pw,
+		lr:         lr,
+		rd:         bufio.NewReaderSize(lr, defaultReadBufferSize),
+		peerCerts:  peerCerts,
+		pendingCh:  make(chan struct{}, 1),
+		doneCh:     make(chan struct{}),
+		inactivity: inactivity,
+	}
+}
+
+// Feed makes p available to transport readers, blocking until it has been completely read.
+func (b *BOSHTransport) Feed(p []byte) error {
+	_, err := b.pw.Write(p)
+	return err
+}
+
+// Hold accounts an HTTP request held by the connection manager.
+func (b *BOSHTransport) Hold() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.held++
+	if b.inactTm != nil {
+		b.inactTm.Stop()
+		b.inactTm = nil
+	}
+}
+
+// Release accounts a held HTTP request that has been responded.
+// Inactivity period starts as soon as there are no held requests.
+func (b *BOSHTransport) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.held--
+	if b.held > 0 || b.closed || b.inactivity <= 0 {
+		return
+	}
+	b.inactTm = time.AfterFunc(b.inactivity, b.inactivityTimeout)
+}
+
+// Pending returns a channel that's signaled whenever flushed output is ready to be taken.
+func (b *BOSHTransport) Pending() <-chan struct{} {
+	return b.pendingCh
+}
+
+// TakeOutput returns and discards all flushed output.
+func (b *BOSHTransport) TakeOutput() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.out.Len() == 0 {
+		return nil
+	}
+	p := make([]byte, b.out.Len())
+	copy(p, b.out.Bytes())
+	b.out.Reset()
+	return p
+}
+
+// Done returns a channel that's closed once the transport is closed.
+func (b *BOSHTransport) Done() <-chan struct{} {
+	return b.doneCh
+}
+
+func (b *BOSHTransport) Read(p []byte) (n int, err error) {
+	return b.rd.Read(p)
+}
+
+func (b *BOSHTransport) ReadByte() (byte, error) {
+	var p [1]byte
+	n, err := b.rd.Read(p[:])
+	switch {
+	case n == 1 && err == nil:
+		return p[0], nil
+	case err != nil:
+		return 0, err
+	default:
+		return 0, nil
+	}
+}
+
+func (b *BOSHTransport) Write(p []byte) (n int, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.wb.Write(p)
+}
+
+func (b *BOSHTransport) WriteString(str string) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.wb.WriteString(str)
+}
+
+func (b *BOSHTransport) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	if b.inactTm != nil {
+		b.inactTm.Stop()
+	}
+	b.mu.Unlock()
+
+	close(b.doneCh)
+	_ = b.pr.Close()
+	return b.pw.Close()
+}
+
+func (b *BOSHTransport) Type() Type {
+	return BOSH
+}
+
+// Flush queues buffered data to be delivered in the next HTTP response.
+func (b *BOSHTransport) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.wb.Len() == 0 {
+		return errNoWriteFlush
+	}
+	_, _ = b.wb.WriteTo(&b.out)
+
+	select {
+	case b.pendingCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *BOSHTransport) SetReadRateLimiter(rLim *rate.Limiter) error {
+	b.lr.SetReadRateLimiter(rLim)
+	return nil
+}
+
+// SetWriteRateLimiter does nothing, as output is only handed over to client held HTTP requests.
+func (b *BOSHTransport) SetWriteRateLimiter(_ *rate.Limiter) error {
+	return nil
+}
+
+// SetWriteDeadline does nothing, as writes are never blocked on the underlying HTTP connection.
+func (b *BOSHTransport) SetWriteDeadline(_ time.Time) error {
+	return nil
+}
+
+// SetConnectDeadlineHandler does nothing, as BOSH sessions are created along with the first HTTP request.
+func (b *BOSHTransport) SetConnectDeadlineHandler(_ func()) {}
+
+func (b *BOSHTransport) SetKeepAliveDeadlineHandler(hnd func()) {
+	b.mu.Lock()
+	b.kaHnd = hnd
+	b.mu.Unlock()
+}
+
+// StartTLS does nothing, as BOSH connections are secured by the HTTP server (https).
+func (b *BOSHTransport) StartTLS(_ *tls.Config, _ bool) {}
+
+// EnableCompression does nothing, as stream compression is not available over BOSH.
+func (b *BOSHTransport) EnableCompression(_ compress.Method, _ compress.Level) {}
+
+// SupportsChannelBinding returns false, as a BOSH session may span multiple HTTP connections.
+func (b *BOSHTransport) SupportsChannelBinding() bool {
+	return false
+}
+
+func (b *BOSHTransport) ChannelBindingBytes(_ ChannelBindingMechanism) []byte {
+	return nil
+}
+
+func (b *BOSHTransport) PeerCertificates() []*x509.Certificate {
+	return b.peerCerts
+}
+
+func (b *BOSHTransport) inactivityTimeout() {
+	b.mu.Lock()
+	hnd := b.kaHnd
+	held := b.held
+	b.mu.Unlock()
+
+	if hnd != nil && held == 0 {
+		hnd()
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBOSH_FeedAndFlush(t *testing.T) {
+	// given
+	tr := NewBOSHTransport(time.Minute, nil)
+
+	// when
+	go func() { _ = tr.Feed([]byte(`<presence/>`)) }()
+
+	buf := make([]byte, 1024)
+	n, rdErr := tr.Read(buf)
+
+	_, _ = tr.WriteString(`<iq type='result' id='1'/>`)
+	_, _ = tr.WriteString(`<message/>`)
+	flushErr := tr.Flush()
+
+	// then
+	require.Nil(t, rdErr)
+	require.Equal(t, `<presence/>`, string(buf[:n]))
+
+	require.Nil(t, flushErr)
+	require.Equal(t, errNoWriteFlush, tr.Flush())
+
+	select {
+	case <-tr.Pending():
+	default:
+		require.Fail(t, "expected pending output")
+	}
+	require.Equal(t, `<iq type='result' id='1'/><message/>`, string(tr.TakeOutput()))
+	require.Nil(t, tr.TakeOutput())
+
+	require.Equal(t, BOSH, tr.Type())
+	require.False(t, tr.SupportsChannelBinding())
+}
+
+func TestBOSH_Inactivity(t *testing.T) {
+	// given
+	var expired int32
+
+	tr := NewBOSHTransport(time.Millisecond*50, nil)
+	tr.SetKeepAliveDeadlineHandler(func() {
+		atomic.StoreInt32(&expired, 1)
+	})
+
+	// when
+	tr.Hold()
+	time.Sleep(time.Millisecond * 100)
+	heldExpired := atomic.LoadInt32(&expired) == 1
+
+	tr.Release()
+	time.Sleep(time.Millisecond * 100)
+
+	// then
+	require.False(t, heldExpired) // never expires while holding requests
+	require.Equal(t, int32(1), atomic.LoadInt32(&expired))
+}
+
+func TestBOSH_Close(t *testing.T) {
+	// given
+	tr := NewBOSHTransport(time.Minute, nil)
+
+	// when
+	err := tr.Close()
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, tr.Close())
+
+	select {
+	case <-tr.Done():
+	default:
+		require.Fail(t, "expected closed transport")
+	}
+	require.NotNil(t, tr.Feed([]byte(`<presence/>`)))
+
+	_, rdErr := tr.Read(make([]byte, 16))
+	require.NotNil(t, rdErr)
+}
@@ -48,7 +48,54 @@ func (cl Level) String() string {
 	return ""
 }
 
+// Method represents a stream compression method.
+type Method int
+
+const (
+	// ZlibMethod represents 'zlib' stream compression method.
+	ZlibMethod Method = iota
+
+	// GzipMethod represents 'gzip' stream compression method.
+	GzipMethod
+)
+
+// Methods contains all supported compression methods, in order of preference.
+var Methods = []Method{ZlibMethod, GzipMethod}
+
+// String returns Method string representation.
+func (m Method) String() string {
+	switch m {
+	case ZlibMethod:
+		return "zlib"
+	case GzipMethod:
+		return "gzip"
+	}
+	return ""
+}
+
+// MethodFromString returns the compression method identified by str.
+// The returned bool value tells whether or not the method is supported.
+func MethodFromString(str string) (Method, bool) {
+	for _, m := range Methods {
+		if m.String() == str {
+			return m, true
+		}
+	}
+	return ZlibMethod, false
+}
+
 // Compressor represents a stream compression method.
 type Compressor interface {
 	io.ReadWriter
 }
+
+// NewCompressor returns a new stream compressor using method compression method and level compression level.
+// zlib compressor will be returned in case of unknown method.
+func NewCompressor(reader io.Reader, writer io.Writer, method Method, level Level) Compressor {
+	switch method {
+	case GzipMethod:
+		return NewGzipCompressor(reader, writer, level)
+	default:
+		return NewZlibCompressor(reader, writer, level)
+	}
+}
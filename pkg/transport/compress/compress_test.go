@@ -26,3 +26,22 @@ func TestTypeStrings(t *testing.T) {
 	require.Equal(t, "speed", SpeedCompression.String())
 	require.Equal(t, "", Level(99).String())
 }
+
+func TestMethodStrings(t *testing.T) {
+	require.Equal(t, "zlib", ZlibMethod.String())
+	require.Equal(t, "gzip", GzipMethod.String())
+	require.Equal(t, "", Method(99).String())
+
+	m, ok := MethodFromString("gzip")
+	require.True(t, ok)
+	require.Equal(t, GzipMethod, m)
+
+	_, ok = MethodFromString("lzw")
+	require.False(t, ok)
+}
+
+func TestNewCompressor(t *testing.T) {
+	require.IsType(t, &ZlibCompressor{}, NewCompressor(nil, nil, ZlibMethod, DefaultCompression))
+	require.IsType(t, &GzipCompressor{}, NewCompressor(nil, nil, GzipMethod, DefaultCompression))
+	require.IsType(t, &ZlibCompressor{}, NewCompressor(nil, nil, Method(99), DefaultCompression))
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// GzipCompressor represents gzip stream compressor.
+type GzipCompressor struct {
+	level int
+	w     io.Writer
+	r     io.Reader
+	zw    *gzip.Writer
+	zr    *gzip.Reader
+}
+
+// NewGzipCompressor returns a new gzip compression method.
+func NewGzipCompressor(reader io.Reader, writer io.Writer, level Level) *GzipCompressor {
+	z := &GzipCompressor{
+		w: writer,
+		r: reader,
+	}
+	switch level {
+	case DefaultCompression:
+		z.level = gzip.DefaultCompression
+	case BestCompression:
+		z.level = gzip.BestCompression
+	case SpeedCompression:
+		z.level = gzip.BestSpeed
+	default:
+		z.level = int(level)
+	}
+	return z
+}
+
+func (z *GzipCompressor) Write(p []byte) (int, error) {
+	if z.zw == nil {
+		zw, err := gzip.NewWriterLevel(z.w, z.level)
+		if err != nil {
+			return 0, err
+		}
+		z.zw = zw
+	}
+	defer func() { _ = z.zw.Flush() }()
+	return z.zw.Write(p)
+}
+
+func (z *GzipCompressor) Read(p []byte) (int, error) {
+	if z.zr == nil {
+		zr, err := gzip.NewReader(z.r)
+		if err != nil {
+			return 0, err
+		}
+		zr.Multistream(false)
+		z.zr = zr
+	}
+	return z.zr.Read(p)
+}
@@ -0,0 +1,70 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+	for _, level := range []Level{DefaultCompression, BestCompression, SpeedCompression} {
+		// given
+		pr, pw := io.Pipe()
+
+		wr := NewGzipCompressor(nil, pw, level)
+		rd := NewGzipCompressor(pr, nil, level)
+
+		stanzas := []string{
+			`<message to="noelia@jackal.im"><body>My lord, dispatch; read o'er these articles.</body></message>`,
+			`<presence><status>Neither, fair saint, if either thee dislike.</status></presence>`,
+		}
+
+		// when
+		go func() {
+			for _, stanza := range stanzas {
+				_, _ = wr.Write([]byte(stanza))
+			}
+		}()
+
+		// then
+		for _, stanza := range stanzas {
+			// every write is flushed, so it can be read on its own
+			b := make([]byte, len(stanza))
+			_, err := io.ReadFull(rd, b)
+			require.Nil(t, err)
+			require.Equal(t, stanza, string(b))
+		}
+		_ = pr.Close()
+	}
+}
+
+func TestGzipInvalidCompressionLevel(t *testing.T) {
+	compressor := NewGzipCompressor(new(bytes.Buffer), new(bytes.Buffer), Level(100))
+	_, err := compressor.Write([]byte("Failing!"))
+	require.NotNil(t, err)
+}
+
+func TestGzipInvalidInflate(t *testing.T) {
+	rBuf := new(bytes.Buffer)
+	rBuf.Write([]byte("this is garbage!"))
+	compressor := NewGzipCompressor(rBuf, nil, DefaultCompression)
+	_, err := ioutil.ReadAll(compressor)
+	require.NotNil(t, err)
+}
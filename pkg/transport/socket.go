@@ -30,7 +30,15 @@ import (
 	"golang.org/x/time/rate"
 )
 
-const readBufferSize = 4096
+const (
+	defaultReadBufferSize = 4096
+
+	defaultMaxPooledWriterSize = 64 * 1024
+
+	// 'tls-exporter' channel binding parameters (RFC 9266)
+	tlsExporterLabel  = "EXPORTER-Channel-Binding"
+	tlsExporterLength = 32
+)
 
 var errNoWriteFlush = errors.New("transport: flushing buffer before writing")
 
@@ -43,6 +51,7 @@ var bufWriterPool = sync.Pool{
 type socketTransport struct {
 	conn             *deadlineConn
 	lr               *ratelimiter.Reader
+	lw               *ratelimiter.Writer
 	rd               io.Reader
 	wr               io.Writer
 	bw               *bufio.Writer
@@ -50,21 +59,85 @@ type socketTransport struct {
 	supportsCb       bool
 	connectTimeout   time.Duration
 	keepAliveTimeout time.Duration
+	rdBufSize        int
+	maxPooledWrSize  int
+	flushDelay       time.Duration
+
+	mu       sync.Mutex
+	flushTm  *time.Timer
+	flushErr error
 }
 
 // NewSocketTransport creates a socket class stream transport.
-func NewSocketTransport(conn net.Conn, connectTimeout, keepAliveTimeout time.Duration) Transport {
+// Incoming data is read through a buffer of readBufferSize bytes. A zero value means a default 4KiB buffer.
+//
+// Writes not fitting into a pooled 4KiB writer are buffered into a writer large enough to hold them.
+// Buffered writers larger than maxPooledWriterSize bytes are discarded on release instead of being
+// returned to the shared writer pool. A zero value means a default 64KiB threshold.
+//
+// If flushDelay is greater than zero, flushes are coalesced: the first Flush call schedules a deferred
+// flush after flushDelay, and any data written and flushed in the meantime goes out in the same batch.
+func NewSocketTransport(
+	conn net.Conn,
+	connectTimeout, keepAliveTimeout time.Duration,
+	readBufferSize, maxPooledWriterSize int,
+	flushDelay time.Duration,
+) Transport {
+	return newSocketTransport(conn, connectTimeout, keepAliveTimeout, readBufferSize, maxPooledWriterSize, flushDelay)
+}
+
+// NewTLSSocketTransport creates a socket class stream transport over an already secured connection,
+// as the ones accepted by direct TLS listeners.
+// TLS handshake is completed before returning, bounded by connectTimeout if greater than zero.
+func NewTLSSocketTransport(
+	conn *tls.Conn,
+	connectTimeout, keepAliveTimeout time.Duration,
+	readBufferSize, maxPooledWriterSize int,
+	flushDelay time.Duration,
+) (Transport, error) {
+	if connectTimeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(connectTimeout)); err != nil {
+			return nil, err
+		}
+	}
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+	s := newSocketTransport(conn, connectTimeout, keepAliveTimeout, readBufferSize, maxPooledWriterSize, flushDelay)
+	s.supportsCb = true
+	return s, nil
+}
+
+func newSocketTransport(
+	conn net.Conn,
+	connectTimeout, keepAliveTimeout time.Duration,
+	readBufferSize, maxPooledWriterSize int,
+	flushDelay time.Duration,
+) *socketTransport {
+	if readBufferSize <= 0 {
+		readBufferSize = defaultReadBufferSize
+	}
+	if maxPooledWriterSize <= 0 {
+		maxPooledWriterSize = defaultMaxPooledWriterSize
+	}
 	dConn := newDeadlineConn(conn, connectTimeout, keepAliveTimeout)
 	lr := ratelimiter.NewReader(dConn)
-	s := &socketTransport{
+	lw := ratelimiter.NewWriter(conn)
+	return &socketTransport{
 		conn:             dConn,
 		lr:               lr,
+		lw:               lw,
 		rd:               bufio.NewReaderSize(lr, readBufferSize),
-		wr:               conn,
+		wr:               lw,
 		connectTimeout:   connectTimeout,
 		keepAliveTimeout: keepAliveTimeout,
+		rdBufSize:        readBufferSize,
+		maxPooledWrSize:  maxPooledWriterSize,
+		flushDelay:       flushDelay,
 	}
-	return s
 }
 
 func (s *socketTransport) Read(p []byte) (n int, err error) {
@@ -85,21 +158,34 @@ func (s *socketTransport) ReadByte() (byte, error) {
 }
 
 func (s *socketTransport) Write(p []byte) (n int, err error) {
-	if s.bw == nil {
-		s.grabBuffWriter()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushErr != nil {
+		return 0, s.flushErr
+	}
+	if err := s.reserveBuffWriter(len(p)); err != nil {
+		return 0, err
 	}
 	return s.bw.Write(p)
 }
 
 func (s *socketTransport) WriteString(str string) (int, error) {
-	if s.bw == nil {
-		s.grabBuffWriter()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushErr != nil {
+		return 0, s.flushErr
+	}
+	if err := s.reserveBuffWriter(len(str)); err != nil {
+		return 0, err
 	}
 	n, err := io.Copy(s.bw, strings.NewReader(str))
 	return int(n), err
 }
 
 func (s *socketTransport) Close() error {
+	_ = s.flushPending() // do not lose coalesced data
 	return s.conn.Close()
 }
 
@@ -108,13 +194,21 @@ func (s *socketTransport) Type() Type {
 }
 
 func (s *socketTransport) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushErr != nil {
+		return s.flushErr
+	}
 	if s.bw == nil {
 		return errNoWriteFlush
 	}
-	if err := s.bw.Flush(); err != nil {
-		return err
+	if s.flushDelay <= 0 {
+		return s.flush()
+	}
+	if s.flushTm == nil {
+		s.flushTm = time.AfterFunc(s.flushDelay, s.deferredFlush)
 	}
-	s.releaseBuffWriter()
 	return nil
 }
 
@@ -123,6 +217,11 @@ func (s *socketTransport) SetReadRateLimiter(rLim *rate.Limiter) error {
 	return nil
 }
 
+func (s *socketTransport) SetWriteRateLimiter(wLim *rate.Limiter) error {
+	s.lw.SetWriteRateLimiter(wLim)
+	return nil
+}
+
 func (s *socketTransport) SetWriteDeadline(d time.Time) error {
 	return s.conn.SetWriteDeadline(d)
 }
@@ -136,6 +235,8 @@ func (s *socketTransport) SetKeepAliveDeadlineHandler(hnd func()) {
 }
 
 func (s *socketTransport) StartTLS(cfg *tls.Config, asClient bool) {
+	_ = s.flushPending() // pending data must go out before the handshake
+
 	_, ok := s.conn.underlyingConn().(*net.TCPConn)
 	if !ok {
 		return
@@ -147,29 +248,42 @@ func (s *socketTransport) StartTLS(cfg *tls.Config, asClient bool) {
 		tlsConn = tls.Server(s.conn, cfg)
 	}
 	s.conn = newDeadlineConn(tlsConn, s.connectTimeout, s.keepAliveTimeout)
-	s.supportsCb = tlsConn.ConnectionState().Version < tls.VersionTLS13
+	s.supportsCb = true
 
 	lr := ratelimiter.NewReader(s.conn)
 	if rLim := s.lr.ReadRateLimiter(); rLim != nil {
 		lr.SetReadRateLimiter(rLim)
 	}
 	s.lr = lr
-	s.rd = bufio.NewReaderSize(lr, readBufferSize)
-	s.wr = s.conn
+	s.rd = bufio.NewReaderSize(lr, s.rdBufSize)
+
+	lw := ratelimiter.NewWriter(s.conn)
+	if wLim := s.lw.WriteRateLimiter(); wLim != nil {
+		lw.SetWriteRateLimiter(wLim)
+	}
+	s.lw = lw
+	s.wr = lw
 }
 
-func (s *socketTransport) EnableCompression(level compress.Level) {
+func (s *socketTransport) EnableCompression(method compress.Method, level compress.Level) {
 	if s.compressed {
 		return
 	}
-	rw := compress.NewZlibCompressor(s.rd, s.wr, level)
-	s.rd = rw
+	_ = s.flushPending() // pending data must go out uncompressed
+
+	rw := compress.NewCompressor(s.rd, s.wr, method, level)
+	s.rd = bufio.NewReaderSize(rw, s.rdBufSize)
 	s.wr = rw
 	s.compressed = true
 }
 
 func (s *socketTransport) SupportsChannelBinding() bool {
-	return s.supportsCb
+	if !s.supportsCb {
+		return false
+	}
+	// only advertise channel binding once negotiated TLS version is able to provide binding data
+	// ('tls-unique' up to TLS 1.2, 'tls-exporter' on TLS 1.3)
+	return len(s.ChannelBindingBytes(TLSUnique)) > 0 || len(s.ChannelBindingBytes(TLSExporter)) > 0
 }
 
 func (s *socketTransport) ChannelBindingBytes(mechanism ChannelBindingMechanism) []byte {
@@ -180,10 +294,24 @@ func (s *socketTransport) ChannelBindingBytes(mechanism ChannelBindingMechanism)
 	if !ok {
 		return nil
 	}
+	connSt := conn.ConnectionState()
+	if !connSt.HandshakeComplete {
+		return nil
+	}
 	switch mechanism {
 	case TLSUnique:
-		connSt := conn.ConnectionState()
-		return connSt.TLSUnique
+		return connSt.TLSUnique // not defined for TLS 1.3
+
+	case TLSExporter:
+		if connSt.Version < tls.VersionTLS13 {
+			return nil
+		}
+		cb, err := connSt.ExportKeyingMaterial(tlsExporterLabel, nil, tlsExporterLength)
+		if err != nil {
+			return nil
+		}
+		return cb
+
 	default:
 		break
 	}
@@ -199,6 +327,62 @@ func (s *socketTransport) PeerCertificates() []*x509.Certificate {
 	return st.PeerCertificates
 }
 
+func (s *socketTransport) deferredFlush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.flushTm = nil
+	if s.bw == nil {
+		return
+	}
+	if err := s.flush(); err != nil {
+		s.flushErr = err // reported on next write or flush
+	}
+}
+
+func (s *socketTransport) flushPending() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.flushTm == nil {
+		return nil
+	}
+	s.flushTm.Stop()
+	s.flushTm = nil
+
+	if s.bw == nil {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *socketTransport) flush() error {
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	s.releaseBuffWriter()
+	return nil
+}
+
+// reserveBuffWriter makes sure current buffered writer is able to hold n more bytes,
+// replacing it with a larger one in case n exceeds pooled writers size.
+func (s *socketTransport) reserveBuffWriter(n int) error {
+	if s.bw == nil {
+		s.grabBuffWriter()
+	}
+	if n <= s.bw.Size() {
+		return nil // buffered data is flushed in case n exceeds available space
+	}
+	if s.bw.Buffered() > 0 {
+		if err := s.bw.Flush(); err != nil {
+			return err
+		}
+	}
+	s.releaseBuffWriter()
+	s.bw = bufio.NewWriterSize(s.wr, n)
+	return nil
+}
+
 func (s *socketTransport) grabBuffWriter() {
 	if s.bw != nil {
 		return
@@ -211,6 +395,9 @@ func (s *socketTransport) releaseBuffWriter() {
 	if s.bw == nil {
 		return
 	}
-	bufWriterPool.Put(s.bw)
+	if s.bw.Size() <= s.maxPooledWrSize {
+		s.bw.Reset(nil) // do not retain underlying writer
+		bufWriterPool.Put(s.bw)
+	}
 	s.bw = nil
 }
@@ -15,15 +15,26 @@
 package transport
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"io"
+	"math/big"
 	"net"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/ortuman/jackal/pkg/transport/compress"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 )
 
 type fakeSocketConn struct {
@@ -61,7 +72,7 @@ func (a fakeAddr) String() string  { return "str" }
 func TestSocket(t *testing.T) {
 	buff := make([]byte, 4096)
 	conn := newFakeSocketConn()
-	st := NewSocketTransport(conn, time.Minute, time.Minute)
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 0)
 	st2 := st.(*socketTransport)
 
 	str := `<elem xmlns="exodus:ns"/>`
@@ -76,7 +87,7 @@ func TestSocket(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, str2, string(buff[:n]))
 
-	st.EnableCompression(compress.BestCompression)
+	st.EnableCompression(compress.ZlibMethod, compress.BestCompression)
 	require.True(t, st2.compressed)
 
 	st.(*socketTransport).conn = newDeadlineConn(&net.TCPConn{}, time.Minute, time.Minute)
@@ -91,3 +102,404 @@ func TestSocket(t *testing.T) {
 	_ = st.Close()
 	require.True(t, conn.closed)
 }
+
+func TestSocket_EnableGzipCompression(t *testing.T) {
+	// given
+	conn := newFakeSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 0)
+	st2 := st.(*socketTransport)
+
+	// when
+	st.EnableCompression(compress.GzipMethod, compress.DefaultCompression)
+	rd, wr := st2.rd, st2.wr
+
+	st.EnableCompression(compress.ZlibMethod, compress.DefaultCompression) // already compressed
+
+	_, _ = io.WriteString(st, `<elem xmlns="exodus:ns"/>`)
+	_ = st.Flush()
+
+	// then
+	require.True(t, st2.compressed)
+	require.Equal(t, rd, st2.rd) // not re-wrapped
+	require.Equal(t, wr, st2.wr)
+	require.IsType(t, &compress.GzipCompressor{}, st2.wr)
+
+	zr, err := gzip.NewReader(conn.w)
+	require.Nil(t, err)
+
+	b := make([]byte, 25)
+	_, err = io.ReadFull(zr, b)
+	require.Nil(t, err)
+	require.Equal(t, `<elem xmlns="exodus:ns"/>`, string(b))
+}
+
+func TestSocket_WriteRateLimiter(t *testing.T) {
+	// given
+	conn := newFakeSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 0)
+	st2 := st.(*socketTransport)
+
+	wLim := rate.NewLimiter(1_000, 100)
+
+	// when
+	_ = st.SetWriteRateLimiter(wLim)
+
+	_, _ = io.WriteString(st, strings.Repeat("a", 100))
+	_ = st.Flush()
+
+	t0 := time.Now()
+	_, _ = io.WriteString(st, strings.Repeat("b", 50))
+	_ = st.Flush()
+	elapsed := time.Since(t0)
+
+	st2.conn = newDeadlineConn(&net.TCPConn{}, time.Minute, time.Minute)
+	st.StartTLS(&tls.Config{}, false)
+
+	// then
+	require.Equal(t, 150, conn.w.Len())
+	require.True(t, elapsed >= time.Millisecond*40)
+
+	require.Equal(t, wLim, st2.lw.WriteRateLimiter()) // survives TLS re-wrapping
+	require.Equal(t, st2.lw, st2.wr)
+}
+
+func TestSocket_ReadBufferSize(t *testing.T) {
+	// given
+	conn := newFakeSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 32*1024, 0, 0)
+	st2 := st.(*socketTransport)
+
+	// when
+	rdSize := st2.rd.(*bufio.Reader).Size()
+
+	st2.conn = newDeadlineConn(&net.TCPConn{}, time.Minute, time.Minute)
+	st.StartTLS(&tls.Config{}, false)
+	tlsRdSize := st2.rd.(*bufio.Reader).Size()
+
+	st.EnableCompression(compress.ZlibMethod, compress.DefaultCompression)
+	cmpRdSize := st2.rd.(*bufio.Reader).Size()
+
+	// then
+	require.Equal(t, 32*1024, rdSize)
+	require.Equal(t, 32*1024, tlsRdSize) // reapplied on TLS re-wrapping
+	require.Equal(t, 32*1024, cmpRdSize) // reapplied on compression
+
+	dst := NewSocketTransport(newFakeSocketConn(), time.Minute, time.Minute, 0, 0, 0).(*socketTransport)
+	require.Equal(t, defaultReadBufferSize, dst.rd.(*bufio.Reader).Size())
+}
+
+func BenchmarkSocket_ReadThroughput(b *testing.B) {
+	const chunkSize = 64 * 1024
+
+	for _, bm := range []struct {
+		name    string
+		bufSize int
+	}{
+		{name: "4KiB", bufSize: 4 * 1024},
+		{name: "32KiB", bufSize: 32 * 1024},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			require.Nil(b, err)
+			defer func() { _ = ln.Close() }()
+
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				defer func() { _ = conn.Close() }()
+
+				chunk := bytes.Repeat([]byte("a"), chunkSize)
+				for i := 0; i < b.N; i++ {
+					if _, err := conn.Write(chunk); err != nil {
+						return
+					}
+				}
+			}()
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			require.Nil(b, err)
+
+			st := NewSocketTransport(conn, 0, 0, bm.bufSize, 0, 0)
+			defer func() { _ = st.Close() }()
+
+			p := make([]byte, 4096) // XML decoder read size
+			b.SetBytes(chunkSize)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for n := 0; n < chunkSize; {
+					rp := p
+					if rem := chunkSize - n; rem < len(rp) {
+						rp = rp[:rem]
+					}
+					rn, err := st.Read(rp)
+					if err != nil {
+						b.Fatal(err)
+					}
+					n += rn
+				}
+			}
+		})
+	}
+}
+
+func TestSocket_DiscardOversizedWriter(t *testing.T) {
+	// given
+	conn := newFakeSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 0)
+	st2 := st.(*socketTransport)
+
+	str := `<elem xmlns="exodus:ns">` + strings.Repeat("a", defaultMaxPooledWriterSize) + `</elem>`
+	_, _ = io.WriteString(st, str)
+
+	bw := st2.bw
+	require.GreaterOrEqual(t, bw.Size(), len(str))
+
+	// when
+	err := st.Flush()
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, str, string(conn.w.Bytes()))
+	require.Nil(t, st2.bw)
+
+	for i := 0; i < 16; i++ {
+		require.NotSame(t, bw, bufWriterPool.Get())
+	}
+}
+
+func TestSocket_PooledWriter(t *testing.T) {
+	// given
+	conn := newFakeSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 0)
+	st2 := st.(*socketTransport)
+
+	str := `<elem xmlns="exodus:ns"/>`
+
+	// when
+	_, _ = io.WriteString(st, str)
+	bw := st2.bw
+	err := st.Flush()
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, str, string(conn.w.Bytes()))
+	require.Equal(t, 4096, bw.Size())
+	require.Nil(t, st2.bw)
+}
+
+func TestSocket_CoalescedFlush(t *testing.T) {
+	// given
+	conn := newCountingSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, 50*time.Millisecond)
+
+	// when
+	start := time.Now()
+	for i := 0; i < 8; i++ {
+		_, _ = io.WriteString(st, `<elem xmlns="exodus:ns"/>`)
+		require.Nil(t, st.Flush())
+	}
+	require.Equal(t, 0, conn.writeCount()) // nothing written yet
+
+	// then
+	select {
+	case <-conn.writeCh:
+		break
+	case <-time.After(time.Second):
+		require.Fail(t, "coalesced flush not performed")
+	}
+	require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	require.Equal(t, 1, conn.writeCount())
+	require.Equal(t, strings.Repeat(`<elem xmlns="exodus:ns"/>`, 8), conn.written())
+}
+
+func TestSocket_CoalescedFlushOnClose(t *testing.T) {
+	// given
+	conn := newCountingSocketConn()
+	st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, time.Hour)
+
+	_, _ = io.WriteString(st, `</stream:stream>`)
+	_ = st.Flush()
+
+	// when
+	err := st.Close()
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 1, conn.writeCount())
+	require.Equal(t, `</stream:stream>`, conn.written())
+}
+
+func BenchmarkSocket_FlushBurst(b *testing.B) {
+	const burstSize = 64
+
+	for _, bm := range []struct {
+		name       string
+		flushDelay time.Duration
+	}{
+		{name: "Immediate"},
+		{name: "Coalesced", flushDelay: time.Hour},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			conn := newCountingSocketConn()
+			st := NewSocketTransport(conn, time.Minute, time.Minute, 0, 0, bm.flushDelay)
+			st2 := st.(*socketTransport)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < burstSize; j++ {
+					_, _ = io.WriteString(st, `<message to="noelia@jackal.im"><body>Hi!</body></message>`)
+					_ = st.Flush()
+				}
+				_ = st2.flushPending() // end of burst
+			}
+			b.ReportMetric(float64(conn.writeCount())/float64(b.N), "flushes/op")
+		})
+	}
+}
+
+type countingSocketConn struct {
+	*fakeSocketConn
+
+	mu      sync.Mutex
+	writes  int
+	writeCh chan struct{}
+}
+
+func newCountingSocketConn() *countingSocketConn {
+	return &countingSocketConn{
+		fakeSocketConn: newFakeSocketConn(),
+		writeCh:        make(chan struct{}, 1),
+	}
+}
+
+func (c *countingSocketConn) Write(b []byte) (n int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes++
+	select {
+	case c.writeCh <- struct{}{}:
+	default:
+	}
+	return c.w.Write(b)
+}
+
+func (c *countingSocketConn) writeCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+func (c *countingSocketConn) written() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.w.String()
+}
+
+func TestSocket_DirectTLS(t *testing.T) {
+	// given
+	srvCert := selfSignedCertificate(t, "jackal.im")
+	cliCert := selfSignedCertificate(t, "jabber.org")
+
+	srvConn, cliConn := net.Pipe()
+
+	tlsCli := tls.Client(cliConn, &tls.Config{
+		Certificates:       []tls.Certificate{cliCert},
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	})
+	cliErrCh := make(chan error, 1)
+	go func() { cliErrCh <- tlsCli.Handshake() }()
+
+	// when
+	st, err := NewTLSSocketTransport(tls.Server(srvConn, &tls.Config{
+		Certificates: []tls.Certificate{srvCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}), time.Minute, time.Minute, 0, 0, 0)
+
+	// then
+	require.Nil(t, err)
+	require.Nil(t, <-cliErrCh)
+
+	require.True(t, st.SupportsChannelBinding())
+	require.NotNil(t, st.ChannelBindingBytes(TLSUnique))
+	require.Nil(t, st.ChannelBindingBytes(TLSExporter))
+
+	peerCerts := st.PeerCertificates()
+	require.Len(t, peerCerts, 1)
+	require.Equal(t, "jabber.org", peerCerts[0].Subject.CommonName)
+
+	_ = cliConn.Close()
+	_ = st.Close()
+}
+
+func TestSocket_TLSExporterChannelBinding(t *testing.T) {
+	// given
+	srvConn, cliConn := net.Pipe()
+
+	tlsCli := tls.Client(cliConn, &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+	})
+	cliErrCh := make(chan error, 1)
+	go func() { cliErrCh <- tlsCli.Handshake() }()
+
+	st, err := NewTLSSocketTransport(tls.Server(srvConn, &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCertificate(t, "jackal.im")},
+	}), time.Minute, time.Minute, 0, 0, 0)
+	require.Nil(t, err)
+	require.Nil(t, <-cliErrCh)
+
+	// when
+	cb := st.ChannelBindingBytes(TLSExporter)
+
+	// then
+	cliSt := tlsCli.ConnectionState()
+	cliCb, _ := cliSt.ExportKeyingMaterial("EXPORTER-Channel-Binding", nil, 32)
+
+	require.True(t, st.SupportsChannelBinding())
+	require.Len(t, cb, 32)
+	require.Equal(t, cliCb, cb)
+	require.Nil(t, st.ChannelBindingBytes(TLSUnique))
+
+	_ = cliConn.Close()
+	_ = st.Close()
+}
+
+func TestSocket_DirectTLSHandshakeError(t *testing.T) {
+	// given
+	srvConn, cliConn := net.Pipe()
+	go func() {
+		_, _ = cliConn.Write([]byte(`<?xml version="1.0"?>`)) // not a TLS client hello
+		_ = cliConn.Close()
+	}()
+
+	// when
+	st, err := NewTLSSocketTransport(tls.Server(srvConn, &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCertificate(t, "jackal.im")},
+	}), time.Minute, time.Minute, 0, 0, 0)
+
+	// then
+	require.NotNil(t, err)
+	require.Nil(t, st)
+}
+
+func selfSignedCertificate(t *testing.T, domain string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domain},
+		DNSNames:     []string{domain},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.Nil(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
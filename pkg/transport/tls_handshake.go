@@ -0,0 +1,66 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+)
+
+const (
+	tlsFailureTimeout         = "timeout"
+	tlsFailureEOF             = "eof"
+	tlsFailureNotTLS          = "not_tls"
+	tlsFailureBadCertificate  = "bad_certificate"
+	tlsFailureProtocolVersion = "protocol_version"
+	tlsFailureNoCipherSuite   = "no_cipher_suite"
+	tlsFailureOther           = "other"
+)
+
+// TLSHandshakeFailureReason classifies a TLS handshake error into a metric friendly reason.
+func TLSHandshakeFailureReason(err error) string {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return tlsFailureTimeout
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return tlsFailureEOF
+	}
+	var recHdrErr tls.RecordHeaderError
+	if errors.As(err, &recHdrErr) {
+		return tlsFailureNotTLS
+	}
+	var unkAuthErr x509.UnknownAuthorityError
+	var certInvErr x509.CertificateInvalidError
+	var hostErr x509.HostnameError
+	if errors.As(err, &unkAuthErr) || errors.As(err, &certInvErr) || errors.As(err, &hostErr) {
+		return tlsFailureBadCertificate
+	}
+	errStr := err.Error()
+	switch {
+	case strings.Contains(errStr, "certificate"):
+		return tlsFailureBadCertificate
+	case strings.Contains(errStr, "unsupported versions"), strings.Contains(errStr, "protocol version"):
+		return tlsFailureProtocolVersion
+	case strings.Contains(errStr, "no cipher suite"):
+		return tlsFailureNoCipherSuite
+	default:
+		return tlsFailureOther
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSHandshakeFailureReason(t *testing.T) {
+	var tests = []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{name: "Timeout", err: fmt.Errorf("read: %w", os.ErrDeadlineExceeded), reason: tlsFailureTimeout},
+		{name: "EOF", err: io.EOF, reason: tlsFailureEOF},
+		{name: "NotTLS", err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, reason: tlsFailureNotTLS},
+		{name: "UnknownAuthority", err: x509.UnknownAuthorityError{}, reason: tlsFailureBadCertificate},
+		{name: "BadCertificateAlert", err: errors.New("remote error: tls: bad certificate"), reason: tlsFailureBadCertificate},
+		{name: "ProtocolVersion", err: errors.New("tls: client offered only unsupported versions: [301]"), reason: tlsFailureProtocolVersion},
+		{name: "NoCipherSuite", err: errors.New("tls: no cipher suite supported by both client and server"), reason: tlsFailureNoCipherSuite},
+		{name: "Other", err: errors.New("tls: unexpected message"), reason: tlsFailureOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.reason, TLSHandshakeFailureReason(tt.err))
+		})
+	}
+}
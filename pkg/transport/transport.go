@@ -30,6 +30,12 @@ type Type int
 const (
 	// Socket represents a socket transport type.
 	Socket Type = iota + 1
+
+	// WebSocket represents a WebSocket transport type (RFC 7395).
+	WebSocket
+
+	// BOSH represents a BOSH transport type (XEP-0206).
+	BOSH
 )
 
 // String returns TransportType string representation.
@@ -37,6 +43,10 @@ func (tt Type) String() string {
 	switch tt {
 	case Socket:
 		return "socket"
+	case WebSocket:
+		return "websocket"
+	case BOSH:
+		return "bosh"
 	}
 	return ""
 }
@@ -47,6 +57,9 @@ type ChannelBindingMechanism int
 const (
 	// TLSUnique represents 'tls-unique' channel binding mechanism.
 	TLSUnique ChannelBindingMechanism = iota
+
+	// TLSExporter represents 'tls-exporter' channel binding mechanism (RFC 9266).
+	TLSExporter
 )
 
 // Transport represents a stream transport mechanism.
@@ -61,11 +74,15 @@ type Transport interface {
 	WriteString(s string) (n int, err error)
 
 	// Flush writes any buffered data to the underlying io.Writer.
+	// Transports may defer the actual write in order to coalesce flushes issued in quick succession.
 	Flush() error
 
 	// SetReadRateLimiter sets transport read rate limiter.
 	SetReadRateLimiter(rLim *rate.Limiter) error
 
+	// SetWriteRateLimiter sets transport write rate limiter.
+	SetWriteRateLimiter(wLim *rate.Limiter) error
+
 	// SetWriteDeadline sets the deadline for future write calls.
 	SetWriteDeadline(d time.Time) error
 
@@ -79,7 +96,7 @@ type Transport interface {
 	StartTLS(cfg *tls.Config, asClient bool)
 
 	// EnableCompression activates a compression mechanism on the transport.
-	EnableCompression(compress.Level)
+	EnableCompression(compress.Method, compress.Level)
 
 	// SupportsChannelBinding tells whether the underlying connection supports channel binding.
 	SupportsChannelBinding() bool
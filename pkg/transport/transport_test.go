@@ -22,5 +22,7 @@ import (
 
 func TestTypeStrings(t *testing.T) {
 	require.Equal(t, "socket", Socket.String())
+	require.Equal(t, "websocket", WebSocket.String())
+	require.Equal(t, "bosh", BOSH.String())
 	require.Equal(t, "", Type(99).String())
 }
@@ -0,0 +1,142 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"time"
+
+	"github.com/ortuman/jackal/pkg/transport/compress"
+	"github.com/ortuman/jackal/pkg/util/ratelimiter"
+	"golang.org/x/net/websocket"
+	"golang.org/x/time/rate"
+)
+
+type webSocketTransport struct {
+	conn *websocket.Conn
+	dc   *deadlineConn
+	lr   *ratelimiter.Reader
+	lw   *ratelimiter.Writer
+	rd   io.Reader
+	wb   bytes.Buffer
+}
+
+// NewWebSocketTransport creates a WebSocket class stream transport (RFC 7395).
+// Every flush is sent as a single WebSocket text message, so that each message carries complete XML elements.
+func NewWebSocketTransport(conn *websocket.Conn, connectTimeout, keepAliveTimeout time.Duration) Transport {
+	conn.PayloadType = websocket.TextFrame
+
+	dc := newDeadlineConn(conn, connectTimeout, keepAliveTimeout)
+	lr := ratelimiter.NewReader(dc)
+	return &webSocketTransport{
+		conn: conn,
+		dc:   dc,
+		lr:   lr,
+		lw:   ratelimiter.NewWriter(dc),
+		rd:   bufio.NewReaderSize(lr, defaultReadBufferSize),
+	}
+}
+
+func (w *webSocketTransport) Read(p []byte) (n int, err error) {
+	return w.rd.Read(p)
+}
+
+func (w *webSocketTransport) ReadByte() (byte, error) {
+	var p [1]byte
+	n, err := w.rd.Read(p[:])
+	switch {
+	case n == 1 && err == nil:
+		return p[0], nil
+	case err != nil:
+		return 0, err
+	default:
+		return 0, nil
+	}
+}
+
+func (w *webSocketTransport) Write(p []byte) (n int, err error) {
+	return w.wb.Write(p)
+}
+
+func (w *webSocketTransport) WriteString(str string) (int, error) {
+	return w.wb.WriteString(str)
+}
+
+func (w *webSocketTransport) Close() error {
+	return w.conn.Close()
+}
+
+func (w *webSocketTransport) Type() Type {
+	return WebSocket
+}
+
+func (w *webSocketTransport) Flush() error {
+	if w.wb.Len() == 0 {
+		return errNoWriteFlush
+	}
+	defer w.wb.Reset()
+
+	_, err := w.lw.Write(w.wb.Bytes())
+	return err
+}
+
+func (w *webSocketTransport) SetReadRateLimiter(rLim *rate.Limiter) error {
+	w.lr.SetReadRateLimiter(rLim)
+	return nil
+}
+
+func (w *webSocketTransport) SetWriteRateLimiter(wLim *rate.Limiter) error {
+	w.lw.SetWriteRateLimiter(wLim)
+	return nil
+}
+
+func (w *webSocketTransport) SetWriteDeadline(d time.Time) error {
+	return w.dc.SetWriteDeadline(d)
+}
+
+func (w *webSocketTransport) SetConnectDeadlineHandler(hnd func()) {
+	w.dc.setConnectDeadlineHandler(hnd)
+}
+
+func (w *webSocketTransport) SetKeepAliveDeadlineHandler(hnd func()) {
+	w.dc.setReadDeadlineHandler(hnd)
+}
+
+// StartTLS does nothing, as WebSocket connections are secured during the HTTP handshake (wss).
+func (w *webSocketTransport) StartTLS(_ *tls.Config, _ bool) {}
+
+// EnableCompression does nothing, as stream compression is not available over WebSocket.
+func (w *webSocketTransport) EnableCompression(_ compress.Method, _ compress.Level) {}
+
+// SupportsChannelBinding returns false, as TLS channel binding data is not exposed to browser clients.
+func (w *webSocketTransport) SupportsChannelBinding() bool {
+	return false
+}
+
+func (w *webSocketTransport) ChannelBindingBytes(_ ChannelBindingMechanism) []byte {
+	return nil
+}
+
+func (w *webSocketTransport) PeerCertificates() []*x509.Certificate {
+	req := w.conn.Request()
+	if req == nil || req.TLS == nil {
+		return nil
+	}
+	return req.TLS.PeerCertificates
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	// given
+	srvTrCh := make(chan Transport, 1)
+	doneCh := make(chan struct{})
+
+	srv := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		srvTrCh <- NewWebSocketTransport(ws, time.Minute, time.Minute)
+		<-doneCh
+	}))
+	defer srv.Close()
+	defer close(doneCh)
+
+	wsCfg, _ := websocket.NewConfig("ws"+strings.TrimPrefix(srv.URL, "http"), srv.URL)
+	clientWS, err := websocket.DialConfig(wsCfg)
+	require.Nil(t, err)
+	defer func() { _ = clientWS.Close() }()
+
+	tr := <-srvTrCh
+
+	// when
+	_, _ = tr.WriteString(`<open xmlns='urn:ietf:params:xml:ns:xmpp-framing'/>`)
+	_, _ = tr.WriteString(`<stream:features xmlns:stream='http://etherx.jabber.org/streams'/>`)
+	flushErr := tr.Flush()
+
+	var msg string
+	rcvErr := websocket.Message.Receive(clientWS, &msg)
+
+	sndErr := websocket.Message.Send(clientWS, `<presence xmlns='jabber:client'/>`)
+
+	buf := make([]byte, 1024)
+	n, rdErr := tr.Read(buf)
+
+	// then
+	require.Nil(t, flushErr)
+	require.Nil(t, rcvErr)
+	require.Nil(t, sndErr)
+	require.Nil(t, rdErr)
+
+	require.Equal(t, WebSocket, tr.Type())
+	require.Equal(t, `<open xmlns='urn:ietf:params:xml:ns:xmpp-framing'/><stream:features xmlns:stream='http://etherx.jabber.org/streams'/>`, msg)
+	require.Equal(t, `<presence xmlns='jabber:client'/>`, string(buf[:n]))
+
+	require.Equal(t, errNoWriteFlush, tr.Flush()) // nothing to flush
+	require.False(t, tr.SupportsChannelBinding())
+	require.Nil(t, tr.PeerCertificates())
+}
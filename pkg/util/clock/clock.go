@@ -0,0 +1,88 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// StampLayout defines the XEP-0082 layout used for every server generated timestamp.
+const StampLayout = "2006-01-02T15:04:05Z"
+
+// Clock is a server time source.
+// Wall time follows the system clock, so that NTP step corrections are honored, but a backward
+// step is never leaked into generated timestamps: whenever system clock goes back in time, last
+// returned time is kept until wall time catches up with it again.
+type Clock struct {
+	nowFn func() time.Time
+
+	mu      sync.Mutex
+	last    time.Time
+	skew    time.Duration
+	hasSkew bool
+}
+
+// New returns a new initialized Clock instance.
+func New() *Clock {
+	return &Clock{nowFn: time.Now}
+}
+
+// Now returns current monotonic corrected time.
+func (c *Clock) Now() time.Time {
+	tm := c.nowFn().Round(0) // strip monotonic reading to compare wall times
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tm.Before(c.last) {
+		return c.last
+	}
+	c.last = tm
+	return tm
+}
+
+// SetSkew sets the perceived local clock skew relative to reference time.
+// A positive value means local clock is ahead.
+func (c *Clock) SetSkew(skew time.Duration) {
+	c.mu.Lock()
+	c.skew = skew
+	c.hasSkew = true
+	c.mu.Unlock()
+}
+
+// Skew returns the perceived local clock skew and whether or not it's known.
+func (c *Clock) Skew() (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew, c.hasSkew
+}
+
+var std = New()
+
+// Now returns current server time.
+// Every server generated timestamp (entity time, delay stamps...) should be taken from here,
+// so that all of them are consistent with each other.
+func Now() time.Time { return std.Now() }
+
+// SetSkew sets the default server clock perceived skew.
+func SetSkew(skew time.Duration) { std.SetSkew(skew) }
+
+// Skew returns the default server clock perceived skew.
+func Skew() (time.Duration, bool) { return std.Skew() }
+
+// FormatStamp formats tm as an UTC XEP-0082 timestamp.
+func FormatStamp(tm time.Time) string {
+	return tm.UTC().Format(StampLayout)
+}
@@ -0,0 +1,86 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNow(t *testing.T) {
+	// when
+	t0 := Now()
+	t1 := Now()
+
+	// then
+	require.False(t, t1.Before(t0))
+	require.WithinDuration(t, time.Now(), t1, time.Second)
+}
+
+func TestClock_WallClockSteps(t *testing.T) {
+	// given
+	t0 := time.Date(1984, 01, 03, 00, 00, 00, 00, time.UTC)
+
+	wallTm := t0
+	c := &Clock{nowFn: func() time.Time { return wallTm }}
+
+	// when
+	tm0 := c.Now()
+
+	wallTm = t0.Add(-time.Minute) // backward step
+	tm1 := c.Now()
+
+	wallTm = t0.Add(time.Hour) // forward step
+	tm2 := c.Now()
+
+	// then
+	require.Equal(t, t0, tm0)
+	require.Equal(t, t0, tm1)
+	require.Equal(t, t0.Add(time.Hour), tm2)
+}
+
+func TestClock_Skew(t *testing.T) {
+	// given
+	c := New()
+
+	_, ok := c.Skew()
+	require.False(t, ok)
+
+	// when
+	c.SetSkew(-1500 * time.Millisecond)
+
+	// then
+	skew, ok := c.Skew()
+	require.True(t, ok)
+	require.Equal(t, -1500*time.Millisecond, skew)
+}
+
+func TestFormatStamp(t *testing.T) {
+	// given
+	loc := time.FixedZone("CET", 3600)
+	tm := time.Date(1984, 01, 03, 01, 30, 00, 500, loc)
+
+	// when
+	stamp := FormatStamp(tm)
+
+	// then
+	require.Equal(t, "1984-01-03T00:30:00Z", stamp)
+
+	parsed, err := time.Parse(StampLayout, stamp)
+	require.Nil(t, err)
+	require.True(t, parsed.Equal(tm.Truncate(time.Second)))
+}
@@ -0,0 +1,96 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"time"
+)
+
+const (
+	ntpPacketSize     = 48
+	ntpEpochOffset    = 2208988800 // seconds between 1900-01-01 and 1970-01-01
+	ntpDefaultTimeout = 5 * time.Second
+)
+
+var (
+	errNTPInvalidResponse = errors.New("clock: invalid NTP response")
+	errNTPKissOfDeath     = errors.New("clock: NTP kiss-o'-death response")
+)
+
+// QueryOffset queries the SNTP (RFC 4330) server at addr and returns the perceived local clock
+// skew relative to it. A positive value means local clock is ahead.
+func QueryOffset(ctx context.Context, addr string) (time.Duration, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(ntpDefaultTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return 0, err
+	}
+	req := make([]byte, ntpPacketSize)
+	req[0] = 0x23 // LI: 0, VN: 4, Mode: 3 (client)
+
+	// a random transmit timestamp is used as request nonce, origin time is taken from the monotonic clock
+	if _, err := rand.Read(req[40:]); err != nil {
+		return 0, err
+	}
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, ntpPacketSize)
+	for {
+		n, err := conn.Read(resp)
+		if err != nil {
+			return 0, err
+		}
+		if n == ntpPacketSize && string(resp[24:32]) == string(req[40:]) {
+			break
+		}
+		// ignore unrelated datagrams
+	}
+	t4 := t1.Add(time.Since(t1))
+
+	if mode := resp[0] & 0x07; mode != 4 {
+		return 0, errNTPInvalidResponse
+	}
+	if stratum := resp[1]; stratum == 0 {
+		return 0, errNTPKissOfDeath
+	}
+	t2 := ntpTime(resp[32:40])
+	t3 := ntpTime(resp[40:48])
+
+	// offset = ((T2 - T1) + (T3 - T4)) / 2, being positive when reference clock is ahead
+	offset := (t2.Sub(t1) + t3.Sub(t4)) / 2
+	return -offset, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:]))
+	return time.Unix(secs, (frac*int64(time.Second))>>32)
+}
@@ -0,0 +1,93 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryOffset(t *testing.T) {
+	// given
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+
+	go func() {
+		b := make([]byte, ntpPacketSize)
+		_, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		// reference clock is one hour behind
+		refTm := time.Now().Add(-time.Hour)
+
+		resp := make([]byte, ntpPacketSize)
+		resp[0] = 0x24 // LI: 0, VN: 4, Mode: 4 (server)
+		resp[1] = 1    // stratum
+		copy(resp[24:32], b[40:48])
+		putNTPTime(resp[32:40], refTm)
+		putNTPTime(resp[40:48], refTm)
+		_, _ = conn.WriteTo(resp, addr)
+	}()
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	skew, err := QueryOffset(ctx, conn.LocalAddr().String())
+
+	// then
+	require.Nil(t, err)
+	require.InDelta(t, time.Hour, skew, float64(100*time.Millisecond))
+}
+
+func TestQueryOffset_KissOfDeath(t *testing.T) {
+	// given
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+
+	go func() {
+		b := make([]byte, ntpPacketSize)
+		_, addr, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+		resp := make([]byte, ntpPacketSize)
+		resp[0] = 0x24
+		copy(resp[24:32], b[40:48])
+		_, _ = conn.WriteTo(resp, addr)
+	}()
+
+	// when
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err = QueryOffset(ctx, conn.LocalAddr().String())
+
+	// then
+	require.Equal(t, errNTPKissOfDeath, err)
+}
+
+func putNTPTime(b []byte, tm time.Time) {
+	binary.BigEndian.PutUint32(b[:4], uint32(tm.Unix()+ntpEpochOffset))
+	binary.BigEndian.PutUint32(b[4:], uint32((int64(tm.Nanosecond())<<32)/int64(time.Second)))
+}
@@ -0,0 +1,122 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupe
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+)
+
+const stanzaIDNamespace = "urn:xmpp:sid:0"
+
+type entry struct {
+	key string
+	tm  time.Time
+}
+
+// Cache keeps track of recently seen stanza keys within a time window, so that side effects
+// derived from a stanza delivered more than once (e.g. on SM retransmissions) can be applied only once.
+type Cache struct {
+	window  time.Duration
+	maxSize int
+	nowFn   func() time.Time
+
+	mu      sync.Mutex
+	seen    map[string]time.Time
+	entries []entry
+}
+
+// New returns a new Cache instance that remembers up to maxSize keys during window.
+func New(window time.Duration, maxSize int) *Cache {
+	return &Cache{
+		window:  window,
+		maxSize: maxSize,
+		nowFn:   time.Now,
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Seen records key, returning true if it was already seen within the cache window.
+func (c *Cache) Seen(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.nowFn()
+	c.evict(now)
+
+	if _, ok := c.seen[key]; ok {
+		return true
+	}
+	c.seen[key] = now
+	c.entries = append(c.entries, entry{key: key, tm: now})
+
+	for c.maxSize > 0 && len(c.seen) > c.maxSize {
+		c.evictOldest()
+	}
+	return false
+}
+
+// Forget removes key from the cache, so that it can be processed again.
+func (c *Cache) Forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, key)
+}
+
+// Len returns the number of keys currently kept in the cache.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.seen)
+}
+
+func (c *Cache) evict(now time.Time) {
+	for len(c.entries) > 0 && now.Sub(c.entries[0].tm) >= c.window {
+		c.evictOldest()
+	}
+}
+
+func (c *Cache) evictOldest() {
+	e := c.entries[0]
+	c.entries = c.entries[1:]
+
+	// key might have been forgotten and seen again afterwards
+	if tm, ok := c.seen[e.key]; ok && tm.Equal(e.tm) {
+		delete(c.seen, e.key)
+	}
+}
+
+// StanzaKey returns the key that identifies stanza for deduplication purposes, based on its
+// XEP-0359 origin-id or, in its absence, stanza-id. Returns false if stanza carries none of them.
+func StanzaKey(stanza stravaganza.Stanza) (string, bool) {
+	if originID := stanza.ChildNamespace("origin-id", stanzaIDNamespace); originID != nil {
+		// origin-id uniqueness is only guaranteed per sender
+		id := originID.Attribute("id")
+		if len(id) == 0 {
+			return "", false
+		}
+		return "o:" + stanza.FromJID().ToBareJID().String() + ":" + stanza.ToJID().String() + ":" + id, true
+	}
+	if stanzaID := stanza.ChildNamespace("stanza-id", stanzaIDNamespace); stanzaID != nil {
+		id := stanzaID.Attribute("id")
+		if len(id) == 0 {
+			return "", false
+		}
+		return "s:" + stanzaID.Attribute("by") + ":" + id, true
+	}
+	return "", false
+}
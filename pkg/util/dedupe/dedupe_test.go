@@ -0,0 +1,114 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dedupe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Seen(t *testing.T) {
+	// given
+	tm := time.Now()
+
+	c := New(time.Minute, 0)
+	c.nowFn = func() time.Time { return tm }
+
+	// when
+	seen0 := c.Seen("k1")
+	seen1 := c.Seen("k1")
+	seen2 := c.Seen("k2")
+
+	tm = tm.Add(time.Minute) // window elapsed
+	seen3 := c.Seen("k1")
+
+	// then
+	require.False(t, seen0)
+	require.True(t, seen1)
+	require.False(t, seen2)
+	require.False(t, seen3)
+	require.Equal(t, 1, c.Len())
+}
+
+func TestCache_Forget(t *testing.T) {
+	// given
+	c := New(time.Minute, 0)
+
+	// when
+	seen0 := c.Seen("k1")
+	c.Forget("k1")
+	seen1 := c.Seen("k1")
+
+	// then
+	require.False(t, seen0)
+	require.False(t, seen1)
+	require.Equal(t, 1, c.Len())
+}
+
+func TestCache_MaxSize(t *testing.T) {
+	// given
+	c := New(time.Minute, 2)
+
+	// when
+	c.Seen("k1")
+	c.Seen("k2")
+	c.Seen("k3")
+
+	// then
+	require.Equal(t, 2, c.Len())
+	require.False(t, c.Seen("k1")) // oldest key evicted
+}
+
+func TestStanzaKey(t *testing.T) {
+	// given
+	buildMessage := func(from string, children ...stravaganza.Element) *stravaganza.Message {
+		msg, _ := stravaganza.NewMessageBuilder().
+			WithAttribute(stravaganza.From, from).
+			WithAttribute(stravaganza.To, "ortuman@jackal.im").
+			WithChildren(children...).
+			BuildMessage()
+		return msg
+	}
+	originID := stravaganza.NewBuilder("origin-id").
+		WithAttribute(stravaganza.Namespace, stanzaIDNamespace).
+		WithAttribute("id", "de305d54").
+		Build()
+	stanzaID := stravaganza.NewBuilder("stanza-id").
+		WithAttribute(stravaganza.Namespace, stanzaIDNamespace).
+		WithAttribute("id", "5f3dbc5e").
+		WithAttribute("by", "jackal.im").
+		Build()
+
+	// when
+	k0, ok0 := StanzaKey(buildMessage("noelia@jackal.im/yard", originID))
+	k1, ok1 := StanzaKey(buildMessage("noelia@jackal.im/balcony", originID, stanzaID))
+	k2, ok2 := StanzaKey(buildMessage("romeo@jackal.im/yard", originID))
+	k3, ok3 := StanzaKey(buildMessage("noelia@jackal.im/yard", stanzaID))
+	_, ok4 := StanzaKey(buildMessage("noelia@jackal.im/yard"))
+
+	// then
+	require.True(t, ok0)
+	require.True(t, ok1)
+	require.True(t, ok2)
+	require.True(t, ok3)
+	require.False(t, ok4)
+
+	require.Equal(t, k0, k1) // same sender account
+	require.NotEqual(t, k0, k2)
+	require.Equal(t, "s:jackal.im:5f3dbc5e", k3)
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iqtracker
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTimeout defines the default time to wait for a server-originated IQ response.
+const DefaultTimeout = time.Second * 32
+
+type pendingIQ struct {
+	payload interface{}
+	tm      *time.Timer
+}
+
+// Tracker keeps track of server-originated IQ requests awaiting for a response, discarding
+// them once the configured timeout elapses.
+type Tracker struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*pendingIQ
+}
+
+// New returns a new Tracker instance that discards unanswered requests after timeout.
+func New(timeout time.Duration) *Tracker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Tracker{
+		timeout: timeout,
+		pending: make(map[string]*pendingIQ),
+	}
+}
+
+// Timeout returns the tracker request timeout.
+func (t *Tracker) Timeout() time.Duration { return t.timeout }
+
+// Track registers a pending request identified by id along with its associated payload.
+// If no response is resolved before timeout the entry is removed and onTimeout, if not nil, is invoked.
+func (t *Tracker) Track(id string, payload interface{}, onTimeout func(payload interface{})) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if p := t.pending[id]; p != nil {
+		p.tm.Stop()
+	}
+	p := &pendingIQ{payload: payload}
+	p.tm = time.AfterFunc(t.timeout, func() {
+		if !t.remove(id, p) {
+			return // already resolved
+		}
+		if onTimeout != nil {
+			onTimeout(payload)
+		}
+	})
+	t.pending[id] = p
+}
+
+// Resolve removes the pending request identified by id, returning its associated payload.
+// Returns false if no request was pending under id.
+func (t *Tracker) Resolve(id string) (interface{}, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p, ok := t.pending[id]
+	if !ok {
+		return nil, false
+	}
+	p.tm.Stop()
+	delete(t.pending, id)
+	return p.payload, true
+}
+
+// Len returns the number of currently pending requests.
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.pending)
+}
+
+// Stop discards all pending requests without invoking their timeout callbacks.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, p := range t.pending {
+		p.tm.Stop()
+		delete(t.pending, id)
+	}
+}
+
+func (t *Tracker) remove(id string, p *pendingIQ) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.pending[id] != p {
+		return false
+	}
+	delete(t.pending, id)
+	return true
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package iqtracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker_Resolve(t *testing.T) {
+	// given
+	tr := New(time.Minute)
+
+	var timedOut bool
+	tr.Track("iq1", "payload", func(_ interface{}) { timedOut = true })
+
+	// when
+	p0, ok0 := tr.Resolve("iq1")
+	_, ok1 := tr.Resolve("iq1")
+
+	// then
+	require.True(t, ok0)
+	require.Equal(t, "payload", p0)
+	require.False(t, ok1)
+	require.False(t, timedOut)
+	require.Equal(t, 0, tr.Len())
+}
+
+func TestTracker_Timeout(t *testing.T) {
+	// given
+	tr := New(50 * time.Millisecond)
+
+	timeoutCh := make(chan interface{}, 1)
+	tr.Track("iq1", "payload", func(p interface{}) { timeoutCh <- p })
+
+	// when
+	var p interface{}
+	select {
+	case p = <-timeoutCh:
+		break
+	case <-time.After(time.Second):
+		require.Fail(t, "pending iq did not time out")
+	}
+
+	// then
+	require.Equal(t, "payload", p)
+	require.Equal(t, 0, tr.Len())
+
+	_, ok := tr.Resolve("iq1")
+	require.False(t, ok)
+}
+
+func TestTracker_Stop(t *testing.T) {
+	// given
+	tr := New(50 * time.Millisecond)
+
+	var timedOut bool
+	tr.Track("iq1", nil, func(_ interface{}) { timedOut = true })
+	tr.Track("iq2", nil, nil)
+
+	// when
+	tr.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// then
+	require.False(t, timedOut)
+	require.Equal(t, 0, tr.Len())
+}
+
+func TestTracker_DefaultTimeout(t *testing.T) {
+	// given
+	tr := New(0)
+
+	// then
+	require.Equal(t, DefaultTimeout, tr.Timeout())
+}
@@ -0,0 +1,190 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyproto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	headerLen = 16
+
+	versionMask = 0xf0
+	commandMask = 0x0f
+
+	version2 = 0x20
+
+	cmdLocal = 0x00
+	cmdProxy = 0x01
+
+	famUnspec = 0x00
+	famTCP4   = 0x11
+	famTCP6   = 0x21
+
+	tcp4AddrLen = 12
+	tcp6AddrLen = 36
+)
+
+var signature = []byte{0x0d, 0x0a, 0x0d, 0x0a, 0x00, 0x0d, 0x0a, 0x51, 0x55, 0x49, 0x54, 0x0a}
+
+var (
+	// ErrMissingHeader will be returned when a connection does not start with a PROXY protocol v2 header.
+	ErrMissingHeader = errors.New("proxyproto: missing PROXY protocol v2 header")
+
+	// ErrInvalidHeader will be returned when a connection PROXY protocol v2 header is malformed.
+	ErrInvalidHeader = errors.New("proxyproto: invalid PROXY protocol v2 header")
+)
+
+// Listener wraps a net.Listener so that accepted connections expect a PROXY protocol v2 header
+// before any payload data.
+type Listener struct {
+	net.Listener
+	headerTimeout time.Duration
+}
+
+// NewListener returns a new PROXY protocol listener.
+// headerTimeout bounds the time a connection may take to send its header. Zero means no timeout.
+func NewListener(ln net.Listener, headerTimeout time.Duration) *Listener {
+	return &Listener{
+		Listener:      ln,
+		headerTimeout: headerTimeout,
+	}
+}
+
+// Accept waits for and returns the next connection to the listener.
+// Header is lazily read on first connection read or on first remote address request,
+// so that a slow peer does not block the accept loop.
+func (l *Listener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(conn, l.headerTimeout), nil
+}
+
+// Conn represents a connection whose remote address is the one conveyed by its PROXY protocol header.
+type Conn struct {
+	net.Conn
+	headerTimeout time.Duration
+
+	once       sync.Once
+	headerErr  error
+	remoteAddr net.Addr
+}
+
+// NewConn returns a new PROXY protocol connection.
+func NewConn(conn net.Conn, headerTimeout time.Duration) *Conn {
+	return &Conn{
+		Conn:          conn,
+		headerTimeout: headerTimeout,
+	}
+}
+
+// ReadHeader reads and validates the connection PROXY protocol header.
+// Subsequent calls return the same result.
+func (c *Conn) ReadHeader() error {
+	c.once.Do(func() {
+		c.headerErr = c.readHeader()
+	})
+	return c.headerErr
+}
+
+// Read reads data from the connection once the PROXY protocol header has been consumed.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.ReadHeader(); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// RemoteAddr returns the source address conveyed by the PROXY protocol header.
+// In case the header is invalid, or it does not carry address information, underlying connection
+// remote address is returned.
+func (c *Conn) RemoteAddr() net.Addr {
+	if err := c.ReadHeader(); err != nil || c.remoteAddr == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remoteAddr
+}
+
+func (c *Conn) readHeader() error {
+	if c.headerTimeout > 0 {
+		if err := c.Conn.SetReadDeadline(time.Now().Add(c.headerTimeout)); err != nil {
+			return err
+		}
+		defer func() { _ = c.Conn.SetReadDeadline(time.Time{}) }()
+	}
+	var hdr [headerLen]byte
+	if _, err := io.ReadFull(c.Conn, hdr[:]); err != nil {
+		return fmt.Errorf("proxyproto: failed to read header: %w", err)
+	}
+	if !bytes.Equal(hdr[:len(signature)], signature) {
+		return ErrMissingHeader
+	}
+	verCmd, fam := hdr[12], hdr[13]
+	if verCmd&versionMask != version2 {
+		return ErrInvalidHeader
+	}
+	addrs := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(c.Conn, addrs); err != nil {
+		return fmt.Errorf("proxyproto: failed to read header addresses: %w", err)
+	}
+	switch verCmd & commandMask {
+	case cmdLocal:
+		// health checks and such originated by the proxy itself
+		return nil
+	case cmdProxy:
+		return c.readAddresses(fam, addrs)
+	default:
+		return ErrInvalidHeader
+	}
+}
+
+func (c *Conn) readAddresses(fam byte, addrs []byte) error {
+	switch fam {
+	case famUnspec:
+		return nil
+
+	case famTCP4:
+		if len(addrs) < tcp4AddrLen {
+			return ErrInvalidHeader
+		}
+		c.remoteAddr = &net.TCPAddr{
+			IP:   net.IP(addrs[0:4]),
+			Port: int(binary.BigEndian.Uint16(addrs[8:])),
+		}
+		return nil
+
+	case famTCP6:
+		if len(addrs) < tcp6AddrLen {
+			return ErrInvalidHeader
+		}
+		c.remoteAddr = &net.TCPAddr{
+			IP:   net.IP(addrs[0:16]),
+			Port: int(binary.BigEndian.Uint16(addrs[32:])),
+		}
+		return nil
+
+	default:
+		return ErrInvalidHeader
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxyproto
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConn_TCP4(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Second)
+
+	// when
+	go func() {
+		hdr := header(cmdProxy, famTCP4, []byte{
+			192, 168, 1, 10, // source address
+			10, 0, 0, 1, // destination address
+			0x30, 0x39, // source port
+			0x14, 0x66, // destination port
+		})
+		_, _ = cli.Write(append(hdr, []byte("<stream:stream>")...))
+	}()
+
+	// then
+	require.Nil(t, conn.ReadHeader())
+
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	require.True(t, ok)
+	require.Equal(t, "192.168.1.10", addr.IP.String())
+	require.Equal(t, 12345, addr.Port)
+
+	b := make([]byte, 15)
+	_, err := io.ReadFull(conn, b)
+	require.Nil(t, err)
+	require.Equal(t, "<stream:stream>", string(b))
+}
+
+func TestConn_TCP6WithTLVs(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Second)
+
+	src := net.ParseIP("2001:db8::1")
+	dst := net.ParseIP("2001:db8::2")
+
+	addrs := make([]byte, 0, tcp6AddrLen+4)
+	addrs = append(addrs, src...)
+	addrs = append(addrs, dst...)
+	addrs = append(addrs, 0x00, 0x50, 0x14, 0x66)
+	addrs = append(addrs, 0x04, 0x00, 0x00) // NOOP TLV
+
+	// when
+	go func() {
+		_, _ = cli.Write(append(header(cmdProxy, famTCP6, addrs), '<'))
+	}()
+
+	// then
+	b := make([]byte, 1)
+	_, err := conn.Read(b)
+	require.Nil(t, err)
+	require.Equal(t, "<", string(b))
+
+	addr := conn.RemoteAddr().(*net.TCPAddr)
+	require.Equal(t, "2001:db8::1", addr.IP.String())
+	require.Equal(t, 80, addr.Port)
+}
+
+func TestConn_Local(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Second)
+
+	// when
+	go func() {
+		_, _ = cli.Write(header(cmdLocal, famUnspec, nil))
+	}()
+
+	// then
+	require.Nil(t, conn.ReadHeader())
+	require.Equal(t, srv.RemoteAddr(), conn.RemoteAddr())
+}
+
+func TestConn_MissingHeader(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Second)
+
+	// when
+	go func() {
+		_, _ = cli.Write([]byte(`<?xml version="1.0"?><stream:stream>`))
+	}()
+
+	// then
+	b := make([]byte, 64)
+	_, err := conn.Read(b)
+	require.Equal(t, ErrMissingHeader, err)
+	require.Equal(t, srv.RemoteAddr(), conn.RemoteAddr())
+}
+
+func TestConn_InvalidHeader(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Second)
+
+	// when
+	go func() {
+		// truncated TCP4 addresses
+		_, _ = cli.Write(header(cmdProxy, famTCP4, []byte{192, 168, 1, 10}))
+	}()
+
+	// then
+	require.Equal(t, ErrInvalidHeader, conn.ReadHeader())
+}
+
+func TestConn_HeaderTimeout(t *testing.T) {
+	// given
+	cli, srv := net.Pipe()
+	defer func() { _ = cli.Close() }()
+
+	conn := NewConn(srv, time.Millisecond*50)
+
+	// when
+	err := conn.ReadHeader()
+
+	// then
+	require.NotNil(t, err)
+}
+
+func header(cmd, fam byte, addrs []byte) []byte {
+	b := make([]byte, 0, headerLen+len(addrs))
+	b = append(b, signature...)
+	b = append(b, version2|cmd, fam, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(addrs)))
+	return append(b, addrs...)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiter
+
+import (
+	"time"
+
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	readThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "read_throttled_total",
+			Help:      "The total number of throttled read operations.",
+		},
+		[]string{"instance"},
+	)
+	readThrottledBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "read_throttled_bytes_total",
+			Help:      "The total number of delayed bytes due to read throttling.",
+		},
+		[]string{"instance"},
+	)
+	readThrottledDuration = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "read_throttled_seconds_total",
+			Help:      "The total time spent delaying throttled read operations.",
+		},
+		[]string{"instance"},
+	)
+	writeThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "write_throttled_total",
+			Help:      "The total number of throttled write operations.",
+		},
+		[]string{"instance"},
+	)
+	writeThrottledBytes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "write_throttled_bytes_total",
+			Help:      "The total number of delayed bytes due to write throttling.",
+		},
+		[]string{"instance"},
+	)
+	writeThrottledDuration = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "write_throttled_seconds_total",
+			Help:      "The total time spent delaying throttled write operations.",
+		},
+		[]string{"instance"},
+	)
+	readLimitExceeded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "jackal",
+			Subsystem: "ratelimiter",
+			Name:      "read_limit_exceeded_total",
+			Help:      "The total number of read operations rejected due to rate limit.",
+		},
+		[]string{"instance"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(readThrottled)
+	prometheus.MustRegister(readThrottledBytes)
+	prometheus.MustRegister(readThrottledDuration)
+	prometheus.MustRegister(readLimitExceeded)
+	prometheus.MustRegister(writeThrottled)
+	prometheus.MustRegister(writeThrottledBytes)
+	prometheus.MustRegister(writeThrottledDuration)
+}
+
+func reportReadThrottled(n int, delay time.Duration) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	readThrottled.With(metricLabel).Inc()
+	readThrottledBytes.With(metricLabel).Add(float64(n))
+	readThrottledDuration.With(metricLabel).Add(delay.Seconds())
+}
+
+func reportWriteThrottled(n int, delay time.Duration) {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	writeThrottled.With(metricLabel).Inc()
+	writeThrottledBytes.With(metricLabel).Add(float64(n))
+	writeThrottledDuration.With(metricLabel).Add(delay.Seconds())
+}
+
+func reportReadLimitExceeded() {
+	metricLabel := prometheus.Labels{
+		"instance": instance.ID(),
+	}
+	readLimitExceeded.With(metricLabel).Inc()
+}
@@ -44,14 +44,45 @@ func (lr *Reader) Read(p []byte) (n int, err error) {
 		return 0, err
 	}
 	if v := lr.rLim.Load(); v != nil {
-		rLim := v.(*rate.Limiter)
-		if !rLim.AllowN(time.Now(), n) {
-			return 0, ErrReadLimitExcedeed
+		if err := throttle(v.(*rate.Limiter), n); err != nil {
+			return 0, err
 		}
 	}
 	return n, nil
 }
 
+// throttle delays the caller until n bytes are allowed by rLim.
+// Reads are delayed at most the time needed to refill the limiter burst allowance,
+// beyond that ErrReadLimitExcedeed is returned.
+func throttle(rLim *rate.Limiter, n int) error {
+	now := time.Now()
+
+	r := rLim.ReserveN(now, n)
+	if !r.OK() {
+		reportReadLimitExceeded()
+		return ErrReadLimitExcedeed
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	if delay > maxThrottleDelay(rLim) {
+		r.CancelAt(now)
+		reportReadLimitExceeded()
+		return ErrReadLimitExcedeed
+	}
+	reportReadThrottled(n, delay)
+	time.Sleep(delay)
+	return nil
+}
+
+func maxThrottleDelay(rLim *rate.Limiter) time.Duration {
+	if rLim.Limit() <= 0 {
+		return 0
+	}
+	return time.Duration(float64(rLim.Burst()) / float64(rLim.Limit()) * float64(time.Second))
+}
+
 // SetReadRateLimiter sets current ReadWriter read rate limit.
 func (lr *Reader) SetReadRateLimiter(rLim *rate.Limiter) {
 	lr.rLim.Store(rLim)
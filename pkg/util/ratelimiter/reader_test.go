@@ -16,7 +16,11 @@ package ratelimiter
 
 import (
 	"testing"
+	"time"
 
+	"github.com/ortuman/jackal/pkg/cluster/instance"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
 )
@@ -63,3 +67,85 @@ func TestReader_ReadRateLimit(t *testing.T) {
 
 	require.Equal(t, ErrReadLimitExcedeed, err2)
 }
+
+func TestReader_ReadBurst(t *testing.T) {
+	// given
+	mockR := &mockReader{}
+	mockR.rFn = func(p []byte) (n int, err error) {
+		return len(p), nil
+	}
+	r := NewReader(mockR)
+	r.SetReadRateLimiter(rate.NewLimiter(1_000, 500))
+
+	throttledCount := counterValue(readThrottled)
+	exceededCount := counterValue(readLimitExceeded)
+
+	// when
+	n, err := r.Read(make([]byte, 500))
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 500, n)
+
+	require.Equal(t, throttledCount, counterValue(readThrottled))
+	require.Equal(t, exceededCount, counterValue(readLimitExceeded))
+}
+
+func TestReader_ReadThrottle(t *testing.T) {
+	// given
+	mockR := &mockReader{}
+	mockR.rFn = func(p []byte) (n int, err error) {
+		return len(p), nil
+	}
+	r := NewReader(mockR)
+	r.SetReadRateLimiter(rate.NewLimiter(1_000, 100))
+
+	throttledCount := counterValue(readThrottled)
+	throttledBytes := counterValue(readThrottledBytes)
+
+	// when
+	_, err1 := r.Read(make([]byte, 100))
+
+	t0 := time.Now()
+	_, err2 := r.Read(make([]byte, 50))
+	elapsed := time.Since(t0)
+
+	// then
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+
+	require.True(t, elapsed >= time.Millisecond*40)
+
+	require.Equal(t, throttledCount+1, counterValue(readThrottled))
+	require.Equal(t, throttledBytes+50, counterValue(readThrottledBytes))
+}
+
+func TestReader_ReadThrottleExceeded(t *testing.T) {
+	// given
+	mockR := &mockReader{}
+	mockR.rFn = func(p []byte) (n int, err error) {
+		return len(p), nil
+	}
+	rLim := rate.NewLimiter(1_000, 100)
+
+	r := NewReader(mockR)
+	r.SetReadRateLimiter(rLim)
+
+	exceededCount := counterValue(readLimitExceeded)
+
+	// when
+	_, err1 := r.Read(make([]byte, 100))
+	_ = rLim.ReserveN(time.Now(), 100) // exhaust next burst allowance
+
+	_, err2 := r.Read(make([]byte, 100))
+
+	// then
+	require.Nil(t, err1)
+	require.Equal(t, ErrReadLimitExcedeed, err2)
+
+	require.Equal(t, exceededCount+1, counterValue(readLimitExceeded))
+}
+
+func counterValue(c *prometheus.CounterVec) float64 {
+	return testutil.ToFloat64(c.With(prometheus.Labels{"instance": instance.ID()}))
+}
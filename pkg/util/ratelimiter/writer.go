@@ -0,0 +1,83 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiter
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Writer implements io.Writer interface.
+type Writer struct {
+	w    io.Writer
+	wLim atomic.Value
+}
+
+// NewWriter returns a rate limited io.Writer implementation.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write implements Writer interface method.
+// The caller is delayed until all p bytes are allowed by the write rate limiter, and then p is written at once.
+func (lw *Writer) Write(p []byte) (n int, err error) {
+	if wLim := lw.WriteRateLimiter(); wLim != nil {
+		throttleWrite(wLim, len(p))
+	}
+	return lw.w.Write(p)
+}
+
+// SetWriteRateLimiter sets current Writer write rate limit.
+// A nil value disables write rate limiting.
+func (lw *Writer) SetWriteRateLimiter(wLim *rate.Limiter) {
+	lw.wLim.Store(wLim)
+}
+
+// WriteRateLimiter returns previously set rate limiter.
+func (lw *Writer) WriteRateLimiter() *rate.Limiter {
+	if v := lw.wLim.Load(); v != nil {
+		return v.(*rate.Limiter)
+	}
+	return nil
+}
+
+// throttleWrite delays the caller until n bytes are allowed by wLim.
+// Unlike reads, writes are never rejected: writes larger than the limiter burst are
+// reserved in burst sized steps.
+func throttleWrite(wLim *rate.Limiter, n int) {
+	now := time.Now()
+
+	var delay time.Duration
+	for rem := n; rem > 0; {
+		step := rem
+		if burst := wLim.Burst(); burst > 0 && step > burst {
+			step = burst
+		}
+		r := wLim.ReserveN(now, step)
+		if !r.OK() {
+			return // not limited
+		}
+		delay = r.DelayFrom(now)
+		rem -= step
+	}
+	if delay == 0 {
+		return
+	}
+	reportWriteThrottled(n, delay)
+	time.Sleep(delay)
+}
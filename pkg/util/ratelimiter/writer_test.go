@@ -0,0 +1,97 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimiter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestWriter_WriteNoLimit(t *testing.T) {
+	// given
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+
+	throttledCount := counterValue(writeThrottled)
+
+	// when
+	n, err := w.Write([]byte("foo"))
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, "foo", buf.String())
+	require.Nil(t, w.WriteRateLimiter())
+	require.Equal(t, throttledCount, counterValue(writeThrottled))
+}
+
+func TestWriter_WriteThrottle(t *testing.T) {
+	// given
+	buf := new(bytes.Buffer)
+	w := NewWriter(buf)
+	w.SetWriteRateLimiter(rate.NewLimiter(1_000, 100))
+
+	throttledCount := counterValue(writeThrottled)
+	throttledBytes := counterValue(writeThrottledBytes)
+
+	// when
+	_, err1 := w.Write(make([]byte, 100))
+
+	t0 := time.Now()
+	_, err2 := w.Write(make([]byte, 50))
+	elapsed := time.Since(t0)
+
+	// then
+	require.Nil(t, err1)
+	require.Nil(t, err2)
+	require.Equal(t, 150, buf.Len())
+
+	require.True(t, elapsed >= time.Millisecond*40)
+
+	require.Equal(t, throttledCount+1, counterValue(writeThrottled))
+	require.Equal(t, throttledBytes+50, counterValue(writeThrottledBytes))
+}
+
+func TestWriter_WriteExceedingBurst(t *testing.T) {
+	// given
+	var writes int
+
+	buf := new(bytes.Buffer)
+	w := NewWriter(writerFunc(func(p []byte) (int, error) {
+		writes++
+		return buf.Write(p)
+	}))
+	w.SetWriteRateLimiter(rate.NewLimiter(1_000, 50))
+
+	// when
+	t0 := time.Now()
+	n, err := w.Write(make([]byte, 120))
+	elapsed := time.Since(t0)
+
+	// then
+	require.Nil(t, err)
+	require.Equal(t, 120, n)
+	require.Equal(t, 1, writes) // never split
+
+	require.True(t, elapsed >= time.Millisecond*60)
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
@@ -0,0 +1,122 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsm
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/jackal-xmpp/stravaganza"
+)
+
+// Namespace defines result set management namespace.
+const Namespace = "http://jabber.org/protocol/rsm"
+
+var (
+	// ErrMalformedRequest will be returned by ParseRequest in case set element cannot be parsed.
+	ErrMalformedRequest = errors.New("rsm: malformed request")
+
+	// ErrUnsupportedRequest will be returned by ParseRequest when backward or index based paging is requested.
+	ErrUnsupportedRequest = errors.New("rsm: unsupported request")
+)
+
+// Request represents a forward paging result set request.
+type Request struct {
+	// Max is the maximum number of items to be returned. Zero value means not specified.
+	Max int
+
+	// CountOnly tells whether the requester is only interested in the number of items (<max>0</max>).
+	CountOnly bool
+
+	// After is the UID of the item after which the page starts.
+	After string
+}
+
+// ParseRequest parses a result set request contained in parent element.
+// Returns nil in case parent doesn't contain a set element.
+func ParseRequest(parent stravaganza.Element) (*Request, error) {
+	set := parent.ChildNamespace("set", Namespace)
+	if set == nil {
+		return nil, nil
+	}
+	if set.Child("before") != nil || set.Child("index") != nil {
+		return nil, ErrUnsupportedRequest
+	}
+	var req Request
+	if maxEl := set.Child("max"); maxEl != nil {
+		max, err := strconv.Atoi(maxEl.Text())
+		if err != nil || max < 0 {
+			return nil, ErrMalformedRequest
+		}
+		req.Max = max
+		req.CountOnly = max == 0
+	}
+	if afterEl := set.Child("after"); afterEl != nil {
+		req.After = afterEl.Text()
+	}
+	return &req, nil
+}
+
+// Config contains result set management paging options.
+type Config struct {
+	// DefaultPageSize defines the number of items returned per page whenever a request
+	// doesn't specify a maximum. If not set, MaxPageSize is used.
+	DefaultPageSize int `fig:"default_page_size"`
+
+	// MaxPageSize defines the maximum number of items returned per page.
+	// Larger requested page sizes are clamped to this value.
+	MaxPageSize int `fig:"max_page_size" default:"100"`
+}
+
+// PageSize returns request page size according to cfg paging options.
+// A non-positive cfg.MaxPageSize means no bound, in which case a zero value may be returned.
+func (r *Request) PageSize(cfg Config) int {
+	size := r.Max
+	if size == 0 {
+		size = cfg.DefaultPageSize
+	}
+	if cfg.MaxPageSize > 0 && (size == 0 || size > cfg.MaxPageSize) {
+		return cfg.MaxPageSize
+	}
+	return size
+}
+
+// Result represents a result set response.
+type Result struct {
+	// First is the UID of the first item of the page.
+	First string
+
+	// Last is the UID of the last item of the page.
+	Last string
+
+	// Count is the total number of items. Negative value means unknown.
+	Count int
+}
+
+// Element returns result set element representation.
+func (r Result) Element() stravaganza.Element {
+	b := stravaganza.NewBuilder("set").
+		WithAttribute(stravaganza.Namespace, Namespace)
+	if len(r.First) > 0 {
+		b.WithChild(stravaganza.NewBuilder("first").WithText(r.First).Build())
+	}
+	if len(r.Last) > 0 {
+		b.WithChild(stravaganza.NewBuilder("last").WithText(r.Last).Build())
+	}
+	if r.Count >= 0 {
+		b.WithChild(stravaganza.NewBuilder("count").WithText(strconv.Itoa(r.Count)).Build())
+	}
+	return b.Build()
+}
@@ -0,0 +1,111 @@
+// Copyright 2022 The jackal Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rsm
+
+import (
+	"testing"
+
+	"github.com/jackal-xmpp/stravaganza"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRequest(t *testing.T) {
+	var tests = []struct {
+		name   string
+		set    stravaganza.Element
+		expReq *Request
+		expErr error
+	}{
+		{
+			name:   "NoSet",
+			expReq: nil,
+		},
+		{
+			name:   "MaxAfter",
+			set:    testSet(stravaganza.NewBuilder("max").WithText("10").Build(), stravaganza.NewBuilder("after").WithText("noelia@jackal.im").Build()),
+			expReq: &Request{Max: 10, After: "noelia@jackal.im"},
+		},
+		{
+			name:   "CountOnly",
+			set:    testSet(stravaganza.NewBuilder("max").WithText("0").Build()),
+			expReq: &Request{CountOnly: true},
+		},
+		{
+			name:   "MalformedMax",
+			set:    testSet(stravaganza.NewBuilder("max").WithText("ten").Build()),
+			expErr: ErrMalformedRequest,
+		},
+		{
+			name:   "Before",
+			set:    testSet(stravaganza.NewBuilder("before").Build()),
+			expErr: ErrUnsupportedRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			qb := stravaganza.NewBuilder("query")
+			if tt.set != nil {
+				qb.WithChild(tt.set)
+			}
+
+			// when
+			req, err := ParseRequest(qb.Build())
+
+			// then
+			require.Equal(t, tt.expErr, err)
+			require.Equal(t, tt.expReq, req)
+		})
+	}
+}
+
+func TestRequest_PageSize(t *testing.T) {
+	var tests = []struct {
+		name    string
+		req     Request
+		cfg     Config
+		expSize int
+	}{
+		{name: "Unbounded", req: Request{Max: 10}, expSize: 10},
+		{name: "WithinMax", req: Request{Max: 10}, cfg: Config{MaxPageSize: 50}, expSize: 10},
+		{name: "ClampedToMax", req: Request{Max: 10000}, cfg: Config{DefaultPageSize: 20, MaxPageSize: 50}, expSize: 50},
+		{name: "DefaultWhenAbsent", req: Request{}, cfg: Config{DefaultPageSize: 20, MaxPageSize: 50}, expSize: 20},
+		{name: "MaxWhenNoDefault", req: Request{}, cfg: Config{MaxPageSize: 50}, expSize: 50},
+		{name: "DefaultClampedToMax", req: Request{}, cfg: Config{DefaultPageSize: 80, MaxPageSize: 50}, expSize: 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expSize, tt.req.PageSize(tt.cfg))
+		})
+	}
+}
+
+func TestResult_Element(t *testing.T) {
+	// given
+	res := Result{First: "a@jackal.im", Last: "b@jackal.im", Count: 2}
+
+	// when
+	elem := res.Element()
+
+	// then
+	require.Equal(t, `<set xmlns='http://jabber.org/protocol/rsm'><first>a@jackal.im</first><last>b@jackal.im</last><count>2</count></set>`, elem.String())
+}
+
+func testSet(children ...stravaganza.Element) stravaganza.Element {
+	return stravaganza.NewBuilder("set").
+		WithAttribute(stravaganza.Namespace, Namespace).
+		WithChildren(children...).
+		Build()
+}
@@ -20,8 +20,11 @@ import (
 	"github.com/jackal-xmpp/stravaganza"
 	stanzaerror "github.com/jackal-xmpp/stravaganza/errors/stanza"
 	"github.com/jackal-xmpp/stravaganza/jid"
+	"github.com/ortuman/jackal/pkg/util/clock"
 )
 
+const stanzaErrorNamespace = "urn:ietf:params:xml:ns:xmpp-stanzas"
+
 // MakeResultIQ creates a new result stanza derived from iq.
 func MakeResultIQ(iq *stravaganza.IQ, queryChild stravaganza.Element) *stravaganza.IQ {
 	b := iq.ResultBuilder()
@@ -50,6 +53,41 @@ func MakeErrorStanza(stanza stravaganza.Stanza, errReason stanzaerror.Reason) st
 	return errStanza
 }
 
+// MakePolicyViolationErrorStanza creates a policy-violation error stanza (RFC 6120, 8.3.3.12) derived from stanza.
+func MakePolicyViolationErrorStanza(stanza stravaganza.Stanza) stravaganza.Stanza {
+	errStanza, _ := stravaganza.NewBuilderFromElement(stanza).
+		WithAttribute(stravaganza.Type, stravaganza.ErrorType).
+		WithAttribute(stravaganza.From, stanza.Attribute(stravaganza.To)).
+		WithAttribute(stravaganza.To, stanza.Attribute(stravaganza.From)).
+		WithChild(
+			stravaganza.NewBuilder("error").
+				WithAttribute(stravaganza.Type, "modify").
+				WithChild(
+					stravaganza.NewBuilder("policy-violation").
+						WithAttribute(stravaganza.Namespace, stanzaErrorNamespace).
+						Build(),
+				).
+				Build(),
+		).
+		BuildStanza()
+	return errStanza
+}
+
+// IsBounceable tells whether an error reply can be generated for stanza.
+// An error stanza must never be replied with another error stanza, as that could lead to bounce loops (RFC 6120, 8.3.1),
+// and only IQ requests can be replied, never IQ responses (RFC 6120, 8.2.3).
+func IsBounceable(stanza stravaganza.Stanza) bool {
+	if iq, ok := stanza.(*stravaganza.IQ); ok {
+		return iq.IsGet() || iq.IsSet()
+	}
+	return stanza.Attribute(stravaganza.Type) != stravaganza.ErrorType
+}
+
+// BounceDepth returns the number of times stanza has been bounced back as an error.
+func BounceDepth(stanza stravaganza.Stanza) int {
+	return len(stanza.Children("error"))
+}
+
 // MakeDelayMessage creates a new message adding delayed information.
 func MakeDelayMessage(stanza stravaganza.Stanza, stamp time.Time, from, text string) *stravaganza.Message {
 	sb := stravaganza.NewBuilderFromElement(stanza)
@@ -57,7 +95,7 @@ func MakeDelayMessage(stanza stravaganza.Stanza, stamp time.Time, from, text str
 		stravaganza.NewBuilder("delay").
 			WithAttribute(stravaganza.Namespace, "urn:xmpp:delay").
 			WithAttribute(stravaganza.From, from).
-			WithAttribute("stamp", stamp.UTC().Format("2006-01-02T15:04:05Z")).
+			WithAttribute("stamp", clock.FormatStamp(stamp)).
 			WithText(text).
 			Build(),
 	)
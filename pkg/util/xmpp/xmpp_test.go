@@ -46,6 +46,28 @@ func TestMakePresence(t *testing.T) {
 	require.Len(t, p.AllChildren(), 1)
 }
 
+func TestMakePolicyViolationErrorStanza(t *testing.T) {
+	// given
+	from, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
+	to, _ := jid.NewWithString("noelia@jackal.im", true)
+
+	pr := MakePresence(from, to, stravaganza.SubscribeType, nil)
+
+	// when
+	errStanza := MakePolicyViolationErrorStanza(pr)
+
+	// then
+	require.NotNil(t, errStanza)
+	require.Equal(t, stravaganza.ErrorType, errStanza.Attribute(stravaganza.Type))
+	require.Equal(t, to.String(), errStanza.Attribute(stravaganza.From))
+	require.Equal(t, from.String(), errStanza.Attribute(stravaganza.To))
+
+	errEl := errStanza.Child("error")
+	require.NotNil(t, errEl)
+	require.Equal(t, "modify", errEl.Attribute(stravaganza.Type))
+	require.NotNil(t, errEl.ChildNamespace("policy-violation", "urn:ietf:params:xml:ns:xmpp-stanzas"))
+}
+
 func TestMakeResultIQ(t *testing.T) {
 	// given
 	from, _ := jid.NewWithString("ortuman@jackal.im/yard", true)
@@ -103,6 +125,58 @@ func TestMakeErrorStanza(t *testing.T) {
 	require.NotNil(t, errEl)
 }
 
+func TestBounceDepth(t *testing.T) {
+	// given
+	msg, _ := stravaganza.NewMessageBuilder().
+		WithAttribute(stravaganza.ID, "msg1234").
+		WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+		WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+		WithChild(
+			stravaganza.NewBuilder("body").
+				WithText("Hi!").
+				Build(),
+		).
+		BuildMessage()
+
+	// when
+	errStanza0 := MakeErrorStanza(msg, stanzaerror.ServiceUnavailable)
+	errStanza1 := MakeErrorStanza(errStanza0, stanzaerror.ServiceUnavailable)
+
+	// then
+	require.True(t, IsBounceable(msg))
+	require.Equal(t, 0, BounceDepth(msg))
+
+	require.False(t, IsBounceable(errStanza0))
+	require.Equal(t, 1, BounceDepth(errStanza0))
+
+	require.False(t, IsBounceable(errStanza1))
+	require.Equal(t, 2, BounceDepth(errStanza1))
+}
+
+func TestIsBounceableIQ(t *testing.T) {
+	// given
+	buildIQ := func(typ string) *stravaganza.IQ {
+		iq, _ := stravaganza.NewIQBuilder().
+			WithAttribute(stravaganza.ID, "iq1234").
+			WithAttribute(stravaganza.From, "ortuman@jackal.im/yard").
+			WithAttribute(stravaganza.To, "noelia@jackal.im/balcony").
+			WithAttribute(stravaganza.Type, typ).
+			WithChild(
+				stravaganza.NewBuilder("ping").
+					WithAttribute(stravaganza.Namespace, "urn:xmpp:ping").
+					Build(),
+			).
+			BuildIQ()
+		return iq
+	}
+
+	// then
+	require.True(t, IsBounceable(buildIQ(stravaganza.GetType)))
+	require.True(t, IsBounceable(buildIQ(stravaganza.SetType)))
+	require.False(t, IsBounceable(buildIQ(stravaganza.ResultType)))
+	require.False(t, IsBounceable(buildIQ(stravaganza.ErrorType)))
+}
+
 func TestMakeDelayStanza(t *testing.T) {
 	// given
 	b := stravaganza.NewMessageBuilder()
@@ -13,6 +13,8 @@ go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.1
 
 FILES=(
   "admin/v1/users.proto"
+  "admin/v1/debug.proto"
+  "policy/v1/policy.proto"
   "c2s/v1/resourceinfo.proto"
   "cluster/v1/cluster.proto"
   "model/v1/user.proto"
@@ -20,6 +22,8 @@ FILES=(
   "model/v1/blocklist.proto"
   "model/v1/caps.proto"
   "model/v1/roster.proto"
+  "model/v1/scheduled.proto"
+  "model/v1/stream.proto"
 )
 
 for file in "${FILES[@]}"; do